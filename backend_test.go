@@ -1,10 +1,12 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -13,7 +15,7 @@ import (
 // Register a test backend for PrefixDB as well, with some unrelated junk data
 func init() {
 	//nolint: errcheck
-	registerDBCreator("prefixdb", func(name, dir string) (DB, error) {
+	registerDBCreator("prefixdb", func(name, dir string, _ *Options) (DB, error) {
 		mdb := NewMemDB()
 		mdb.Set([]byte("a"), []byte{1})
 		mdb.Set([]byte("b"), []byte{2})
@@ -23,6 +25,62 @@ func init() {
 		mdb.Set([]byte("z"), []byte{26})
 		return NewPrefixDB(mdb, []byte("test/")), nil
 	}, false)
+
+	// And for ShardedDB, hashing and range-partitioning over MemDBs
+	registerDBCreator("shardeddb", func(name, dir string, _ *Options) (DB, error) {
+		return NewShardedDB([]DB{NewMemDB(), NewMemDB(), NewMemDB()}, HashSharding)
+	}, false)
+	registerDBCreator("rangeshardeddb", func(name, dir string, _ *Options) (DB, error) {
+		return NewShardedDB([]DB{NewMemDB(), NewMemDB(), NewMemDB()}, RangeSharding([]byte("b"), []byte("m")))
+	}, false)
+
+	// And for MirrorDB
+	registerDBCreator("mirrordb", func(name, dir string, _ *Options) (DB, error) {
+		return NewMirrorDB(NewMemDB(), NewMemDB()), nil
+	}, false)
+
+	// And for ReadReplicaDB, using the primary as its own replica so that reads
+	// observe the writes
+	registerDBCreator("readreplicadb", func(name, dir string, _ *Options) (DB, error) {
+		primary := NewMemDB()
+		return NewReadReplicaDB(primary, primary), nil
+	}, false)
+
+	// And for FaultDB, without faults
+	registerDBCreator("faultdb", func(name, dir string, _ *Options) (DB, error) {
+		return NewFaultDB(NewMemDB()), nil
+	}, false)
+
+	// And for OverlayDB, with base data hidden by tombstones
+	registerDBCreator("overlaydb", func(name, dir string, _ *Options) (DB, error) {
+		base := NewMemDB()
+		base.Set([]byte("a"), []byte{1})
+		base.Set([]byte("z"), []byte{26})
+		odb := NewOverlayDB(base)
+		odb.Delete([]byte("a"))
+		odb.Delete([]byte("z"))
+		return odb, nil
+	}, false)
+
+	// And for TieredDB, with some data already in the cold tier
+	registerDBCreator("tiereddb", func(name, dir string, _ *Options) (DB, error) {
+		cold := NewMemDB()
+		cold.Set([]byte("a"), []byte{1})
+		cold.Set([]byte("z"), []byte{26})
+		tdb := NewTieredDB(NewMemDB(), cold, TierPolicy{PromoteOnRead: true})
+		tdb.Delete([]byte("a"))
+		tdb.Delete([]byte("z"))
+		return tdb, nil
+	}, false)
+
+	// And for TTLDB, with keys which already expired
+	registerDBCreator("ttldb", func(name, dir string, _ *Options) (DB, error) {
+		tdb := NewTTLDB(NewMemDB(), 0)
+		tdb.SetWithTTL([]byte("a"), []byte{1}, time.Nanosecond)
+		tdb.SetWithTTL([]byte("z"), []byte{26}, time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		return tdb, nil
+	}, false)
 }
 
 func cleanupDBDir(dir, name string) {
@@ -30,6 +88,10 @@ func cleanupDBDir(dir, name string) {
 	if err != nil {
 		panic(err)
 	}
+	err = os.RemoveAll(filepath.Join(dir, name) + ".lock")
+	if err != nil {
+		panic(err)
+	}
 }
 
 func testBackendGetSetDelete(t *testing.T, backend BackendType) {
@@ -347,16 +409,20 @@ func testDBBatch(t *testing.T, backend BackendType) {
 	require.NoError(t, batch.Set([]byte("b"), []byte{2}))
 	require.NoError(t, batch.Set([]byte("c"), []byte{3}))
 	assertKeyValues(t, db, map[string][]byte{})
+	assert.Equal(t, 3, batch.Count())
+	assert.GreaterOrEqual(t, batch.SizeBytes(), 6)
 
 	err = batch.Write()
 	require.NoError(t, err)
 	assertKeyValues(t, db, map[string][]byte{"a": {1}, "b": {2}, "c": {3}})
+	assert.Zero(t, batch.Count())
+	assert.Zero(t, batch.SizeBytes())
 
 	// trying to modify or rewrite a written batch should error, but closing it should work
-	require.Error(t, batch.Set([]byte("a"), []byte{9}))
-	require.Error(t, batch.Delete([]byte("a")))
-	require.Error(t, batch.Write())
-	require.Error(t, batch.WriteSync())
+	require.ErrorIs(t, batch.Set([]byte("a"), []byte{9}), ErrBatchWritten)
+	require.ErrorIs(t, batch.Delete([]byte("a")), ErrBatchWritten)
+	require.ErrorIs(t, batch.Write(), ErrBatchWritten)
+	require.ErrorIs(t, batch.WriteSync(), ErrBatchWritten)
 	require.NoError(t, batch.Close())
 
 	// batches should write changes in order
@@ -400,10 +466,10 @@ func testDBBatch(t *testing.T, backend BackendType) {
 	batch.Close()
 
 	// all other operations on a closed batch should error
-	require.Error(t, batch.Set([]byte("a"), []byte{9}))
-	require.Error(t, batch.Delete([]byte("a")))
-	require.Error(t, batch.Write())
-	require.Error(t, batch.WriteSync())
+	require.ErrorIs(t, batch.Set([]byte("a"), []byte{9}), ErrBatchWritten)
+	require.ErrorIs(t, batch.Delete([]byte("a")), ErrBatchWritten)
+	require.ErrorIs(t, batch.Write(), ErrBatchWritten)
+	require.ErrorIs(t, batch.WriteSync(), ErrBatchWritten)
 }
 
 func assertKeyValues(t *testing.T, db DB, expect map[string][]byte) {
@@ -419,3 +485,147 @@ func assertKeyValues(t *testing.T, db DB, expect map[string][]byte) {
 
 	assert.Equal(t, expect, actual)
 }
+
+func TestBatchSizeBytes(t *testing.T) {
+	// The size of a wrapping batch includes its encoding, e.g. the key prefix.
+	for _, tc := range []struct {
+		db   DB
+		size int
+	}{
+		{NewMemDB(), 11},
+		{NewPrefixDB(NewMemDB(), []byte("p/")), 17},
+	} {
+		batch := tc.db.NewBatch()
+		require.NoError(t, batch.Set([]byte("a"), []byte("123")))
+		require.NoError(t, batch.Set([]byte("bb"), []byte("1234")))
+		require.NoError(t, batch.Delete([]byte("c")))
+		assert.Equal(t, 3, batch.Count())
+		assert.Equal(t, tc.size, batch.SizeBytes())
+		require.NoError(t, batch.Close())
+		assert.Zero(t, batch.Count())
+	}
+}
+
+func TestBatchReset(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			for _, db := range []DB{db, NewPrefixDB(db, bz("p/"))} {
+				batch := db.NewBatch()
+				if errors.Is(ResetBatch(batch), ErrNotSupported) {
+					require.NoError(t, batch.Close())
+					t.Skip("batch reset not supported")
+				}
+
+				// A reset batch discards its operations.
+				require.NoError(t, batch.Set(bz("a"), bz("1")))
+				require.NoError(t, ResetBatch(batch))
+				assert.Zero(t, batch.Count())
+				assert.Zero(t, batch.SizeBytes())
+
+				// A written batch can be reused.
+				require.NoError(t, batch.Set(bz("b"), bz("2")))
+				require.NoError(t, batch.Write())
+				assert.ErrorIs(t, batch.Set(bz("c"), bz("3")), ErrBatchWritten)
+				require.NoError(t, ResetBatch(batch))
+				require.NoError(t, batch.Set(bz("c"), bz("3")))
+				require.NoError(t, batch.Delete(bz("b")))
+				assert.Equal(t, 2, batch.Count())
+				require.NoError(t, batch.WriteSync())
+
+				// So can a closed batch.
+				require.NoError(t, batch.Close())
+				require.NoError(t, ResetBatch(batch))
+				require.NoError(t, batch.Set(bz("d"), bz("4")))
+				require.NoError(t, batch.Write())
+				require.NoError(t, batch.Close())
+
+				checkValue(t, db, bz("a"), nil)
+				checkValue(t, db, bz("b"), nil)
+				checkValue(t, db, bz("c"), bz("3"))
+				checkValue(t, db, bz("d"), bz("4"))
+			}
+		})
+	}
+}
+
+func BenchmarkGoLevelDBBatchReset(b *testing.B) {
+	db, err := NewGoLevelDB("goleveldb", b.TempDir())
+	require.NoError(b, err)
+	defer db.Close()
+	value := make([]byte, 100)
+
+	write := func(b *testing.B, batch Batch) {
+		for i := int64(0); i < 100; i++ {
+			require.NoError(b, batch.Set(int642Bytes(i), value))
+		}
+		require.NoError(b, batch.Write())
+	}
+	b.Run("NewBatch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			batch := db.NewBatch()
+			write(b, batch)
+			require.NoError(b, batch.Close())
+		}
+	})
+	b.Run("Reset", func(b *testing.B) {
+		b.ReportAllocs()
+		batch := db.NewBatch()
+		defer batch.Close()
+		for i := 0; i < b.N; i++ {
+			require.NoError(b, ResetBatch(batch))
+			write(b, batch)
+		}
+	})
+}
+
+func TestBackendsClosed(t *testing.T) {
+	// The on-disk backends fail with ErrClosed once closed, rather than crash.
+	for _, backend := range []BackendType{
+		GoLevelDBBackend, CLevelDBBackend, RocksDBBackend, BoltDBBackend,
+		BadgerDBBackend, SQLiteDBBackend, LMDBBackend, PebbleDBBackend,
+	} {
+		if _, ok := backends[backend]; !ok {
+			continue
+		}
+		t.Run(string(backend), func(t *testing.T) {
+			db, err := NewDB("db", backend, t.TempDir())
+			require.NoError(t, err)
+			require.NoError(t, db.Set(bz("a"), bz("1")))
+			batch := db.NewBatch()
+			require.NoError(t, batch.Set(bz("b"), bz("2")))
+			require.NoError(t, db.Close())
+
+			_, err = db.Get(bz("a"))
+			assert.ErrorIs(t, err, ErrClosed)
+			_, err = db.Has(bz("a"))
+			assert.ErrorIs(t, err, ErrClosed)
+			assert.ErrorIs(t, db.Set(bz("a"), bz("2")), ErrClosed)
+			assert.ErrorIs(t, db.SetSync(bz("a"), bz("2")), ErrClosed)
+			assert.ErrorIs(t, db.Delete(bz("a")), ErrClosed)
+			assert.ErrorIs(t, db.DeleteSync(bz("a")), ErrClosed)
+			_, err = db.Iterator(nil, nil)
+			assert.ErrorIs(t, err, ErrClosed)
+			_, err = db.ReverseIterator(nil, nil)
+			assert.ErrorIs(t, err, ErrClosed)
+			assert.ErrorIs(t, batch.Write(), ErrClosed)
+			require.NoError(t, batch.Close())
+			// The batches of a closed database may fail early.
+			batch = db.NewBatch()
+			if err := batch.Set(bz("c"), bz("3")); err != nil {
+				assert.ErrorIs(t, err, ErrClosed)
+			}
+			assert.ErrorIs(t, batch.WriteSync(), ErrClosed)
+			require.NoError(t, batch.Close())
+			db.Stats()
+			assert.ErrorIs(t, db.Close(), ErrClosed)
+		})
+	}
+}
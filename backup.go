@@ -0,0 +1,46 @@
+package db
+
+import (
+	"context"
+	"io"
+)
+
+// backupBatchSize is the approximate size in bytes of the batches written by
+// backups which copy the keys one by one.
+const backupBatchSize = 4 << 20
+
+// Backupper is implemented by databases which can copy themselves while in
+// use.
+type Backupper interface {
+	// Backup writes a consistent copy of the database as of the call to
+	// targetDir, which is created if needed. The copy has the name of the
+	// database, so that it can be opened with the same backend from targetDir.
+	// Writes made during the backup are not included in it, and are not
+	// blocked by it. The backup fails if there already is a database with that
+	// name in targetDir, and may leave a partial copy behind on failure.
+	Backup(ctx context.Context, targetDir string) error
+}
+
+// Backup writes a consistent copy of db to targetDir without stopping it, see
+// Backupper. It returns ErrNotSupported if db does not implement Backupper.
+func Backup(ctx context.Context, db DB, targetDir string) error {
+	b, ok := db.(Backupper)
+	if !ok {
+		return ErrNotSupported
+	}
+	return b.Backup(ctx, targetDir)
+}
+
+// ctxWriter fails writes once its context is done, to cancel backups streamed
+// by the backends.
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (cw ctxWriter) Write(p []byte) (int, error) {
+	if err := cw.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cw.w.Write(p)
+}
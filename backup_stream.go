@@ -0,0 +1,268 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	// backupStreamMagic starts every backup stream, followed by the version of
+	// the format.
+	backupStreamMagic   = "CMTDBBAK"
+	backupStreamVersion = 1
+
+	// maxBackupStreamItemSize bounds the size of the keys and values read from
+	// a backup stream, so that a corrupted length does not exhaust memory.
+	maxBackupStreamItemSize = 1 << 30
+)
+
+var (
+	errBackupStreamInvalid = fmt.Errorf("invalid backup stream: %w", ErrCorrupted)
+	errBackupStreamVersion = errors.New("unsupported backup stream version")
+
+	backupStreamCRCTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// RestoreProgress is the progress of a Restore, reported after every batch
+// written to the database.
+type RestoreProgress struct {
+	// Keys is the number of keys restored so far.
+	Keys uint64
+	// Bytes is the size of the keys and values restored so far.
+	Bytes uint64
+}
+
+// BackupTo writes a backup stream of db to w, which can be restored into a
+// database of any backend by Restore. It is ExportTo for all the keys of db.
+func BackupTo(ctx context.Context, db DB, w io.Writer) error {
+	return ExportTo(ctx, db, w, nil, nil)
+}
+
+// ExportTo writes the keys of db in the domain [start, end) to w as a backup
+// stream, which can be imported into a database of any backend by ImportFrom
+// or Restore, e.g. to move keys between machines, backends or versions of this
+// package without relying on the file formats of the backends. The keys are
+// read from a Snapshot of db if it supports them, so that the export is
+// consistent without stopping writes. Otherwise db must not be written to
+// during the export.
+//
+// The stream starts with the magic bytes "CMTDBBAK" and the version of the
+// format, currently 1, which later versions of this package will still read.
+// The key/value pairs follow in key order, each prefixed by the uvarint lengths
+// of the key and value, followed by an empty key, the uvarint count of pairs
+// and the CRC-32C of everything before it.
+func ExportTo(ctx context.Context, db DB, w io.Writer, start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	var src DBReader = db
+	snap, err := Snapshot(db)
+	switch {
+	case err == nil:
+		defer snap.Close()
+		src = snap
+	case !errors.Is(err, ErrNotSupported):
+		return err
+	}
+
+	itr, err := src.Iterator(start, end)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	crc := crc32.New(backupStreamCRCTable)
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+	if _, err := bw.WriteString(backupStreamMagic); err != nil {
+		return err
+	}
+	if err := bw.WriteByte(backupStreamVersion); err != nil {
+		return err
+	}
+	var (
+		count   uint64
+		pending int
+		buf     [binary.MaxVarintLen64]byte
+	)
+	for ; itr.Valid(); itr.Next() {
+		key, value := itr.Key(), itr.Value()
+		for _, b := range [][]byte{key, value} {
+			if _, err := bw.Write(buf[:binary.PutUvarint(buf[:], uint64(len(b)))]); err != nil {
+				return err
+			}
+			if _, err := bw.Write(b); err != nil {
+				return err
+			}
+		}
+		count++
+		pending += len(key) + len(value)
+		if pending >= backupBatchSize {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			pending = 0
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return err
+	}
+
+	if err := bw.WriteByte(0); err != nil {
+		return err
+	}
+	if _, err := bw.Write(buf[:binary.PutUvarint(buf[:], count)]); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	_, err = w.Write(crc.Sum(nil))
+	return err
+}
+
+// Restore writes the key/value pairs of a backup stream produced by BackupTo or
+// ExportTo to db, in batches of about backupBatchSize bytes. progress, if not
+// nil, is called after every batch. Existing keys of db which are not in the
+// backup are left as is, so db should usually be empty.
+//
+// The integrity of the stream is only known once it has been read completely:
+// if it turns out to be truncated or corrupted, the error wraps ErrCorrupted
+// and the batches already written are not rolled back.
+func Restore(ctx context.Context, db DB, r io.Reader, progress func(RestoreProgress)) error {
+	cr := &crcReader{r: bufio.NewReader(r), crc: crc32.New(backupStreamCRCTable)}
+	header := make([]byte, len(backupStreamMagic)+1)
+	if _, err := io.ReadFull(cr, header); err != nil {
+		return backupStreamError(err)
+	}
+	if string(header[:len(backupStreamMagic)]) != backupStreamMagic {
+		return errBackupStreamInvalid
+	}
+	if header[len(backupStreamMagic)] != backupStreamVersion {
+		return fmt.Errorf("%w %d", errBackupStreamVersion, header[len(backupStreamMagic)])
+	}
+
+	var (
+		p       RestoreProgress
+		pending int
+	)
+	batch := db.NewBatch()
+	defer func() { batch.Close() }()
+	for {
+		key, err := cr.readBytes()
+		if err != nil {
+			return err
+		}
+		if len(key) == 0 {
+			break
+		}
+		value, err := cr.readBytes()
+		if err != nil {
+			return err
+		}
+		if err := batch.Set(key, value); err != nil {
+			return err
+		}
+		p.Keys++
+		pending += len(key) + len(value)
+		if pending < backupBatchSize {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Close()
+		batch = db.NewBatch()
+		p.Bytes += uint64(pending)
+		pending = 0
+		if progress != nil {
+			progress(p)
+		}
+	}
+
+	count, err := binary.ReadUvarint(cr)
+	if err != nil {
+		return backupStreamError(err)
+	}
+	sum := cr.crc.Sum(nil)
+	stored := make([]byte, len(sum))
+	if _, err := io.ReadFull(cr.r, stored); err != nil {
+		return backupStreamError(err)
+	}
+	if count != p.Keys || string(stored) != string(sum) {
+		return errBackupStreamInvalid
+	}
+	if err := batch.WriteSync(); err != nil {
+		return err
+	}
+	p.Bytes += uint64(pending)
+	if progress != nil {
+		progress(p)
+	}
+	return nil
+}
+
+// ImportFrom writes the key/value pairs of a stream produced by ExportTo or
+// BackupTo to db, and returns the number of keys written, including by the
+// batches written before a failure. It is Restore without progress reports,
+// see Restore for how failures are handled.
+func ImportFrom(ctx context.Context, db DB, r io.Reader) (uint64, error) {
+	var keys uint64
+	err := Restore(ctx, db, r, func(p RestoreProgress) {
+		keys = p.Keys
+	})
+	return keys, err
+}
+
+// backupStreamError returns the error for a failed read of a backup stream,
+// which is corrupted if it ended early.
+func backupStreamError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("%w: %w", errBackupStreamInvalid, io.ErrUnexpectedEOF)
+	}
+	return err
+}
+
+// crcReader computes the checksum of the bytes read from a backup stream.
+type crcReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+}
+
+func (cr *crcReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.crc.Write(p[:n])
+	return n, err
+}
+
+func (cr *crcReader) ReadByte() (byte, error) {
+	b, err := cr.r.ReadByte()
+	if err == nil {
+		cr.crc.Write([]byte{b})
+	}
+	return b, err
+}
+
+// readBytes reads a length-prefixed byte slice.
+func (cr *crcReader) readBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(cr)
+	if err != nil {
+		return nil, backupStreamError(err)
+	}
+	if n > uint64(maxBackupStreamItemSize) {
+		return nil, errBackupStreamInvalid
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(cr, b); err != nil {
+		return nil, backupStreamError(err)
+	}
+	return b, nil
+}
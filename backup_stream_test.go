@@ -0,0 +1,144 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupToRestore(t *testing.T) {
+	src := NewMemDB()
+	for i := int64(0); i < 100; i++ {
+		require.NoError(t, src.Set(int642Bytes(i), int642Bytes(i)))
+	}
+	require.NoError(t, src.Set(bz("empty"), []byte{}))
+	var buf bytes.Buffer
+	require.NoError(t, BackupTo(context.Background(), src, &buf))
+
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			var progress []RestoreProgress
+			err = Restore(context.Background(), db, bytes.NewReader(buf.Bytes()), func(p RestoreProgress) {
+				progress = append(progress, p)
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []RestoreProgress{{Keys: 101, Bytes: 100*16 + 5}}, progress)
+
+			for i := int64(0); i < 100; i++ {
+				checkValue(t, db, int642Bytes(i), int642Bytes(i))
+			}
+			checkValue(t, db, bz("empty"), []byte{})
+		})
+	}
+}
+
+func TestRestoreProgress(t *testing.T) {
+	src := NewMemDB()
+	value := make([]byte, 1024)
+	n := int64(2*backupBatchSize/len(value) + 10)
+	for i := int64(0); i < n; i++ {
+		require.NoError(t, src.Set(int642Bytes(i), value))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, BackupTo(context.Background(), src, &buf))
+
+	var progress []RestoreProgress
+	dst := NewMemDB()
+	require.NoError(t, Restore(context.Background(), dst, &buf, func(p RestoreProgress) {
+		progress = append(progress, p)
+	}))
+	require.Len(t, progress, 3)
+	assert.Less(t, progress[0].Keys, progress[1].Keys)
+	assert.EqualValues(t, n, progress[2].Keys)
+	assert.EqualValues(t, n*(8+1024), progress[2].Bytes)
+	count, err := Count(dst)
+	require.NoError(t, err)
+	assert.EqualValues(t, n, count)
+}
+
+func TestRestoreCorrupted(t *testing.T) {
+	src := NewMemDB()
+	for i := int64(0); i < 10; i++ {
+		require.NoError(t, src.Set(int642Bytes(i), int642Bytes(i)))
+	}
+	var buf bytes.Buffer
+	require.NoError(t, BackupTo(context.Background(), src, &buf))
+	stream := buf.Bytes()
+
+	testCases := map[string][]byte{
+		"empty":     {},
+		"magic":     append([]byte("NOTADB!!"), stream[8:]...),
+		"truncated": stream[:len(stream)-1],
+		"no_end":    stream[:len(stream)/2],
+		"flipped":   append(append(append([]byte{}, stream[:20]...), stream[20]^1), stream[21:]...),
+	}
+	for name, data := range testCases {
+		data := data
+		t.Run(name, func(t *testing.T) {
+			err := Restore(context.Background(), NewMemDB(), bytes.NewReader(data), nil)
+			assert.ErrorIs(t, err, ErrCorrupted)
+		})
+	}
+
+	version := append([]byte{}, stream...)
+	version[len(backupStreamMagic)] = 2
+	err := Restore(context.Background(), NewMemDB(), bytes.NewReader(version), nil)
+	assert.ErrorIs(t, err, errBackupStreamVersion)
+}
+
+func TestExportToImportFrom(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemDB()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, src.Set(bz(key), bz(key+"1")))
+	}
+
+	for name, tc := range map[string]struct {
+		start, end []byte
+		keys       []string
+	}{
+		"all":   {nil, nil, []string{"a", "b", "c", "d"}},
+		"range": {bz("b"), bz("d"), []string{"b", "c"}},
+		"start": {bz("c"), nil, []string{"c", "d"}},
+		"empty": {bz("x"), nil, nil},
+	} {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, ExportTo(ctx, src, &buf, tc.start, tc.end))
+			dst, err := NewGoLevelDB("db", t.TempDir())
+			require.NoError(t, err)
+			defer dst.Close()
+			keys, err := ImportFrom(ctx, dst, &buf)
+			require.NoError(t, err)
+			assert.EqualValues(t, len(tc.keys), keys)
+
+			itr, err := dst.Iterator(nil, nil)
+			require.NoError(t, err)
+			defer itr.Close()
+			for _, key := range tc.keys {
+				checkItem(t, itr, bz(key), bz(key+"1"))
+				itr.Next()
+			}
+			checkInvalid(t, itr)
+		})
+	}
+
+	var buf bytes.Buffer
+	assert.ErrorIs(t, ExportTo(ctx, src, &buf, []byte{}, nil), errKeyEmpty)
+	require.NoError(t, ExportTo(ctx, src, &buf, nil, nil))
+	_, err := ImportFrom(ctx, NewMemDB(), bytes.NewReader(buf.Bytes()[:buf.Len()-1]))
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
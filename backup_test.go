@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackup(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			for i := int64(0); i < 100; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+			targetDir, err := os.MkdirTemp("", "backup_test")
+			require.NoError(t, err)
+			defer os.RemoveAll(targetDir)
+			err = Backup(context.Background(), db, targetDir)
+			if errors.Is(err, ErrNotSupported) {
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+
+			// Later writes are not in the backup, and the backup is not
+			// overwritten.
+			require.NoError(t, db.Set(int642Bytes(100), int642Bytes(100)))
+			require.Error(t, Backup(context.Background(), db, targetDir))
+
+			backup, err := NewDB(name, dbType, targetDir)
+			require.NoError(t, err)
+			defer backup.Close()
+			itr, err := backup.Iterator(nil, nil)
+			require.NoError(t, err)
+			for i := int64(0); i < 100; i++ {
+				checkValid(t, itr, true)
+				checkItem(t, itr, int642Bytes(i), int642Bytes(i))
+				itr.Next()
+			}
+			checkValid(t, itr, false)
+			require.NoError(t, itr.Close())
+		})
+	}
+}
+
+func TestBackupNotSupported(t *testing.T) {
+	dir, err := os.MkdirTemp("", "backup_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.ErrorIs(t, Backup(context.Background(), NewMemDB(), dir), ErrNotSupported)
+	assert.ErrorIs(t, Backup(context.Background(), NewPrefixDB(NewMemDB(), bz("p")), dir), ErrNotSupported)
+}
+
+func TestBackupCanceled(t *testing.T) {
+	dir, err := os.MkdirTemp("", "backup_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := NewPebbleDB("pebble", dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, db.Backup(ctx, dir+"/backup"), context.Canceled)
+}
@@ -5,17 +5,82 @@ package db
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
 
-	"github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/y"
+)
+
+const (
+	// badgerGCInterval is how often the value log garbage collector runs.
+	badgerGCInterval = 5 * time.Minute
+	// badgerGCDiscardRatio is the fraction of a value log file that must be
+	// discardable before the file is rewritten.
+	badgerGCDiscardRatio = 0.5
 )
 
 func init() { registerDBCreator(BadgerDBBackend, badgerDBCreator, true) }
 
-func badgerDBCreator(dbName, dir string) (DB, error) {
-	return NewBadgerDB(dbName, dir)
+// badgerDBCreator accepts badger.Options as backend options, whose directories
+// are replaced by the one of the database.
+func badgerDBCreator(dbName, dir string, opts *Options) (DB, error) {
+	if opts.FS != nil {
+		return nil, errOption(BadgerDBBackend, "FS")
+	}
+	if opts.SharedCache != nil {
+		return nil, errOption(BadgerDBBackend, "SharedCache")
+	}
+	if opts.Recover {
+		return nil, errOption(BadgerDBBackend, "Recover")
+	}
+	path := filepath.Join(dir, dbName)
+	bopts := badgerDefaultOptions(path)
+	if opts.BackendOptions != nil {
+		bo, ok := opts.BackendOptions.(badger.Options)
+		if !ok {
+			return nil, errBackendOptions(BadgerDBBackend, opts.BackendOptions)
+		}
+		bopts = bo.WithDir(path).WithValueDir(path)
+	}
+	if opts.CacheSize > 0 {
+		bopts.BlockCacheSize = opts.CacheSize
+	}
+	if opts.ReadOnly {
+		bopts.ReadOnly = true
+	}
+	if opts.SyncWrites {
+		bopts.SyncWrites = true
+	}
+	if opts.InMemory {
+		bopts = bopts.WithInMemory(true).WithDir("").WithValueDir("")
+	} else if !bopts.ReadOnly {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, err
+		}
+	}
+	db, err := NewBadgerDBWithOptions(bopts)
+	if err != nil {
+		return nil, err
+	}
+	db.iters = newIteratorTracker(dbName, opts)
+	db.lock = opts.lock
+	return db, nil
+}
+
+// badgerDefaultOptions returns the options of NewBadgerDB for path.
+func badgerDefaultOptions(path string) badger.Options {
+	opts := badger.DefaultOptions(path)
+	opts.SyncWrites = false // note that we have Sync methods
+	opts.Logger = nil       // badger is too chatty by default
+	return opts
 }
 
 // NewBadgerDB creates a Badger key-value store backed to the
@@ -28,37 +93,78 @@ func NewBadgerDB(dbName, dir string) (*BadgerDB, error) {
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
 	}
-	opts := badger.DefaultOptions(path)
-	opts.SyncWrites = false // note that we have Sync methods
-	opts.Logger = nil       // badger is too chatty by default
-	return NewBadgerDBWithOptions(opts)
+	return NewBadgerDBWithOptions(badgerDefaultOptions(path))
 }
 
 // NewBadgerDBWithOptions creates a BadgerDB key value store
 // gives the flexibility of initializing a database with the
 // respective options.
+//
+// A background goroutine periodically runs the value log garbage collector
+// until the database is closed.
 func NewBadgerDBWithOptions(opts badger.Options) (*BadgerDB, error) {
 	db, err := badger.Open(opts)
 	if err != nil {
 		return nil, err
 	}
-	return &BadgerDB{db: db}, nil
+	b := &BadgerDB{
+		db:   db,
+		ops:  newOpTracker(),
+		quit: make(chan struct{}),
+	}
+	b.wg.Add(1)
+	go b.runValueLogGC(badgerGCInterval)
+	return b, nil
 }
 
+// BadgerDB is a BadgerDB (v4) backend.
 type BadgerDB struct {
-	db *badger.DB
+	db    *badger.DB
+	iters *iteratorTracker
+	lock  *dbLock
+	ops   *opTracker
+
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
 }
 
 var _ DB = (*BadgerDB)(nil)
 
+// errBadgerDBClosed is returned by the operations on a closed BadgerDB, some of
+// which panic in badger once it is closed.
+var errBadgerDBClosed = wrapError(ErrClosed, badger.ErrDBClosed)
+
+// badgerError wraps the badger errors with the shared error kinds. Writes are
+// only blocked by badger while closing, since DropAll is never called.
+func badgerError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, badger.ErrDBClosed), errors.Is(err, badger.ErrBlockedWrites):
+		return wrapError(ErrClosed, err)
+	case errors.Is(err, badger.ErrReadOnlyTxn):
+		return wrapError(ErrReadOnly, err)
+	case errors.Is(err, y.ErrChecksumMismatch), errors.Is(err, badger.ErrTruncateNeeded):
+		return wrapError(ErrCorrupted, err)
+	case errors.Is(err, badger.ErrConflict):
+		return wrapError(ErrUnavailable, err)
+	}
+	return err
+}
+
 func (b *BadgerDB) Get(key []byte) ([]byte, error) {
 	if len(key) == 0 {
 		return nil, errKeyEmpty
 	}
+	if !b.ops.begin() {
+		return nil, errBadgerDBClosed
+	}
+	defer b.ops.end()
 	var val []byte
 	err := b.db.View(func(txn *badger.Txn) error {
 		item, err := txn.Get(key)
-		if err == badger.ErrKeyNotFound {
+		if errors.Is(err, badger.ErrKeyNotFound) {
 			return nil
 		} else if err != nil {
 			return err
@@ -69,23 +175,60 @@ func (b *BadgerDB) Get(key []byte) ([]byte, error) {
 		}
 		return err
 	})
-	return val, err
+	return val, badgerError(err)
+}
+
+// GetUnsafe implements UnsafeGetter, passing the value read by badger within a
+// read transaction.
+func (b *BadgerDB) GetUnsafe(key []byte, fn func(value []byte) error) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if !b.ops.begin() {
+		return errBadgerDBClosed
+	}
+	defer b.ops.end()
+	var fnErr error
+	err := b.db.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(key)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			fnErr = fn(nil)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			if val == nil {
+				val = []byte{}
+			}
+			fnErr = fn(val)
+			return nil
+		})
+	})
+	if err != nil {
+		return badgerError(err)
+	}
+	return fnErr
 }
 
 func (b *BadgerDB) Has(key []byte) (bool, error) {
 	if len(key) == 0 {
 		return false, errKeyEmpty
 	}
+	if !b.ops.begin() {
+		return false, errBadgerDBClosed
+	}
+	defer b.ops.end()
 	var found bool
 	err := b.db.View(func(txn *badger.Txn) error {
 		_, err := txn.Get(key)
-		if err != nil && err != badger.ErrKeyNotFound {
+		if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
 			return err
 		}
-		found = (err != badger.ErrKeyNotFound)
+		found = err == nil
 		return nil
 	})
-	return found, err
+	return found, badgerError(err)
 }
 
 func (b *BadgerDB) Set(key, value []byte) error {
@@ -95,37 +238,110 @@ func (b *BadgerDB) Set(key, value []byte) error {
 	if value == nil {
 		return errValueNil
 	}
-	return b.db.Update(func(txn *badger.Txn) error {
+	if !b.ops.begin() {
+		return errBadgerDBClosed
+	}
+	defer b.ops.end()
+	return badgerError(b.db.Update(func(txn *badger.Txn) error {
 		return txn.Set(key, value)
-	})
+	}))
 }
 
 func withSync(db *badger.DB, err error) error {
 	if err != nil {
 		return err
 	}
-	return db.Sync()
+	return badgerError(db.Sync())
 }
 
 func (b *BadgerDB) SetSync(key, value []byte) error {
+	if !b.ops.begin() {
+		return errBadgerDBClosed
+	}
+	defer b.ops.end()
 	return withSync(b.db, b.Set(key, value))
 }
 
+// SetWithTTL implements TTLSetter, using the native expiry of badger. Expired
+// keys are dropped during compaction.
+func (b *BadgerDB) SetWithTTL(key, value []byte, ttl time.Duration) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if ttl <= 0 {
+		return errTTLInvalid
+	}
+	if !b.ops.begin() {
+		return errBadgerDBClosed
+	}
+	defer b.ops.end()
+	return badgerError(b.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry(key, value).WithTTL(ttl))
+	}))
+}
+
 func (b *BadgerDB) Delete(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
-	return b.db.Update(func(txn *badger.Txn) error {
+	if !b.ops.begin() {
+		return errBadgerDBClosed
+	}
+	defer b.ops.end()
+	return badgerError(b.db.Update(func(txn *badger.Txn) error {
 		return txn.Delete(key)
-	})
+	}))
 }
 
 func (b *BadgerDB) DeleteSync(key []byte) error {
+	if !b.ops.begin() {
+		return errBadgerDBClosed
+	}
+	defer b.ops.end()
 	return withSync(b.db, b.Delete(key))
 }
 
+// runValueLogGC reclaims value log space every interval until the database is
+// closed. Every run rewrites files until badger reports there is nothing left
+// to rewrite.
+func (b *BadgerDB) runValueLogGC(interval time.Duration) {
+	defer b.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				if err := b.db.RunValueLogGC(badgerGCDiscardRatio); err != nil {
+					break
+				}
+				// Stop early if Close was called in the middle of a GC run.
+				select {
+				case <-b.quit:
+					return
+				default:
+				}
+			}
+		case <-b.quit:
+			return
+		}
+	}
+}
+
 func (b *BadgerDB) Close() error {
-	return b.db.Close()
+	defer b.lock.release()
+	b.closeOnce.Do(func() {
+		close(b.quit)
+	})
+	b.wg.Wait()
+	if !b.ops.close() {
+		return errBadgerDBClosed
+	}
+	b.iters.close()
+	return badgerError(b.db.Close())
 }
 
 func (b *BadgerDB) Print() error {
@@ -136,7 +352,16 @@ func (b *BadgerDB) iteratorOpts(start, end []byte, opts badger.IteratorOptions)
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
-	txn := b.db.NewTransaction(false)
+	if !b.ops.begin() {
+		return nil, errBadgerDBClosed
+	}
+	defer b.ops.end()
+	return newBadgerDBIterator(b.db.NewTransaction(false), start, end, opts), nil
+}
+
+// newBadgerDBIterator creates an iterator within txn, which it discards when
+// closed unless keepTxn is set afterwards.
+func newBadgerDBIterator(txn *badger.Txn, start, end []byte, opts badger.IteratorOptions) *badgerDBIterator {
 	iter := txn.NewIterator(opts)
 	iter.Rewind()
 	iter.Seek(start)
@@ -152,47 +377,324 @@ func (b *BadgerDB) iteratorOpts(start, end []byte, opts badger.IteratorOptions)
 
 		txn:  txn,
 		iter: iter,
-	}, nil
+	}
 }
 
 func (b *BadgerDB) Iterator(start, end []byte) (Iterator, error) {
 	opts := badger.DefaultIteratorOptions
-	return b.iteratorOpts(start, end, opts)
+	return b.iters.track(b.iteratorOpts(start, end, opts))
 }
 
 func (b *BadgerDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	opts := badger.DefaultIteratorOptions
 	opts.Reverse = true
-	return b.iteratorOpts(end, start, opts)
+	return b.iters.track(b.iteratorOpts(end, start, opts))
 }
 
-func (b *BadgerDB) Stats() map[string]string {
+// IteratorWithOptions implements IteratorOptionsDB. KeysOnly iterators do not
+// prefetch the values, which may not even be read from the value log.
+func (b *BadgerDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	bopts := badger.DefaultIteratorOptions
+	bopts.PrefetchValues = !opts.KeysOnly
+	var (
+		itr *badgerDBIterator
+		err error
+	)
+	if opts.Reverse {
+		bopts.Reverse = true
+		itr, err = b.iteratorOpts(end, start, bopts)
+	} else {
+		itr, err = b.iteratorOpts(start, end, bopts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.KeysOnly {
+		return b.iters.track(newKeysOnlyIterator(itr), nil)
+	}
+	return b.iters.track(itr, nil)
+}
+
+// NewTxn implements Transactor, using a native badger transaction. Commit
+// returns ErrTxnConflict if a key read in the transaction was written by
+// another transaction committed in the meantime. Only one iterator may be open
+// at a time in a transaction.
+func (b *BadgerDB) NewTxn() (Txn, error) {
+	if !b.ops.begin() {
+		return nil, errBadgerDBClosed
+	}
+	defer b.ops.end()
+	return &badgerTxn{txn: b.db.NewTransaction(true)}, nil
+}
+
+type badgerTxn struct {
+	txn  *badger.Txn
+	done bool
+}
+
+var _ Txn = (*badgerTxn)(nil)
+
+func (t *badgerTxn) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	if t.done {
+		return nil, errTxnDone
+	}
+	item, err := t.txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, badgerError(err)
+	}
+	val, err := item.ValueCopy(nil)
+	if err == nil && val == nil {
+		val = []byte{}
+	}
+	return val, badgerError(err)
+}
+
+func (t *badgerTxn) Has(key []byte) (bool, error) {
+	val, err := t.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return val != nil, nil
+}
+
+func (t *badgerTxn) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if t.done {
+		return errTxnDone
+	}
+	// Badger keeps references to them until the transaction ends.
+	return t.txn.Set(cp(key), cp(value))
+}
+
+func (t *badgerTxn) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if t.done {
+		return errTxnDone
+	}
+	return t.txn.Delete(cp(key))
+}
+
+func (t *badgerTxn) Iterator(start, end []byte) (Iterator, error) {
+	return t.iterator(start, end, badger.DefaultIteratorOptions)
+}
+
+func (t *badgerTxn) ReverseIterator(start, end []byte) (Iterator, error) {
+	opts := badger.DefaultIteratorOptions
+	opts.Reverse = true
+	return t.iterator(end, start, opts)
+}
+
+func (t *badgerTxn) iterator(start, end []byte, opts badger.IteratorOptions) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if t.done {
+		return nil, errTxnDone
+	}
+	itr := newBadgerDBIterator(t.txn, start, end, opts)
+	itr.keepTxn = true
+	return itr, nil
+}
+
+func (t *badgerTxn) Commit() error {
+	if t.done {
+		return errTxnDone
+	}
+	t.done = true
+	err := t.txn.Commit()
+	if errors.Is(err, badger.ErrConflict) {
+		return ErrTxnConflict
+	}
+	return badgerError(err)
+}
+
+func (t *badgerTxn) Discard() error {
+	if !t.done {
+		t.done = true
+		t.txn.Discard()
+	}
 	return nil
 }
 
+func (b *BadgerDB) Stats() map[string]string {
+	if !b.ops.begin() {
+		return map[string]string{}
+	}
+	defer b.ops.end()
+	lsm, vlog := b.db.Size()
+	stats := make(map[string]string)
+	stats["badger.lsm-size"] = fmt.Sprintf("%d", lsm)
+	stats["badger.vlog-size"] = fmt.Sprintf("%d", vlog)
+	return stats
+}
+
+// Metrics implements MetricsReporter. The disk size includes the value log, and
+// the block cache metrics are zero unless the block cache is enabled. Badger
+// does not report compaction metrics.
+func (b *BadgerDB) Metrics() (Metrics, error) {
+	if !b.ops.begin() {
+		return Metrics{}, errBadgerDBClosed
+	}
+	defer b.ops.end()
+	lsm, vlog := b.db.Size()
+	cache := b.db.BlockCacheMetrics()
+	m := Metrics{
+		DiskSize: uint64(lsm + vlog),
+		BlockCache: CacheMetrics{
+			Size:   cache.CostAdded() - cache.CostEvicted(),
+			Hits:   cache.Hits(),
+			Misses: cache.Misses(),
+		},
+	}
+	for _, level := range b.db.Levels() {
+		for len(m.Levels) <= level.Level {
+			m.Levels = append(m.Levels, LevelMetrics{})
+		}
+		m.Levels[level.Level] = LevelMetrics{Files: int64(level.NumTables), Size: uint64(level.Size)}
+	}
+	return m, nil
+}
+
+// EstimateCount implements CountEstimator, summing the keys of the LSM tables.
+// Older versions of a key are counted too, and data still in the memtables is
+// not accounted for.
+func (b *BadgerDB) EstimateCount() (uint64, error) {
+	if !b.ops.begin() {
+		return 0, errBadgerDBClosed
+	}
+	defer b.ops.end()
+	var count uint64
+	for _, table := range b.db.Tables() {
+		count += uint64(table.KeyCount)
+	}
+	return count, nil
+}
+
+// Sync implements Syncer, syncing the value log and the manifest. Badger has no
+// way to flush its memtables on demand, so it does not implement Flusher.
+func (b *BadgerDB) Sync() error {
+	if !b.ops.begin() {
+		return errBadgerDBClosed
+	}
+	defer b.ops.end()
+	return badgerError(b.db.Sync())
+}
+
+// badgerLoadPendingWrites is the number of pending writes while loading a
+// backup stream.
+const badgerLoadPendingWrites = 256
+
+// Backup implements Backupper, streaming a badger backup of the database, as of
+// a read timestamp, into a new database opened with the same options. Expiry
+// times are preserved.
+func (b *BadgerDB) Backup(ctx context.Context, targetDir string) error {
+	if !b.ops.begin() {
+		return errBadgerDBClosed
+	}
+	defer b.ops.end()
+	opts := b.db.Opts()
+	path := filepath.Join(targetDir, filepath.Base(opts.Dir))
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		if err == nil {
+			err = fmt.Errorf("backup %s already exists", path)
+		}
+		return err
+	}
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return err
+	}
+	dst, err := badger.Open(opts.WithDir(path).WithValueDir(path).WithReadOnly(false))
+	if err != nil {
+		return badgerError(err)
+	}
+	defer dst.Close()
+
+	pr, pw := io.Pipe()
+	backupErr := make(chan error, 1)
+	go func() {
+		_, err := b.db.Backup(ctxWriter{ctx: ctx, w: pw}, 0)
+		pw.CloseWithError(err)
+		backupErr <- err
+	}()
+	err = dst.Load(pr, badgerLoadPendingWrites)
+	// Unblock the backup if loading failed.
+	pr.CloseWithError(err)
+	berr := <-backupErr
+	if err != nil {
+		return badgerError(err)
+	}
+	if berr != nil {
+		return badgerError(berr)
+	}
+	return badgerError(dst.Close())
+}
+
+// Compact implements Compacter. Badger cannot compact a key range, so the whole
+// LSM tree is flattened and the value log garbage collected instead.
+func (b *BadgerDB) Compact(_, _ []byte) error {
+	if !b.ops.begin() {
+		return errBadgerDBClosed
+	}
+	defer b.ops.end()
+	if err := b.db.Flatten(runtime.NumCPU()); err != nil {
+		return badgerError(err)
+	}
+	for {
+		// RunValueLogGC rewrites at most one value log file per call.
+		err := b.db.RunValueLogGC(badgerGCDiscardRatio)
+		if errors.Is(err, badger.ErrNoRewrite) {
+			return nil
+		}
+		if err != nil {
+			return badgerError(err)
+		}
+	}
+}
+
 func (b *BadgerDB) NewBatch() Batch {
-	wb := &badgerDBBatch{
-		db:         b.db,
-		wb:         b.db.NewWriteBatch(),
-		firstFlush: make(chan struct{}, 1),
+	if !b.ops.begin() {
+		return &badgerDBBatch{db: b.db, ops: b.ops, closedDB: true}
+	}
+	defer b.ops.end()
+	return &badgerDBBatch{
+		db:  b.db,
+		ops: b.ops,
+		wb:  b.db.NewWriteBatch(),
 	}
-	wb.firstFlush <- struct{}{}
-	return wb
 }
 
 var _ Batch = (*badgerDBBatch)(nil)
 
+// badgerDBBatch maps a Batch onto a badger.WriteBatch, which transparently
+// splits the writes over as many transactions as needed.
 type badgerDBBatch struct {
-	db *badger.DB
-	wb *badger.WriteBatch
-
-	// Calling db.Flush twice panics, so we must keep track of whether we've
-	// flushed already on our own. If Write can receive from the firstFlush
-	// channel, then it's the first and only Flush call we should do.
+	db  *badger.DB
+	ops *opTracker // of the database
+	// closedDB is set if the database was closed when the batch was created,
+	// in which case there is no write batch and the batch fails with
+	// ErrClosed.
+	closedDB bool
+	// wb is set to nil once the batch has been written or closed. Calling
+	// Flush twice, or Flush after Cancel, panics in badger.
 	//
 	// Upstream bug report:
 	// https://github.com/dgraph-io/badger/issues/1394
-	firstFlush chan struct{}
+	wb *badger.WriteBatch
+
+	count int
+	size  int
 }
 
 func (b *badgerDBBatch) Set(key, value []byte) error {
@@ -202,35 +704,77 @@ func (b *badgerDBBatch) Set(key, value []byte) error {
 	if value == nil {
 		return errValueNil
 	}
-	return b.wb.Set(key, value)
+	if b.closedDB {
+		return errBadgerDBClosed
+	}
+	if b.wb == nil {
+		return ErrBatchWritten
+	}
+	if err := b.wb.Set(key, value); err != nil {
+		return err
+	}
+	b.count++
+	b.size += len(key) + len(value)
+	return nil
 }
 
 func (b *badgerDBBatch) Delete(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
-	return b.wb.Delete(key)
+	if b.closedDB {
+		return errBadgerDBClosed
+	}
+	if b.wb == nil {
+		return ErrBatchWritten
+	}
+	if err := b.wb.Delete(key); err != nil {
+		return err
+	}
+	b.count++
+	b.size += len(key)
+	return nil
 }
 
+func (b *badgerDBBatch) Count() int     { return b.count }
+func (b *badgerDBBatch) SizeBytes() int { return b.size }
+
 func (b *badgerDBBatch) Write() error {
-	select {
-	case <-b.firstFlush:
-		return b.wb.Flush()
-	default:
-		return fmt.Errorf("batch already flushed")
+	if b.closedDB {
+		return errBadgerDBClosed
 	}
+	if b.wb == nil {
+		return ErrBatchWritten
+	}
+	if !b.ops.begin() {
+		return errBadgerDBClosed
+	}
+	defer b.ops.end()
+	wb := b.wb
+	b.wb = nil
+	b.count, b.size = 0, 0
+	return badgerError(wb.Flush())
 }
 
 func (b *badgerDBBatch) WriteSync() error {
+	if !b.ops.begin() {
+		return errBadgerDBClosed
+	}
+	defer b.ops.end()
 	return withSync(b.db, b.Write())
 }
 
 func (b *badgerDBBatch) Close() error {
-	select {
-	case <-b.firstFlush: // a Flush after Cancel panics too
-	default:
+	if b.wb != nil {
+		// Canceling discards the transaction of the batch, which is dropped
+		// along with the database once closed.
+		if b.ops.begin() {
+			b.wb.Cancel()
+			b.ops.end()
+		}
+		b.wb = nil
+		b.count, b.size = 0, 0
 	}
-	b.wb.Cancel()
 	return nil
 }
 
@@ -238,15 +782,18 @@ type badgerDBIterator struct {
 	reverse    bool
 	start, end []byte
 
-	txn  *badger.Txn
-	iter *badger.Iterator
+	txn     *badger.Txn
+	keepTxn bool // the transaction belongs to a badgerTxn
+	iter    *badger.Iterator
 
 	lastErr error
 }
 
 func (i *badgerDBIterator) Close() error {
 	i.iter.Close()
-	i.txn.Discard()
+	if !i.keepTxn {
+		i.txn.Discard()
+	}
 	return nil
 }
 
@@ -289,7 +836,7 @@ func (i *badgerDBIterator) Value() []byte {
 	}
 	val, err := i.iter.Item().ValueCopy(nil)
 	if err != nil {
-		i.lastErr = err
+		i.lastErr = badgerError(err)
 	}
 	return val
 }
@@ -0,0 +1,74 @@
+//go:build badgerdb
+// +build badgerdb
+
+package db
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBadgerDBBackend(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, BadgerDBBackend, dir)
+	require.NoError(t, err)
+	defer os.RemoveAll(filepath.Join(dir, name))
+
+	_, ok := db.(*BadgerDB)
+	assert.True(t, ok)
+	assert.NotEmpty(t, db.Stats())
+
+	// Closing must stop the value log GC goroutine.
+	require.NoError(t, db.Close())
+}
+
+func TestBadgerDBTxnConflict(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewBadgerDB(name, dir)
+	require.NoError(t, err)
+	defer os.RemoveAll(filepath.Join(dir, name))
+	defer db.Close()
+
+	txn1, err := NewTxn(db)
+	require.NoError(t, err)
+	txn2, err := NewTxn(db)
+	require.NoError(t, err)
+
+	_, err = txn1.Get(bz("a"))
+	require.NoError(t, err)
+	require.NoError(t, txn1.Set(bz("a"), bz("1")))
+	require.NoError(t, txn2.Set(bz("a"), bz("2")))
+	require.NoError(t, txn2.Commit())
+
+	// txn1 read a key written by txn2 since it started.
+	assert.ErrorIs(t, txn1.Commit(), ErrTxnConflict)
+	checkValue(t, db, bz("a"), bz("2"))
+}
+
+func TestBadgerDBSetWithTTL(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewBadgerDB(name, dir)
+	require.NoError(t, err)
+	defer os.RemoveAll(filepath.Join(dir, name))
+	defer db.Close()
+
+	require.NoError(t, SetWithTTL(db, []byte("a"), []byte("1"), time.Hour))
+	require.NoError(t, SetWithTTL(db, []byte("b"), []byte("2"), time.Second))
+	checkValue(t, db, []byte("a"), []byte("1"))
+
+	// badger expires keys with a resolution of a second.
+	assert.Eventually(t, func() bool {
+		value, err := db.Get([]byte("b"))
+		return err == nil && value == nil
+	}, 3*time.Second, 50*time.Millisecond)
+	checkValue(t, db, []byte("a"), []byte("1"))
+}
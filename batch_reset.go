@@ -0,0 +1,28 @@
+package db
+
+// BatchResetter is implemented by batches which can be reused, so that code
+// writing many batches, e.g. one per block while syncing, does not allocate a
+// batch and its buffers for each of them.
+type BatchResetter interface {
+	// Reset discards the operations of the batch, and makes it usable again
+	// even if it was written or closed. The buffers of the batch are kept, or
+	// taken from a pool, rather than allocated anew. The batch must still be
+	// closed once no longer used.
+	Reset() error
+}
+
+// ResetBatch discards the operations of batch and makes it usable again, see
+// BatchResetter. It returns ErrNotSupported if batch does not implement
+// BatchResetter, in which case callers should close it and create a new one:
+//
+//	if err := ResetBatch(batch); err != nil {
+//		batch.Close()
+//		batch = db.NewBatch()
+//	}
+func ResetBatch(batch Batch) error {
+	r, ok := batch.(BatchResetter)
+	if !ok {
+		return ErrNotSupported
+	}
+	return r.Reset()
+}
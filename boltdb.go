@@ -4,10 +4,13 @@
 package db
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync/atomic"
+	"time"
 
 	"go.etcd.io/bbolt"
 )
@@ -16,10 +19,71 @@ var (
 	bucket = []byte("tm")
 )
 
+// boltReadOnlyLockTimeout bounds the wait for the file lock when opening a
+// database read-only with Options, unless the backend options set a timeout.
+const boltReadOnlyLockTimeout = time.Second
+
 func init() {
-	registerDBCreator(BoltDBBackend, func(name, dir string) (DB, error) {
-		return NewBoltDB(name, dir)
-	}, false)
+	registerDBCreator(BoltDBBackend, newBoltDBFromOptions, false)
+	registerBackendParams(BoltDBBackend)
+}
+
+// newBoltDBFromOptions accepts *bbolt.Options as backend options, and the
+// backend params of applyBoltDBParams. Bolt has no cache of its own, so the
+// cache size is ignored.
+func newBoltDBFromOptions(name, dir string, opts *Options) (DB, error) {
+	if opts.InMemory {
+		return nil, errOption(BoltDBBackend, "InMemory")
+	}
+	if opts.FS != nil {
+		return nil, errOption(BoltDBBackend, "FS")
+	}
+	if opts.Recover {
+		return nil, errOption(BoltDBBackend, "Recover")
+	}
+	o := *bbolt.DefaultOptions
+	if opts.BackendOptions != nil {
+		bo, ok := opts.BackendOptions.(*bbolt.Options)
+		if !ok {
+			return nil, errBackendOptions(BoltDBBackend, opts.BackendOptions)
+		}
+		o = *bo
+	}
+	if err := applyBoltDBParams(&o, newBackendParams(opts.BackendParams)); err != nil {
+		return nil, err
+	}
+	if opts.ReadOnly {
+		o.ReadOnly = true
+		// Fail rather than wait forever while a writer holds the lock.
+		if o.Timeout == 0 {
+			o.Timeout = boltReadOnlyLockTimeout
+		}
+	}
+	if opts.SyncWrites {
+		o.NoSync = false
+	}
+	db, err := NewBoltDBWithOpts(name, dir, &o)
+	if err != nil {
+		return nil, err
+	}
+	db.iters = newIteratorTracker(name, opts)
+	db.lock = opts.lock
+	return db, nil
+}
+
+// applyBoltDBParams applies the backend params of BoltDB to o: the readonly
+// and nosync options and the lock timeout.
+func applyBoltDBParams(o *bbolt.Options, params *backendParams) error {
+	if v, ok := params.bool("readonly"); ok {
+		o.ReadOnly = v
+	}
+	if v, ok := params.bool("nosync"); ok {
+		o.NoSync = v
+	}
+	if v, ok := params.duration("timeout"); ok {
+		o.Timeout = v
+	}
+	return params.err()
 }
 
 // BoltDB is a wrapper around etcd's fork of bolt (https://github.com/etcd-io/bbolt).
@@ -30,41 +94,66 @@ func init() {
 // A single bucket ([]byte("tm")) is used per a database instance. This could
 // lead to performance issues when/if there will be lots of keys.
 type BoltDB struct {
-	db *bbolt.DB
+	db     *bbolt.DB
+	iters  *iteratorTracker
+	lock   *dbLock
+	closed atomic.Bool
 }
 
 var _ DB = (*BoltDB)(nil)
 
 // NewBoltDB returns a BoltDB with default options.
-func NewBoltDB(name, dir string) (DB, error) {
+func NewBoltDB(name, dir string) (*BoltDB, error) {
 	return NewBoltDBWithOpts(name, dir, bbolt.DefaultOptions)
 }
 
-// NewBoltDBWithOpts allows you to supply *bbolt.Options. ReadOnly: true is not
-// supported because NewBoltDBWithOpts creates a global bucket.
-func NewBoltDBWithOpts(name string, dir string, opts *bbolt.Options) (DB, error) {
-	if opts.ReadOnly {
-		return nil, errors.New("ReadOnly: true is not supported")
-	}
-
+// NewBoltDBWithOpts allows you to supply *bbolt.Options. With ReadOnly: true
+// the database must already exist, since the global bucket cannot be created,
+// and all writes will fail.
+func NewBoltDBWithOpts(name string, dir string, opts *bbolt.Options) (*BoltDB, error) {
 	dbPath := filepath.Join(dir, name+".db")
 	db, err := bbolt.Open(dbPath, os.ModePerm, opts)
 	if err != nil {
-		return nil, err
+		return nil, boltDBError(err)
 	}
 
-	// create a global bucket
-	err = db.Update(func(tx *bbolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists(bucket)
-		return err
-	})
+	if opts.ReadOnly {
+		err = db.View(func(tx *bbolt.Tx) error {
+			if tx.Bucket(bucket) == nil {
+				return errors.New("bucket not found, the database has not been initialized")
+			}
+			return nil
+		})
+	} else {
+		// create a global bucket
+		err = db.Update(func(tx *bbolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			return err
+		})
+	}
 	if err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	return &BoltDB{db: db}, nil
 }
 
+// boltDBError wraps the bolt errors with the shared error kinds.
+func boltDBError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, bbolt.ErrDatabaseNotOpen):
+		return wrapError(ErrClosed, err)
+	case errors.Is(err, bbolt.ErrDatabaseReadOnly):
+		return wrapError(ErrReadOnly, err)
+	case errors.Is(err, bbolt.ErrInvalid), errors.Is(err, bbolt.ErrChecksum), errors.Is(err, bbolt.ErrVersionMismatch):
+		return wrapError(ErrCorrupted, err)
+	}
+	return err
+}
+
 // Get implements DB.
 func (bdb *BoltDB) Get(key []byte) (value []byte, err error) {
 	if len(key) == 0 {
@@ -78,11 +167,51 @@ func (bdb *BoltDB) Get(key []byte) (value []byte, err error) {
 		return nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, boltDBError(err)
 	}
 	return
 }
 
+// GetUnsafe implements UnsafeGetter, passing the value in the memory map within
+// a read transaction.
+func (bdb *BoltDB) GetUnsafe(key []byte, fn func(value []byte) error) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	var fnErr error
+	err := bdb.db.View(func(tx *bbolt.Tx) error {
+		fnErr = fn(tx.Bucket(bucket).Get(key))
+		return nil
+	})
+	if err != nil {
+		return boltDBError(err)
+	}
+	return fnErr
+}
+
+// MultiGet implements MultiGetter, reading all keys in a single transaction.
+func (bdb *BoltDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+	}
+	values := make([][]byte, len(keys))
+	err := bdb.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for i, key := range keys {
+			if v := b.Get(key); v != nil {
+				values[i] = append([]byte{}, v...)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, boltDBError(err)
+	}
+	return values, nil
+}
+
 // Has implements DB.
 func (bdb *BoltDB) Has(key []byte) (bool, error) {
 	bytes, err := bdb.Get(key)
@@ -105,7 +234,7 @@ func (bdb *BoltDB) Set(key, value []byte) error {
 		return b.Put(key, value)
 	})
 	if err != nil {
-		return err
+		return boltDBError(err)
 	}
 	return nil
 }
@@ -124,7 +253,7 @@ func (bdb *BoltDB) Delete(key []byte) error {
 		return tx.Bucket(bucket).Delete(key)
 	})
 	if err != nil {
-		return err
+		return boltDBError(err)
 	}
 	return nil
 }
@@ -134,9 +263,46 @@ func (bdb *BoltDB) DeleteSync(key []byte) error {
 	return bdb.Delete(key)
 }
 
+// Sync implements Syncer. Commits are synced unless the database was opened
+// with NoSync, in which case Sync syncs the database file.
+func (bdb *BoltDB) Sync() error {
+	return boltDBError(bdb.db.Sync())
+}
+
+// Backup implements Backupper, writing the database file as of a read
+// transaction, which does not block writers.
+func (bdb *BoltDB) Backup(ctx context.Context, targetDir string) error {
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(targetDir, filepath.Base(bdb.db.Path()))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = bdb.db.View(func(tx *bbolt.Tx) error {
+		_, err := tx.WriteTo(ctxWriter{ctx: ctx, w: f})
+		return err
+	})
+	if err != nil {
+		return boltDBError(err)
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
 // Close implements DB.
 func (bdb *BoltDB) Close() error {
-	return bdb.db.Close()
+	defer bdb.lock.release()
+	if bdb.closed.Swap(true) {
+		return boltDBError(bbolt.ErrDatabaseNotOpen)
+	}
+	// bolt waits for the open transactions, and thus iterators, to be closed.
+	bdb.iters.close()
+	return boltDBError(bdb.db.Close())
 }
 
 // Print implements DB.
@@ -145,14 +311,13 @@ func (bdb *BoltDB) Print() error {
 	fmt.Printf("%v\n", stats)
 
 	err := bdb.db.View(func(tx *bbolt.Tx) error {
-		tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+		return tx.Bucket(bucket).ForEach(func(k, v []byte) error {
 			fmt.Printf("[%X]:\t[%X]\n", k, v)
 			return nil
 		})
-		return nil
 	})
 	if err != nil {
-		return err
+		return boltDBError(err)
 	}
 	return nil
 }
@@ -175,33 +340,50 @@ func (bdb *BoltDB) Stats() map[string]string {
 	return m
 }
 
+// Metrics implements MetricsReporter. BoltDB is a B+tree relying on the page
+// cache of the operating system, so only the disk size is set.
+func (bdb *BoltDB) Metrics() (Metrics, error) {
+	var m Metrics
+	err := bdb.db.View(func(tx *bbolt.Tx) error {
+		m.DiskSize = uint64(tx.Size())
+		return nil
+	})
+	return m, boltDBError(err)
+}
+
 // NewBatch implements DB.
 func (bdb *BoltDB) NewBatch() Batch {
 	return newBoltDBBatch(bdb)
 }
 
-// WARNING: Any concurrent writes or reads will block until the iterator is
-// closed.
+// Iterator implements DB.
+//
+// The iterator holds a read-only transaction, since bolt cursors are only valid
+// within one. WARNING: Any concurrent writes or reads will block until the
+// iterator is closed.
 func (bdb *BoltDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
 	tx, err := bdb.db.Begin(false)
 	if err != nil {
-		return nil, err
+		return nil, boltDBError(err)
 	}
-	return newBoltDBIterator(tx, start, end, false), nil
+	return bdb.iters.track(newBoltDBIterator(tx, start, end, false), nil)
 }
 
-// WARNING: Any concurrent writes or reads will block until the iterator is
-// closed.
+// ReverseIterator implements DB.
+//
+// The iterator holds a read-only transaction, since bolt cursors are only valid
+// within one. WARNING: Any concurrent writes or reads will block until the
+// iterator is closed.
 func (bdb *BoltDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
 	tx, err := bdb.db.Begin(false)
 	if err != nil {
-		return nil, err
+		return nil, boltDBError(err)
 	}
-	return newBoltDBIterator(tx, start, end, true), nil
+	return bdb.iters.track(newBoltDBIterator(tx, start, end, true), nil)
 }
@@ -29,7 +29,7 @@ func (b *boltDBBatch) Set(key, value []byte) error {
 		return errValueNil
 	}
 	if b.ops == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.ops = append(b.ops, operation{opTypeSet, key, value})
 	return nil
@@ -41,18 +41,30 @@ func (b *boltDBBatch) Delete(key []byte) error {
 		return errKeyEmpty
 	}
 	if b.ops == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
 	return nil
 }
 
+// Count implements Batch.
+func (b *boltDBBatch) Count() int {
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *boltDBBatch) SizeBytes() int {
+	return opsSize(b.ops)
+}
+
 // Write implements Batch.
 func (b *boltDBBatch) Write() error {
 	if b.ops == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
-	err := b.db.db.Batch(func(tx *bbolt.Tx) error {
+	// db.Update and not db.Batch: the latter waits up to MaxBatchDelay for
+	// other callers to coalesce with, and may run the function more than once.
+	err := b.db.db.Update(func(tx *bbolt.Tx) error {
 		bkt := tx.Bucket(bucket)
 		for _, op := range b.ops {
 			switch op.opType {
@@ -69,7 +81,7 @@ func (b *boltDBBatch) Write() error {
 		return nil
 	})
 	if err != nil {
-		return err
+		return boltDBError(err)
 	}
 	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
 	return b.Close()
@@ -5,6 +5,7 @@ package db
 
 import (
 	"bytes"
+	"errors"
 
 	"go.etcd.io/bbolt"
 )
@@ -12,7 +13,8 @@ import (
 // boltDBIterator allows you to iterate on range of keys/values given some
 // start / end keys (nil & nil will result in doing full scan).
 type boltDBIterator struct {
-	tx *bbolt.Tx
+	tx     *bbolt.Tx
+	keepTx bool // the transaction belongs to a boltTxn
 
 	itr   *bbolt.Cursor
 	start []byte
@@ -132,7 +134,16 @@ func (itr *boltDBIterator) Error() error {
 
 // Close implements Iterator.
 func (itr *boltDBIterator) Close() error {
-	return itr.tx.Rollback()
+	// The cursor must not be used once the transaction is gone.
+	itr.isInvalid = true
+	if itr.keepTx {
+		return nil
+	}
+	err := itr.tx.Rollback()
+	if errors.Is(err, bbolt.ErrTxClosed) {
+		return nil
+	}
+	return err
 }
 
 func (itr *boltDBIterator) assertIsValid() {
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
 )
 
 func TestBoltDBNewBoltDB(t *testing.T) {
@@ -18,7 +19,37 @@ func TestBoltDBNewBoltDB(t *testing.T) {
 
 	db, err := NewBoltDB(name, dir)
 	require.NoError(t, err)
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
 	db.Close()
+
+	// Read-only access requires the database (and its bucket) to exist.
+	ro, err := NewBoltDBWithOpts(name, dir, &bbolt.Options{ReadOnly: true})
+	require.NoError(t, err)
+	defer ro.Close()
+	value, err := ro.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, value)
+	require.Error(t, ro.Set([]byte("b"), []byte{2}))
+
+	defer cleanupDBDir(dir, name+"_missing")
+	_, err = NewBoltDBWithOpts(name+"_missing", dir, &bbolt.Options{ReadOnly: true})
+	require.Error(t, err)
+}
+
+func TestBoltDBIteratorDoubleClose(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	defer cleanupDBDir(dir, name)
+
+	db, err := NewBoltDB(name, dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, itr.Close())
+	require.NoError(t, itr.Close())
+	require.False(t, itr.Valid())
 }
 
 func BenchmarkBoltDBRandomReadsWrites(b *testing.B) {
@@ -34,3 +65,14 @@ func BenchmarkBoltDBRandomReadsWrites(b *testing.B) {
 
 	benchmarkRandomReadsWrites(b, db)
 }
+
+func TestBoltDBOpenReadOnlyLocked(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB("db", BoltDBBackend, dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	// The writer holds the lock, so opening fails instead of waiting forever.
+	_, err = OpenReadOnly("db", BoltDBBackend, dir)
+	require.Error(t, err)
+}
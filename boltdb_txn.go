@@ -0,0 +1,118 @@
+//go:build boltdb
+// +build boltdb
+
+package db
+
+import "go.etcd.io/bbolt"
+
+// boltTxn is a native read-write bolt transaction. bolt allows a single one at
+// a time, so other writers to the database block until it is committed or
+// discarded.
+type boltTxn struct {
+	tx *bbolt.Tx // nil once committed or discarded
+}
+
+var _ Txn = (*boltTxn)(nil)
+
+// NewTxn implements Transactor. The transaction holds the bolt writer lock
+// until it ends, so it must not be used alongside writes to the database from
+// the same goroutine. Since writes invalidate bolt cursors, iterators must be
+// closed before writing to the transaction.
+func (bdb *BoltDB) NewTxn() (Txn, error) {
+	tx, err := bdb.db.Begin(true)
+	if err != nil {
+		return nil, err
+	}
+	return &boltTxn{tx: tx}, nil
+}
+
+// Get implements Txn.
+func (t *boltTxn) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	if t.tx == nil {
+		return nil, errTxnDone
+	}
+	if v := t.tx.Bucket(bucket).Get(key); v != nil {
+		return append([]byte{}, v...), nil
+	}
+	return nil, nil
+}
+
+// Has implements Txn.
+func (t *boltTxn) Has(key []byte) (bool, error) {
+	bytes, err := t.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return bytes != nil, nil
+}
+
+// Set implements Txn.
+func (t *boltTxn) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if t.tx == nil {
+		return errTxnDone
+	}
+	// bolt keeps references to them until the transaction ends.
+	return t.tx.Bucket(bucket).Put(cp(key), cp(value))
+}
+
+// Delete implements Txn.
+func (t *boltTxn) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if t.tx == nil {
+		return errTxnDone
+	}
+	return t.tx.Bucket(bucket).Delete(key)
+}
+
+// Iterator implements Txn.
+func (t *boltTxn) Iterator(start, end []byte) (Iterator, error) {
+	return t.iterator(start, end, false)
+}
+
+// ReverseIterator implements Txn.
+func (t *boltTxn) ReverseIterator(start, end []byte) (Iterator, error) {
+	return t.iterator(start, end, true)
+}
+
+func (t *boltTxn) iterator(start, end []byte, isReverse bool) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if t.tx == nil {
+		return nil, errTxnDone
+	}
+	itr := newBoltDBIterator(t.tx, start, end, isReverse)
+	itr.keepTx = true
+	return itr, nil
+}
+
+// Commit implements Txn.
+func (t *boltTxn) Commit() error {
+	if t.tx == nil {
+		return errTxnDone
+	}
+	tx := t.tx
+	t.tx = nil
+	return tx.Commit()
+}
+
+// Discard implements Txn.
+func (t *boltTxn) Discard() error {
+	if t.tx == nil {
+		return nil
+	}
+	tx := t.tx
+	t.tx = nil
+	return tx.Rollback()
+}
@@ -0,0 +1,90 @@
+package db
+
+import "sync"
+
+// maxPooledBufferSize is the capacity above which released buffers are left to
+// the garbage collector rather than pooled, so that a few large values do not
+// pin memory.
+const maxPooledBufferSize = 1 << 20
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return &Buffer{} },
+}
+
+// Buffer holds a value read with GetBuffer or CopyValue in memory taken from a
+// pool. Release gives the memory back once the value is no longer used, so
+// that reading many values, e.g. while replaying blocks, does not allocate for
+// each of them.
+type Buffer struct {
+	b []byte
+}
+
+// newBuffer returns a pooled buffer holding a copy of value.
+func newBuffer(value []byte) *Buffer {
+	buf := bufferPool.Get().(*Buffer)
+	if buf.b == nil {
+		// Empty values are non-nil, like those returned by Get.
+		buf.b = make([]byte, 0, len(value))
+	}
+	buf.b = append(buf.b[:0], value...)
+	return buf
+}
+
+// Bytes returns the value held by the buffer. It is only valid until Release
+// is called, and must not be retained after that.
+func (buf *Buffer) Bytes() []byte {
+	if buf == nil {
+		return nil
+	}
+	return buf.b
+}
+
+// Release returns the buffer to the pool. Neither the buffer nor its Bytes
+// may be used afterwards. It does nothing on a nil buffer.
+func (buf *Buffer) Release() {
+	if buf == nil || cap(buf.b) > maxPooledBufferSize {
+		return
+	}
+	buf.b = buf.b[:0]
+	bufferPool.Put(buf)
+}
+
+// GetBuffer returns the value of key in db in a pooled Buffer, or nil if it
+// does not exist. The caller must Release the buffer once done with the value.
+// If db implements UnsafeGetter the value is copied straight from the internal
+// buffers of db, without allocating, see GetUnsafe.
+func GetBuffer(db DB, key []byte) (*Buffer, error) {
+	var buf *Buffer
+	err := GetUnsafe(db, key, func(value []byte) error {
+		if value != nil {
+			buf = newBuffer(value)
+		}
+		return nil
+	})
+	return buf, err
+}
+
+// UnsafeIterator is implemented by iterators which can pass their current key
+// and value without copying them out of the internal buffers of the database.
+type UnsafeIterator interface {
+	Iterator
+
+	// KeyUnsafe returns the current key like Key, but only valid until the
+	// iterator is moved or closed. It must not be modified.
+	KeyUnsafe() []byte
+
+	// ValueUnsafe returns the current value like Value, but only valid until
+	// the iterator is moved or closed. It must not be modified.
+	ValueUnsafe() []byte
+}
+
+// CopyValue returns the current value of itr in a pooled Buffer, which the
+// caller must Release once done with the value. If itr implements
+// UnsafeIterator the value is copied straight from the internal buffers of the
+// database, without allocating.
+func CopyValue(itr Iterator) *Buffer {
+	if uitr, ok := itr.(UnsafeIterator); ok {
+		return newBuffer(uitr.ValueUnsafe())
+	}
+	return newBuffer(itr.Value())
+}
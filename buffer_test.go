@@ -0,0 +1,74 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBuffer(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			require.NoError(t, db.Set(bz("a"), bz("1")))
+			require.NoError(t, db.Set(bz("empty"), []byte{}))
+
+			buf, err := GetBuffer(db, bz("a"))
+			require.NoError(t, err)
+			assert.Equal(t, bz("1"), buf.Bytes())
+			buf.Release()
+			buf, err = GetBuffer(db, bz("empty"))
+			require.NoError(t, err)
+			assert.Equal(t, []byte{}, buf.Bytes())
+			buf.Release()
+			buf, err = GetBuffer(db, bz("missing"))
+			require.NoError(t, err)
+			assert.Nil(t, buf)
+			assert.Nil(t, buf.Bytes())
+			buf.Release()
+
+			itr, err := db.Iterator(nil, nil)
+			require.NoError(t, err)
+			defer itr.Close()
+			buf = CopyValue(itr)
+			assert.Equal(t, bz("1"), buf.Bytes())
+			buf.Release()
+		})
+	}
+}
+
+func BenchmarkPebbleDBCopyValue(b *testing.B) {
+	db, err := NewPebbleDB("pebble", b.TempDir())
+	require.NoError(b, err)
+	defer db.Close()
+	for i := int64(0); i < 1000; i++ {
+		require.NoError(b, db.Set(int642Bytes(i), make([]byte, 1024)))
+	}
+
+	scan := func(b *testing.B, read func(Iterator)) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			itr, err := db.Iterator(nil, nil)
+			require.NoError(b, err)
+			for ; itr.Valid(); itr.Next() {
+				read(itr)
+			}
+			require.NoError(b, itr.Close())
+		}
+	}
+	b.Run("Value", func(b *testing.B) {
+		scan(b, func(itr Iterator) { _ = itr.Value() })
+	})
+	b.Run("CopyValue", func(b *testing.B) {
+		scan(b, func(itr Iterator) { CopyValue(itr).Release() })
+	})
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+)
+
+// BufferedBatch is a Batch which records its operations in memory, and only
+// creates a batch of its database on write. Unlike the batches of the
+// backends, buffered batches can be merged with Append: concurrent workers can
+// each build their own batch, and combine them into a single one written
+// atomically (if the database batches are).
+//
+// A BufferedBatch is safe for concurrent use.
+type BufferedBatch struct {
+	mtx sync.Mutex
+	db  DB
+	ops []operation // nil once written or closed
+}
+
+var (
+	_ Batch        = (*BufferedBatch)(nil)
+	_ RangeDeleter = (*BufferedBatch)(nil)
+)
+
+// NewBufferedBatch creates a BufferedBatch writing to db.
+func NewBufferedBatch(db DB) *BufferedBatch {
+	return &BufferedBatch{
+		db:  db,
+		ops: []operation{},
+	}
+}
+
+// Set implements Batch.
+func (b *BufferedBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return b.append(operation{opTypeSet, key, value})
+}
+
+// Delete implements Batch.
+func (b *BufferedBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return b.append(operation{opTypeDelete, key, nil})
+}
+
+// DeleteRange implements RangeDeleter. The range is deleted with
+// BatchDeleteRange on write, so without native range deletion it only deletes
+// the keys in the database at that time.
+func (b *BufferedBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	return b.append(operation{opTypeDeleteRange, start, end})
+}
+
+func (b *BufferedBatch) append(op operation) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, op)
+	return nil
+}
+
+// Append adds the operations of other after those of b, as if they had been
+// made on b. other is left unchanged, and must still be closed. Both batches
+// must write to the same database.
+func (b *BufferedBatch) Append(other *BufferedBatch) error {
+	if other == b {
+		return fmt.Errorf("cannot append a batch to itself")
+	}
+	if other.db != b.db {
+		return fmt.Errorf("cannot append a batch of another database")
+	}
+	other.mtx.Lock()
+	ops := other.ops
+	other.mtx.Unlock()
+	if ops == nil {
+		return ErrBatchWritten
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, ops...)
+	return nil
+}
+
+// Count implements Batch.
+func (b *BufferedBatch) Count() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *BufferedBatch) SizeBytes() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return opsSize(b.ops)
+}
+
+// Write implements Batch.
+func (b *BufferedBatch) Write() error {
+	return b.write(false)
+}
+
+// WriteSync implements Batch.
+func (b *BufferedBatch) WriteSync() error {
+	return b.write(true)
+}
+
+// write applies the operations to a single batch of the database.
+func (b *BufferedBatch) write(sync bool) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+
+	batch := b.db.NewBatch()
+	defer batch.Close()
+	for _, op := range b.ops {
+		var err error
+		switch op.opType {
+		case opTypeSet:
+			err = batch.Set(op.key, op.value)
+		case opTypeDelete:
+			err = batch.Delete(op.key)
+		case opTypeDeleteRange:
+			err = BatchDeleteRange(b.db, batch, op.key, op.value)
+		default:
+			err = fmt.Errorf("unknown operation type %v (%v)", op.opType, op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	var err error
+	if sync {
+		err = batch.WriteSync()
+	} else {
+		err = batch.Write()
+	}
+	if err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	b.ops = nil
+	return nil
+}
+
+// Close implements Batch.
+func (b *BufferedBatch) Close() error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.ops = nil
+	return nil
+}
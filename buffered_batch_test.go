@@ -0,0 +1,108 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBufferedBatchAppend(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			require.NoError(t, db.Set(int642Bytes(1000), bz("old")))
+
+			// Workers build their batches concurrently.
+			const workers = 4
+			batches := make([]*BufferedBatch, workers)
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				batches[w] = NewBufferedBatch(db)
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := int64(w); i < 100; i += workers {
+						assert.NoError(t, batches[w].Set(int642Bytes(i), int642Bytes(i)))
+					}
+				}(w)
+			}
+			wg.Wait()
+
+			batch := NewBufferedBatch(db)
+			defer batch.Close()
+			require.NoError(t, batch.Delete(int642Bytes(1000)))
+			for _, b := range batches {
+				require.NoError(t, batch.Append(b))
+				require.NoError(t, b.Close())
+			}
+			assert.Equal(t, 101, batch.Count())
+			assert.Equal(t, 101*8+100*8, batch.SizeBytes())
+
+			// Nothing is written before the merged batch is.
+			checkValue(t, db, int642Bytes(1000), bz("old"))
+			checkValue(t, db, int642Bytes(0), nil)
+			require.NoError(t, batch.Write())
+			assert.Equal(t, 100, countKeys(t, db))
+			checkValue(t, db, int642Bytes(1000), nil)
+			checkValue(t, db, int642Bytes(99), int642Bytes(99))
+
+			require.Equal(t, ErrBatchWritten, batch.Set(bz("a"), bz("b")))
+			require.Equal(t, ErrBatchWritten, batch.Write())
+			assert.Zero(t, batch.Count())
+		})
+	}
+}
+
+func TestBufferedBatchOrder(t *testing.T) {
+	db := NewMemDB()
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.Set(bz("b"), bz("1")))
+
+	first := NewBufferedBatch(db)
+	require.NoError(t, first.Set(bz("c"), bz("1")))
+	second := NewBufferedBatch(db)
+	require.NoError(t, second.DeleteRange(nil, bz("c")))
+	require.NoError(t, second.Set(bz("a"), bz("2")))
+
+	// Appended operations come after those of the batch.
+	require.NoError(t, first.Append(second))
+	require.NoError(t, first.WriteSync())
+	require.NoError(t, first.Close())
+	assert.Equal(t, 2, countKeys(t, db))
+	checkValue(t, db, bz("a"), bz("2"))
+	checkValue(t, db, bz("b"), nil)
+	checkValue(t, db, bz("c"), bz("1"))
+
+	// The appended batch is unchanged.
+	assert.Equal(t, 2, second.Count())
+	require.NoError(t, second.Close())
+}
+
+func TestBufferedBatchAppendErrors(t *testing.T) {
+	db := NewMemDB()
+	batch := NewBufferedBatch(db)
+	defer batch.Close()
+
+	assert.Error(t, batch.Append(batch))
+	assert.Error(t, batch.Append(NewBufferedBatch(NewMemDB())))
+
+	closed := NewBufferedBatch(db)
+	require.NoError(t, closed.Close())
+	assert.Equal(t, ErrBatchWritten, batch.Append(closed))
+	assert.Equal(t, ErrBatchWritten, closed.Append(batch))
+
+	assert.Equal(t, errKeyEmpty, batch.Set(nil, bz("v")))
+	assert.Equal(t, errValueNil, batch.Set(bz("k"), nil))
+	assert.Equal(t, errKeyEmpty, batch.Delete([]byte{}))
+	assert.Equal(t, errKeyEmpty, batch.DeleteRange([]byte{}, nil))
+}
@@ -0,0 +1,284 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+)
+
+// CachedDB caches the values read from a database in a least-recently-used
+// cache bounded by the bytes of the cached keys and values, e.g. for the hot
+// keys repeatedly read by RPC nodes. Writes through the CachedDB, including
+// batches, invalidate the keys they write; the underlying database must
+// therefore not be written to directly. Iterators and snapshots read the
+// underlying database.
+type CachedDB struct {
+	db       DB
+	capacity int
+
+	mtx     sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // of *cacheEntry, most recently used first
+	size    int
+	gen     uint64 // incremented by every invalidation
+	hits    uint64
+	misses  uint64
+}
+
+var (
+	_ DB           = (*CachedDB)(nil)
+	_ RangeDeleter = (*CachedDB)(nil)
+)
+
+type cacheEntry struct {
+	key   string
+	value []byte
+}
+
+// NewCachedDB creates a CachedDB over db, caching up to capacity bytes. Closing
+// the CachedDB closes db.
+func NewCachedDB(db DB, capacity int) *CachedDB {
+	return &CachedDB{
+		db:       db,
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// lookup returns the cached value of key, or nil. On a miss it also returns the
+// current generation, to pass to add once the value is read.
+func (cdb *CachedDB) lookup(key []byte) ([]byte, uint64) {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+	if elem, ok := cdb.entries[string(key)]; ok {
+		cdb.hits++
+		cdb.lru.MoveToFront(elem)
+		return elem.Value.(*cacheEntry).value, 0
+	}
+	cdb.misses++
+	return nil, cdb.gen
+}
+
+// add caches the value of key read at generation gen, unless a write
+// invalidated keys since then, in which case value may be stale.
+func (cdb *CachedDB) add(key, value []byte, gen uint64) {
+	cost := len(key) + len(value)
+	if cost > cdb.capacity {
+		return
+	}
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+	if gen != cdb.gen {
+		return
+	}
+	if _, ok := cdb.entries[string(key)]; ok {
+		return
+	}
+	cdb.entries[string(key)] = cdb.lru.PushFront(&cacheEntry{key: string(key), value: value})
+	cdb.size += cost
+	for cdb.size > cdb.capacity {
+		cdb.remove(cdb.lru.Back())
+	}
+}
+
+// remove removes elem from the cache. The caller must hold mtx.
+func (cdb *CachedDB) remove(elem *list.Element) {
+	entry := cdb.lru.Remove(elem).(*cacheEntry)
+	delete(cdb.entries, entry.key)
+	cdb.size -= len(entry.key) + len(entry.value)
+}
+
+// invalidate removes keys from the cache. It is called after they are written.
+func (cdb *CachedDB) invalidate(keys ...[]byte) {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+	cdb.gen++
+	for _, key := range keys {
+		if elem, ok := cdb.entries[string(key)]; ok {
+			cdb.remove(elem)
+		}
+	}
+}
+
+// invalidateRange removes the keys in the range [start, end) from the cache.
+func (cdb *CachedDB) invalidateRange(start, end []byte) {
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+	cdb.gen++
+	for key, elem := range cdb.entries {
+		if (start == nil || key >= string(start)) && (end == nil || key < string(end)) {
+			cdb.remove(elem)
+		}
+	}
+}
+
+// Get implements DB.
+func (cdb *CachedDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	value, gen := cdb.lookup(key)
+	if value != nil {
+		return value, nil
+	}
+	value, err := cdb.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if value != nil {
+		cdb.add(key, value, gen)
+	}
+	return value, nil
+}
+
+// Has implements DB.
+func (cdb *CachedDB) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	cdb.mtx.Lock()
+	_, ok := cdb.entries[string(key)]
+	cdb.mtx.Unlock()
+	if ok {
+		return true, nil
+	}
+	return cdb.db.Has(key)
+}
+
+// Set implements DB.
+func (cdb *CachedDB) Set(key []byte, value []byte) error {
+	defer cdb.invalidate(key)
+	return cdb.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (cdb *CachedDB) SetSync(key []byte, value []byte) error {
+	defer cdb.invalidate(key)
+	return cdb.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (cdb *CachedDB) Delete(key []byte) error {
+	defer cdb.invalidate(key)
+	return cdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (cdb *CachedDB) DeleteSync(key []byte) error {
+	defer cdb.invalidate(key)
+	return cdb.db.DeleteSync(key)
+}
+
+// DeleteRange implements RangeDeleter, with the range deletion of the
+// underlying database.
+func (cdb *CachedDB) DeleteRange(start, end []byte) error {
+	defer cdb.invalidateRange(start, end)
+	return DeleteRange(cdb.db, start, end)
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database.
+func (cdb *CachedDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	defer cdb.invalidate(key)
+	return CompareAndSwap(cdb.db, key, old, new)
+}
+
+// Iterator implements DB.
+func (cdb *CachedDB) Iterator(start, end []byte) (Iterator, error) {
+	return cdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (cdb *CachedDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return cdb.db.ReverseIterator(start, end)
+}
+
+// Close implements DB.
+func (cdb *CachedDB) Close() error {
+	cdb.invalidateRange(nil, nil)
+	return cdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (cdb *CachedDB) NewBatch() Batch {
+	return newCachedDBBatch(cdb)
+}
+
+// Print implements DB.
+func (cdb *CachedDB) Print() error {
+	return cdb.db.Print()
+}
+
+// Stats implements DB.
+func (cdb *CachedDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range cdb.db.Stats() {
+		stats["cache.db."+k] = v
+	}
+	cdb.mtx.Lock()
+	defer cdb.mtx.Unlock()
+	stats["cache.entries"] = strconv.Itoa(len(cdb.entries))
+	stats["cache.size"] = strconv.Itoa(cdb.size)
+	stats["cache.capacity"] = strconv.Itoa(cdb.capacity)
+	stats["cache.hits"] = strconv.FormatUint(cdb.hits, 10)
+	stats["cache.misses"] = strconv.FormatUint(cdb.misses, 10)
+	return stats
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+func (cdb *CachedDB) Snapshot() (DBReader, error) {
+	return Snapshot(cdb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (cdb *CachedDB) Metrics() (Metrics, error) {
+	return GetMetrics(cdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (cdb *CachedDB) EstimateCount() (uint64, error) {
+	return EstimateCount(cdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (cdb *CachedDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(cdb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (cdb *CachedDB) Compact(start, end []byte) error {
+	return Compact(cdb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (cdb *CachedDB) Flush() error {
+	return Flush(cdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (cdb *CachedDB) Sync() error {
+	return Sync(cdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+func (cdb *CachedDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, cdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (cdb *CachedDB) Checkpoint(path string) error {
+	return Checkpoint(cdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (cdb *CachedDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, cdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which CachedDB passes through.
+func (cdb *CachedDB) Capabilities() Capability {
+	return Capabilities(cdb.db) & (CapSnapshot | CapDeleteRange | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
@@ -0,0 +1,64 @@
+package db
+
+// cachedDBBatch wraps a batch of the underlying database, remembering the keys
+// written so that they are invalidated when the batch is written.
+type cachedDBBatch struct {
+	db     *CachedDB
+	source Batch
+	keys   [][]byte
+}
+
+var _ Batch = (*cachedDBBatch)(nil)
+
+func newCachedDBBatch(db *CachedDB) *cachedDBBatch {
+	return &cachedDBBatch{
+		db:     db,
+		source: db.db.NewBatch(),
+	}
+}
+
+// Set implements Batch.
+func (b *cachedDBBatch) Set(key, value []byte) error {
+	if err := b.source.Set(key, value); err != nil {
+		return err
+	}
+	b.keys = append(b.keys, cp(key))
+	return nil
+}
+
+// Delete implements Batch.
+func (b *cachedDBBatch) Delete(key []byte) error {
+	if err := b.source.Delete(key); err != nil {
+		return err
+	}
+	b.keys = append(b.keys, cp(key))
+	return nil
+}
+
+// Count implements Batch.
+func (b *cachedDBBatch) Count() int {
+	return b.source.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *cachedDBBatch) SizeBytes() int {
+	return b.source.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *cachedDBBatch) Write() error {
+	defer b.db.invalidate(b.keys...)
+	return b.source.Write()
+}
+
+// WriteSync implements Batch.
+func (b *cachedDBBatch) WriteSync() error {
+	defer b.db.invalidate(b.keys...)
+	return b.source.WriteSync()
+}
+
+// Close implements Batch.
+func (b *cachedDBBatch) Close() error {
+	b.keys = nil
+	return b.source.Close()
+}
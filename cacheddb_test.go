@@ -0,0 +1,95 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedDB(t *testing.T) {
+	source := NewMemDB()
+	cdb := NewCachedDB(source, 1024)
+	defer cdb.Close()
+
+	require.NoError(t, source.Set(bz("a"), bz("1")))
+	checkValue(t, cdb, bz("a"), bz("1"))
+	checkValue(t, cdb, bz("missing"), nil)
+
+	// Cached values are served without reading the underlying database.
+	require.NoError(t, source.Set(bz("a"), bz("stale")))
+	checkValue(t, cdb, bz("a"), bz("1"))
+	stats := cdb.Stats()
+	assert.Equal(t, "1", stats["cache.hits"])
+	assert.Equal(t, "2", stats["cache.misses"])
+	assert.Equal(t, "1", stats["cache.entries"])
+
+	// Writes invalidate the cache.
+	require.NoError(t, cdb.Set(bz("a"), bz("2")))
+	checkValue(t, cdb, bz("a"), bz("2"))
+	require.NoError(t, cdb.Delete(bz("a")))
+	checkValue(t, cdb, bz("a"), nil)
+
+	require.NoError(t, cdb.Set(bz("b"), bz("1")))
+	checkValue(t, cdb, bz("b"), bz("1"))
+	batch := cdb.NewBatch()
+	require.NoError(t, batch.Set(bz("b"), bz("2")))
+	checkValue(t, cdb, bz("b"), bz("1"))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	checkValue(t, cdb, bz("b"), bz("2"))
+
+	require.NoError(t, DeleteRange(cdb, bz("a"), bz("c")))
+	checkValue(t, cdb, bz("b"), nil)
+
+	swapped, err := CompareAndSwap(cdb, bz("c"), nil, bz("1"))
+	require.NoError(t, err)
+	require.True(t, swapped)
+	checkValue(t, cdb, bz("c"), bz("1"))
+	swapped, err = CompareAndSwap(cdb, bz("c"), bz("1"), bz("2"))
+	require.NoError(t, err)
+	require.True(t, swapped)
+	checkValue(t, cdb, bz("c"), bz("2"))
+}
+
+func TestCachedDBEviction(t *testing.T) {
+	source := NewMemDB()
+	// Room for three entries of a one-byte key and a one-byte value.
+	cdb := NewCachedDB(source, 6)
+	defer cdb.Close()
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, source.Set(bz(key), bz("1")))
+	}
+	checkValue(t, cdb, bz("a"), bz("1"))
+	checkValue(t, cdb, bz("b"), bz("1"))
+	checkValue(t, cdb, bz("c"), bz("1"))
+	checkValue(t, cdb, bz("a"), bz("1")) // a is now the most recently used
+	checkValue(t, cdb, bz("d"), bz("1")) // evicts b
+
+	stats := cdb.Stats()
+	assert.Equal(t, "3", stats["cache.entries"])
+	assert.Equal(t, "6", stats["cache.size"])
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, source.Set(bz(key), bz("2")))
+	}
+	checkValue(t, cdb, bz("a"), bz("1"))
+	checkValue(t, cdb, bz("b"), bz("2"))
+
+	// Values larger than the cache are not cached.
+	require.NoError(t, source.Set(bz("large"), bz("0123456789")))
+	checkValue(t, cdb, bz("large"), bz("0123456789"))
+	assert.Equal(t, "3", cdb.Stats()["cache.entries"])
+}
+
+func TestCachedDBStaleRead(t *testing.T) {
+	cdb := NewCachedDB(NewMemDB(), 1024)
+	defer cdb.Close()
+	require.NoError(t, cdb.Set(bz("a"), bz("1")))
+
+	// A value read before a write is not cached once the write invalidated it.
+	_, gen := cdb.lookup(bz("a"))
+	require.NoError(t, cdb.Set(bz("a"), bz("2")))
+	cdb.add(bz("a"), bz("1"), gen)
+	checkValue(t, cdb, bz("a"), bz("2"))
+}
@@ -0,0 +1,143 @@
+package db
+
+import "strings"
+
+// Capability is a set of optional features supported by a database, see
+// Capabilities.
+type Capability uint32
+
+const (
+	// CapSnapshot is set if the database implements Snapshotter.
+	CapSnapshot Capability = 1 << iota
+	// CapDeleteRange is set if the database deletes ranges natively, so that
+	// DeleteRange does not need to iterate over the keys.
+	CapDeleteRange
+	// CapTTL is set if the database implements TTLSetter.
+	CapTTL
+	// CapCompact is set if the database implements Compacter.
+	CapCompact
+	// CapTxn is set if the database has native transactions, so that NewTxn
+	// does not need to emulate them.
+	CapTxn
+	// CapFlush is set if the database implements Flusher.
+	CapFlush
+	// CapSync is set if the database implements Syncer.
+	CapSync
+	// CapBackup is set if the database implements Backupper.
+	CapBackup
+	// CapCheckpoint is set if the database implements Checkpointer.
+	CapCheckpoint
+	// CapGetUnsafe is set if the database reads values without copying them,
+	// so that GetUnsafe does not fall back to Get.
+	CapGetUnsafe
+	// CapIngest is set if the database implements Ingester.
+	CapIngest
+)
+
+var capabilityNames = []struct {
+	c    Capability
+	name string
+}{
+	{CapSnapshot, "snapshot"},
+	{CapDeleteRange, "deleterange"},
+	{CapTTL, "ttl"},
+	{CapCompact, "compact"},
+	{CapTxn, "txn"},
+	{CapFlush, "flush"},
+	{CapSync, "sync"},
+	{CapBackup, "backup"},
+	{CapCheckpoint, "checkpoint"},
+	{CapGetUnsafe, "getunsafe"},
+	{CapIngest, "ingest"},
+}
+
+// Has reports whether all the capabilities in o are in c.
+func (c Capability) Has(o Capability) bool {
+	return c&o == o
+}
+
+// String returns the names of the capabilities in c separated by "|", e.g.
+// "snapshot|compact", or "none" if c is empty.
+func (c Capability) String() string {
+	names := []string{}
+	for _, cn := range capabilityNames {
+		if c.Has(cn.c) {
+			names = append(names, cn.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// CapabilityReporter is implemented by databases whose support for optional
+// features is not apparent from the interfaces they implement, typically
+// wrappers which implement them only when the wrapped databases do.
+type CapabilityReporter interface {
+	// Capabilities returns the optional features supported by the database.
+	Capabilities() Capability
+}
+
+// Capabilities returns the optional features supported by db, so that generic
+// code can choose a fast path up front instead of handling ErrNotSupported. It
+// uses the report of db if it implements CapabilityReporter, and otherwise
+// checks which of the optional interfaces db implements.
+func Capabilities(db DB) Capability {
+	if r, ok := db.(CapabilityReporter); ok {
+		return r.Capabilities()
+	}
+	var c Capability
+	if _, ok := db.(Snapshotter); ok {
+		c |= CapSnapshot
+	}
+	if _, ok := db.(RangeDeleter); ok {
+		c |= CapDeleteRange
+	}
+	if _, ok := db.(TTLSetter); ok {
+		c |= CapTTL
+	}
+	if _, ok := db.(Compacter); ok {
+		c |= CapCompact
+	}
+	if _, ok := db.(Transactor); ok {
+		c |= CapTxn
+	}
+	if _, ok := db.(Flusher); ok {
+		c |= CapFlush
+	}
+	if _, ok := db.(Syncer); ok {
+		c |= CapSync
+	}
+	if _, ok := db.(Backupper); ok {
+		c |= CapBackup
+	}
+	if _, ok := db.(Checkpointer); ok {
+		c |= CapCheckpoint
+	}
+	if _, ok := db.(UnsafeGetter); ok {
+		c |= CapGetUnsafe
+	}
+	if _, ok := db.(Ingester); ok {
+		c |= CapIngest
+	}
+	return c
+}
+
+// capabilitiesAll returns the capabilities supported by all dbs.
+func capabilitiesAll(dbs []DB) Capability {
+	c := ^Capability(0)
+	for _, db := range dbs {
+		c &= Capabilities(db)
+	}
+	return c
+}
+
+// capabilitiesAny returns the capabilities supported by any of dbs.
+func capabilitiesAny(dbs []DB) Capability {
+	var c Capability
+	for _, db := range dbs {
+		c |= Capabilities(db)
+	}
+	return c
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			// Every reported capability must actually be supported.
+			caps := Capabilities(db)
+			if caps.Has(CapSnapshot) {
+				snap, err := Snapshot(db)
+				require.NoError(t, err)
+				require.NoError(t, snap.Close())
+			}
+			if caps.Has(CapCompact) {
+				require.NoError(t, Compact(db, nil, nil))
+			}
+			if caps.Has(CapTTL) {
+				require.NoError(t, SetWithTTL(db, bz("a"), bz("1"), time.Hour))
+			}
+			if caps.Has(CapFlush) {
+				require.NoError(t, Flush(db))
+			}
+			if caps.Has(CapSync) {
+				require.NoError(t, Sync(db))
+			}
+			if caps.Has(CapIngest) {
+				path := filepath.Join(t.TempDir(), "ingest.sst")
+				w, err := NewSSTWriter(db, path)
+				require.NoError(t, err)
+				require.NoError(t, w.Set(bz("a"), bz("1")))
+				require.NoError(t, w.Finish())
+				require.NoError(t, IngestExternalFiles(db, []string{path}))
+			}
+		})
+	}
+}
+
+func TestCapabilitiesWrappers(t *testing.T) {
+	dir, err := os.MkdirTemp("", "capabilities_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	pdb, err := NewPebbleDB("pebble", dir)
+	require.NoError(t, err)
+	defer pdb.Close()
+
+	pebbleCaps := CapSnapshot | CapDeleteRange | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint | CapGetUnsafe
+	memCaps := CapSnapshot | CapDeleteRange
+	assert.Equal(t, pebbleCaps|CapIngest, Capabilities(pdb))
+	assert.Equal(t, memCaps, Capabilities(NewMemDB()))
+
+	testCases := map[string]struct {
+		db   DB
+		caps Capability
+	}{
+		"prefixdb":      {NewPrefixDB(pdb, bz("p")), pebbleCaps},
+		"prefixdb_ttl":  {NewPrefixDB(NewTTLDB(NewMemDB(), 0), bz("p")), CapTTL},
+		"faultdb":       {NewFaultDB(NewMemDB()), 0},
+		"readreplicadb": {NewReadReplicaDB(pdb, NewMemDB()), CapDeleteRange | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint},
+		"shardeddb":     {mustShardedDB(t, pdb, NewMemDB()), CapDeleteRange | CapCompact},
+		"tiereddb":      {NewTieredDB(NewMemDB(), pdb, TierPolicy{}), CapCompact},
+		"overlaydb":     {NewOverlayDB(pdb), CapCompact},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.caps, Capabilities(tc.db))
+		})
+	}
+}
+
+func mustShardedDB(t *testing.T, shards ...DB) *ShardedDB {
+	sdb, err := NewShardedDB(shards, HashSharding)
+	require.NoError(t, err)
+	return sdb
+}
+
+func TestCapabilityString(t *testing.T) {
+	assert.Equal(t, "none", Capability(0).String())
+	assert.Equal(t, "snapshot|compact", (CapCompact | CapSnapshot).String())
+	assert.True(t, (CapTxn | CapTTL).Has(CapTTL))
+	assert.False(t, CapTTL.Has(CapTxn|CapTTL))
+}
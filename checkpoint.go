@@ -0,0 +1,30 @@
+package db
+
+// Checkpointer is implemented by databases which can create on-disk
+// checkpoints of themselves while in use, typically by hard linking their
+// immutable files, which makes checkpoints almost instant and initially free
+// of disk space.
+type Checkpointer interface {
+	// Checkpoint creates a consistent checkpoint of the database as of the
+	// call in the directory path, which must not exist. The checkpoint is a
+	// database of the same backend, opened like the database itself: e.g. a
+	// checkpoint at dir/name.db is opened by NewDB(name, backend, dir). Hard
+	// links are only possible when path is on the same filesystem as the
+	// database, and files are copied otherwise.
+	//
+	// A hard linked file keeps using disk space as long as either the database
+	// or the checkpoint references it, so checkpoints should be removed once
+	// they are no longer needed.
+	Checkpoint(path string) error
+}
+
+// Checkpoint creates an on-disk checkpoint of db at path, see Checkpointer. It
+// returns ErrNotSupported if db does not implement Checkpointer, in which case
+// Backup may be used instead.
+func Checkpoint(db DB, path string) error {
+	c, ok := db.(Checkpointer)
+	if !ok {
+		return ErrNotSupported
+	}
+	return c.Checkpoint(path)
+}
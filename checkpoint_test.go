@@ -0,0 +1,84 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			for i := int64(0); i < 100; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+			cpDir, err := os.MkdirTemp("", "checkpoint_test")
+			require.NoError(t, err)
+			defer os.RemoveAll(cpDir)
+			err = Checkpoint(db, filepath.Join(cpDir, name+".db"))
+			if errors.Is(err, ErrNotSupported) {
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+			require.NoError(t, db.Set(int642Bytes(100), int642Bytes(100)))
+
+			cp, err := NewDB(name, dbType, cpDir)
+			require.NoError(t, err)
+			defer cp.Close()
+			itr, err := cp.Iterator(nil, nil)
+			require.NoError(t, err)
+			for i := int64(0); i < 100; i++ {
+				checkValid(t, itr, true)
+				checkItem(t, itr, int642Bytes(i), int642Bytes(i))
+				itr.Next()
+			}
+			checkValid(t, itr, false)
+			require.NoError(t, itr.Close())
+		})
+	}
+}
+
+func TestPebbleDBCheckpointHardLinks(t *testing.T) {
+	dir, err := os.MkdirTemp("", "checkpoint_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := NewPebbleDB("pebble", dir)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.Flush())
+
+	path := filepath.Join(dir, "checkpoint.db")
+	require.NoError(t, db.Checkpoint(path))
+	assert.Error(t, db.Checkpoint(path))
+
+	// The sstables of the checkpoint are the files of the database.
+	entries, err := os.ReadDir(path)
+	require.NoError(t, err)
+	linked := 0
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".sst") {
+			continue
+		}
+		cpInfo, err := os.Stat(filepath.Join(path, entry.Name()))
+		require.NoError(t, err)
+		info, err := os.Stat(filepath.Join(dir, "pebble.db", entry.Name()))
+		require.NoError(t, err)
+		assert.True(t, os.SameFile(info, cpInfo))
+		linked++
+	}
+	assert.Positive(t, linked)
+}
@@ -0,0 +1,240 @@
+package db
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// checksumSize is the size of the checksum appended to the values of a
+// ChecksumDB.
+const checksumSize = 4
+
+var checksumCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ChecksumError is returned by a ChecksumDB for a value whose checksum does not
+// match. It wraps ErrCorrupted.
+type ChecksumError struct {
+	Key      []byte
+	Expected uint32 // the stored checksum
+	Actual   uint32 // the checksum of the stored key and value
+}
+
+// Error implements error.
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("%v: checksum mismatch for key %X: expected %08x, got %08x", ErrCorrupted, e.Key, e.Expected, e.Actual)
+}
+
+// Unwrap returns ErrCorrupted.
+func (e *ChecksumError) Unwrap() error {
+	return ErrCorrupted
+}
+
+// ChecksumDB appends a CRC-32C checksum of the key and value to every value of
+// a database, and verifies it when the value is read, so that values corrupted
+// anywhere after they were written, such as by bit rot on the disk or in the
+// caches of the backend, are reported as a *ChecksumError rather than returned.
+// Covering the key also detects values read from the wrong key.
+//
+// The underlying database must only be used through the ChecksumDB.
+type ChecksumDB struct {
+	db DB
+}
+
+var _ DB = (*ChecksumDB)(nil)
+
+// NewChecksumDB wraps db, checksumming its values. Closing the ChecksumDB
+// closes db.
+func NewChecksumDB(db DB) *ChecksumDB {
+	return &ChecksumDB{db: db}
+}
+
+// checksum returns the checksum of key and value.
+func checksum(key, value []byte) uint32 {
+	return crc32.Update(crc32.Checksum(key, checksumCRCTable), checksumCRCTable, value)
+}
+
+// checksumEncode returns the stored form of the value of key: the value
+// followed by the big-endian checksum.
+func checksumEncode(key, value []byte) []byte {
+	item := make([]byte, len(value), len(value)+checksumSize)
+	copy(item, value)
+	return binary.BigEndian.AppendUint32(item, checksum(key, value))
+}
+
+// checksumDecode returns the value of key stored as item, after verifying it.
+func checksumDecode(key, item []byte) ([]byte, error) {
+	if len(item) < checksumSize {
+		return nil, fmt.Errorf("%w: value of key %X without checksum", ErrCorrupted, key)
+	}
+	value := item[:len(item)-checksumSize]
+	expected := binary.BigEndian.Uint32(item[len(value):])
+	if actual := checksum(key, value); actual != expected {
+		return nil, &ChecksumError{Key: cp(key), Expected: expected, Actual: actual}
+	}
+	return value, nil
+}
+
+// Get implements DB.
+func (cdb *ChecksumDB) Get(key []byte) ([]byte, error) {
+	item, err := cdb.db.Get(key)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	return checksumDecode(key, item)
+}
+
+// Has implements DB. The value is not verified.
+func (cdb *ChecksumDB) Has(key []byte) (bool, error) {
+	return cdb.db.Has(key)
+}
+
+// Set implements DB.
+func (cdb *ChecksumDB) Set(key []byte, value []byte) error {
+	if value == nil {
+		return errValueNil
+	}
+	return cdb.db.Set(key, checksumEncode(key, value))
+}
+
+// SetSync implements DB.
+func (cdb *ChecksumDB) SetSync(key []byte, value []byte) error {
+	if value == nil {
+		return errValueNil
+	}
+	return cdb.db.SetSync(key, checksumEncode(key, value))
+}
+
+// Delete implements DB.
+func (cdb *ChecksumDB) Delete(key []byte) error {
+	return cdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (cdb *ChecksumDB) DeleteSync(key []byte) error {
+	return cdb.db.DeleteSync(key)
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database. A corrupted current value does not match old.
+func (cdb *ChecksumDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	if new == nil {
+		return false, errValueNil
+	}
+	var stored []byte
+	if old != nil {
+		stored = checksumEncode(key, old)
+	}
+	return CompareAndSwap(cdb.db, key, stored, checksumEncode(key, new))
+}
+
+// Iterator implements DB.
+func (cdb *ChecksumDB) Iterator(start, end []byte) (Iterator, error) {
+	itr, err := cdb.db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newChecksumDBIterator(itr), nil
+}
+
+// ReverseIterator implements DB.
+func (cdb *ChecksumDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	itr, err := cdb.db.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newChecksumDBIterator(itr), nil
+}
+
+// Close implements DB.
+func (cdb *ChecksumDB) Close() error {
+	return cdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (cdb *ChecksumDB) NewBatch() Batch {
+	return newChecksumDBBatch(cdb)
+}
+
+// Print implements DB.
+func (cdb *ChecksumDB) Print() error {
+	itr, err := cdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB.
+func (cdb *ChecksumDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range cdb.db.Stats() {
+		stats["checksum.db."+k] = v
+	}
+	return stats
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+// The sizes include the checksums.
+func (cdb *ChecksumDB) Metrics() (Metrics, error) {
+	return GetMetrics(cdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (cdb *ChecksumDB) EstimateCount() (uint64, error) {
+	return EstimateCount(cdb.db)
+}
+
+// EstimateSize implements SizeEstimator, with the size of the checksums.
+func (cdb *ChecksumDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(cdb.db, start, end)
+}
+
+// DeleteRange implements RangeDeleter, with the range deletion of the
+// underlying database.
+func (cdb *ChecksumDB) DeleteRange(start, end []byte) error {
+	return DeleteRange(cdb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (cdb *ChecksumDB) Compact(start, end []byte) error {
+	return Compact(cdb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (cdb *ChecksumDB) Flush() error {
+	return Flush(cdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (cdb *ChecksumDB) Sync() error {
+	return Sync(cdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it. The
+// backup holds the checksums.
+func (cdb *ChecksumDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, cdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+// The checkpoint holds the checksums.
+func (cdb *ChecksumDB) Checkpoint(path string) error {
+	return Checkpoint(cdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (cdb *ChecksumDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, cdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which ChecksumDB passes through.
+func (cdb *ChecksumDB) Capabilities() Capability {
+	return Capabilities(cdb.db) & (CapDeleteRange | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
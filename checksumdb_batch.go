@@ -0,0 +1,53 @@
+package db
+
+// checksumDBBatch checksums the values set in a batch of the underlying
+// database.
+type checksumDBBatch struct {
+	source Batch
+}
+
+var _ Batch = (*checksumDBBatch)(nil)
+
+func newChecksumDBBatch(db *ChecksumDB) *checksumDBBatch {
+	return &checksumDBBatch{
+		source: db.db.NewBatch(),
+	}
+}
+
+// Set implements Batch.
+func (b *checksumDBBatch) Set(key, value []byte) error {
+	if value == nil {
+		return errValueNil
+	}
+	return b.source.Set(key, checksumEncode(key, value))
+}
+
+// Delete implements Batch.
+func (b *checksumDBBatch) Delete(key []byte) error {
+	return b.source.Delete(key)
+}
+
+// Count implements Batch.
+func (b *checksumDBBatch) Count() int {
+	return b.source.Count()
+}
+
+// SizeBytes implements Batch, with the size of the checksums.
+func (b *checksumDBBatch) SizeBytes() int {
+	return b.source.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *checksumDBBatch) Write() error {
+	return b.source.Write()
+}
+
+// WriteSync implements Batch.
+func (b *checksumDBBatch) WriteSync() error {
+	return b.source.WriteSync()
+}
+
+// Close implements Batch.
+func (b *checksumDBBatch) Close() error {
+	return b.source.Close()
+}
@@ -0,0 +1,74 @@
+package db
+
+// checksumDBIterator verifies the values of an iterator of the underlying
+// database. If a value does not match its checksum, the iterator becomes
+// invalid and Error returns the error.
+type checksumDBIterator struct {
+	source Iterator
+	value  []byte
+	err    error
+}
+
+var _ Iterator = (*checksumDBIterator)(nil)
+
+func newChecksumDBIterator(source Iterator) *checksumDBIterator {
+	itr := &checksumDBIterator{source: source}
+	itr.verifyValue()
+	return itr
+}
+
+// verifyValue verifies the value of the item the iterator landed on.
+func (itr *checksumDBIterator) verifyValue() {
+	itr.value = nil
+	if itr.source.Valid() {
+		itr.value, itr.err = checksumDecode(itr.source.Key(), itr.source.Value())
+	}
+}
+
+// Domain implements Iterator.
+func (itr *checksumDBIterator) Domain() ([]byte, []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *checksumDBIterator) Valid() bool {
+	return itr.err == nil && itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *checksumDBIterator) Next() {
+	itr.assertIsValid()
+	itr.source.Next()
+	itr.verifyValue()
+}
+
+// Key implements Iterator.
+func (itr *checksumDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *checksumDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.value
+}
+
+// Error implements Iterator.
+func (itr *checksumDBIterator) Error() error {
+	if itr.err != nil {
+		return itr.err
+	}
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *checksumDBIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *checksumDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksumDB(t *testing.T) {
+	mem := NewMemDB()
+	cdb := NewChecksumDB(mem)
+	defer cdb.Close()
+
+	require.NoError(t, cdb.Set(bz("a"), bz("1")))
+	require.NoError(t, cdb.SetSync(bz("b"), []byte{}))
+	batch := cdb.NewBatch()
+	require.NoError(t, batch.Set(bz("c"), bz("3")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	checkValue(t, cdb, bz("a"), bz("1"))
+	checkValue(t, cdb, bz("b"), []byte{})
+	checkValue(t, cdb, bz("c"), bz("3"))
+	checkValue(t, cdb, bz("d"), nil)
+	stored, err := mem.Get(bz("a"))
+	require.NoError(t, err)
+	assert.Len(t, stored, 1+checksumSize)
+
+	swapped, err := cdb.CompareAndSwap(bz("a"), bz("1"), bz("2"))
+	require.NoError(t, err)
+	assert.True(t, swapped)
+	swapped, err = cdb.CompareAndSwap(bz("a"), bz("1"), bz("3"))
+	require.NoError(t, err)
+	assert.False(t, swapped)
+	checkValue(t, cdb, bz("a"), bz("2"))
+
+	// A flipped bit is reported, by reads and iterators.
+	stored, err = mem.Get(bz("b"))
+	require.NoError(t, err)
+	stored = cp(stored)
+	stored[0] ^= 0x01
+	require.NoError(t, mem.Set(bz("b"), stored))
+	_, err = cdb.Get(bz("b"))
+	assert.ErrorIs(t, err, ErrCorrupted)
+	var cerr *ChecksumError
+	require.True(t, errors.As(err, &cerr))
+	assert.Equal(t, bz("b"), cerr.Key)
+	assert.NotEqual(t, cerr.Expected, cerr.Actual)
+
+	itr, err := cdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("a"), bz("2"))
+	itr.Next()
+	checkInvalid(t, itr)
+	assert.ErrorIs(t, itr.Error(), ErrCorrupted)
+	require.NoError(t, itr.Close())
+
+	// Values moved to another key are detected, as are truncated ones.
+	stored, err = mem.Get(bz("c"))
+	require.NoError(t, err)
+	require.NoError(t, mem.Set(bz("d"), stored))
+	_, err = cdb.Get(bz("d"))
+	assert.ErrorIs(t, err, ErrCorrupted)
+	require.NoError(t, mem.Set(bz("e"), bz("x")))
+	_, err = cdb.Get(bz("e"))
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
@@ -4,17 +4,53 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/jmhodges/levigo"
 )
 
 func init() {
-	dbCreator := func(name string, dir string) (DB, error) {
-		return NewCLevelDB(name, dir)
+	registerDBCreator(CLevelDBBackend, newCLevelDBFromOptions, false)
+}
+
+// newCLevelDBFromOptions sets the block cache size and synced writes. LevelDB
+// cannot open a database read-only.
+func newCLevelDBFromOptions(name, dir string, opts *Options) (DB, error) {
+	switch {
+	case opts.ReadOnly:
+		return nil, errOption(CLevelDBBackend, "ReadOnly")
+	case opts.InMemory:
+		return nil, errOption(CLevelDBBackend, "InMemory")
+	case opts.FS != nil:
+		return nil, errOption(CLevelDBBackend, "FS")
+	case opts.SharedCache != nil:
+		return nil, errOption(CLevelDBBackend, "SharedCache")
+	case opts.BackendOptions != nil:
+		return nil, errBackendOptions(CLevelDBBackend, opts.BackendOptions)
+	}
+	cacheSize := 1 << 30
+	if opts.CacheSize > 0 {
+		cacheSize = int(opts.CacheSize)
+	}
+	db, err := newCLevelDB(name, dir, cacheSize, opts.SyncWrites)
+	if err = cLevelDBError(err); errors.Is(err, ErrCorrupted) && opts.Recover {
+		cause := err
+		if err = repairCLevelDB(name, dir); err != nil {
+			return nil, fmt.Errorf("failed to recover from %v: %w", cause, err)
+		}
+		if db, err = newCLevelDB(name, dir, cacheSize, opts.SyncWrites); err == nil {
+			reportRecovery(name, opts, RecoveryInfo{Err: cause})
+		}
+	}
+	if err != nil {
+		return nil, err
 	}
-	registerDBCreator(CLevelDBBackend, dbCreator, false)
+	db.iters = newIteratorTracker(name, opts)
+	db.lock = opts.lock
+	return db, nil
 }
 
 // CLevelDB uses the C LevelDB database via a Go wrapper.
@@ -23,16 +59,27 @@ type CLevelDB struct {
 	ro     *levigo.ReadOptions
 	wo     *levigo.WriteOptions
 	woSync *levigo.WriteOptions
+	iters  *iteratorTracker
+	lock   *dbLock
+	ops    *opTracker
 }
 
 var _ DB = (*CLevelDB)(nil)
 
+// errCLevelDBClosed is returned by the operations on a closed CLevelDB, which
+// would otherwise use the memory freed by Close.
+var errCLevelDBClosed = fmt.Errorf("cleveldb: %w", ErrClosed)
+
 // NewCLevelDB creates a new CLevelDB.
 func NewCLevelDB(name string, dir string) (*CLevelDB, error) {
+	return newCLevelDB(name, dir, 1<<30, false)
+}
+
+func newCLevelDB(name string, dir string, cacheSize int, syncWrites bool) (*CLevelDB, error) {
 	dbPath := filepath.Join(dir, name+".db")
 
 	opts := levigo.NewOptions()
-	opts.SetCache(levigo.NewLRUCache(1 << 30))
+	opts.SetCache(levigo.NewLRUCache(cacheSize))
 	opts.SetCreateIfMissing(true)
 	db, err := levigo.Open(dbPath, opts)
 	if err != nil {
@@ -40,6 +87,7 @@ func NewCLevelDB(name string, dir string) (*CLevelDB, error) {
 	}
 	ro := levigo.NewReadOptions()
 	wo := levigo.NewWriteOptions()
+	wo.SetSync(syncWrites)
 	woSync := levigo.NewWriteOptions()
 	woSync.SetSync(true)
 	database := &CLevelDB{
@@ -47,18 +95,59 @@ func NewCLevelDB(name string, dir string) (*CLevelDB, error) {
 		ro:     ro,
 		wo:     wo,
 		woSync: woSync,
+		ops:    newOpTracker(),
 	}
 	return database, nil
 }
 
+// repairCLevelDB repairs the database name in dir, which must not be open,
+// dropping what cannot be read.
+func repairCLevelDB(name, dir string) error {
+	opts := levigo.NewOptions()
+	defer opts.Close()
+	return cLevelDBError(levigo.RepairDatabase(filepath.Join(dir, name+".db"), opts))
+}
+
+// cLevelDBError wraps the LevelDB errors with the shared error kinds. LevelDB
+// only returns status messages, so they are matched by their prefix.
+func cLevelDBError(err error) error {
+	if err != nil && strings.HasPrefix(err.Error(), "Corruption:") {
+		return wrapError(ErrCorrupted, err)
+	}
+	return err
+}
+
+// readOptions returns the LevelDB read options for opts, and the function
+// releasing them.
+func (db *CLevelDB) readOptions(opts ReadOptions) (*levigo.ReadOptions, func()) {
+	if opts == (ReadOptions{}) {
+		return db.ro, func() {}
+	}
+	ro := levigo.NewReadOptions()
+	ro.SetFillCache(!opts.DontFillCache)
+	ro.SetVerifyChecksums(opts.VerifyChecksums)
+	return ro, ro.Close
+}
+
 // Get implements DB.
 func (db *CLevelDB) Get(key []byte) ([]byte, error) {
+	return db.GetWithOptions(key, ReadOptions{})
+}
+
+// GetWithOptions implements ReadOptionsDB.
+func (db *CLevelDB) GetWithOptions(key []byte, opts ReadOptions) ([]byte, error) {
 	if len(key) == 0 {
 		return nil, errKeyEmpty
 	}
-	res, err := db.db.Get(db.ro, key)
+	if !db.ops.begin() {
+		return nil, errCLevelDBClosed
+	}
+	defer db.ops.end()
+	ro, release := db.readOptions(opts)
+	defer release()
+	res, err := db.db.Get(ro, key)
 	if err != nil {
-		return nil, err
+		return nil, cLevelDBError(err)
 	}
 	return res, nil
 }
@@ -80,8 +169,12 @@ func (db *CLevelDB) Set(key []byte, value []byte) error {
 	if value == nil {
 		return errValueNil
 	}
+	if !db.ops.begin() {
+		return errCLevelDBClosed
+	}
+	defer db.ops.end()
 	if err := db.db.Put(db.wo, key, value); err != nil {
-		return err
+		return cLevelDBError(err)
 	}
 	return nil
 }
@@ -94,8 +187,12 @@ func (db *CLevelDB) SetSync(key []byte, value []byte) error {
 	if value == nil {
 		return errValueNil
 	}
+	if !db.ops.begin() {
+		return errCLevelDBClosed
+	}
+	defer db.ops.end()
 	if err := db.db.Put(db.woSync, key, value); err != nil {
-		return err
+		return cLevelDBError(err)
 	}
 	return nil
 }
@@ -105,8 +202,12 @@ func (db *CLevelDB) Delete(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
+	if !db.ops.begin() {
+		return errCLevelDBClosed
+	}
+	defer db.ops.end()
 	if err := db.db.Delete(db.wo, key); err != nil {
-		return err
+		return cLevelDBError(err)
 	}
 	return nil
 }
@@ -116,8 +217,12 @@ func (db *CLevelDB) DeleteSync(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
+	if !db.ops.begin() {
+		return errCLevelDBClosed
+	}
+	defer db.ops.end()
 	if err := db.db.Delete(db.woSync, key); err != nil {
-		return err
+		return cLevelDBError(err)
 	}
 	return nil
 }
@@ -127,8 +232,14 @@ func (db *CLevelDB) DB() *levigo.DB {
 	return db.db
 }
 
-// Close implements DB.
+// Close implements DB. It waits for the operations in flight, such as batch
+// writes, after which the operations fail with ErrClosed.
 func (db *CLevelDB) Close() error {
+	defer db.lock.release()
+	if !db.ops.close() {
+		return errCLevelDBClosed
+	}
+	db.iters.close()
 	db.db.Close()
 	db.ro.Close()
 	db.wo.Close()
@@ -153,6 +264,10 @@ func (db *CLevelDB) Print() error {
 
 // Stats implements DB.
 func (db *CLevelDB) Stats() map[string]string {
+	if !db.ops.begin() {
+		return map[string]string{}
+	}
+	defer db.ops.end()
 	keys := []string{
 		"leveldb.aliveiters",
 		"leveldb.alivesnaps",
@@ -172,6 +287,16 @@ func (db *CLevelDB) Stats() map[string]string {
 	return stats
 }
 
+// Compact implements Compacter.
+func (db *CLevelDB) Compact(start, end []byte) error {
+	if !db.ops.begin() {
+		return errCLevelDBClosed
+	}
+	defer db.ops.end()
+	db.db.CompactRange(levigo.Range{Start: start, Limit: end})
+	return nil
+}
+
 // NewBatch implements DB.
 func (db *CLevelDB) NewBatch() Batch {
 	return newCLevelDBBatch(db)
@@ -179,18 +304,31 @@ func (db *CLevelDB) NewBatch() Batch {
 
 // Iterator implements DB.
 func (db *CLevelDB) Iterator(start, end []byte) (Iterator, error) {
-	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
-		return nil, errKeyEmpty
-	}
-	itr := db.db.NewIterator(db.ro)
-	return newCLevelDBIterator(itr, start, end, false), nil
+	return db.IteratorWithOptions(start, end, IteratorOptions{})
 }
 
 // ReverseIterator implements DB.
 func (db *CLevelDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return db.IteratorWithOptions(start, end, IteratorOptions{Reverse: true})
+}
+
+// IteratorWithOptions implements IteratorOptionsDB, applying the ReadOptions.
+// KeysOnly iterators still read the values.
+func (db *CLevelDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
-	itr := db.db.NewIterator(db.ro)
-	return newCLevelDBIterator(itr, start, end, true), nil
+	if !db.ops.begin() {
+		return nil, errCLevelDBClosed
+	}
+	defer db.ops.end()
+	// The read options are copied by the iterator.
+	ro, release := db.readOptions(opts.ReadOptions)
+	defer release()
+	source := db.db.NewIterator(ro)
+	itr := Iterator(newCLevelDBIterator(source, start, end, opts.Reverse))
+	if opts.KeysOnly {
+		itr = newKeysOnlyIterator(itr)
+	}
+	return db.iters.track(itr, nil)
 }
@@ -9,6 +9,8 @@ import "github.com/jmhodges/levigo"
 type cLevelDBBatch struct {
 	db    *CLevelDB
 	batch *levigo.WriteBatch
+	count int
+	size  int
 }
 
 func newCLevelDBBatch(db *CLevelDB) *cLevelDBBatch {
@@ -27,9 +29,11 @@ func (b *cLevelDBBatch) Set(key, value []byte) error {
 		return errValueNil
 	}
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.batch.Put(key, value)
+	b.count++
+	b.size += len(key) + len(value)
 	return nil
 }
 
@@ -39,20 +43,36 @@ func (b *cLevelDBBatch) Delete(key []byte) error {
 		return errKeyEmpty
 	}
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.batch.Delete(key)
+	b.count++
+	b.size += len(key)
 	return nil
 }
 
+// Count implements Batch.
+func (b *cLevelDBBatch) Count() int {
+	return b.count
+}
+
+// SizeBytes implements Batch.
+func (b *cLevelDBBatch) SizeBytes() int {
+	return b.size
+}
+
 // Write implements Batch.
 func (b *cLevelDBBatch) Write() error {
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
+	}
+	if !b.db.ops.begin() {
+		return errCLevelDBClosed
 	}
+	defer b.db.ops.end()
 	err := b.db.db.Write(b.db.wo, b.batch)
 	if err != nil {
-		return err
+		return cLevelDBError(err)
 	}
 	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
 	return b.Close()
@@ -61,11 +81,15 @@ func (b *cLevelDBBatch) Write() error {
 // WriteSync implements Batch.
 func (b *cLevelDBBatch) WriteSync() error {
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
+	}
+	if !b.db.ops.begin() {
+		return errCLevelDBClosed
 	}
+	defer b.db.ops.end()
 	err := b.db.db.Write(b.db.woSync, b.batch)
 	if err != nil {
-		return err
+		return cLevelDBError(err)
 	}
 	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
 	b.Close()
@@ -77,6 +101,7 @@ func (b *cLevelDBBatch) Close() error {
 	if b.batch != nil {
 		b.batch.Close()
 		b.batch = nil
+		b.count, b.size = 0, 0
 	}
 	return nil
 }
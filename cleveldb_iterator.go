@@ -119,7 +119,7 @@ func (itr cLevelDBIterator) Next() {
 
 // Error implements Iterator.
 func (itr cLevelDBIterator) Error() error {
-	return itr.source.GetError()
+	return cLevelDBError(itr.source.GetError())
 }
 
 // Close implements Iterator.
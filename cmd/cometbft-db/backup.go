@@ -0,0 +1,189 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	dbm "github.com/cometbft/cometbft-db"
+)
+
+// backup backs up the database with the hot-backup API of its backend, to a
+// directory or as a tar stream to stdout. The database is not opened read-only,
+// since some backends, such as pebbledb, cannot back up read-only databases.
+func (c *cli) backup(args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	out := flags.String("out", "-", "backup directory, or - for a tar stream to stdout")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if *out != "-" {
+		return dbm.Backup(ctx, db, *out)
+	}
+
+	tmp, err := os.MkdirTemp("", "cometbft-db-backup")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err := dbm.Backup(ctx, db, tmp); err != nil {
+		return err
+	}
+	return writeTar(c.stdout, tmp)
+}
+
+// restore restores a backup made by backup into the directory of the database,
+// which must not exist yet.
+func (c *cli) restore(args []string, stdin io.Reader) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	in := flags.String("in", "-", "backup directory, or - for a tar stream from stdin")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	if _, err := os.Stat(c.path()); err == nil {
+		return fmt.Errorf("database %s already exists", c.path())
+	}
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	if *in == "-" {
+		return extractTar(stdin, c.dir)
+	}
+	return copyDir(*in, c.dir)
+}
+
+// writeTar writes the files of dir to w as a tar stream, with paths relative
+// to dir.
+func writeTar(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// extractTar extracts the directories and regular files of a tar stream into
+// dir, without overwriting existing files.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.FromSlash(header.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("invalid path %q in backup", header.Name)
+		}
+		path := filepath.Join(dir, name)
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(path, tr); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported file %q in backup", header.Name)
+		}
+	}
+}
+
+// copyDir copies the directories and regular files of src into dst, without
+// overwriting existing files.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == src {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case d.Type().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return writeFile(target, f)
+		default:
+			return fmt.Errorf("unsupported file %q in backup", path)
+		}
+	})
+}
+
+// writeFile writes a new file at path with the contents of r, and syncs it.
+func writeFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
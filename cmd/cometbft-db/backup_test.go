@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBackupRestore(t *testing.T) {
+	for _, backend := range []string{"goleveldb", "pebbledb"} {
+		t.Run(backend, func(t *testing.T) {
+			dir := t.TempDir()
+			exec := func(stdin *bytes.Buffer, args ...string) (*bytes.Buffer, error) {
+				var stdout, stderr bytes.Buffer
+				c := append([]string{"-backend", backend, "-name", "test"}, args...)
+				if stdin == nil {
+					return &stdout, run(c, &stdout, &stderr)
+				}
+				return &stdout, runWithStdin(c, stdin, &stdout, &stderr)
+			}
+			_, err := exec(nil, "-dir", dir, "set", "k", "v")
+			require.NoError(t, err)
+
+			// To and from a directory.
+			backupDir := filepath.Join(t.TempDir(), "backup")
+			_, err = exec(nil, "-dir", dir, "backup", "-out", backupDir)
+			require.NoError(t, err)
+			restored := t.TempDir()
+			_, err = exec(nil, "-dir", restored, "restore", "-in", backupDir)
+			require.NoError(t, err)
+			out, err := exec(nil, "-dir", restored, "get", "k")
+			require.NoError(t, err)
+			assert.Equal(t, "v\n", out.String())
+			_, err = exec(nil, "-dir", restored, "restore", "-in", backupDir)
+			assert.ErrorContains(t, err, "already exists")
+
+			// As a tar stream.
+			stream, err := exec(nil, "-dir", dir, "backup")
+			require.NoError(t, err)
+			restored = t.TempDir()
+			_, err = exec(stream, "-dir", restored, "restore")
+			require.NoError(t, err)
+			out, err = exec(nil, "-dir", restored, "get", "k")
+			require.NoError(t, err)
+			assert.Equal(t, "v\n", out.String())
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cometbft/cometbft-db/dbbench"
+)
+
+// bench runs benchmark workloads in a new database, which is removed
+// afterwards, and prints a line of results per workload.
+func (c *cli) bench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	workloadsArg := flags.String("workloads", "fillseq,fillrandom,readrandom,scan,mixed", "comma-separated workloads to run, in order")
+	var cfg dbbench.Config
+	flags.IntVar(&cfg.Num, "num", 100000, "number of keys and operations")
+	flags.IntVar(&cfg.KeySize, "key-size", 16, "size of the keys in bytes")
+	flags.IntVar(&cfg.ValueSize, "value-size", 100, "size of the values in bytes")
+	flags.IntVar(&cfg.BatchSize, "batch-size", 1, "keys written per batch by the fill workloads")
+	flags.IntVar(&cfg.ScanLength, "scan-length", 100, "keys read per iterator by scan")
+	flags.Float64Var(&cfg.ReadRatio, "read-ratio", 0.5, "fraction of reads of mixed")
+	flags.BoolVar(&cfg.Sync, "sync", false, "synchronous writes")
+	flags.Int64Var(&cfg.Seed, "seed", 0, "seed of the random keys and values")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("bench: %w", err)
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("bench: unexpected arguments %v", flags.Args())
+	}
+	workloads, err := dbbench.ParseWorkloads(*workloadsArg)
+	if err != nil {
+		return fmt.Errorf("bench: %w", err)
+	}
+	if _, err := os.Stat(c.path()); err == nil {
+		return fmt.Errorf("database %s already exists, bench needs a new one", c.path())
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(c.path())
+	defer db.Close()
+	if _, err := fmt.Fprintf(c.stdout, "Backend %s, %d keys of %d bytes, values of %d bytes\n",
+		c.backend, cfg.Num, cfg.KeySize, cfg.ValueSize); err != nil {
+		return err
+	}
+	for _, w := range workloads {
+		res, err := dbbench.Run(context.Background(), db, w, cfg)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(c.stdout, res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunBench(t *testing.T) {
+	dir := t.TempDir()
+	exec := func(args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		err := run(append([]string{"-dir", dir, "-name", "bench"}, args...), &stdout, &stderr)
+		return stdout.String(), err
+	}
+
+	out, err := exec("bench", "-num", "100", "-workloads", "fillseq,readrandom")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "Backend goleveldb, 100 keys of 16 bytes, values of 100 bytes", lines[0])
+	assert.Regexp(t, `^fillseq +: .* ops/s .* MB/s  p50 .*\(100 ops, 100 keys in .*\)$`, lines[1])
+	assert.Regexp(t, `^readrandom +: .*\(100 ops, 100 keys in .*\)$`, lines[2])
+	assert.NoDirExists(t, filepath.Join(dir, "bench.db"))
+
+	_, err = exec("bench", "-workloads", "readseq")
+	assert.EqualError(t, err, `bench: unknown workload "readseq"`)
+	_, err = exec("set", "k", "v")
+	require.NoError(t, err)
+	_, err = exec("bench")
+	assert.ErrorContains(t, err, "already exists")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	dbm "github.com/cometbft/cometbft-db"
+)
+
+// export writes a range of keys in the portable stream format of ExportTo, to
+// a file or to stdout.
+func (c *cli) export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	startArg := flags.String("start", "", "first key of the range, from the first key if empty")
+	endArg := flags.String("end", "", "key after the range, to the last key if empty")
+	out := flags.String("out", "-", "file to write, or - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	var start, end []byte
+	var err error
+	if *startArg != "" {
+		if start, err = c.decode(*startArg); err != nil {
+			return err
+		}
+	}
+	if *endArg != "" {
+		if end, err = c.decode(*endArg); err != nil {
+			return err
+		}
+	}
+
+	db, err := c.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if *out == "-" {
+		return dbm.ExportTo(context.Background(), db, c.stdout, start, end)
+	}
+	f, err := os.OpenFile(*out, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := dbm.ExportTo(context.Background(), db, w, start, end); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// importKeys writes the keys of a stream written by export, from a file or
+// from stdin.
+func (c *cli) importKeys(args []string, stdin io.Reader) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	in := flags.String("in", "-", "file to read, or - for stdin")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	r := stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	keys, err := dbm.ImportFrom(context.Background(), db, r)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.stdout, "Imported %d keys\n", keys)
+	return err
+}
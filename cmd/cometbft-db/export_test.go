@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExportImport(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	exec := func(stdin *bytes.Buffer, args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		if stdin == nil {
+			stdin = &bytes.Buffer{}
+		}
+		err := runWithStdin(append([]string{"-name", "test"}, args...), stdin, &stdout, &stderr)
+		return stdout.String(), err
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := exec(nil, "-dir", src, "set", key, key+"1")
+		require.NoError(t, err)
+	}
+
+	// Through stdout and stdin, to another backend.
+	stream, err := exec(nil, "-dir", src, "export", "-start", "b")
+	require.NoError(t, err)
+	out, err := exec(bytes.NewBufferString(stream), "-dir", dst, "-backend", "pebbledb", "import")
+	require.NoError(t, err)
+	assert.Equal(t, "Imported 2 keys\n", out)
+	out, err = exec(nil, "-dir", dst, "-backend", "pebbledb", "dump")
+	require.NoError(t, err)
+	assert.Equal(t, "b\tb1\nc\tc1\n", out)
+
+	// Through a file.
+	file := filepath.Join(t.TempDir(), "export")
+	_, err = exec(nil, "-dir", src, "export", "-end", "b", "-out", file)
+	require.NoError(t, err)
+	_, err = exec(nil, "-dir", src, "export", "-out", file)
+	assert.Error(t, err)
+	out, err = exec(nil, "-dir", dst, "-backend", "pebbledb", "import", "-in", file)
+	require.NoError(t, err)
+	assert.Equal(t, "Imported 1 keys\n", out)
+	out, err = exec(nil, "-dir", dst, "-backend", "pebbledb", "list")
+	require.NoError(t, err)
+	assert.Equal(t, "a\nb\nc\n", out)
+
+	_, err = exec(bytes.NewBufferString("garbage"), "-dir", dst, "-backend", "pebbledb", "import")
+	assert.ErrorContains(t, err, "invalid backup stream")
+}
@@ -0,0 +1,421 @@
+// Command cometbft-db inspects and edits the keys of a database of any backend
+// compiled in, e.g. to look at a key of a stopped node:
+//
+//	cometbft-db -backend goleveldb -dir ~/.cometbft/data -name state get stateKey
+//
+// The backends behind build tags, such as rocksdb, are available when the
+// command is built with their tags. Keys and values are read and printed as
+// text, or in hex with -hex. In text mode, arguments starting with 0x are read
+// as hex, and keys and values which are not printable text, or start with 0x,
+// are printed in hex with a 0x prefix.
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	dbm "github.com/cometbft/cometbft-db"
+)
+
+const usage = `Usage: cometbft-db [flags] <command> [args]
+
+Commands:
+  dbs                                print the databases in -dir, with their backend,
+                                     size and last modification, without -name
+  get <key>                          print the value of a key
+  set <key> <value>                  set a key
+  del <key>                          delete a key
+  list [-prefix p] [-limit n]        print the keys, in order
+  dump [-prefix p] [-limit n]        print the keys and values, tab-separated
+  migrate -to-backend b -to-dir d [-to-name n] [-batch-size bytes] [-resume] [-verify]
+                                     copy all keys to another database
+  compact [-start s] [-end e]        compact the database, or a range of keys
+  backup [-out dir|-]                back up the database to a directory, or as a
+                                     tar stream to stdout with - (the default)
+  restore [-in dir|-]                restore a backup into -dir, from a directory or
+                                     from a tar stream on stdin with - (the default)
+  export [-start s] [-end e] [-out file|-]
+                                     export a range of keys in a portable format, to a
+                                     file or to stdout with - (the default)
+  import [-in file|-]                import the keys of an export, from a file or from
+                                     stdin with - (the default)
+  verify [-recover [-dry-run]]       check the checksums of all keys and values, after
+                                     recovering a goleveldb database, or a copy of it
+                                     with -dry-run
+  bench [-workloads w,...] [-num n] [-key-size n] [-value-size n] [-batch-size n]
+        [-scan-length n] [-read-ratio r] [-sync] [-seed n]
+                                     benchmark the backend in a new database, which
+                                     is removed afterwards
+
+Flags:
+`
+
+func main() {
+	if err := runWithStdin(os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+// cli is the configuration of an invocation.
+type cli struct {
+	backend string
+	dir     string
+	name    string
+	hex     bool
+	stdout  io.Writer
+}
+
+func run(args []string, stdout, stderr io.Writer) error {
+	return runWithStdin(args, strings.NewReader(""), stdout, stderr)
+}
+
+func runWithStdin(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	c := &cli{stdout: stdout}
+	flags := flag.NewFlagSet("cometbft-db", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&c.backend, "backend", string(dbm.GoLevelDBBackend), "database backend")
+	flags.StringVar(&c.dir, "dir", ".", "database directory")
+	flags.StringVar(&c.name, "name", "", "database name (required)")
+	flags.BoolVar(&c.hex, "hex", false, "read and print keys and values in hex")
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("missing command")
+	}
+	command, args := flags.Arg(0), flags.Args()[1:]
+	if command == "dbs" {
+		return c.dbs(args)
+	}
+	if c.name == "" {
+		return errors.New("missing -name")
+	}
+
+	switch command {
+	case "get":
+		return c.get(args)
+	case "set":
+		return c.set(args)
+	case "del":
+		return c.del(args)
+	case "list":
+		return c.list(command, args, false)
+	case "dump":
+		return c.list(command, args, true)
+	case "migrate":
+		return c.migrate(args, stderr)
+	case "compact":
+		return c.compact(args)
+	case "backup":
+		return c.backup(args)
+	case "restore":
+		return c.restore(args, stdin)
+	case "export":
+		return c.export(args)
+	case "import":
+		return c.importKeys(args, stdin)
+	case "verify":
+		return c.verify(args)
+	case "bench":
+		return c.bench(args)
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// open opens the database, read-only unless write is set.
+func (c *cli) open(write bool) (dbm.DB, error) {
+	if !write {
+		return dbm.OpenReadOnly(c.name, dbm.BackendType(c.backend), c.dir)
+	}
+	return dbm.NewDB(c.name, dbm.BackendType(c.backend), c.dir)
+}
+
+// decode returns the bytes of a key or value argument.
+func (c *cli) decode(arg string) ([]byte, error) {
+	if !c.hex && !strings.HasPrefix(arg, "0x") {
+		return []byte(arg), nil
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(arg, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex %q: %w", arg, err)
+	}
+	return b, nil
+}
+
+// format returns the printed form of a key or value.
+func (c *cli) format(b []byte) string {
+	if !c.hex && printable(b) {
+		return string(b)
+	}
+	if c.hex {
+		return hex.EncodeToString(b)
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+// printable reports whether b is valid UTF-8 text without control characters,
+// which cannot be mistaken for a hex argument.
+func printable(b []byte) bool {
+	if !utf8.Valid(b) || strings.HasPrefix(string(b), "0x") {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *cli) keyArgs(args []string, n int, names string) ([][]byte, error) {
+	if len(args) != n {
+		return nil, fmt.Errorf("expected %s", names)
+	}
+	decoded := make([][]byte, n)
+	for i, arg := range args {
+		b, err := c.decode(arg)
+		if err != nil {
+			return nil, err
+		}
+		decoded[i] = b
+	}
+	return decoded, nil
+}
+
+func (c *cli) get(args []string) error {
+	kv, err := c.keyArgs(args, 1, "<key>")
+	if err != nil {
+		return err
+	}
+	db, err := c.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	value, err := db.Get(kv[0])
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return fmt.Errorf("key %s not found", c.format(kv[0]))
+	}
+	_, err = fmt.Fprintln(c.stdout, c.format(value))
+	return err
+}
+
+func (c *cli) set(args []string) error {
+	kv, err := c.keyArgs(args, 2, "<key> <value>")
+	if err != nil {
+		return err
+	}
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.SetSync(kv[0], kv[1])
+}
+
+func (c *cli) del(args []string) error {
+	kv, err := c.keyArgs(args, 1, "<key>")
+	if err != nil {
+		return err
+	}
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return db.DeleteSync(kv[0])
+}
+
+// list prints the keys with a prefix, and their values if values is set.
+func (c *cli) list(command string, args []string, values bool) error {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	prefixArg := flags.String("prefix", "", "only the keys with this prefix")
+	limit := flags.Int("limit", 0, "print at most this many keys, if positive")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%s: %w", command, err)
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%s: unexpected arguments %v", command, flags.Args())
+	}
+	prefix, err := c.decode(*prefixArg)
+	if err != nil {
+		return err
+	}
+
+	db, err := c.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	itr, err := dbm.PrefixIterator(db, prefix)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for n := 0; itr.Valid() && (*limit <= 0 || n < *limit); n++ {
+		line := c.format(itr.Key())
+		if values {
+			line += "\t" + c.format(itr.Value())
+		}
+		if _, err := fmt.Fprintln(c.stdout, line); err != nil {
+			return err
+		}
+		itr.Next()
+	}
+	return itr.Error()
+}
+
+// migrate copies the database to another one, reporting the progress to
+// stderr every second.
+func (c *cli) migrate(args []string, stderr io.Writer) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	toBackend := flags.String("to-backend", "", "destination backend (required)")
+	toDir := flags.String("to-dir", "", "destination directory (required)")
+	toName := flags.String("to-name", c.name, "destination name")
+	batchSize := flags.Int("batch-size", 0, "size of the batches in bytes, 4 MiB if 0")
+	resume := flags.Bool("resume", false, "resume an interrupted migration")
+	verify := flags.Bool("verify", false, "compare the databases after copying")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	if *toBackend == "" || *toDir == "" {
+		return errors.New("migrate: missing -to-backend or -to-dir")
+	}
+
+	src, err := c.open(false)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := dbm.NewDB(*toName, dbm.BackendType(*toBackend), *toDir)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	var reported time.Time
+	err = dbm.Migrate(context.Background(), src, dst, dbm.MigrateOptions{
+		BatchSize: *batchSize,
+		Resume:    *resume,
+		Verify:    *verify,
+		Progress: func(p dbm.MigrateProgress) {
+			if time.Since(reported) < time.Second {
+				return
+			}
+			reported = time.Now()
+			fmt.Fprintf(stderr, "Copied %d keys, %d bytes, up to %s\n", p.Keys, p.Bytes, c.format(p.LastKey))
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.stdout, "Migration complete")
+	return err
+}
+
+// compact compacts a range of keys, and prints the size of the database files
+// before and after.
+func (c *cli) compact(args []string) error {
+	flags := flag.NewFlagSet("compact", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	startArg := flags.String("start", "", "first key of the range, from the first key if empty")
+	endArg := flags.String("end", "", "key after the range, to the last key if empty")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("compact: %w", err)
+	}
+	var start, end []byte
+	var err error
+	if *startArg != "" {
+		if start, err = c.decode(*startArg); err != nil {
+			return err
+		}
+	}
+	if *endArg != "" {
+		if end, err = c.decode(*endArg); err != nil {
+			return err
+		}
+	}
+
+	usage := dbm.DirUsage(c.path())
+	before, err := usage()
+	if err != nil {
+		return err
+	}
+	db, err := c.open(true)
+	if err != nil {
+		return err
+	}
+	if err := dbm.Compact(db, start, end); err != nil {
+		db.Close()
+		return err
+	}
+	// Some backends only delete the compacted files when closed.
+	if err := db.Close(); err != nil {
+		return err
+	}
+	after, err := usage()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.stdout, "Compacted: %d bytes before, %d bytes after\n", before, after)
+	return err
+}
+
+// dbs prints the databases in the directory, tab-separated.
+func (c *cli) dbs(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("dbs: unexpected arguments %v", args)
+	}
+	dbs, err := dbm.ListDBs(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range dbs {
+		backend := string(info.Backend)
+		if backend == "" {
+			backend = "unknown"
+		}
+		_, err := fmt.Fprintf(c.stdout, "%s\t%s\t%d\t%s\n", info.Name, backend, info.Size,
+			info.ModTime.UTC().Format(time.RFC3339))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// path returns the path of the database files, which is the directory or file
+// named after the database with a .db extension for most backends, and without
+// one for badgerdb.
+func (c *cli) path() string {
+	path := filepath.Join(c.dir, c.name+".db")
+	if _, err := os.Stat(path); err != nil {
+		return filepath.Join(c.dir, c.name)
+	}
+	return path
+}
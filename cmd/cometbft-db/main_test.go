@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	exec := func(args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		err := run(append([]string{"-dir", dir, "-name", "test"}, args...), &stdout, &stderr)
+		return stdout.String(), err
+	}
+
+	for _, kv := range [][]string{{"a/1", "one"}, {"a/2", "0x00ff"}, {"b/1", "three"}} {
+		_, err := exec("set", kv[0], kv[1])
+		require.NoError(t, err)
+	}
+
+	out, err := exec("get", "a/1")
+	require.NoError(t, err)
+	assert.Equal(t, "one\n", out)
+	out, err = exec("get", "a/2")
+	require.NoError(t, err)
+	assert.Equal(t, "0x00ff\n", out)
+	out, err = exec("-hex", "get", "612f31")
+	require.NoError(t, err)
+	assert.Equal(t, "6f6e65\n", out)
+	_, err = exec("get", "missing")
+	assert.EqualError(t, err, "key missing not found")
+
+	out, err = exec("list")
+	require.NoError(t, err)
+	assert.Equal(t, "a/1\na/2\nb/1\n", out)
+	out, err = exec("list", "-prefix", "a/", "-limit", "1")
+	require.NoError(t, err)
+	assert.Equal(t, "a/1\n", out)
+	out, err = exec("dump", "-prefix", "a/")
+	require.NoError(t, err)
+	assert.Equal(t, "a/1\tone\na/2\t0x00ff\n", out)
+
+	_, err = exec("del", "a/1")
+	require.NoError(t, err)
+	out, err = exec("list")
+	require.NoError(t, err)
+	assert.Equal(t, "a/2\nb/1\n", out)
+
+	_, err = exec("set", "k")
+	assert.EqualError(t, err, "expected <key> <value>")
+	_, err = exec("frob")
+	assert.EqualError(t, err, `unknown command "frob"`)
+	_, err = exec("-backend", "nope", "list")
+	assert.Error(t, err)
+}
+
+func TestRunDBs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"state", "blockstore"} {
+		var stdout, stderr bytes.Buffer
+		err := run([]string{"-dir", dir, "-name", name, "set", "k", "v"}, &stdout, &stderr)
+		require.NoError(t, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	require.NoError(t, run([]string{"-dir", dir, "dbs"}, &stdout, &stderr))
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	require.Len(t, lines, 2)
+	for i, name := range []string{"blockstore", "state"} {
+		fields := strings.Split(lines[i], "\t")
+		require.Len(t, fields, 4)
+		assert.Equal(t, name, fields[0])
+		assert.Equal(t, "goleveldb", fields[1])
+	}
+}
+
+func TestRunMigrate(t *testing.T) {
+	dir, toDir := t.TempDir(), t.TempDir()
+	exec := func(args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		err := run(args, &stdout, &stderr)
+		return stdout.String(), err
+	}
+	_, err := exec("-dir", dir, "-name", "test", "set", "k", "v")
+	require.NoError(t, err)
+
+	out, err := exec("-dir", dir, "-name", "test", "migrate", "-to-backend", "pebbledb", "-to-dir", toDir, "-verify")
+	require.NoError(t, err)
+	assert.Equal(t, "Migration complete\n", out)
+	out, err = exec("-backend", "pebbledb", "-dir", toDir, "-name", "test", "dump")
+	require.NoError(t, err)
+	assert.Equal(t, "k\tv\n", out)
+
+	_, err = exec("-dir", dir, "-name", "test", "migrate", "-to-dir", toDir)
+	assert.EqualError(t, err, "migrate: missing -to-backend or -to-dir")
+}
+
+func TestRunCompact(t *testing.T) {
+	dir := t.TempDir()
+	exec := func(args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		err := run(append([]string{"-dir", dir, "-name", "test"}, args...), &stdout, &stderr)
+		return stdout.String(), err
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		_, err := exec("set", key, "value")
+		require.NoError(t, err)
+	}
+	_, err := exec("del", "b")
+	require.NoError(t, err)
+
+	out, err := exec("compact")
+	require.NoError(t, err)
+	assert.Regexp(t, `^Compacted: [1-9]\d* bytes before, [1-9]\d* bytes after\n$`, out)
+	_, err = exec("compact", "-start", "a", "-end", "c")
+	require.NoError(t, err)
+	out, err = exec("list")
+	require.NoError(t, err)
+	assert.Equal(t, "a\nc\n", out)
+}
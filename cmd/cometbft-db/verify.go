@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	dbm "github.com/cometbft/cometbft-db"
+)
+
+// verify reads every key and value with the checksums verified, and prints the
+// ranges of keys which are corrupted. With -recover, a goleveldb database is
+// recovered first, or a copy of it with -dry-run, to see what the recovery
+// would keep without changing the database.
+func (c *cli) verify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	recoverDB := flags.Bool("recover", false, "recover the database before verifying it, only for goleveldb")
+	dryRun := flags.Bool("dry-run", false, "recover a copy of the database, leaving it unchanged")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("verify: %w", err)
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("verify: unexpected arguments %v", flags.Args())
+	}
+	if *dryRun && !*recoverDB {
+		return errors.New("verify: -dry-run requires -recover")
+	}
+
+	target := *c
+	if *recoverDB {
+		if dbm.BackendType(c.backend) != dbm.GoLevelDBBackend {
+			return fmt.Errorf("verify: recovery is not supported by backend %s", c.backend)
+		}
+		if *dryRun {
+			tmp, err := os.MkdirTemp("", "cometbft-db-verify")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(tmp)
+			if err := copyDir(c.path(), filepath.Join(tmp, c.name+".db")); err != nil {
+				return err
+			}
+			target.dir = tmp
+		}
+		if err := dbm.RecoverGoLevelDB(target.name, target.dir); err != nil {
+			return err
+		}
+		msg := "Recovered the database"
+		if *dryRun {
+			msg = "Recovered a copy of the database (dry run)"
+		}
+		if _, err := fmt.Fprintln(c.stdout, msg); err != nil {
+			return err
+		}
+	}
+
+	db, err := target.open(false)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	report, err := dbm.VerifyIntegrity(context.Background(), db)
+	if err != nil {
+		return err
+	}
+	for _, r := range report.Corrupted {
+		start, end := "the first key", "the last key"
+		if r.Start != nil {
+			start = c.format(r.Start)
+		}
+		if r.End != nil {
+			end = "before " + c.format(r.End)
+		}
+		if _, err := fmt.Fprintf(c.stdout, "Corrupted keys from %s to %s: %v\n", start, end, r.Err); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(c.stdout, "Verified %d keys, %d bytes\n", report.Keys, report.Bytes); err != nil {
+		return err
+	}
+	if len(report.Corrupted) > 0 {
+		return fmt.Errorf("found %d corrupted key ranges", len(report.Corrupted))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	dbm "github.com/cometbft/cometbft-db"
+)
+
+func TestRunVerify(t *testing.T) {
+	dir := t.TempDir()
+	exec := func(args ...string) (string, error) {
+		var stdout, stderr bytes.Buffer
+		err := run(append([]string{"-dir", dir, "-name", "test"}, args...), &stdout, &stderr)
+		return stdout.String(), err
+	}
+
+	// Many keys are compacted into a table of many blocks, one of which is
+	// corrupted.
+	db, err := dbm.NewGoLevelDB("test", dir)
+	require.NoError(t, err)
+	value := make([]byte, 100)
+	for i := 0; i < 2000; i++ {
+		rand.Read(value) //nolint:gosec
+		require.NoError(t, db.Set([]byte(fmt.Sprintf("key%05d", i)), value))
+	}
+	require.NoError(t, db.Compact(nil, nil))
+	require.NoError(t, db.Close())
+
+	out, err := exec("verify")
+	require.NoError(t, err)
+	assert.Equal(t, "Verified 2000 keys, 216000 bytes\n", out)
+
+	tables, err := filepath.Glob(filepath.Join(dir, "test.db", "*.ldb"))
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	data, err := os.ReadFile(tables[0])
+	require.NoError(t, err)
+	data[len(data)/3] ^= 0xFF
+	require.NoError(t, os.WriteFile(tables[0], data, 0o644))
+
+	out, err = exec("verify")
+	assert.EqualError(t, err, "found 1 corrupted key ranges")
+	assert.Regexp(t, "^Corrupted keys from 0x[0-9a-f]+ to before key[0-9]+: .*\nVerified [0-9]+ keys, [0-9]+ bytes\n$", out)
+
+	// A dry run leaves the database corrupted.
+	out, err = exec("verify", "-recover", "-dry-run")
+	require.NoError(t, err)
+	assert.Regexp(t, "^Recovered a copy of the database \\(dry run\\)\nVerified [0-9]+ keys, [0-9]+ bytes\n$", out)
+	_, err = exec("verify")
+	assert.Error(t, err)
+
+	out, err = exec("verify", "-recover")
+	require.NoError(t, err)
+	assert.Regexp(t, "^Recovered the database\nVerified [0-9]+ keys, [0-9]+ bytes\n$", out)
+	_, err = exec("verify")
+	assert.NoError(t, err)
+
+	_, err = exec("verify", "-dry-run")
+	assert.EqualError(t, err, "verify: -dry-run requires -recover")
+	_, err = exec("-backend", "pebbledb", "verify", "-recover")
+	assert.EqualError(t, err, "verify: recovery is not supported by backend pebbledb")
+}
@@ -14,7 +14,7 @@ import (
 //----------------------------------------
 // Helper functions.
 
-func checkValue(t *testing.T, db DB, key []byte, valueWanted []byte) {
+func checkValue(t *testing.T, db DBReader, key []byte, valueWanted []byte) {
 	valueGot, err := db.Get(key)
 	assert.NoError(t, err)
 	assert.Equal(t, valueWanted, valueGot)
@@ -0,0 +1,23 @@
+package db
+
+// Compacter is implemented by databases which can compact a range of keys on
+// demand, e.g. to reclaim the disk space of deleted keys after a large prune.
+type Compacter interface {
+	// Compact compacts the underlying storage for the key range [start, end).
+	// A nil start is the first key, a nil end is after the last key. Backends
+	// without range compaction may compact the whole database instead.
+	Compact(start, end []byte) error
+}
+
+// Compact compacts the key range [start, end) of db, see Compacter. It returns
+// ErrNotSupported if db does not implement Compacter.
+func Compact(db DB, start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	c, ok := db.(Compacter)
+	if !ok {
+		return ErrNotSupported
+	}
+	return c.Compact(start, end)
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompact(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			for i := int64(0); i < 100; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+			for i := int64(0); i < 50; i++ {
+				require.NoError(t, db.Delete(int642Bytes(i)))
+			}
+
+			_, ok := db.(Compacter)
+			for _, r := range [][2][]byte{{nil, nil}, {int642Bytes(10), int642Bytes(60)}, {int642Bytes(50), nil}} {
+				err = Compact(db, r[0], r[1])
+				if !ok || errors.Is(err, ErrNotSupported) {
+					// Not implemented, or a wrapper over a database without compaction.
+					require.ErrorIs(t, err, ErrNotSupported)
+					return
+				}
+				require.NoError(t, err)
+			}
+
+			// Compaction must not change the contents.
+			checkValue(t, db, int642Bytes(10), nil)
+			checkValue(t, db, int642Bytes(60), int642Bytes(60))
+		})
+	}
+}
+
+func TestCompactEmptyKey(t *testing.T) {
+	db := NewMemDB()
+	assert.Equal(t, errKeyEmpty, Compact(db, []byte{}, nil))
+	assert.Equal(t, errKeyEmpty, Compact(db, nil, []byte{}))
+}
+
+func TestCompactWrappers(t *testing.T) {
+	// Wrappers return ErrNotSupported when nothing underneath supports it.
+	assert.ErrorIs(t, Compact(NewPrefixDB(NewMemDB(), bz("p")), nil, nil), ErrNotSupported)
+
+	dir, err := os.MkdirTemp("", "compact_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ldb, err := NewGoLevelDB("compact", dir)
+	require.NoError(t, err)
+	defer ldb.Close()
+
+	sdb, err := NewShardedDB([]DB{NewMemDB(), ldb}, HashSharding)
+	require.NoError(t, err)
+	assert.NoError(t, Compact(sdb, nil, nil))
+	assert.NoError(t, Compact(NewPrefixDB(ldb, []byte{0xFF}), nil, nil))
+	assert.NoError(t, Compact(NewTieredDB(NewMemDB(), ldb, TierPolicy{}), nil, nil))
+}
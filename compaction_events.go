@@ -0,0 +1,45 @@
+package db
+
+import "time"
+
+// CompactionKind is the kind of background operation of a CompactionInfo.
+type CompactionKind int
+
+const (
+	// CompactionKindCompaction is a compaction of tables into a level of the
+	// LSM tree.
+	CompactionKindCompaction CompactionKind = iota
+	// CompactionKindFlush is a flush of memtables to level 0.
+	CompactionKindFlush
+)
+
+// String returns "compaction" or "flush".
+func (k CompactionKind) String() string {
+	if k == CompactionKindFlush {
+		return "flush"
+	}
+	return "compaction"
+}
+
+// CompactionInfo describes a compaction or flush completed by a backend, as
+// reported to Options.OnCompaction.
+type CompactionInfo struct {
+	// Kind is whether this is a compaction or a flush.
+	Kind CompactionKind
+	// Reason is the backend-specific reason of the operation, e.g. "default"
+	// or "move" for pebble compactions.
+	Reason string
+	// InputLevels are the levels of the compacted tables. They are empty for
+	// flushes, whose input is in memory.
+	InputLevels []int
+	// OutputLevel is the level of the written tables.
+	OutputLevel int
+	// InputBytes is the size of the compacted tables, zero for flushes.
+	InputBytes uint64
+	// OutputBytes is the size of the written tables.
+	OutputBytes uint64
+	// Duration is how long the operation took.
+	Duration time.Duration
+	// Err is the error which made the operation fail, if any.
+	Err error
+}
@@ -0,0 +1,221 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// defaultCompactionCheckInterval is how often a CompactionScheduler checks
+	// whether to compact, if not given.
+	defaultCompactionCheckInterval = time.Minute
+	// defaultCompactionMinInterval is the minimum time between the compactions
+	// triggered by the tombstone ratio, if not given.
+	defaultCompactionMinInterval = time.Hour
+)
+
+// TombstoneEstimator is implemented by databases which can estimate the
+// deletion tombstones of their on-disk tables, which take space until they are
+// compacted away along with the keys they delete.
+type TombstoneEstimator interface {
+	// EstimateTombstones returns the approximate number of deletion tombstones
+	// and of entries, tombstones included, of the tables of the database.
+	EstimateTombstones() (tombstones, entries uint64, err error)
+}
+
+// CompactionWindow is a window of the day, such as the quiet hours of a node,
+// during which a CompactionScheduler compacts the database.
+type CompactionWindow struct {
+	// Start and End are the times of day of the window, as offsets from
+	// midnight. A window whose end is before its start spans midnight.
+	Start, End time.Duration
+}
+
+// contains reports whether the window contains t, and if so returns the start
+// of the window.
+func (w CompactionWindow) contains(t time.Time) (time.Time, bool) {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	switch {
+	case w.Start <= w.End:
+		return midnight.Add(w.Start), offset >= w.Start && offset < w.End
+	case offset >= w.Start:
+		return midnight.Add(w.Start), true
+	case offset < w.End:
+		// The window started the day before.
+		return midnight.AddDate(0, 0, -1).Add(w.Start), true
+	}
+	return time.Time{}, false
+}
+
+// CompactionPolicy configures when a CompactionScheduler compacts a database.
+type CompactionPolicy struct {
+	// Windows are the windows of the day during which the database is
+	// compacted, once per window.
+	Windows []CompactionWindow
+	// Location is the time zone of the windows, UTC if nil.
+	Location *time.Location
+
+	// TombstoneRatio, if positive, compacts the database at any time once the
+	// ratio of deletion tombstones to entries of its tables exceeds it, e.g.
+	// after pruning. The database must implement TombstoneEstimator.
+	TombstoneRatio float64
+	// MinInterval is the minimum time between two compactions triggered by
+	// the tombstone ratio, an hour if zero.
+	MinInterval time.Duration
+
+	// Start and End are the range of keys compacted, see Compacter. The whole
+	// database is compacted if both are nil.
+	Start, End []byte
+
+	// CheckInterval is how often the scheduler checks whether to compact, a
+	// minute if zero.
+	CheckInterval time.Duration
+
+	// OnCompaction, if set, is called after each compaction run by the
+	// scheduler.
+	OnCompaction func(ScheduledCompaction)
+	// Logger logs the compactions run by the scheduler, if set.
+	Logger Logger
+}
+
+// ScheduledCompaction describes a compaction run by a CompactionScheduler.
+type ScheduledCompaction struct {
+	// Window is the window the compaction ran in, or nil if it was triggered
+	// by the tombstone ratio.
+	Window *CompactionWindow
+	// TombstoneRatio is the ratio of tombstones which triggered the
+	// compaction, zero if it ran in a window.
+	TombstoneRatio float64
+	// Duration is how long the compaction took.
+	Duration time.Duration
+	// Err is the error which made the compaction fail, if any.
+	Err error
+}
+
+// CompactionScheduler compacts a database in the background, during quiet
+// windows of the day or when deleted keys pile up, so that the space of pruned
+// data is reclaimed without running compactions by hand. Compacting is
+// expensive, so the windows should be long enough for a whole compaction of
+// the database, and the tombstone ratio high enough not to compact often.
+type CompactionScheduler struct {
+	db     DB
+	policy CompactionPolicy
+	now    func() time.Time
+
+	mtx        sync.Mutex // serializes the checks
+	lastWindow time.Time  // start of the last window compacted in
+	lastRatio  time.Time  // last compaction triggered by the tombstone ratio
+
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewCompactionScheduler starts compacting db according to policy, until the
+// scheduler is closed. It returns ErrNotSupported if db does not implement
+// Compacter, or TombstoneEstimator with a tombstone ratio.
+func NewCompactionScheduler(db DB, policy CompactionPolicy) (*CompactionScheduler, error) {
+	if _, ok := db.(Compacter); !ok {
+		return nil, ErrNotSupported
+	}
+	if _, ok := db.(TombstoneEstimator); policy.TombstoneRatio > 0 && !ok {
+		return nil, ErrNotSupported
+	}
+	if policy.Location == nil {
+		policy.Location = time.UTC
+	}
+	if policy.MinInterval <= 0 {
+		policy.MinInterval = defaultCompactionMinInterval
+	}
+	if policy.CheckInterval <= 0 {
+		policy.CheckInterval = defaultCompactionCheckInterval
+	}
+	s := &CompactionScheduler{
+		db:     db,
+		policy: policy,
+		now:    time.Now,
+		quit:   make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+func (s *CompactionScheduler) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.policy.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+			s.check()
+		}
+	}
+}
+
+// check compacts the database if the current time is in a window it was not
+// compacted in yet, or if the tombstone ratio is exceeded.
+func (s *CompactionScheduler) check() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	now := s.now().In(s.policy.Location)
+	for i := range s.policy.Windows {
+		window := &s.policy.Windows[i]
+		start, ok := window.contains(now)
+		if ok && start.After(s.lastWindow) {
+			s.lastWindow = start
+			s.compact(ScheduledCompaction{Window: window})
+			return
+		}
+	}
+	if s.policy.TombstoneRatio <= 0 || now.Sub(s.lastRatio) < s.policy.MinInterval {
+		return
+	}
+	tombstones, entries, err := s.db.(TombstoneEstimator).EstimateTombstones()
+	if err != nil {
+		s.logError("Failed to estimate tombstones", err)
+		return
+	}
+	if entries == 0 {
+		return
+	}
+	if ratio := float64(tombstones) / float64(entries); ratio > s.policy.TombstoneRatio {
+		s.lastRatio = now
+		s.compact(ScheduledCompaction{TombstoneRatio: ratio})
+	}
+}
+
+// compact compacts the database, and reports the compaction.
+func (s *CompactionScheduler) compact(c ScheduledCompaction) {
+	start := time.Now()
+	c.Err = Compact(s.db, s.policy.Start, s.policy.End)
+	c.Duration = time.Since(start)
+	if c.Err != nil {
+		s.logError("Scheduled compaction failed", c.Err)
+	} else if s.policy.Logger != nil {
+		s.policy.Logger.Info("Scheduled compaction", "in_window", c.Window != nil,
+			"tombstone_ratio", c.TombstoneRatio, "duration", c.Duration)
+	}
+	if s.policy.OnCompaction != nil {
+		s.policy.OnCompaction(c)
+	}
+}
+
+func (s *CompactionScheduler) logError(msg string, err error) {
+	if s.policy.Logger != nil {
+		s.policy.Logger.Error(msg, "err", err)
+	}
+}
+
+// Close stops the scheduler, waiting for a compaction in progress to end. The
+// database is not closed.
+func (s *CompactionScheduler) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.quit)
+	})
+	s.wg.Wait()
+	return nil
+}
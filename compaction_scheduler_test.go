@@ -0,0 +1,171 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// compactCountingDB counts its compactions, and reports the given tombstones.
+type compactCountingDB struct {
+	DB
+	compactions         int
+	tombstones, entries uint64
+	err                 error
+}
+
+func (db *compactCountingDB) Compact(start, end []byte) error {
+	db.compactions++
+	return db.err
+}
+
+func (db *compactCountingDB) EstimateTombstones() (uint64, uint64, error) {
+	return db.tombstones, db.entries, nil
+}
+
+func TestCompactionWindowContains(t *testing.T) {
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+	testcases := []struct {
+		window CompactionWindow
+		t      time.Time
+		start  time.Time
+		ok     bool
+	}{
+		{CompactionWindow{2 * time.Hour, 4 * time.Hour}, at(3, 0), at(2, 0), true},
+		{CompactionWindow{2 * time.Hour, 4 * time.Hour}, at(4, 0), time.Time{}, false},
+		{CompactionWindow{2 * time.Hour, 4 * time.Hour}, at(1, 59), time.Time{}, false},
+		{CompactionWindow{23 * time.Hour, time.Hour}, at(23, 30), at(23, 0), true},
+		{CompactionWindow{23 * time.Hour, time.Hour}, at(0, 30), at(-1, 0), true},
+		{CompactionWindow{23 * time.Hour, time.Hour}, at(12, 0), time.Time{}, false},
+	}
+	for i, tc := range testcases {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			start, ok := tc.window.contains(tc.t)
+			assert.Equal(t, tc.ok, ok)
+			if ok {
+				assert.Equal(t, tc.start, start)
+			}
+		})
+	}
+}
+
+func TestCompactionSchedulerWindows(t *testing.T) {
+	db := &compactCountingDB{DB: NewMemDB()}
+	var runs []ScheduledCompaction
+	s, err := NewCompactionScheduler(db, CompactionPolicy{
+		Windows:       []CompactionWindow{{Start: 2 * time.Hour, End: 4 * time.Hour}},
+		CheckInterval: time.Hour,
+		OnCompaction:  func(c ScheduledCompaction) { runs = append(runs, c) },
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	for _, check := range []struct {
+		offset      time.Duration
+		compactions int
+	}{
+		{time.Hour, 0},
+		{2 * time.Hour, 1},
+		{3 * time.Hour, 1}, // once per window
+		{5 * time.Hour, 1},
+		{26 * time.Hour, 2}, // the next day
+	} {
+		s.now = func() time.Time { return day.Add(check.offset) }
+		s.check()
+		assert.Equal(t, check.compactions, db.compactions, check.offset)
+	}
+	require.Len(t, runs, 2)
+	assert.NotNil(t, runs[0].Window)
+	assert.NoError(t, runs[0].Err)
+
+	// Failures are reported.
+	db.err = errors.New("boom")
+	s.now = func() time.Time { return day.Add(50 * time.Hour) }
+	s.check()
+	require.Len(t, runs, 3)
+	assert.Equal(t, db.err, runs[2].Err)
+}
+
+func TestCompactionSchedulerTombstoneRatio(t *testing.T) {
+	db := &compactCountingDB{DB: NewMemDB(), tombstones: 10, entries: 100}
+	var runs []ScheduledCompaction
+	s, err := NewCompactionScheduler(db, CompactionPolicy{
+		TombstoneRatio: 0.2,
+		MinInterval:    time.Hour,
+		CheckInterval:  time.Hour,
+		OnCompaction:   func(c ScheduledCompaction) { runs = append(runs, c) },
+	})
+	require.NoError(t, err)
+	defer s.Close()
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	s.check()
+	assert.Zero(t, db.compactions)
+
+	db.tombstones = 50
+	s.check()
+	assert.Equal(t, 1, db.compactions)
+	require.Len(t, runs, 1)
+	assert.Nil(t, runs[0].Window)
+	assert.Equal(t, 0.5, runs[0].TombstoneRatio)
+
+	// Not again before MinInterval.
+	now = now.Add(30 * time.Minute)
+	s.check()
+	assert.Equal(t, 1, db.compactions)
+	now = now.Add(time.Hour)
+	s.check()
+	assert.Equal(t, 2, db.compactions)
+}
+
+func TestCompactionSchedulerNotSupported(t *testing.T) {
+	_, err := NewCompactionScheduler(NewMemDB(), CompactionPolicy{})
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = NewCompactionScheduler(NewPrefixDB(&compactCountingDB{DB: NewMemDB()}, bz("p")),
+		CompactionPolicy{TombstoneRatio: 0.5})
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestCompactionSchedulerPebble(t *testing.T) {
+	// The tombstones are only compacted away by the scheduler.
+	db, err := NewDB("db", PebbleDBBackend, t.TempDir(),
+		WithBackendOptions(&pebble.Options{DisableAutomaticCompactions: true}))
+	require.NoError(t, err)
+	defer db.Close()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, db.Set(int642Bytes(int64(i)), bz("value")))
+	}
+	require.NoError(t, Flush(db))
+	for i := 0; i < 800; i++ {
+		require.NoError(t, db.Delete(int642Bytes(int64(i))))
+	}
+	require.NoError(t, Flush(db))
+	tombstones, entries, err := db.(TombstoneEstimator).EstimateTombstones()
+	require.NoError(t, err)
+	assert.EqualValues(t, 800, tombstones)
+	assert.EqualValues(t, 1800, entries)
+
+	done := make(chan ScheduledCompaction, 1)
+	s, err := NewCompactionScheduler(db, CompactionPolicy{
+		TombstoneRatio: 0.3,
+		CheckInterval:  time.Millisecond,
+		OnCompaction:   func(c ScheduledCompaction) { done <- c },
+	})
+	require.NoError(t, err)
+	c := <-done
+	require.NoError(t, s.Close())
+	require.NoError(t, c.Err)
+
+	tombstones, entries, err = db.(TombstoneEstimator).EstimateTombstones()
+	require.NoError(t, err)
+	assert.Zero(t, tombstones)
+	assert.EqualValues(t, 200, entries)
+}
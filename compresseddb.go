@@ -0,0 +1,277 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is a compression algorithm of a CompressedDB. Its value is the
+// header byte of the values it compressed.
+type Compression byte
+
+const (
+	// NoCompression stores values as is.
+	NoCompression Compression = iota
+	// SnappyCompression compresses values with snappy, which is fast but
+	// compresses less.
+	SnappyCompression
+	// ZstdCompression compresses values with zstd, which compresses more but is
+	// slower.
+	ZstdCompression
+)
+
+// String implements fmt.Stringer.
+func (c Compression) String() string {
+	switch c {
+	case NoCompression:
+		return "none"
+	case SnappyCompression:
+		return "snappy"
+	case ZstdCompression:
+		return "zstd"
+	default:
+		return fmt.Sprintf("Compression(%d)", byte(c))
+	}
+}
+
+// CompressedDB compresses the values of a database which are larger than a
+// threshold, and decompresses them when they are read. It helps with
+// compressible data, such as blocks and transactions, on backends without good
+// built-in compression.
+//
+// Every value is stored with a header byte naming its compression, so values
+// written with another compression, or left uncompressed because they were
+// small or did not compress, remain readable. The underlying database must
+// therefore only be used through the CompressedDB.
+type CompressedDB struct {
+	db          DB
+	compression Compression
+	threshold   int
+
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+}
+
+var _ DB = (*CompressedDB)(nil)
+
+// NewCompressedDB creates a CompressedDB over db, compressing the values of at
+// least threshold bytes with compression. Closing the CompressedDB closes db.
+func NewCompressedDB(db DB, compression Compression, threshold int) (*CompressedDB, error) {
+	if compression > ZstdCompression {
+		return nil, fmt.Errorf("unknown compression %v", compression)
+	}
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		encoder.Close()
+		return nil, err
+	}
+	return &CompressedDB{
+		db:          db,
+		compression: compression,
+		threshold:   threshold,
+		zstdEncoder: encoder,
+		zstdDecoder: decoder,
+	}, nil
+}
+
+// encode returns the stored form of value: a header byte followed by the
+// compressed value, or by the value itself if it is too small or does not
+// compress.
+func (cdb *CompressedDB) encode(value []byte) []byte {
+	var compressed []byte
+	if len(value) >= cdb.threshold {
+		switch cdb.compression {
+		case SnappyCompression:
+			compressed = snappy.Encode(nil, value)
+		case ZstdCompression:
+			compressed = cdb.zstdEncoder.EncodeAll(value, nil)
+		}
+	}
+	if compressed == nil || len(compressed) >= len(value) {
+		return append([]byte{byte(NoCompression)}, value...)
+	}
+	return append([]byte{byte(cdb.compression)}, compressed...)
+}
+
+// decode returns the value stored as item.
+func (cdb *CompressedDB) decode(item []byte) ([]byte, error) {
+	if len(item) == 0 {
+		return nil, fmt.Errorf("%w: compressed value without header", ErrCorrupted)
+	}
+	var (
+		value []byte
+		err   error
+	)
+	switch Compression(item[0]) {
+	case NoCompression:
+		return item[1:], nil
+	case SnappyCompression:
+		value, err = snappy.Decode(nil, item[1:])
+	case ZstdCompression:
+		value, err = cdb.zstdDecoder.DecodeAll(item[1:], []byte{})
+	default:
+		err = fmt.Errorf("unknown compression %v", Compression(item[0]))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupted, err)
+	}
+	return value, nil
+}
+
+// Get implements DB.
+func (cdb *CompressedDB) Get(key []byte) ([]byte, error) {
+	item, err := cdb.db.Get(key)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	return cdb.decode(item)
+}
+
+// Has implements DB.
+func (cdb *CompressedDB) Has(key []byte) (bool, error) {
+	return cdb.db.Has(key)
+}
+
+// Set implements DB.
+func (cdb *CompressedDB) Set(key []byte, value []byte) error {
+	if value == nil {
+		return errValueNil
+	}
+	return cdb.db.Set(key, cdb.encode(value))
+}
+
+// SetSync implements DB.
+func (cdb *CompressedDB) SetSync(key []byte, value []byte) error {
+	if value == nil {
+		return errValueNil
+	}
+	return cdb.db.SetSync(key, cdb.encode(value))
+}
+
+// Delete implements DB.
+func (cdb *CompressedDB) Delete(key []byte) error {
+	return cdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (cdb *CompressedDB) DeleteSync(key []byte) error {
+	return cdb.db.DeleteSync(key)
+}
+
+// Iterator implements DB.
+func (cdb *CompressedDB) Iterator(start, end []byte) (Iterator, error) {
+	itr, err := cdb.db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newCompressedDBIterator(cdb, itr), nil
+}
+
+// ReverseIterator implements DB.
+func (cdb *CompressedDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	itr, err := cdb.db.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newCompressedDBIterator(cdb, itr), nil
+}
+
+// Close implements DB.
+func (cdb *CompressedDB) Close() error {
+	cdb.zstdDecoder.Close()
+	if err := cdb.zstdEncoder.Close(); err != nil {
+		return err
+	}
+	return cdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (cdb *CompressedDB) NewBatch() Batch {
+	return newCompressedDBBatch(cdb)
+}
+
+// Print implements DB.
+func (cdb *CompressedDB) Print() error {
+	itr, err := cdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB.
+func (cdb *CompressedDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range cdb.db.Stats() {
+		stats["compressed.db."+k] = v
+	}
+	stats["compressed.compression"] = cdb.compression.String()
+	stats["compressed.threshold"] = strconv.Itoa(cdb.threshold)
+	return stats
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+// The sizes are those of the compressed data.
+func (cdb *CompressedDB) Metrics() (Metrics, error) {
+	return GetMetrics(cdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (cdb *CompressedDB) EstimateCount() (uint64, error) {
+	return EstimateCount(cdb.db)
+}
+
+// EstimateSize implements SizeEstimator, with the compressed size.
+func (cdb *CompressedDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(cdb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (cdb *CompressedDB) Compact(start, end []byte) error {
+	return Compact(cdb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (cdb *CompressedDB) Flush() error {
+	return Flush(cdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (cdb *CompressedDB) Sync() error {
+	return Sync(cdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it. The
+// backup holds the compressed values.
+func (cdb *CompressedDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, cdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+// The checkpoint holds the compressed values.
+func (cdb *CompressedDB) Checkpoint(path string) error {
+	return Checkpoint(cdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (cdb *CompressedDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, cdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which CompressedDB passes through.
+func (cdb *CompressedDB) Capabilities() Capability {
+	return Capabilities(cdb.db) & (CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
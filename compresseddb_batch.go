@@ -0,0 +1,55 @@
+package db
+
+// compressedDBBatch compresses the values set in a batch of the underlying
+// database.
+type compressedDBBatch struct {
+	db     *CompressedDB
+	source Batch
+}
+
+var _ Batch = (*compressedDBBatch)(nil)
+
+func newCompressedDBBatch(db *CompressedDB) *compressedDBBatch {
+	return &compressedDBBatch{
+		db:     db,
+		source: db.db.NewBatch(),
+	}
+}
+
+// Set implements Batch.
+func (b *compressedDBBatch) Set(key, value []byte) error {
+	if value == nil {
+		return errValueNil
+	}
+	return b.source.Set(key, b.db.encode(value))
+}
+
+// Delete implements Batch.
+func (b *compressedDBBatch) Delete(key []byte) error {
+	return b.source.Delete(key)
+}
+
+// Count implements Batch.
+func (b *compressedDBBatch) Count() int {
+	return b.source.Count()
+}
+
+// SizeBytes implements Batch, with the compressed size.
+func (b *compressedDBBatch) SizeBytes() int {
+	return b.source.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *compressedDBBatch) Write() error {
+	return b.source.Write()
+}
+
+// WriteSync implements Batch.
+func (b *compressedDBBatch) WriteSync() error {
+	return b.source.WriteSync()
+}
+
+// Close implements Batch.
+func (b *compressedDBBatch) Close() error {
+	return b.source.Close()
+}
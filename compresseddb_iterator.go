@@ -0,0 +1,78 @@
+package db
+
+// compressedDBIterator decompresses the values of an iterator of the
+// underlying database. If a value cannot be decompressed, the iterator becomes
+// invalid and Error returns the error.
+type compressedDBIterator struct {
+	db     *CompressedDB
+	source Iterator
+	value  []byte
+	err    error
+}
+
+var _ Iterator = (*compressedDBIterator)(nil)
+
+func newCompressedDBIterator(db *CompressedDB, source Iterator) *compressedDBIterator {
+	itr := &compressedDBIterator{
+		db:     db,
+		source: source,
+	}
+	itr.decodeValue()
+	return itr
+}
+
+// decodeValue decompresses the value of the item the iterator landed on.
+func (itr *compressedDBIterator) decodeValue() {
+	itr.value = nil
+	if itr.source.Valid() {
+		itr.value, itr.err = itr.db.decode(itr.source.Value())
+	}
+}
+
+// Domain implements Iterator.
+func (itr *compressedDBIterator) Domain() ([]byte, []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *compressedDBIterator) Valid() bool {
+	return itr.err == nil && itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *compressedDBIterator) Next() {
+	itr.assertIsValid()
+	itr.source.Next()
+	itr.decodeValue()
+}
+
+// Key implements Iterator.
+func (itr *compressedDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *compressedDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.value
+}
+
+// Error implements Iterator.
+func (itr *compressedDBIterator) Error() error {
+	if itr.err != nil {
+		return itr.err
+	}
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *compressedDBIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *compressedDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
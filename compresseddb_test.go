@@ -0,0 +1,93 @@
+package db
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressedDB(t *testing.T) {
+	large := bytes.Repeat([]byte("block"), 100)
+	for _, compression := range []Compression{NoCompression, SnappyCompression, ZstdCompression} {
+		t.Run(compression.String(), func(t *testing.T) {
+			source := NewMemDB()
+			cdb, err := NewCompressedDB(source, compression, 64)
+			require.NoError(t, err)
+			defer cdb.Close()
+
+			require.NoError(t, cdb.Set(bz("large"), large))
+			require.NoError(t, cdb.Set(bz("small"), bz("value")))
+			require.NoError(t, cdb.Set(bz("empty"), []byte{}))
+			batch := cdb.NewBatch()
+			require.NoError(t, batch.Set(bz("batch"), large))
+			require.NoError(t, batch.Write())
+			require.NoError(t, batch.Close())
+
+			checkValue(t, cdb, bz("large"), large)
+			checkValue(t, cdb, bz("small"), bz("value"))
+			checkValue(t, cdb, bz("empty"), []byte{})
+			checkValue(t, cdb, bz("missing"), nil)
+
+			// Small values are stored as is, large ones compressed.
+			checkValue(t, source, bz("small"), append([]byte{0}, bz("value")...))
+			stored, err := source.Get(bz("large"))
+			require.NoError(t, err)
+			assert.Equal(t, byte(compression), stored[0])
+			if compression != NoCompression {
+				assert.Less(t, len(stored), len(large)/4)
+			}
+
+			itr, err := cdb.Iterator(nil, nil)
+			require.NoError(t, err)
+			checkItem(t, itr, bz("batch"), large)
+			checkNext(t, itr, true)
+			checkItem(t, itr, bz("empty"), []byte{})
+			checkNext(t, itr, true)
+			checkItem(t, itr, bz("large"), large)
+			checkNext(t, itr, true)
+			checkItem(t, itr, bz("small"), bz("value"))
+			checkNext(t, itr, false)
+			require.NoError(t, itr.Close())
+		})
+	}
+}
+
+func TestCompressedDBMixed(t *testing.T) {
+	large := bytes.Repeat([]byte("tx"), 100)
+	source := NewMemDB()
+	snappyDB, err := NewCompressedDB(source, SnappyCompression, 0)
+	require.NoError(t, err)
+	require.NoError(t, snappyDB.Set(bz("a"), large))
+
+	// Values written with another compression remain readable.
+	zstdDB, err := NewCompressedDB(source, ZstdCompression, 0)
+	require.NoError(t, err)
+	defer zstdDB.Close()
+	checkValue(t, zstdDB, bz("a"), large)
+
+	// Incompressible values are stored as is.
+	require.NoError(t, zstdDB.Set(bz("b"), bz("x")))
+	checkValue(t, source, bz("b"), bz("\x00x"))
+}
+
+func TestCompressedDBCorrupted(t *testing.T) {
+	source := NewMemDB()
+	cdb, err := NewCompressedDB(source, ZstdCompression, 0)
+	require.NoError(t, err)
+	defer cdb.Close()
+
+	require.NoError(t, source.Set(bz("a"), bz("\x02garbage")))
+	_, err = cdb.Get(bz("a"))
+	assert.ErrorIs(t, err, ErrCorrupted)
+
+	itr, err := cdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	assert.False(t, itr.Valid())
+	assert.ErrorIs(t, itr.Error(), ErrCorrupted)
+	require.NoError(t, itr.Close())
+
+	_, err = NewCompressedDB(source, Compression(3), 0)
+	assert.Error(t, err)
+}
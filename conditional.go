@@ -0,0 +1,108 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"hash/maphash"
+	"sync"
+)
+
+const (
+	// casMaxAttempts is the number of times CompareAndSwap tries a native
+	// transaction before giving up with ErrTxnConflict.
+	casMaxAttempts = 10
+
+	// casLockStripes is the number of locks serializing the fallback
+	// CompareAndSwap, which are shared by all databases and picked by key.
+	casLockStripes = 64
+)
+
+var (
+	casSeed  = maphash.MakeSeed()
+	casLocks [casLockStripes]sync.Mutex
+)
+
+// ConditionalWriter is implemented by databases which can atomically set a key
+// depending on its current value.
+type ConditionalWriter interface {
+	// CompareAndSwap sets key to new if its current value is old, where a nil
+	// old means the key must not exist, and reports whether it did. An empty
+	// old only matches an existing empty value.
+	// CONTRACT: key, old, new readonly []byte
+	CompareAndSwap(key, old, new []byte) (bool, error)
+}
+
+// CompareAndSwap atomically sets key to new in db if its current value is old,
+// with a nil old meaning the key must not exist, and reports whether it did.
+//
+// It uses db's ConditionalWriter implementation if any, and otherwise a
+// transaction if db implements Transactor, retrying on conflicts. Failing both,
+// the read and write are made under a lock internal to this package: the swap
+// is then only atomic with respect to the other conditional writes, and other
+// writers to the key must be excluded by the caller.
+func CompareAndSwap(db DB, key, old, new []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if new == nil {
+		return false, errValueNil
+	}
+	if cw, ok := db.(ConditionalWriter); ok {
+		return cw.CompareAndSwap(key, old, new)
+	}
+	if t, ok := db.(Transactor); ok {
+		return compareAndSwapTxn(t, key, old, new)
+	}
+
+	mtx := &casLocks[maphash.Bytes(casSeed, key)%casLockStripes]
+	mtx.Lock()
+	defer mtx.Unlock()
+	current, err := db.Get(key)
+	if err != nil || !casMatch(current, old) {
+		return false, err
+	}
+	return true, db.Set(key, new)
+}
+
+// SetIfAbsent atomically sets key to value in db if it does not exist, and
+// reports whether it did. It is CompareAndSwap with a nil old value.
+func SetIfAbsent(db DB, key, value []byte) (bool, error) {
+	return CompareAndSwap(db, key, nil, value)
+}
+
+// compareAndSwapTxn runs CompareAndSwap in a native transaction of t.
+func compareAndSwapTxn(t Transactor, key, old, new []byte) (bool, error) {
+	for attempt := 1; ; attempt++ {
+		swapped, err := func() (bool, error) {
+			txn, err := t.NewTxn()
+			if err != nil {
+				return false, err
+			}
+			defer txn.Discard() //nolint:errcheck // no-op once committed
+			current, err := txn.Get(key)
+			if err != nil || !casMatch(current, old) {
+				return false, err
+			}
+			if err := txn.Set(key, new); err != nil {
+				return false, err
+			}
+			return true, txn.Commit()
+		}()
+		if errors.Is(err, ErrTxnConflict) && attempt < casMaxAttempts {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		return swapped, nil
+	}
+}
+
+// casMatch reports whether the current value of a key, nil if it does not
+// exist, matches the expected old value of CompareAndSwap.
+func casMatch(current, old []byte) bool {
+	if current == nil || old == nil {
+		return current == nil && old == nil
+	}
+	return bytes.Equal(current, old)
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareAndSwap(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			ok, err := SetIfAbsent(db, bz("a"), bz("1"))
+			require.NoError(t, err)
+			assert.True(t, ok)
+			ok, err = SetIfAbsent(db, bz("a"), bz("2"))
+			require.NoError(t, err)
+			assert.False(t, ok)
+			checkValue(t, db, bz("a"), bz("1"))
+
+			ok, err = CompareAndSwap(db, bz("a"), bz("2"), bz("3"))
+			require.NoError(t, err)
+			assert.False(t, ok)
+			ok, err = CompareAndSwap(db, bz("a"), bz("1"), bz("3"))
+			require.NoError(t, err)
+			assert.True(t, ok)
+			checkValue(t, db, bz("a"), bz("3"))
+
+			// An empty old value does not match a missing key.
+			ok, err = CompareAndSwap(db, bz("b"), []byte{}, bz("1"))
+			require.NoError(t, err)
+			assert.False(t, ok)
+			checkValue(t, db, bz("b"), nil)
+
+			_, err = CompareAndSwap(db, []byte{}, nil, bz("1"))
+			assert.Equal(t, errKeyEmpty, err)
+			_, err = CompareAndSwap(db, bz("a"), nil, nil)
+			assert.Equal(t, errValueNil, err)
+		})
+	}
+}
+
+func TestCompareAndSwapConcurrent(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			// Concurrent increments must not be lost.
+			const workers, increments = 4, 25
+			require.NoError(t, db.Set(bz("counter"), int642Bytes(0)))
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := 0; i < increments; {
+						value, err := db.Get(bz("counter"))
+						if !assert.NoError(t, err) {
+							return
+						}
+						next := int642Bytes(bytes2Int64(value) + 1)
+						ok, err := CompareAndSwap(db, bz("counter"), value, next)
+						if !assert.NoError(t, err) {
+							return
+						}
+						if ok {
+							i++
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			checkValue(t, db, bz("counter"), int642Bytes(workers*increments))
+		})
+	}
+}
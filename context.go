@@ -0,0 +1,222 @@
+package db
+
+import "context"
+
+// ContextDB is implemented by databases whose operations can be cancelled
+// through a context, such as network-backed ones. The methods behave like their
+// DB counterparts, but give up with the context error once it is done.
+type ContextDB interface {
+	GetContext(ctx context.Context, key []byte) ([]byte, error)
+	HasContext(ctx context.Context, key []byte) (bool, error)
+	SetContext(ctx context.Context, key, value []byte) error
+	SetSyncContext(ctx context.Context, key, value []byte) error
+	DeleteContext(ctx context.Context, key []byte) error
+	DeleteSyncContext(ctx context.Context, key []byte) error
+
+	// IteratorContext and ReverseIteratorContext return iterators which
+	// become invalid, with the context error as Error, once ctx is done.
+	IteratorContext(ctx context.Context, start, end []byte) (Iterator, error)
+	ReverseIteratorContext(ctx context.Context, start, end []byte) (Iterator, error)
+}
+
+// The helpers below use the ContextDB methods of db if it implements them.
+// Otherwise the call runs in a separate goroutine, and the helper returns the
+// context error as soon as ctx is done without waiting for it. The abandoned
+// call still runs to completion in the background, so a cancelled write may or
+// may not be applied.
+
+// GetContext is DB.Get with a context, see ContextDB.
+func GetContext(ctx context.Context, db DB, key []byte) ([]byte, error) {
+	if cdb, ok := db.(ContextDB); ok {
+		return cdb.GetContext(ctx, key)
+	}
+	var value []byte
+	err := runContext(ctx, func() (err error) {
+		value, err = db.Get(key)
+		return err
+	})
+	return value, err
+}
+
+// HasContext is DB.Has with a context, see ContextDB.
+func HasContext(ctx context.Context, db DB, key []byte) (bool, error) {
+	if cdb, ok := db.(ContextDB); ok {
+		return cdb.HasContext(ctx, key)
+	}
+	var has bool
+	err := runContext(ctx, func() (err error) {
+		has, err = db.Has(key)
+		return err
+	})
+	return has, err
+}
+
+// SetContext is DB.Set with a context, see ContextDB.
+func SetContext(ctx context.Context, db DB, key, value []byte) error {
+	if cdb, ok := db.(ContextDB); ok {
+		return cdb.SetContext(ctx, key, value)
+	}
+	return runContext(ctx, func() error { return db.Set(key, value) })
+}
+
+// SetSyncContext is DB.SetSync with a context, see ContextDB.
+func SetSyncContext(ctx context.Context, db DB, key, value []byte) error {
+	if cdb, ok := db.(ContextDB); ok {
+		return cdb.SetSyncContext(ctx, key, value)
+	}
+	return runContext(ctx, func() error { return db.SetSync(key, value) })
+}
+
+// DeleteContext is DB.Delete with a context, see ContextDB.
+func DeleteContext(ctx context.Context, db DB, key []byte) error {
+	if cdb, ok := db.(ContextDB); ok {
+		return cdb.DeleteContext(ctx, key)
+	}
+	return runContext(ctx, func() error { return db.Delete(key) })
+}
+
+// DeleteSyncContext is DB.DeleteSync with a context, see ContextDB.
+func DeleteSyncContext(ctx context.Context, db DB, key []byte) error {
+	if cdb, ok := db.(ContextDB); ok {
+		return cdb.DeleteSyncContext(ctx, key)
+	}
+	return runContext(ctx, func() error { return db.DeleteSync(key) })
+}
+
+// IteratorContext is DB.Iterator with a context, see ContextDB.
+func IteratorContext(ctx context.Context, db DB, start, end []byte) (Iterator, error) {
+	if cdb, ok := db.(ContextDB); ok {
+		return cdb.IteratorContext(ctx, start, end)
+	}
+	return iteratorContext(ctx, func() (Iterator, error) { return db.Iterator(start, end) })
+}
+
+// ReverseIteratorContext is DB.ReverseIterator with a context, see ContextDB.
+func ReverseIteratorContext(ctx context.Context, db DB, start, end []byte) (Iterator, error) {
+	if cdb, ok := db.(ContextDB); ok {
+		return cdb.ReverseIteratorContext(ctx, start, end)
+	}
+	return iteratorContext(ctx, func() (Iterator, error) { return db.ReverseIterator(start, end) })
+}
+
+// runContext runs fn, returning early with the context error if ctx is done
+// first.
+func runContext(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ctx.Done() == nil {
+		// Never cancelled, e.g. context.Background().
+		return fn()
+	}
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// iteratorContext creates an iterator with newIterator and ties it to ctx. If
+// ctx is done before the iterator is created, it is closed once it is.
+func iteratorContext(ctx context.Context, newIterator func() (Iterator, error)) (Iterator, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		return newIterator()
+	}
+	type result struct {
+		itr Iterator
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		itr, err := newIterator()
+		done <- result{itr, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, r.err
+		}
+		return newContextIterator(ctx, r.itr), nil
+	case <-ctx.Done():
+		go func() {
+			if r := <-done; r.err == nil {
+				r.itr.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// contextIterator invalidates the source iterator once its context is done.
+// The context is checked on every Next.
+type contextIterator struct {
+	ctx    context.Context
+	source Iterator
+	err    error
+}
+
+var _ Iterator = (*contextIterator)(nil)
+
+// newContextIterator wraps source so that it stops when ctx is done.
+func newContextIterator(ctx context.Context, source Iterator) Iterator {
+	if ctx.Done() == nil {
+		return source
+	}
+	itr := &contextIterator{ctx: ctx, source: source}
+	itr.err = ctx.Err()
+	return itr
+}
+
+// Domain implements Iterator.
+func (itr *contextIterator) Domain() (start, end []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *contextIterator) Valid() bool {
+	return itr.err == nil && itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *contextIterator) Next() {
+	itr.assertIsValid()
+	itr.source.Next()
+	itr.err = itr.ctx.Err()
+}
+
+// Key implements Iterator.
+func (itr *contextIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *contextIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.source.Value()
+}
+
+// Error implements Iterator.
+func (itr *contextIterator) Error() error {
+	if itr.err != nil {
+		return itr.err
+	}
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *contextIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *contextIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingDB blocks Get, Has and Iterator until unblock is closed.
+type blockingDB struct {
+	DB
+	unblock chan struct{}
+}
+
+func (db *blockingDB) Get(key []byte) ([]byte, error) {
+	<-db.unblock
+	return db.DB.Get(key)
+}
+
+func (db *blockingDB) Has(key []byte) (bool, error) {
+	<-db.unblock
+	return db.DB.Has(key)
+}
+
+func (db *blockingDB) Iterator(start, end []byte) (Iterator, error) {
+	<-db.unblock
+	return db.DB.Iterator(start, end)
+}
+
+func TestContextFallback(t *testing.T) {
+	db := NewMemDB()
+	require.NoError(t, SetContext(context.Background(), db, bz("a"), bz("1")))
+	require.NoError(t, SetSyncContext(context.Background(), db, bz("b"), bz("2")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	value, err := GetContext(ctx, db, bz("a"))
+	require.NoError(t, err)
+	assert.Equal(t, bz("1"), value)
+	ok, err := HasContext(ctx, db, bz("b"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, DeleteSyncContext(ctx, db, bz("b")))
+	checkValue(t, db, bz("b"), nil)
+
+	cancel()
+	_, err = GetContext(ctx, db, bz("a"))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, DeleteContext(ctx, db, bz("a")), context.Canceled)
+	checkValue(t, db, bz("a"), bz("1"))
+	_, err = IteratorContext(ctx, db, nil, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestContextFallbackDeadline(t *testing.T) {
+	db := &blockingDB{DB: NewMemDB(), unblock: make(chan struct{})}
+	defer close(db.unblock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := GetContext(ctx, db, bz("a"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = IteratorContext(ctx, db, nil, nil)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestIteratorContextCancel(t *testing.T) {
+	db := NewMemDB()
+	for i := int64(0); i < 10; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	itr, err := ReverseIteratorContext(ctx, db, nil, nil)
+	require.NoError(t, err)
+	defer itr.Close()
+	checkItem(t, itr, int642Bytes(9), int642Bytes(9))
+	checkNext(t, itr, true)
+	checkItem(t, itr, int642Bytes(8), int642Bytes(8))
+
+	cancel()
+	checkNext(t, itr, false)
+	assert.ErrorIs(t, itr.Error(), context.Canceled)
+	checkNextPanics(t, itr)
+}
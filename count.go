@@ -0,0 +1,82 @@
+package db
+
+// Counter is implemented by databases which keep track of their number of keys,
+// and can therefore count them exactly without iterating over them.
+type Counter interface {
+	// Count returns the number of keys in the database.
+	Count() (uint64, error)
+}
+
+// CountEstimator is implemented by databases which can estimate their number
+// of keys from metadata, e.g. the properties of their on-disk tables.
+type CountEstimator interface {
+	// EstimateCount returns the approximate number of keys in the database. The
+	// estimate may count overwritten or deleted keys which have not been
+	// compacted yet, and may only account for data already flushed to disk.
+	EstimateCount() (uint64, error)
+}
+
+// Count returns the exact number of keys in db. It uses the native count of db
+// if it implements Counter, and otherwise iterates over all keys, without
+// reading their values where the backend allows it.
+func Count(db DB) (uint64, error) {
+	if c, ok := db.(Counter); ok {
+		return c.Count()
+	}
+	itr, err := IteratorWithOptions(db, nil, nil, IteratorOptions{KeysOnly: true})
+	if err != nil {
+		return 0, err
+	}
+	defer itr.Close()
+
+	var count uint64
+	for ; itr.Valid(); itr.Next() {
+		count++
+	}
+	if err := itr.Error(); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// EstimateCount returns the approximate number of keys in db without iterating
+// over them, see CountEstimator. If db only implements Counter its exact count
+// is returned. It returns ErrNotSupported if db implements neither, in which
+// case Count can be used instead.
+func EstimateCount(db DB) (uint64, error) {
+	if ce, ok := db.(CountEstimator); ok {
+		return ce.EstimateCount()
+	}
+	if c, ok := db.(Counter); ok {
+		return c.Count()
+	}
+	return 0, ErrNotSupported
+}
+
+// estimateCountAll sums the estimates of dbs. Unlike sizes, a partial count
+// would be misleading, so it returns ErrNotSupported unless all of them
+// support it.
+func estimateCountAll(dbs []DB) (uint64, error) {
+	var total uint64
+	for _, db := range dbs {
+		count, err := EstimateCount(db)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// countAll sums the exact counts of dbs, which must not have keys in common.
+func countAll(dbs []DB) (uint64, error) {
+	var total uint64
+	for _, db := range dbs {
+		count, err := Count(db)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCount(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			count, err := Count(db)
+			require.NoError(t, err)
+			assert.Zero(t, count)
+
+			for i := int64(0); i < 1000; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+			// Overwrites are not counted twice.
+			require.NoError(t, db.Set(int642Bytes(0), bz("new")))
+			for i := int64(0); i < 100; i++ {
+				require.NoError(t, db.Delete(int642Bytes(i)))
+			}
+
+			count, err = Count(db)
+			require.NoError(t, err)
+			assert.EqualValues(t, 900, count)
+
+			err = Compact(db, nil, nil)
+			if !errors.Is(err, ErrNotSupported) {
+				require.NoError(t, err)
+			}
+			estimate, err := EstimateCount(db)
+			if errors.Is(err, ErrNotSupported) {
+				return
+			}
+			require.NoError(t, err)
+			assert.LessOrEqual(t, estimate, uint64(2000))
+		})
+	}
+}
+
+func TestCountNative(t *testing.T) {
+	db := NewMemDB()
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.Set(bz("b"), bz("2")))
+
+	sdb, err := NewShardedDB([]DB{db, NewMemDB()}, HashSharding)
+	require.NoError(t, err)
+	require.NoError(t, sdb.Set(bz("c"), bz("3")))
+
+	for _, db := range []DB{db, sdb} {
+		require.Implements(t, (*Counter)(nil), db)
+		count, err := Count(db)
+		require.NoError(t, err)
+		estimate, err := EstimateCount(db)
+		require.NoError(t, err)
+		assert.Equal(t, count, estimate)
+	}
+
+	// Without native support, keys can only be counted by iterating.
+	require.NoError(t, db.Set(bz("x1"), bz("4")))
+	pdb := NewPrefixDB(db, bz("x"))
+	count, err := Count(pdb)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	_, err = EstimateCount(pdb)
+	assert.ErrorIs(t, err, ErrNotSupported)
+
+	// A partial estimate is not returned.
+	tdb := NewTieredDB(NewMemDB(), NewDiscardDB(), TierPolicy{})
+	_, err = EstimateCount(tdb)
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestPebbleDBEstimateCount(t *testing.T) {
+	dir, err := os.MkdirTemp("", "count_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := NewPebbleDB("pebble", dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := int64(0); i < 1000; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+	}
+	require.NoError(t, db.DB().Flush())
+
+	estimate, err := EstimateCount(db)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1000, estimate)
+}
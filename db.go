@@ -1,6 +1,7 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -36,13 +37,31 @@ const (
 
 	BadgerDBBackend BackendType = "badgerdb"
 
-	// PebbleDBDBBackend represents pebble (uses github.com/cockroachdb/pebble)
+	// PebbleDBBackend represents pebble (uses github.com/cockroachdb/pebble)
 	//   - EXPERIMENTAL
-	//   - use pebble build tag (go build -tags pebbledb)
+	//   - pure go
 	PebbleDBBackend BackendType = "pebbledb"
+	// SQLiteDBBackend represents sqlite (uses github.com/mattn/go-sqlite3)
+	//   - EXPERIMENTAL
+	//   - requires gcc
+	//   - use sqlitedb build tag (go build -tags sqlitedb)
+	SQLiteDBBackend BackendType = "sqlitedb"
+	// LMDBBackend represents lmdb (uses github.com/bmatsuo/lmdb-go)
+	//   - EXPERIMENTAL
+	//   - requires gcc
+	//   - fast concurrent reads and iteration (memory-mapped)
+	//   - keys are limited to 511 bytes
+	//   - use lmdb build tag (go build -tags lmdb)
+	LMDBBackend BackendType = "lmdb"
+	// RemoteDBBackend represents a database served over gRPC (see remotedb)
+	//   - EXPERIMENTAL
+	//   - registered by importing github.com/cometbft/cometbft-db/remotedb
+	//   - dir is the server address, see remotedb for the accepted parameters
+	RemoteDBBackend BackendType = "remotedb"
 )
 
-type dbCreator func(name string, dir string) (DB, error)
+// dbCreator creates a database of a backend, configured by opts.
+type dbCreator func(name string, dir string, opts *Options) (DB, error)
 
 var backends = map[BackendType]dbCreator{}
 
@@ -54,8 +73,26 @@ func registerDBCreator(backend BackendType, creator dbCreator, force bool) {
 	backends[backend] = creator
 }
 
-// NewDB creates a new database of type backend with the given name.
-func NewDB(name string, backend BackendType, dir string) (DB, error) {
+// RegisterBackend makes a backend implemented outside of this package available
+// to NewDB. It is meant to be called from the init function of the implementing
+// package, and does nothing if the backend is already registered. Such backends
+// cannot be configured: NewDB fails with ErrNotSupported if any Option other
+// than WithLogger, WithStatsInterval, WithLockTimeout and WithMergeOperator is
+// given, including the event callbacks of WithOnCompaction and
+// WithOnWriteStall.
+func RegisterBackend(backend BackendType, creator func(name, dir string) (DB, error)) {
+	registerDBCreator(backend, func(name, dir string, opts *Options) (DB, error) {
+		if !opts.isDefault() {
+			return nil, fmt.Errorf("%s does not accept options: %w", backend, ErrNotSupported)
+		}
+		return creator(name, dir)
+	}, false)
+}
+
+// NewDB creates a new database of type backend with the given name, configured
+// by opts. The on-disk databases are locked with the file name.lock in dir,
+// which is left there once they are closed, see Options.LockTimeout.
+func NewDB(name string, backend BackendType, dir string, opts ...Option) (DB, error) {
 	dbCreator, ok := backends[backend]
 	if !ok {
 		keys := make([]string, 0, len(backends))
@@ -66,9 +103,31 @@ func NewDB(name string, backend BackendType, dir string) (DB, error) {
 			backend, strings.Join(keys, ","))
 	}
 
-	db, err := dbCreator(name, dir)
+	o := newOptions(opts)
+	if len(o.BackendParams) > 0 && !paramBackends[backend] {
+		return nil, fmt.Errorf("db_backend %s does not accept options, got %v: %w",
+			backend, sortedKeys(o.BackendParams), ErrNotSupported)
+	}
+	if fileBackends[backend] && !o.InMemory && o.FS == nil && !(o.ReadOnly && lockFreeReadBackends[backend]) {
+		lock, err := lockDB(name, dir, o.ReadOnly, o.LockTimeout)
+		var lerr *LockError
+		switch {
+		case errors.As(err, &lerr):
+			return nil, fmt.Errorf("failed to initialize database: %w", err)
+		case err != nil && !o.ReadOnly:
+			return nil, fmt.Errorf("failed to lock database: %w", err)
+		}
+		// Read-only databases are opened without lock if the lock file cannot
+		// be created, e.g. on a read-only file system.
+		o.lock = lock
+	}
+	db, err := dbCreator(name, dir, o)
 	if err != nil {
+		o.lock.release()
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
+	if _, ok := db.(Merger); o.MergeOperator != nil && !ok {
+		db = NewMergeDB(db, o.MergeOperator)
+	}
 	return db, nil
 }
@@ -0,0 +1,305 @@
+package dbbench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	db "github.com/cometbft/cometbft-db"
+)
+
+// Workload is a standardized benchmark workload.
+type Workload string
+
+const (
+	// FillSeq writes Config.Num keys in key order.
+	FillSeq Workload = "fillseq"
+	// FillRandom writes Config.Num keys drawn at random, so some are written
+	// several times and others not at all.
+	FillRandom Workload = "fillrandom"
+	// ReadRandom reads Config.Num keys drawn at random.
+	ReadRandom Workload = "readrandom"
+	// Scan iterates over Config.ScanLength keys from a random key, until about
+	// Config.Num keys are read.
+	Scan Workload = "scan"
+	// Mixed reads or writes Config.Num keys drawn at random, reading with the
+	// probability Config.ReadRatio.
+	Mixed Workload = "mixed"
+)
+
+// Workloads are all the workloads, in an order where the read workloads run
+// on the keys written by the fill workloads.
+var Workloads = []Workload{FillSeq, FillRandom, ReadRandom, Scan, Mixed}
+
+// ParseWorkloads parses a comma-separated list of workloads, such as
+// "fillseq,readrandom".
+func ParseWorkloads(s string) ([]Workload, error) {
+	var workloads []Workload
+	for _, name := range strings.Split(s, ",") {
+		w := Workload(strings.TrimSpace(name))
+		if !w.valid() {
+			return nil, fmt.Errorf("unknown workload %q", name)
+		}
+		workloads = append(workloads, w)
+	}
+	return workloads, nil
+}
+
+func (w Workload) valid() bool {
+	for _, v := range Workloads {
+		if w == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Config configures the workloads. The zero values are replaced by the
+// defaults.
+type Config struct {
+	// Num is the number of keys, and of operations of the workloads, 100000 by
+	// default.
+	Num int
+	// KeySize is the size of the keys in bytes, 16 by default.
+	KeySize int
+	// ValueSize is the size of the values in bytes, 100 by default.
+	ValueSize int
+	// BatchSize is the number of keys written per batch by the fill workloads,
+	// or 1, the default, for writing them one by one.
+	BatchSize int
+	// ScanLength is the number of keys read per iterator by Scan, 100 by
+	// default.
+	ScanLength int
+	// ReadRatio is the fraction of the operations of Mixed which are reads, 0.5
+	// by default. A negative ratio means writes only.
+	ReadRatio float64
+	// Sync makes the writes synchronous, with SetSync and WriteSync.
+	Sync bool
+	// Seed seeds the random keys and values, so that runs are reproducible.
+	Seed int64
+}
+
+func (cfg Config) withDefaults() Config {
+	if cfg.Num <= 0 {
+		cfg.Num = 100000
+	}
+	if cfg.KeySize <= 0 {
+		cfg.KeySize = 16
+	}
+	if cfg.ValueSize <= 0 {
+		cfg.ValueSize = 100
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1
+	}
+	if cfg.ScanLength <= 0 {
+		cfg.ScanLength = 100
+	}
+	if cfg.ReadRatio == 0 {
+		cfg.ReadRatio = 0.5
+	}
+	return cfg
+}
+
+// Result is the result of a workload.
+type Result struct {
+	Workload Workload
+	// Ops is the number of timed operations: key writes and reads, batch
+	// writes, or scans.
+	Ops int
+	// Keys is the number of keys written or found.
+	Keys int
+	// Bytes is the size of the keys and values written or found.
+	Bytes int64
+	// Elapsed is the duration of the workload.
+	Elapsed time.Duration
+	// P50, P90, P99 and P999 are the percentiles of the latency of the
+	// operations, and Max the slowest one.
+	P50, P90, P99, P999, Max time.Duration
+}
+
+// OpsPerSec returns the throughput in operations per second.
+func (r Result) OpsPerSec() float64 {
+	return float64(r.Ops) / r.Elapsed.Seconds()
+}
+
+// MBPerSec returns the throughput in MB (10^6 bytes) per second.
+func (r Result) MBPerSec() float64 {
+	return float64(r.Bytes) / 1e6 / r.Elapsed.Seconds()
+}
+
+// String formats the result on one line.
+func (r Result) String() string {
+	return fmt.Sprintf("%-10s : %10.0f ops/s %8.2f MB/s  p50 %v  p90 %v  p99 %v  p99.9 %v  max %v  (%d ops, %d keys in %v)",
+		r.Workload, r.OpsPerSec(), r.MBPerSec(), r.P50, r.P90, r.P99, r.P999, r.Max, r.Ops, r.Keys, r.Elapsed)
+}
+
+// Run runs a workload against database with cfg. The read workloads are meant
+// to run after a fill workload with the same Config.Num and Config.KeySize,
+// otherwise the keys are not found.
+func Run(ctx context.Context, database db.DB, workload Workload, cfg Config) (Result, error) {
+	b := newBench(database, cfg.withDefaults())
+	var (
+		ops int
+		op  func(i int) (keys, bytes int, err error)
+	)
+	switch workload {
+	case FillSeq:
+		ops, op = b.fillOps(), func(i int) (int, int, error) {
+			return b.fill(i, func(j int) int { return j })
+		}
+	case FillRandom:
+		ops, op = b.fillOps(), func(i int) (int, int, error) {
+			return b.fill(i, func(int) int { return b.rng.Intn(b.cfg.Num) })
+		}
+	case ReadRandom:
+		ops, op = b.cfg.Num, func(int) (int, int, error) {
+			return b.read()
+		}
+	case Scan:
+		ops, op = (b.cfg.Num+b.cfg.ScanLength-1)/b.cfg.ScanLength, func(int) (int, int, error) {
+			return b.scan()
+		}
+	case Mixed:
+		ops, op = b.cfg.Num, func(int) (int, int, error) {
+			if b.rng.Float64() < b.cfg.ReadRatio {
+				return b.read()
+			}
+			return b.write()
+		}
+	default:
+		return Result{}, fmt.Errorf("unknown workload %q", workload)
+	}
+
+	res := Result{Workload: workload, Ops: ops}
+	latencies := make([]time.Duration, ops)
+	start := time.Now()
+	for i := 0; i < ops; i++ {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		opStart := time.Now()
+		keys, bytes, err := op(i)
+		latencies[i] = time.Since(opStart)
+		if err != nil {
+			return Result{}, fmt.Errorf("%s: %w", workload, err)
+		}
+		res.Keys += keys
+		res.Bytes += int64(bytes)
+	}
+	res.Elapsed = time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	res.P50 = percentile(latencies, 0.5)
+	res.P90 = percentile(latencies, 0.9)
+	res.P99 = percentile(latencies, 0.99)
+	res.P999 = percentile(latencies, 0.999)
+	res.Max = latencies[len(latencies)-1]
+	return res, nil
+}
+
+// percentile returns the nearest-rank percentile q of the sorted latencies.
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	i := int(q*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// bench is the state of a workload.
+type bench struct {
+	db     db.DB
+	cfg    Config
+	rng    *rand.Rand
+	values []byte // random bytes, from which the values are sliced
+}
+
+func newBench(database db.DB, cfg Config) *bench {
+	rng := rand.New(rand.NewSource(cfg.Seed)) //nolint:gosec
+	// The values are slices of a buffer larger than the block sizes of the
+	// backends, so that they do not compress away.
+	values := make([]byte, 1<<20+cfg.ValueSize)
+	rng.Read(values)
+	return &bench{db: database, cfg: cfg, rng: rng, values: values}
+}
+
+// key returns the key numbered i, as a new slice since the databases may
+// retain it.
+func (b *bench) key(i int) []byte {
+	return []byte(fmt.Sprintf("%0*d", b.cfg.KeySize, i))
+}
+
+func (b *bench) value() []byte {
+	offset := b.rng.Intn(len(b.values) - b.cfg.ValueSize)
+	return b.values[offset : offset+b.cfg.ValueSize]
+}
+
+func (b *bench) fillOps() int {
+	return (b.cfg.Num + b.cfg.BatchSize - 1) / b.cfg.BatchSize
+}
+
+// fill writes the keys of the batch numbered i, numbering the keys with next.
+func (b *bench) fill(i int, next func(j int) int) (keys, bytes int, err error) {
+	if b.cfg.BatchSize == 1 {
+		return b.set(b.key(next(i)))
+	}
+	batch := b.db.NewBatch()
+	defer batch.Close()
+	for j := i * b.cfg.BatchSize; j < (i+1)*b.cfg.BatchSize && j < b.cfg.Num; j++ {
+		key, value := b.key(next(j)), b.value()
+		if err := batch.Set(key, value); err != nil {
+			return 0, 0, err
+		}
+		keys++
+		bytes += len(key) + len(value)
+	}
+	if b.cfg.Sync {
+		err = batch.WriteSync()
+	} else {
+		err = batch.Write()
+	}
+	return keys, bytes, err
+}
+
+func (b *bench) write() (keys, bytes int, err error) {
+	return b.set(b.key(b.rng.Intn(b.cfg.Num)))
+}
+
+func (b *bench) set(key []byte) (keys, bytes int, err error) {
+	value := b.value()
+	if b.cfg.Sync {
+		err = b.db.SetSync(key, value)
+	} else {
+		err = b.db.Set(key, value)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return 1, len(key) + len(value), nil
+}
+
+func (b *bench) read() (keys, bytes int, err error) {
+	key := b.key(b.rng.Intn(b.cfg.Num))
+	value, err := b.db.Get(key)
+	if err != nil || value == nil {
+		return 0, 0, err
+	}
+	return 1, len(key) + len(value), nil
+}
+
+func (b *bench) scan() (keys, bytes int, err error) {
+	itr, err := b.db.Iterator(b.key(b.rng.Intn(b.cfg.Num)), nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer itr.Close()
+	for ; itr.Valid() && keys < b.cfg.ScanLength; itr.Next() {
+		keys++
+		bytes += len(itr.Key()) + len(itr.Value())
+	}
+	return keys, bytes, itr.Error()
+}
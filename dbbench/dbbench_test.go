@@ -0,0 +1,82 @@
+package dbbench
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/cometbft/cometbft-db"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	for _, cfg := range []Config{
+		{Num: 100, KeySize: 8, ValueSize: 10},
+		{Num: 100, KeySize: 8, ValueSize: 10, BatchSize: 30, ScanLength: 7, ReadRatio: 1, Sync: true},
+	} {
+		mdb := db.NewMemDB()
+		for _, w := range Workloads {
+			res, err := Run(ctx, mdb, w, cfg)
+			require.NoError(t, err, w)
+			assert.Equal(t, w, res.Workload)
+			assert.Positive(t, res.Elapsed)
+			assert.LessOrEqual(t, res.P50, res.P90)
+			assert.LessOrEqual(t, res.P90, res.P99)
+			assert.LessOrEqual(t, res.P99, res.P999)
+			assert.LessOrEqual(t, res.P999, res.Max)
+			assert.Contains(t, res.String(), string(w))
+
+			switch w {
+			case FillSeq:
+				wantOps := 100
+				if cfg.BatchSize > 0 {
+					wantOps = 4
+				}
+				assert.Equal(t, wantOps, res.Ops)
+				assert.Equal(t, 100, res.Keys)
+				assert.EqualValues(t, 100*18, res.Bytes)
+			case ReadRandom:
+				// All the keys were written by fillseq.
+				assert.Equal(t, 100, res.Ops)
+				assert.Equal(t, 100, res.Keys)
+			case Scan:
+				scanLength := 100
+				if cfg.ScanLength > 0 {
+					scanLength = cfg.ScanLength
+				}
+				assert.Equal(t, (100+scanLength-1)/scanLength, res.Ops)
+				assert.Positive(t, res.Keys)
+			case Mixed:
+				assert.Equal(t, 100, res.Ops)
+			}
+		}
+		count, err := db.EstimateCount(mdb)
+		require.NoError(t, err)
+		assert.EqualValues(t, 100, count)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	_, err := Run(context.Background(), db.NewMemDB(), "unknown", Config{})
+	assert.EqualError(t, err, `unknown workload "unknown"`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Run(ctx, db.NewMemDB(), FillSeq, Config{Num: 10})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	fdb := db.NewFaultDB(db.NewMemDB())
+	fdb.Inject(db.Fault{Ops: db.FaultSet, After: 5, Err: db.ErrFaultDiskFull})
+	_, err = Run(context.Background(), fdb, FillRandom, Config{Num: 10})
+	assert.ErrorIs(t, err, db.ErrFaultDiskFull)
+}
+
+func TestParseWorkloads(t *testing.T) {
+	workloads, err := ParseWorkloads("fillseq, readrandom")
+	require.NoError(t, err)
+	assert.Equal(t, []Workload{FillSeq, ReadRandom}, workloads)
+	_, err = ParseWorkloads("fillseq,readseq")
+	assert.EqualError(t, err, `unknown workload "readseq"`)
+}
@@ -0,0 +1,24 @@
+/*
+dbbench runs standardized workloads against a db.DB, in the spirit of the
+db_bench tool of LevelDB, so that backends can be compared on the same
+operations with the same data:
+
+	ldb, err := db.NewDB("bench", db.PebbleDBBackend, dir)
+	cfg := dbbench.Config{Num: 1_000_000, ValueSize: 256}
+	for _, w := range dbbench.Workloads {
+	    res, err := dbbench.Run(ctx, ldb, w, cfg)
+	    if err != nil {
+	        log.Fatalf("%s: %v", w, err)
+	    }
+	    fmt.Println(res)
+	}
+
+The keys are the decimal numbers from 0 to Config.Num-1, zero-padded to
+Config.KeySize bytes, so the read workloads find the keys written by the fill
+workloads run before them with the same configuration. Each Result reports the
+throughput and the latency percentiles of the timed operations.
+
+The workloads write to the database, which should be created for the
+benchmark. The cometbft-db command runs them with its bench subcommand.
+*/
+package dbbench
@@ -0,0 +1,368 @@
+package dbtest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	db "github.com/cometbft/cometbft-db"
+)
+
+// Run runs the conformance suite against the databases returned by newDB, one
+// new empty database per subtest, which is closed at the end of the subtest.
+func Run(t *testing.T, newDB func() db.DB) {
+	for _, tc := range []struct {
+		name string
+		test func(*testing.T, db.DB)
+	}{
+		{"GetSetDelete", testGetSetDelete},
+		{"EmptyKeys", testEmptyKeys},
+		{"IteratorOrder", testIteratorOrder},
+		{"IteratorRanges", testIteratorRanges},
+		{"IteratorEmpty", testIteratorEmpty},
+		{"BatchAtomicity", testBatchAtomicity},
+		{"BatchOrder", testBatchOrder},
+		{"BatchLifecycle", testBatchLifecycle},
+		{"BatchEmptyKeys", testBatchEmptyKeys},
+		{"Model", testModel},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newDB()
+			require.NotNil(t, d)
+			defer func() {
+				assert.NoError(t, d.Close())
+			}()
+			tc.test(t, d)
+		})
+	}
+}
+
+func testGetSetDelete(t *testing.T, d db.DB) {
+	// A nonexistent key has a nil value.
+	value, err := d.Get([]byte("a"))
+	require.NoError(t, err)
+	require.Nil(t, value)
+	ok, err := d.Has([]byte("a"))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, d.Set([]byte("a"), []byte{0x01}))
+	ok, err = d.Has([]byte("a"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	checkValue(t, d, []byte("a"), []byte{0x01})
+
+	require.NoError(t, d.SetSync([]byte("b"), []byte{0x02}))
+	checkValue(t, d, []byte("b"), []byte{0x02})
+
+	// Setting a key again replaces its value.
+	require.NoError(t, d.Set([]byte("a"), []byte{0x03}))
+	checkValue(t, d, []byte("a"), []byte{0x03})
+
+	// An empty value is distinct from a missing key.
+	require.NoError(t, d.Set([]byte("e"), []byte{}))
+	checkValue(t, d, []byte("e"), []byte{})
+	ok, err = d.Has([]byte("e"))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Deleting a nonexistent key is fine.
+	require.NoError(t, d.Delete([]byte("x")))
+	require.NoError(t, d.DeleteSync([]byte("x")))
+
+	require.NoError(t, d.Delete([]byte("a")))
+	checkValue(t, d, []byte("a"), nil)
+	require.NoError(t, d.DeleteSync([]byte("b")))
+	checkValue(t, d, []byte("b"), nil)
+	ok, err = d.Has([]byte("b"))
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func testEmptyKeys(t *testing.T, d db.DB) {
+	for _, key := range [][]byte{nil, {}} {
+		_, err := d.Get(key)
+		assert.Error(t, err, "Get(%#v)", key)
+		_, err = d.Has(key)
+		assert.Error(t, err, "Has(%#v)", key)
+		assert.Error(t, d.Set(key, []byte{0x01}), "Set(%#v)", key)
+		assert.Error(t, d.SetSync(key, []byte{0x01}), "SetSync(%#v)", key)
+		assert.Error(t, d.Delete(key), "Delete(%#v)", key)
+		assert.Error(t, d.DeleteSync(key), "DeleteSync(%#v)", key)
+	}
+
+	// A nil value is rejected, but an empty one is not.
+	assert.Error(t, d.Set([]byte("a"), nil))
+	assert.Error(t, d.SetSync([]byte("a"), nil))
+	checkValue(t, d, []byte("a"), nil)
+
+	// Empty iterator bounds are rejected, unlike nil ones.
+	_, err := d.Iterator([]byte{}, nil)
+	assert.Error(t, err)
+	_, err = d.Iterator(nil, []byte{})
+	assert.Error(t, err)
+	_, err = d.ReverseIterator([]byte{}, nil)
+	assert.Error(t, err)
+	_, err = d.ReverseIterator(nil, []byte{})
+	assert.Error(t, err)
+}
+
+func testIteratorOrder(t *testing.T, d db.DB) {
+	// The keys are ordered bytewise, whatever the order they were written in.
+	keys := []string{"b", "a", "ab", "\x00", "\xff", "aa", "a\x00", "B"}
+	for _, key := range keys {
+		require.NoError(t, d.Set([]byte(key), []byte("v"+key)))
+	}
+	sorted := []string{"\x00", "B", "a", "a\x00", "aa", "ab", "b", "\xff"}
+
+	itr, err := d.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkDomain(t, itr, nil, nil)
+	var got []string
+	for ; itr.Valid(); itr.Next() {
+		got = append(got, string(itr.Key()))
+		assert.Equal(t, []byte("v"+string(itr.Key())), itr.Value())
+	}
+	require.NoError(t, itr.Error())
+	require.NoError(t, itr.Close())
+	assert.Equal(t, sorted, got, "forward iterator")
+
+	ritr, err := d.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	got = nil
+	for ; ritr.Valid(); ritr.Next() {
+		got = append(got, string(ritr.Key()))
+	}
+	require.NoError(t, ritr.Error())
+	require.NoError(t, ritr.Close())
+	for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+		sorted[i], sorted[j] = sorted[j], sorted[i]
+	}
+	assert.Equal(t, sorted, got, "reverse iterator")
+
+	// An exhausted iterator panics on use.
+	itr, err = d.Iterator([]byte("\xff\x00"), nil)
+	require.NoError(t, err)
+	assert.False(t, itr.Valid())
+	assert.Panics(t, func() { itr.Key() })
+	assert.Panics(t, func() { itr.Value() })
+	assert.Panics(t, func() { itr.Next() })
+	require.NoError(t, itr.Close())
+}
+
+func testIteratorRanges(t *testing.T, d db.DB) {
+	for i := int64(0); i < 10; i++ {
+		if i != 6 { // but skip 6.
+			require.NoError(t, d.Set(int642Bytes(i), []byte{}))
+		}
+	}
+
+	for _, tc := range []struct {
+		start, end int64 // -1 for nil
+		reverse    bool
+		expect     []int64
+	}{
+		{-1, -1, false, []int64{0, 1, 2, 3, 4, 5, 7, 8, 9}},
+		{-1, -1, true, []int64{9, 8, 7, 5, 4, 3, 2, 1, 0}},
+		{-1, 0, false, nil},
+		{10, -1, true, nil},
+		{0, -1, false, []int64{0, 1, 2, 3, 4, 5, 7, 8, 9}},
+		{1, -1, false, []int64{1, 2, 3, 4, 5, 7, 8, 9}},
+		{-1, 10, true, []int64{9, 8, 7, 5, 4, 3, 2, 1, 0}},
+		{-1, 9, true, []int64{8, 7, 5, 4, 3, 2, 1, 0}},
+		{-1, 8, true, []int64{7, 5, 4, 3, 2, 1, 0}},
+		{5, 6, false, []int64{5}},
+		{5, 7, false, []int64{5}},
+		{5, 8, false, []int64{5, 7}},
+		{6, 7, false, nil},
+		{6, 8, false, []int64{7}},
+		{7, 8, false, []int64{7}},
+		{4, 5, true, []int64{4}},
+		{4, 6, true, []int64{5, 4}},
+		{4, 7, true, []int64{5, 4}},
+		{5, 6, true, []int64{5}},
+		{5, 7, true, []int64{5}},
+		{6, 7, true, nil},
+		{6, -1, true, []int64{9, 8, 7}},
+		{5, -1, true, []int64{9, 8, 7, 5}},
+		{8, 9, true, []int64{8}},
+		{2, 4, true, []int64{3, 2}},
+		{4, 2, true, nil},
+		{4, 2, false, nil},
+	} {
+		start, end := boundBytes(tc.start), boundBytes(tc.end)
+		msg := fmt.Sprintf("iterator [%d, %d) reverse=%v", tc.start, tc.end, tc.reverse)
+		var (
+			itr db.Iterator
+			err error
+		)
+		if tc.reverse {
+			itr, err = d.ReverseIterator(start, end)
+		} else {
+			itr, err = d.Iterator(start, end)
+		}
+		require.NoError(t, err, msg)
+		checkDomain(t, itr, start, end)
+		var got []int64
+		for ; itr.Valid(); itr.Next() {
+			got = append(got, bytes2Int64(itr.Key()))
+		}
+		require.NoError(t, itr.Error(), msg)
+		require.NoError(t, itr.Close(), msg)
+		assert.Equal(t, tc.expect, got, msg)
+	}
+}
+
+func testIteratorEmpty(t *testing.T, d db.DB) {
+	itr, err := d.Iterator(nil, nil)
+	require.NoError(t, err)
+	assert.False(t, itr.Valid())
+	require.NoError(t, itr.Close())
+
+	ritr, err := d.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	assert.False(t, ritr.Valid())
+	require.NoError(t, ritr.Close())
+}
+
+func testBatchAtomicity(t *testing.T, d db.DB) {
+	require.NoError(t, d.Set([]byte("c"), []byte{9}))
+
+	// The operations of a batch are not visible until it is written.
+	batch := d.NewBatch()
+	require.NoError(t, batch.Set([]byte("a"), []byte{1}))
+	require.NoError(t, batch.Set([]byte("b"), []byte{2}))
+	require.NoError(t, batch.Delete([]byte("c")))
+	checkKeyValues(t, d, map[string][]byte{"c": {9}})
+	assert.Equal(t, 3, batch.Count())
+	assert.GreaterOrEqual(t, batch.SizeBytes(), 3)
+
+	require.NoError(t, batch.Write())
+	checkKeyValues(t, d, map[string][]byte{"a": {1}, "b": {2}})
+	require.NoError(t, batch.Close())
+
+	// Nor are those of a batch closed without being written.
+	batch = d.NewBatch()
+	require.NoError(t, batch.Set([]byte("x"), []byte{1}))
+	require.NoError(t, batch.Delete([]byte("a")))
+	require.NoError(t, batch.Close())
+	checkKeyValues(t, d, map[string][]byte{"a": {1}, "b": {2}})
+
+	// WriteSync writes like Write.
+	batch = d.NewBatch()
+	require.NoError(t, batch.Set([]byte("c"), []byte{3}))
+	require.NoError(t, batch.WriteSync())
+	require.NoError(t, batch.Close())
+	checkKeyValues(t, d, map[string][]byte{"a": {1}, "b": {2}, "c": {3}})
+}
+
+func testBatchOrder(t *testing.T, d db.DB) {
+	require.NoError(t, d.Set([]byte("a"), []byte{0}))
+
+	// The operations of a batch apply in order, the last one winning.
+	batch := d.NewBatch()
+	require.NoError(t, batch.Delete([]byte("a")))
+	require.NoError(t, batch.Set([]byte("a"), []byte{1}))
+	require.NoError(t, batch.Set([]byte("b"), []byte{1}))
+	require.NoError(t, batch.Set([]byte("b"), []byte{2}))
+	require.NoError(t, batch.Set([]byte("c"), []byte{3}))
+	require.NoError(t, batch.Delete([]byte("c")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	checkKeyValues(t, d, map[string][]byte{"a": {1}, "b": {2}})
+}
+
+func testBatchLifecycle(t *testing.T, d db.DB) {
+	// A written batch is reset, and rejects any use but Close.
+	batch := d.NewBatch()
+	require.NoError(t, batch.Set([]byte("a"), []byte{1}))
+	require.NoError(t, batch.Write())
+	assert.Zero(t, batch.Count())
+	assert.Zero(t, batch.SizeBytes())
+	assert.ErrorIs(t, batch.Set([]byte("a"), []byte{9}), db.ErrBatchWritten)
+	assert.ErrorIs(t, batch.Delete([]byte("a")), db.ErrBatchWritten)
+	assert.ErrorIs(t, batch.Write(), db.ErrBatchWritten)
+	assert.ErrorIs(t, batch.WriteSync(), db.ErrBatchWritten)
+	require.NoError(t, batch.Close())
+	checkValue(t, d, []byte("a"), []byte{1})
+
+	// An empty batch can be written.
+	batch = d.NewBatch()
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	checkKeyValues(t, d, map[string][]byte{"a": {1}})
+
+	// A batch can be closed twice, and rejects any other use once closed.
+	batch = d.NewBatch()
+	require.NoError(t, batch.Close())
+	require.NoError(t, batch.Close())
+	assert.ErrorIs(t, batch.Set([]byte("a"), []byte{9}), db.ErrBatchWritten)
+	assert.ErrorIs(t, batch.Delete([]byte("a")), db.ErrBatchWritten)
+	assert.ErrorIs(t, batch.Write(), db.ErrBatchWritten)
+	assert.ErrorIs(t, batch.WriteSync(), db.ErrBatchWritten)
+}
+
+func testBatchEmptyKeys(t *testing.T, d db.DB) {
+	batch := d.NewBatch()
+	for _, key := range [][]byte{nil, {}} {
+		assert.Error(t, batch.Set(key, []byte{0x01}), "Set(%#v)", key)
+		assert.Error(t, batch.Delete(key), "Delete(%#v)", key)
+	}
+	assert.Error(t, batch.Set([]byte("a"), nil))
+	require.NoError(t, batch.Close())
+}
+
+func testModel(t *testing.T, d db.DB) {
+	RunModel(t, d, RandomOps(rand.New(rand.NewSource(1)), 2000)) //nolint:gosec
+}
+
+func checkValue(t *testing.T, d db.DB, key, expect []byte) {
+	t.Helper()
+	value, err := d.Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, expect, value, "value of %q", key)
+}
+
+func checkDomain(t *testing.T, itr db.Iterator, start, end []byte) {
+	t.Helper()
+	ds, de := itr.Domain()
+	assert.Equal(t, start, ds, "domain start")
+	assert.Equal(t, end, de, "domain end")
+}
+
+// checkKeyValues checks that the database has exactly the given keys and
+// values.
+func checkKeyValues(t *testing.T, d db.DB, expect map[string][]byte) {
+	t.Helper()
+	itr, err := d.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer itr.Close()
+	actual := make(map[string][]byte)
+	for ; itr.Valid(); itr.Next() {
+		actual[string(itr.Key())] = itr.Value()
+	}
+	require.NoError(t, itr.Error())
+	assert.Equal(t, expect, actual)
+}
+
+// boundBytes returns the key of an iterator bound, nil for -1.
+func boundBytes(i int64) []byte {
+	if i < 0 {
+		return nil
+	}
+	return int642Bytes(i)
+}
+
+func int642Bytes(i int64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, uint64(i))
+	return buf
+}
+
+func bytes2Int64(buf []byte) int64 {
+	return int64(binary.BigEndian.Uint64(buf))
+}
@@ -0,0 +1,115 @@
+package dbtest
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	db "github.com/cometbft/cometbft-db"
+)
+
+func TestRun(t *testing.T) {
+	for _, backend := range []db.BackendType{db.MemDBBackend, db.GoLevelDBBackend, db.PebbleDBBackend} {
+		t.Run(string(backend), func(t *testing.T) {
+			Run(t, func() db.DB {
+				d, err := db.NewDB("testdb", backend, t.TempDir())
+				require.NoError(t, err)
+				return d
+			})
+		})
+	}
+}
+
+func TestRunPrefixDB(t *testing.T) {
+	Run(t, func() db.DB {
+		mdb := db.NewMemDB()
+		require.NoError(t, mdb.Set([]byte("a"), []byte{1}))
+		require.NoError(t, mdb.Set([]byte("z"), []byte{26}))
+		return db.NewPrefixDB(mdb, []byte("test/"))
+	})
+}
+
+func TestRandomOps(t *testing.T) {
+	// The operations are reproducible from the seed.
+	ops := RandomOps(rand.New(rand.NewSource(7)), 100)
+	require.Len(t, ops, 100)
+	require.Equal(t, ops, RandomOps(rand.New(rand.NewSource(7)), 100))
+	for _, op := range ops {
+		switch op.Kind {
+		case OpSet, OpDelete, OpGet:
+			require.NotEmpty(t, op.Key, op)
+		case OpBatch:
+			require.NotEmpty(t, op.Batch, op)
+			for _, bop := range op.Batch {
+				require.Contains(t, []OpKind{OpSet, OpDelete}, bop.Kind, op)
+			}
+		}
+	}
+	require.Empty(t, OpsFromBytes(nil))
+	require.NotEmpty(t, OpsFromBytes([]byte{1, 2, 3}))
+}
+
+// fuzzBackends are the backends checked by the fuzz targets.
+var fuzzBackends = []db.BackendType{db.MemDBBackend, db.GoLevelDBBackend, db.PebbleDBBackend}
+
+func FuzzModel(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 1, 4, 0, 0})
+	f.Add([]byte{2, 3, 0, 0, 1, 'x', 1, 1, 4, 1, 1, 2, 0, 1, 5})
+	f.Add([]byte{0, 2, 5, 5, 2, 'v', 'w', 4, 1, 1, 1, 5, 1, 1, 4})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ops := OpsFromBytes(data)
+		for _, backend := range fuzzBackends {
+			d, err := db.NewDB("fuzzdb", backend, t.TempDir())
+			require.NoError(t, err)
+			RunModel(t, d, ops)
+			require.NoError(t, d.Close())
+		}
+	})
+}
+
+func FuzzIteratorBounds(f *testing.F) {
+	// The keys are those of the model, in every backend.
+	var keys []Op
+	m := newModel()
+	for _, op := range RandomOps(rand.New(rand.NewSource(1)), 500) {
+		if op.Kind == OpSet {
+			keys = append(keys, op)
+			m.apply(op)
+		}
+	}
+	dbs := make([]db.DB, 0, len(fuzzBackends))
+	for _, backend := range fuzzBackends {
+		d, err := db.NewDB("fuzzdb", backend, f.TempDir())
+		require.NoError(f, err)
+		for _, op := range keys {
+			require.NoError(f, d.Set(op.Key, op.Value))
+		}
+		dbs = append(dbs, d)
+	}
+	defer func() {
+		for _, d := range dbs {
+			require.NoError(f, d.Close())
+		}
+	}()
+
+	f.Add([]byte{}, []byte{}, false)
+	f.Add([]byte{0x00}, []byte{0xff}, false)
+	f.Add([]byte("a"), []byte("a\x00"), true)
+	f.Add([]byte{0xff, 0xff}, []byte{}, true)
+	f.Add([]byte("b"), []byte("a"), false)
+	f.Fuzz(func(t *testing.T, start, end []byte, reverse bool) {
+		// Empty bounds are rejected, so they stand for unbounded ranges.
+		op := Op{Kind: OpIterate, Reverse: reverse}
+		if len(start) > 0 {
+			op.Start = start
+		}
+		if len(end) > 0 {
+			op.End = end
+		}
+		for i, d := range dbs {
+			checkModelRange(t, d, m, op, fmt.Sprintf("%s: %v", fuzzBackends[i], op))
+		}
+	})
+}
@@ -0,0 +1,31 @@
+/*
+dbtest is the conformance suite of the db.DB contract, run against the
+backends of this module and exported so that the authors of other backends can
+check theirs against the same expectations:
+
+	func TestConformance(t *testing.T) {
+	    dbtest.Run(t, func() db.DB {
+	        mydb, err := NewMyDB(t.TempDir())
+	        if err != nil {
+	            t.Fatal(err)
+	        }
+	        return mydb
+	    })
+	}
+
+Run calls the constructor for each of its subtests, which expect an empty
+database and close it once done. The subtests cover reads and writes, the
+rejection of empty keys and nil values, the order and the domains of the
+iterators, and the atomicity and the lifecycle of the batches.
+
+RunModel applies a sequence of operations to a database and to a reference
+model of the contract, a sorted map, and fails at the first disagreement. The
+operations are generated by RandomOps from a seed, or decoded by OpsFromBytes
+from the input of a fuzz target, over keys drawn from a few bytes at the ends
+of the byte range, where the ordering and the range boundary bugs are. The
+FuzzModel and FuzzIteratorBounds targets of the package run them against the
+backends of this module:
+
+	go test ./dbtest -run '^$' -fuzz FuzzIteratorBounds
+*/
+package dbtest
@@ -0,0 +1,248 @@
+package dbtest
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	db "github.com/cometbft/cometbft-db"
+)
+
+// OpKind is the kind of an Op.
+type OpKind uint8
+
+const (
+	// OpSet sets Key to Value.
+	OpSet OpKind = iota
+	// OpDelete deletes Key.
+	OpDelete
+	// OpBatch writes the Set and Delete operations of Batch in a batch.
+	OpBatch
+	// OpGet checks the value of Key.
+	OpGet
+	// OpIterate checks the keys and values of the range [Start, End), in
+	// reverse order if Reverse is set.
+	OpIterate
+
+	numOpKinds
+)
+
+// Op is an operation applied by RunModel to both a database and its reference
+// model.
+type Op struct {
+	Kind       OpKind
+	Key, Value []byte
+	Start, End []byte // nil for an unbounded range
+	Reverse    bool
+	Batch      []Op
+}
+
+// String returns a readable form of the operation, for failure reports.
+func (op Op) String() string {
+	switch op.Kind {
+	case OpSet:
+		return fmt.Sprintf("Set(%X, %X)", op.Key, op.Value)
+	case OpDelete:
+		return fmt.Sprintf("Delete(%X)", op.Key)
+	case OpBatch:
+		return fmt.Sprintf("Batch%v", op.Batch)
+	case OpGet:
+		return fmt.Sprintf("Get(%X)", op.Key)
+	case OpIterate:
+		if op.Reverse {
+			return fmt.Sprintf("ReverseIterator(%X, %X)", op.Start, op.End)
+		}
+		return fmt.Sprintf("Iterator(%X, %X)", op.Start, op.End)
+	}
+	return fmt.Sprintf("Op(%d)", op.Kind)
+}
+
+// keyAlphabet are the bytes of the generated keys: few, so that operations
+// often hit the same keys, and at the boundaries of the byte range, where the
+// ordering and prefix bugs hide.
+var keyAlphabet = []byte{0x00, 0x01, 'a', 'b', 0xfe, 0xff}
+
+// maxKeyLen is the maximum length of the generated keys.
+const maxKeyLen = 3
+
+// RandomOps returns n random operations, drawn from rng.
+func RandomOps(rng *rand.Rand, n int) []Op {
+	g := opGenerator{next: func() byte { return byte(rng.Intn(256)) }}
+	ops := make([]Op, 0, n)
+	for len(ops) < n {
+		ops = append(ops, g.op(true))
+	}
+	return ops
+}
+
+// OpsFromBytes decodes operations from arbitrary bytes, e.g. the input of a
+// fuzz target, so that the fuzzer mutates the operations themselves.
+func OpsFromBytes(data []byte) []Op {
+	g := opGenerator{next: func() byte {
+		if len(data) == 0 {
+			return 0
+		}
+		b := data[0]
+		data = data[1:]
+		return b
+	}}
+	var ops []Op
+	for len(data) > 0 {
+		ops = append(ops, g.op(true))
+	}
+	return ops
+}
+
+// opGenerator generates operations from a source of bytes.
+type opGenerator struct {
+	next func() byte
+}
+
+func (g opGenerator) op(nested bool) Op {
+	kind := OpKind(g.next() % byte(numOpKinds))
+	if !nested && kind != OpSet {
+		// The operations of a batch are writes.
+		kind = OpDelete
+	}
+	switch kind {
+	case OpSet:
+		value := make([]byte, g.next()%4) // empty values included
+		for i := range value {
+			value[i] = g.next()
+		}
+		return Op{Kind: OpSet, Key: g.key(), Value: value}
+	case OpDelete, OpGet:
+		return Op{Kind: kind, Key: g.key()}
+	case OpBatch:
+		batch := make([]Op, 1+g.next()%5)
+		for i := range batch {
+			batch[i] = g.op(false)
+		}
+		return Op{Kind: OpBatch, Batch: batch}
+	default:
+		op := Op{Kind: OpIterate, Reverse: g.next()%2 == 1}
+		if g.next()%4 != 0 {
+			op.Start = g.key()
+		}
+		if g.next()%4 != 0 {
+			op.End = g.key()
+		}
+		return op
+	}
+}
+
+// key returns a non-empty key.
+func (g opGenerator) key() []byte {
+	key := make([]byte, 1+g.next()%maxKeyLen)
+	for i := range key {
+		key[i] = keyAlphabet[int(g.next())%len(keyAlphabet)]
+	}
+	return key
+}
+
+// RunModel applies ops to d, which must be empty, and to a reference model of
+// the DB contract, and fails at the first operation where they disagree. The
+// whole database is compared with the model once all the operations applied.
+func RunModel(t *testing.T, d db.DB, ops []Op) {
+	m := newModel()
+	for i, op := range ops {
+		msg := fmt.Sprintf("op %d: %v", i, op)
+		switch op.Kind {
+		case OpSet:
+			require.NoError(t, d.Set(op.Key, op.Value), msg)
+			m.apply(op)
+		case OpDelete:
+			require.NoError(t, d.Delete(op.Key), msg)
+			m.apply(op)
+		case OpBatch:
+			batch := d.NewBatch()
+			for _, bop := range op.Batch {
+				if bop.Kind == OpSet {
+					require.NoError(t, batch.Set(bop.Key, bop.Value), msg)
+				} else {
+					require.NoError(t, batch.Delete(bop.Key), msg)
+				}
+			}
+			require.NoError(t, batch.Write(), msg)
+			require.NoError(t, batch.Close(), msg)
+			for _, bop := range op.Batch {
+				m.apply(bop)
+			}
+		case OpGet:
+			value, err := d.Get(op.Key)
+			require.NoError(t, err, msg)
+			require.Equal(t, m.get(op.Key), value, msg)
+		case OpIterate:
+			checkModelRange(t, d, m, op, msg)
+		}
+	}
+	checkModelRange(t, d, m, Op{Kind: OpIterate}, "final contents")
+	checkModelRange(t, d, m, Op{Kind: OpIterate, Reverse: true}, "final contents, reversed")
+}
+
+// checkModelRange checks the iterator of the range of op against the model.
+func checkModelRange(t *testing.T, d db.DB, m *model, op Op, msg string) {
+	var (
+		itr db.Iterator
+		err error
+	)
+	if op.Reverse {
+		itr, err = d.ReverseIterator(op.Start, op.End)
+	} else {
+		itr, err = d.Iterator(op.Start, op.End)
+	}
+	require.NoError(t, err, msg)
+	defer itr.Close()
+	expect := m.keys(op.Start, op.End, op.Reverse)
+	var got []string
+	for ; itr.Valid(); itr.Next() {
+		key := string(itr.Key())
+		got = append(got, key)
+		// Iterators need not tell empty values from nil ones.
+		require.True(t, bytes.Equal(m.kv[key], itr.Value()), "%s: value of %X", msg, key)
+	}
+	require.NoError(t, itr.Error(), msg)
+	require.Equal(t, expect, got, msg)
+}
+
+// model is the reference model of a database: a map iterated in key order.
+type model struct {
+	kv map[string][]byte
+}
+
+func newModel() *model {
+	return &model{kv: make(map[string][]byte)}
+}
+
+func (m *model) apply(op Op) {
+	if op.Kind == OpSet {
+		m.kv[string(op.Key)] = op.Value
+	} else {
+		delete(m.kv, string(op.Key))
+	}
+}
+
+func (m *model) get(key []byte) []byte {
+	return m.kv[string(key)]
+}
+
+// keys returns the keys of the range [start, end) in order, or in reverse.
+func (m *model) keys(start, end []byte, reverse bool) []string {
+	var keys []string
+	for key := range m.kv {
+		if (start == nil || key >= string(start)) && (end == nil || key < string(end)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if reverse {
+		for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+			keys[i], keys[j] = keys[j], keys[i]
+		}
+	}
+	return keys
+}
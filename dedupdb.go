@@ -0,0 +1,383 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Key prefixes of the DedupDB data, blobs and blob reference counts in the
+// underlying database.
+var (
+	dedupDataPrefix = []byte{0x00}
+	dedupBlobPrefix = []byte{0x01}
+	dedupRefsPrefix = []byte{0x02}
+)
+
+// Markers prefixed to the values of the DedupDB data, followed by the value
+// itself or by the hash of its blob.
+const (
+	dedupInline byte = iota
+	dedupPointer
+)
+
+// DedupDB stores the values of a database larger than a threshold once, under
+// their SHA-256 hash, deduplicating identical values across keys, such as
+// repeated contract code. The keys point to the blobs by their hash, and each
+// blob counts its references to be deleted with the last one.
+//
+// The data, blobs and reference counts are stored under separate prefixes of
+// the underlying database, and are updated atomically by each write. Writes are
+// serialized, since they update the reference counts. The underlying database
+// must only be used through the DedupDB.
+type DedupDB struct {
+	mtx       sync.RWMutex // held shared by reads and exclusively by writes
+	db        DB
+	threshold int
+}
+
+var _ DB = (*DedupDB)(nil)
+
+// NewDedupDB creates a DedupDB over db, deduplicating the values of at least
+// threshold bytes. Closing the DedupDB closes db.
+func NewDedupDB(db DB, threshold int) *DedupDB {
+	return &DedupDB{db: db, threshold: threshold}
+}
+
+func dedupDataKey(key []byte) []byte {
+	return append(cp(dedupDataPrefix), key...)
+}
+
+func dedupBlobKey(hash []byte) []byte {
+	return append(cp(dedupBlobPrefix), hash...)
+}
+
+func dedupRefsKey(hash []byte) []byte {
+	return append(cp(dedupRefsPrefix), hash...)
+}
+
+// dedupHash returns the hash of the blob a data entry points to, or nil if its
+// value is inline.
+func dedupHash(entry []byte) ([]byte, error) {
+	if len(entry) == 0 {
+		return nil, fmt.Errorf("%w: empty dedup entry", ErrCorrupted)
+	}
+	switch entry[0] {
+	case dedupInline:
+		return nil, nil
+	case dedupPointer:
+		if len(entry) != 1+sha256.Size {
+			return nil, fmt.Errorf("%w: invalid dedup pointer %X", ErrCorrupted, entry)
+		}
+		return entry[1:], nil
+	default:
+		return nil, fmt.Errorf("%w: unknown dedup marker %d", ErrCorrupted, entry[0])
+	}
+}
+
+// dedupResolve returns the value of a data entry, reading its blob from r if needed.
+func dedupResolve(r DBReader, entry []byte) ([]byte, error) {
+	hash, err := dedupHash(entry)
+	if err != nil {
+		return nil, err
+	}
+	if hash == nil {
+		return entry[1:], nil
+	}
+	value, err := r.Get(dedupBlobKey(hash))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, fmt.Errorf("%w: missing dedup blob %X", ErrCorrupted, hash)
+	}
+	return value, nil
+}
+
+// apply writes ops atomically, updating the reference counts of the blobs they
+// set and overwrite.
+func (ddb *DedupDB) apply(ops []operation, sync bool) error {
+	ddb.mtx.Lock()
+	defer ddb.mtx.Unlock()
+
+	batch := ddb.db.NewBatch()
+	defer batch.Close()
+	var (
+		entries = make(map[string][]byte) // pending data entries, nil if deleted
+		refs    = make(map[string]int64)  // reference count changes by hash
+		blobs   = make(map[string][]byte) // values of the blobs referenced by ops
+		order   [][]byte                  // hashes in the order they were referenced
+	)
+	for _, op := range ops {
+		dkey := dedupDataKey(op.key)
+		old, ok := entries[string(dkey)]
+		if !ok {
+			var err error
+			if old, err = ddb.db.Get(dkey); err != nil {
+				return err
+			}
+		}
+		if old != nil {
+			hash, err := dedupHash(old)
+			if err != nil {
+				return err
+			}
+			if hash != nil {
+				if _, ok := refs[string(hash)]; !ok {
+					order = append(order, hash)
+				}
+				refs[string(hash)]--
+			}
+		}
+
+		var entry []byte
+		switch {
+		case op.opType == opTypeDelete:
+			if err := batch.Delete(dkey); err != nil {
+				return err
+			}
+		case len(op.value) >= ddb.threshold:
+			sum := sha256.Sum256(op.value)
+			hash := sum[:]
+			if _, ok := refs[string(hash)]; !ok {
+				order = append(order, hash)
+			}
+			refs[string(hash)]++
+			blobs[string(hash)] = op.value
+			entry = append([]byte{dedupPointer}, hash...)
+		default:
+			entry = append([]byte{dedupInline}, op.value...)
+		}
+		if entry != nil {
+			if err := batch.Set(dkey, entry); err != nil {
+				return err
+			}
+		}
+		entries[string(dkey)] = entry
+	}
+
+	for _, hash := range order {
+		delta := refs[string(hash)]
+		if delta == 0 {
+			continue
+		}
+		count, err := ddb.refs(hash)
+		if err != nil {
+			return err
+		}
+		switch n := int64(count) + delta; {
+		case n <= 0:
+			if err := batch.Delete(dedupRefsKey(hash)); err != nil {
+				return err
+			}
+			if err := batch.Delete(dedupBlobKey(hash)); err != nil {
+				return err
+			}
+		default:
+			if err := batch.Set(dedupRefsKey(hash), binary.BigEndian.AppendUint64(nil, uint64(n))); err != nil {
+				return err
+			}
+			if count == 0 {
+				if err := batch.Set(dedupBlobKey(hash), blobs[string(hash)]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if sync {
+		return batch.WriteSync()
+	}
+	return batch.Write()
+}
+
+// refs returns the reference count of a blob.
+func (ddb *DedupDB) refs(hash []byte) (uint64, error) {
+	value, err := ddb.db.Get(dedupRefsKey(hash))
+	if err != nil || value == nil {
+		return 0, err
+	}
+	if len(value) != 8 {
+		return 0, fmt.Errorf("%w: invalid dedup reference count %X", ErrCorrupted, value)
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// Get implements DB.
+func (ddb *DedupDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	ddb.mtx.RLock()
+	defer ddb.mtx.RUnlock()
+
+	entry, err := ddb.db.Get(dedupDataKey(key))
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	return dedupResolve(ddb.db, entry)
+}
+
+// Has implements DB.
+func (ddb *DedupDB) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	return ddb.db.Has(dedupDataKey(key))
+}
+
+// Set implements DB.
+func (ddb *DedupDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return ddb.apply([]operation{{opTypeSet, key, value}}, false)
+}
+
+// SetSync implements DB.
+func (ddb *DedupDB) SetSync(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return ddb.apply([]operation{{opTypeSet, key, value}}, true)
+}
+
+// Delete implements DB.
+func (ddb *DedupDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return ddb.apply([]operation{{opTypeDelete, key, nil}}, false)
+}
+
+// DeleteSync implements DB.
+func (ddb *DedupDB) DeleteSync(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return ddb.apply([]operation{{opTypeDelete, key, nil}}, true)
+}
+
+// Iterator implements DB. The iterator reads a snapshot of the underlying
+// database if it supports them; otherwise the DedupDB must not be written to
+// while the iterator is open.
+func (ddb *DedupDB) Iterator(start, end []byte) (Iterator, error) {
+	return ddb.iterator(start, end, false)
+}
+
+// ReverseIterator implements DB. The iterator reads a snapshot of the
+// underlying database if it supports them, as with Iterator.
+func (ddb *DedupDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return ddb.iterator(start, end, true)
+}
+
+func (ddb *DedupDB) iterator(start, end []byte, reverse bool) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	var reader DBReader = ddb.db
+	snapshot, err := Snapshot(ddb.db)
+	switch {
+	case err == nil:
+		reader = snapshot
+	case !errors.Is(err, ErrNotSupported):
+		return nil, err
+	}
+	pstart, pend := prefixRange(dedupDataPrefix, start, end)
+	var source Iterator
+	if reverse {
+		source, err = reader.ReverseIterator(pstart, pend)
+	} else {
+		source, err = reader.Iterator(pstart, pend)
+	}
+	if err != nil {
+		if snapshot != nil {
+			snapshot.Close()
+		}
+		return nil, err
+	}
+	return newDedupDBIterator(reader, snapshot, source, start, end), nil
+}
+
+// Close implements DB.
+func (ddb *DedupDB) Close() error {
+	return ddb.db.Close()
+}
+
+// NewBatch implements DB. The batch writes its operations atomically when it
+// is written.
+func (ddb *DedupDB) NewBatch() Batch {
+	return newDedupDBBatch(ddb)
+}
+
+// Print implements DB.
+func (ddb *DedupDB) Print() error {
+	itr, err := ddb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		key := itr.Key()
+		value := itr.Value()
+		fmt.Printf("[%X]:\t[%X]\n", key, value)
+	}
+	return itr.Error()
+}
+
+// Stats implements DB. The stats of the underlying database are prefixed with
+// "dedup.db.".
+func (ddb *DedupDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range ddb.db.Stats() {
+		stats["dedup.db."+k] = v
+	}
+	stats["dedup.threshold"] = strconv.Itoa(ddb.threshold)
+	return stats
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (ddb *DedupDB) Metrics() (Metrics, error) {
+	return GetMetrics(ddb.db)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (ddb *DedupDB) Flush() error {
+	return Flush(ddb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (ddb *DedupDB) Sync() error {
+	return Sync(ddb.db)
+}
+
+// Backup implements Backupper, backing up the underlying database, from which
+// the DedupDB can be recreated.
+func (ddb *DedupDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, ddb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (ddb *DedupDB) Checkpoint(path string) error {
+	return Checkpoint(ddb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (ddb *DedupDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, ddb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which DedupDB passes through.
+func (ddb *DedupDB) Capabilities() Capability {
+	return Capabilities(ddb.db) & (CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
@@ -0,0 +1,81 @@
+package db
+
+// dedupDBBatch buffers the writes of a batch of a DedupDB, which are applied
+// with the reference counts they change when the batch is written.
+type dedupDBBatch struct {
+	db  *DedupDB
+	ops []operation
+}
+
+var _ Batch = (*dedupDBBatch)(nil)
+
+func newDedupDBBatch(db *DedupDB) *dedupDBBatch {
+	return &dedupDBBatch{
+		db:  db,
+		ops: []operation{},
+	}
+}
+
+// Set implements Batch.
+func (b *dedupDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *dedupDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	return nil
+}
+
+// Count implements Batch.
+func (b *dedupDBBatch) Count() int {
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *dedupDBBatch) SizeBytes() int {
+	return opsSize(b.ops)
+}
+
+// Write implements Batch.
+func (b *dedupDBBatch) Write() error {
+	return b.write(false)
+}
+
+// WriteSync implements Batch.
+func (b *dedupDBBatch) WriteSync() error {
+	return b.write(true)
+}
+
+func (b *dedupDBBatch) write(sync bool) error {
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	if err := b.db.apply(b.ops, sync); err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// Close implements Batch.
+func (b *dedupDBBatch) Close() error {
+	b.ops = nil
+	return nil
+}
@@ -0,0 +1,89 @@
+package db
+
+// dedupDBIterator iterates over the data of a DedupDB, reading the blobs of
+// the values it points to. If a value cannot be read, the iterator becomes
+// invalid and Error returns the error.
+type dedupDBIterator struct {
+	reader     DBReader
+	snapshot   DBReader // closed with the iterator, if not nil
+	source     Iterator
+	start, end []byte
+	value      []byte
+	err        error
+}
+
+var _ Iterator = (*dedupDBIterator)(nil)
+
+func newDedupDBIterator(reader, snapshot DBReader, source Iterator, start, end []byte) *dedupDBIterator {
+	itr := &dedupDBIterator{
+		reader:   reader,
+		snapshot: snapshot,
+		source:   source,
+		start:    start,
+		end:      end,
+	}
+	itr.resolveValue()
+	return itr
+}
+
+// resolveValue reads the value of the item the iterator landed on.
+func (itr *dedupDBIterator) resolveValue() {
+	itr.value = nil
+	if itr.source.Valid() {
+		itr.value, itr.err = dedupResolve(itr.reader, itr.source.Value())
+	}
+}
+
+// Domain implements Iterator.
+func (itr *dedupDBIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+// Valid implements Iterator.
+func (itr *dedupDBIterator) Valid() bool {
+	return itr.err == nil && itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *dedupDBIterator) Next() {
+	itr.assertIsValid()
+	itr.source.Next()
+	itr.resolveValue()
+}
+
+// Key implements Iterator.
+func (itr *dedupDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()[len(dedupDataPrefix):]
+}
+
+// Value implements Iterator.
+func (itr *dedupDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.value
+}
+
+// Error implements Iterator.
+func (itr *dedupDBIterator) Error() error {
+	if itr.err != nil {
+		return itr.err
+	}
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *dedupDBIterator) Close() error {
+	err := itr.source.Close()
+	if itr.snapshot != nil {
+		if serr := itr.snapshot.Close(); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+func (itr *dedupDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countPrefix returns the number of keys with prefix in db.
+func countPrefix(t *testing.T, db DB, prefix []byte) int {
+	itr, err := PrefixIterator(db, prefix)
+	require.NoError(t, err)
+	defer itr.Close()
+	count := 0
+	for ; itr.Valid(); itr.Next() {
+		count++
+	}
+	require.NoError(t, itr.Error())
+	return count
+}
+
+func TestDedupDB(t *testing.T) {
+	mem := NewMemDB()
+	ddb := NewDedupDB(mem, 4)
+	defer ddb.Close()
+
+	blob := bz("contract code")
+	require.NoError(t, ddb.Set(bz("a"), blob))
+	require.NoError(t, ddb.Set(bz("b"), blob))
+	require.NoError(t, ddb.SetSync(bz("c"), bz("1")))
+	checkValue(t, ddb, bz("a"), blob)
+	checkValue(t, ddb, bz("b"), blob)
+	checkValue(t, ddb, bz("c"), bz("1"))
+	assert.Equal(t, 1, countPrefix(t, mem, dedupBlobPrefix))
+
+	itr, err := ddb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("a"), blob)
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("b"), blob)
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("c"), bz("1"))
+	checkNext(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	itr, err = ddb.ReverseIterator(bz("a"), bz("c"))
+	require.NoError(t, err)
+	checkDomain(t, itr, bz("a"), bz("c"))
+	checkItem(t, itr, bz("b"), blob)
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("a"), blob)
+	checkNext(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	// The blob is deleted with its last reference, including within a batch
+	// which references another blob.
+	require.NoError(t, ddb.Delete(bz("a")))
+	checkValue(t, ddb, bz("b"), blob)
+	batch := ddb.NewBatch()
+	require.NoError(t, batch.Set(bz("b"), bz("other blob")))
+	require.NoError(t, batch.Set(bz("c"), bz("other blob")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	checkValue(t, ddb, bz("b"), bz("other blob"))
+	checkValue(t, ddb, bz("c"), bz("other blob"))
+	assert.Equal(t, 1, countPrefix(t, mem, dedupBlobPrefix))
+	refs, err := ddb.refs(dedupHashOf(bz("other blob")))
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, refs)
+
+	// Setting a key to the blob it points to keeps the blob.
+	require.NoError(t, ddb.Set(bz("b"), bz("other blob")))
+	require.NoError(t, ddb.DeleteSync(bz("c")))
+	checkValue(t, ddb, bz("b"), bz("other blob"))
+	require.NoError(t, ddb.Set(bz("b"), bz("2")))
+	assert.Zero(t, countPrefix(t, mem, dedupBlobPrefix))
+	assert.Zero(t, countPrefix(t, mem, dedupRefsPrefix))
+
+	// Missing blobs are reported as corruption.
+	require.NoError(t, ddb.Set(bz("d"), blob))
+	require.NoError(t, mem.Delete(dedupBlobKey(dedupHashOf(blob))))
+	_, err = ddb.Get(bz("d"))
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
+
+func dedupHashOf(value []byte) []byte {
+	sum := sha256.Sum256(value)
+	return sum[:]
+}
@@ -0,0 +1,125 @@
+package db
+
+import "bytes"
+
+// deleteRangeBatchSize is the number of keys removed per batch by the
+// DeleteRange fallback.
+const deleteRangeBatchSize = 1000
+
+// RangeDeleter is implemented by databases and batches which can delete a
+// range of keys natively, e.g. by writing a single range tombstone instead of
+// one tombstone per key.
+type RangeDeleter interface {
+	// DeleteRange deletes all keys in the range [start, end). A nil start is
+	// the first key, a nil end is after the last key. Empty keys are not
+	// allowed.
+	DeleteRange(start, end []byte) error
+}
+
+// DeleteRange deletes all keys of db in the range [start, end), using the
+// native range deletion of db if it implements RangeDeleter. Otherwise the keys
+// are iterated over and deleted in batches of deleteRangeBatchSize, so the
+// deletion is not atomic: concurrent readers may observe a partially deleted
+// range, and if an error is returned some keys may already have been deleted.
+func DeleteRange(db DB, start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if rd, ok := db.(RangeDeleter); ok {
+		return rd.DeleteRange(start, end)
+	}
+	for {
+		keys, err := collectKeys(db, start, end, deleteRangeBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		if err := deleteKeys(db, keys); err != nil {
+			return err
+		}
+		if len(keys) < deleteRangeBatchSize {
+			return nil
+		}
+		// Continue right after the last deleted key.
+		start = append(keys[len(keys)-1], 0)
+	}
+}
+
+// BatchDeleteRange adds the deletion of all keys in the range [start, end) to
+// batch, which must have been created by db. If batch implements RangeDeleter
+// the range is deleted natively when the batch is written. Otherwise a Delete
+// is added for every key currently in db within the range; in that case keys
+// added to the batch by earlier calls to Set, or written to db after this call,
+// are not deleted.
+func BatchDeleteRange(db DB, batch Batch, start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if rd, ok := batch.(RangeDeleter); ok {
+		return rd.DeleteRange(start, end)
+	}
+	keys, err := collectKeys(db, start, end, 0)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectKeys returns copies of the keys of db in the range [start, end), at
+// most limit of them if limit is positive. The iterator is closed before
+// returning, so the keys can be deleted by the caller.
+func collectKeys(db DB, start, end []byte, limit int) (keys [][]byte, err error) {
+	itr, err := db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if cerr := itr.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, cp(itr.Key()))
+		if limit > 0 && len(keys) >= limit {
+			break
+		}
+	}
+	return keys, itr.Error()
+}
+
+// deleteKeys deletes the given keys of db in a single batch.
+func deleteKeys(db DB, keys [][]byte) error {
+	batch := db.NewBatch()
+	defer batch.Close()
+	for _, key := range keys {
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// rangeUpperBound returns the exclusive upper bound right after last, for
+// backends whose range deletion requires an explicit end key. It returns nil
+// if last is nil.
+func rangeUpperBound(last []byte) []byte {
+	if last == nil {
+		return nil
+	}
+	return append(cp(last), 0)
+}
+
+// maxKey returns the larger of the keys a and b, treating nil as the smallest.
+func maxKey(a, b []byte) []byte {
+	if bytes.Compare(a, b) >= 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteRange(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			for i := int64(0); i < 100; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+
+			require.NoError(t, DeleteRange(db, int642Bytes(10), int642Bytes(20)))
+			checkValue(t, db, int642Bytes(9), int642Bytes(9))
+			checkValue(t, db, int642Bytes(10), nil)
+			checkValue(t, db, int642Bytes(19), nil)
+			checkValue(t, db, int642Bytes(20), int642Bytes(20))
+			assert.Equal(t, 90, countKeys(t, db))
+
+			// Empty and inverted ranges are no-ops.
+			require.NoError(t, DeleteRange(db, int642Bytes(30), int642Bytes(30)))
+			require.NoError(t, DeleteRange(db, int642Bytes(40), int642Bytes(30)))
+			assert.Equal(t, 90, countKeys(t, db))
+
+			require.NoError(t, DeleteRange(db, nil, int642Bytes(5)))
+			checkValue(t, db, int642Bytes(4), nil)
+			checkValue(t, db, int642Bytes(5), int642Bytes(5))
+
+			require.NoError(t, DeleteRange(db, int642Bytes(90), nil))
+			checkValue(t, db, int642Bytes(89), int642Bytes(89))
+			checkValue(t, db, int642Bytes(99), nil)
+			assert.Equal(t, 75, countKeys(t, db))
+
+			require.NoError(t, DeleteRange(db, nil, nil))
+			assert.Equal(t, 0, countKeys(t, db))
+
+			// Deleting from an empty database is fine.
+			require.NoError(t, DeleteRange(db, nil, nil))
+		})
+	}
+}
+
+func TestBatchDeleteRange(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			for i := int64(0); i < 100; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+
+			batch := db.NewBatch()
+			defer batch.Close()
+			require.NoError(t, BatchDeleteRange(db, batch, int642Bytes(10), int642Bytes(20)))
+			require.NoError(t, BatchDeleteRange(db, batch, int642Bytes(90), nil))
+			// Keys set after the range deletion survive it.
+			require.NoError(t, batch.Set(int642Bytes(15), bz("new")))
+			assert.Equal(t, errKeyEmpty, BatchDeleteRange(db, batch, []byte{}, nil))
+
+			// Nothing is deleted before the batch is written.
+			checkValue(t, db, int642Bytes(10), int642Bytes(10))
+			require.NoError(t, batch.Write())
+
+			checkValue(t, db, int642Bytes(9), int642Bytes(9))
+			checkValue(t, db, int642Bytes(10), nil)
+			checkValue(t, db, int642Bytes(15), bz("new"))
+			checkValue(t, db, int642Bytes(20), int642Bytes(20))
+			checkValue(t, db, int642Bytes(89), int642Bytes(89))
+			checkValue(t, db, int642Bytes(90), nil)
+			assert.Equal(t, 81, countKeys(t, db))
+		})
+	}
+}
+
+func TestBatchDeleteRangeNative(t *testing.T) {
+	// With native range deletion, keys set earlier in the batch are deleted too.
+	dir, err := os.MkdirTemp("", "deleterange_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	pdb, err := NewPebbleDB("pebble", dir)
+	require.NoError(t, err)
+	defer pdb.Close()
+
+	for _, db := range []DB{NewMemDB(), pdb, NewPrefixDB(NewMemDB(), bz("p"))} {
+		require.NoError(t, db.Set(bz("a"), bz("1")))
+
+		batch := db.NewBatch()
+		require.Implements(t, (*RangeDeleter)(nil), batch)
+		require.NoError(t, batch.Set(bz("b"), bz("2")))
+		require.NoError(t, batch.Set(bz("z"), bz("3")))
+		require.NoError(t, BatchDeleteRange(db, batch, nil, nil))
+		require.NoError(t, batch.Set(bz("c"), bz("4")))
+		require.NoError(t, batch.Write())
+		require.NoError(t, batch.Close())
+
+		checkValue(t, db, bz("a"), nil)
+		checkValue(t, db, bz("b"), nil)
+		checkValue(t, db, bz("z"), nil)
+		checkValue(t, db, bz("c"), bz("4"))
+	}
+}
+
+func TestDeleteRangeFallback(t *testing.T) {
+	// goleveldb has no native range deletion, so keys are deleted in chunks.
+	dir, err := os.MkdirTemp("", "deleterange_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := NewGoLevelDB("goleveldb", dir)
+	require.NoError(t, err)
+	defer db.Close()
+	_, ok := interface{}(db).(RangeDeleter)
+	require.False(t, ok)
+
+	n := int64(2*deleteRangeBatchSize + 10)
+	for i := int64(0); i < n; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+	}
+	require.NoError(t, DeleteRange(db, int642Bytes(1), nil))
+	assert.Equal(t, 1, countKeys(t, db))
+	checkValue(t, db, int642Bytes(0), int642Bytes(0))
+}
+
+func TestPrefixDBDeleteRange(t *testing.T) {
+	db := NewMemDB()
+	require.NoError(t, db.Set(bz("a1"), bz("v")))
+	require.NoError(t, db.Set(bz("b1"), bz("v")))
+	require.NoError(t, db.Set(bz("b2"), bz("v")))
+	require.NoError(t, db.Set(bz("c1"), bz("v")))
+
+	pdb := NewPrefixDB(db, bz("b"))
+	require.NoError(t, DeleteRange(pdb, nil, nil))
+	checkValue(t, db, bz("a1"), bz("v"))
+	checkValue(t, db, bz("b1"), nil)
+	checkValue(t, db, bz("b2"), nil)
+	checkValue(t, db, bz("c1"), bz("v"))
+}
+
+func TestDeleteRangeEmptyKey(t *testing.T) {
+	db := NewMemDB()
+	assert.Equal(t, errKeyEmpty, DeleteRange(db, []byte{}, nil))
+	assert.Equal(t, errKeyEmpty, DeleteRange(db, nil, []byte{}))
+	assert.Equal(t, errKeyEmpty, DeleteRange(NewPrefixDB(db, bz("p")), []byte{}, nil))
+}
@@ -0,0 +1,233 @@
+package db
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// DiscardDB accepts and drops all writes, and behaves as an empty database for
+// reads. It is meant for benchmarking and profiling: swapping it in for a real
+// database isolates the overhead of the application from that of the storage.
+//
+// It still validates keys and values like other databases, and counts the
+// writes it discarded in its Stats.
+//
+// DiscardDB is not registered as a backend, since it does not keep data.
+type DiscardDB struct {
+	writes uint64 // atomic
+	bytes  uint64 // atomic
+}
+
+var _ DB = (*DiscardDB)(nil)
+
+// NewDiscardDB creates a DiscardDB.
+func NewDiscardDB() *DiscardDB {
+	return &DiscardDB{}
+}
+
+func (db *DiscardDB) discard(key, value []byte) {
+	atomic.AddUint64(&db.writes, 1)
+	atomic.AddUint64(&db.bytes, uint64(len(key)+len(value)))
+}
+
+// Get implements DB.
+func (db *DiscardDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	return nil, nil
+}
+
+// Has implements DB.
+func (db *DiscardDB) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	return false, nil
+}
+
+// Set implements DB.
+func (db *DiscardDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	db.discard(key, value)
+	return nil
+}
+
+// SetSync implements DB.
+func (db *DiscardDB) SetSync(key []byte, value []byte) error {
+	return db.Set(key, value)
+}
+
+// Delete implements DB.
+func (db *DiscardDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	db.discard(key, nil)
+	return nil
+}
+
+// DeleteSync implements DB.
+func (db *DiscardDB) DeleteSync(key []byte) error {
+	return db.Delete(key)
+}
+
+// Iterator implements DB.
+func (db *DiscardDB) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return &discardDBIterator{start: start, end: end}, nil
+}
+
+// ReverseIterator implements DB.
+func (db *DiscardDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return db.Iterator(start, end)
+}
+
+// Close implements DB.
+func (db *DiscardDB) Close() error {
+	return nil
+}
+
+// NewBatch implements DB.
+func (db *DiscardDB) NewBatch() Batch {
+	return &discardDBBatch{db: db}
+}
+
+// Print implements DB.
+func (db *DiscardDB) Print() error {
+	return nil
+}
+
+// Stats implements DB.
+func (db *DiscardDB) Stats() map[string]string {
+	return map[string]string{
+		"discard.writes": strconv.FormatUint(atomic.LoadUint64(&db.writes), 10),
+		"discard.bytes":  strconv.FormatUint(atomic.LoadUint64(&db.bytes), 10),
+	}
+}
+
+// discardDBBatch counts its operations and discards them on write.
+type discardDBBatch struct {
+	db     *DiscardDB
+	writes uint64
+	bytes  uint64
+	closed bool
+}
+
+var _ Batch = (*discardDBBatch)(nil)
+
+// Set implements Batch.
+func (b *discardDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.closed {
+		return ErrBatchWritten
+	}
+	b.writes++
+	b.bytes += uint64(len(key) + len(value))
+	return nil
+}
+
+// Delete implements Batch.
+func (b *discardDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.closed {
+		return ErrBatchWritten
+	}
+	b.writes++
+	b.bytes += uint64(len(key))
+	return nil
+}
+
+// Count implements Batch.
+func (b *discardDBBatch) Count() int {
+	if b.closed {
+		return 0
+	}
+	return int(b.writes)
+}
+
+// SizeBytes implements Batch.
+func (b *discardDBBatch) SizeBytes() int {
+	if b.closed {
+		return 0
+	}
+	return int(b.bytes)
+}
+
+// Write implements Batch.
+func (b *discardDBBatch) Write() error {
+	if b.closed {
+		return ErrBatchWritten
+	}
+	atomic.AddUint64(&b.db.writes, b.writes)
+	atomic.AddUint64(&b.db.bytes, b.bytes)
+	return b.Close()
+}
+
+// WriteSync implements Batch.
+func (b *discardDBBatch) WriteSync() error {
+	return b.Write()
+}
+
+// Close implements Batch.
+func (b *discardDBBatch) Close() error {
+	b.closed = true
+	return nil
+}
+
+// discardDBIterator is always invalid.
+type discardDBIterator struct {
+	start []byte
+	end   []byte
+}
+
+var _ Iterator = (*discardDBIterator)(nil)
+
+// Domain implements Iterator.
+func (itr *discardDBIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+// Valid implements Iterator.
+func (itr *discardDBIterator) Valid() bool {
+	return false
+}
+
+// Next implements Iterator.
+func (itr *discardDBIterator) Next() {
+	panic("iterator is invalid")
+}
+
+// Key implements Iterator.
+func (itr *discardDBIterator) Key() []byte {
+	panic("iterator is invalid")
+}
+
+// Value implements Iterator.
+func (itr *discardDBIterator) Value() []byte {
+	panic("iterator is invalid")
+}
+
+// Error implements Iterator.
+func (itr *discardDBIterator) Error() error {
+	return nil
+}
+
+// Close implements Iterator.
+func (itr *discardDBIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscardDB(t *testing.T) {
+	db := NewDiscardDB()
+	defer db.Close()
+
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.SetSync(bz("b"), bz("2")))
+	require.NoError(t, db.Delete(bz("a")))
+	checkValue(t, db, bz("b"), nil)
+	ok, err := db.Has(bz("b"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.Equal(t, errKeyEmpty, db.Set(nil, bz("1")))
+	require.Equal(t, errValueNil, db.Set(bz("a"), nil))
+
+	batch := db.NewBatch()
+	require.NoError(t, batch.Set(bz("c"), bz("3")))
+	require.NoError(t, batch.Delete(bz("d")))
+	require.NoError(t, batch.Write())
+	require.Equal(t, ErrBatchWritten, batch.Write())
+	require.NoError(t, batch.Close())
+
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkInvalid(t, itr)
+	require.NoError(t, itr.Close())
+
+	stats := db.Stats()
+	assert.Equal(t, "5", stats["discard.writes"])
+	assert.Equal(t, "8", stats["discard.bytes"])
+}
+
+func BenchmarkDiscardDBWrites(b *testing.B) {
+	db := NewDiscardDB()
+	value := make([]byte, 32)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.Set(int642Bytes(int64(i)), value); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
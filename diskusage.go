@@ -0,0 +1,134 @@
+package db
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DiskUsageReport is the disk usage of the files of a database, in bytes, by
+// file type.
+type DiskUsageReport struct {
+	// Total is the size of all the files.
+	Total uint64
+	// Data is the size of the live tables, value logs and single-file
+	// databases.
+	Data uint64
+	// WAL is the size of the write-ahead logs.
+	WAL uint64
+	// Metadata is the size of the manifests, options and lock files.
+	Metadata uint64
+	// InfoLogs is the size of the informational logs of the backend.
+	InfoLogs uint64
+	// Obsolete is the size of the files which are no longer used but have not
+	// been deleted yet: the tables which are not part of the database, if the
+	// backend lists its live tables, the old manifests and the archived logs.
+	Obsolete uint64
+	// Other is the size of the files which are not recognized.
+	Other uint64
+}
+
+// DiskUsageReporter is implemented by databases which can report the disk
+// usage of their files.
+type DiskUsageReporter interface {
+	// DiskUsage returns the disk usage of the files of the database.
+	DiskUsage() (DiskUsageReport, error)
+}
+
+// DiskUsage returns the disk usage of the files of db, see DiskUsageReporter.
+// It returns ErrNotSupported if db does not implement DiskUsageReporter, in
+// which case DirDiskUsage can be called with the directory of db.
+func DiskUsage(db DB) (DiskUsageReport, error) {
+	dr, ok := db.(DiskUsageReporter)
+	if !ok {
+		return DiskUsageReport{}, ErrNotSupported
+	}
+	return dr.DiskUsage()
+}
+
+// DirDiskUsage returns the disk usage of the files of the database at path,
+// which is either the directory of a database or the file of a single-file
+// database. Since it does not know which tables are live, it only counts the
+// old manifests and the archived logs as obsolete.
+func DirDiskUsage(path string) (DiskUsageReport, error) {
+	return diskUsage(path, nil)
+}
+
+// diskUsage returns the disk usage of the files at path. The tables whose
+// number is not in liveTables are obsolete, unless liveTables is nil. The
+// tables being written by a running compaction may be counted as obsolete.
+func diskUsage(path string, liveTables map[uint64]struct{}) (DiskUsageReport, error) {
+	var r DiskUsageReport
+	info, err := os.Stat(path)
+	if err != nil {
+		return r, err
+	}
+	if info.Mode().IsRegular() {
+		r.Total = uint64(info.Size())
+		r.Data = r.Total
+		return r, nil
+	}
+	current, err := os.ReadFile(filepath.Join(path, "CURRENT"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return r, err
+	}
+	currentManifest := strings.TrimSpace(string(current))
+
+	err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				// Removed during the walk, e.g. by a compaction.
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		size := uint64(info.Size())
+		r.Total += size
+		name := d.Name()
+		archived := filepath.Base(filepath.Dir(file)) == "archive"
+		switch ext := filepath.Ext(name); {
+		case archived:
+			r.Obsolete += size
+		case ext == ".sst" || ext == ".ldb":
+			num, err := strconv.ParseUint(strings.TrimSuffix(name, ext), 10, 64)
+			_, live := liveTables[num]
+			if liveTables != nil && err == nil && !live {
+				r.Obsolete += size
+			} else {
+				r.Data += size
+			}
+		case ext == ".vlog":
+			r.Data += size
+		case ext == ".log", strings.HasSuffix(name, "-wal"):
+			r.WAL += size
+		case strings.HasPrefix(name, "MANIFEST"):
+			if currentManifest != "" && name != currentManifest {
+				r.Obsolete += size
+			} else {
+				r.Metadata += size
+			}
+		case name == "CURRENT", name == "LOCK", name == "KEYREGISTRY", name == "DISCARD",
+			strings.HasPrefix(name, "OPTIONS"), strings.HasPrefix(name, "marker."), strings.HasSuffix(name, "-shm"):
+			r.Metadata += size
+		case name == "LOG", strings.HasPrefix(name, "LOG."):
+			r.InfoLogs += size
+		default:
+			r.Other += size
+		}
+		return nil
+	})
+	return r, err
+}
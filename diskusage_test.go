@@ -0,0 +1,76 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirDiskUsage(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]int{
+		"000005.ldb":      10,
+		"000007.sst":      20,
+		"000008.log":      30,
+		"CURRENT":         16,
+		"MANIFEST-000006": 40,
+		"MANIFEST-000002": 50,
+		"LOG":             60,
+		"LOG.old":         70,
+		"archive/1.log":   80,
+		"unknown":         90,
+	}
+	for name, size := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, make([]byte, size), 0o600))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "CURRENT"), []byte("MANIFEST-000006\n"), 0o600))
+
+	r, err := DirDiskUsage(dir)
+	require.NoError(t, err)
+	assert.Equal(t, DiskUsageReport{
+		Total:    466,
+		Data:     30,
+		WAL:      30,
+		Metadata: 56,
+		InfoLogs: 130,
+		Obsolete: 130,
+		Other:    90,
+	}, r)
+
+	r, err = diskUsage(dir, map[uint64]struct{}{5: {}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, r.Data)
+	assert.EqualValues(t, 150, r.Obsolete)
+
+	r, err = DirDiskUsage(filepath.Join(dir, "unknown"))
+	require.NoError(t, err)
+	assert.Equal(t, DiskUsageReport{Total: 90, Data: 90}, r)
+}
+
+func TestDiskUsage(t *testing.T) {
+	for _, backend := range []BackendType{GoLevelDBBackend, PebbleDBBackend} {
+		t.Run(string(backend), func(t *testing.T) {
+			db, err := NewDB("db", backend, t.TempDir())
+			require.NoError(t, err)
+			defer db.Close()
+			for i := int64(0); i < 1000; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+			require.NoError(t, Compact(db, nil, nil))
+
+			r, err := DiskUsage(db)
+			require.NoError(t, err)
+			assert.Positive(t, r.Data)
+			assert.Positive(t, r.Metadata)
+			assert.Equal(t, r.Total, r.Data+r.WAL+r.Metadata+r.InfoLogs+r.Obsolete+r.Other)
+		})
+	}
+
+	_, err := DiskUsage(NewMemDB())
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
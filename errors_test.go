@@ -0,0 +1,53 @@
+package db
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+func TestGoLevelDBErrorKinds(t *testing.T) {
+	dir, err := os.MkdirTemp("", "errors_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db, err := NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.Close())
+
+	// The original error is kept in the chain.
+	_, err = db.Get(bz("a"))
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.ErrorIs(t, err, leveldb.ErrClosed)
+	assert.ErrorIs(t, db.Set(bz("a"), bz("2")), ErrClosed)
+
+	ro, err := NewGoLevelDBWithOpts("db", dir, &opt.Options{ReadOnly: true})
+	require.NoError(t, err)
+	defer ro.Close()
+	checkValue(t, ro, bz("a"), bz("1"))
+	err = ro.Set(bz("a"), bz("2"))
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.ErrorIs(t, err, leveldb.ErrReadOnly)
+}
+
+func TestWrapperErrorKinds(t *testing.T) {
+	fdb := NewFaultDB(NewMemDB())
+	fdb.Inject(Fault{Ops: FaultGet, Err: ErrFaultCorruption})
+	_, err := fdb.Get(bz("a"))
+	assert.ErrorIs(t, err, ErrCorrupted)
+	assert.ErrorIs(t, err, ErrFaultCorruption)
+
+	// Errors of the wrapped database pass through the wrappers.
+	pdb := NewPrefixDB(fdb, bz("p"))
+	_, err = pdb.Get(bz("a"))
+	assert.ErrorIs(t, err, ErrCorrupted)
+
+	mdb := NewMirrorDB(NewMemDB(), NewMemDB())
+	require.NoError(t, mdb.Close())
+	assert.ErrorIs(t, mdb.Set(bz("a"), bz("1")), ErrClosed)
+}
@@ -0,0 +1,244 @@
+package db
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+)
+
+// expvarPrefix is the prefix of the names under which ExpvarDB publishes its
+// variables.
+const expvarPrefix = "cometbft_db."
+
+// ExpvarDB publishes counters of the operations on a database with the expvar
+// package, served at /debug/vars by the default HTTP mux, for deployments
+// without Prometheus. The variables of a database named name are published as
+// a map named "cometbft_db.<name>", holding:
+//
+//   - operations: the number of operations, by operation as in MetricsDB
+//   - errors: the number of failed operations, by operation
+//   - read_bytes, written_bytes: the bytes of keys and values read and written
+//   - size: the sizes of the database, if it reports Metrics, read when the
+//     variables are served
+type ExpvarDB struct {
+	db           DB
+	operations   *expvar.Map
+	errors       *expvar.Map
+	readBytes    *expvar.Int
+	writtenBytes *expvar.Int
+}
+
+var _ DB = (*ExpvarDB)(nil)
+
+// NewExpvarDB wraps db, publishing its variables under "cometbft_db.<name>".
+// It fails if a variable of that name is already published. Since expvar
+// variables cannot be removed, closing the ExpvarDB closes db but leaves its
+// variables published, and name cannot be reused within the process.
+func NewExpvarDB(db DB, name string) (*ExpvarDB, error) {
+	varName := expvarPrefix + name
+	if expvar.Get(varName) != nil {
+		return nil, fmt.Errorf("expvar %q is already published", varName)
+	}
+	edb := &ExpvarDB{
+		db:           db,
+		operations:   new(expvar.Map).Init(),
+		errors:       new(expvar.Map).Init(),
+		readBytes:    new(expvar.Int),
+		writtenBytes: new(expvar.Int),
+	}
+	vars := new(expvar.Map).Init()
+	vars.Set("operations", edb.operations)
+	vars.Set("errors", edb.errors)
+	vars.Set("read_bytes", edb.readBytes)
+	vars.Set("written_bytes", edb.writtenBytes)
+	vars.Set("size", expvar.Func(edb.size))
+	expvar.Publish(varName, vars)
+	return edb, nil
+}
+
+// size returns the sizes of the database, or nil if it does not report them.
+func (edb *ExpvarDB) size() interface{} {
+	m, err := GetMetrics(edb.db)
+	if err != nil {
+		return nil
+	}
+	levels := make([]map[string]interface{}, len(m.Levels))
+	for i, level := range m.Levels {
+		levels[i] = map[string]interface{}{"files": level.Files, "size": level.Size}
+	}
+	return map[string]interface{}{
+		"disk_size":        m.DiskSize,
+		"memtable_size":    m.MemTableSize,
+		"block_cache_size": m.BlockCache.Size,
+		"files":            m.Files(),
+		"levels":           levels,
+	}
+}
+
+// observe records an operation which returned err.
+func (edb *ExpvarDB) observe(op string, err error) {
+	edb.operations.Add(op, 1)
+	if err != nil {
+		edb.errors.Add(op, 1)
+	}
+}
+
+// observeWrite records a write of size bytes, counted if it succeeded.
+func (edb *ExpvarDB) observeWrite(op string, size int, err error) {
+	edb.observe(op, err)
+	if err == nil {
+		edb.writtenBytes.Add(int64(size))
+	}
+}
+
+// Get implements DB.
+func (edb *ExpvarDB) Get(key []byte) ([]byte, error) {
+	value, err := edb.db.Get(key)
+	edb.observe(metricsOpGet, err)
+	edb.readBytes.Add(int64(len(value)))
+	return value, err
+}
+
+// Has implements DB.
+func (edb *ExpvarDB) Has(key []byte) (bool, error) {
+	ok, err := edb.db.Has(key)
+	edb.observe(metricsOpHas, err)
+	return ok, err
+}
+
+// Set implements DB.
+func (edb *ExpvarDB) Set(key []byte, value []byte) error {
+	err := edb.db.Set(key, value)
+	edb.observeWrite(metricsOpSet, len(key)+len(value), err)
+	return err
+}
+
+// SetSync implements DB.
+func (edb *ExpvarDB) SetSync(key []byte, value []byte) error {
+	err := edb.db.SetSync(key, value)
+	edb.observeWrite(metricsOpSetSync, len(key)+len(value), err)
+	return err
+}
+
+// Delete implements DB.
+func (edb *ExpvarDB) Delete(key []byte) error {
+	err := edb.db.Delete(key)
+	edb.observeWrite(metricsOpDelete, len(key), err)
+	return err
+}
+
+// DeleteSync implements DB.
+func (edb *ExpvarDB) DeleteSync(key []byte) error {
+	err := edb.db.DeleteSync(key)
+	edb.observeWrite(metricsOpDeleteSync, len(key), err)
+	return err
+}
+
+// Iterator implements DB.
+func (edb *ExpvarDB) Iterator(start, end []byte) (Iterator, error) {
+	itr, err := edb.db.Iterator(start, end)
+	edb.observe(metricsOpIterator, err)
+	if err != nil {
+		return nil, err
+	}
+	return newExpvarDBIterator(edb.readBytes, itr), nil
+}
+
+// ReverseIterator implements DB.
+func (edb *ExpvarDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	itr, err := edb.db.ReverseIterator(start, end)
+	edb.observe(metricsOpReverseIterator, err)
+	if err != nil {
+		return nil, err
+	}
+	return newExpvarDBIterator(edb.readBytes, itr), nil
+}
+
+// Close implements DB.
+func (edb *ExpvarDB) Close() error {
+	return edb.db.Close()
+}
+
+// NewBatch implements DB.
+func (edb *ExpvarDB) NewBatch() Batch {
+	return newExpvarDBBatch(edb, edb.db.NewBatch())
+}
+
+// Print implements DB.
+func (edb *ExpvarDB) Print() error {
+	return edb.db.Print()
+}
+
+// Stats implements DB.
+func (edb *ExpvarDB) Stats() map[string]string {
+	return edb.db.Stats()
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database.
+func (edb *ExpvarDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	swapped, err := CompareAndSwap(edb.db, key, old, new)
+	size := 0
+	if swapped {
+		size = len(key) + len(new)
+	}
+	edb.observeWrite(metricsOpCompareAndSwap, size, err)
+	return swapped, err
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+// Reads of the snapshot are not counted.
+func (edb *ExpvarDB) Snapshot() (DBReader, error) {
+	return Snapshot(edb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (edb *ExpvarDB) Metrics() (Metrics, error) {
+	return GetMetrics(edb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (edb *ExpvarDB) EstimateCount() (uint64, error) {
+	return EstimateCount(edb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (edb *ExpvarDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(edb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (edb *ExpvarDB) Compact(start, end []byte) error {
+	return Compact(edb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (edb *ExpvarDB) Flush() error {
+	return Flush(edb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (edb *ExpvarDB) Sync() error {
+	return Sync(edb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+func (edb *ExpvarDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, edb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (edb *ExpvarDB) Checkpoint(path string) error {
+	return Checkpoint(edb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (edb *ExpvarDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, edb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which ExpvarDB passes through.
+func (edb *ExpvarDB) Capabilities() Capability {
+	return Capabilities(edb.db) & (CapSnapshot | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
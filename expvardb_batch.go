@@ -0,0 +1,65 @@
+package db
+
+// expvarDBBatch wraps a batch of the underlying database, counting the bytes
+// written when it is written.
+type expvarDBBatch struct {
+	edb    *ExpvarDB
+	source Batch
+	size   int
+}
+
+var _ Batch = (*expvarDBBatch)(nil)
+
+func newExpvarDBBatch(edb *ExpvarDB, source Batch) *expvarDBBatch {
+	return &expvarDBBatch{
+		edb:    edb,
+		source: source,
+	}
+}
+
+// Set implements Batch.
+func (b *expvarDBBatch) Set(key, value []byte) error {
+	if err := b.source.Set(key, value); err != nil {
+		return err
+	}
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete implements Batch.
+func (b *expvarDBBatch) Delete(key []byte) error {
+	if err := b.source.Delete(key); err != nil {
+		return err
+	}
+	b.size += len(key)
+	return nil
+}
+
+// Count implements Batch.
+func (b *expvarDBBatch) Count() int {
+	return b.source.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *expvarDBBatch) SizeBytes() int {
+	return b.source.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *expvarDBBatch) Write() error {
+	err := b.source.Write()
+	b.edb.observeWrite(metricsOpBatchWrite, b.size, err)
+	return err
+}
+
+// WriteSync implements Batch.
+func (b *expvarDBBatch) WriteSync() error {
+	err := b.source.WriteSync()
+	b.edb.observeWrite(metricsOpBatchWriteSync, b.size, err)
+	return err
+}
+
+// Close implements Batch.
+func (b *expvarDBBatch) Close() error {
+	return b.source.Close()
+}
@@ -0,0 +1,64 @@
+package db
+
+import "expvar"
+
+// expvarDBIterator wraps an iterator of the underlying database, counting the
+// bytes of the items it lands on.
+type expvarDBIterator struct {
+	readBytes *expvar.Int
+	source    Iterator
+}
+
+var _ Iterator = (*expvarDBIterator)(nil)
+
+func newExpvarDBIterator(readBytes *expvar.Int, source Iterator) *expvarDBIterator {
+	itr := &expvarDBIterator{
+		readBytes: readBytes,
+		source:    source,
+	}
+	itr.observeItem()
+	return itr
+}
+
+// observeItem counts the bytes of the item the iterator landed on.
+func (itr *expvarDBIterator) observeItem() {
+	if itr.source.Valid() {
+		itr.readBytes.Add(int64(len(itr.source.Key()) + len(itr.source.Value())))
+	}
+}
+
+// Domain implements Iterator.
+func (itr *expvarDBIterator) Domain() ([]byte, []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *expvarDBIterator) Valid() bool {
+	return itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *expvarDBIterator) Next() {
+	itr.source.Next()
+	itr.observeItem()
+}
+
+// Key implements Iterator.
+func (itr *expvarDBIterator) Key() []byte {
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *expvarDBIterator) Value() []byte {
+	return itr.source.Value()
+}
+
+// Error implements Iterator.
+func (itr *expvarDBIterator) Error() error {
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *expvarDBIterator) Close() error {
+	return itr.source.Close()
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpvarDB(t *testing.T) {
+	edb, err := NewExpvarDB(NewMemDB(), "expvar_test")
+	require.NoError(t, err)
+	defer edb.Close()
+
+	require.NoError(t, edb.Set(bz("a"), bz("1")))
+	require.NoError(t, edb.SetSync(bz("b"), bz("22")))
+	checkValue(t, edb, bz("b"), bz("22"))
+	_, err = edb.Get(nil)
+	require.Error(t, err)
+
+	batch := edb.NewBatch()
+	require.NoError(t, batch.Set(bz("c"), bz("333")))
+	require.NoError(t, batch.Delete(bz("a")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	itr, err := edb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("b"), bz("22"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("c"), bz("333"))
+	checkNext(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	v := expvar.Get("cometbft_db.expvar_test")
+	require.NotNil(t, v)
+	var vars struct {
+		Operations   map[string]int64 `json:"operations"`
+		Errors       map[string]int64 `json:"errors"`
+		ReadBytes    int64            `json:"read_bytes"`
+		WrittenBytes int64            `json:"written_bytes"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(v.String()), &vars))
+	assert.Equal(t, map[string]int64{
+		"set": 1, "set_sync": 1, "get": 2, "batch_write": 1, "iterator": 1,
+	}, vars.Operations)
+	assert.Equal(t, map[string]int64{"get": 1}, vars.Errors)
+	assert.EqualValues(t, 9, vars.ReadBytes)
+	assert.EqualValues(t, 10, vars.WrittenBytes)
+
+	_, err = NewExpvarDB(NewMemDB(), "expvar_test")
+	assert.Error(t, err)
+}
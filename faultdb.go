@@ -0,0 +1,343 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Errors returned by a FaultDB, resembling the failures of real backends.
+var (
+	// ErrFaultDiskFull simulates a write failing for lack of space.
+	ErrFaultDiskFull = errors.New("fault injection: disk full")
+
+	// ErrFaultCorruption simulates corrupted data being read. It is an
+	// ErrCorrupted.
+	ErrFaultCorruption = fmt.Errorf("fault injection: %w", ErrCorrupted)
+
+	// ErrFaultTimeout simulates an operation timing out, e.g. on a remote
+	// database. It is an ErrUnavailable.
+	ErrFaultTimeout = fmt.Errorf("fault injection: timeout: %w", ErrUnavailable)
+)
+
+// FaultOp is a set of operations a Fault applies to.
+type FaultOp uint
+
+const (
+	FaultGet        FaultOp = 1 << iota // Get
+	FaultHas                            // Has
+	FaultSet                            // Set and SetSync
+	FaultDelete                         // Delete and DeleteSync
+	FaultIterator                       // Iterator and ReverseIterator creation
+	FaultIterate                        // every iterator item, matched against its key
+	FaultBatchWrite                     // Batch.Write and WriteSync, matched against every key in the batch
+
+	// FaultRead and FaultWrite group the reading and writing operations.
+	FaultRead  = FaultGet | FaultHas | FaultIterator | FaultIterate
+	FaultWrite = FaultSet | FaultDelete | FaultBatchWrite
+	FaultAll   = FaultRead | FaultWrite
+)
+
+// Fault describes when a FaultDB fails an operation.
+type Fault struct {
+	// Ops are the operations the fault applies to.
+	Ops FaultOp
+	// KeyPrefix, if set, restricts the fault to operations on keys with this
+	// prefix. Operations without a key (e.g. creating an iterator) never match.
+	KeyPrefix []byte
+	// After is the number of matching operations let through before the fault
+	// fires.
+	After int
+	// Times is how many times the fault fires; zero means every time after.
+	Times int
+	// Probability, if set, is the probability with which the fault fires for
+	// each matching operation, e.g. to inject random failures in soak tests.
+	Probability float64
+	// Latency, if set, delays the operations the fault fires for.
+	Latency Latency
+	// Err is the error returned when the fault fires, e.g. ErrFaultDiskFull.
+	// It may be nil for faults which only inject latency.
+	Err error
+}
+
+// Latency is a distribution of the delays injected by a Fault. It draws a
+// delay from rng.
+type Latency func(rng *rand.Rand) time.Duration
+
+// FixedLatency delays every operation by d.
+func FixedLatency(d time.Duration) Latency {
+	return func(*rand.Rand) time.Duration { return d }
+}
+
+// UniformLatency delays operations by a duration uniformly distributed in
+// [min, max].
+func UniformLatency(min, max time.Duration) Latency {
+	return func(rng *rand.Rand) time.Duration {
+		return min + time.Duration(rng.Int63n(int64(max-min)+1))
+	}
+}
+
+// NormalLatency delays operations by a normally distributed duration, never
+// negative.
+func NormalLatency(mean, stddev time.Duration) Latency {
+	return func(rng *rand.Rand) time.Duration {
+		if d := time.Duration(rng.NormFloat64()*float64(stddev)) + mean; d > 0 {
+			return d
+		}
+		return 0
+	}
+}
+
+// ExponentialLatency delays operations by an exponentially distributed
+// duration, i.e. mostly short with a long tail, like degraded disks.
+func ExponentialLatency(mean time.Duration) Latency {
+	return func(rng *rand.Rand) time.Duration {
+		return time.Duration(rng.ExpFloat64() * float64(mean))
+	}
+}
+
+// faultState tracks how many times a Fault was matched and fired.
+type faultState struct {
+	Fault
+	seen  int
+	fired int
+}
+
+// FaultDB wraps a database and fails or delays operations as programmed by
+// Inject, so that crash and recovery paths can be tested deterministically,
+// and timing behavior soak-tested with random latency and failures. A failed
+// operation is not applied to the wrapped database.
+type FaultDB struct {
+	db     DB
+	mtx    sync.Mutex
+	faults []*faultState
+	rng    *rand.Rand
+}
+
+var _ DB = (*FaultDB)(nil)
+
+// NewFaultDB wraps db. Without injected faults, operations pass through.
+func NewFaultDB(db DB) *FaultDB {
+	return &FaultDB{db: db, rng: rand.New(rand.NewSource(time.Now().UnixNano()))} //nolint:gosec
+}
+
+// Seed seeds the random source of the faults with a Probability or Latency, so
+// that their sequences can be reproduced.
+func (fdb *FaultDB) Seed(seed int64) {
+	fdb.mtx.Lock()
+	defer fdb.mtx.Unlock()
+	fdb.rng = rand.New(rand.NewSource(seed)) //nolint:gosec
+}
+
+// Inject adds a fault. Faults are independent, an operation fails with the
+// error of the first fault firing for it, and is delayed by the latencies of
+// all of them.
+func (fdb *FaultDB) Inject(f Fault) {
+	if f.Err == nil && f.Latency == nil {
+		panic("fault requires an error or a latency")
+	}
+	if f.KeyPrefix != nil {
+		f.KeyPrefix = cp(f.KeyPrefix)
+	}
+	fdb.mtx.Lock()
+	defer fdb.mtx.Unlock()
+	fdb.faults = append(fdb.faults, &faultState{Fault: f})
+}
+
+// Clear removes all faults.
+func (fdb *FaultDB) Clear() {
+	fdb.mtx.Lock()
+	defer fdb.mtx.Unlock()
+	fdb.faults = nil
+}
+
+// check records an operation on keys, sleeps for the latencies of the faults
+// firing for it and returns the error of the first one with an error, if any.
+// Each fault counts an operation once, even if several keys match.
+func (fdb *FaultDB) check(op FaultOp, keys ...[]byte) error {
+	var (
+		err   error
+		delay time.Duration
+	)
+	fdb.mtx.Lock()
+	for _, f := range fdb.faults {
+		if f.Ops&op == 0 || !f.matches(keys) {
+			continue
+		}
+		f.seen++
+		if f.seen <= f.After || (f.Times > 0 && f.fired >= f.Times) ||
+			(err != nil && f.Latency == nil) ||
+			(f.Probability > 0 && fdb.rng.Float64() >= f.Probability) {
+			continue
+		}
+		f.fired++
+		if f.Latency != nil {
+			delay += f.Latency(fdb.rng)
+		}
+		if err == nil {
+			err = f.Err
+		}
+	}
+	fdb.mtx.Unlock()
+	time.Sleep(delay)
+	return err
+}
+
+func (f *faultState) matches(keys [][]byte) bool {
+	if f.KeyPrefix == nil {
+		return true
+	}
+	for _, key := range keys {
+		if bytes.HasPrefix(key, f.KeyPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get implements DB.
+func (fdb *FaultDB) Get(key []byte) ([]byte, error) {
+	if err := fdb.check(FaultGet, key); err != nil {
+		return nil, err
+	}
+	return fdb.db.Get(key)
+}
+
+// Has implements DB.
+func (fdb *FaultDB) Has(key []byte) (bool, error) {
+	if err := fdb.check(FaultHas, key); err != nil {
+		return false, err
+	}
+	return fdb.db.Has(key)
+}
+
+// Set implements DB.
+func (fdb *FaultDB) Set(key []byte, value []byte) error {
+	if err := fdb.check(FaultSet, key); err != nil {
+		return err
+	}
+	return fdb.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (fdb *FaultDB) SetSync(key []byte, value []byte) error {
+	if err := fdb.check(FaultSet, key); err != nil {
+		return err
+	}
+	return fdb.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (fdb *FaultDB) Delete(key []byte) error {
+	if err := fdb.check(FaultDelete, key); err != nil {
+		return err
+	}
+	return fdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (fdb *FaultDB) DeleteSync(key []byte) error {
+	if err := fdb.check(FaultDelete, key); err != nil {
+		return err
+	}
+	return fdb.db.DeleteSync(key)
+}
+
+// Iterator implements DB.
+func (fdb *FaultDB) Iterator(start, end []byte) (Iterator, error) {
+	if err := fdb.check(FaultIterator); err != nil {
+		return nil, err
+	}
+	itr, err := fdb.db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newFaultDBIterator(fdb, itr), nil
+}
+
+// ReverseIterator implements DB.
+func (fdb *FaultDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if err := fdb.check(FaultIterator); err != nil {
+		return nil, err
+	}
+	itr, err := fdb.db.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newFaultDBIterator(fdb, itr), nil
+}
+
+// Close implements DB.
+func (fdb *FaultDB) Close() error {
+	return fdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (fdb *FaultDB) NewBatch() Batch {
+	return newFaultDBBatch(fdb)
+}
+
+// Print implements DB.
+func (fdb *FaultDB) Print() error {
+	return fdb.db.Print()
+}
+
+// Stats implements DB.
+func (fdb *FaultDB) Stats() map[string]string {
+	return fdb.db.Stats()
+}
+
+// Metrics implements MetricsReporter, if the wrapped database supports it.
+func (fdb *FaultDB) Metrics() (Metrics, error) {
+	return GetMetrics(fdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (fdb *FaultDB) EstimateCount() (uint64, error) {
+	return EstimateCount(fdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (fdb *FaultDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(fdb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (fdb *FaultDB) Compact(start, end []byte) error {
+	return Compact(fdb.db, start, end)
+}
+
+// Flush implements Flusher, if the wrapped database supports it.
+func (fdb *FaultDB) Flush() error {
+	return Flush(fdb.db)
+}
+
+// Sync implements Syncer, if the wrapped database supports it.
+func (fdb *FaultDB) Sync() error {
+	return Sync(fdb.db)
+}
+
+// Backup implements Backupper, if the wrapped database supports it.
+func (fdb *FaultDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, fdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the wrapped database supports it.
+func (fdb *FaultDB) Checkpoint(path string) error {
+	return Checkpoint(fdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the wrapped database.
+func (fdb *FaultDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, fdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the wrapped
+// database which FaultDB passes through.
+func (fdb *FaultDB) Capabilities() Capability {
+	return Capabilities(fdb.db) & (CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
@@ -0,0 +1,68 @@
+package db
+
+// faultDBBatch wraps a batch of the underlying database, remembering the keys
+// written so that FaultBatchWrite faults can match them.
+type faultDBBatch struct {
+	db     *FaultDB
+	source Batch
+	keys   [][]byte
+}
+
+var _ Batch = (*faultDBBatch)(nil)
+
+func newFaultDBBatch(db *FaultDB) *faultDBBatch {
+	return &faultDBBatch{
+		db:     db,
+		source: db.db.NewBatch(),
+	}
+}
+
+// Set implements Batch.
+func (b *faultDBBatch) Set(key, value []byte) error {
+	if err := b.source.Set(key, value); err != nil {
+		return err
+	}
+	b.keys = append(b.keys, key)
+	return nil
+}
+
+// Delete implements Batch.
+func (b *faultDBBatch) Delete(key []byte) error {
+	if err := b.source.Delete(key); err != nil {
+		return err
+	}
+	b.keys = append(b.keys, key)
+	return nil
+}
+
+// Count implements Batch.
+func (b *faultDBBatch) Count() int {
+	return b.source.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *faultDBBatch) SizeBytes() int {
+	return b.source.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *faultDBBatch) Write() error {
+	if err := b.db.check(FaultBatchWrite, b.keys...); err != nil {
+		return err
+	}
+	return b.source.Write()
+}
+
+// WriteSync implements Batch.
+func (b *faultDBBatch) WriteSync() error {
+	if err := b.db.check(FaultBatchWrite, b.keys...); err != nil {
+		return err
+	}
+	return b.source.WriteSync()
+}
+
+// Close implements Batch.
+func (b *faultDBBatch) Close() error {
+	b.keys = nil
+	return b.source.Close()
+}
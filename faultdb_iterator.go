@@ -0,0 +1,76 @@
+package db
+
+// faultDBIterator wraps an iterator of the underlying database. When a
+// FaultIterate fault fires for the item it lands on, the iterator becomes invalid and Error
+// returns the fault's error.
+type faultDBIterator struct {
+	db     *FaultDB
+	source Iterator
+	err    error
+}
+
+var _ Iterator = (*faultDBIterator)(nil)
+
+func newFaultDBIterator(db *FaultDB, source Iterator) *faultDBIterator {
+	itr := &faultDBIterator{
+		db:     db,
+		source: source,
+	}
+	itr.checkKey()
+	return itr
+}
+
+// checkKey checks FaultIterate faults against the key the iterator landed on.
+func (itr *faultDBIterator) checkKey() {
+	if itr.source.Valid() {
+		itr.err = itr.db.check(FaultIterate, itr.source.Key())
+	}
+}
+
+// Domain implements Iterator.
+func (itr *faultDBIterator) Domain() ([]byte, []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *faultDBIterator) Valid() bool {
+	return itr.err == nil && itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *faultDBIterator) Next() {
+	itr.assertIsValid()
+	itr.source.Next()
+	itr.checkKey()
+}
+
+// Key implements Iterator.
+func (itr *faultDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *faultDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.source.Value()
+}
+
+// Error implements Iterator.
+func (itr *faultDBIterator) Error() error {
+	if itr.err != nil {
+		return itr.err
+	}
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *faultDBIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *faultDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
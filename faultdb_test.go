@@ -0,0 +1,124 @@
+package db
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultDBAfter(t *testing.T) {
+	fdb := NewFaultDB(NewMemDB())
+	fdb.Inject(Fault{Ops: FaultWrite, After: 2, Times: 1, Err: ErrFaultDiskFull})
+
+	require.NoError(t, fdb.Set(bz("a"), bz("1")))
+	require.NoError(t, fdb.Delete(bz("b")))
+	require.Equal(t, ErrFaultDiskFull, fdb.Set(bz("c"), bz("3")))
+	checkValue(t, fdb, bz("c"), nil)
+	// Fired once only.
+	require.NoError(t, fdb.SetSync(bz("c"), bz("3")))
+	checkValue(t, fdb, bz("c"), bz("3"))
+}
+
+func TestFaultDBKeyPrefix(t *testing.T) {
+	fdb := NewFaultDB(NewMemDB())
+	require.NoError(t, fdb.Set(bz("bad/1"), bz("1")))
+	fdb.Inject(Fault{Ops: FaultGet | FaultHas, KeyPrefix: bz("bad/"), Err: ErrFaultCorruption})
+
+	_, err := fdb.Get(bz("bad/1"))
+	require.Equal(t, ErrFaultCorruption, err)
+	_, err = fdb.Has(bz("bad/2"))
+	require.Equal(t, ErrFaultCorruption, err)
+	checkValue(t, fdb, bz("good"), nil)
+
+	fdb.Clear()
+	checkValue(t, fdb, bz("bad/1"), bz("1"))
+}
+
+func TestFaultDBBatch(t *testing.T) {
+	fdb := NewFaultDB(NewMemDB())
+	fdb.Inject(Fault{Ops: FaultBatchWrite, KeyPrefix: bz("c"), Err: ErrFaultDiskFull})
+
+	batch := fdb.NewBatch()
+	require.NoError(t, batch.Set(bz("a"), bz("1")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	batch = fdb.NewBatch()
+	require.NoError(t, batch.Set(bz("b"), bz("2")))
+	require.NoError(t, batch.Set(bz("c"), bz("3")))
+	require.Equal(t, ErrFaultDiskFull, batch.WriteSync())
+	require.NoError(t, batch.Close())
+
+	checkValue(t, fdb, bz("a"), bz("1"))
+	checkValue(t, fdb, bz("b"), nil)
+}
+
+func TestFaultDBIterator(t *testing.T) {
+	fdb := NewFaultDB(NewMemDB())
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, fdb.Set(bz(key), bz(key)))
+	}
+
+	fdb.Inject(Fault{Ops: FaultIterator, Times: 1, Err: ErrFaultTimeout})
+	_, err := fdb.Iterator(nil, nil)
+	require.Equal(t, ErrFaultTimeout, err)
+
+	fdb.Inject(Fault{Ops: FaultIterate, KeyPrefix: bz("b"), Err: ErrFaultCorruption})
+	itr, err := fdb.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("c"), bz("c"))
+	checkNext(t, itr, false)
+	assert.Equal(t, ErrFaultCorruption, itr.Error())
+	require.NoError(t, itr.Close())
+}
+
+func TestFaultDBInjectWithoutError(t *testing.T) {
+	assert.Panics(t, func() { NewFaultDB(NewMemDB()).Inject(Fault{Ops: FaultAll}) })
+}
+
+func TestFaultDBLatency(t *testing.T) {
+	fdb := NewFaultDB(NewMemDB())
+	fdb.Inject(Fault{Ops: FaultSet, Latency: FixedLatency(20 * time.Millisecond)})
+
+	start := time.Now()
+	require.NoError(t, fdb.Set(bz("a"), bz("1")))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	checkValue(t, fdb, bz("a"), bz("1"))
+
+	// Latency adds up with errors.
+	fdb.Inject(Fault{Ops: FaultSet, Err: ErrFaultTimeout})
+	start = time.Now()
+	assert.Equal(t, ErrFaultTimeout, fdb.Set(bz("a"), bz("2")))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestFaultDBProbability(t *testing.T) {
+	run := func(seed int64) (failed []int) {
+		fdb := NewFaultDB(NewMemDB())
+		fdb.Seed(seed)
+		fdb.Inject(Fault{Ops: FaultGet, Probability: 0.25, Err: ErrFaultTimeout})
+		for i := 0; i < 1000; i++ {
+			if _, err := fdb.Get(bz("a")); err != nil {
+				failed = append(failed, i)
+			}
+		}
+		return failed
+	}
+	failed := run(1)
+	assert.InDelta(t, 250, len(failed), 50)
+	assert.Equal(t, failed, run(1), "a seed reproduces the failures")
+}
+
+func TestFaultDBLatencyDistributions(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		d := UniformLatency(time.Millisecond, 2*time.Millisecond)(rng)
+		require.GreaterOrEqual(t, d, time.Millisecond)
+		require.LessOrEqual(t, d, 2*time.Millisecond)
+		require.GreaterOrEqual(t, NormalLatency(time.Millisecond, 10*time.Millisecond)(rng), time.Duration(0))
+		require.GreaterOrEqual(t, ExponentialLatency(time.Millisecond)(rng), time.Duration(0))
+	}
+}
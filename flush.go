@@ -0,0 +1,64 @@
+package db
+
+// Flusher is implemented by databases which buffer writes in memory (e.g. the
+// memtable of an LSM tree) and can write them out to their on-disk tables on
+// demand.
+type Flusher interface {
+	// Flush writes the buffered writes to the on-disk tables, and returns once
+	// they are durable. It does not need to be called for durability, since
+	// buffered writes are also in the write-ahead log, but it leaves the files
+	// in a state which can be restored without replaying the log.
+	Flush() error
+}
+
+// Syncer is implemented by databases which can make all the writes made so far
+// durable on demand, e.g. by syncing their write-ahead log.
+type Syncer interface {
+	// Sync makes all the writes completed before the call durable, as if they
+	// had been made with SetSync, DeleteSync or WriteSync.
+	Sync() error
+}
+
+// Flush writes the buffered writes of db to its on-disk tables, see Flusher.
+// It returns ErrNotSupported if db does not implement Flusher.
+func Flush(db DB) error {
+	f, ok := db.(Flusher)
+	if !ok {
+		return ErrNotSupported
+	}
+	return f.Flush()
+}
+
+// Sync makes the writes completed so far on db durable, see Syncer. It returns
+// ErrNotSupported if db does not implement Syncer.
+//
+// Together with Flush, it can be used to bring the database files to a
+// consistent state before taking a filesystem snapshot of them, provided no
+// writes are made in the meantime.
+func Sync(db DB) error {
+	s, ok := db.(Syncer)
+	if !ok {
+		return ErrNotSupported
+	}
+	return s.Sync()
+}
+
+// flushAll flushes all dbs, which must all support it.
+func flushAll(dbs []DB) error {
+	for _, db := range dbs {
+		if err := Flush(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncAll syncs all dbs, which must all support it.
+func syncAll(dbs []DB) error {
+	for _, db := range dbs {
+		if err := Sync(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
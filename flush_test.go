@@ -0,0 +1,71 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlushSync(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			for i := int64(0); i < 100; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+			if err := Flush(db); !errors.Is(err, ErrNotSupported) {
+				require.NoError(t, err)
+			}
+			if err := Sync(db); !errors.Is(err, ErrNotSupported) {
+				require.NoError(t, err)
+			}
+			for i := int64(0); i < 100; i++ {
+				checkValue(t, db, int642Bytes(i), int642Bytes(i))
+			}
+		})
+	}
+}
+
+func TestFlushSyncNotSupported(t *testing.T) {
+	db := NewMemDB()
+	assert.ErrorIs(t, Flush(db), ErrNotSupported)
+	assert.ErrorIs(t, Sync(db), ErrNotSupported)
+
+	// Wrappers over several databases require all of them to support it.
+	dir, err := os.MkdirTemp("", "flush_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	pdb, err := NewPebbleDB("pebble", dir)
+	require.NoError(t, err)
+	defer pdb.Close()
+	tdb := NewTieredDB(pdb, NewMemDB(), TierPolicy{})
+	assert.ErrorIs(t, Flush(tdb), ErrNotSupported)
+	assert.ErrorIs(t, Sync(tdb), ErrNotSupported)
+}
+
+func TestPebbleDBFlush(t *testing.T) {
+	dir, err := os.MkdirTemp("", "flush_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := NewPebbleDB("pebble", dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.Sync())
+	assert.Zero(t, db.DB().Metrics().Flush.Count)
+
+	require.NoError(t, db.Flush())
+	assert.EqualValues(t, 1, db.DB().Metrics().Flush.Count)
+	checkValue(t, db, bz("a"), bz("1"))
+}
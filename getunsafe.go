@@ -0,0 +1,31 @@
+package db
+
+// UnsafeGetter is implemented by databases which can pass a stored value to the
+// caller without copying it out of their internal buffers, e.g. a page of a
+// memory-mapped file or a block of the block cache.
+type UnsafeGetter interface {
+	// GetUnsafe calls fn with the value of the given key, or with nil if it
+	// does not exist. The value is only valid until fn returns, and must not be
+	// modified or retained: fn must copy whatever it needs to keep. fn must not
+	// use the database, which may be holding locks or a read transaction while
+	// it runs. The error returned by fn is returned as is.
+	// CONTRACT: key readonly []byte
+	GetUnsafe(key []byte, fn func(value []byte) error) error
+}
+
+// GetUnsafe calls fn with the value of key in db, or with nil if it does not
+// exist, see UnsafeGetter. It saves the copy made by Get if db implements
+// UnsafeGetter, and otherwise calls fn with the result of Get.
+func GetUnsafe(db DB, key []byte, fn func(value []byte) error) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if ug, ok := db.(UnsafeGetter); ok {
+		return ug.GetUnsafe(key, fn)
+	}
+	value, err := db.Get(key)
+	if err != nil {
+		return err
+	}
+	return fn(value)
+}
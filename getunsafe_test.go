@@ -0,0 +1,75 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUnsafe(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			require.NoError(t, db.Set(bz("a"), bz("1")))
+			require.NoError(t, db.Set(bz("empty"), []byte{}))
+
+			var value []byte
+			copyValue := func(v []byte) error {
+				if v != nil {
+					v = append([]byte{}, v...)
+				}
+				value = v
+				return nil
+			}
+			require.NoError(t, GetUnsafe(db, bz("a"), copyValue))
+			assert.Equal(t, bz("1"), value)
+			require.NoError(t, GetUnsafe(db, bz("empty"), copyValue))
+			assert.Equal(t, []byte{}, value)
+			require.NoError(t, GetUnsafe(db, bz("missing"), copyValue))
+			assert.Nil(t, value)
+
+			errFn := errors.New("fn failed")
+			err = GetUnsafe(db, bz("a"), func([]byte) error { return errFn })
+			assert.Equal(t, errFn, err)
+			err = GetUnsafe(db, bz("missing"), func([]byte) error { return errFn })
+			assert.Equal(t, errFn, err)
+			assert.Equal(t, errKeyEmpty, GetUnsafe(db, []byte{}, copyValue))
+		})
+	}
+}
+
+func BenchmarkPebbleDBGetUnsafe(b *testing.B) {
+	dir := b.TempDir()
+	db, err := NewPebbleDB("pebble", dir)
+	require.NoError(b, err)
+	defer db.Close()
+	value := make([]byte, 1024)
+	require.NoError(b, db.Set(bz("key"), value))
+
+	b.Run("Get", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := db.Get(bz("key")); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("GetUnsafe", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := db.GetUnsafe(bz("key"), func([]byte) error { return nil }); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
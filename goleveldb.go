@@ -1,80 +1,364 @@
 package db
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/filter"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/storage"
 	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
+const (
+	// defaultGoLevelDBBloomBits is the bits per key of the bloom filter of
+	// DefaultGoLevelDBOptions, giving about 1% of false positives.
+	defaultGoLevelDBBloomBits = 10
+
+	// defaultGoLevelDBCacheSize is the block cache capacity of
+	// DefaultGoLevelDBOptions.
+	defaultGoLevelDBCacheSize = 32 << 20
+
+	// defaultGoLevelDBWriteBuffer is the write buffer size of
+	// DefaultGoLevelDBOptions.
+	defaultGoLevelDBWriteBuffer = 16 << 20
+)
+
 func init() {
-	dbCreator := func(name string, dir string) (DB, error) {
-		return NewGoLevelDB(name, dir)
+	registerDBCreator(GoLevelDBBackend, newGoLevelDBFromOptions, false)
+	registerBackendParams(GoLevelDBBackend)
+}
+
+// DefaultGoLevelDBOptions returns the options used by NewGoLevelDB and NewDB: a
+// bloom filter of 10 bits per key, so that reads of missing keys rarely touch
+// the tables, a 32 MiB block cache and a 16 MiB write buffer. The goleveldb
+// defaults, used with nil options, have no filter, an 8 MiB cache and a 4 MiB
+// write buffer.
+func DefaultGoLevelDBOptions() *opt.Options {
+	return &opt.Options{
+		Filter:             filter.NewBloomFilter(defaultGoLevelDBBloomBits),
+		BlockCacheCapacity: defaultGoLevelDBCacheSize,
+		WriteBuffer:        defaultGoLevelDBWriteBuffer,
+	}
+}
+
+// newGoLevelDBFromOptions accepts *opt.Options as backend options, which
+// replace DefaultGoLevelDBOptions, and the backend params of
+// applyGoLevelDBParams.
+func newGoLevelDBFromOptions(name, dir string, opts *Options) (DB, error) {
+	o := DefaultGoLevelDBOptions()
+	if opts.BackendOptions != nil {
+		bo, ok := opts.BackendOptions.(*opt.Options)
+		if !ok {
+			return nil, errBackendOptions(GoLevelDBBackend, opts.BackendOptions)
+		}
+		*o = *bo
+	}
+	if err := applyGoLevelDBParams(o, newBackendParams(opts.BackendParams)); err != nil {
+		return nil, err
+	}
+	if opts.CacheSize > 0 {
+		o.BlockCacheCapacity = int(opts.CacheSize)
+	}
+	if opts.WriteBufferSize > 0 {
+		o.WriteBuffer = int(opts.WriteBufferSize)
+	}
+	switch {
+	case opts.BloomFilterBits > 0:
+		o.Filter = filter.NewBloomFilter(opts.BloomFilterBits)
+	case opts.BloomFilterBits < 0:
+		o.Filter = nil
+	}
+	if opts.ReadOnly {
+		o.ReadOnly = true
+	}
+	if opts.SharedCache == nil {
+		return newGoLevelDB(name, dir, o, opts)
+	}
+	// The memtable being written and the one being flushed.
+	cacher, err := opts.SharedCache.goLevelDBCacher(2 * o.GetWriteBuffer())
+	if err != nil {
+		return nil, err
+	}
+	o.BlockCacher = cacher
+	o.BlockCacheCapacity = cacher.Capacity()
+	db, err := newGoLevelDB(name, dir, o, opts)
+	if err != nil {
+		// goleveldb closes the cacher with the database once opened.
+		cacher.Close()
+		return nil, err
 	}
-	registerDBCreator(GoLevelDBBackend, dbCreator, false)
+	return db, nil
 }
 
-type timerFunc func()
+// applyGoLevelDBParams applies the backend params of goleveldb to o: the cache,
+// write_buffer, block_size and table_size (of the tables written by
+// compactions) sizes, the max_open_files count, the bloom filter bits per key
+// (filter_bits, 0 disabling the filter), compression (none or snappy) and
+// readonly.
+func applyGoLevelDBParams(o *opt.Options, params *backendParams) error {
+	if v, ok := params.size("cache"); ok {
+		o.BlockCacheCapacity = int(v)
+	}
+	if v, ok := params.size("write_buffer"); ok {
+		o.WriteBuffer = int(v)
+	}
+	if v, ok := params.size("block_size"); ok {
+		o.BlockSize = int(v)
+	}
+	if v, ok := params.size("table_size"); ok {
+		o.CompactionTableSize = int(v)
+	}
+	if v, ok := params.int("max_open_files"); ok {
+		o.OpenFilesCacheCapacity = v
+	}
+	if v, ok := params.int("filter_bits"); ok {
+		o.Filter = nil
+		if v > 0 {
+			o.Filter = filter.NewBloomFilter(v)
+		}
+	}
+	if v, ok := params.string("compression"); ok {
+		switch v {
+		case "none":
+			o.Compression = opt.NoCompression
+		case "snappy":
+			o.Compression = opt.SnappyCompression
+		default:
+			params.fail("compression", v, errors.New("expected none or snappy"))
+		}
+	}
+	if v, ok := params.bool("readonly"); ok {
+		o.ReadOnly = v
+	}
+	return params.err()
+}
 
 type GoLevelDB struct {
 	db      *leveldb.DB
+	stor    storage.Storage // closed after db
 	name    string
-	written uint64
-	f       timerFunc
+	path    string
+	written uint64            // bytes written, accessed atomically
+	wo      *opt.WriteOptions // nil unless writes are synced
+	logger  Logger
+	iters   *iteratorTracker
+	lock    *dbLock
+	stalls  *writeStallWatcher
+
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
 }
 
 var _ DB = (*GoLevelDB)(nil)
 
+// NewGoLevelDB creates a GoLevelDB with DefaultGoLevelDBOptions.
 func NewGoLevelDB(name string, dir string) (*GoLevelDB, error) {
-	return NewGoLevelDBWithOpts(name, dir, nil)
+	return NewGoLevelDBWithOpts(name, dir, DefaultGoLevelDBOptions())
 }
 
+// NewGoLevelDBWithOpts creates a GoLevelDB with the given options, or the
+// goleveldb defaults if nil.
 func NewGoLevelDBWithOpts(name string, dir string, o *opt.Options) (*GoLevelDB, error) {
-	log.Printf("New db: %s", name)
-	dbPath := filepath.Join(dir, name+".db")
-	db, err := leveldb.OpenFile(dbPath, o)
+	return newGoLevelDB(name, dir, o, newOptions(nil))
+}
+
+func newGoLevelDB(name string, dir string, o *opt.Options, opts *Options) (*GoLevelDB, error) {
+	opts.Logger.Info("New db", "name", name)
+	var (
+		dbPath string
+		stor   storage.Storage
+		err    error
+	)
+	switch {
+	case opts.InMemory:
+		stor = storage.NewMemStorage()
+	case opts.FS != nil:
+		stor, err = newGoLevelDBVFSStorage(opts.FS, opts.FS.PathJoin(dir, name+".db"))
+	default:
+		dbPath = filepath.Join(dir, name+".db")
+		stor, err = storage.OpenFile(dbPath, o.GetReadOnly())
+	}
 	if err != nil {
-		return nil, err
+		return nil, goLevelDBError(err)
+	}
+	db, err := leveldb.Open(stor, o)
+	if errors.IsCorrupted(err) && opts.Recover && !o.GetReadOnly() {
+		db, err = recoverGoLevelDB(name, stor, o, opts, err)
+	}
+	if err != nil {
+		stor.Close()
+		return nil, goLevelDBError(err)
 	}
 	database := &GoLevelDB{
-		db:   db,
-		name: name,
-	}
-	ticker := time.NewTicker(1 * time.Minute)
-
-	f := func() {
-		for {
-			select {
-			case <-ticker.C:
-				log.Printf("DB %s stats", database.name)
-				log.Printf("%d bytes written", database.written)
-				for k, v := range database.Stats() {
-					log.Printf("%s %s", k, v)
-				}
+		db:     db,
+		stor:   stor,
+		name:   name,
+		path:   dbPath,
+		logger: opts.Logger,
+		iters:  newIteratorTracker(name, opts),
+		lock:   opts.lock,
+		quit:   make(chan struct{}),
+	}
+	if opts.SyncWrites {
+		database.wo = &opt.WriteOptions{Sync: true}
+	}
+	if opts.logsStats() {
+		database.wg.Add(1)
+		go database.runStats(opts.StatsInterval)
+	}
+	if opts.OnWriteStall != nil {
+		database.stalls = watchWriteStalls(database.writeStalled, opts.OnWriteStall)
+	}
+	return database, nil
+}
+
+// writeStalled reports whether goleveldb pauses writes, which it does while
+// level 0 has too many tables.
+func (db *GoLevelDB) writeStalled() (bool, string) {
+	var stats leveldb.DBStats
+	if err := db.db.Stats(&stats); err != nil || !stats.WritePaused {
+		return false, ""
+	}
+	return true, "too many level 0 tables"
+}
+
+// RecoverGoLevelDB recovers the goleveldb database name in dir, which must not
+// be open, by rebuilding its manifest from the table files, e.g. after the
+// manifest was lost or corrupted. The corrupted blocks of the tables are
+// dropped, along with their keys.
+func RecoverGoLevelDB(name, dir string) error {
+	db, err := leveldb.RecoverFile(filepath.Join(dir, name+".db"), nil)
+	if err != nil {
+		return goLevelDBError(err)
+	}
+	return goLevelDBError(db.Close())
+}
+
+// recoverGoLevelDB recovers the database of stor, which could not be opened
+// because of the corruption cause, like RecoverGoLevelDB, and reports the
+// recovery.
+func recoverGoLevelDB(name string, stor storage.Storage, o *opt.Options, opts *Options, cause error) (*leveldb.DB, error) {
+	rstor := &goLevelDBRecoveryStorage{Storage: stor}
+	db, err := leveldb.Recover(rstor, o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover from %v: %w", cause, err)
+	}
+	rstor.mtx.Lock()
+	info := rstor.info
+	rstor.done = true
+	rstor.mtx.Unlock()
+	info.Err = goLevelDBError(cause)
+	reportRecovery(name, opts, info)
+	return db, nil
+}
+
+// goLevelDBRecoveryStorage tallies what the recovery of goleveldb drops from
+// the messages it logs, which are its only report.
+type goLevelDBRecoveryStorage struct {
+	storage.Storage
+
+	mtx  sync.Mutex
+	info RecoveryInfo
+	done bool // set once the recovery is over
+}
+
+// Log implements storage.Storage.
+func (s *goLevelDBRecoveryStorage) Log(str string) {
+	s.Storage.Log(str)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.done {
+		return
+	}
+	var tables, entries int
+	switch {
+	case strings.HasPrefix(str, "table@recovery block corruption "):
+		s.info.CorruptedBlocks++
+	case strings.HasPrefix(str, "table@recovery unrecoverable "),
+		strings.HasPrefix(str, "table@recovery dropped "):
+		s.info.DroppedTables++
+	case strings.HasPrefix(str, "table@recovery recovered F·"):
+		if _, err := fmt.Sscanf(str, "table@recovery recovered F·%d N·%d", &tables, &entries); err == nil {
+			s.info.Tables = tables
+			s.info.RecoveredEntries = entries
+		}
+	}
+}
+
+// runStats logs the metrics of the database every interval until it is closed.
+func (db *GoLevelDB) runStats(interval time.Duration) {
+	defer db.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-db.quit:
+			return
+		case <-ticker.C:
+			m, err := db.Metrics()
+			if err != nil {
+				db.logger.Error("DB stats", "name", db.name, "err", err)
+				continue
 			}
+			logMetrics(db.logger, "DB stats", db.name, atomic.LoadUint64(&db.written), m)
 		}
 	}
-	database.f = f
-	go database.f()
-	return database, nil
+}
+
+// goLevelDBError wraps the goleveldb errors with the shared error kinds.
+func goLevelDBError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case err == leveldb.ErrClosed:
+		return wrapError(ErrClosed, err)
+	case err == leveldb.ErrReadOnly:
+		return wrapError(ErrReadOnly, err)
+	case errors.IsCorrupted(err):
+		return wrapError(ErrCorrupted, err)
+	}
+	return err
+}
+
+// goLevelDBReadOptions returns the goleveldb options for opts, nil for the
+// defaults.
+func goLevelDBReadOptions(opts ReadOptions) *opt.ReadOptions {
+	if opts == (ReadOptions{}) {
+		return nil
+	}
+	ro := &opt.ReadOptions{DontFillCache: opts.DontFillCache}
+	if opts.VerifyChecksums {
+		ro.Strict = opt.StrictBlockChecksum
+	}
+	return ro
 }
 
 // Get implements DB.
 func (db *GoLevelDB) Get(key []byte) ([]byte, error) {
+	return db.GetWithOptions(key, ReadOptions{})
+}
+
+// GetWithOptions implements ReadOptionsDB.
+func (db *GoLevelDB) GetWithOptions(key []byte, opts ReadOptions) ([]byte, error) {
 	if len(key) == 0 {
 		return nil, errKeyEmpty
 	}
-	res, err := db.db.Get(key, nil)
+	res, err := db.db.Get(key, goLevelDBReadOptions(opts))
 	if err != nil {
 		if err == errors.ErrNotFound {
 			return nil, nil
 		}
-		return nil, err
+		return nil, goLevelDBError(err)
 	}
 	return res, nil
 }
@@ -90,32 +374,40 @@ func (db *GoLevelDB) Has(key []byte) (bool, error) {
 
 // Set implements DB.
 func (db *GoLevelDB) Set(key []byte, value []byte) error {
-	// log.Printf("Set call: name is %s, key is %s, value is %d bytes", db.name, hex.EncodeToString(key), len(value))
-	db.written += uint64(len(value))
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
 	if value == nil {
 		return errValueNil
 	}
-	if err := db.db.Put(key, value, nil); err != nil {
-		return err
+	db.logWrite(metricsOpSet, key, value)
+	atomic.AddUint64(&db.written, uint64(len(value)))
+	if err := db.db.Put(key, value, db.wo); err != nil {
+		return goLevelDBError(err)
 	}
 	return nil
 }
 
+// logWrite logs a write at LogLevelDebug, if the logger passes it on. The key
+// is only hex-encoded when the message is formatted.
+func (db *GoLevelDB) logWrite(op string, key, value []byte) {
+	if logEnabled(db.logger, LogLevelDebug) {
+		db.logger.Debug("Write", "db", db.name, "op", op, "key", hexKey(key), "value_size", len(value))
+	}
+}
+
 // SetSync implements DB.
 func (db *GoLevelDB) SetSync(key []byte, value []byte) error {
-	// log.Printf("Set call: name is %s, key is %s, value is %d bytes", db.name, hex.EncodeToString(key), len(value))
-	db.written += uint64(len(value))
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
 	if value == nil {
 		return errValueNil
 	}
+	db.logWrite(metricsOpSetSync, key, value)
+	atomic.AddUint64(&db.written, uint64(len(value)))
 	if err := db.db.Put(key, value, &opt.WriteOptions{Sync: true}); err != nil {
-		return err
+		return goLevelDBError(err)
 	}
 	return nil
 }
@@ -125,8 +417,8 @@ func (db *GoLevelDB) Delete(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
-	if err := db.db.Delete(key, nil); err != nil {
-		return err
+	if err := db.db.Delete(key, db.wo); err != nil {
+		return goLevelDBError(err)
 	}
 	return nil
 }
@@ -138,7 +430,7 @@ func (db *GoLevelDB) DeleteSync(key []byte) error {
 	}
 	err := db.db.Delete(key, &opt.WriteOptions{Sync: true})
 	if err != nil {
-		return err
+		return goLevelDBError(err)
 	}
 	return nil
 }
@@ -149,8 +441,19 @@ func (db *GoLevelDB) DB() *leveldb.DB {
 
 // Close implements DB.
 func (db *GoLevelDB) Close() error {
-	if err := db.db.Close(); err != nil {
-		return err
+	defer db.lock.release()
+	db.closeOnce.Do(func() {
+		close(db.quit)
+		db.stalls.stop()
+	})
+	db.wg.Wait()
+	db.iters.close()
+	err := db.db.Close()
+	if serr := db.stor.Close(); err == nil {
+		err = serr
+	}
+	if err != nil {
+		return goLevelDBError(err)
 	}
 	return nil
 }
@@ -195,26 +498,186 @@ func (db *GoLevelDB) Stats() map[string]string {
 	return stats
 }
 
+// Metrics implements MetricsReporter. goleveldb does not count block cache hits
+// and misses, nor track the size of its memtable, and the disk size excludes
+// the journal.
+func (db *GoLevelDB) Metrics() (Metrics, error) {
+	var stats leveldb.DBStats
+	if err := db.db.Stats(&stats); err != nil {
+		return Metrics{}, goLevelDBError(err)
+	}
+	m := Metrics{
+		Levels:   make([]LevelMetrics, len(stats.LevelSizes)),
+		DiskSize: uint64(stats.LevelSizes.Sum()),
+		BlockCache: CacheMetrics{
+			Size: uint64(stats.BlockCacheSize),
+		},
+		Compaction: CompactionMetrics{
+			Count:      uint64(stats.MemComp) + uint64(stats.Level0Comp) + uint64(stats.NonLevel0Comp) + uint64(stats.SeekComp),
+			ReadBytes:  uint64(stats.LevelRead.Sum()),
+			WriteBytes: uint64(stats.LevelWrite.Sum()),
+		},
+		Snapshots:    int64(stats.AliveSnapshots),
+		Iterators:    int64(stats.AliveIterators),
+		WriteStalled: stats.WritePaused,
+		WriteStalls:  uint64(stats.WriteDelayCount),
+	}
+	for i, size := range stats.LevelSizes {
+		m.Levels[i] = LevelMetrics{Files: int64(stats.LevelTablesCounts[i]), Size: uint64(size)}
+	}
+	return m, nil
+}
+
+// DiskUsage implements DiskUsageReporter, with the live tables listed by the
+// "leveldb.sstables" property. It returns ErrNotSupported for databases kept
+// in memory or in Options.FS.
+func (db *GoLevelDB) DiskUsage() (DiskUsageReport, error) {
+	if db.path == "" {
+		return DiskUsageReport{}, ErrNotSupported
+	}
+	tables, err := db.db.GetProperty("leveldb.sstables")
+	if err != nil {
+		return DiskUsageReport{}, goLevelDBError(err)
+	}
+	live := make(map[uint64]struct{})
+	for _, line := range strings.Split(tables, "\n") {
+		// Tables are listed as "num:size[min .. max]".
+		num, _, ok := strings.Cut(line, ":")
+		if n, err := strconv.ParseUint(num, 10, 64); ok && err == nil {
+			live[n] = struct{}{}
+		}
+	}
+	return diskUsage(db.path, live)
+}
+
+// HealthCheck implements HealthChecker. goleveldb pauses writes while level 0
+// has too many tables; corruption found by a compaction makes writes fail,
+// which HealthCheck detects.
+func (db *GoLevelDB) HealthCheck(context.Context) error {
+	var stats leveldb.DBStats
+	if err := db.db.Stats(&stats); err != nil {
+		return goLevelDBError(err)
+	}
+	if stats.WritePaused {
+		return ErrWriteStalled
+	}
+	return nil
+}
+
+// Compact implements Compacter.
+func (db *GoLevelDB) Compact(start, end []byte) error {
+	return goLevelDBError(db.db.CompactRange(util.Range{Start: start, Limit: end}))
+}
+
+// Backup implements Backupper. The keys of a snapshot are copied to a new
+// database, since the files of a live database cannot be copied consistently.
+// The copy is opened with the default options.
+func (db *GoLevelDB) Backup(ctx context.Context, targetDir string) error {
+	return db.copySnapshot(ctx, filepath.Join(targetDir, db.name+".db"))
+}
+
+// Checkpoint implements Checkpointer. goleveldb cannot keep its files from
+// being deleted by compactions while they are linked, so the keys of a
+// snapshot are copied instead, as by Backup. The checkpoint is therefore
+// neither instant nor space-efficient.
+func (db *GoLevelDB) Checkpoint(path string) error {
+	return db.copySnapshot(context.Background(), path)
+}
+
+// copySnapshot copies the keys of a snapshot to a new database at path.
+func (db *GoLevelDB) copySnapshot(ctx context.Context, path string) error {
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return goLevelDBError(err)
+	}
+	defer snap.Release()
+
+	dst, err := leveldb.OpenFile(path, &opt.Options{ErrorIfExist: true})
+	if err != nil {
+		return goLevelDBError(err)
+	}
+	defer dst.Close()
+
+	itr := snap.NewIterator(nil, nil)
+	defer itr.Release()
+	batch := new(leveldb.Batch)
+	for itr.Next() {
+		batch.Put(itr.Key(), itr.Value())
+		if len(batch.Dump()) < backupBatchSize {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := dst.Write(batch, nil); err != nil {
+			return goLevelDBError(err)
+		}
+		batch.Reset()
+	}
+	if err := itr.Error(); err != nil {
+		return goLevelDBError(err)
+	}
+	if err := dst.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		return goLevelDBError(err)
+	}
+	return goLevelDBError(dst.Close())
+}
+
+// EstimateSize implements SizeEstimator, using the sizes of the sstables
+// overlapping the range. Data still in the memtable is not accounted for.
+func (db *GoLevelDB) EstimateSize(start, end []byte) (uint64, error) {
+	if end == nil {
+		// SizeOf needs an upper bound: use the key right after the last one.
+		itr := db.db.NewIterator(nil, nil)
+		if itr.Last() {
+			end = rangeUpperBound(itr.Key())
+		}
+		itr.Release()
+		if err := itr.Error(); err != nil {
+			return 0, goLevelDBError(err)
+		}
+		if end == nil {
+			// Empty database
+			return 0, nil
+		}
+	}
+	sizes, err := db.db.SizeOf([]util.Range{{Start: start, Limit: end}})
+	if err != nil {
+		return 0, goLevelDBError(err)
+	}
+	return uint64(sizes.Sum()), nil
+}
+
 // NewBatch implements DB.
 func (db *GoLevelDB) NewBatch() Batch {
-	// log.Printf("NewBatch call: name is %s", db.name)
 	return newGoLevelDBBatch(db)
 }
 
 // Iterator implements DB.
 func (db *GoLevelDB) Iterator(start, end []byte) (Iterator, error) {
-	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
-		return nil, errKeyEmpty
-	}
-	itr := db.db.NewIterator(&util.Range{Start: start, Limit: end}, nil)
-	return newGoLevelDBIterator(itr, start, end, false), nil
+	return db.IteratorWithOptions(start, end, IteratorOptions{})
 }
 
 // ReverseIterator implements DB.
 func (db *GoLevelDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return db.IteratorWithOptions(start, end, IteratorOptions{Reverse: true})
+}
+
+// IteratorWithOptions implements IteratorOptionsDB, applying the ReadOptions.
+// KeysOnly iterators still read the values.
+func (db *GoLevelDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
-	itr := db.db.NewIterator(&util.Range{Start: start, Limit: end}, nil)
-	return newGoLevelDBIterator(itr, start, end, true), nil
+	source := db.db.NewIterator(&util.Range{Start: start, Limit: end}, goLevelDBReadOptions(opts.ReadOptions))
+	// The iterators of a closed database are empty, failing with ErrClosed.
+	if err := source.Error(); err != nil {
+		source.Release()
+		return nil, goLevelDBError(err)
+	}
+	itr := Iterator(newGoLevelDBIterator(source, start, end, opts.Reverse))
+	if opts.KeysOnly {
+		itr = newKeysOnlyIterator(itr)
+	}
+	return db.iters.track(itr, nil)
 }
@@ -1,21 +1,38 @@
 package db
 
 import (
+	"sync"
+	"sync/atomic"
+
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 )
 
+// maxPooledBatchSize is the size above which the buffers of closed batches are
+// left to the garbage collector rather than pooled.
+const maxPooledBatchSize = 16 << 20
+
+// goLevelDBBatchPool holds the leveldb batches of closed batches, for reuse by
+// new and reset ones.
+var goLevelDBBatchPool = sync.Pool{
+	New: func() interface{} { return new(leveldb.Batch) },
+}
+
 type goLevelDBBatch struct {
 	db    *GoLevelDB
 	batch *leveldb.Batch
+	size  int
 }
 
-var _ Batch = (*goLevelDBBatch)(nil)
+var (
+	_ Batch         = (*goLevelDBBatch)(nil)
+	_ BatchResetter = (*goLevelDBBatch)(nil)
+)
 
 func newGoLevelDBBatch(db *GoLevelDB) *goLevelDBBatch {
 	return &goLevelDBBatch{
 		db:    db,
-		batch: new(leveldb.Batch),
+		batch: goLevelDBBatchPool.Get().(*leveldb.Batch),
 	}
 }
 
@@ -28,9 +45,10 @@ func (b *goLevelDBBatch) Set(key, value []byte) error {
 		return errValueNil
 	}
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.batch.Put(key, value)
+	b.size += len(key) + len(value)
 	return nil
 }
 
@@ -40,12 +58,29 @@ func (b *goLevelDBBatch) Delete(key []byte) error {
 		return errKeyEmpty
 	}
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.batch.Delete(key)
+	b.size += len(key)
 	return nil
 }
 
+// Count implements Batch.
+func (b *goLevelDBBatch) Count() int {
+	if b.batch == nil {
+		return 0
+	}
+	return b.batch.Len()
+}
+
+// SizeBytes implements Batch.
+func (b *goLevelDBBatch) SizeBytes() int {
+	if b.batch == nil {
+		return 0
+	}
+	return b.size
+}
+
 // Write implements Batch.
 func (b *goLevelDBBatch) Write() error {
 	return b.write(false)
@@ -57,25 +92,40 @@ func (b *goLevelDBBatch) WriteSync() error {
 }
 
 func (b *goLevelDBBatch) write(sync bool) error {
-	// log.Printf("Write (batch): name is %s, size is %d bytes", b.db.name, len(b.batch.Dump()))
-	b.db.written += uint64(len(b.batch.Dump()))
-
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
-	err := b.db.db.Write(b.batch, &opt.WriteOptions{Sync: sync})
+	atomic.AddUint64(&b.db.written, uint64(len(b.batch.Dump())))
+
+	err := b.db.db.Write(b.batch, &opt.WriteOptions{Sync: sync || b.db.wo.GetSync()})
 	if err != nil {
-		return err
+		return goLevelDBError(err)
 	}
 	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
 	return b.Close()
 }
 
-// Close implements Batch.
+// Reset implements BatchResetter.
+func (b *goLevelDBBatch) Reset() error {
+	if b.batch == nil {
+		b.batch = goLevelDBBatchPool.Get().(*leveldb.Batch)
+	} else {
+		b.batch.Reset()
+	}
+	b.size = 0
+	return nil
+}
+
+// Close implements Batch. The leveldb batch is returned to the pool, unless it
+// grew too large.
 func (b *goLevelDBBatch) Close() error {
 	if b.batch != nil {
-		b.batch.Reset()
+		if len(b.batch.Dump()) <= maxPooledBatchSize {
+			b.batch.Reset()
+			goLevelDBBatchPool.Put(b.batch)
+		}
 		b.batch = nil
+		b.size = 0
 	}
 	return nil
 }
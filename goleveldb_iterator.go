@@ -14,7 +14,7 @@ type goLevelDBIterator struct {
 	isInvalid bool
 }
 
-var _ Iterator = (*goLevelDBIterator)(nil)
+var _ UnsafeIterator = (*goLevelDBIterator)(nil)
 
 func newGoLevelDBIterator(source iterator.Iterator, start, end []byte, isReverse bool) *goLevelDBIterator {
 	if isReverse {
@@ -108,6 +108,18 @@ func (itr *goLevelDBIterator) Value() []byte {
 	return cp(itr.source.Value())
 }
 
+// KeyUnsafe implements UnsafeIterator.
+func (itr *goLevelDBIterator) KeyUnsafe() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()
+}
+
+// ValueUnsafe implements UnsafeIterator.
+func (itr *goLevelDBIterator) ValueUnsafe() []byte {
+	itr.assertIsValid()
+	return itr.source.Value()
+}
+
 // Next implements Iterator.
 func (itr *goLevelDBIterator) Next() {
 	itr.assertIsValid()
@@ -120,7 +132,7 @@ func (itr *goLevelDBIterator) Next() {
 
 // Error implements Iterator.
 func (itr *goLevelDBIterator) Error() error {
-	return itr.source.Error()
+	return goLevelDBError(itr.source.Error())
 }
 
 // Close implements Iterator.
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+type goLevelDBSnapshot struct {
+	snap  *leveldb.Snapshot
+	iters *iteratorTracker
+}
+
+var _ DBReader = (*goLevelDBSnapshot)(nil)
+
+// Snapshot implements Snapshotter.
+func (db *GoLevelDB) Snapshot() (DBReader, error) {
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return nil, goLevelDBError(err)
+	}
+	return &goLevelDBSnapshot{snap: snap, iters: db.iters}, nil
+}
+
+// Get implements DBReader.
+func (s *goLevelDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	res, err := s.snap.Get(key, nil)
+	if err != nil {
+		if err == errors.ErrNotFound {
+			return nil, nil
+		}
+		return nil, goLevelDBError(err)
+	}
+	return res, nil
+}
+
+// Has implements DBReader.
+func (s *goLevelDBSnapshot) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	return s.snap.Has(key, nil)
+}
+
+// Iterator implements DBReader.
+func (s *goLevelDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := s.snap.NewIterator(&util.Range{Start: start, Limit: end}, nil)
+	return s.iters.track(newGoLevelDBIterator(itr, start, end, false), nil)
+}
+
+// ReverseIterator implements DBReader.
+func (s *goLevelDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	itr := s.snap.NewIterator(&util.Range{Start: start, Limit: end}, nil)
+	return s.iters.track(newGoLevelDBIterator(itr, start, end, true), nil)
+}
+
+// Close implements DBReader.
+func (s *goLevelDBSnapshot) Close() error {
+	s.snap.Release()
+	return nil
+}
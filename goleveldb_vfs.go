@@ -0,0 +1,320 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+// goLevelDBVFSStorage is a goleveldb storage keeping its files in a directory
+// of a vfs.FS, named like those of the file storage of goleveldb. The messages
+// of goleveldb are discarded rather than written to a LOG file.
+type goLevelDBVFSStorage struct {
+	fs  vfs.FS
+	dir string
+
+	mtx    sync.Mutex
+	flock  io.Closer // lock of the directory, nil once closed
+	locked bool      // held by the DB
+}
+
+var _ storage.Storage = (*goLevelDBVFSStorage)(nil)
+
+// newGoLevelDBVFSStorage opens the storage in dir of fs, creating dir if
+// needed, and locks it.
+func newGoLevelDBVFSStorage(fs vfs.FS, dir string) (*goLevelDBVFSStorage, error) {
+	if err := fs.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	flock, err := fs.Lock(fs.PathJoin(dir, "LOCK"))
+	if err != nil {
+		return nil, err
+	}
+	return &goLevelDBVFSStorage{fs: fs, dir: dir, flock: flock}, nil
+}
+
+// goLevelDBFileName returns the name of the file of fd, as in the file storage.
+func goLevelDBFileName(fd storage.FileDesc) string {
+	switch fd.Type {
+	case storage.TypeManifest:
+		return fmt.Sprintf("MANIFEST-%06d", fd.Num)
+	case storage.TypeJournal:
+		return fmt.Sprintf("%06d.log", fd.Num)
+	case storage.TypeTable:
+		return fmt.Sprintf("%06d.ldb", fd.Num)
+	case storage.TypeTemp:
+		return fmt.Sprintf("%06d.tmp", fd.Num)
+	default:
+		panic("invalid file type")
+	}
+}
+
+// goLevelDBParseFileName returns the file descriptor of the file name, if it is
+// one of the files of a storage.
+func goLevelDBParseFileName(name string) (fd storage.FileDesc, ok bool) {
+	var tail string
+	if _, err := fmt.Sscanf(name, "%d.%s", &fd.Num, &tail); err == nil {
+		switch tail {
+		case "log":
+			fd.Type = storage.TypeJournal
+		case "ldb", "sst":
+			fd.Type = storage.TypeTable
+		case "tmp":
+			fd.Type = storage.TypeTemp
+		default:
+			return fd, false
+		}
+		return fd, true
+	}
+	if n, _ := fmt.Sscanf(name, "MANIFEST-%d%s", &fd.Num, &tail); n == 1 {
+		fd.Type = storage.TypeManifest
+		return fd, true
+	}
+	return fd, false
+}
+
+// notExist converts the errors of missing files of fs, which may be wrapped, to
+// errors that goleveldb recognizes with os.IsNotExist.
+func (s *goLevelDBVFSStorage) notExist(op, name string, err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+	return err
+}
+
+func (s *goLevelDBVFSStorage) path(name string) string {
+	return s.fs.PathJoin(s.dir, name)
+}
+
+// syncDir makes the entries of the directory durable.
+func (s *goLevelDBVFSStorage) syncDir() error {
+	dir, err := s.fs.OpenDir(s.dir)
+	if err != nil {
+		return err
+	}
+	err = dir.Sync()
+	if cerr := dir.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Lock implements storage.Storage.
+func (s *goLevelDBVFSStorage) Lock() (storage.Locker, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.flock == nil {
+		return nil, storage.ErrClosed
+	}
+	if s.locked {
+		return nil, storage.ErrLocked
+	}
+	s.locked = true
+	return goLevelDBVFSLock{s}, nil
+}
+
+// goLevelDBVFSLock releases the lock of a goLevelDBVFSStorage.
+type goLevelDBVFSLock struct {
+	s *goLevelDBVFSStorage
+}
+
+// Unlock implements storage.Locker.
+func (l goLevelDBVFSLock) Unlock() {
+	l.s.mtx.Lock()
+	l.s.locked = false
+	l.s.mtx.Unlock()
+}
+
+// Log implements storage.Storage.
+func (s *goLevelDBVFSStorage) Log(string) {}
+
+// SetMeta implements storage.Storage. CURRENT is replaced atomically, by
+// renaming a synced temporary file over it.
+func (s *goLevelDBVFSStorage) SetMeta(fd storage.FileDesc) error {
+	if !storage.FileDescOk(fd) {
+		return storage.ErrInvalidFile
+	}
+	tmp := fmt.Sprintf("CURRENT.%d", fd.Num)
+	file, err := s.fs.Create(s.path(tmp))
+	if err != nil {
+		return err
+	}
+	_, err = file.Write([]byte(goLevelDBFileName(fd) + "\n"))
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.fs.Rename(s.path(tmp), s.path("CURRENT")); err != nil {
+		return err
+	}
+	return s.syncDir()
+}
+
+// GetMeta implements storage.Storage.
+func (s *goLevelDBVFSStorage) GetMeta() (storage.FileDesc, error) {
+	file, err := s.fs.Open(s.path("CURRENT"))
+	if err != nil {
+		return storage.FileDesc{}, s.notExist("open", "CURRENT", err)
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return storage.FileDesc{}, err
+	}
+	name := strings.TrimSuffix(string(data), "\n")
+	fd, ok := goLevelDBParseFileName(name)
+	if !ok || fd.Type != storage.TypeManifest || !strings.HasSuffix(string(data), "\n") {
+		return storage.FileDesc{}, &storage.ErrCorrupted{
+			Err: errors.New("leveldb/storage: corrupted or incomplete CURRENT file"),
+		}
+	}
+	if _, err := s.fs.Stat(s.path(name)); err != nil {
+		return storage.FileDesc{}, s.notExist("stat", name, err)
+	}
+	return fd, nil
+}
+
+// List implements storage.Storage.
+func (s *goLevelDBVFSStorage) List(ft storage.FileType) ([]storage.FileDesc, error) {
+	names, err := s.fs.List(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var fds []storage.FileDesc
+	for _, name := range names {
+		if fd, ok := goLevelDBParseFileName(name); ok && fd.Type&ft != 0 {
+			fds = append(fds, fd)
+		}
+	}
+	return fds, nil
+}
+
+// Open implements storage.Storage.
+func (s *goLevelDBVFSStorage) Open(fd storage.FileDesc) (storage.Reader, error) {
+	if !storage.FileDescOk(fd) {
+		return nil, storage.ErrInvalidFile
+	}
+	name := goLevelDBFileName(fd)
+	file, err := s.fs.Open(s.path(name))
+	if err != nil {
+		return nil, s.notExist("open", name, err)
+	}
+	return &goLevelDBVFSReader{File: file}, nil
+}
+
+// Create implements storage.Storage.
+func (s *goLevelDBVFSStorage) Create(fd storage.FileDesc) (storage.Writer, error) {
+	if !storage.FileDescOk(fd) {
+		return nil, storage.ErrInvalidFile
+	}
+	file, err := s.fs.Create(s.path(goLevelDBFileName(fd)))
+	if err != nil {
+		return nil, err
+	}
+	return &goLevelDBVFSWriter{File: file, s: s}, nil
+}
+
+// Remove implements storage.Storage.
+func (s *goLevelDBVFSStorage) Remove(fd storage.FileDesc) error {
+	if !storage.FileDescOk(fd) {
+		return storage.ErrInvalidFile
+	}
+	name := goLevelDBFileName(fd)
+	return s.notExist("remove", name, s.fs.Remove(s.path(name)))
+}
+
+// Rename implements storage.Storage.
+func (s *goLevelDBVFSStorage) Rename(oldfd, newfd storage.FileDesc) error {
+	if !storage.FileDescOk(oldfd) || !storage.FileDescOk(newfd) {
+		return storage.ErrInvalidFile
+	}
+	if oldfd == newfd {
+		return nil
+	}
+	name := goLevelDBFileName(oldfd)
+	return s.notExist("rename", name, s.fs.Rename(s.path(name), s.path(goLevelDBFileName(newfd))))
+}
+
+// Close implements storage.Storage. It releases the lock of the directory.
+func (s *goLevelDBVFSStorage) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.flock == nil {
+		return nil
+	}
+	err := s.flock.Close()
+	s.flock = nil
+	return err
+}
+
+// goLevelDBVFSReader adds the Seek needed by goleveldb to a file of a vfs.FS.
+type goLevelDBVFSReader struct {
+	vfs.File
+	offset int64
+}
+
+// Read implements io.Reader, at the offset set by Seek.
+func (r *goLevelDBVFSReader) Read(p []byte) (int, error) {
+	n, err := r.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker.
+func (r *goLevelDBVFSReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		info, err := r.Stat()
+		if err != nil {
+			return 0, err
+		}
+		offset += info.Size()
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d", offset)
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+// goLevelDBVFSWriter is a file of a goLevelDBVFSStorage being written. The
+// directory is synced along with the first sync of the file, so that the file
+// survives a crash once synced.
+type goLevelDBVFSWriter struct {
+	vfs.File
+	s         *goLevelDBVFSStorage
+	dirSynced bool
+}
+
+// Sync implements storage.Syncer.
+func (w *goLevelDBVFSWriter) Sync() error {
+	if err := w.File.Sync(); err != nil {
+		return err
+	}
+	if !w.dirSynced {
+		if err := w.s.syncDir(); err != nil {
+			return err
+		}
+		w.dirSynced = true
+	}
+	return nil
+}
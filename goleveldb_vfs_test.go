@@ -0,0 +1,117 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDBFS(t *testing.T) {
+	for _, dbType := range []BackendType{GoLevelDBBackend, PebbleDBBackend} {
+		t.Run(string(dbType), func(t *testing.T) {
+			dir := t.TempDir()
+			fs := vfs.NewMem()
+			db, err := NewDB("db", dbType, dir, WithFS(fs))
+			require.NoError(t, err)
+			for i := int64(0); i < 1000; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+			require.NoError(t, Compact(db, nil, nil))
+			require.NoError(t, db.Close())
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+
+			// The database is reopened from the files in fs.
+			db, err = NewDB("db", dbType, dir, WithFS(fs))
+			require.NoError(t, err)
+			defer db.Close()
+			checkValue(t, db, int642Bytes(0), int642Bytes(0))
+			checkValue(t, db, int642Bytes(999), int642Bytes(999))
+		})
+	}
+}
+
+func TestNewDBFSPowerLoss(t *testing.T) {
+	for _, dbType := range []BackendType{GoLevelDBBackend, PebbleDBBackend} {
+		t.Run(string(dbType), func(t *testing.T) {
+			dir := t.TempDir()
+			fs := vfs.NewStrictMem()
+			// Create the directory of the database durably beforehand, which the
+			// backends leave to the caller.
+			require.NoError(t, fs.MkdirAll(filepath.Join(dir, "db.db"), 0o755))
+			for d := filepath.Join(dir, "db.db"); ; d = filepath.Dir(d) {
+				f, err := fs.OpenDir(filepath.Dir(d))
+				require.NoError(t, err)
+				require.NoError(t, f.Sync())
+				require.NoError(t, f.Close())
+				if d == filepath.Dir(d) {
+					break
+				}
+			}
+			db, err := NewDB("db", dbType, dir, WithFS(fs))
+			require.NoError(t, err)
+			require.NoError(t, db.SetSync(bz("a"), bz("1")))
+			require.NoError(t, db.Set(bz("b"), bz("2")))
+
+			// Lose the writes not synced, as in a power loss.
+			fs.SetIgnoreSyncs(true)
+			require.NoError(t, db.Close())
+			fs.ResetToSyncedState()
+			fs.SetIgnoreSyncs(false)
+
+			db, err = NewDB("db", dbType, dir, WithFS(fs))
+			require.NoError(t, err)
+			defer db.Close()
+			checkValue(t, db, bz("a"), bz("1"))
+			checkValue(t, db, bz("b"), nil)
+		})
+	}
+}
+
+var errDiskFull = errors.New("disk full")
+
+// fullFS is a file system whose writes fail once it is full.
+type fullFS struct {
+	vfs.FS
+	full atomic.Bool
+}
+
+func (fs *fullFS) Create(name string) (vfs.File, error) {
+	file, err := fs.FS.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return &fullFile{File: file, fs: fs}, nil
+}
+
+type fullFile struct {
+	vfs.File
+	fs *fullFS
+}
+
+func (f *fullFile) Write(p []byte) (int, error) {
+	if f.fs.full.Load() {
+		return 0, errDiskFull
+	}
+	return f.File.Write(p)
+}
+
+func TestGoLevelDBFSDiskFull(t *testing.T) {
+	fs := &fullFS{FS: vfs.NewMem()}
+	db, err := NewDB("db", GoLevelDBBackend, "", WithFS(fs))
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+
+	fs.full.Store(true)
+	assert.ErrorIs(t, db.SetSync(bz("b"), bz("2")), errDiskFull)
+	checkValue(t, db, bz("a"), bz("1"))
+}
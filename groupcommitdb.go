@@ -0,0 +1,327 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// defaultGroupCommitSize is the size beyond which a GroupCommitDB stops adding
+// writes to a group, if not given.
+const defaultGroupCommitSize = 4 << 20
+
+// WriteFuture is the completion of the writes queued in a GroupCommitDB, which
+// are committed together in a group.
+type WriteFuture struct {
+	ops  []operation
+	size int
+	done chan struct{}
+	err  error
+}
+
+// Done returns a channel closed once the writes have been committed, or have
+// failed.
+func (f *WriteFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits for the writes to be committed, and returns the error of the
+// commit if it failed.
+func (f *WriteFuture) Wait() error {
+	<-f.done
+	return f.err
+}
+
+// GroupCommitDB queues the writes to another database, and commits them in
+// groups, each written as a single batch synced to disk once. While a group is
+// being committed, the writes made meanwhile, from any goroutine, are queued
+// in the next one, so that many small synchronous writes, e.g. while fast
+// syncing blocks, share the cost of an fsync.
+//
+// Set, Delete and the batches block until their writes are committed and
+// durable, whether synced or not. SetAsync and DeleteAsync only queue their
+// writes, and return a WriteFuture to wait for; Flush waits for all the writes
+// queued so far. The writes are applied in the order they were queued, and
+// reads, which are served by the underlying database, only see them once
+// committed. Queuing blocks while the group being filled is full.
+type GroupCommitDB struct {
+	db        DB
+	groupSize int
+
+	mtx     sync.Mutex
+	cond    *sync.Cond   // signaled when the pending group is created or taken, or on close
+	pending *WriteFuture // group being filled, nil if none
+	last    *WriteFuture // last group created, nil if none
+	closed  bool
+	groups  uint64 // number of groups committed
+	writes  uint64 // number of writes committed
+
+	done chan struct{} // closed when the committer has stopped
+}
+
+var (
+	_ DB      = (*GroupCommitDB)(nil)
+	_ Flusher = (*GroupCommitDB)(nil)
+	_ Syncer  = (*GroupCommitDB)(nil)
+)
+
+// NewGroupCommitDB creates a GroupCommitDB over db, adding writes to a group
+// until it reaches groupSize bytes of keys and values. A groupSize of 0 uses a
+// default of 4 MiB. Closing the GroupCommitDB commits the queued writes and
+// closes db.
+func NewGroupCommitDB(db DB, groupSize int) *GroupCommitDB {
+	if groupSize <= 0 {
+		groupSize = defaultGroupCommitSize
+	}
+	gdb := &GroupCommitDB{
+		db:        db,
+		groupSize: groupSize,
+		done:      make(chan struct{}),
+	}
+	gdb.cond = sync.NewCond(&gdb.mtx)
+	go gdb.commit()
+	return gdb
+}
+
+// commit commits the queued groups in order, until the GroupCommitDB is closed
+// and the queue is empty.
+func (gdb *GroupCommitDB) commit() {
+	defer close(gdb.done)
+	gdb.mtx.Lock()
+	defer gdb.mtx.Unlock()
+	for {
+		for gdb.pending == nil && !gdb.closed {
+			gdb.cond.Wait()
+		}
+		group := gdb.pending
+		if group == nil {
+			return
+		}
+		gdb.pending = nil
+		gdb.cond.Broadcast()
+		gdb.mtx.Unlock()
+
+		group.err = walApply(gdb.db, group.ops, true)
+		writes := len(group.ops)
+		group.ops = nil
+
+		gdb.mtx.Lock()
+		gdb.groups++
+		gdb.writes += uint64(writes)
+		close(group.done)
+	}
+}
+
+// queue adds ops to the pending group, and returns it.
+func (gdb *GroupCommitDB) queue(ops []operation) (*WriteFuture, error) {
+	size := opsSize(ops)
+	gdb.mtx.Lock()
+	defer gdb.mtx.Unlock()
+	for !gdb.closed && gdb.pending != nil && gdb.pending.size >= gdb.groupSize {
+		gdb.cond.Wait()
+	}
+	if gdb.closed {
+		return nil, ErrClosed
+	}
+	if gdb.pending == nil {
+		gdb.pending = &WriteFuture{done: make(chan struct{})}
+		gdb.last = gdb.pending
+		gdb.cond.Broadcast()
+	}
+	gdb.pending.ops = append(gdb.pending.ops, ops...)
+	gdb.pending.size += size
+	return gdb.pending, nil
+}
+
+// write queues ops and waits for them to be committed.
+func (gdb *GroupCommitDB) write(ops []operation) error {
+	future, err := gdb.queue(ops)
+	if err != nil {
+		return err
+	}
+	return future.Wait()
+}
+
+// SetAsync queues the set of key to value, and returns the completion of its
+// group without waiting for it. key and value are copied.
+func (gdb *GroupCommitDB) SetAsync(key, value []byte) (*WriteFuture, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	if value == nil {
+		return nil, errValueNil
+	}
+	return gdb.queue([]operation{{opTypeSet, cp(key), cp(value)}})
+}
+
+// DeleteAsync queues the deletion of key, and returns the completion of its
+// group without waiting for it. key is copied.
+func (gdb *GroupCommitDB) DeleteAsync(key []byte) (*WriteFuture, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	return gdb.queue([]operation{{opTypeDelete, cp(key), nil}})
+}
+
+// Flush implements Flusher. It waits for the writes queued so far to be
+// committed, and returns the error of the last group if it failed. The
+// underlying database is not flushed.
+func (gdb *GroupCommitDB) Flush() error {
+	gdb.mtx.Lock()
+	last := gdb.last
+	gdb.mtx.Unlock()
+	if last == nil {
+		return nil
+	}
+	return last.Wait()
+}
+
+// Sync implements Syncer. Since the groups are synced when committed, it is
+// the same as Flush.
+func (gdb *GroupCommitDB) Sync() error {
+	return gdb.Flush()
+}
+
+// Get implements DB.
+func (gdb *GroupCommitDB) Get(key []byte) ([]byte, error) {
+	return gdb.db.Get(key)
+}
+
+// Has implements DB.
+func (gdb *GroupCommitDB) Has(key []byte) (bool, error) {
+	return gdb.db.Has(key)
+}
+
+// Set implements DB.
+func (gdb *GroupCommitDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return gdb.write([]operation{{opTypeSet, key, value}})
+}
+
+// SetSync implements DB.
+func (gdb *GroupCommitDB) SetSync(key []byte, value []byte) error {
+	return gdb.Set(key, value)
+}
+
+// Delete implements DB.
+func (gdb *GroupCommitDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return gdb.write([]operation{{opTypeDelete, key, nil}})
+}
+
+// DeleteSync implements DB.
+func (gdb *GroupCommitDB) DeleteSync(key []byte) error {
+	return gdb.Delete(key)
+}
+
+// Iterator implements DB.
+func (gdb *GroupCommitDB) Iterator(start, end []byte) (Iterator, error) {
+	return gdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (gdb *GroupCommitDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return gdb.db.ReverseIterator(start, end)
+}
+
+// Close implements DB. It commits the queued writes, and closes the database.
+func (gdb *GroupCommitDB) Close() error {
+	gdb.mtx.Lock()
+	gdb.closed = true
+	gdb.cond.Broadcast()
+	gdb.mtx.Unlock()
+	<-gdb.done
+	err := gdb.Flush()
+	if cerr := gdb.db.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// NewBatch implements DB. The batch is queued as a whole when it is written,
+// and committed atomically in a group.
+func (gdb *GroupCommitDB) NewBatch() Batch {
+	return newGroupCommitDBBatch(gdb)
+}
+
+// Print implements DB.
+func (gdb *GroupCommitDB) Print() error {
+	return gdb.db.Print()
+}
+
+// Stats implements DB. The stats of the underlying database are prefixed with
+// "group_commit.db.".
+func (gdb *GroupCommitDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range gdb.db.Stats() {
+		stats["group_commit.db."+k] = v
+	}
+	gdb.mtx.Lock()
+	defer gdb.mtx.Unlock()
+	stats["group_commit.groups"] = strconv.FormatUint(gdb.groups, 10)
+	stats["group_commit.writes"] = strconv.FormatUint(gdb.writes, 10)
+	return stats
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it. The
+// snapshot does not include the writes not yet committed.
+func (gdb *GroupCommitDB) Snapshot() (DBReader, error) {
+	return Snapshot(gdb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (gdb *GroupCommitDB) Metrics() (Metrics, error) {
+	return GetMetrics(gdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (gdb *GroupCommitDB) EstimateCount() (uint64, error) {
+	return EstimateCount(gdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (gdb *GroupCommitDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(gdb.db, start, end)
+}
+
+// Compact implements Compacter, if the underlying database supports it.
+func (gdb *GroupCommitDB) Compact(start, end []byte) error {
+	return Compact(gdb.db, start, end)
+}
+
+// Backup implements Backupper, if the underlying database supports it, after
+// committing the queued writes.
+func (gdb *GroupCommitDB) Backup(ctx context.Context, targetDir string) error {
+	if err := gdb.Flush(); err != nil {
+		return err
+	}
+	return Backup(ctx, gdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it,
+// after committing the queued writes.
+func (gdb *GroupCommitDB) Checkpoint(path string) error {
+	if err := gdb.Flush(); err != nil {
+		return err
+	}
+	return Checkpoint(gdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (gdb *GroupCommitDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, gdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which GroupCommitDB passes through, and flushing and
+// syncing of the queue.
+func (gdb *GroupCommitDB) Capabilities() Capability {
+	return Capabilities(gdb.db)&(CapSnapshot|CapCompact|CapBackup|CapCheckpoint) | CapFlush | CapSync
+}
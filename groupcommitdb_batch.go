@@ -0,0 +1,80 @@
+package db
+
+// groupCommitDBBatch buffers the writes of a batch of a GroupCommitDB, which
+// are queued together when the batch is written.
+type groupCommitDBBatch struct {
+	db  *GroupCommitDB
+	ops []operation
+}
+
+var _ Batch = (*groupCommitDBBatch)(nil)
+
+func newGroupCommitDBBatch(db *GroupCommitDB) *groupCommitDBBatch {
+	return &groupCommitDBBatch{
+		db:  db,
+		ops: []operation{},
+	}
+}
+
+// Set implements Batch.
+func (b *groupCommitDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *groupCommitDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	return nil
+}
+
+// Count implements Batch.
+func (b *groupCommitDBBatch) Count() int {
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *groupCommitDBBatch) SizeBytes() int {
+	return opsSize(b.ops)
+}
+
+// Write implements Batch.
+func (b *groupCommitDBBatch) Write() error {
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	if len(b.ops) > 0 {
+		if err := b.db.write(b.ops); err != nil {
+			return err
+		}
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// WriteSync implements Batch. Groups are always synced, so it is the same as
+// Write.
+func (b *groupCommitDBBatch) WriteSync() error {
+	return b.Write()
+}
+
+// Close implements Batch.
+func (b *groupCommitDBBatch) Close() error {
+	b.ops = nil
+	return nil
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupCommitDB(t *testing.T) {
+	gdb := NewGroupCommitDB(NewMemDB(), 0)
+	require.NoError(t, gdb.Set(bz("a"), bz("1")))
+	require.NoError(t, gdb.SetSync(bz("b"), bz("2")))
+	require.NoError(t, gdb.Delete(bz("a")))
+	checkValue(t, gdb, bz("a"), nil)
+	checkValue(t, gdb, bz("b"), bz("2"))
+
+	batch := gdb.NewBatch()
+	require.NoError(t, batch.Set(bz("c"), bz("3")))
+	require.NoError(t, batch.Delete(bz("b")))
+	require.NoError(t, batch.WriteSync())
+	require.NoError(t, batch.Close())
+	checkValue(t, gdb, bz("b"), nil)
+	checkValue(t, gdb, bz("c"), bz("3"))
+
+	// Async writes copy their keys and values, and are committed in order.
+	key, value := bz("d"), bz("4")
+	future, err := gdb.SetAsync(key, value)
+	require.NoError(t, err)
+	key[0], value[0] = 'x', 'x'
+	_, err = gdb.DeleteAsync(bz("c"))
+	require.NoError(t, err)
+	require.NoError(t, gdb.Flush())
+	<-future.Done()
+	require.NoError(t, future.Wait())
+	checkValue(t, gdb, bz("c"), nil)
+	checkValue(t, gdb, bz("d"), bz("4"))
+
+	require.NoError(t, gdb.Close())
+	assert.ErrorIs(t, gdb.Set(bz("a"), bz("1")), ErrClosed)
+}
+
+func TestGroupCommitDBConcurrent(t *testing.T) {
+	const writers, writes = 8, 100
+	gdb := NewGroupCommitDB(NewMemDB(), 64)
+	defer gdb.Close()
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				key := bz(strconv.Itoa(w) + "/" + strconv.Itoa(i))
+				if i%2 == 0 {
+					assert.NoError(t, gdb.SetSync(key, key))
+				} else {
+					_, err := gdb.SetAsync(key, key)
+					assert.NoError(t, err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	require.NoError(t, gdb.Flush())
+
+	count := 0
+	itr, err := gdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	for ; itr.Valid(); itr.Next() {
+		assert.Equal(t, itr.Key(), itr.Value())
+		count++
+	}
+	require.NoError(t, itr.Close())
+	assert.Equal(t, writers*writes, count)
+
+	stats := gdb.Stats()
+	assert.Equal(t, strconv.Itoa(writers*writes), stats["group_commit.writes"])
+	groups, err := strconv.Atoi(stats["group_commit.groups"])
+	require.NoError(t, err)
+	assert.Less(t, groups, writers*writes)
+}
+
+func TestGroupCommitDBCloseCommitsQueue(t *testing.T) {
+	memdb := NewMemDB()
+	gdb := NewGroupCommitDB(memdb, 0)
+	for i := int64(0); i < 100; i++ {
+		_, err := gdb.SetAsync(int642Bytes(i), bz("v"))
+		require.NoError(t, err)
+	}
+	require.NoError(t, gdb.Close())
+	for i := int64(0); i < 100; i++ {
+		checkValue(t, memdb, int642Bytes(i), bz("v"))
+	}
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// healthCheckKey is the key read and deleted by HealthCheck. Deleting a key
+// which does not exist is a real write for most backends, while leaving the
+// contents of the database unchanged.
+var healthCheckKey = []byte("\x00cometbft-db/health-check")
+
+// HealthChecker is implemented by databases which can detect problems that a
+// read and a write do not surface yet, e.g. writes stalled by a compaction
+// backlog or corruption found by a background operation.
+type HealthChecker interface {
+	// HealthCheck returns ErrWriteStalled while writes are stalled, an error
+	// wrapping ErrCorrupted once corruption has been detected, and nil if no
+	// problem is known.
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthCheck checks that db is usable, e.g. for liveness probes. It reads and
+// deletes a reserved key, giving up with the context error once ctx is done, so
+// that a closed, failed or blocked database is detected. Databases opened
+// read-only only get the read. If db implements HealthChecker, its own checks
+// are used in addition.
+func HealthCheck(ctx context.Context, db DB) error {
+	if _, err := HasContext(ctx, db, healthCheckKey); err != nil {
+		return err
+	}
+	if err := DeleteContext(ctx, db, healthCheckKey); err != nil && !errors.Is(err, ErrReadOnly) {
+		return err
+	}
+	if hc, ok := db.(HealthChecker); ok {
+		return hc.HealthCheck(ctx)
+	}
+	return nil
+}
+
+// healthCheckAll checks the health of all dbs, returning the first error.
+func healthCheckAll(ctx context.Context, dbs []DB) error {
+	for _, db := range dbs {
+		if err := HealthCheck(ctx, db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
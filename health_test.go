@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheck(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			require.NoError(t, HealthCheck(context.Background(), db))
+
+			// The check leaves no key behind.
+			itr, err := db.Iterator(nil, nil)
+			require.NoError(t, err)
+			defer itr.Close()
+			assert.False(t, itr.Valid())
+		})
+	}
+}
+
+func TestHealthCheckErrors(t *testing.T) {
+	ctx := context.Background()
+	for _, backend := range []BackendType{GoLevelDBBackend, PebbleDBBackend} {
+		t.Run(string(backend), func(t *testing.T) {
+			dir := t.TempDir()
+			db, err := NewDB("db", backend, dir)
+			require.NoError(t, err)
+			require.NoError(t, db.Close())
+
+			// A read-only database is healthy.
+			db, err = NewDB("db", backend, dir, WithReadOnly())
+			require.NoError(t, err)
+			defer db.Close()
+			assert.NoError(t, HealthCheck(ctx, db))
+		})
+	}
+
+	// pebble panics when used after Close.
+	db, err := NewGoLevelDB("db", t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+	assert.ErrorIs(t, HealthCheck(ctx, db), ErrClosed)
+
+	fdb := NewFaultDB(NewMemDB())
+	fdb.Inject(Fault{Ops: FaultDelete, Err: ErrFaultDiskFull})
+	assert.ErrorIs(t, HealthCheck(ctx, fdb), ErrFaultDiskFull)
+	assert.ErrorIs(t, HealthCheck(ctx, mustShardedDB(t, NewMemDB(), fdb)), ErrFaultDiskFull)
+	fdb.Clear()
+	fdb.Inject(Fault{Ops: FaultHas, Err: ErrFaultCorruption})
+	assert.ErrorIs(t, HealthCheck(ctx, NewPrefixDB(fdb, bz("p"))), ErrCorrupted)
+}
+
+func TestHealthCheckPebbleStalled(t *testing.T) {
+	pdb, err := NewPebbleDB("db", t.TempDir())
+	require.NoError(t, err)
+	defer pdb.Close()
+
+	listener := pdb.health.listener()
+	listener.WriteStallBegin(pebble.WriteStallBeginInfo{Reason: "test"})
+	assert.ErrorIs(t, HealthCheck(context.Background(), pdb), ErrWriteStalled)
+	listener.WriteStallEnd()
+	assert.NoError(t, HealthCheck(context.Background(), pdb))
+}
+
+func TestHealthCheckContext(t *testing.T) {
+	db := &blockingDB{DB: NewMemDB(), unblock: make(chan struct{})}
+	defer close(db.unblock)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, HealthCheck(ctx, db), context.DeadlineExceeded)
+}
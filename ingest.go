@@ -0,0 +1,66 @@
+package db
+
+import "errors"
+
+// errSSTWriterDone is returned when an SSTWriter is used after it has been
+// finished or closed.
+var errSSTWriterDone = errors.New("SST writer has been finished or closed")
+
+// SSTWriter writes an SST file which can be ingested by the database which
+// created it, see Ingester. The keys must be written in strictly increasing
+// order.
+type SSTWriter interface {
+	// Set writes a key with its value.
+	// CONTRACT: key, value readonly []byte
+	Set(key, value []byte) error
+
+	// Delete writes a deletion of a key, which deletes it from the database
+	// when the file is ingested.
+	// CONTRACT: key readonly []byte
+	Delete(key []byte) error
+
+	// Finish completes and syncs the file, which can then be ingested. The
+	// writer cannot be used afterwards.
+	Finish() error
+
+	// Close releases the writer. If it was not finished, the incomplete file
+	// is left as is and must not be ingested. Close can be called multiple
+	// times.
+	Close() error
+}
+
+// Ingester is implemented by databases which can ingest SST files written
+// outside of them, bypassing their memtable and write-ahead log, so that bulk
+// loads such as genesis imports or snapshot restores are much faster than with
+// batches.
+type Ingester interface {
+	// NewSSTWriter creates an SST file at path, in the format of the database.
+	NewSSTWriter(path string) (SSTWriter, error)
+
+	// IngestExternalFiles atomically adds the keys of the finished SST files
+	// at paths to the database, overwriting the existing ones. The key ranges
+	// of the files must not overlap. On success, the files are moved into the
+	// database, so they should be on the same filesystem.
+	IngestExternalFiles(paths []string) error
+}
+
+// NewSSTWriter creates a writer of an SST file at path for ingestion by db, see
+// Ingester. It returns ErrNotSupported if db does not implement Ingester.
+func NewSSTWriter(db DB, path string) (SSTWriter, error) {
+	i, ok := db.(Ingester)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return i.NewSSTWriter(path)
+}
+
+// IngestExternalFiles ingests the SST files at paths into db, see Ingester. It
+// returns ErrNotSupported if db does not implement Ingester, in which case the
+// keys should be written with batches instead.
+func IngestExternalFiles(db DB, paths []string) error {
+	i, ok := db.(Ingester)
+	if !ok {
+		return ErrNotSupported
+	}
+	return i.IngestExternalFiles(paths)
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIngestExternalFiles(t *testing.T) {
+	db, err := NewPebbleDB("db", t.TempDir())
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.Set(bz("a/0"), bz("old")))
+	require.NoError(t, db.Set(bz("b/0"), bz("old")))
+
+	// Two files of disjoint ranges, with an overwrite and a deletion.
+	dir := t.TempDir()
+	var paths []string
+	for _, prefix := range []string{"a", "b"} {
+		path := filepath.Join(dir, prefix+".sst")
+		w, err := NewSSTWriter(db, path)
+		require.NoError(t, err)
+		if prefix == "b" {
+			require.NoError(t, w.Delete(bz("b/0")))
+		}
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("%s/%03d", prefix, i)
+			if key == "b/000" {
+				continue
+			}
+			require.NoError(t, w.Set(bz(key), bz(key)))
+		}
+		require.NoError(t, w.Finish())
+		require.NoError(t, w.Close())
+		paths = append(paths, path)
+	}
+	require.NoError(t, IngestExternalFiles(db, paths))
+	require.NoError(t, IngestExternalFiles(db, nil))
+
+	checkValue(t, db, bz("a/0"), bz("old"))
+	checkValue(t, db, bz("a/000"), bz("a/000"))
+	checkValue(t, db, bz("b/099"), bz("b/099"))
+	checkValue(t, db, bz("b/0"), nil)
+	count, err := Count(db)
+	require.NoError(t, err)
+	assert.EqualValues(t, 200, count)
+
+	// The files are moved into the database.
+	for _, path := range paths {
+		assert.NoFileExists(t, path)
+	}
+}
+
+func TestSSTWriterErrors(t *testing.T) {
+	db, err := NewPebbleDB("db", t.TempDir())
+	require.NoError(t, err)
+	defer db.Close()
+	path := filepath.Join(t.TempDir(), "file.sst")
+	w, err := NewSSTWriter(db, path)
+	require.NoError(t, err)
+	defer w.Close()
+
+	assert.Equal(t, errKeyEmpty, w.Set(nil, bz("1")))
+	assert.Equal(t, errValueNil, w.Set(bz("a"), nil))
+	assert.Equal(t, errKeyEmpty, w.Delete([]byte{}))
+	require.NoError(t, w.Set(bz("b"), bz("1")))
+	assert.Error(t, w.Set(bz("a"), bz("1")))
+
+	// An unfinished file cannot be ingested.
+	require.NoError(t, w.Close())
+	require.NoError(t, w.Close())
+	assert.Equal(t, errSSTWriterDone, w.Set(bz("c"), bz("1")))
+	assert.Equal(t, errSSTWriterDone, w.Finish())
+	assert.Error(t, IngestExternalFiles(db, []string{path}))
+
+	_, err = NewSSTWriter(NewMemDB(), path)
+	assert.ErrorIs(t, err, ErrNotSupported)
+	assert.ErrorIs(t, IngestExternalFiles(NewMemDB(), []string{path}), ErrNotSupported)
+}
@@ -0,0 +1,93 @@
+package db
+
+// IteratorOptions are optional settings for iterators created with
+// IteratorWithOptions. The zero value is a plain ascending iterator.
+type IteratorOptions struct {
+	// Reverse iterates in descending order, like DB.ReverseIterator.
+	Reverse bool
+
+	// KeysOnly skips loading values, for scans which only need the keys.
+	// Value returns nil on such iterators.
+	KeysOnly bool
+
+	// Prefetch, if positive, makes the iterator read up to Prefetch entries
+	// ahead of the caller in a background goroutine, so that the reads from
+	// disk overlap with the processing of the entries, e.g. in full scans for
+	// state export. The keys and values are copied, and the iterator must be
+	// closed to stop the goroutine.
+	Prefetch int
+
+	// ReadOptions apply to the reads made by the iterator.
+	ReadOptions
+}
+
+// IteratorOptionsDB is implemented by databases which can apply some of the
+// IteratorOptions natively, e.g. by not reading values from disk for KeysOnly.
+type IteratorOptionsDB interface {
+	// IteratorWithOptions returns an iterator over the domain [start, end),
+	// with the same semantics as DB.Iterator, configured by opts.
+	IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error)
+}
+
+// IteratorWithOptions returns an iterator over the domain [start, end) of db,
+// configured by opts. Options that db cannot apply natively are emulated, e.g.
+// KeysOnly still reads the values but hides them, except for the ReadOptions
+// which are ignored.
+func IteratorWithOptions(db DB, start, end []byte, opts IteratorOptions) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	// Prefetching is applied on top of the iterator, rather than passed down.
+	prefetch := opts.Prefetch
+	opts.Prefetch = 0
+	var (
+		itr Iterator
+		err error
+	)
+	if odb, ok := db.(IteratorOptionsDB); ok {
+		itr, err = odb.IteratorWithOptions(start, end, opts)
+	} else {
+		itr, err = iteratorWithOptions(db, start, end, opts)
+	}
+	if err != nil || prefetch <= 0 {
+		return itr, err
+	}
+	return newPrefetchIterator(itr, prefetch, opts.KeysOnly), nil
+}
+
+// iteratorWithOptions returns an iterator of db emulating opts.
+func iteratorWithOptions(db DB, start, end []byte, opts IteratorOptions) (Iterator, error) {
+	var (
+		itr Iterator
+		err error
+	)
+	if opts.Reverse {
+		itr, err = db.ReverseIterator(start, end)
+	} else {
+		itr, err = db.Iterator(start, end)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if opts.KeysOnly {
+		itr = newKeysOnlyIterator(itr)
+	}
+	return itr, nil
+}
+
+// keysOnlyIterator hides the values of the source iterator.
+type keysOnlyIterator struct {
+	Iterator
+}
+
+func newKeysOnlyIterator(source Iterator) *keysOnlyIterator {
+	return &keysOnlyIterator{Iterator: source}
+}
+
+// Value implements Iterator.
+func (itr *keysOnlyIterator) Value() []byte {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+	return nil
+}
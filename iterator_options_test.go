@@ -0,0 +1,136 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIteratorWithOptions(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			for i := int64(0); i < 10; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+
+			itr, err := IteratorWithOptions(db, int642Bytes(2), int642Bytes(5), IteratorOptions{})
+			require.NoError(t, err)
+			for i := int64(2); i < 5; i++ {
+				checkValid(t, itr, true)
+				checkItem(t, itr, int642Bytes(i), int642Bytes(i))
+				itr.Next()
+			}
+			checkValid(t, itr, false)
+			require.NoError(t, itr.Close())
+
+			itr, err = IteratorWithOptions(db, nil, int642Bytes(5), IteratorOptions{KeysOnly: true})
+			require.NoError(t, err)
+			for i := int64(0); i < 5; i++ {
+				checkValid(t, itr, true)
+				assert.Equal(t, int642Bytes(i), itr.Key())
+				assert.Nil(t, itr.Value())
+				itr.Next()
+			}
+			checkValid(t, itr, false)
+			checkValuePanics(t, itr)
+			require.NoError(t, itr.Error())
+			require.NoError(t, itr.Close())
+
+			itr, err = IteratorWithOptions(db, int642Bytes(7), nil, IteratorOptions{Reverse: true, KeysOnly: true})
+			require.NoError(t, err)
+			for i := int64(9); i >= 7; i-- {
+				checkValid(t, itr, true)
+				assert.Equal(t, int642Bytes(i), itr.Key())
+				assert.Nil(t, itr.Value())
+				itr.Next()
+			}
+			checkValid(t, itr, false)
+			require.NoError(t, itr.Close())
+
+			_, err = IteratorWithOptions(db, []byte{}, nil, IteratorOptions{})
+			assert.Equal(t, errKeyEmpty, err)
+		})
+	}
+}
+
+func TestIteratorWithOptionsPrefetch(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			for i := int64(0); i < 100; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+
+			itr, err := IteratorWithOptions(db, int642Bytes(10), nil, IteratorOptions{Prefetch: 8})
+			require.NoError(t, err)
+			start, end := itr.Domain()
+			assert.Equal(t, int642Bytes(10), start)
+			assert.Nil(t, end)
+			for i := int64(10); i < 100; i++ {
+				checkValid(t, itr, true)
+				checkItem(t, itr, int642Bytes(i), int642Bytes(i))
+				itr.Next()
+			}
+			checkValid(t, itr, false)
+			checkNextPanics(t, itr)
+			require.NoError(t, itr.Error())
+			require.NoError(t, itr.Close())
+
+			itr, err = IteratorWithOptions(db, nil, nil, IteratorOptions{Reverse: true, KeysOnly: true, Prefetch: 1})
+			require.NoError(t, err)
+			for i := int64(99); i >= 0; i-- {
+				checkValid(t, itr, true)
+				assert.Equal(t, int642Bytes(i), itr.Key())
+				assert.Nil(t, itr.Value())
+				itr.Next()
+			}
+			checkValid(t, itr, false)
+			require.NoError(t, itr.Close())
+
+			// Closing stops the prefetching before the end.
+			itr, err = IteratorWithOptions(db, nil, nil, IteratorOptions{Prefetch: 4})
+			require.NoError(t, err)
+			checkItem(t, itr, int642Bytes(0), int642Bytes(0))
+			require.NoError(t, itr.Close())
+			checkValid(t, itr, false)
+			require.NoError(t, itr.Close())
+		})
+	}
+}
+
+func TestIteratorWithOptionsPrefetchError(t *testing.T) {
+	db := NewMemDB()
+	for _, key := range []string{"a1", "a2", "b1", "c1"} {
+		require.NoError(t, db.Set(bz(key), bz(key)))
+	}
+	fdb := NewFaultDB(db)
+	fdb.Inject(Fault{Ops: FaultIterate, KeyPrefix: bz("b"), Err: ErrFaultCorruption})
+
+	itr, err := IteratorWithOptions(fdb, nil, nil, IteratorOptions{Prefetch: 8})
+	require.NoError(t, err)
+	defer itr.Close()
+	for _, key := range []string{"a1", "a2"} {
+		checkValid(t, itr, true)
+		require.NoError(t, itr.Error())
+		checkItem(t, itr, bz(key), bz(key))
+		itr.Next()
+	}
+	checkValid(t, itr, false)
+	assert.ErrorIs(t, itr.Error(), ErrFaultCorruption)
+}
@@ -0,0 +1,129 @@
+package db
+
+// prefetchEntry is an entry read ahead by a prefetchIterator.
+type prefetchEntry struct {
+	key   []byte
+	value []byte
+}
+
+// prefetchIterator reads the entries of the source iterator in a background
+// goroutine, into a buffered channel of the prefetched entries. The source is
+// only used by the goroutine until it stops.
+type prefetchIterator struct {
+	source     Iterator
+	start, end []byte
+	keysOnly   bool
+
+	entries chan prefetchEntry // closed once the source is exhausted
+	quit    chan struct{}      // closed by Close
+	done    chan struct{}      // closed when the goroutine has stopped
+	err     error              // error of the source, set before entries is closed
+
+	current   prefetchEntry
+	valid     bool
+	exhausted bool
+	closed    bool
+}
+
+var _ Iterator = (*prefetchIterator)(nil)
+
+// newPrefetchIterator returns an iterator over the entries of source, reading
+// up to n of them ahead. With keysOnly, the values are not copied.
+func newPrefetchIterator(source Iterator, n int, keysOnly bool) *prefetchIterator {
+	start, end := source.Domain()
+	itr := &prefetchIterator{
+		source:   source,
+		start:    start,
+		end:      end,
+		keysOnly: keysOnly,
+		entries:  make(chan prefetchEntry, n),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go itr.prefetch()
+	itr.next()
+	return itr
+}
+
+// prefetch reads the entries of the source until it is exhausted or the
+// iterator is closed.
+func (itr *prefetchIterator) prefetch() {
+	defer close(itr.done)
+	for ; itr.source.Valid(); itr.source.Next() {
+		entry := prefetchEntry{key: cp(itr.source.Key())}
+		if !itr.keysOnly {
+			entry.value = cp(itr.source.Value())
+		}
+		select {
+		case itr.entries <- entry:
+		case <-itr.quit:
+			return
+		}
+	}
+	itr.err = itr.source.Error()
+	close(itr.entries)
+}
+
+// next moves to the next prefetched entry, waiting for it if needed.
+func (itr *prefetchIterator) next() {
+	entry, ok := <-itr.entries
+	if !ok {
+		itr.current, itr.valid, itr.exhausted = prefetchEntry{}, false, true
+		return
+	}
+	itr.current, itr.valid = entry, true
+}
+
+// Domain implements Iterator.
+func (itr *prefetchIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+// Valid implements Iterator.
+func (itr *prefetchIterator) Valid() bool {
+	return itr.valid && !itr.closed
+}
+
+// Next implements Iterator.
+func (itr *prefetchIterator) Next() {
+	itr.assertIsValid()
+	itr.next()
+}
+
+// Key implements Iterator.
+func (itr *prefetchIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.current.key
+}
+
+// Value implements Iterator.
+func (itr *prefetchIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.current.value
+}
+
+// Error implements Iterator. The error of the source is only reported once all
+// the entries read before it have been consumed.
+func (itr *prefetchIterator) Error() error {
+	if !itr.exhausted {
+		return nil
+	}
+	return itr.err
+}
+
+// Close implements Iterator. It stops the goroutine and closes the source.
+func (itr *prefetchIterator) Close() error {
+	if itr.closed {
+		return nil
+	}
+	itr.closed = true
+	close(itr.quit)
+	<-itr.done
+	return itr.source.Close()
+}
+
+func (itr *prefetchIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// iteratorTracker tracks the open iterators of a database to report leaked
+// ones, see Options.IteratorLeakThreshold. A nil tracker tracks nothing, so
+// that the backends can use it unconditionally.
+type iteratorTracker struct {
+	name      string
+	logger    Logger
+	threshold time.Duration
+
+	mtx   sync.Mutex
+	iters map[*trackedIterator]struct{}
+
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// newIteratorTracker returns the tracker for the options of database name, or
+// nil if leak detection is disabled.
+func newIteratorTracker(name string, opts *Options) *iteratorTracker {
+	if opts.IteratorLeakThreshold <= 0 {
+		return nil
+	}
+	t := &iteratorTracker{
+		name:      name,
+		logger:    opts.Logger,
+		threshold: opts.IteratorLeakThreshold,
+		iters:     make(map[*trackedIterator]struct{}),
+		quit:      make(chan struct{}),
+	}
+	t.wg.Add(1)
+	go t.run()
+	return t
+}
+
+// track returns itr, tracked until it is closed. It passes errors through, so
+// that it can wrap the results of the iterator constructors.
+func (t *iteratorTracker) track(itr Iterator, err error) (Iterator, error) {
+	if t == nil || err != nil {
+		return itr, err
+	}
+	ti := &trackedIterator{
+		Iterator: itr,
+		tracker:  t,
+		created:  time.Now(),
+		stack:    debug.Stack(),
+	}
+	t.mtx.Lock()
+	t.iters[ti] = struct{}{}
+	t.mtx.Unlock()
+	return ti, nil
+}
+
+// run reports the iterators open for longer than the threshold, each once,
+// until the tracker is closed.
+func (t *iteratorTracker) run() {
+	defer t.wg.Done()
+	ticker := time.NewTicker(t.threshold)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.quit:
+			return
+		case now := <-ticker.C:
+			t.mtx.Lock()
+			for ti := range t.iters {
+				if !ti.reported && now.Sub(ti.created) >= t.threshold {
+					ti.reported = true
+					t.report("Iterator open for too long", ti, now)
+				}
+			}
+			t.mtx.Unlock()
+		}
+	}
+}
+
+// close stops the tracker, reporting the iterators which are still open. It is
+// called by Close before the database is closed.
+func (t *iteratorTracker) close() {
+	if t == nil {
+		return
+	}
+	t.closeOnce.Do(func() {
+		close(t.quit)
+	})
+	t.wg.Wait()
+
+	now := time.Now()
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	for ti := range t.iters {
+		t.report("Iterator open at Close", ti, now)
+	}
+}
+
+func (t *iteratorTracker) report(msg string, ti *trackedIterator, now time.Time) {
+	t.logger.Error(msg, "name", t.name, "age", now.Sub(ti.created), "stack", string(ti.stack))
+}
+
+// trackedIterator is an iterator tracked by an iteratorTracker.
+type trackedIterator struct {
+	Iterator
+	tracker  *iteratorTracker
+	created  time.Time
+	stack    []byte
+	reported bool // guarded by tracker.mtx
+}
+
+var _ Iterator = (*trackedIterator)(nil)
+
+// Close implements Iterator.
+func (ti *trackedIterator) Close() error {
+	ti.tracker.mtx.Lock()
+	delete(ti.tracker.iters, ti)
+	ti.tracker.mtx.Unlock()
+	return ti.Iterator.Close()
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIteratorLeakDetection(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			logger := &testLogger{}
+			db, err := NewDB("db", dbType, t.TempDir(),
+				WithLogger(logger), WithIteratorLeakDetection(10*time.Millisecond))
+			require.NoError(t, err)
+			require.NoError(t, db.Set(bz("a"), bz("1")))
+
+			// Closed iterators are not reported.
+			itr, err := db.Iterator(nil, nil)
+			require.NoError(t, err)
+			if _, ok := itr.(*trackedIterator); !ok {
+				// The test backends wrapping other databases ignore the options.
+				itr.Close()
+				db.Close()
+				t.Skip("options not applied by", dbType)
+			}
+			checkItem(t, itr, bz("a"), bz("1"))
+			require.NoError(t, itr.Close())
+
+			// Iterators open for too long are reported once.
+			itr, err = db.ReverseIterator(nil, nil)
+			require.NoError(t, err)
+			require.Eventually(t, func() bool {
+				return logger.count("Iterator open for too long name db") > 0
+			}, time.Second, time.Millisecond)
+			time.Sleep(30 * time.Millisecond)
+			require.NoError(t, itr.Close())
+			assert.Equal(t, 1, logger.count("Iterator open for too long"))
+			logger.mtx.Lock()
+			assert.Contains(t, logger.msgs[len(logger.msgs)-1], "TestIteratorLeakDetection")
+			logger.mtx.Unlock()
+
+			require.NoError(t, db.Close())
+			assert.Zero(t, logger.count("Iterator open at Close"))
+		})
+	}
+}
+
+func TestIteratorLeakDetectionClose(t *testing.T) {
+	for _, backend := range []BackendType{GoLevelDBBackend, PebbleDBBackend, MemDBBackend} {
+		t.Run(string(backend), func(t *testing.T) {
+			logger := &testLogger{}
+			db, err := NewDB("db", backend, t.TempDir(),
+				WithLogger(logger), WithIteratorLeakDetection(time.Hour))
+			require.NoError(t, err)
+
+			snapshot, err := Snapshot(db)
+			require.NoError(t, err)
+			defer snapshot.Close()
+			itr, err := snapshot.Iterator(nil, nil)
+			require.NoError(t, err)
+			defer itr.Close()
+
+			// pebble fails to close with open iterators, which are reported
+			// beforehand.
+			_ = db.Close()
+			assert.Equal(t, 1, logger.count("Iterator open at Close name db"))
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Each power of two of nanoseconds is divided into latencySubBuckets buckets,
+// bounding the relative error of the quantiles to 1/16.
+const (
+	latencySubBucketBits = 4
+	latencySubBuckets    = 1 << latencySubBucketBits
+)
+
+// LatencyStats is the distribution of the latencies of an operation, recorded
+// in logarithmic buckets: the quantiles are accurate to about 6%, however far
+// in the tail they are.
+type LatencyStats struct {
+	// Count is the number of operations.
+	Count uint64
+	// Sum is the total latency of the operations.
+	Sum time.Duration
+	// Min and Max are the lowest and highest latencies.
+	Min, Max time.Duration
+
+	buckets map[int]uint64
+}
+
+// Mean returns the mean latency, or 0 if there were no operations.
+func (s LatencyStats) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Quantile returns the latency below which a fraction q of the operations
+// completed, e.g. 0.999 for the 99.9th percentile, or 0 if there were no
+// operations.
+func (s LatencyStats) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	indexes := make([]int, 0, len(s.buckets))
+	for i := range s.buckets {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	rank := uint64(q * float64(s.Count))
+	var seen uint64
+	for _, i := range indexes {
+		seen += s.buckets[i]
+		if seen > rank {
+			return clampDuration(latencyBucketUpper(i), s.Min, s.Max)
+		}
+	}
+	return s.Max
+}
+
+func clampDuration(d, lo, hi time.Duration) time.Duration {
+	switch {
+	case d < lo:
+		return lo
+	case d > hi:
+		return hi
+	default:
+		return d
+	}
+}
+
+// latencyBucket returns the index of the bucket of d: latencies below
+// latencySubBuckets nanoseconds have a bucket each, and every following power
+// of two is divided into latencySubBuckets buckets.
+func latencyBucket(d time.Duration) int {
+	if d < latencySubBuckets {
+		if d < 0 {
+			return 0
+		}
+		return int(d)
+	}
+	shift := bits.Len64(uint64(d)) - latencySubBucketBits - 1
+	return (shift+1)*latencySubBuckets + int(uint64(d)>>shift) - latencySubBuckets
+}
+
+// latencyBucketUpper returns the highest latency of the bucket i.
+func latencyBucketUpper(i int) time.Duration {
+	if i < latencySubBuckets {
+		return time.Duration(i)
+	}
+	shift := i/latencySubBuckets - 1
+	mantissa := i%latencySubBuckets + latencySubBuckets
+	return time.Duration((mantissa+1)<<shift - 1)
+}
+
+// latencyTracker records the latencies of the operations on a database, by
+// operation.
+type latencyTracker struct {
+	mtx   sync.Mutex
+	stats map[string]*LatencyStats
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{stats: make(map[string]*LatencyStats)}
+}
+
+// observe records an operation op which took d.
+func (t *latencyTracker) observe(op string, d time.Duration) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	s, ok := t.stats[op]
+	if !ok {
+		s = &LatencyStats{Min: d, Max: d, buckets: make(map[int]uint64)}
+		t.stats[op] = s
+	}
+	s.Count++
+	s.Sum += d
+	if d < s.Min {
+		s.Min = d
+	}
+	if d > s.Max {
+		s.Max = d
+	}
+	s.buckets[latencyBucket(d)]++
+}
+
+// snapshot returns a copy of the recorded latencies.
+func (t *latencyTracker) snapshot() map[string]LatencyStats {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	stats := make(map[string]LatencyStats, len(t.stats))
+	for op, s := range t.stats {
+		c := *s
+		c.buckets = make(map[int]uint64, len(s.buckets))
+		for i, n := range s.buckets {
+			c.buckets[i] = n
+		}
+		stats[op] = c
+	}
+	return stats
+}
+
+// reset discards the recorded latencies.
+func (t *latencyTracker) reset() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.stats = make(map[string]*LatencyStats)
+}
+
+// LatencyReporter is implemented by databases which record the latencies of
+// their operations.
+type LatencyReporter interface {
+	// LatencyStats returns the latencies recorded since the database was
+	// opened or since the last ResetLatencyStats, by operation.
+	LatencyStats() map[string]LatencyStats
+
+	// ResetLatencyStats discards the recorded latencies.
+	ResetLatencyStats()
+}
+
+// GetLatencyStats returns the latencies of the operations on db, see
+// LatencyReporter. It returns ErrNotSupported if db does not implement
+// LatencyReporter.
+func GetLatencyStats(db DB) (map[string]LatencyStats, error) {
+	lr, ok := db.(LatencyReporter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return lr.LatencyStats(), nil
+}
+
+// ResetLatencyStats discards the latencies recorded by db, see
+// LatencyReporter. It returns ErrNotSupported if db does not implement
+// LatencyReporter.
+func ResetLatencyStats(db DB) error {
+	lr, ok := db.(LatencyReporter)
+	if !ok {
+		return ErrNotSupported
+	}
+	lr.ResetLatencyStats()
+	return nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyBuckets(t *testing.T) {
+	prev := -1
+	for _, d := range []time.Duration{0, 1, 15, 16, 31, 32, 34, 1000, time.Millisecond, time.Hour} {
+		i := latencyBucket(d)
+		assert.Greater(t, i, prev, d)
+		assert.GreaterOrEqual(t, latencyBucketUpper(i), d)
+		assert.LessOrEqual(t, float64(latencyBucketUpper(i)-d), float64(d)/latencySubBuckets, d)
+		prev = i
+	}
+}
+
+func TestLatencyStats(t *testing.T) {
+	tracker := newLatencyTracker()
+	for i := 1; i <= 1000; i++ {
+		tracker.observe("get", time.Duration(i)*time.Microsecond)
+	}
+	tracker.observe("get", time.Second)
+
+	stats := tracker.snapshot()["get"]
+	assert.EqualValues(t, 1001, stats.Count)
+	assert.Equal(t, time.Microsecond, stats.Min)
+	assert.Equal(t, time.Second, stats.Max)
+	assert.InEpsilon(t, 500*time.Microsecond, stats.Quantile(0.5), 0.07)
+	assert.InEpsilon(t, 990*time.Microsecond, stats.Quantile(0.99), 0.07)
+	assert.Equal(t, time.Second, stats.Quantile(1))
+	assert.InEpsilon(t, time.Microsecond, stats.Quantile(0), 0.07)
+
+	tracker.reset()
+	assert.Empty(t, tracker.snapshot())
+	assert.Zero(t, LatencyStats{}.Quantile(0.5))
+}
+
+func TestMetricsDBLatencyStats(t *testing.T) {
+	mdb, err := NewMetricsDB(NewMemDB(), prometheus.NewRegistry(), "test")
+	require.NoError(t, err)
+	defer mdb.Close()
+
+	require.NoError(t, mdb.Set(bz("a"), bz("1")))
+	checkValue(t, mdb, bz("a"), bz("1"))
+	checkValue(t, mdb, bz("b"), nil)
+
+	stats, err := GetLatencyStats(mdb)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, stats["set"].Count)
+	assert.EqualValues(t, 2, stats["get"].Count)
+	assert.LessOrEqual(t, stats["get"].Quantile(0.999), stats["get"].Max)
+
+	require.NoError(t, ResetLatencyStats(mdb))
+	stats, err = GetLatencyStats(mdb)
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	_, err = GetLatencyStats(NewMemDB())
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
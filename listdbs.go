@@ -0,0 +1,150 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DBInfo describes a database found by ListDBs.
+type DBInfo struct {
+	// Name is the name of the database, to open it with NewDB.
+	Name string
+	// Backend is the backend whose files the database has, or "" if they are
+	// not recognized. The databases of goleveldb and cleveldb have the same
+	// format, and are reported as goleveldb.
+	Backend BackendType
+	// Path is the directory or file of the database.
+	Path string
+	// Size is the size of the files of the database, in bytes.
+	Size uint64
+	// ModTime is the last modification time of the files of the database.
+	ModTime time.Time
+}
+
+// boltMagic is the magic number of the meta pages of bolt, which follow the
+// 16 bytes of the page header.
+const boltMagic = 0xED0CDAED
+
+// backendMarkers are the files recognizing the directories of the backends
+// whose databases have no CURRENT file, unlike those of the LevelDB family.
+var backendMarkers = []struct {
+	file    string
+	backend BackendType
+}{
+	{"data.sqlite", SQLiteDBBackend},
+	{"data.mdb", LMDBBackend},
+	{"KEYREGISTRY", BadgerDBBackend},
+}
+
+// ListDBs lists the databases in dir, by name: the directories and files
+// named after their database with a .db extension, and the badgerdb
+// directories, which have none. The backend of each database is detected from
+// its files, and other entries of dir are ignored.
+func ListDBs(dir string) ([]DBInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var dbs []DBInfo
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		name, ok := strings.CutSuffix(entry.Name(), ".db")
+		if !ok && !entry.IsDir() {
+			continue
+		}
+		backend, err := detectBackend(path, entry.IsDir())
+		if err != nil {
+			return nil, err
+		}
+		if !ok && backend != BadgerDBBackend {
+			continue
+		}
+		info := DBInfo{Name: name, Backend: backend, Path: path}
+		if info.Size, info.ModTime, err = dbFilesInfo(path); err != nil {
+			return nil, err
+		}
+		dbs = append(dbs, info)
+	}
+	return dbs, nil
+}
+
+// detectBackend returns the backend of the database at path, from the markers
+// found in its files, or "" if none.
+func detectBackend(path string, isDir bool) (BackendType, error) {
+	if !isDir {
+		header := make([]byte, 20)
+		f, err := os.Open(path)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		if _, err := io.ReadFull(f, header); err != nil {
+			return "", nil
+		}
+		if binary.LittleEndian.Uint32(header[16:]) == boltMagic {
+			return BoltDBBackend, nil
+		}
+		return "", nil
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(path, name))
+		return err == nil
+	}
+	for _, marker := range backendMarkers {
+		if exists(marker.file) {
+			return marker.backend, nil
+		}
+	}
+	if !exists("CURRENT") {
+		return "", nil
+	}
+	// The OPTIONS files of pebble and RocksDB name their version, while
+	// LevelDB has none.
+	options, err := filepath.Glob(filepath.Join(path, "OPTIONS-*"))
+	if err != nil || len(options) == 0 {
+		return GoLevelDBBackend, nil
+	}
+	contents, err := os.ReadFile(options[len(options)-1])
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+	switch {
+	case bytes.Contains(contents, []byte("pebble_version")):
+		return PebbleDBBackend, nil
+	case bytes.Contains(contents, []byte("rocksdb_version")):
+		return RocksDBBackend, nil
+	}
+	return "", nil
+}
+
+// dbFilesInfo returns the size and the last modification time of the files
+// at path.
+func dbFilesInfo(path string) (size uint64, modTime time.Time, err error) {
+	err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			// Removed meanwhile, e.g. by a compaction.
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += uint64(info.Size())
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+		return nil
+	})
+	return size, modTime, err
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDBs(t *testing.T) {
+	dir := t.TempDir()
+	for _, backend := range []BackendType{GoLevelDBBackend, PebbleDBBackend} {
+		db, err := NewDB(string(backend), backend, dir)
+		require.NoError(t, err)
+		require.NoError(t, db.Set(bz("key"), bz("value")))
+		require.NoError(t, db.Close())
+	}
+	// The other backends are recognized by their files.
+	header := make([]byte, 4096)
+	binary.LittleEndian.PutUint32(header[16:], boltMagic)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bolt.db"), header, 0o600))
+	for name, marker := range map[string]string{"sqlite.db": "data.sqlite", "badger": "KEYREGISTRY"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, name), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name, marker), nil, 0o600))
+	}
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "unknown.db"), 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "other"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o600))
+
+	dbs, err := ListDBs(dir)
+	require.NoError(t, err)
+	backends := make(map[string]BackendType)
+	for _, info := range dbs {
+		backends[info.Name] = info.Backend
+	}
+	assert.Equal(t, map[string]BackendType{
+		"badger":    BadgerDBBackend,
+		"bolt":      BoltDBBackend,
+		"goleveldb": GoLevelDBBackend,
+		"pebbledb":  PebbleDBBackend,
+		"sqlite":    SQLiteDBBackend,
+		"unknown":   "",
+	}, backends)
+
+	info := dbs[2]
+	require.Equal(t, "goleveldb", info.Name)
+	assert.Equal(t, filepath.Join(dir, "goleveldb.db"), info.Path)
+	assert.Positive(t, info.Size)
+	assert.WithinDuration(t, time.Now(), info.ModTime, time.Minute)
+	assert.EqualValues(t, 4096, dbs[1].Size)
+
+	_, err = ListDBs(filepath.Join(dir, "missing"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
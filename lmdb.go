@@ -0,0 +1,477 @@
+//go:build lmdb
+// +build lmdb
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/bmatsuo/lmdb-go/lmdb"
+)
+
+// lmdbMapSize is the maximum size of the memory map, and therefore of the
+// database. It only reserves address space, disk space is allocated as needed.
+const lmdbMapSize = 1 << 40
+
+// lmdbMaxKeySize is the maximum key length of LMDB as built by lmdb-go, which
+// fails the writes of longer keys with MDB_BAD_VALSIZE.
+const lmdbMaxKeySize = 511
+
+func init() {
+	registerDBCreator(LMDBBackend, newLMDBFromOptions, false)
+}
+
+// newLMDBFromOptions opens the environment with the flags of the options. LMDB
+// reads from a memory map, so the cache size is ignored.
+func newLMDBFromOptions(name, dir string, opts *Options) (DB, error) {
+	if opts.InMemory {
+		return nil, errOption(LMDBBackend, "InMemory")
+	}
+	if opts.FS != nil {
+		return nil, errOption(LMDBBackend, "FS")
+	}
+	if opts.Recover {
+		return nil, errOption(LMDBBackend, "Recover")
+	}
+	if opts.BackendOptions != nil {
+		return nil, errBackendOptions(LMDBBackend, opts.BackendOptions)
+	}
+	var flags uint = lmdb.NoSync
+	if opts.SyncWrites {
+		flags = 0
+	}
+	if opts.ReadOnly {
+		flags |= lmdb.Readonly
+	}
+	db, err := newLMDB(name, dir, flags)
+	if err != nil {
+		return nil, err
+	}
+	db.iters = newIteratorTracker(name, opts)
+	db.lock = opts.lock
+	return db, nil
+}
+
+// LMDB is a wrapper around the Lightning Memory-Mapped Database
+// (https://www.symas.com/lmdb), using the root database of the environment.
+//
+// Reads are served straight from the memory map and never block on writers,
+// which makes it a good fit for read and iteration heavy workloads. Writes are
+// serialized by LMDB: a single write transaction is used per Set/Delete call or
+// per Batch.
+type LMDB struct {
+	env   *lmdb.Env
+	dbi   lmdb.DBI
+	iters *iteratorTracker
+	lock  *dbLock
+	ops   *opTracker
+}
+
+var _ DB = (*LMDB)(nil)
+
+// errLMDBClosed is returned by the operations on a closed LMDB, whose
+// environment is freed by Close.
+var errLMDBClosed = fmt.Errorf("lmdb: %w", ErrClosed)
+
+// NewLMDB opens (creating it if needed) the LMDB environment under dir/name.db.
+func NewLMDB(name, dir string) (*LMDB, error) {
+	// Commits are not synced, SetSync, DeleteSync and WriteSync sync explicitly.
+	return newLMDB(name, dir, lmdb.NoSync)
+}
+
+func newLMDB(name, dir string, flags uint) (*LMDB, error) {
+	dbPath := filepath.Join(dir, name+".db")
+	if flags&lmdb.Readonly == 0 {
+		if err := os.MkdirAll(dbPath, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	env, err := lmdb.NewEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := env.SetMapSize(lmdbMapSize); err != nil {
+		env.Close()
+		return nil, err
+	}
+	if err := env.Open(dbPath, flags, 0o644); err != nil {
+		env.Close()
+		return nil, lmdbError(err)
+	}
+	var dbi lmdb.DBI
+	err = env.View(func(txn *lmdb.Txn) (err error) {
+		dbi, err = txn.OpenRoot(0)
+		return err
+	})
+	if err != nil {
+		env.Close()
+		return nil, err
+	}
+	return &LMDB{env: env, dbi: dbi, ops: newOpTracker()}, nil
+}
+
+// lmdbError wraps the LMDB errors with the shared error kinds. Writes to a
+// read-only environment fail with EACCES.
+func lmdbError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case lmdb.IsErrnoSys(err, syscall.EACCES):
+		return wrapError(ErrReadOnly, err)
+	case lmdb.IsErrno(err, lmdb.Corrupted), lmdb.IsErrno(err, lmdb.PageNotFound),
+		lmdb.IsErrno(err, lmdb.Invalid), lmdb.IsErrno(err, lmdb.VersionMismatch):
+		return wrapError(ErrCorrupted, err)
+	}
+	return err
+}
+
+// checkLMDBKey returns a clear error for the keys LMDB cannot store.
+func checkLMDBKey(key []byte) error {
+	if len(key) > lmdbMaxKeySize {
+		return fmt.Errorf("lmdb: key of %d bytes exceeds the maximum key size of %d bytes",
+			len(key), lmdbMaxKeySize)
+	}
+	return nil
+}
+
+// Get implements DB.
+func (db *LMDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	if !db.ops.begin() {
+		return nil, errLMDBClosed
+	}
+	defer db.ops.end()
+	var value []byte
+	err := db.env.View(func(txn *lmdb.Txn) error {
+		v, err := txn.Get(db.dbi, key)
+		if lmdb.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		value = append([]byte{}, v...)
+		return nil
+	})
+	if err != nil {
+		return nil, lmdbError(err)
+	}
+	return value, nil
+}
+
+// GetUnsafe implements UnsafeGetter, passing the value in the memory map within
+// a read transaction. Writing to the memory map segfaults, rather than only
+// corrupting the database.
+func (db *LMDB) GetUnsafe(key []byte, fn func(value []byte) error) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if !db.ops.begin() {
+		return errLMDBClosed
+	}
+	defer db.ops.end()
+	var fnErr error
+	err := db.env.View(func(txn *lmdb.Txn) error {
+		txn.RawRead = true
+		v, err := txn.Get(db.dbi, key)
+		switch {
+		case lmdb.IsNotFound(err):
+			v = nil
+		case err != nil:
+			return err
+		case v == nil:
+			v = []byte{}
+		}
+		fnErr = fn(v)
+		return nil
+	})
+	if err != nil {
+		return lmdbError(err)
+	}
+	return fnErr
+}
+
+// MultiGet implements MultiGetter, reading all keys in a single transaction.
+func (db *LMDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+	}
+	if !db.ops.begin() {
+		return nil, errLMDBClosed
+	}
+	defer db.ops.end()
+	values := make([][]byte, len(keys))
+	err := db.env.View(func(txn *lmdb.Txn) error {
+		for i, key := range keys {
+			v, err := txn.Get(db.dbi, key)
+			if lmdb.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			values[i] = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, lmdbError(err)
+	}
+	return values, nil
+}
+
+// Has implements DB.
+func (db *LMDB) Has(key []byte) (bool, error) {
+	bytes, err := db.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return bytes != nil, nil
+}
+
+// Set implements DB. Keys are limited to lmdbMaxKeySize bytes.
+func (db *LMDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if err := checkLMDBKey(key); err != nil {
+		return err
+	}
+	if !db.ops.begin() {
+		return errLMDBClosed
+	}
+	defer db.ops.end()
+	return lmdbError(db.env.Update(func(txn *lmdb.Txn) error {
+		return txn.Put(db.dbi, key, value, 0)
+	}))
+}
+
+// CompareAndSwap implements ConditionalWriter, within a single LMDB write
+// transaction.
+func (db *LMDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if new == nil {
+		return false, errValueNil
+	}
+	if err := checkLMDBKey(key); err != nil {
+		return false, err
+	}
+	if !db.ops.begin() {
+		return false, errLMDBClosed
+	}
+	defer db.ops.end()
+	swapped := false
+	err := db.env.Update(func(txn *lmdb.Txn) error {
+		current, err := txn.Get(db.dbi, key)
+		if lmdb.IsNotFound(err) {
+			current, err = nil, nil
+		}
+		if err != nil || !casMatch(current, old) {
+			return err
+		}
+		swapped = true
+		return txn.Put(db.dbi, key, new, 0)
+	})
+	if err != nil {
+		return false, lmdbError(err)
+	}
+	return swapped, nil
+}
+
+// SetSync implements DB.
+func (db *LMDB) SetSync(key []byte, value []byte) error {
+	if !db.ops.begin() {
+		return errLMDBClosed
+	}
+	defer db.ops.end()
+	if err := db.Set(key, value); err != nil {
+		return err
+	}
+	return lmdbError(db.env.Sync(true))
+}
+
+// Delete implements DB.
+func (db *LMDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if err := checkLMDBKey(key); err != nil {
+		return err
+	}
+	if !db.ops.begin() {
+		return errLMDBClosed
+	}
+	defer db.ops.end()
+	return lmdbError(db.env.Update(func(txn *lmdb.Txn) error {
+		err := txn.Del(db.dbi, key, nil)
+		if lmdb.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}))
+}
+
+// DeleteSync implements DB.
+func (db *LMDB) DeleteSync(key []byte) error {
+	if !db.ops.begin() {
+		return errLMDBClosed
+	}
+	defer db.ops.end()
+	if err := db.Delete(key); err != nil {
+		return err
+	}
+	return lmdbError(db.env.Sync(true))
+}
+
+// Sync implements Syncer, flushing the buffers of the environment to disk.
+// Commits are not synced otherwise.
+func (db *LMDB) Sync() error {
+	if !db.ops.begin() {
+		return errLMDBClosed
+	}
+	defer db.ops.end()
+	return lmdbError(db.env.Sync(true))
+}
+
+// Backup implements Backupper with a copy of the environment as of a read
+// transaction, which does not block writers. The context is only checked
+// before starting.
+func (db *LMDB) Backup(ctx context.Context, targetDir string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !db.ops.begin() {
+		return errLMDBClosed
+	}
+	defer db.ops.end()
+	path, err := db.env.Path()
+	if err != nil {
+		return lmdbError(err)
+	}
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		return err
+	}
+	path = filepath.Join(targetDir, filepath.Base(path))
+	if err := os.Mkdir(path, 0o755); err != nil {
+		return err
+	}
+	return lmdbError(db.env.Copy(path))
+}
+
+// Count implements Counter, using the entry count of the LMDB database.
+func (db *LMDB) Count() (uint64, error) {
+	if !db.ops.begin() {
+		return 0, errLMDBClosed
+	}
+	defer db.ops.end()
+	var count uint64
+	err := db.env.View(func(txn *lmdb.Txn) error {
+		stat, err := txn.Stat(db.dbi)
+		if err != nil {
+			return err
+		}
+		count = stat.Entries
+		return nil
+	})
+	return count, lmdbError(err)
+}
+
+// Env returns the underlying LMDB environment.
+func (db *LMDB) Env() *lmdb.Env {
+	return db.env
+}
+
+// Close implements DB. It waits for the operations in flight, such as batch
+// writes, after which the operations fail with ErrClosed.
+func (db *LMDB) Close() error {
+	defer db.lock.release()
+	if !db.ops.close() {
+		return errLMDBClosed
+	}
+	db.iters.close()
+	return lmdbError(db.env.Close())
+}
+
+// Print implements DB.
+func (db *LMDB) Print() error {
+	itr, err := db.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB.
+func (db *LMDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	if !db.ops.begin() {
+		return stats
+	}
+	defer db.ops.end()
+	if stat, err := db.env.Stat(); err == nil {
+		stats["lmdb.entries"] = fmt.Sprintf("%d", stat.Entries)
+		stats["lmdb.depth"] = fmt.Sprintf("%d", stat.Depth)
+		stats["lmdb.page-size"] = fmt.Sprintf("%d", stat.PSize)
+		stats["lmdb.branch-pages"] = fmt.Sprintf("%d", stat.BranchPages)
+		stats["lmdb.leaf-pages"] = fmt.Sprintf("%d", stat.LeafPages)
+		stats["lmdb.overflow-pages"] = fmt.Sprintf("%d", stat.OverflowPages)
+	}
+	if info, err := db.env.Info(); err == nil {
+		stats["lmdb.map-size"] = fmt.Sprintf("%d", info.MapSize)
+		stats["lmdb.last-page"] = fmt.Sprintf("%d", info.LastPNO)
+		stats["lmdb.readers"] = fmt.Sprintf("%d", info.NumReaders)
+	}
+	return stats
+}
+
+// NewBatch implements DB.
+func (db *LMDB) NewBatch() Batch {
+	return newLMDBBatch(db)
+}
+
+// Iterator implements DB.
+//
+// The iterator holds a read-only transaction open until it is closed. This does
+// not block writers, but prevents LMDB from reusing pages freed in the meantime.
+func (db *LMDB) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if !db.ops.begin() {
+		return nil, errLMDBClosed
+	}
+	defer db.ops.end()
+	return db.iters.track(newLMDBIterator(db, start, end, false))
+}
+
+// ReverseIterator implements DB.
+//
+// The iterator holds a read-only transaction open until it is closed. This does
+// not block writers, but prevents LMDB from reusing pages freed in the meantime.
+func (db *LMDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if !db.ops.begin() {
+		return nil, errLMDBClosed
+	}
+	defer db.ops.end()
+	return db.iters.track(newLMDBIterator(db, start, end, true))
+}
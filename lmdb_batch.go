@@ -0,0 +1,120 @@
+//go:build lmdb
+// +build lmdb
+
+package db
+
+import (
+	"fmt"
+
+	"github.com/bmatsuo/lmdb-go/lmdb"
+)
+
+// lmdbBatch stores operations internally and applies them within a single LMDB
+// write transaction on Write().
+type lmdbBatch struct {
+	db  *LMDB
+	ops []operation
+}
+
+var _ Batch = (*lmdbBatch)(nil)
+
+func newLMDBBatch(db *LMDB) *lmdbBatch {
+	return &lmdbBatch{
+		db:  db,
+		ops: []operation{},
+	}
+}
+
+// Set implements Batch.
+func (b *lmdbBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if err := checkLMDBKey(key); err != nil {
+		return err
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *lmdbBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if err := checkLMDBKey(key); err != nil {
+		return err
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	return nil
+}
+
+// Count implements Batch.
+func (b *lmdbBatch) Count() int {
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *lmdbBatch) SizeBytes() int {
+	return opsSize(b.ops)
+}
+
+// Write implements Batch.
+func (b *lmdbBatch) Write() error {
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	if !b.db.ops.begin() {
+		return errLMDBClosed
+	}
+	defer b.db.ops.end()
+	err := b.db.env.Update(func(txn *lmdb.Txn) error {
+		for _, op := range b.ops {
+			switch op.opType {
+			case opTypeSet:
+				if err := txn.Put(b.db.dbi, op.key, op.value, 0); err != nil {
+					return err
+				}
+			case opTypeDelete:
+				if err := txn.Del(b.db.dbi, op.key, nil); err != nil && !lmdb.IsNotFound(err) {
+					return err
+				}
+			default:
+				return fmt.Errorf("unknown operation type %v (%v)", op.opType, op)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return lmdbError(err)
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// WriteSync implements Batch.
+func (b *lmdbBatch) WriteSync() error {
+	if !b.db.ops.begin() {
+		return errLMDBClosed
+	}
+	defer b.db.ops.end()
+	if err := b.Write(); err != nil {
+		return err
+	}
+	return lmdbError(b.db.env.Sync(true))
+}
+
+// Close implements Batch.
+func (b *lmdbBatch) Close() error {
+	b.ops = nil
+	return nil
+}
@@ -0,0 +1,162 @@
+//go:build lmdb
+// +build lmdb
+
+package db
+
+import (
+	"bytes"
+
+	"github.com/bmatsuo/lmdb-go/lmdb"
+)
+
+// lmdbIterator iterates over a read-only transaction using an LMDB cursor.
+type lmdbIterator struct {
+	txn    *lmdb.Txn
+	cursor *lmdb.Cursor
+	start  []byte
+	end    []byte
+
+	currentKey   []byte
+	currentValue []byte
+
+	isReverse bool
+	isInvalid bool
+	err       error
+}
+
+var _ Iterator = (*lmdbIterator)(nil)
+
+func newLMDBIterator(db *LMDB, start, end []byte, isReverse bool) (*lmdbIterator, error) {
+	txn, err := db.env.BeginTxn(nil, lmdb.Readonly)
+	if err != nil {
+		return nil, lmdbError(err)
+	}
+	cursor, err := txn.OpenCursor(db.dbi)
+	if err != nil {
+		txn.Abort()
+		return nil, err
+	}
+	itr := &lmdbIterator{
+		txn:       txn,
+		cursor:    cursor,
+		start:     start,
+		end:       end,
+		isReverse: isReverse,
+	}
+
+	if isReverse {
+		if end == nil {
+			itr.get(nil, lmdb.Last)
+		} else {
+			itr.get(end, lmdb.SetRange)
+			switch {
+			case itr.err != nil:
+			case itr.currentKey == nil:
+				// end is past the last key
+				itr.get(nil, lmdb.Last)
+			default:
+				// end is exclusive
+				itr.get(nil, lmdb.Prev)
+			}
+		}
+	} else {
+		if start == nil {
+			itr.get(nil, lmdb.First)
+		} else {
+			itr.get(start, lmdb.SetRange)
+		}
+	}
+	return itr, nil
+}
+
+// get positions the cursor and loads the current item. Running past either end
+// of the database leaves a nil current key.
+func (itr *lmdbIterator) get(setKey []byte, op uint) {
+	k, v, err := itr.cursor.Get(setKey, nil, op)
+	if lmdb.IsNotFound(err) {
+		itr.currentKey, itr.currentValue = nil, nil
+		return
+	}
+	if err != nil {
+		itr.err = lmdbError(err)
+		itr.currentKey, itr.currentValue = nil, nil
+		return
+	}
+	itr.currentKey, itr.currentValue = k, v
+}
+
+// Domain implements Iterator.
+func (itr *lmdbIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+// Valid implements Iterator.
+func (itr *lmdbIterator) Valid() bool {
+	if itr.isInvalid {
+		return false
+	}
+
+	if itr.err != nil || itr.currentKey == nil {
+		itr.isInvalid = true
+		return false
+	}
+
+	if itr.isReverse {
+		if itr.start != nil && bytes.Compare(itr.currentKey, itr.start) < 0 {
+			itr.isInvalid = true
+			return false
+		}
+	} else {
+		if itr.end != nil && bytes.Compare(itr.end, itr.currentKey) <= 0 {
+			itr.isInvalid = true
+			return false
+		}
+	}
+
+	// Valid
+	return true
+}
+
+// Next implements Iterator.
+func (itr *lmdbIterator) Next() {
+	itr.assertIsValid()
+	if itr.isReverse {
+		itr.get(nil, lmdb.Prev)
+	} else {
+		itr.get(nil, lmdb.Next)
+	}
+}
+
+// Key implements Iterator.
+func (itr *lmdbIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.currentKey
+}
+
+// Value implements Iterator.
+func (itr *lmdbIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.currentValue
+}
+
+// Error implements Iterator.
+func (itr *lmdbIterator) Error() error {
+	return itr.err
+}
+
+// Close implements Iterator.
+func (itr *lmdbIterator) Close() error {
+	if itr.txn != nil {
+		itr.cursor.Close()
+		itr.txn.Abort()
+		itr.txn = nil
+	}
+	itr.isInvalid = true
+	return nil
+}
+
+func (itr *lmdbIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -0,0 +1,90 @@
+//go:build lmdb
+// +build lmdb
+
+package db
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLMDBBackend(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, LMDBBackend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+	defer db.Close()
+
+	_, ok := db.(*LMDB)
+	assert.True(t, ok)
+	assert.NotEmpty(t, db.Stats())
+}
+
+func TestLMDBIteratorWhileWriting(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewLMDB(name, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+	defer db.Close()
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer itr.Close()
+
+	// Writers are not blocked by readers, and readers keep their view.
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+	require.True(t, itr.Valid())
+	assert.Equal(t, []byte("a"), itr.Key())
+	itr.Next()
+	assert.False(t, itr.Valid())
+}
+
+func BenchmarkLMDBRandomReadsWrites(b *testing.B) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewLMDB(name, dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		db.Close()
+		cleanupDBDir(dir, name)
+	}()
+
+	benchmarkRandomReadsWrites(b, db)
+}
+
+func TestLMDBMaxKeySize(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewLMDB(name, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+	defer db.Close()
+
+	key := make([]byte, lmdbMaxKeySize)
+	require.NoError(t, db.Set(key, []byte{1}))
+	require.NoError(t, db.Delete(key))
+
+	long := make([]byte, lmdbMaxKeySize+1)
+	assert.ErrorContains(t, db.Set(long, []byte{1}), "maximum key size")
+	assert.ErrorContains(t, db.Delete(long), "maximum key size")
+	_, err = db.CompareAndSwap(long, nil, []byte{1})
+	assert.ErrorContains(t, err, "maximum key size")
+
+	// The batch is rejected before being written.
+	batch := db.NewBatch()
+	defer batch.Close()
+	assert.ErrorContains(t, batch.Set(long, []byte{1}), "maximum key size")
+	assert.ErrorContains(t, batch.Delete(long), "maximum key size")
+	require.NoError(t, batch.Set(key, []byte{2}))
+	require.NoError(t, batch.Write())
+	checkValue(t, db, key, []byte{2})
+}
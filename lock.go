@@ -0,0 +1,151 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// lockRetryInterval is how often NewDB tries again to lock a database, until
+// Options.LockTimeout.
+const lockRetryInterval = 10 * time.Millisecond
+
+// fileBackends are the backends whose databases NewDB locks, those keeping
+// their files in the directory.
+var fileBackends = map[BackendType]bool{
+	GoLevelDBBackend: true,
+	CLevelDBBackend:  true,
+	BoltDBBackend:    true,
+	RocksDBBackend:   true,
+	BadgerDBBackend:  true,
+	PebbleDBBackend:  true,
+	SQLiteDBBackend:  true,
+	LMDBBackend:      true,
+}
+
+// lockFreeReadBackends are the file backends which open their databases
+// read-only without locking them, so that they can be inspected while in use
+// by a writer. NewDB does not lock them either when read-only.
+var lockFreeReadBackends = map[BackendType]bool{
+	RocksDBBackend: true,
+}
+
+// LockError is returned by NewDB when the database is locked by another
+// process, or by another database of this process. It is an ErrDBLocked.
+type LockError struct {
+	// Path is the lock file of the database.
+	Path string
+	// PID is the ID of the process holding the lock, or 0 if unknown.
+	PID int
+	// Err is the error of the last attempt to take the lock.
+	Err error
+}
+
+// Error implements error.
+func (e *LockError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("%v: %s: %v", ErrDBLocked, e.Path, e.Err)
+	}
+	return fmt.Sprintf("%v: %s is held by process %d: %v", ErrDBLocked, e.Path, e.PID, e.Err)
+}
+
+// Unwrap returns ErrDBLocked and the error of the lock attempt.
+func (e *LockError) Unwrap() []error {
+	return []error{ErrDBLocked, e.Err}
+}
+
+// errLockHeld is the error of the attempts to lock a database locked by this
+// process.
+var errLockHeld = errors.New("lock held by this process")
+
+// lockedFiles are the lock files locked by this process, by path. The locks of
+// the files are held by processes rather than by file descriptors, so they do
+// not exclude the databases of a single process from each other.
+var lockedFiles = struct {
+	sync.Mutex
+	files map[string]*lockedFile
+}{files: make(map[string]*lockedFile)}
+
+// lockedFile is a lock file locked by this process, exclusively unless shared
+// by the databases opened read-only.
+type lockedFile struct {
+	path   string
+	shared bool
+	refs   int // number of databases holding the lock
+	file   io.Closer
+}
+
+// dbLock is the lock of a database, in the file name.lock next to its files.
+type dbLock struct {
+	file *lockedFile
+	once sync.Once
+}
+
+// lockDB locks the database name in dir, shared if read-only, waiting until
+// timeout for the lock to be released.
+func lockDB(name, dir string, readOnly bool, timeout time.Duration) (*dbLock, error) {
+	path, err := filepath.Abs(filepath.Join(dir, name+".lock"))
+	if err != nil {
+		return nil, err
+	}
+	if !readOnly && dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		file, err := tryLockFile(path, readOnly)
+		if err == nil {
+			return &dbLock{file: file}, nil
+		}
+		if !errors.Is(err, ErrDBLocked) || !time.Now().Before(deadline) {
+			return nil, err
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// tryLockFile locks the lock file path, or fails with a LockError if it is
+// locked.
+func tryLockFile(path string, shared bool) (*lockedFile, error) {
+	lockedFiles.Lock()
+	defer lockedFiles.Unlock()
+	if lf, ok := lockedFiles.files[path]; ok {
+		if !shared || !lf.shared {
+			return nil, &LockError{Path: path, PID: os.Getpid(), Err: errLockHeld}
+		}
+		lf.refs++
+		return lf, nil
+	}
+	file, err := lockFile(path, shared)
+	if err != nil {
+		return nil, err
+	}
+	lf := &lockedFile{path: path, shared: shared, refs: 1, file: file}
+	lockedFiles.files[path] = lf
+	return lf, nil
+}
+
+// release releases the lock of the database, and the lock file once released
+// by all the databases sharing it. It does nothing on a nil lock, or once
+// released.
+func (l *dbLock) release() (err error) {
+	if l == nil {
+		return nil
+	}
+	l.once.Do(func() {
+		lockedFiles.Lock()
+		defer lockedFiles.Unlock()
+		if l.file.refs--; l.file.refs > 0 {
+			return
+		}
+		delete(lockedFiles.files, l.file.path)
+		err = l.file.file.Close()
+	})
+	return err
+}
@@ -0,0 +1,17 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris || windows)
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris,!windows
+
+package db
+
+import (
+	"io"
+
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// lockFile locks the file path, created if needed. The lock is always
+// exclusive, and does not tell the process holding it. Lock contention cannot
+// be told from other failures, so errors are returned as is.
+func lockFile(path string, _ bool) (io.Closer, error) {
+	return vfs.Default.Lock(path)
+}
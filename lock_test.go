@@ -0,0 +1,88 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDBLocked(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB("db", GoLevelDBBackend, dir)
+	require.NoError(t, err)
+
+	_, err = NewDB("db", PebbleDBBackend, dir)
+	require.ErrorIs(t, err, ErrDBLocked)
+	var lerr *LockError
+	require.ErrorAs(t, err, &lerr)
+	assert.Equal(t, os.Getpid(), lerr.PID)
+	assert.Equal(t, filepath.Join(dir, "db.lock"), lerr.Path)
+	_, err = NewDB("db", GoLevelDBBackend, dir, WithReadOnly())
+	assert.ErrorIs(t, err, ErrDBLocked)
+
+	// Other databases of the directory are not locked.
+	other, err := NewDB("other", GoLevelDBBackend, dir)
+	require.NoError(t, err)
+	require.NoError(t, other.Close())
+
+	require.NoError(t, db.Close())
+	db, err = NewDB("db", GoLevelDBBackend, dir)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
+
+func TestNewDBLockedReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB("db", GoLevelDBBackend, dir)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	db1, err := NewDB("db", GoLevelDBBackend, dir, WithReadOnly())
+	require.NoError(t, err)
+	db2, err := NewDB("db", GoLevelDBBackend, dir, WithReadOnly())
+	require.NoError(t, err)
+	_, err = NewDB("db", GoLevelDBBackend, dir)
+	assert.ErrorIs(t, err, ErrDBLocked)
+
+	// The lock is held until both databases are closed, once each.
+	require.NoError(t, db1.Close())
+	db1.Close()
+	_, err = NewDB("db", GoLevelDBBackend, dir)
+	assert.ErrorIs(t, err, ErrDBLocked)
+	require.NoError(t, db2.Close())
+	db, err = NewDB("db", GoLevelDBBackend, dir)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
+
+func TestNewDBLockTimeout(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB("db", PebbleDBBackend, dir)
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = NewDB("db", PebbleDBBackend, dir, WithLockTimeout(50*time.Millisecond))
+	assert.ErrorIs(t, err, ErrDBLocked)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		db.Close()
+	}()
+	db, err = NewDB("db", PebbleDBBackend, dir, WithLockTimeout(10*time.Second))
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
+
+func TestNewDBLockReleasedOnError(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewDB("db", GoLevelDBBackend, dir, WithBackendOptions(42))
+	require.Error(t, err)
+	db, err := NewDB("db", GoLevelDBBackend, dir)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
@@ -0,0 +1,41 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package db
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile locks the file path, created if needed, with a POSIX record lock,
+// which tells the process holding it to the processes failing to lock it.
+func lockFile(path string, shared bool) (io.Closer, error) {
+	flag := os.O_RDWR
+	lk := unix.Flock_t{Type: unix.F_WRLCK, Whence: io.SeekStart}
+	if shared {
+		flag = os.O_RDONLY
+		lk.Type = unix.F_RDLCK
+	}
+	f, err := os.OpenFile(path, flag|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.FcntlFlock(f.Fd(), unix.F_SETLK, &lk); err != nil {
+		lerr := err
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EACCES) {
+			lerr = &LockError{Path: path, Err: err}
+			// The lock is not held by this process, so no lock is released when
+			// closing f.
+			if unix.FcntlFlock(f.Fd(), unix.F_GETLK, &lk) == nil && lk.Type != unix.F_UNLCK {
+				lerr.(*LockError).PID = int(lk.Pid)
+			}
+		}
+		f.Close()
+		return nil, lerr
+	}
+	return f, nil
+}
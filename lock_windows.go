@@ -0,0 +1,26 @@
+//go:build windows
+// +build windows
+
+package db
+
+import (
+	"errors"
+	"io"
+
+	"github.com/cockroachdb/pebble/vfs"
+	"golang.org/x/sys/windows"
+)
+
+// lockFile locks the file path, created if needed, by opening it without
+// sharing. The lock is always exclusive, and does not tell the process holding
+// it.
+func lockFile(path string, _ bool) (io.Closer, error) {
+	closer, err := vfs.Default.Lock(path)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_SHARING_VIOLATION) {
+			return nil, &LockError{Path: path, Err: err}
+		}
+		return nil, err
+	}
+	return closer, nil
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+// Logger receives the log messages of the backends, e.g. the stats logged
+// periodically by goleveldb. The arguments after the message are alternating
+// keys and values.
+//
+// The Logger of CometBFT (libs/log.Logger) implements it as is. Adapters are
+// provided for zap (NewSugaredLogger) and log/slog (NewSlogLogger).
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// LogLevel is the minimum level of the messages passed on by the loggers of
+// NewStdLogger and NewFilteredLogger.
+type LogLevel int
+
+const (
+	// LogLevelDebug passes on all messages, including the periodic stats of
+	// the backends.
+	LogLevelDebug LogLevel = iota
+	// LogLevelInfo passes on informational and error messages, e.g. databases
+	// being opened.
+	LogLevelInfo
+	// LogLevelError only passes on error messages.
+	LogLevelError
+	// LogLevelNone passes on no messages.
+	LogLevelNone
+)
+
+// LevelLogger is implemented by loggers which can tell whether they pass on
+// the messages of a level, so that the messages which would be discarded, e.g.
+// those logged on every write, are not built at all.
+type LevelLogger interface {
+	Enabled(level LogLevel) bool
+}
+
+// logEnabled reports whether logger passes on the messages of level. Loggers
+// which do not implement LevelLogger are assumed to.
+func logEnabled(logger Logger, level LogLevel) bool {
+	if ll, ok := logger.(LevelLogger); ok {
+		return ll.Enabled(level)
+	}
+	return true
+}
+
+// hexKey formats a key in hexadecimal when logged. The encoding is deferred
+// until the logger formats the message, so that it is not paid for messages
+// discarded by the logger.
+type hexKey []byte
+
+// String implements fmt.Stringer.
+func (k hexKey) String() string {
+	return hex.EncodeToString(k)
+}
+
+// NewStdLogger returns a Logger writing the messages of at least level to the
+// standard library logger, formatted as the message followed by key=value
+// pairs. It is the default logger of NewDB at LogLevelInfo.
+func NewStdLogger(level LogLevel) Logger {
+	return NewFilteredLogger(stdLogger{}, level)
+}
+
+// NewNopLogger returns a Logger discarding all messages.
+func NewNopLogger() Logger {
+	return nopLogger{}
+}
+
+// NewFilteredLogger returns a Logger passing the messages of at least level on
+// to logger, and discarding the others.
+func NewFilteredLogger(logger Logger, level LogLevel) Logger {
+	return &filteredLogger{logger: logger, level: level}
+}
+
+// SugaredLogger is the structured logging interface of *zap.SugaredLogger.
+type SugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// NewSugaredLogger returns a Logger writing to a zap logger, e.g.
+// zap.L().Sugar(). The level of the zap logger applies.
+func NewSugaredLogger(logger SugaredLogger) Logger {
+	return sugaredLogger{logger: logger}
+}
+
+// stdLogger writes all messages to the standard library logger.
+type stdLogger struct{}
+
+func (stdLogger) Debug(msg string, keyvals ...interface{}) { logf(msg, keyvals) }
+func (stdLogger) Info(msg string, keyvals ...interface{})  { logf(msg, keyvals) }
+func (stdLogger) Error(msg string, keyvals ...interface{}) { logf(msg, keyvals) }
+func (stdLogger) Enabled(LogLevel) bool                    { return true }
+
+func logf(msg string, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 < len(keyvals) {
+			msg += fmt.Sprintf(" %v=%v", keyvals[i], keyvals[i+1])
+		} else {
+			msg += fmt.Sprintf(" %v", keyvals[i])
+		}
+	}
+	log.Print(msg)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+func (nopLogger) Enabled(LogLevel) bool        { return false }
+
+type filteredLogger struct {
+	logger Logger
+	level  LogLevel
+}
+
+func (l *filteredLogger) Enabled(level LogLevel) bool {
+	return level >= l.level && level < LogLevelNone && logEnabled(l.logger, level)
+}
+
+func (l *filteredLogger) Debug(msg string, keyvals ...interface{}) {
+	if l.level <= LogLevelDebug {
+		l.logger.Debug(msg, keyvals...)
+	}
+}
+
+func (l *filteredLogger) Info(msg string, keyvals ...interface{}) {
+	if l.level <= LogLevelInfo {
+		l.logger.Info(msg, keyvals...)
+	}
+}
+
+func (l *filteredLogger) Error(msg string, keyvals ...interface{}) {
+	if l.level <= LogLevelError {
+		l.logger.Error(msg, keyvals...)
+	}
+}
+
+type sugaredLogger struct {
+	logger SugaredLogger
+}
+
+func (l sugaredLogger) Debug(msg string, keyvals ...interface{}) { l.logger.Debugw(msg, keyvals...) }
+func (l sugaredLogger) Info(msg string, keyvals ...interface{})  { l.logger.Infow(msg, keyvals...) }
+func (l sugaredLogger) Error(msg string, keyvals ...interface{}) { l.logger.Errorw(msg, keyvals...) }
@@ -0,0 +1,44 @@
+//go:build go1.21
+// +build go1.21
+
+package db
+
+import (
+	"context"
+	"log/slog"
+)
+
+// NewSlogLogger returns a Logger writing to a log/slog logger. The level of the
+// slog handler applies.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) Enabled(level LogLevel) bool {
+	switch level {
+	case LogLevelDebug:
+		return l.logger.Enabled(context.Background(), slog.LevelDebug)
+	case LogLevelInfo:
+		return l.logger.Enabled(context.Background(), slog.LevelInfo)
+	case LogLevelError:
+		return l.logger.Enabled(context.Background(), slog.LevelError)
+	default:
+		return false
+	}
+}
+
+func (l slogLogger) Debug(msg string, keyvals ...interface{}) {
+	l.logger.Debug(msg, keyvals...)
+}
+
+func (l slogLogger) Info(msg string, keyvals ...interface{}) {
+	l.logger.Info(msg, keyvals...)
+}
+
+func (l slogLogger) Error(msg string, keyvals ...interface{}) {
+	l.logger.Error(msg, keyvals...)
+}
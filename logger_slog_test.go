@@ -0,0 +1,32 @@
+//go:build go1.21
+// +build go1.21
+
+package db
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelInfo,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	logger := NewSlogLogger(slog.New(handler))
+	assert.False(t, logEnabled(logger, LogLevelDebug))
+	assert.True(t, logEnabled(logger, LogLevelInfo))
+	logger.Debug("hidden")
+	logger.Info("New db", "name", "test")
+	logger.Error("failed")
+	assert.Equal(t, "level=INFO msg=\"New db\" name=test\nlevel=ERROR msg=failed\n", buf.String())
+}
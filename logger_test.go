@@ -0,0 +1,116 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilteredLogger(t *testing.T) {
+	testCases := map[LogLevel][]string{
+		LogLevelDebug: {"debug k v", "info", "error"},
+		LogLevelInfo:  {"info", "error"},
+		LogLevelError: {"error"},
+		LogLevelNone:  nil,
+	}
+	for level, expected := range testCases {
+		logger := &testLogger{}
+		filtered := NewFilteredLogger(logger, level)
+		filtered.Debug("debug", "k", "v")
+		filtered.Info("info")
+		filtered.Error("error")
+		assert.Equal(t, expected, logger.msgs, "level %d", level)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	output, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(output)
+		log.SetFlags(flags)
+	}()
+
+	logger := NewStdLogger(LogLevelInfo)
+	logger.Debug("hidden")
+	logger.Info("New db", "name", "test", "odd")
+	assert.Equal(t, "New db name=test odd\n", buf.String())
+
+	NewNopLogger().Error("hidden")
+	assert.Equal(t, "New db name=test odd\n", buf.String())
+}
+
+// testSugaredLogger records the messages it receives like zap.SugaredLogger.
+type testSugaredLogger struct {
+	msgs []string
+}
+
+func (l *testSugaredLogger) Debugw(msg string, _ ...interface{}) { l.msgs = append(l.msgs, "D "+msg) }
+func (l *testSugaredLogger) Infow(msg string, _ ...interface{})  { l.msgs = append(l.msgs, "I "+msg) }
+func (l *testSugaredLogger) Errorw(msg string, _ ...interface{}) { l.msgs = append(l.msgs, "E "+msg) }
+
+func TestSugaredLogger(t *testing.T) {
+	sugared := &testSugaredLogger{}
+	logger := NewSugaredLogger(sugared)
+	logger.Debug("a")
+	logger.Info("b")
+	logger.Error("c")
+	assert.Equal(t, []string{"D a", "I b", "E c"}, sugared.msgs)
+}
+
+func TestLoggerEnabled(t *testing.T) {
+	logger := NewFilteredLogger(&testLogger{}, LogLevelInfo)
+	assert.False(t, logEnabled(logger, LogLevelDebug))
+	assert.True(t, logEnabled(logger, LogLevelInfo))
+	assert.True(t, logEnabled(logger, LogLevelError))
+	assert.False(t, logEnabled(logger, LogLevelNone))
+
+	// The levels of nested filters combine.
+	nested := NewFilteredLogger(NewFilteredLogger(&testLogger{}, LogLevelError), LogLevelDebug)
+	assert.False(t, logEnabled(nested, LogLevelInfo))
+	assert.True(t, logEnabled(nested, LogLevelError))
+
+	assert.False(t, logEnabled(NewNopLogger(), LogLevelError))
+	assert.True(t, logEnabled(&testLogger{}, LogLevelDebug))
+}
+
+// stringerLogger counts the values formatted by String, like a logger which
+// formats its messages.
+type stringerLogger struct {
+	testLogger
+	formatted int
+}
+
+func (l *stringerLogger) Debug(msg string, keyvals ...interface{}) {
+	for _, v := range keyvals {
+		if s, ok := v.(fmt.Stringer); ok {
+			_ = s.String()
+			l.formatted++
+		}
+	}
+	l.testLogger.Debug(msg, keyvals...)
+}
+
+func TestGoLevelDBLogWrites(t *testing.T) {
+	logger := &stringerLogger{}
+	db, err := NewDB("db", GoLevelDBBackend, t.TempDir(), WithLogger(NewFilteredLogger(logger, LogLevelInfo)))
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Writes are not logged, nor their keys formatted, above LogLevelDebug.
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	assert.Zero(t, logger.formatted)
+
+	db2, err := NewDB("db2", GoLevelDBBackend, t.TempDir(), WithLogger(NewFilteredLogger(logger, LogLevelDebug)))
+	require.NoError(t, err)
+	defer db2.Close()
+	require.NoError(t, db2.SetSync(bz("b"), bz("2")))
+	assert.Equal(t, 1, logger.formatted)
+	assert.Contains(t, logger.msgs, "Write db db2 op set_sync key 62 value_size 1")
+}
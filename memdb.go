@@ -14,32 +14,42 @@ const (
 )
 
 func init() {
-	registerDBCreator(MemDBBackend, func(name, dir string) (DB, error) {
-		return NewMemDB(), nil
+	// The cache size and synced writes do not apply to an in-memory database.
+	registerDBCreator(MemDBBackend, func(name, dir string, opts *Options) (DB, error) {
+		switch {
+		case opts.ReadOnly:
+			return nil, errOption(MemDBBackend, "ReadOnly")
+		case opts.BackendOptions != nil:
+			return nil, errBackendOptions(MemDBBackend, opts.BackendOptions)
+		}
+		db := NewMemDB()
+		db.iters = newIteratorTracker(name, opts)
+		return db, nil
 	}, false)
 }
 
-// item is a btree.Item with byte slices as keys and values
+// item is a B-tree item with byte slices as keys and values. Items are stored by
+// value, so no per-item allocation or type assertion is needed.
 type item struct {
 	key   []byte
 	value []byte
 }
 
-// Less implements btree.Item.
-func (i *item) Less(other btree.Item) bool {
+// itemLess orders items by key.
+func itemLess(a, b item) bool {
 	// this considers nil == []byte{}, but that's ok since we handle nil endpoints
 	// in iterators specially anyway
-	return bytes.Compare(i.key, other.(*item).key) == -1
+	return bytes.Compare(a.key, b.key) == -1
 }
 
 // newKey creates a new key item.
-func newKey(key []byte) *item {
-	return &item{key: key}
+func newKey(key []byte) item {
+	return item{key: key}
 }
 
 // newPair creates a new pair item.
-func newPair(key, value []byte) *item {
-	return &item{key: key, value: value}
+func newPair(key, value []byte) item {
+	return item{key: key, value: value}
 }
 
 // MemDB is an in-memory database backend using a B-tree for storage.
@@ -49,8 +59,10 @@ func newPair(key, value []byte) *item {
 // already specify that keys and values should be considered read-only, but this is especially
 // important with MemDB.
 type MemDB struct {
-	mtx   sync.RWMutex
-	btree *btree.BTree
+	mtx      sync.RWMutex
+	cloneMtx sync.Mutex // serializes Snapshot calls, which hold mtx for reading
+	btree    *btree.BTreeG[item]
+	iters    *iteratorTracker
 }
 
 var _ DB = (*MemDB)(nil)
@@ -58,7 +70,7 @@ var _ DB = (*MemDB)(nil)
 // NewMemDB creates a new in-memory database.
 func NewMemDB() *MemDB {
 	database := &MemDB{
-		btree: btree.New(bTreeDegree),
+		btree: btree.NewG(bTreeDegree, itemLess),
 	}
 	return database
 }
@@ -71,9 +83,9 @@ func (db *MemDB) Get(key []byte) ([]byte, error) {
 	db.mtx.RLock()
 	defer db.mtx.RUnlock()
 
-	i := db.btree.Get(newKey(key))
-	if i != nil {
-		return i.(*item).value, nil
+	i, ok := db.btree.Get(newKey(key))
+	if ok {
+		return i.value, nil
 	}
 	return nil, nil
 }
@@ -89,6 +101,25 @@ func (db *MemDB) Has(key []byte) (bool, error) {
 	return db.btree.Has(newKey(key)), nil
 }
 
+// MultiGet implements MultiGetter, reading all keys under a single lock.
+func (db *MemDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+	}
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		if item, ok := db.btree.Get(newKey(key)); ok {
+			values[i] = item.value
+		}
+	}
+	return values, nil
+}
+
 // Set implements DB.
 func (db *MemDB) Set(key []byte, value []byte) error {
 	if len(key) == 0 {
@@ -104,6 +135,28 @@ func (db *MemDB) Set(key []byte, value []byte) error {
 	return nil
 }
 
+// CompareAndSwap implements ConditionalWriter.
+func (db *MemDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	if new == nil {
+		return false, errValueNil
+	}
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	var current []byte
+	if i, ok := db.btree.Get(newKey(key)); ok {
+		current = i.value
+	}
+	if !casMatch(current, old) {
+		return false, nil
+	}
+	db.set(key, new)
+	return true, nil
+}
+
 // set sets a value without locking the mutex.
 func (db *MemDB) set(key []byte, value []byte) {
 	db.btree.ReplaceOrInsert(newPair(key, value))
@@ -136,10 +189,65 @@ func (db *MemDB) DeleteSync(key []byte) error {
 	return db.Delete(key)
 }
 
+// DeleteRange implements RangeDeleter.
+func (db *MemDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	db.mtx.Lock()
+	defer db.mtx.Unlock()
+
+	db.deleteRange(start, end)
+	return nil
+}
+
+// deleteRange deletes the keys in [start, end) without locking the mutex.
+func (db *MemDB) deleteRange(start, end []byte) {
+	var items []item
+	db.btree.AscendGreaterOrEqual(newKey(start), func(i item) bool {
+		if end != nil && bytes.Compare(i.key, end) >= 0 {
+			return false
+		}
+		items = append(items, i)
+		return true
+	})
+	for _, i := range items {
+		db.btree.Delete(i)
+	}
+}
+
+// EstimateSize implements SizeEstimator, returning the exact size of the keys and
+// values in the range.
+func (db *MemDB) EstimateSize(start, end []byte) (uint64, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return 0, errKeyEmpty
+	}
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+
+	var size uint64
+	db.btree.AscendGreaterOrEqual(newKey(start), func(i item) bool {
+		if end != nil && bytes.Compare(i.key, end) >= 0 {
+			return false
+		}
+		size += uint64(len(i.key) + len(i.value))
+		return true
+	})
+	return size, nil
+}
+
+// Count implements Counter.
+func (db *MemDB) Count() (uint64, error) {
+	db.mtx.RLock()
+	defer db.mtx.RUnlock()
+	return uint64(db.btree.Len()), nil
+}
+
 // Close implements DB.
 func (db *MemDB) Close() error {
 	// Close is a noop since for an in-memory database, we don't have a destination to flush
 	// contents to nor do we want any data loss on invoking Close().
+	db.iters.close()
 	return nil
 }
 
@@ -148,9 +256,8 @@ func (db *MemDB) Print() error {
 	db.mtx.RLock()
 	defer db.mtx.RUnlock()
 
-	db.btree.Ascend(func(i btree.Item) bool {
-		item := i.(*item)
-		fmt.Printf("[%X]:\t[%X]\n", item.key, item.value)
+	db.btree.Ascend(func(i item) bool {
+		fmt.Printf("[%X]:\t[%X]\n", i.key, i.value)
 		return true
 	})
 	return nil
@@ -178,7 +285,7 @@ func (db *MemDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
-	return newMemDBIterator(db, start, end, false), nil
+	return db.iters.track(newMemDBIterator(db, start, end, false), nil)
 }
 
 // ReverseIterator implements DB.
@@ -187,7 +294,7 @@ func (db *MemDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
-	return newMemDBIterator(db, start, end, true), nil
+	return db.iters.track(newMemDBIterator(db, start, end, true), nil)
 }
 
 // IteratorNoMtx makes an iterator with no mutex.
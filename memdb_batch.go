@@ -8,6 +8,8 @@ type opType int
 const (
 	opTypeSet opType = iota + 1
 	opTypeDelete
+	// opTypeDeleteRange stores the range start in key and its end in value.
+	opTypeDeleteRange
 )
 
 type operation struct {
@@ -16,13 +18,26 @@ type operation struct {
 	value []byte
 }
 
+// opsSize returns the total length of the keys and values of ops.
+func opsSize(ops []operation) int {
+	size := 0
+	for _, op := range ops {
+		size += len(op.key) + len(op.value)
+	}
+	return size
+}
+
 // memDBBatch handles in-memory batching.
 type memDBBatch struct {
-	db  *MemDB
-	ops []operation
+	db    *MemDB
+	ops   []operation
+	spare []operation // the cleared operations of a written or closed batch, for Reset
 }
 
-var _ Batch = (*memDBBatch)(nil)
+var (
+	_ Batch         = (*memDBBatch)(nil)
+	_ BatchResetter = (*memDBBatch)(nil)
+)
 
 // newMemDBBatch creates a new memDBBatch
 func newMemDBBatch(db *MemDB) *memDBBatch {
@@ -41,7 +56,7 @@ func (b *memDBBatch) Set(key, value []byte) error {
 		return errValueNil
 	}
 	if b.ops == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.ops = append(b.ops, operation{opTypeSet, key, value})
 	return nil
@@ -53,16 +68,38 @@ func (b *memDBBatch) Delete(key []byte) error {
 		return errKeyEmpty
 	}
 	if b.ops == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
 	return nil
 }
 
+// DeleteRange implements RangeDeleter.
+func (b *memDBBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeDeleteRange, start, end})
+	return nil
+}
+
+// Count implements Batch.
+func (b *memDBBatch) Count() int {
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *memDBBatch) SizeBytes() int {
+	return opsSize(b.ops)
+}
+
 // Write implements Batch.
 func (b *memDBBatch) Write() error {
 	if b.ops == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.db.mtx.Lock()
 	defer b.db.mtx.Unlock()
@@ -73,6 +110,8 @@ func (b *memDBBatch) Write() error {
 			b.db.set(op.key, op.value)
 		case opTypeDelete:
 			b.db.delete(op.key)
+		case opTypeDeleteRange:
+			b.db.deleteRange(op.key, op.value)
 		default:
 			return fmt.Errorf("unknown operation type %v (%v)", op.opType, op)
 		}
@@ -87,8 +126,22 @@ func (b *memDBBatch) WriteSync() error {
 	return b.Write()
 }
 
-// Close implements Batch.
+// Reset implements BatchResetter.
+func (b *memDBBatch) Reset() error {
+	_ = b.Close()
+	b.ops, b.spare = b.spare, nil
+	return nil
+}
+
+// Close implements Batch. The operations are cleared, so that they do not
+// retain their keys and values, and kept for Reset.
 func (b *memDBBatch) Close() error {
-	b.ops = nil
+	if b.ops != nil {
+		for i := range b.ops {
+			b.ops[i] = operation{}
+		}
+		b.spare = b.ops[:0]
+		b.ops = nil
+	}
 	return nil
 }
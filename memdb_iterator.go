@@ -3,8 +3,6 @@ package db
 import (
 	"bytes"
 	"context"
-
-	"github.com/google/btree"
 )
 
 const (
@@ -16,9 +14,10 @@ const (
 
 // memDBIterator is a memDB iterator.
 type memDBIterator struct {
-	ch     <-chan *item
+	ch     <-chan item
 	cancel context.CancelFunc
-	item   *item
+	item   item
+	valid  bool
 	start  []byte
 	end    []byte
 	useMtx bool
@@ -33,7 +32,7 @@ func newMemDBIterator(db *MemDB, start []byte, end []byte, reverse bool) *memDBI
 
 func newMemDBIteratorMtxChoice(db *MemDB, start []byte, end []byte, reverse bool, useMtx bool) *memDBIterator {
 	ctx, cancel := context.WithCancel(context.Background())
-	ch := make(chan *item, chBufferSize)
+	ch := make(chan item, chBufferSize)
 	iter := &memDBIterator{
 		ch:     ch,
 		cancel: cancel,
@@ -55,8 +54,7 @@ func newMemDBIteratorMtxChoice(db *MemDB, start []byte, end []byte, reverse bool
 			skipEqual     []byte
 			abortLessThan []byte
 		)
-		visitor := func(i btree.Item) bool {
-			item := i.(*item)
+		visitor := func(item item) bool {
 			if skipEqual != nil && bytes.Equal(item.key, skipEqual) {
 				skipEqual = nil
 				return true
@@ -95,9 +93,7 @@ func newMemDBIteratorMtxChoice(db *MemDB, start []byte, end []byte, reverse bool
 	}()
 
 	// prime the iterator with the first value, if any
-	if item, ok := <-ch; ok {
-		iter.item = item
-	}
+	iter.item, iter.valid = <-ch
 
 	return iter
 }
@@ -107,7 +103,7 @@ func (i *memDBIterator) Close() error {
 	i.cancel()
 	for range i.ch { // drain channel
 	}
-	i.item = nil
+	i.item, i.valid = item{}, false
 	return nil
 }
 
@@ -118,19 +114,13 @@ func (i *memDBIterator) Domain() ([]byte, []byte) {
 
 // Valid implements Iterator.
 func (i *memDBIterator) Valid() bool {
-	return i.item != nil
+	return i.valid
 }
 
 // Next implements Iterator.
 func (i *memDBIterator) Next() {
 	i.assertIsValid()
-	item, ok := <-i.ch
-	switch {
-	case ok:
-		i.item = item
-	default:
-		i.item = nil
-	}
+	i.item, i.valid = <-i.ch
 }
 
 // Error implements Iterator.
@@ -2,8 +2,25 @@ package db
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+func TestMemDBBackend(t *testing.T) {
+	db, err := NewDB("memdb", MemDBBackend, "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, ok := db.(*MemDB)
+	assert.True(t, ok)
+
+	require.NoError(t, db.Set([]byte("b"), []byte{2}))
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	assertKeyValues(t, db, map[string][]byte{"a": {1}, "b": {2}})
+	assert.Equal(t, "2", db.Stats()["database.size"])
+}
+
 func BenchmarkMemDBRangeScans1M(b *testing.B) {
 	db := NewMemDB()
 	defer db.Close()
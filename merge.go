@@ -0,0 +1,76 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// MergeOperator defines how the values merged into a key are combined, e.g. by
+// adding counters or appending to lists. The backends with native merging
+// (PebbleDB and RocksDB) combine the merged values lazily, when the key is read
+// or compacted, so a merge does not need to read the current value.
+//
+// Merging a value into a missing key sets it to that value. Merge is only
+// called for keys which already have a value, and must be associative, since
+// the native backends may combine operands before the current value is known:
+// Merge(Merge(a, b), c) must equal Merge(a, Merge(b, c)).
+type MergeOperator interface {
+	// Name identifies the operator. The native backends store it, and fail to
+	// open a database with another operator than the one it was written with.
+	Name() string
+
+	// Merge returns the combination of the older value of key and a newer
+	// merged one, as a new slice. It must not modify its arguments. An error
+	// means that a value is invalid for the operator.
+	Merge(key, older, newer []byte) ([]byte, error)
+}
+
+// Merger is implemented by databases which can merge values into keys with a
+// MergeOperator, see NewDB with WithMergeOperator and NewMergeDB.
+type Merger interface {
+	// Merge combines operand with the current value of key, as defined by the
+	// merge operator of the database.
+	// CONTRACT: key, operand readonly []byte
+	Merge(key, operand []byte) error
+}
+
+// Merge merges operand into the value of key in db, see Merger. It returns
+// ErrNotSupported if db does not implement Merger.
+func Merge(db DB, key, operand []byte) error {
+	m, ok := db.(Merger)
+	if !ok {
+		return ErrNotSupported
+	}
+	return m.Merge(key, operand)
+}
+
+var (
+	// AppendOperator is a MergeOperator concatenating the merged values, e.g.
+	// for append-only indexes.
+	AppendOperator MergeOperator = appendOperator{}
+
+	// AddUint64Operator is a MergeOperator adding the merged values as 8-byte
+	// big-endian unsigned integers, wrapping around on overflow, e.g. for
+	// counters.
+	AddUint64Operator MergeOperator = addUint64Operator{}
+)
+
+type appendOperator struct{}
+
+func (appendOperator) Name() string { return "cometbft-db.append" }
+
+func (appendOperator) Merge(_, older, newer []byte) ([]byte, error) {
+	value := make([]byte, 0, len(older)+len(newer))
+	return append(append(value, older...), newer...), nil
+}
+
+type addUint64Operator struct{}
+
+func (addUint64Operator) Name() string { return "cometbft-db.add_uint64" }
+
+func (addUint64Operator) Merge(_, older, newer []byte) ([]byte, error) {
+	if len(older) != 8 || len(newer) != 8 {
+		return nil, fmt.Errorf("add_uint64 merge operands must be 8 bytes, got %d and %d", len(older), len(newer))
+	}
+	return binary.BigEndian.AppendUint64(nil, binary.BigEndian.Uint64(older)+binary.BigEndian.Uint64(newer)), nil
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func u64(v uint64) []byte {
+	return binary.BigEndian.AppendUint64(nil, v)
+}
+
+func TestMergeOperators(t *testing.T) {
+	value, err := AppendOperator.Merge(bz("k"), bz("ab"), bz("c"))
+	require.NoError(t, err)
+	assert.Equal(t, bz("abc"), value)
+
+	value, err = AddUint64Operator.Merge(bz("k"), u64(2), u64(3))
+	require.NoError(t, err)
+	assert.Equal(t, u64(5), value)
+	_, err = AddUint64Operator.Merge(bz("k"), u64(2), bz("3"))
+	assert.Error(t, err)
+}
+
+func TestMerge(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir, WithMergeOperator(AddUint64Operator))
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			err = Merge(db, bz("a"), u64(1))
+			if errors.Is(err, ErrNotSupported) {
+				// The test backends wrapping other databases ignore the options.
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+			checkValue(t, db, bz("a"), u64(1))
+			require.NoError(t, Merge(db, bz("a"), u64(2)))
+			_ = Flush(db)
+			require.NoError(t, Merge(db, bz("a"), u64(3)))
+			require.NoError(t, db.Set(bz("b"), u64(10)))
+			require.NoError(t, Merge(db, bz("b"), u64(5)))
+			_ = Compact(db, nil, nil)
+			checkValue(t, db, bz("a"), u64(6))
+			checkValue(t, db, bz("b"), u64(15))
+
+			require.NoError(t, db.Delete(bz("a")))
+			require.NoError(t, Merge(db, bz("a"), u64(7)))
+			checkValue(t, db, bz("a"), u64(7))
+
+			itr, err := db.Iterator(nil, nil)
+			require.NoError(t, err)
+			defer itr.Close()
+			checkItem(t, itr, bz("a"), u64(7))
+			checkNext(t, itr, true)
+			checkItem(t, itr, bz("b"), u64(15))
+
+			assert.ErrorIs(t, Merge(db, nil, u64(1)), errKeyEmpty)
+		})
+	}
+}
+
+func TestMergePebbleDefault(t *testing.T) {
+	pdb, err := NewPebbleDB("db", t.TempDir())
+	require.NoError(t, err)
+	defer pdb.Close()
+
+	// pebble concatenates the values by default.
+	require.NoError(t, Merge(pdb, bz("a"), bz("1")))
+	require.NoError(t, Merge(pdb, bz("a"), bz("2")))
+	checkValue(t, pdb, bz("a"), bz("12"))
+
+	prefixed := NewPrefixDB(pdb, bz("p"))
+	require.NoError(t, Merge(prefixed, bz("a"), bz("3")))
+	checkValue(t, prefixed, bz("a"), bz("3"))
+
+	assert.ErrorIs(t, Merge(NewMemDB(), bz("a"), bz("1")), ErrNotSupported)
+}
@@ -0,0 +1,175 @@
+package db
+
+import "context"
+
+// MergeDB adds merging with a MergeOperator to any database, by reading the
+// current value of a key and setting the merged one. Backends with native
+// merging, such as PebbleDB and RocksDB opened with WithMergeOperator, do not
+// need it.
+//
+// The merged value is set with CompareAndSwap, and recomputed if the key was
+// written in the meantime, so merges are atomic to the same extent as
+// CompareAndSwap on the underlying database. The other operations pass through.
+type MergeDB struct {
+	db DB
+	op MergeOperator
+}
+
+var (
+	_ DB     = (*MergeDB)(nil)
+	_ Merger = (*MergeDB)(nil)
+)
+
+// NewMergeDB wraps db, merging values with op. Closing the MergeDB closes db.
+func NewMergeDB(db DB, op MergeOperator) *MergeDB {
+	return &MergeDB{db: db, op: op}
+}
+
+// Merge implements Merger.
+func (mdb *MergeDB) Merge(key, operand []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if operand == nil {
+		return errValueNil
+	}
+	for {
+		current, err := mdb.db.Get(key)
+		if err != nil {
+			return err
+		}
+		value := operand
+		if current != nil {
+			value, err = mdb.op.Merge(key, current, operand)
+			if err != nil {
+				return err
+			}
+		}
+		swapped, err := CompareAndSwap(mdb.db, key, current, value)
+		if err != nil || swapped {
+			return err
+		}
+	}
+}
+
+// Get implements DB.
+func (mdb *MergeDB) Get(key []byte) ([]byte, error) {
+	return mdb.db.Get(key)
+}
+
+// Has implements DB.
+func (mdb *MergeDB) Has(key []byte) (bool, error) {
+	return mdb.db.Has(key)
+}
+
+// Set implements DB.
+func (mdb *MergeDB) Set(key []byte, value []byte) error {
+	return mdb.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (mdb *MergeDB) SetSync(key []byte, value []byte) error {
+	return mdb.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (mdb *MergeDB) Delete(key []byte) error {
+	return mdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (mdb *MergeDB) DeleteSync(key []byte) error {
+	return mdb.db.DeleteSync(key)
+}
+
+// Iterator implements DB.
+func (mdb *MergeDB) Iterator(start, end []byte) (Iterator, error) {
+	return mdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (mdb *MergeDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return mdb.db.ReverseIterator(start, end)
+}
+
+// Close implements DB.
+func (mdb *MergeDB) Close() error {
+	return mdb.db.Close()
+}
+
+// NewBatch implements DB. Batches cannot merge values.
+func (mdb *MergeDB) NewBatch() Batch {
+	return mdb.db.NewBatch()
+}
+
+// Print implements DB.
+func (mdb *MergeDB) Print() error {
+	return mdb.db.Print()
+}
+
+// Stats implements DB.
+func (mdb *MergeDB) Stats() map[string]string {
+	return mdb.db.Stats()
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database.
+func (mdb *MergeDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	return CompareAndSwap(mdb.db, key, old, new)
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+func (mdb *MergeDB) Snapshot() (DBReader, error) {
+	return Snapshot(mdb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (mdb *MergeDB) Metrics() (Metrics, error) {
+	return GetMetrics(mdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (mdb *MergeDB) EstimateCount() (uint64, error) {
+	return EstimateCount(mdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (mdb *MergeDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(mdb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (mdb *MergeDB) Compact(start, end []byte) error {
+	return Compact(mdb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (mdb *MergeDB) Flush() error {
+	return Flush(mdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (mdb *MergeDB) Sync() error {
+	return Sync(mdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+func (mdb *MergeDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, mdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (mdb *MergeDB) Checkpoint(path string) error {
+	return Checkpoint(mdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (mdb *MergeDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, mdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which MergeDB passes through.
+func (mdb *MergeDB) Capabilities() Capability {
+	return Capabilities(mdb.db) & (CapSnapshot | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
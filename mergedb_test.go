@@ -0,0 +1,35 @@
+package db
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeDBConcurrent(t *testing.T) {
+	mdb := NewMergeDB(NewMemDB(), AddUint64Operator)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.NoError(t, mdb.Merge(bz("counter"), u64(1)))
+			}
+		}()
+	}
+	wg.Wait()
+	checkValue(t, mdb, bz("counter"), u64(800))
+}
+
+func TestMergeDBErrors(t *testing.T) {
+	mdb := NewMergeDB(NewMemDB(), AddUint64Operator)
+	require.NoError(t, mdb.Set(bz("a"), bz("not a counter")))
+	assert.Error(t, mdb.Merge(bz("a"), u64(1)))
+	checkValue(t, mdb, bz("a"), bz("not a counter"))
+	assert.ErrorIs(t, mdb.Merge(bz("a"), nil), errValueNil)
+
+	assert.Equal(t, CapSnapshot, Capabilities(mdb))
+}
@@ -0,0 +1,173 @@
+package db
+
+import "errors"
+
+// Metrics are typed statistics of a database, normalized across backends so
+// that they can be exported without parsing the backend-specific text of
+// Stats. Statistics which a backend does not track are zero.
+type Metrics struct {
+	// Levels are the levels of the LSM tree, starting with level 0. They are
+	// nil for backends which are not LSM trees.
+	Levels []LevelMetrics
+
+	// DiskSize is the size of the files of the database in bytes.
+	DiskSize uint64
+
+	// MemTableSize is the size in bytes of the writes buffered in memory and
+	// not yet written to the on-disk tables.
+	MemTableSize uint64
+
+	// BlockCache are the metrics of the block cache.
+	BlockCache CacheMetrics
+
+	// Compaction are the metrics of the compactions since the database was
+	// opened.
+	Compaction CompactionMetrics
+
+	// Snapshots is the number of open snapshots.
+	Snapshots int64
+
+	// Iterators is the number of open iterators.
+	Iterators int64
+
+	// WriteStalled is set while the backend stalls writes.
+	WriteStalled bool
+
+	// WriteStalls is the number of write stalls since the database was
+	// opened. For goleveldb, it is the number of writes which were delayed.
+	WriteStalls uint64
+}
+
+// LevelMetrics are the metrics of a level of an LSM tree.
+type LevelMetrics struct {
+	// Files is the number of tables in the level.
+	Files int64
+	// Size is the size of the tables in the level in bytes.
+	Size uint64
+}
+
+// CacheMetrics are the metrics of a cache.
+type CacheMetrics struct {
+	// Size is the size of the cached data in bytes.
+	Size uint64
+	// Hits is the number of lookups found in the cache.
+	Hits uint64
+	// Misses is the number of lookups not found in the cache.
+	Misses uint64
+}
+
+// HitRate returns the fraction of the lookups which were found in the cache,
+// or 0 if there were none.
+func (m CacheMetrics) HitRate() float64 {
+	if m.Hits+m.Misses == 0 {
+		return 0
+	}
+	return float64(m.Hits) / float64(m.Hits+m.Misses)
+}
+
+// CompactionMetrics are the metrics of the compactions of an LSM tree.
+type CompactionMetrics struct {
+	// Count is the number of compactions, including memtable flushes for
+	// backends which count them as compactions.
+	Count uint64
+	// ReadBytes is the number of bytes read by compactions.
+	ReadBytes uint64
+	// WriteBytes is the number of bytes written by compactions.
+	WriteBytes uint64
+	// Running is the number of compactions in progress.
+	Running int64
+	// PendingBytes is the estimated number of bytes which remain to be
+	// compacted for the LSM tree to reach its target shape.
+	PendingBytes uint64
+}
+
+// Files returns the total number of tables in the levels.
+func (m Metrics) Files() int64 {
+	var files int64
+	for _, level := range m.Levels {
+		files += level.Files
+	}
+	return files
+}
+
+// add adds the metrics of o to m, level by level.
+func (m *Metrics) add(o Metrics) {
+	for i, level := range o.Levels {
+		if i == len(m.Levels) {
+			m.Levels = append(m.Levels, LevelMetrics{})
+		}
+		m.Levels[i].Files += level.Files
+		m.Levels[i].Size += level.Size
+	}
+	m.DiskSize += o.DiskSize
+	m.MemTableSize += o.MemTableSize
+	m.BlockCache.Size += o.BlockCache.Size
+	m.BlockCache.Hits += o.BlockCache.Hits
+	m.BlockCache.Misses += o.BlockCache.Misses
+	m.Compaction.Count += o.Compaction.Count
+	m.Compaction.ReadBytes += o.Compaction.ReadBytes
+	m.Compaction.WriteBytes += o.Compaction.WriteBytes
+	m.Compaction.Running += o.Compaction.Running
+	m.Compaction.PendingBytes += o.Compaction.PendingBytes
+	m.Snapshots += o.Snapshots
+	m.Iterators += o.Iterators
+	m.WriteStalled = m.WriteStalled || o.WriteStalled
+	m.WriteStalls += o.WriteStalls
+}
+
+// logMetrics logs the main metrics of a database at LogLevelDebug, along with
+// the number of bytes written to it.
+func logMetrics(logger Logger, msg, name string, written uint64, m Metrics) {
+	logger.Debug(msg,
+		"name", name,
+		"written", written,
+		"files", m.Files(),
+		"disk_size", m.DiskSize,
+		"memtable_size", m.MemTableSize,
+		"block_cache_size", m.BlockCache.Size,
+		"block_cache_hit_rate", m.BlockCache.HitRate(),
+		"compactions", m.Compaction.Count,
+		"compaction_write_bytes", m.Compaction.WriteBytes,
+	)
+}
+
+// MetricsReporter is implemented by databases which report typed metrics.
+type MetricsReporter interface {
+	// Metrics returns the current metrics of the database.
+	Metrics() (Metrics, error)
+}
+
+// GetMetrics returns the current metrics of db, see MetricsReporter. It returns
+// ErrNotSupported if db does not implement MetricsReporter, in which case only
+// Stats is available.
+func GetMetrics(db DB) (Metrics, error) {
+	mr, ok := db.(MetricsReporter)
+	if !ok {
+		return Metrics{}, ErrNotSupported
+	}
+	return mr.Metrics()
+}
+
+// metricsAll sums the metrics of dbs, skipping those which do not support
+// them. It returns ErrNotSupported if none do.
+func metricsAll(dbs []DB) (Metrics, error) {
+	var (
+		total     Metrics
+		supported bool
+	)
+	for _, db := range dbs {
+		m, err := GetMetrics(db)
+		if errors.Is(err, ErrNotSupported) {
+			continue
+		}
+		if err != nil {
+			return Metrics{}, err
+		}
+		total.add(m)
+		supported = true
+	}
+	if !supported {
+		return Metrics{}, ErrNotSupported
+	}
+	return total, nil
+}
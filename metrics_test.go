@@ -0,0 +1,95 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			for i := int64(0); i < 1000; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+			_, err = GetMetrics(db)
+			if errors.Is(err, ErrNotSupported) {
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestGoLevelDBMetrics(t *testing.T) {
+	dir, err := os.MkdirTemp("", "metrics_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := int64(0); i < 1000; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+	}
+	require.NoError(t, db.Compact(nil, nil))
+	m, err := db.Metrics()
+	require.NoError(t, err)
+	assert.Positive(t, m.Files())
+	assert.Positive(t, m.DiskSize)
+	assert.Positive(t, m.Compaction.Count)
+	assert.Positive(t, m.Compaction.WriteBytes)
+}
+
+func TestPebbleDBMetrics(t *testing.T) {
+	dir, err := os.MkdirTemp("", "metrics_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := NewPebbleDB("pebble", dir)
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	m, err := db.Metrics()
+	require.NoError(t, err)
+	assert.Zero(t, m.Files())
+	assert.Positive(t, m.MemTableSize)
+
+	require.NoError(t, db.Flush())
+	for i := 0; i < 3; i++ {
+		checkValue(t, db, bz("a"), bz("1"))
+	}
+	m, err = db.Metrics()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, m.Files())
+	assert.Positive(t, m.DiskSize)
+	assert.Positive(t, m.BlockCache.Hits)
+	assert.Positive(t, m.BlockCache.HitRate())
+
+	// Wrappers over several databases sum the metrics of those supporting them.
+	sdb, err := NewShardedDB([]DB{db, NewMemDB(), db}, HashSharding)
+	require.NoError(t, err)
+	sm, err := sdb.Metrics()
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, sm.Files())
+	assert.Equal(t, 2*m.DiskSize, sm.DiskSize)
+
+	_, err = GetMetrics(NewTieredDB(NewMemDB(), NewMemDB(), TierPolicy{}))
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestCacheMetricsHitRate(t *testing.T) {
+	assert.Zero(t, CacheMetrics{}.HitRate())
+	assert.Equal(t, 0.75, CacheMetrics{Hits: 3, Misses: 1}.HitRate())
+}
@@ -0,0 +1,325 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsDB records Prometheus metrics of the operations on a database: their
+// counts, errors and latencies, the bytes read and written, the batch sizes
+// and the iterator lifetimes. The typed Metrics of the database, if it reports
+// them, are exported as well when the metrics are gathered, e.g. the level
+// sizes, compaction counts and open snapshots of goleveldb. The metrics are
+// labeled with the name of the database, so that several databases can share
+// a registerer.
+type MetricsDB struct {
+	db      DB
+	metrics *dbMetrics
+}
+
+var _ DB = (*MetricsDB)(nil)
+
+// The operations recorded by a MetricsDB, as values of the "op" label.
+const (
+	metricsOpGet             = "get"
+	metricsOpHas             = "has"
+	metricsOpSet             = "set"
+	metricsOpSetSync         = "set_sync"
+	metricsOpDelete          = "delete"
+	metricsOpDeleteSync      = "delete_sync"
+	metricsOpIterator        = "iterator"
+	metricsOpReverseIterator = "reverse_iterator"
+	metricsOpBatchWrite      = "batch_write"
+	metricsOpBatchWriteSync  = "batch_write_sync"
+	metricsOpCompareAndSwap  = "compare_and_swap"
+)
+
+// dbMetrics are the Prometheus metrics of a MetricsDB.
+type dbMetrics struct {
+	operations       *prometheus.CounterVec
+	errors           *prometheus.CounterVec
+	duration         *prometheus.HistogramVec
+	readBytes        prometheus.Counter
+	writtenBytes     prometheus.Counter
+	batchSize        prometheus.Histogram
+	iteratorLifetime prometheus.Histogram
+	backend          *backendCollector
+	latencies        *latencyTracker
+}
+
+func newDBMetrics(db DB, name string) *dbMetrics {
+	labels := prometheus.Labels{"db_name": name}
+	return &dbMetrics{
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "cometbft",
+			Subsystem:   "db",
+			Name:        "operations_total",
+			Help:        "Number of database operations.",
+			ConstLabels: labels,
+		}, []string{"op"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   "cometbft",
+			Subsystem:   "db",
+			Name:        "errors_total",
+			Help:        "Number of failed database operations.",
+			ConstLabels: labels,
+		}, []string{"op"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   "cometbft",
+			Subsystem:   "db",
+			Name:        "operation_duration_seconds",
+			Help:        "Latency of database operations.",
+			ConstLabels: labels,
+			Buckets:     prometheus.ExponentialBuckets(0.00001, 4, 10),
+		}, []string{"op"}),
+		readBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "cometbft",
+			Subsystem:   "db",
+			Name:        "read_bytes_total",
+			Help:        "Bytes of keys and values read from the database.",
+			ConstLabels: labels,
+		}),
+		writtenBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   "cometbft",
+			Subsystem:   "db",
+			Name:        "written_bytes_total",
+			Help:        "Bytes of keys and values written to the database.",
+			ConstLabels: labels,
+		}),
+		batchSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   "cometbft",
+			Subsystem:   "db",
+			Name:        "batch_size_operations",
+			Help:        "Number of operations in the written batches.",
+			ConstLabels: labels,
+			Buckets:     prometheus.ExponentialBuckets(1, 4, 10),
+		}),
+		iteratorLifetime: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   "cometbft",
+			Subsystem:   "db",
+			Name:        "iterator_lifetime_seconds",
+			Help:        "Time between the creation and the closing of iterators.",
+			ConstLabels: labels,
+			Buckets:     prometheus.ExponentialBuckets(0.0001, 4, 12),
+		}),
+		backend:   newBackendCollector(db, name),
+		latencies: newLatencyTracker(),
+	}
+}
+
+func (m *dbMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.operations, m.errors, m.duration, m.readBytes, m.writtenBytes, m.batchSize, m.iteratorLifetime, m.backend,
+	}
+}
+
+// observe records an operation which started at start and returned err.
+func (m *dbMetrics) observe(op string, start time.Time, err error) {
+	elapsed := time.Since(start)
+	m.operations.WithLabelValues(op).Inc()
+	m.duration.WithLabelValues(op).Observe(elapsed.Seconds())
+	m.latencies.observe(op, elapsed)
+	if err != nil {
+		m.errors.WithLabelValues(op).Inc()
+	}
+}
+
+// NewMetricsDB wraps db, registering its metrics labeled with name with
+// registerer. It fails if metrics for name are already registered. Closing the
+// MetricsDB closes db, but does not unregister the metrics.
+func NewMetricsDB(db DB, registerer prometheus.Registerer, name string) (*MetricsDB, error) {
+	metrics := newDBMetrics(db, name)
+	for i, c := range metrics.collectors() {
+		if err := registerer.Register(c); err != nil {
+			for _, registered := range metrics.collectors()[:i] {
+				registerer.Unregister(registered)
+			}
+			return nil, err
+		}
+	}
+	return &MetricsDB{db: db, metrics: metrics}, nil
+}
+
+// Get implements DB.
+func (mdb *MetricsDB) Get(key []byte) ([]byte, error) {
+	start := time.Now()
+	value, err := mdb.db.Get(key)
+	mdb.metrics.observe(metricsOpGet, start, err)
+	mdb.metrics.readBytes.Add(float64(len(value)))
+	return value, err
+}
+
+// Has implements DB.
+func (mdb *MetricsDB) Has(key []byte) (bool, error) {
+	start := time.Now()
+	ok, err := mdb.db.Has(key)
+	mdb.metrics.observe(metricsOpHas, start, err)
+	return ok, err
+}
+
+// Set implements DB.
+func (mdb *MetricsDB) Set(key []byte, value []byte) error {
+	start := time.Now()
+	err := mdb.db.Set(key, value)
+	mdb.observeWrite(metricsOpSet, start, len(key)+len(value), err)
+	return err
+}
+
+// SetSync implements DB.
+func (mdb *MetricsDB) SetSync(key []byte, value []byte) error {
+	start := time.Now()
+	err := mdb.db.SetSync(key, value)
+	mdb.observeWrite(metricsOpSetSync, start, len(key)+len(value), err)
+	return err
+}
+
+// Delete implements DB.
+func (mdb *MetricsDB) Delete(key []byte) error {
+	start := time.Now()
+	err := mdb.db.Delete(key)
+	mdb.observeWrite(metricsOpDelete, start, len(key), err)
+	return err
+}
+
+// DeleteSync implements DB.
+func (mdb *MetricsDB) DeleteSync(key []byte) error {
+	start := time.Now()
+	err := mdb.db.DeleteSync(key)
+	mdb.observeWrite(metricsOpDeleteSync, start, len(key), err)
+	return err
+}
+
+// observeWrite records a write of size bytes, counted if it succeeded.
+func (mdb *MetricsDB) observeWrite(op string, start time.Time, size int, err error) {
+	mdb.metrics.observe(op, start, err)
+	if err == nil {
+		mdb.metrics.writtenBytes.Add(float64(size))
+	}
+}
+
+// Iterator implements DB.
+func (mdb *MetricsDB) Iterator(start, end []byte) (Iterator, error) {
+	now := time.Now()
+	itr, err := mdb.db.Iterator(start, end)
+	mdb.metrics.observe(metricsOpIterator, now, err)
+	if err != nil {
+		return nil, err
+	}
+	return newMetricsDBIterator(mdb.metrics, itr, now), nil
+}
+
+// ReverseIterator implements DB.
+func (mdb *MetricsDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	now := time.Now()
+	itr, err := mdb.db.ReverseIterator(start, end)
+	mdb.metrics.observe(metricsOpReverseIterator, now, err)
+	if err != nil {
+		return nil, err
+	}
+	return newMetricsDBIterator(mdb.metrics, itr, now), nil
+}
+
+// Close implements DB.
+func (mdb *MetricsDB) Close() error {
+	return mdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (mdb *MetricsDB) NewBatch() Batch {
+	return newMetricsDBBatch(mdb.metrics, mdb.db.NewBatch())
+}
+
+// Print implements DB.
+func (mdb *MetricsDB) Print() error {
+	return mdb.db.Print()
+}
+
+// Stats implements DB.
+func (mdb *MetricsDB) Stats() map[string]string {
+	return mdb.db.Stats()
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database.
+func (mdb *MetricsDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	start := time.Now()
+	swapped, err := CompareAndSwap(mdb.db, key, old, new)
+	size := 0
+	if swapped {
+		size = len(key) + len(new)
+	}
+	mdb.observeWrite(metricsOpCompareAndSwap, start, size, err)
+	return swapped, err
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+// Reads of the snapshot are not recorded.
+func (mdb *MetricsDB) Snapshot() (DBReader, error) {
+	return Snapshot(mdb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (mdb *MetricsDB) Metrics() (Metrics, error) {
+	return GetMetrics(mdb.db)
+}
+
+// LatencyStats implements LatencyReporter, with the latencies of the
+// operations by the values of the "op" label. Unlike the Prometheus histograms,
+// they keep their accuracy in the tail, e.g. for the 99.9th percentile.
+func (mdb *MetricsDB) LatencyStats() map[string]LatencyStats {
+	return mdb.metrics.latencies.snapshot()
+}
+
+// ResetLatencyStats implements LatencyReporter. The Prometheus metrics are not
+// reset.
+func (mdb *MetricsDB) ResetLatencyStats() {
+	mdb.metrics.latencies.reset()
+}
+
+// EstimateCount implements CountEstimator.
+func (mdb *MetricsDB) EstimateCount() (uint64, error) {
+	return EstimateCount(mdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (mdb *MetricsDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(mdb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (mdb *MetricsDB) Compact(start, end []byte) error {
+	return Compact(mdb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (mdb *MetricsDB) Flush() error {
+	return Flush(mdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (mdb *MetricsDB) Sync() error {
+	return Sync(mdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+func (mdb *MetricsDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, mdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (mdb *MetricsDB) Checkpoint(path string) error {
+	return Checkpoint(mdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (mdb *MetricsDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, mdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which MetricsDB passes through.
+func (mdb *MetricsDB) Capabilities() Capability {
+	return Capabilities(mdb.db) & (CapSnapshot | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
@@ -0,0 +1,75 @@
+package db
+
+import "time"
+
+// metricsDBBatch wraps a batch of the underlying database, recording its size
+// and the bytes written when it is written.
+type metricsDBBatch struct {
+	metrics *dbMetrics
+	source  Batch
+	size    int
+}
+
+var _ Batch = (*metricsDBBatch)(nil)
+
+func newMetricsDBBatch(metrics *dbMetrics, source Batch) *metricsDBBatch {
+	return &metricsDBBatch{
+		metrics: metrics,
+		source:  source,
+	}
+}
+
+// Set implements Batch.
+func (b *metricsDBBatch) Set(key, value []byte) error {
+	if err := b.source.Set(key, value); err != nil {
+		return err
+	}
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete implements Batch.
+func (b *metricsDBBatch) Delete(key []byte) error {
+	if err := b.source.Delete(key); err != nil {
+		return err
+	}
+	b.size += len(key)
+	return nil
+}
+
+// Count implements Batch.
+func (b *metricsDBBatch) Count() int {
+	return b.source.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *metricsDBBatch) SizeBytes() int {
+	return b.source.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *metricsDBBatch) Write() error {
+	return b.write(metricsOpBatchWrite, b.source.Write)
+}
+
+// WriteSync implements Batch.
+func (b *metricsDBBatch) WriteSync() error {
+	return b.write(metricsOpBatchWriteSync, b.source.WriteSync)
+}
+
+func (b *metricsDBBatch) write(op string, write func() error) error {
+	count := b.source.Count()
+	start := time.Now()
+	err := write()
+	b.metrics.observe(op, start, err)
+	if err == nil {
+		b.metrics.batchSize.Observe(float64(count))
+		b.metrics.writtenBytes.Add(float64(b.size))
+	}
+	return err
+}
+
+// Close implements Batch.
+func (b *metricsDBBatch) Close() error {
+	return b.source.Close()
+}
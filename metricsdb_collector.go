@@ -0,0 +1,108 @@
+package db
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// backendCollector exports the typed Metrics of the database wrapped by a
+// MetricsDB, read when the metrics are gathered, e.g. the level sizes,
+// compaction counts and open snapshots and iterators of goleveldb, rather than
+// the text of Stats. Nothing is exported if the database does not implement
+// MetricsReporter or its metrics cannot be read, e.g. once it is closed.
+type backendCollector struct {
+	db DB
+
+	levelFiles         *prometheus.Desc
+	levelSize          *prometheus.Desc
+	diskSize           *prometheus.Desc
+	memTableSize       *prometheus.Desc
+	blockCacheSize     *prometheus.Desc
+	blockCacheHits     *prometheus.Desc
+	blockCacheMisses   *prometheus.Desc
+	compactions        *prometheus.Desc
+	compactionRead     *prometheus.Desc
+	compactionWritten  *prometheus.Desc
+	compactionsRunning *prometheus.Desc
+	compactionPending  *prometheus.Desc
+	snapshots          *prometheus.Desc
+	iterators          *prometheus.Desc
+	writeStalled       *prometheus.Desc
+	writeStalls        *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*backendCollector)(nil)
+
+func newBackendCollector(db DB, name string) *backendCollector {
+	labels := prometheus.Labels{"db_name": name}
+	desc := func(metric, help string, variableLabels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName("cometbft", "db", metric), help, variableLabels, labels)
+	}
+	return &backendCollector{
+		db:                 db,
+		levelFiles:         desc("level_files", "Number of tables in a level of the LSM tree.", "level"),
+		levelSize:          desc("level_size_bytes", "Size of the tables in a level of the LSM tree.", "level"),
+		diskSize:           desc("disk_size_bytes", "Size of the files of the database."),
+		memTableSize:       desc("memtable_size_bytes", "Size of the writes buffered in memory."),
+		blockCacheSize:     desc("block_cache_size_bytes", "Size of the cached blocks."),
+		blockCacheHits:     desc("block_cache_hits_total", "Number of lookups found in the block cache."),
+		blockCacheMisses:   desc("block_cache_misses_total", "Number of lookups not found in the block cache."),
+		compactions:        desc("compactions_total", "Number of compactions since the database was opened."),
+		compactionRead:     desc("compaction_read_bytes_total", "Bytes read by compactions since the database was opened."),
+		compactionWritten:  desc("compaction_written_bytes_total", "Bytes written by compactions since the database was opened."),
+		compactionsRunning: desc("compactions_running", "Number of compactions in progress."),
+		compactionPending:  desc("compaction_pending_bytes", "Estimated bytes remaining to be compacted."),
+		snapshots:          desc("snapshots", "Number of open snapshots."),
+		iterators:          desc("iterators", "Number of open iterators."),
+		writeStalled:       desc("write_stalled", "Whether the backend stalls writes (1) or not (0)."),
+		writeStalls:        desc("write_stalls_total", "Number of write stalls since the database was opened."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *backendCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		c.levelFiles, c.levelSize, c.diskSize, c.memTableSize, c.blockCacheSize, c.blockCacheHits, c.blockCacheMisses,
+		c.compactions, c.compactionRead, c.compactionWritten, c.compactionsRunning, c.compactionPending, c.snapshots,
+		c.iterators, c.writeStalled, c.writeStalls,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *backendCollector) Collect(ch chan<- prometheus.Metric) {
+	m, err := GetMetrics(c.db)
+	if err != nil {
+		return
+	}
+	gauge := func(desc *prometheus.Desc, value float64, labelValues ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labelValues...)
+	}
+	counter := func(desc *prometheus.Desc, value uint64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(value))
+	}
+	for i, level := range m.Levels {
+		gauge(c.levelFiles, float64(level.Files), strconv.Itoa(i))
+		gauge(c.levelSize, float64(level.Size), strconv.Itoa(i))
+	}
+	gauge(c.diskSize, float64(m.DiskSize))
+	gauge(c.memTableSize, float64(m.MemTableSize))
+	gauge(c.blockCacheSize, float64(m.BlockCache.Size))
+	counter(c.blockCacheHits, m.BlockCache.Hits)
+	counter(c.blockCacheMisses, m.BlockCache.Misses)
+	counter(c.compactions, m.Compaction.Count)
+	counter(c.compactionRead, m.Compaction.ReadBytes)
+	counter(c.compactionWritten, m.Compaction.WriteBytes)
+	gauge(c.compactionsRunning, float64(m.Compaction.Running))
+	gauge(c.compactionPending, float64(m.Compaction.PendingBytes))
+	gauge(c.snapshots, float64(m.Snapshots))
+	gauge(c.iterators, float64(m.Iterators))
+	stalled := 0.0
+	if m.WriteStalled {
+		stalled = 1
+	}
+	gauge(c.writeStalled, stalled)
+	counter(c.writeStalls, m.WriteStalls)
+}
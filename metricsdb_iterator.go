@@ -0,0 +1,71 @@
+package db
+
+import "time"
+
+// metricsDBIterator wraps an iterator of the underlying database, recording
+// the bytes of the items it lands on and its lifetime when it is closed.
+type metricsDBIterator struct {
+	metrics *dbMetrics
+	source  Iterator
+	created time.Time
+	closed  bool
+}
+
+var _ Iterator = (*metricsDBIterator)(nil)
+
+func newMetricsDBIterator(metrics *dbMetrics, source Iterator, created time.Time) *metricsDBIterator {
+	itr := &metricsDBIterator{
+		metrics: metrics,
+		source:  source,
+		created: created,
+	}
+	itr.observeItem()
+	return itr
+}
+
+// observeItem records the bytes of the item the iterator landed on.
+func (itr *metricsDBIterator) observeItem() {
+	if itr.source.Valid() {
+		itr.metrics.readBytes.Add(float64(len(itr.source.Key()) + len(itr.source.Value())))
+	}
+}
+
+// Domain implements Iterator.
+func (itr *metricsDBIterator) Domain() ([]byte, []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *metricsDBIterator) Valid() bool {
+	return itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *metricsDBIterator) Next() {
+	itr.source.Next()
+	itr.observeItem()
+}
+
+// Key implements Iterator.
+func (itr *metricsDBIterator) Key() []byte {
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *metricsDBIterator) Value() []byte {
+	return itr.source.Value()
+}
+
+// Error implements Iterator.
+func (itr *metricsDBIterator) Error() error {
+	return itr.source.Error()
+}
+
+// Close implements Iterator. The lifetime is recorded on the first call.
+func (itr *metricsDBIterator) Close() error {
+	if !itr.closed {
+		itr.closed = true
+		itr.metrics.iteratorLifetime.Observe(time.Since(itr.created).Seconds())
+	}
+	return itr.source.Close()
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsDB(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mdb, err := NewMetricsDB(NewMemDB(), registry, "test")
+	require.NoError(t, err)
+	defer mdb.Close()
+	m := mdb.metrics
+
+	require.NoError(t, mdb.Set(bz("a"), bz("1")))
+	require.NoError(t, mdb.SetSync(bz("b"), bz("22")))
+	checkValue(t, mdb, bz("b"), bz("22"))
+	_, err = mdb.Get(nil)
+	require.Error(t, err)
+	require.NoError(t, mdb.Delete(bz("c")))
+
+	assert.EqualValues(t, 1, testutil.ToFloat64(m.operations.WithLabelValues("set")))
+	assert.EqualValues(t, 1, testutil.ToFloat64(m.operations.WithLabelValues("set_sync")))
+	assert.EqualValues(t, 2, testutil.ToFloat64(m.operations.WithLabelValues("get")))
+	assert.EqualValues(t, 1, testutil.ToFloat64(m.errors.WithLabelValues("get")))
+	assert.EqualValues(t, 0, testutil.ToFloat64(m.errors.WithLabelValues("set")))
+	assert.EqualValues(t, 6, testutil.ToFloat64(m.writtenBytes))
+	assert.EqualValues(t, 2, testutil.ToFloat64(m.readBytes))
+
+	batch := mdb.NewBatch()
+	require.NoError(t, batch.Set(bz("c"), bz("333")))
+	require.NoError(t, batch.Delete(bz("a")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	assert.EqualValues(t, 1, testutil.ToFloat64(m.operations.WithLabelValues("batch_write")))
+	assert.EqualValues(t, 11, testutil.ToFloat64(m.writtenBytes))
+
+	itr, err := mdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("b"), bz("22"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("c"), bz("333"))
+	checkNext(t, itr, false)
+	time.Sleep(time.Millisecond)
+	require.NoError(t, itr.Close())
+	assert.EqualValues(t, 9, testutil.ToFloat64(m.readBytes))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	histograms := make(map[string]uint64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			require.Equal(t, "db_name", metric.GetLabel()[0].GetName())
+			require.Equal(t, "test", metric.GetLabel()[0].GetValue())
+			if h := metric.GetHistogram(); h != nil && len(metric.GetLabel()) == 1 {
+				histograms[family.GetName()] = h.GetSampleCount()
+			}
+		}
+	}
+	assert.Equal(t, map[string]uint64{
+		"cometbft_db_batch_size_operations":     1,
+		"cometbft_db_iterator_lifetime_seconds": 1,
+	}, histograms)
+}
+
+func TestMetricsDBRegister(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	mdb, err := NewMetricsDB(NewMemDB(), registry, "a")
+	require.NoError(t, err)
+	defer mdb.Close()
+	mdb2, err := NewMetricsDB(NewMemDB(), registry, "b")
+	require.NoError(t, err)
+	defer mdb2.Close()
+
+	_, err = NewMetricsDB(NewMemDB(), registry, "a")
+	assert.Error(t, err)
+}
+
+func TestMetricsDBBackend(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	registry := prometheus.NewRegistry()
+	mdb, err := NewMetricsDB(db, registry, "test")
+	require.NoError(t, err)
+	defer mdb.Close()
+
+	for i := int64(0); i < 1000; i++ {
+		require.NoError(t, mdb.Set(int642Bytes(i), int642Bytes(i)))
+	}
+	require.NoError(t, mdb.Compact(nil, nil))
+	itr, err := mdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer itr.Close()
+	snap, err := mdb.Snapshot()
+	require.NoError(t, err)
+	defer snap.Close()
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			switch {
+			case metric.GetGauge() != nil:
+				values[family.GetName()] += metric.GetGauge().GetValue()
+			case metric.GetCounter() != nil && len(metric.GetLabel()) == 1:
+				values[family.GetName()] += metric.GetCounter().GetValue()
+			}
+		}
+	}
+	assert.Positive(t, values["cometbft_db_level_files"])
+	assert.Positive(t, values["cometbft_db_level_size_bytes"])
+	assert.Positive(t, values["cometbft_db_disk_size_bytes"])
+	assert.Positive(t, values["cometbft_db_compactions_total"])
+	assert.Positive(t, values["cometbft_db_compaction_written_bytes_total"])
+	assert.EqualValues(t, 1, values["cometbft_db_snapshots"])
+	assert.EqualValues(t, 1, values["cometbft_db_iterators"])
+}
@@ -0,0 +1,213 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrMigrationMismatch is returned by the verification of Migrate if the
+// destination database differs from the source.
+var ErrMigrationMismatch = errors.New("migrated database differs from the source")
+
+// MigrateProgress is the progress of a Migrate, reported after every batch
+// written to the destination database.
+type MigrateProgress struct {
+	// Keys is the number of keys copied so far, including when resuming.
+	Keys uint64
+	// Bytes is the size of the keys and values copied so far.
+	Bytes uint64
+	// LastKey is the last key copied. The slice must not be retained.
+	LastKey []byte
+}
+
+// MigrateOptions are the options of Migrate.
+type MigrateOptions struct {
+	// BatchSize is the approximate size in bytes of the batches written to the
+	// destination database, 4 MiB if 0.
+	BatchSize int
+
+	// Progress, if not nil, is called after every batch written.
+	Progress func(MigrateProgress)
+
+	// Resume continues an interrupted migration: the keys up to the last one
+	// of the destination database, which was written last, are not copied
+	// again. The destination must not have been written to otherwise.
+	Resume bool
+
+	// Verify compares the destination database with the source once the keys
+	// are copied, failing with ErrMigrationMismatch if they differ.
+	Verify bool
+}
+
+// Migrate copies all keys from src to dst, e.g. to change the backend of a
+// database, in key order and in batches. The keys are read from a Snapshot of
+// src if it supports them, so src may stay in use, although the writes made
+// during a migration are not copied; otherwise src must not be written to.
+// Existing keys of dst which are not in src are left as is, so dst should
+// usually be empty, or the destination of an interrupted migration with
+// Resume.
+func Migrate(ctx context.Context, src, dst DB, opts MigrateOptions) error {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = backupBatchSize
+	}
+	var reader DBReader = src
+	snap, err := Snapshot(src)
+	switch {
+	case err == nil:
+		defer snap.Close()
+		reader = snap
+	case !errors.Is(err, ErrNotSupported):
+		return err
+	}
+
+	var start []byte
+	if opts.Resume {
+		if start, err = lastKey(dst); err != nil {
+			return err
+		}
+	}
+	if err := migrate(ctx, reader, dst, start, batchSize, opts.Progress); err != nil {
+		return err
+	}
+	if opts.Verify {
+		return verifyMigration(ctx, reader, dst)
+	}
+	return nil
+}
+
+// lastKey returns the last key of db, or nil if it is empty.
+func lastKey(db DB) ([]byte, error) {
+	itr, err := db.ReverseIterator(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+	if !itr.Valid() {
+		return nil, itr.Error()
+	}
+	return cp(itr.Key()), nil
+}
+
+// migrate copies the keys of src from start to dst. The keys before start are
+// counted in the progress, without their size.
+func migrate(ctx context.Context, src DBReader, dst DB, start []byte, batchSize int, progress func(MigrateProgress)) error {
+	var p MigrateProgress
+	if start != nil {
+		// Count the keys copied before, excluding start which is copied again
+		// in case its batch was not written completely.
+		itr, err := src.Iterator(nil, start)
+		if err != nil {
+			return err
+		}
+		for ; itr.Valid(); itr.Next() {
+			p.Keys++
+		}
+		err = itr.Error()
+		itr.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	itr, err := src.Iterator(start, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	batch := dst.NewBatch()
+	defer func() { batch.Close() }()
+	pending := 0
+	write := func(key []byte, sync bool) error {
+		if sync {
+			err = batch.WriteSync()
+		} else {
+			err = batch.Write()
+		}
+		if err != nil {
+			return err
+		}
+		batch.Close()
+		batch = dst.NewBatch()
+		p.Bytes += uint64(pending)
+		p.LastKey = key
+		pending = 0
+		if progress != nil {
+			progress(p)
+		}
+		return nil
+	}
+	var key []byte // the last key, copied since the iterator is done after the last batch
+	for ; itr.Valid(); itr.Next() {
+		key = append(key[:0], itr.Key()...)
+		value := itr.Value()
+		if err := batch.Set(itr.Key(), value); err != nil {
+			return err
+		}
+		p.Keys++
+		pending += len(key) + len(value)
+		if pending < batchSize {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := write(key, false); err != nil {
+			return err
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return err
+	}
+	// The last batch is written even if empty, to sync the previous ones.
+	return write(key, true)
+}
+
+// verifyMigration compares the keys and values of src and dst.
+func verifyMigration(ctx context.Context, src DBReader, dst DB) error {
+	sitr, err := src.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer sitr.Close()
+	ditr, err := dst.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer ditr.Close()
+
+	for n := 0; sitr.Valid() && ditr.Valid(); n++ {
+		if n%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if !bytes.Equal(sitr.Key(), ditr.Key()) {
+			key, side := sitr.Key(), "source"
+			if bytes.Compare(ditr.Key(), key) < 0 {
+				key, side = ditr.Key(), "destination"
+			}
+			return fmt.Errorf("%w: key %X in %s only", ErrMigrationMismatch, key, side)
+		}
+		if !bytes.Equal(sitr.Value(), ditr.Value()) {
+			return fmt.Errorf("%w: different values of key %X", ErrMigrationMismatch, sitr.Key())
+		}
+		sitr.Next()
+		ditr.Next()
+	}
+	if err := sitr.Error(); err != nil {
+		return err
+	}
+	if err := ditr.Error(); err != nil {
+		return err
+	}
+	switch {
+	case sitr.Valid():
+		return fmt.Errorf("%w: key %X in source only", ErrMigrationMismatch, sitr.Key())
+	case ditr.Valid():
+		return fmt.Errorf("%w: key %X in destination only", ErrMigrationMismatch, ditr.Key())
+	}
+	return nil
+}
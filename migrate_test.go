@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemDB()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, src.Set([]byte(fmt.Sprintf("key%03d", i)), []byte(fmt.Sprintf("value%d", i))))
+	}
+	dst, err := NewGoLevelDB("dst", t.TempDir())
+	require.NoError(t, err)
+	defer dst.Close()
+
+	var progress []MigrateProgress
+	err = Migrate(ctx, src, dst, MigrateOptions{
+		BatchSize: 100,
+		Verify:    true,
+		Progress: func(p MigrateProgress) {
+			p.LastKey = cp(p.LastKey)
+			progress = append(progress, p)
+		},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, progress)
+	last := progress[len(progress)-1]
+	assert.EqualValues(t, 100, last.Keys)
+	assert.Equal(t, bz("key099"), last.LastKey)
+	assert.Greater(t, len(progress), 5)
+	checkValue(t, dst, bz("key042"), bz("value42"))
+
+	// A mismatch fails the verification.
+	require.NoError(t, dst.Set(bz("key042"), bz("other")))
+	err = verifyMigration(ctx, src, dst)
+	assert.ErrorIs(t, err, ErrMigrationMismatch)
+	assert.ErrorContains(t, err, "different values")
+	require.NoError(t, dst.Set(bz("key042"), bz("value42")))
+	require.NoError(t, dst.Set(bz("zzz"), bz("extra")))
+	assert.ErrorContains(t, verifyMigration(ctx, src, dst), "in destination only")
+	require.NoError(t, dst.Delete(bz("zzz")))
+	require.NoError(t, dst.Delete(bz("key000")))
+	assert.ErrorContains(t, verifyMigration(ctx, src, dst), "key 6B6579303030 in source only")
+}
+
+func TestMigrateResume(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemDB()
+	for i := 0; i < 10; i++ {
+		require.NoError(t, src.Set([]byte{byte(i + 1)}, bz("v")))
+	}
+
+	// An interrupted migration resumes from the last key written.
+	dst := NewMemDB()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, dst.Set([]byte{byte(i + 1)}, bz("v")))
+	}
+	var p MigrateProgress
+	err := Migrate(ctx, src, dst, MigrateOptions{
+		Resume:   true,
+		Verify:   true,
+		Progress: func(progress MigrateProgress) { p = progress },
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 10, p.Keys)
+	assert.EqualValues(t, 7*2, p.Bytes)
+
+	// Migrations stop when the context is canceled.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = Migrate(cctx, src, NewMemDB(), MigrateOptions{BatchSize: 1})
+	assert.ErrorIs(t, err, context.Canceled)
+}
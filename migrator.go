@@ -0,0 +1,411 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// migratorSampleSize is the default number of keys verified by Migrator.Verify.
+const migratorSampleSize = 1000
+
+// ErrMigrationIncomplete is returned by Migrator.Cutover while the keys of the
+// primary database are still being copied.
+var ErrMigrationIncomplete = errors.New("migration backfill is not complete")
+
+// MigratorOptions are the options of NewMigrator.
+type MigratorOptions struct {
+	// BatchSize is the approximate size in bytes of the batches of keys copied
+	// to the secondary database, 4 MiB if 0.
+	BatchSize int
+
+	// SampleSize is the number of copied keys, sampled uniformly, which Verify
+	// compares between the databases, 1000 if 0.
+	SampleSize int
+}
+
+// MigratorStatus is the progress of a Migrator.
+type MigratorStatus struct {
+	// Keys is the number of keys of the primary database copied so far.
+	Keys uint64
+	// Bytes is the size of the keys and values copied so far.
+	Bytes uint64
+	// Skipped is the number of keys which were not copied, since they were
+	// written through the Migrator while being copied.
+	Skipped uint64
+	// LastKey is the last key copied.
+	LastKey []byte
+	// Pending is the number of writes not yet mirrored to the secondary
+	// database.
+	Pending int
+	// Done is set once all the keys are copied. The databases have converged
+	// when Done is set and Pending is 0.
+	Done bool
+	// Err is the error which stopped the copy, if any.
+	Err error
+}
+
+// MigratorVerification is the result of Migrator.Verify.
+type MigratorVerification struct {
+	// Checked is the number of sampled keys compared.
+	Checked int
+	// Changed is the number of sampled keys which were written during the
+	// verification, and not compared.
+	Changed int
+	// Mismatched are the compared keys whose values differ.
+	Mismatched [][]byte
+}
+
+// Migrator migrates a live database to another one, e.g. of another backend,
+// without downtime: it copies the existing keys of the primary database to the
+// secondary one in the background, while the writes made through DB are
+// mirrored to the secondary with a MirrorDB. Keys written while the copy is
+// running are not overwritten by it. Once the copy is done, Verify compares a
+// sample of the keys, and Cutover switches the reads and writes to the
+// secondary.
+//
+// All the writes to the primary database must go through DB once the Migrator
+// is created, otherwise they are not mirrored.
+type Migrator struct {
+	primary   DB
+	secondary DB
+	mirror    *MirrorDB
+	batchSize int
+
+	// mtx serializes the copied batches with the mirrored writes, so that a
+	// copied key never overwrites a newer mirrored one.
+	mtx        sync.Mutex
+	status     MigratorStatus
+	dirty      map[string]struct{} // mirrored keys after status.LastKey, until the copy is done
+	sample     [][]byte
+	sampleSize int
+	rng        *rand.Rand
+
+	cancel context.CancelFunc
+	done   chan struct{} // closed once the copy has stopped
+}
+
+// NewMigrator starts migrating primary to secondary, which should be empty.
+// Closing the Migrator closes both databases.
+func NewMigrator(primary, secondary DB, opts MigratorOptions) *Migrator {
+	m := &Migrator{
+		primary:    primary,
+		secondary:  secondary,
+		batchSize:  opts.BatchSize,
+		dirty:      make(map[string]struct{}),
+		sampleSize: opts.SampleSize,
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())), //nolint:gosec
+		done:       make(chan struct{}),
+	}
+	if m.batchSize <= 0 {
+		m.batchSize = backupBatchSize
+	}
+	if m.sampleSize <= 0 {
+		m.sampleSize = migratorSampleSize
+	}
+	m.mirror = NewMirrorDB(primary, &migratorTarget{DB: secondary, m: m})
+
+	var ctx context.Context
+	ctx, m.cancel = context.WithCancel(context.Background())
+	go m.backfill(ctx)
+	return m
+}
+
+// DB returns the database to read and write through during the migration: the
+// primary, with its writes mirrored, and the secondary after Cutover.
+func (m *Migrator) DB() DB {
+	return m.mirror
+}
+
+// Status returns the progress of the migration.
+func (m *Migrator) Status() MigratorStatus {
+	m.mtx.Lock()
+	status := m.status
+	status.LastKey = cp(m.status.LastKey)
+	m.mtx.Unlock()
+	status.Pending = len(m.mirror.queue)
+	return status
+}
+
+// Wait blocks until all the keys are copied, or ctx is done, and returns the
+// error which stopped the copy, if any.
+func (m *Migrator) Wait(ctx context.Context) error {
+	select {
+	case <-m.done:
+		return m.Status().Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Verify compares the values of a uniform sample of the copied keys in both
+// databases, once the writes made before the call are mirrored. The keys
+// written during the verification are not compared. It can be called while
+// the keys are being copied, and must be called before Cutover.
+func (m *Migrator) Verify(ctx context.Context) (MigratorVerification, error) {
+	var v MigratorVerification
+	m.mtx.Lock()
+	keys := append([][]byte(nil), m.sample...)
+	m.mtx.Unlock()
+
+	before := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := m.primary.Get(key)
+		if err != nil {
+			return v, err
+		}
+		before[i] = value
+	}
+	if err := m.mirror.Drain(ctx); err != nil {
+		return v, err
+	}
+	for i, key := range keys {
+		got, err := m.secondary.Get(key)
+		if err != nil {
+			return v, err
+		}
+		after, err := m.primary.Get(key)
+		if err != nil {
+			return v, err
+		}
+		if !equalValues(before[i], after) {
+			v.Changed++
+			continue
+		}
+		v.Checked++
+		if !equalValues(got, after) {
+			v.Mismatched = append(v.Mismatched, key)
+		}
+	}
+	return v, nil
+}
+
+// equalValues reports whether two values returned by Get are equal, telling
+// missing keys from empty values.
+func equalValues(a, b []byte) bool {
+	return (a == nil) == (b == nil) && bytes.Equal(a, b)
+}
+
+// Cutover switches the reads and writes to the secondary database, once all
+// the keys are copied and a Verify finds no mismatched keys, see
+// MirrorDB.Cutover. It fails with ErrMigrationIncomplete while the keys are
+// being copied, and with ErrMigrationMismatch if the verification fails,
+// leaving the primary database active.
+func (m *Migrator) Cutover(ctx context.Context) error {
+	status := m.Status()
+	switch {
+	case status.Err != nil:
+		return status.Err
+	case !status.Done:
+		return ErrMigrationIncomplete
+	}
+	v, err := m.Verify(ctx)
+	if err != nil {
+		return err
+	}
+	if len(v.Mismatched) > 0 {
+		return fmt.Errorf("%w: %d of %d sampled keys differ", ErrMigrationMismatch, len(v.Mismatched), v.Checked)
+	}
+	return m.mirror.Cutover(ctx)
+}
+
+// Close stops the copy if it is still running, and closes both databases,
+// after mirroring the pending writes.
+func (m *Migrator) Close() error {
+	m.cancel()
+	<-m.done
+	return m.mirror.Close()
+}
+
+// backfill copies the keys of the primary database to the secondary one, from
+// a Snapshot if the primary supports them.
+func (m *Migrator) backfill(ctx context.Context) {
+	defer close(m.done)
+	err := m.copyKeys(ctx)
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.status.Done = err == nil
+	m.status.Err = err
+	m.dirty = nil
+}
+
+func (m *Migrator) copyKeys(ctx context.Context) error {
+	var src DBReader = m.primary
+	snap, err := Snapshot(m.primary)
+	switch {
+	case err == nil:
+		defer snap.Close()
+		src = snap
+	case !errors.Is(err, ErrNotSupported):
+		return err
+	}
+	itr, err := src.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	var (
+		ops     []operation
+		pending int
+	)
+	for ; itr.Valid(); itr.Next() {
+		key, value := itr.Key(), itr.Value()
+		ops = append(ops, operation{opTypeSet, cp(key), cp(value)})
+		pending += len(key) + len(value)
+		if pending < m.batchSize {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := m.copyBatch(ops); err != nil {
+			return err
+		}
+		ops, pending = nil, 0
+	}
+	if err := itr.Error(); err != nil {
+		return err
+	}
+	return m.copyBatch(ops)
+}
+
+// copyBatch writes ops to the secondary database, except for the keys which
+// were mirrored since the copy started.
+func (m *Migrator) copyBatch(ops []operation) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	batch := m.secondary.NewBatch()
+	defer batch.Close()
+	var copied, bytes uint64
+	for _, op := range ops {
+		m.addSample(op.key)
+		if _, ok := m.dirty[string(op.key)]; ok {
+			m.status.Skipped++
+			continue
+		}
+		if err := batch.Set(op.key, op.value); err != nil {
+			return err
+		}
+		copied++
+		bytes += uint64(len(op.key) + len(op.value))
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	m.status.Keys += copied
+	m.status.Bytes += bytes
+	m.status.LastKey = ops[len(ops)-1].key
+	// The keys up to the last one copied are not copied again.
+	for key := range m.dirty {
+		if key <= string(m.status.LastKey) {
+			delete(m.dirty, key)
+		}
+	}
+	return nil
+}
+
+// addSample adds a copied key to the sample of Verify, keeping a uniform sample
+// of sampleSize keys with reservoir sampling. The caller must hold mtx.
+func (m *Migrator) addSample(key []byte) {
+	n := m.status.Keys + m.status.Skipped + uint64(len(m.sample))
+	switch {
+	case len(m.sample) < m.sampleSize:
+		m.sample = append(m.sample, key)
+	case m.rng.Int63n(int64(n)+1) < int64(m.sampleSize):
+		m.sample[m.rng.Intn(m.sampleSize)] = key
+	}
+}
+
+// markDirty records a key mirrored to the secondary database, so that the copy
+// does not overwrite it. The caller must hold mtx.
+func (m *Migrator) markDirty(key []byte) {
+	if m.dirty == nil || (m.status.LastKey != nil && bytes.Compare(key, m.status.LastKey) <= 0) {
+		return
+	}
+	m.dirty[string(key)] = struct{}{}
+}
+
+// migratorTarget is the secondary database of the MirrorDB of a Migrator,
+// recording the mirrored keys.
+type migratorTarget struct {
+	DB
+	m *Migrator
+}
+
+func (t *migratorTarget) write(key []byte, fn func() error) error {
+	t.m.mtx.Lock()
+	defer t.m.mtx.Unlock()
+	t.m.markDirty(key)
+	return fn()
+}
+
+// Set implements DB.
+func (t *migratorTarget) Set(key, value []byte) error {
+	return t.write(key, func() error { return t.DB.Set(key, value) })
+}
+
+// SetSync implements DB.
+func (t *migratorTarget) SetSync(key, value []byte) error {
+	return t.write(key, func() error { return t.DB.SetSync(key, value) })
+}
+
+// Delete implements DB.
+func (t *migratorTarget) Delete(key []byte) error {
+	return t.write(key, func() error { return t.DB.Delete(key) })
+}
+
+// DeleteSync implements DB.
+func (t *migratorTarget) DeleteSync(key []byte) error {
+	return t.write(key, func() error { return t.DB.DeleteSync(key) })
+}
+
+// NewBatch implements DB.
+func (t *migratorTarget) NewBatch() Batch {
+	return &migratorTargetBatch{Batch: t.DB.NewBatch(), m: t.m}
+}
+
+// migratorTargetBatch records the keys of a mirrored batch when it is written.
+type migratorTargetBatch struct {
+	Batch
+	m    *Migrator
+	keys [][]byte
+}
+
+// Set implements Batch.
+func (b *migratorTargetBatch) Set(key, value []byte) error {
+	b.keys = append(b.keys, key)
+	return b.Batch.Set(key, value)
+}
+
+// Delete implements Batch.
+func (b *migratorTargetBatch) Delete(key []byte) error {
+	b.keys = append(b.keys, key)
+	return b.Batch.Delete(key)
+}
+
+// Write implements Batch.
+func (b *migratorTargetBatch) Write() error {
+	return b.write(b.Batch.Write)
+}
+
+// WriteSync implements Batch.
+func (b *migratorTargetBatch) WriteSync() error {
+	return b.write(b.Batch.WriteSync)
+}
+
+func (b *migratorTargetBatch) write(fn func() error) error {
+	b.m.mtx.Lock()
+	defer b.m.mtx.Unlock()
+	for _, key := range b.keys {
+		b.m.markDirty(key)
+	}
+	return fn()
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator(t *testing.T) {
+	primary, secondary := NewMemDB(), NewMemDB()
+	for i := int64(0); i < 1000; i++ {
+		require.NoError(t, primary.Set(int642Bytes(i), int642Bytes(i)))
+	}
+	m := NewMigrator(primary, secondary, MigratorOptions{BatchSize: 256, SampleSize: 100})
+	defer m.Close()
+
+	// Writes made during the backfill are mirrored, and not overwritten by it.
+	db := m.DB()
+	for i := int64(0); i < 1000; i += 10 {
+		require.NoError(t, db.Set(int642Bytes(i), bz("updated")))
+	}
+	require.NoError(t, db.Delete(int642Bytes(1)))
+	batch := db.NewBatch()
+	require.NoError(t, batch.Set(bz("new"), bz("value")))
+	require.NoError(t, batch.Delete(int642Bytes(2)))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, m.Wait(ctx))
+	status := m.Status()
+	assert.True(t, status.Done)
+	// The snapshot copied may include the writes made above.
+	assert.InDelta(t, 1000, status.Keys+status.Skipped, 2)
+
+	v, err := m.Verify(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 100, v.Checked+v.Changed)
+	assert.Empty(t, v.Mismatched)
+
+	require.NoError(t, m.Cutover(ctx))
+	assertSameContents(t, primary, secondary)
+	checkValue(t, secondary, int642Bytes(10), bz("updated"))
+	checkValue(t, secondary, int642Bytes(1), nil)
+	checkValue(t, secondary, int642Bytes(2), nil)
+	checkValue(t, secondary, bz("new"), bz("value"))
+
+	require.NoError(t, db.Set(bz("after"), bz("cutover")))
+	checkValue(t, secondary, bz("after"), bz("cutover"))
+	checkValue(t, primary, bz("after"), nil)
+}
+
+func TestMigratorCutoverMismatch(t *testing.T) {
+	primary, secondary := NewMemDB(), NewMemDB()
+	require.NoError(t, primary.Set(bz("a"), bz("1")))
+	m := NewMigrator(primary, secondary, MigratorOptions{})
+	defer m.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, m.Wait(ctx))
+
+	// A write bypassing the Migrator is not mirrored.
+	require.NoError(t, secondary.Set(bz("a"), bz("2")))
+	err := m.Cutover(ctx)
+	assert.ErrorIs(t, err, ErrMigrationMismatch)
+	assert.Same(t, primary, m.mirror.Active())
+}
@@ -0,0 +1,396 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// mirrorQueueSize is the number of writes that may be pending for the secondary
+// before writers are blocked.
+const mirrorQueueSize = 1024
+
+// errMirrorClosed is returned when writing to a closed MirrorDB.
+var errMirrorClosed = fmt.Errorf("mirror db has been closed: %w", ErrClosed)
+
+// MirrorDB writes synchronously to a primary database and replays the same
+// writes asynchronously, in order, on a secondary database. Reads are served by
+// the primary. It is meant for migrating between backends without downtime:
+// once the secondary holds a copy of the data (previous contents have to be
+// copied separately), Cutover switches all reads and writes to it.
+//
+// A write is acknowledged once it is applied to the primary. If the secondary
+// falls behind by more than mirrorQueueSize writes, writers block until it
+// catches up; reads are never blocked by the secondary. A failed write on the secondary is not retried: the first such
+// error is reported by Drain and Cutover, and the secondary should then be
+// considered out of sync.
+type MirrorDB struct {
+	// writeMtx serializes the writes, so that they are replayed on the
+	// secondary in the order they were applied, along with Drain, Cutover and
+	// Close. Reads do not take it.
+	writeMtx  sync.Mutex
+	primary   DB
+	secondary DB
+	active    atomic.Pointer[DB] // the database serving reads and writes
+	isClosed  bool               // guarded by writeMtx
+
+	queue chan mirrorOp
+	done  chan struct{} // closed once the replay goroutine has exited
+
+	errMtx sync.Mutex
+	err    error
+}
+
+var _ DB = (*MirrorDB)(nil)
+
+// mirrorOp is a unit of replay on the secondary: a single write, a batch, or, if
+// flushed is set, a marker signalled once all previous writes were replayed.
+type mirrorOp struct {
+	ops     []operation
+	sync    bool
+	flushed chan struct{}
+}
+
+// NewMirrorDB creates a MirrorDB serving primary and mirroring its writes (made
+// through the MirrorDB) to secondary. Closing the MirrorDB closes both.
+func NewMirrorDB(primary, secondary DB) *MirrorDB {
+	mdb := &MirrorDB{
+		primary:   primary,
+		secondary: secondary,
+		queue:     make(chan mirrorOp, mirrorQueueSize),
+		done:      make(chan struct{}),
+	}
+	mdb.active.Store(&mdb.primary)
+	go mdb.replay()
+	return mdb
+}
+
+// replay applies the queued writes to the secondary until the queue is closed.
+func (mdb *MirrorDB) replay() {
+	defer close(mdb.done)
+	for op := range mdb.queue {
+		if op.flushed != nil {
+			close(op.flushed)
+			continue
+		}
+		if err := mdb.apply(op); err != nil {
+			mdb.errMtx.Lock()
+			if mdb.err == nil {
+				mdb.err = fmt.Errorf("mirror db: secondary write failed: %w", err)
+			}
+			mdb.errMtx.Unlock()
+		}
+	}
+}
+
+func (mdb *MirrorDB) apply(op mirrorOp) error {
+	var batch Batch
+	if len(op.ops) > 1 {
+		batch = mdb.secondary.NewBatch()
+		defer batch.Close()
+	}
+	for _, o := range op.ops {
+		var err error
+		switch {
+		case batch != nil && o.opType == opTypeSet:
+			err = batch.Set(o.key, o.value)
+		case batch != nil && o.opType == opTypeDelete:
+			err = batch.Delete(o.key)
+		case o.opType == opTypeSet && op.sync:
+			err = mdb.secondary.SetSync(o.key, o.value)
+		case o.opType == opTypeSet:
+			err = mdb.secondary.Set(o.key, o.value)
+		case o.opType == opTypeDelete && op.sync:
+			err = mdb.secondary.DeleteSync(o.key)
+		case o.opType == opTypeDelete:
+			err = mdb.secondary.Delete(o.key)
+		default:
+			err = fmt.Errorf("unknown operation type %v (%v)", o.opType, o)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if batch == nil {
+		return nil
+	}
+	if op.sync {
+		return batch.WriteSync()
+	}
+	return batch.Write()
+}
+
+// write applies ops to the active database with fn and, before cutover, queues
+// them for the secondary. writeMtx keeps the replay order identical to the
+// order of the writes on the primary.
+func (mdb *MirrorDB) write(ops []operation, sync bool, fn func(DB) error) error {
+	mdb.writeMtx.Lock()
+	defer mdb.writeMtx.Unlock()
+	if mdb.isClosed {
+		return errMirrorClosed
+	}
+	if err := fn(mdb.Active()); err != nil {
+		return err
+	}
+	if !mdb.isCutover() {
+		mdb.queue <- mirrorOp{ops: ops, sync: sync}
+	}
+	return nil
+}
+
+// isCutover reports whether the secondary has become the active database.
+func (mdb *MirrorDB) isCutover() bool {
+	return mdb.active.Load() == &mdb.secondary
+}
+
+// Active returns the database currently serving reads: the primary, or the
+// secondary after Cutover.
+func (mdb *MirrorDB) Active() DB {
+	return *mdb.active.Load()
+}
+
+// Drain blocks until all writes acknowledged before the call have been applied
+// to the secondary, or ctx is done. It returns the first secondary write error,
+// if any.
+func (mdb *MirrorDB) Drain(ctx context.Context) error {
+	mdb.writeMtx.Lock()
+	if mdb.isClosed {
+		mdb.writeMtx.Unlock()
+		return errMirrorClosed
+	}
+	if mdb.isCutover() {
+		mdb.writeMtx.Unlock()
+		return mdb.replayErr()
+	}
+	flushed := make(chan struct{})
+	select {
+	case mdb.queue <- mirrorOp{flushed: flushed}:
+	case <-ctx.Done():
+		mdb.writeMtx.Unlock()
+		return ctx.Err()
+	}
+	mdb.writeMtx.Unlock()
+
+	select {
+	case <-flushed:
+		return mdb.replayErr()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Cutover waits for the secondary to catch up and then makes it the active
+// database: from then on, reads and writes go to the secondary only and the
+// primary is no longer updated. Writers are blocked while the secondary catches
+// up, while reads keep being served by the primary. Cutover fails, leaving the primary active, if ctx is done first or a
+// secondary write has failed.
+func (mdb *MirrorDB) Cutover(ctx context.Context) error {
+	mdb.writeMtx.Lock()
+	defer mdb.writeMtx.Unlock()
+	if mdb.isClosed {
+		return errMirrorClosed
+	}
+	if mdb.isCutover() {
+		return nil
+	}
+
+	flushed := make(chan struct{})
+	select {
+	case mdb.queue <- mirrorOp{flushed: flushed}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if err := mdb.replayErr(); err != nil {
+		return err
+	}
+
+	close(mdb.queue)
+	<-mdb.done
+	mdb.active.Store(&mdb.secondary)
+	return nil
+}
+
+func (mdb *MirrorDB) replayErr() error {
+	mdb.errMtx.Lock()
+	defer mdb.errMtx.Unlock()
+	return mdb.err
+}
+
+// Get implements DB.
+func (mdb *MirrorDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	return mdb.Active().Get(key)
+}
+
+// Has implements DB.
+func (mdb *MirrorDB) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	return mdb.Active().Has(key)
+}
+
+// Set implements DB.
+func (mdb *MirrorDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	ops := []operation{{opTypeSet, cp(key), cp(value)}}
+	return mdb.write(ops, false, func(db DB) error { return db.Set(key, value) })
+}
+
+// SetSync implements DB.
+func (mdb *MirrorDB) SetSync(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	ops := []operation{{opTypeSet, cp(key), cp(value)}}
+	return mdb.write(ops, true, func(db DB) error { return db.SetSync(key, value) })
+}
+
+// Delete implements DB.
+func (mdb *MirrorDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	ops := []operation{{opTypeDelete, cp(key), nil}}
+	return mdb.write(ops, false, func(db DB) error { return db.Delete(key) })
+}
+
+// DeleteSync implements DB.
+func (mdb *MirrorDB) DeleteSync(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	ops := []operation{{opTypeDelete, cp(key), nil}}
+	return mdb.write(ops, true, func(db DB) error { return db.DeleteSync(key) })
+}
+
+// Iterator implements DB.
+func (mdb *MirrorDB) Iterator(start, end []byte) (Iterator, error) {
+	return mdb.Active().Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (mdb *MirrorDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return mdb.Active().ReverseIterator(start, end)
+}
+
+// Close implements DB. Pending writes are replayed on the secondary before both
+// databases are closed.
+func (mdb *MirrorDB) Close() error {
+	mdb.writeMtx.Lock()
+	defer mdb.writeMtx.Unlock()
+	if mdb.isClosed {
+		return nil
+	}
+	mdb.isClosed = true
+	if !mdb.isCutover() {
+		close(mdb.queue)
+		<-mdb.done
+	}
+	err := mdb.primary.Close()
+	if serr := mdb.secondary.Close(); err == nil {
+		err = serr
+	}
+	return err
+}
+
+// NewBatch implements DB.
+func (mdb *MirrorDB) NewBatch() Batch {
+	return newMirrorDBBatch(mdb)
+}
+
+// Print implements DB.
+func (mdb *MirrorDB) Print() error {
+	return mdb.Active().Print()
+}
+
+// Stats implements DB. It returns the stats of the active database, along with
+// the number of writes pending for the secondary.
+func (mdb *MirrorDB) Stats() map[string]string {
+	active := mdb.active.Load()
+	isCutover := active == &mdb.secondary
+
+	stats := (*active).Stats()
+	if stats == nil {
+		stats = make(map[string]string)
+	}
+	stats["mirror.pending"] = strconv.Itoa(len(mdb.queue))
+	stats["mirror.cutover"] = strconv.FormatBool(isCutover)
+	return stats
+}
+
+// Metrics implements MetricsReporter, with the metrics of the active database.
+func (mdb *MirrorDB) Metrics() (Metrics, error) {
+	return GetMetrics(mdb.Active())
+}
+
+// Count implements Counter, counting the keys of the active database.
+func (mdb *MirrorDB) Count() (uint64, error) {
+	return Count(mdb.Active())
+}
+
+// EstimateCount implements CountEstimator, estimating the keys of the active
+// database.
+func (mdb *MirrorDB) EstimateCount() (uint64, error) {
+	return EstimateCount(mdb.Active())
+}
+
+// EstimateSize implements SizeEstimator, estimating the range on the active
+// database.
+func (mdb *MirrorDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(mdb.Active(), start, end)
+}
+
+// Compact implements Compacter, compacting the active database.
+func (mdb *MirrorDB) Compact(start, end []byte) error {
+	return Compact(mdb.Active(), start, end)
+}
+
+// Flush implements Flusher, flushing the active database.
+func (mdb *MirrorDB) Flush() error {
+	return Flush(mdb.Active())
+}
+
+// Sync implements Syncer, syncing the active database. Writes still queued for
+// the secondary are not waited for.
+func (mdb *MirrorDB) Sync() error {
+	return Sync(mdb.Active())
+}
+
+// Backup implements Backupper, backing up the active database.
+func (mdb *MirrorDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, mdb.Active(), targetDir)
+}
+
+// Checkpoint implements Checkpointer, checkpointing the active database.
+func (mdb *MirrorDB) Checkpoint(path string) error {
+	return Checkpoint(mdb.Active(), path)
+}
+
+// HealthCheck implements HealthChecker, checking the active database.
+func (mdb *MirrorDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, mdb.Active())
+}
+
+// Capabilities implements CapabilityReporter, with the features of the active
+// database which MirrorDB passes through.
+func (mdb *MirrorDB) Capabilities() Capability {
+	return Capabilities(mdb.Active()) & (CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
@@ -0,0 +1,105 @@
+package db
+
+import "fmt"
+
+// mirrorDBBatch buffers operations, and applies them as a single batch on the
+// active database before queueing them for the secondary.
+type mirrorDBBatch struct {
+	db  *MirrorDB
+	ops []operation
+}
+
+var _ Batch = (*mirrorDBBatch)(nil)
+
+func newMirrorDBBatch(db *MirrorDB) *mirrorDBBatch {
+	return &mirrorDBBatch{
+		db:  db,
+		ops: []operation{},
+	}
+}
+
+// Set implements Batch.
+func (b *mirrorDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeSet, cp(key), cp(value)})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *mirrorDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, cp(key), nil})
+	return nil
+}
+
+// Count implements Batch.
+func (b *mirrorDBBatch) Count() int {
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *mirrorDBBatch) SizeBytes() int {
+	return opsSize(b.ops)
+}
+
+// Write implements Batch.
+func (b *mirrorDBBatch) Write() error {
+	return b.write(false)
+}
+
+// WriteSync implements Batch.
+func (b *mirrorDBBatch) WriteSync() error {
+	return b.write(true)
+}
+
+func (b *mirrorDBBatch) write(sync bool) error {
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	err := b.db.write(b.ops, sync, func(db DB) error {
+		batch := db.NewBatch()
+		defer batch.Close()
+		for _, op := range b.ops {
+			var err error
+			switch op.opType {
+			case opTypeSet:
+				err = batch.Set(op.key, op.value)
+			case opTypeDelete:
+				err = batch.Delete(op.key)
+			default:
+				err = fmt.Errorf("unknown operation type %v (%v)", op.opType, op)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if sync {
+			return batch.WriteSync()
+		}
+		return batch.Write()
+	})
+	if err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// Close implements Batch.
+func (b *mirrorDBBatch) Close() error {
+	b.ops = nil
+	return nil
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMirrorDBDrain(t *testing.T) {
+	primary, secondary := NewMemDB(), NewMemDB()
+	mdb := NewMirrorDB(primary, secondary)
+	defer mdb.Close()
+
+	var wg sync.WaitGroup
+	for w := int64(0); w < 4; w++ {
+		wg.Add(1)
+		go func(w int64) {
+			defer wg.Done()
+			for i := int64(0); i < 100; i++ {
+				assert.NoError(t, mdb.Set(int642Bytes(w*100+i), int642Bytes(i)))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	batch := mdb.NewBatch()
+	require.NoError(t, batch.Delete(int642Bytes(0)))
+	require.NoError(t, batch.Set(bz("batched"), bz("value")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	require.NoError(t, mdb.DeleteSync(int642Bytes(1)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, mdb.Drain(ctx))
+	assertSameContents(t, primary, secondary)
+	checkValue(t, secondary, bz("batched"), bz("value"))
+	checkValue(t, secondary, int642Bytes(0), nil)
+	assert.Equal(t, "0", mdb.Stats()["mirror.pending"])
+}
+
+func TestMirrorDBCutover(t *testing.T) {
+	primary, secondary := NewMemDB(), NewMemDB()
+	mdb := NewMirrorDB(primary, secondary)
+	defer mdb.Close()
+
+	require.NoError(t, mdb.Set(bz("a"), bz("1")))
+	assert.Same(t, primary, mdb.Active())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, mdb.Cutover(ctx))
+	assert.Same(t, secondary, mdb.Active())
+	assert.Equal(t, "true", mdb.Stats()["mirror.cutover"])
+
+	// Writes now only go to the secondary.
+	require.NoError(t, mdb.Set(bz("b"), bz("2")))
+	checkValue(t, mdb, bz("a"), bz("1"))
+	checkValue(t, mdb, bz("b"), bz("2"))
+	checkValue(t, primary, bz("b"), nil)
+
+	require.NoError(t, mdb.Cutover(ctx))
+	require.NoError(t, mdb.Drain(ctx))
+}
+
+// failingDB fails every write.
+type failingDB struct {
+	*MemDB
+}
+
+func (db failingDB) Set([]byte, []byte) error {
+	return errors.New("write failed")
+}
+
+func TestMirrorDBSecondaryError(t *testing.T) {
+	primary := NewMemDB()
+	mdb := NewMirrorDB(primary, failingDB{NewMemDB()})
+	defer mdb.Close()
+
+	// The primary write succeeds, the error surfaces on Drain and Cutover.
+	require.NoError(t, mdb.Set(bz("a"), bz("1")))
+	checkValue(t, primary, bz("a"), bz("1"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.Error(t, mdb.Drain(ctx))
+	require.Error(t, mdb.Cutover(ctx))
+	assert.Same(t, primary, mdb.Active())
+}
+
+func TestMirrorDBClosed(t *testing.T) {
+	primary, secondary := NewMemDB(), NewMemDB()
+	mdb := NewMirrorDB(primary, secondary)
+	require.NoError(t, mdb.Set(bz("a"), bz("1")))
+	require.NoError(t, mdb.Close())
+
+	// Pending writes were replayed before closing.
+	checkValue(t, secondary, bz("a"), bz("1"))
+	require.Error(t, mdb.Set(bz("b"), bz("2")))
+	require.Error(t, mdb.Drain(context.Background()))
+	require.NoError(t, mdb.Close())
+}
+
+// stalledDB blocks every write until unblock is closed.
+type stalledDB struct {
+	*MemDB
+	unblock chan struct{}
+}
+
+func (db stalledDB) Set(key, value []byte) error {
+	<-db.unblock
+	return db.MemDB.Set(key, value)
+}
+
+func TestMirrorDBStalledSecondary(t *testing.T) {
+	primary := NewMemDB()
+	secondary := stalledDB{NewMemDB(), make(chan struct{})}
+	mdb := NewMirrorDB(primary, secondary)
+	defer mdb.Close()
+
+	// Fill the queue until a writer is blocked on it.
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		for i := int64(0); i < mirrorQueueSize+2; i++ {
+			assert.NoError(t, mdb.Set(int642Bytes(i), int642Bytes(i)))
+		}
+	}()
+	require.Eventually(t, func() bool {
+		return len(mdb.queue) == mirrorQueueSize
+	}, 5*time.Second, time.Millisecond)
+
+	// Reads are still served by the primary.
+	read := make(chan struct{})
+	go func() {
+		defer close(read)
+		checkValue(t, mdb, int642Bytes(0), int642Bytes(0))
+		_, err := mdb.Has(int642Bytes(1))
+		assert.NoError(t, err)
+		assert.Equal(t, "false", mdb.Stats()["mirror.cutover"])
+	}()
+	select {
+	case <-read:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reads blocked by the stalled secondary")
+	}
+
+	close(secondary.unblock)
+	<-written
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, mdb.Drain(ctx))
+	assertSameContents(t, primary, secondary)
+}
+
+// assertSameContents checks that a and b hold the same key/value pairs.
+func assertSameContents(t *testing.T, a, b DB) {
+	t.Helper()
+	itrA, err := a.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer itrA.Close()
+	itrB, err := b.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer itrB.Close()
+	for ; itrA.Valid(); itrA.Next() {
+		require.True(t, itrB.Valid(), "missing key %X", itrA.Key())
+		require.Equal(t, itrA.Key(), itrB.Key())
+		require.Equal(t, itrA.Value(), itrB.Value())
+		itrB.Next()
+	}
+	require.False(t, itrB.Valid())
+}
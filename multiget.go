@@ -0,0 +1,147 @@
+package db
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// multiGetChunkSize is the number of keys per goroutine of the MultiGet
+// fallback, which starts one for every chunk of keys up to GOMAXPROCS. Smaller
+// requests are read sequentially.
+const multiGetChunkSize = 64
+
+// MultiGetter is implemented by databases which can read many keys at once,
+// e.g. within a single read transaction or a native multi-read call.
+type MultiGetter interface {
+	// MultiGet returns the values of keys, in the same order. The value of a
+	// missing key is nil. Empty keys are not allowed.
+	MultiGet(keys [][]byte) ([][]byte, error)
+}
+
+// MultiGetError reports the keys of a multi-read which could not be read.
+type MultiGetError struct {
+	// Errs are the errors of the reads of the keys, in the same order, with
+	// nil for the keys read successfully.
+	Errs []error
+}
+
+// Error implements error.
+func (e *MultiGetError) Error() string {
+	var (
+		failed int
+		first  error
+	)
+	for _, err := range e.Errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d of %d reads failed, first: %v", failed, len(e.Errs), first)
+}
+
+// Unwrap returns the errors of the failed reads, so that errors.Is and
+// errors.As match any of them.
+func (e *MultiGetError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// MultiGet returns the values of keys in db, in the same order, with nil for
+// missing keys. It uses the native multi-read of db if it implements
+// MultiGetter, and otherwise fans the Gets out over up to GOMAXPROCS
+// goroutines with ConcurrentMultiGet. If any read fails an error is returned,
+// a *MultiGetError for the fallback, and the values are discarded.
+func MultiGet(db DB, keys [][]byte) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+	}
+	if mg, ok := db.(MultiGetter); ok {
+		return mg.MultiGet(keys)
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if n := (len(keys) + multiGetChunkSize - 1) / multiGetChunkSize; n < workers {
+		workers = n
+	}
+	values, err := concurrentMultiGet(db, keys, workers)
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// ConcurrentMultiGet returns the values of keys in db, in the same order, with
+// nil for missing keys, reading them with Gets from up to concurrency
+// goroutines, or GOMAXPROCS if not positive. Unlike MultiGet, it never uses
+// the native multi-read of db: it is meant for backends whose reads block on
+// disk, where more reads in flight than CPUs keep the disk queue busy, e.g.
+// for index lookups.
+//
+// Every key is read even if some reads fail. The failures are reported by a
+// *MultiGetError, and the values of the other keys are still returned.
+func ConcurrentMultiGet(db DB, keys [][]byte, concurrency int) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+	}
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	return concurrentMultiGet(db, keys, concurrency)
+}
+
+// concurrentMultiGet reads keys from db with up to workers goroutines, each
+// taking the next unread key, so that slow reads do not hold up the others.
+func concurrentMultiGet(db DB, keys [][]byte, workers int) ([][]byte, error) {
+	if workers > len(keys) {
+		workers = len(keys)
+	}
+	values := make([][]byte, len(keys))
+	errs := make([]error, len(keys))
+	var (
+		next   int64 = -1
+		failed int32
+		wg     sync.WaitGroup
+	)
+	read := func() {
+		for {
+			i := int(atomic.AddInt64(&next, 1))
+			if i >= len(keys) {
+				return
+			}
+			values[i], errs[i] = db.Get(keys[i])
+			if errs[i] != nil {
+				atomic.StoreInt32(&failed, 1)
+			}
+		}
+	}
+	if workers <= 1 {
+		read()
+	} else {
+		wg.Add(workers)
+		for w := 0; w < workers; w++ {
+			go func() {
+				defer wg.Done()
+				read()
+			}()
+		}
+		wg.Wait()
+	}
+	if failed != 0 {
+		return values, &MultiGetError{Errs: errs}
+	}
+	return values, nil
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiGet(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			// Enough keys for the fallback to read in parallel.
+			n := int64(4 * multiGetChunkSize)
+			for i := int64(0); i < n; i += 2 {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+
+			keys := make([][]byte, 0, n)
+			for i := n - 1; i >= 0; i-- {
+				keys = append(keys, int642Bytes(i))
+			}
+			values, err := MultiGet(db, keys)
+			require.NoError(t, err)
+			require.Len(t, values, len(keys))
+			for j, key := range keys {
+				i := int64(binary.BigEndian.Uint64(key))
+				if i%2 == 0 {
+					assert.Equal(t, int642Bytes(i), values[j], "key %d", i)
+				} else {
+					assert.Nil(t, values[j], "key %d", i)
+				}
+			}
+
+			values, err = MultiGet(db, nil)
+			require.NoError(t, err)
+			assert.Empty(t, values)
+
+			_, err = MultiGet(db, [][]byte{bz("a"), {}})
+			assert.Equal(t, errKeyEmpty, err)
+		})
+	}
+}
+
+func TestMultiGetError(t *testing.T) {
+	errFault := fmt.Errorf("fault")
+	for _, n := range []int{1, 4 * multiGetChunkSize} {
+		fdb := NewFaultDB(NewMemDB())
+		keys := make([][]byte, n)
+		for i := range keys {
+			keys[i] = int642Bytes(int64(i))
+			require.NoError(t, fdb.Set(keys[i], bz("v")))
+		}
+		fdb.Inject(Fault{Ops: FaultGet, KeyPrefix: keys[n-1], Err: errFault})
+
+		values, err := MultiGet(fdb, keys)
+		assert.ErrorIs(t, err, errFault)
+		assert.Nil(t, values)
+	}
+}
+
+func TestConcurrentMultiGet(t *testing.T) {
+	errFault := fmt.Errorf("fault")
+	fdb := NewFaultDB(NewMemDB())
+	keys := make([][]byte, 0, 300)
+	for i := 0; i < 100; i++ {
+		for _, prefix := range []string{"a", "b", "c"} {
+			key := bz(fmt.Sprintf("%s%03d", prefix, i))
+			keys = append(keys, key)
+			if i%2 == 0 {
+				require.NoError(t, fdb.Set(key, key))
+			}
+		}
+	}
+	keys = append(keys, bz("b"))
+	fdb.Inject(Fault{Ops: FaultGet, KeyPrefix: bz("b"), Err: errFault})
+
+	for _, concurrency := range []int{0, 1, 32, 1000} {
+		values, err := ConcurrentMultiGet(fdb, keys, concurrency)
+		assert.ErrorIs(t, err, errFault)
+		var merr *MultiGetError
+		require.ErrorAs(t, err, &merr)
+		require.Len(t, merr.Errs, len(keys))
+		assert.Contains(t, err.Error(), "101 of 301 reads failed")
+		require.Len(t, values, len(keys))
+		for i, key := range keys {
+			switch {
+			case key[0] == 'b':
+				assert.ErrorIs(t, merr.Errs[i], errFault)
+				assert.Nil(t, values[i])
+			case (i/3)%2 == 0:
+				assert.NoError(t, merr.Errs[i])
+				assert.Equal(t, key, values[i])
+			default:
+				assert.NoError(t, merr.Errs[i])
+				assert.Nil(t, values[i])
+			}
+		}
+	}
+
+	fdb.Clear()
+	values, err := ConcurrentMultiGet(fdb, keys[:3], 8)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{bz("a000"), bz("b000"), bz("c000")}, values)
+
+	_, err = ConcurrentMultiGet(fdb, [][]byte{bz("a"), {}}, 8)
+	assert.Equal(t, errKeyEmpty, err)
+}
+
+func TestShardedDBMultiGet(t *testing.T) {
+	shards := []DB{NewMemDB(), NewFaultDB(NewMemDB()), NewMemDB()}
+	sdb, err := NewShardedDB(shards, RangeSharding(bz("b"), bz("c")))
+	require.NoError(t, err)
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, sdb.Set(bz(key), bz(key)))
+	}
+
+	values, err := MultiGet(sdb, [][]byte{bz("c"), bz("x"), bz("a"), bz("b")})
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{bz("c"), nil, bz("a"), bz("b")}, values)
+
+	errFault := fmt.Errorf("fault")
+	shards[1].(*FaultDB).Inject(Fault{Ops: FaultGet, Err: errFault})
+	_, err = MultiGet(sdb, [][]byte{bz("a"), bz("b")})
+	assert.ErrorIs(t, err, errFault)
+}
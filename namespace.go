@@ -0,0 +1,401 @@
+package db
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// namespaceRegistryPrefix prefixes the keys listing the namespaces of a
+	// Manager, by name.
+	namespaceRegistryPrefix = []byte{0x00}
+	// namespaceDataPrefix prefixes the keys of the namespaces, which follow
+	// with the length of the namespace name as a uvarint and the name, so that
+	// no namespace prefix is a prefix of another.
+	namespaceDataPrefix = []byte{0x01}
+
+	errNamespaceDropped = fmt.Errorf("%w: namespace dropped", ErrClosed)
+)
+
+// Manager opens isolated namespaces, e.g. for the modules or tenants of an
+// application, within a single physical database. Each namespace is a DB of
+// its own, with its own stats, and can be dropped as a whole with a range
+// deletion. Keys written to the physical database directly must not start
+// with the 0x00 or 0x01 bytes, which are used by the Manager.
+type Manager struct {
+	db DB
+
+	mtx        sync.Mutex
+	namespaces map[string]*Namespace
+}
+
+// NewManager returns a manager of the namespaces within db. Closing the
+// Manager closes db.
+func NewManager(db DB) *Manager {
+	return &Manager{db: db, namespaces: make(map[string]*Namespace)}
+}
+
+// Namespace returns the namespace called name, creating it if needed. The same
+// handle is returned for every call with the same name, until the namespace is
+// dropped.
+func (m *Manager) Namespace(name string) (*Namespace, error) {
+	if name == "" {
+		return nil, errors.New("namespace name cannot be empty")
+	}
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if ns, ok := m.namespaces[name]; ok {
+		return ns, nil
+	}
+	key := namespaceRegistryKey(name)
+	ok, err := m.db.Has(key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := m.db.SetSync(key, []byte(name)); err != nil {
+			return nil, err
+		}
+	}
+	ns := &Namespace{name: name, pdb: NewPrefixDB(m.db, namespacePrefix(name))}
+	m.namespaces[name] = ns
+	return ns, nil
+}
+
+// Namespaces returns the names of the namespaces in the database, sorted.
+func (m *Manager) Namespaces() ([]string, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	itr, err := PrefixIterator(m.db, namespaceRegistryPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+	var names []string
+	for ; itr.Valid(); itr.Next() {
+		names = append(names, string(itr.Value()))
+	}
+	if err := itr.Error(); err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Drop deletes the namespace called name with all its keys, with a range
+// deletion if the physical database supports it. The handles of the namespace
+// fail with ErrClosed afterwards. Dropping a missing namespace does nothing.
+func (m *Manager) Drop(name string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if ns, ok := m.namespaces[name]; ok {
+		ns.dropped.Store(true)
+		delete(m.namespaces, name)
+	}
+	// The data is deleted first, so that the namespace stays listed, and can
+	// be dropped again, if the deletion fails.
+	prefix := namespacePrefix(name)
+	if err := DeleteRange(m.db, prefix, prefixEnd(prefix)); err != nil {
+		return err
+	}
+	return m.db.DeleteSync(namespaceRegistryKey(name))
+}
+
+// Close closes the physical database. The namespaces cannot be used afterwards.
+func (m *Manager) Close() error {
+	return m.db.Close()
+}
+
+func namespaceRegistryKey(name string) []byte {
+	return append(cp(namespaceRegistryPrefix), name...)
+}
+
+func namespacePrefix(name string) []byte {
+	prefix := binary.AppendUvarint(cp(namespaceDataPrefix), uint64(len(name)))
+	return append(prefix, name...)
+}
+
+// Namespace is a namespace of a Manager, see Manager.Namespace.
+type Namespace struct {
+	name    string
+	pdb     *PrefixDB
+	dropped atomic.Bool
+
+	reads        atomic.Uint64
+	writes       atomic.Uint64
+	readBytes    atomic.Uint64
+	writtenBytes atomic.Uint64
+}
+
+var _ DB = (*Namespace)(nil)
+
+// Name returns the name of the namespace.
+func (ns *Namespace) Name() string {
+	return ns.name
+}
+
+func (ns *Namespace) check() error {
+	if ns.dropped.Load() {
+		return errNamespaceDropped
+	}
+	return nil
+}
+
+func (ns *Namespace) written(key, value []byte) {
+	ns.writes.Add(1)
+	ns.writtenBytes.Add(uint64(len(key) + len(value)))
+}
+
+// Get implements DB.
+func (ns *Namespace) Get(key []byte) ([]byte, error) {
+	if err := ns.check(); err != nil {
+		return nil, err
+	}
+	value, err := ns.pdb.Get(key)
+	if err == nil {
+		ns.reads.Add(1)
+		ns.readBytes.Add(uint64(len(value)))
+	}
+	return value, err
+}
+
+// Has implements DB.
+func (ns *Namespace) Has(key []byte) (bool, error) {
+	if err := ns.check(); err != nil {
+		return false, err
+	}
+	ok, err := ns.pdb.Has(key)
+	if err == nil {
+		ns.reads.Add(1)
+	}
+	return ok, err
+}
+
+// Set implements DB.
+func (ns *Namespace) Set(key []byte, value []byte) error {
+	if err := ns.check(); err != nil {
+		return err
+	}
+	err := ns.pdb.Set(key, value)
+	if err == nil {
+		ns.written(key, value)
+	}
+	return err
+}
+
+// SetSync implements DB.
+func (ns *Namespace) SetSync(key []byte, value []byte) error {
+	if err := ns.check(); err != nil {
+		return err
+	}
+	err := ns.pdb.SetSync(key, value)
+	if err == nil {
+		ns.written(key, value)
+	}
+	return err
+}
+
+// Delete implements DB.
+func (ns *Namespace) Delete(key []byte) error {
+	if err := ns.check(); err != nil {
+		return err
+	}
+	err := ns.pdb.Delete(key)
+	if err == nil {
+		ns.written(key, nil)
+	}
+	return err
+}
+
+// DeleteSync implements DB.
+func (ns *Namespace) DeleteSync(key []byte) error {
+	if err := ns.check(); err != nil {
+		return err
+	}
+	err := ns.pdb.DeleteSync(key)
+	if err == nil {
+		ns.written(key, nil)
+	}
+	return err
+}
+
+// Iterator implements DB.
+func (ns *Namespace) Iterator(start, end []byte) (Iterator, error) {
+	if err := ns.check(); err != nil {
+		return nil, err
+	}
+	ns.reads.Add(1)
+	return ns.pdb.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (ns *Namespace) ReverseIterator(start, end []byte) (Iterator, error) {
+	if err := ns.check(); err != nil {
+		return nil, err
+	}
+	ns.reads.Add(1)
+	return ns.pdb.ReverseIterator(start, end)
+}
+
+// Close implements DB. It does not close the physical database, which is
+// closed by Manager.Close, and the namespace remains usable.
+func (ns *Namespace) Close() error {
+	return nil
+}
+
+// NewBatch implements DB. Batches of a dropped namespace fail to be written.
+func (ns *Namespace) NewBatch() Batch {
+	return &namespaceBatch{Batch: ns.pdb.NewBatch(), ns: ns}
+}
+
+// Print implements DB.
+func (ns *Namespace) Print() error {
+	if err := ns.check(); err != nil {
+		return err
+	}
+	return ns.pdb.Print()
+}
+
+// Stats implements DB. The namespace.* stats count the reads and writes of
+// the namespace since it was opened, and the namespace.db.* stats are the
+// stats of the physical database.
+func (ns *Namespace) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range ns.pdb.db.Stats() {
+		stats["namespace.db."+k] = v
+	}
+	stats["namespace.name"] = ns.name
+	stats["namespace.reads"] = strconv.FormatUint(ns.reads.Load(), 10)
+	stats["namespace.writes"] = strconv.FormatUint(ns.writes.Load(), 10)
+	stats["namespace.read_bytes"] = strconv.FormatUint(ns.readBytes.Load(), 10)
+	stats["namespace.written_bytes"] = strconv.FormatUint(ns.writtenBytes.Load(), 10)
+	return stats
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the physical database.
+func (ns *Namespace) CompareAndSwap(key, old, new []byte) (bool, error) {
+	if err := ns.check(); err != nil {
+		return false, err
+	}
+	swapped, err := ns.pdb.CompareAndSwap(key, old, new)
+	if swapped {
+		ns.written(key, new)
+	}
+	return swapped, err
+}
+
+// DeleteRange implements RangeDeleter, deleting the range within the
+// namespace.
+func (ns *Namespace) DeleteRange(start, end []byte) error {
+	if err := ns.check(); err != nil {
+		return err
+	}
+	return ns.pdb.DeleteRange(start, end)
+}
+
+// EstimateSize implements SizeEstimator, estimating the range within the
+// namespace. An empty range estimates the whole namespace.
+func (ns *Namespace) EstimateSize(start, end []byte) (uint64, error) {
+	if err := ns.check(); err != nil {
+		return 0, err
+	}
+	return ns.pdb.EstimateSize(start, end)
+}
+
+// Compact implements Compacter, compacting the range within the namespace.
+func (ns *Namespace) Compact(start, end []byte) error {
+	if err := ns.check(); err != nil {
+		return err
+	}
+	return ns.pdb.Compact(start, end)
+}
+
+// Metrics implements MetricsReporter, with the metrics of the whole physical
+// database.
+func (ns *Namespace) Metrics() (Metrics, error) {
+	return ns.pdb.Metrics()
+}
+
+// Flush implements Flusher, flushing the whole physical database.
+func (ns *Namespace) Flush() error {
+	return ns.pdb.Flush()
+}
+
+// Sync implements Syncer, syncing the whole physical database.
+func (ns *Namespace) Sync() error {
+	return ns.pdb.Sync()
+}
+
+// HealthCheck implements HealthChecker, checking the physical database.
+func (ns *Namespace) HealthCheck(ctx context.Context) error {
+	if err := ns.check(); err != nil {
+		return err
+	}
+	return ns.pdb.HealthCheck(ctx)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// physical database which Namespace passes through.
+func (ns *Namespace) Capabilities() Capability {
+	return ns.pdb.Capabilities() & (CapDeleteRange | CapCompact | CapFlush | CapSync)
+}
+
+// namespaceBatch counts the writes of a batch of a Namespace.
+type namespaceBatch struct {
+	Batch
+	ns    *Namespace
+	ops   uint64
+	bytes uint64
+}
+
+// Set implements Batch.
+func (b *namespaceBatch) Set(key, value []byte) error {
+	err := b.Batch.Set(key, value)
+	if err == nil {
+		b.ops++
+		b.bytes += uint64(len(key) + len(value))
+	}
+	return err
+}
+
+// Delete implements Batch.
+func (b *namespaceBatch) Delete(key []byte) error {
+	err := b.Batch.Delete(key)
+	if err == nil {
+		b.ops++
+		b.bytes += uint64(len(key))
+	}
+	return err
+}
+
+// Write implements Batch.
+func (b *namespaceBatch) Write() error {
+	return b.write(b.Batch.Write)
+}
+
+// WriteSync implements Batch.
+func (b *namespaceBatch) WriteSync() error {
+	return b.write(b.Batch.WriteSync)
+}
+
+func (b *namespaceBatch) write(write func() error) error {
+	if err := b.ns.check(); err != nil {
+		return err
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	b.ns.writes.Add(b.ops)
+	b.ns.writtenBytes.Add(b.bytes)
+	return nil
+}
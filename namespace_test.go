@@ -0,0 +1,88 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager(t *testing.T) {
+	for _, backend := range []BackendType{GoLevelDBBackend, PebbleDBBackend, MemDBBackend} {
+		t.Run(string(backend), func(t *testing.T) {
+			dir := t.TempDir()
+			db, err := NewDB("db", backend, dir)
+			require.NoError(t, err)
+			m := NewManager(db)
+
+			// Namespaces are isolated, including names prefixing each other.
+			a, err := m.Namespace("a")
+			require.NoError(t, err)
+			ab, err := m.Namespace("ab")
+			require.NoError(t, err)
+			require.NoError(t, a.Set(bz("bk"), bz("1")))
+			require.NoError(t, ab.Set(bz("k"), bz("2")))
+			checkValue(t, a, bz("k"), nil)
+			checkValue(t, ab, bz("k"), bz("2"))
+			itr, err := a.Iterator(nil, nil)
+			require.NoError(t, err)
+			checkItem(t, itr, bz("bk"), bz("1"))
+			checkNext(t, itr, false)
+			require.NoError(t, itr.Close())
+
+			again, err := m.Namespace("a")
+			require.NoError(t, err)
+			assert.Same(t, a, again)
+			_, err = m.Namespace("")
+			assert.Error(t, err)
+
+			batch := ab.NewBatch()
+			require.NoError(t, batch.Set(bz("x"), bz("3")))
+			require.NoError(t, batch.Delete(bz("y")))
+			require.NoError(t, batch.Write())
+			require.NoError(t, batch.Close())
+
+			stats := ab.Stats()
+			assert.Equal(t, "ab", stats["namespace.name"])
+			assert.Equal(t, "3", stats["namespace.writes"])
+			assert.Equal(t, "5", stats["namespace.written_bytes"])
+			assert.Equal(t, "1", stats["namespace.reads"])
+			assert.Equal(t, "1", stats["namespace.read_bytes"])
+
+			names, err := m.Namespaces()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a", "ab"}, names)
+
+			// Dropping a namespace deletes its keys only, and closes its handles.
+			require.NoError(t, m.Drop("a"))
+			assert.ErrorIs(t, a.Set(bz("k"), bz("v")), ErrClosed)
+			_, err = a.Get(bz("bk"))
+			assert.ErrorIs(t, err, ErrClosed)
+			names, err = m.Namespaces()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"ab"}, names)
+			a, err = m.Namespace("a")
+			require.NoError(t, err)
+			checkValue(t, a, bz("bk"), nil)
+			checkValue(t, ab, bz("x"), bz("3"))
+			require.NoError(t, m.Drop("missing"))
+
+			// The namespaces persist across reopens.
+			require.NoError(t, a.Close())
+			require.NoError(t, m.Close())
+			if backend == MemDBBackend {
+				return
+			}
+			db, err = NewDB("db", backend, dir)
+			require.NoError(t, err)
+			m = NewManager(db)
+			defer m.Close()
+			names, err = m.Namespaces()
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a", "ab"}, names)
+			ab, err = m.Namespace("ab")
+			require.NoError(t, err)
+			checkValue(t, ab, bz("k"), bz("2"))
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// opTracker tracks the operations in flight on a database whose backend panics
+// when used after it is closed, so that Close can wait for them and the later
+// operations fail instead.
+type opTracker struct {
+	state   atomic.Int64 // 2 per operation in flight, plus 1 once closed
+	drained chan struct{}
+	once    sync.Once
+}
+
+func newOpTracker() *opTracker {
+	return &opTracker{drained: make(chan struct{})}
+}
+
+// begin starts an operation, which must be ended with end, unless the database
+// is closed, in which case it returns false.
+func (t *opTracker) begin() bool {
+	if t.state.Add(2)&1 != 0 {
+		t.end()
+		return false
+	}
+	return true
+}
+
+// end ends an operation started by begin.
+func (t *opTracker) end() {
+	if t.state.Add(-2) == 1 {
+		t.once.Do(func() { close(t.drained) })
+	}
+}
+
+// close makes begin fail from now on, and waits for the operations in flight
+// to end. It returns false if the database was already closed.
+func (t *opTracker) close() bool {
+	for {
+		state := t.state.Load()
+		if state&1 != 0 {
+			<-t.drained
+			return false
+		}
+		if t.state.CompareAndSwap(state, state|1) {
+			if state == 0 {
+				t.once.Do(func() { close(t.drained) })
+			}
+			<-t.drained
+			return true
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// defaultStatsInterval is the default of Options.StatsInterval.
+const defaultStatsInterval = time.Minute
+
+// Options are the settings of a database created by NewDB, set with the Option
+// functions. The zero value is the default configuration of every backend.
+//
+// The backends ignore the settings which do not apply to them, e.g. CacheSize
+// for BoltDB, which relies on the page cache of the operating system. They fail
+// to open with ReadOnly, SyncWrites or BackendOptions if they cannot honor them.
+type Options struct {
+	// CacheSize is the capacity of the block cache in bytes, if positive.
+	CacheSize int64
+
+	// SharedCache, if set, is the memory budget of the block cache and write
+	// buffers of the database, shared with the other databases opened with it,
+	// see SharedCache. It takes precedence over CacheSize. goleveldb and
+	// PebbleDB support it, BadgerDB, CLevelDB and RocksDB fail to open with it
+	// and the other backends ignore it.
+	SharedCache *SharedCache
+
+	// WriteBufferSize is the size in bytes of the memtable, beyond which the
+	// writes are flushed to a table, if positive. It applies to goleveldb,
+	// PebbleDB and RocksDB.
+	WriteBufferSize int64
+
+	// BloomFilterBits is the number of bits per key of the bloom filters of the
+	// tables, which spare the reads of missing keys from reading the tables,
+	// if positive. A negative number disables the filters. Zero keeps the
+	// default of the backend: about 10 bits for goleveldb and RocksDB, and no
+	// filters for PebbleDB.
+	BloomFilterBits int
+
+	// ReadOnly opens an existing database without allowing writes.
+	ReadOnly bool
+
+	// InMemory keeps the files of the database in memory rather than in the
+	// directory, e.g. for tests which exercise the code paths of an on-disk
+	// backend, such as compactions, without touching the disk. The data is
+	// lost when the database is closed. goleveldb, PebbleDB and BadgerDB
+	// support it, the other on-disk backends fail to open with it and MemDB
+	// ignores it.
+	InMemory bool
+
+	// FS, if set, is the file system in which goleveldb and PebbleDB keep the
+	// files of the database, e.g. for tests simulating disk failures: a
+	// vfs.NewStrictMem loses the writes not synced when reset, as in a power
+	// loss, and wrappers of vfs.Default can inject errors, such as a full
+	// disk, or latency, such as slow fsyncs. The other on-disk backends fail to
+	// open with it, and InMemory takes precedence over it.
+	FS vfs.FS
+
+	// Recover makes the database recover from the corruption found when
+	// opening it, e.g. after a crash in the middle of a write, instead of
+	// failing to open, unless opened read-only: goleveldb rebuilds its
+	// manifest from its tables, dropping their corrupted blocks along with
+	// their entries, like RecoverGoLevelDB, and CLevelDB and RocksDB repair
+	// the database. What was dropped is logged at LogLevelError and reported
+	// to OnRecovery. The other on-disk backends fail to open with it.
+	Recover bool
+
+	// OnRecovery, if set, is called with what was dropped when the database
+	// was recovered with Recover, before it is returned.
+	OnRecovery func(RecoveryInfo)
+
+	// LockTimeout is how long NewDB waits for the lock of the database, held
+	// by another process or by another database of this process, before
+	// failing with a LockError. Zero fails at once. NewDB locks the databases
+	// of the on-disk backends, apart from those in memory or in a custom FS,
+	// with the file name.lock in the directory, exclusively unless read-only,
+	// apart from the read-only RocksDB databases, which are not locked. The
+	// lock file is left in the directory when the database is closed.
+	LockTimeout time.Duration
+
+	// SyncWrites makes every write durable on return, as if Set, Delete and
+	// Write were SetSync, DeleteSync and WriteSync.
+	SyncWrites bool
+
+	// Logger receives the log messages of the backend. Nil is the standard
+	// library logger at LogLevelInfo.
+	Logger Logger
+
+	// StatsInterval is how often the backends with periodic stats (goleveldb
+	// and PebbleDB) log their metrics at LogLevelDebug. Zero is every minute,
+	// and a negative interval disables the stats. The metrics are not computed
+	// at all if the Logger is a LevelLogger discarding LogLevelDebug.
+	StatsInterval time.Duration
+
+	// IteratorLeakThreshold, if positive, makes the database track its open
+	// iterators, including those of its snapshots, and log at LogLevelError
+	// the ones open for longer than the threshold and the ones still open at
+	// Close, along with the stack trace of their creation. Capturing the stack
+	// traces slows down the creation of iterators, so it is meant for
+	// debugging.
+	IteratorLeakThreshold time.Duration
+
+	// MergeOperator, if set, makes the database a Merger combining the merged
+	// values with it. PebbleDB and RocksDB merge natively, the databases of
+	// the other backends are wrapped in a MergeDB.
+	MergeOperator MergeOperator
+
+	// OnCompaction, if set, is called after every compaction and flush of the
+	// backend, e.g. to correlate latency spikes with background work. It is
+	// called from the background goroutines of the backend, and must neither
+	// block nor use the database. Only PebbleDB reports these events, the
+	// other backends ignore it.
+	OnCompaction func(CompactionInfo)
+
+	// OnWriteStall, if set, is called when the backend starts stalling writes,
+	// e.g. because level 0 has too many tables, and when writes resume, so that
+	// nodes can shed load or alert before falling behind. PebbleDB reports the
+	// stalls as they happen, goleveldb and RocksDB are polled for them every
+	// 100ms. It is called from a background goroutine, and must neither block
+	// nor use the database.
+	OnWriteStall func(WriteStallInfo)
+
+	// BackendOptions are the native options of the backend, used as the base
+	// to which the other settings are applied. Their type depends on the
+	// backend: *opt.Options for goleveldb, *pebble.Options for PebbleDB,
+	// badger.Options for BadgerDB, *bbolt.Options for BoltDB and
+	// *grocksdb.Options for RocksDB.
+	BackendOptions interface{}
+
+	// BackendParams are settings of the backend by name, such as
+	// {"compression": "none"} for goleveldb, which tune the backend like
+	// BackendOptions but without importing its package, e.g. from a
+	// configuration file. They are the options accepted in the database URIs
+	// of NewDBFromURI, documented by the backends: goleveldb, PebbleDB, BoltDB
+	// and RocksDB accept them, NewDB fails with them for the other backends and
+	// for unknown or malformed settings. They apply on top of BackendOptions,
+	// and the other settings apply on top of them.
+	BackendParams map[string]string
+
+	// lock is the lock of the database taken by NewDB, which the backend
+	// releases when the database is closed.
+	lock *dbLock
+}
+
+// Option sets part of the Options of NewDB.
+type Option func(*Options)
+
+// WithCacheSize sets the capacity of the block cache in bytes.
+func WithCacheSize(bytes int64) Option {
+	return func(o *Options) {
+		o.CacheSize = bytes
+	}
+}
+
+// WithSharedCache makes the database share the memory budget of cache with the
+// other databases opened with it, see Options.SharedCache.
+func WithSharedCache(cache *SharedCache) Option {
+	return func(o *Options) {
+		o.SharedCache = cache
+	}
+}
+
+// WithWriteBufferSize sets the size of the memtable in bytes, see
+// Options.WriteBufferSize.
+func WithWriteBufferSize(bytes int64) Option {
+	return func(o *Options) {
+		o.WriteBufferSize = bytes
+	}
+}
+
+// WithBloomFilter sets the bits per key of the bloom filters, or disables them
+// if negative, see Options.BloomFilterBits.
+func WithBloomFilter(bitsPerKey int) Option {
+	return func(o *Options) {
+		o.BloomFilterBits = bitsPerKey
+	}
+}
+
+// WithReadOnly opens the database read-only. It must already exist.
+func WithReadOnly() Option {
+	return func(o *Options) {
+		o.ReadOnly = true
+	}
+}
+
+// WithInMemory keeps the files of the database in memory, see
+// Options.InMemory.
+func WithInMemory() Option {
+	return func(o *Options) {
+		o.InMemory = true
+	}
+}
+
+// WithFS keeps the files of the database in fs, see Options.FS.
+func WithFS(fs vfs.FS) Option {
+	return func(o *Options) {
+		o.FS = fs
+	}
+}
+
+// WithRecovery makes the database recover from corruption when opened, and
+// report what was dropped to onRecovery, which may be nil, see
+// Options.Recover.
+func WithRecovery(onRecovery func(RecoveryInfo)) Option {
+	return func(o *Options) {
+		o.Recover = true
+		o.OnRecovery = onRecovery
+	}
+}
+
+// WithLockTimeout makes NewDB wait for the lock of the database held by others
+// for up to timeout, see Options.LockTimeout.
+func WithLockTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.LockTimeout = timeout
+	}
+}
+
+// WithSyncWrites makes every write synchronous.
+func WithSyncWrites() Option {
+	return func(o *Options) {
+		o.SyncWrites = true
+	}
+}
+
+// WithLogger sets the logger of the backend.
+func WithLogger(logger Logger) Option {
+	return func(o *Options) {
+		o.Logger = logger
+	}
+}
+
+// WithStatsInterval sets how often the metrics of the backend are logged, see
+// Options.StatsInterval.
+func WithStatsInterval(interval time.Duration) Option {
+	return func(o *Options) {
+		o.StatsInterval = interval
+	}
+}
+
+// WithIteratorLeakDetection logs the iterators left open for longer than
+// threshold, see Options.IteratorLeakThreshold.
+func WithIteratorLeakDetection(threshold time.Duration) Option {
+	return func(o *Options) {
+		o.IteratorLeakThreshold = threshold
+	}
+}
+
+// WithMergeOperator makes the database merge values with op, see
+// Options.MergeOperator.
+func WithMergeOperator(op MergeOperator) Option {
+	return func(o *Options) {
+		o.MergeOperator = op
+	}
+}
+
+// WithOnCompaction calls fn after every compaction and flush of the backend,
+// see Options.OnCompaction.
+func WithOnCompaction(fn func(CompactionInfo)) Option {
+	return func(o *Options) {
+		o.OnCompaction = fn
+	}
+}
+
+// WithOnWriteStall calls fn when writes start and stop being stalled by the
+// backend, see Options.OnWriteStall.
+func WithOnWriteStall(fn func(WriteStallInfo)) Option {
+	return func(o *Options) {
+		o.OnWriteStall = fn
+	}
+}
+
+// WithBackendOptions sets the native options of the backend, see
+// Options.BackendOptions. Their type is checked when the database is opened.
+func WithBackendOptions(opts interface{}) Option {
+	return func(o *Options) {
+		o.BackendOptions = opts
+	}
+}
+
+// WithBackendParams sets settings of the backend by name, see
+// Options.BackendParams.
+func WithBackendParams(params map[string]string) Option {
+	return func(o *Options) {
+		o.BackendParams = params
+	}
+}
+
+// newOptions applies opts to the default Options.
+func newOptions(opts []Option) *Options {
+	o := &Options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.Logger == nil {
+		o.Logger = NewStdLogger(LogLevelInfo)
+	}
+	if o.StatsInterval == 0 {
+		o.StatsInterval = defaultStatsInterval
+	}
+	return o
+}
+
+// isDefault reports whether o only has default settings, apart from the
+// logging ones, the lock timeout, which only applies to the built-in backends,
+// and the merge operator, which NewDB applies with a MergeDB. The event
+// callbacks are settings too: a backend which cannot call them must not drop
+// them silently.
+func (o *Options) isDefault() bool {
+	return o.CacheSize <= 0 && o.SharedCache == nil && o.WriteBufferSize <= 0 && o.BloomFilterBits == 0 && !o.ReadOnly && !o.InMemory && o.FS == nil && !o.Recover && !o.SyncWrites && o.IteratorLeakThreshold <= 0 &&
+		o.OnRecovery == nil && o.OnCompaction == nil && o.OnWriteStall == nil &&
+		o.BackendOptions == nil && len(o.BackendParams) == 0
+}
+
+// logsStats reports whether the backends with periodic stats should run them:
+// the metrics are only worth computing if their message is logged.
+func (o *Options) logsStats() bool {
+	return o.StatsInterval > 0 && logEnabled(o.Logger, LogLevelDebug)
+}
+
+// errBackendOptions returns the error for backend options of the wrong type.
+func errBackendOptions(backend BackendType, opts interface{}) error {
+	return fmt.Errorf("backend options of type %T are not supported by %s", opts, backend)
+}
+
+// errOption returns the error for a setting the backend cannot honor.
+func errOption(backend BackendType, option string) error {
+	return fmt.Errorf("%s does not support option %s: %w", backend, option, ErrNotSupported)
+}
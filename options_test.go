@@ -0,0 +1,337 @@
+package db
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+func TestNewDBOptions(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir, WithCacheSize(8<<20), WithSyncWrites())
+			if errors.Is(err, ErrNotSupported) {
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			require.NoError(t, db.Set(bz("a"), bz("1")))
+			checkValue(t, db, bz("a"), bz("1"))
+		})
+	}
+}
+
+func TestNewDBBloomFilter(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir, WithBloomFilter(12), WithWriteBufferSize(1<<20))
+			if errors.Is(err, ErrNotSupported) {
+				t.Skip(err)
+			}
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			require.NoError(t, db.Set(bz("a"), bz("1")))
+			checkValue(t, db, bz("a"), bz("1"))
+			checkValue(t, db, bz("b"), nil)
+		})
+	}
+}
+
+func TestGoLevelDBBloomFilterDefault(t *testing.T) {
+	hasFilter := func(t *testing.T, opts ...Option) bool {
+		dir := t.TempDir()
+		db, err := NewDB("db", GoLevelDBBackend, dir, opts...)
+		require.NoError(t, err)
+		defer db.Close()
+		for i := int64(0); i < 100; i++ {
+			require.NoError(t, db.Set(int642Bytes(i), bz("value")))
+		}
+		require.NoError(t, Compact(db, nil, nil))
+
+		tables, err := filepath.Glob(filepath.Join(dir, "db.db", "*.ldb"))
+		require.NoError(t, err)
+		require.NotEmpty(t, tables)
+		data, err := os.ReadFile(tables[0])
+		require.NoError(t, err)
+		// The meta block naming the filter is compressed, but starts with a
+		// literal.
+		return bytes.Contains(data, bz("filter.leveldb.Builtin"))
+	}
+	assert.True(t, hasFilter(t))
+	assert.False(t, hasFilter(t, WithBloomFilter(-1)))
+}
+
+func TestNewDBReadOnly(t *testing.T) {
+	for _, dbType := range []BackendType{GoLevelDBBackend, PebbleDBBackend} {
+		t.Run(string(dbType), func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "options_test")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+			db, err := NewDB("db", dbType, dir)
+			require.NoError(t, err)
+			require.NoError(t, db.Set(bz("a"), bz("1")))
+			require.NoError(t, db.Close())
+
+			db, err = NewDB("db", dbType, dir, WithReadOnly())
+			require.NoError(t, err)
+			defer db.Close()
+			checkValue(t, db, bz("a"), bz("1"))
+			assert.ErrorIs(t, db.Set(bz("b"), bz("2")), ErrReadOnly)
+		})
+	}
+}
+
+func TestNewDBInMemory(t *testing.T) {
+	for _, dbType := range []BackendType{GoLevelDBBackend, PebbleDBBackend, MemDBBackend} {
+		t.Run(string(dbType), func(t *testing.T) {
+			dir := t.TempDir()
+			db, err := NewDB("db", dbType, dir, WithInMemory())
+			require.NoError(t, err)
+			for i := int64(0); i < 1000; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+			require.NoError(t, db.Delete(int642Bytes(0)))
+			if err := Compact(db, nil, nil); !errors.Is(err, ErrNotSupported) {
+				require.NoError(t, err)
+			}
+			checkValue(t, db, int642Bytes(0), nil)
+			checkValue(t, db, int642Bytes(999), int642Bytes(999))
+			itr, err := db.ReverseIterator(nil, int642Bytes(10))
+			require.NoError(t, err)
+			for i := int64(9); i > 0; i-- {
+				checkItem(t, itr, int642Bytes(i), int642Bytes(i))
+				itr.Next()
+			}
+			checkValid(t, itr, false)
+			require.NoError(t, itr.Close())
+			_, err = DiskUsage(db)
+			assert.ErrorIs(t, err, ErrNotSupported)
+			require.NoError(t, db.Close())
+
+			entries, err := os.ReadDir(dir)
+			require.NoError(t, err)
+			assert.Empty(t, entries)
+		})
+	}
+}
+
+func TestNewDBBackendOptions(t *testing.T) {
+	dir, err := os.MkdirTemp("", "options_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db, err := NewDB("db", GoLevelDBBackend, dir, WithBackendOptions(&opt.Options{ErrorIfMissing: true}))
+	require.Error(t, err)
+	assert.Nil(t, db)
+
+	_, err = NewDB("db", GoLevelDBBackend, dir, WithBackendOptions(opt.Options{}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "opt.Options")
+
+	_, err = NewDB("db", MemDBBackend, dir, WithReadOnly())
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestNewDBBackendParams(t *testing.T) {
+	dir := t.TempDir()
+
+	db, err := NewDB("goleveldb", GoLevelDBBackend, dir, WithBackendParams(map[string]string{
+		"compression": "none",
+		"table_size":  "1MB",
+	}))
+	require.NoError(t, err)
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.Close())
+
+	db, err = NewDB("pebble", PebbleDBBackend, dir, WithBackendParams(map[string]string{
+		"cache":                   "8MB",
+		"compression":             "zstd",
+		"target_file_size":        "1MB",
+		"l0_compaction_threshold": "2",
+	}), WithCacheSize(16<<20))
+	require.NoError(t, err)
+	opts := db.(*PebbleDB).opts
+	assert.EqualValues(t, 16<<20, opts.Cache.MaxSize())
+	assert.Equal(t, 2, opts.L0CompactionThreshold)
+	for _, level := range opts.Levels {
+		assert.Equal(t, pebble.ZstdCompression, level.Compression)
+		assert.EqualValues(t, 1<<20, level.TargetFileSize)
+	}
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.Close())
+
+	_, err = NewDB("goleveldb", GoLevelDBBackend, dir, WithBackendParams(map[string]string{"compresion": "none"}))
+	assert.ErrorContains(t, err, "unknown options [compresion]")
+	_, err = NewDB("pebble", PebbleDBBackend, dir, WithBackendParams(map[string]string{"compression": "lz4"}))
+	assert.ErrorContains(t, err, "option compression")
+	_, err = NewDB("db", MemDBBackend, dir, WithBackendParams(map[string]string{"cache": "8MB"}))
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestRegisterBackendOptions(t *testing.T) {
+	const backend BackendType = "test_register_backend_options"
+	RegisterBackend(backend, func(name, dir string) (DB, error) {
+		return NewMemDB(), nil
+	})
+	defer delete(backends, backend)
+
+	db, err := NewDB("db", backend, "", WithLogger(&testLogger{}))
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	_, err = NewDB("db", backend, "", WithSyncWrites())
+	assert.ErrorIs(t, err, ErrNotSupported)
+	// The event callbacks are rejected rather than never called.
+	_, err = NewDB("db", backend, "", WithOnCompaction(func(CompactionInfo) {}))
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = NewDB("db", backend, "", WithOnWriteStall(func(WriteStallInfo) {}))
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+// testLogger records the messages it receives.
+type testLogger struct {
+	mtx  sync.Mutex
+	msgs []string
+}
+
+func (l *testLogger) Debug(msg string, keyvals ...interface{}) { l.log(msg, keyvals) }
+func (l *testLogger) Info(msg string, keyvals ...interface{})  { l.log(msg, keyvals) }
+func (l *testLogger) Error(msg string, keyvals ...interface{}) { l.log(msg, keyvals) }
+
+func (l *testLogger) log(msg string, keyvals []interface{}) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.msgs = append(l.msgs, strings.TrimSpace(fmt.Sprintln(append([]interface{}{msg}, keyvals...)...)))
+}
+
+func TestWithLogger(t *testing.T) {
+	dir, err := os.MkdirTemp("", "options_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logger := &testLogger{}
+	db, err := NewDB("db", GoLevelDBBackend, dir, WithLogger(logger))
+	require.NoError(t, err)
+	defer db.Close()
+	logger.mtx.Lock()
+	defer logger.mtx.Unlock()
+	assert.Equal(t, []string{"New db name db"}, logger.msgs)
+}
+
+// count returns the number of messages starting with prefix.
+func (l *testLogger) count(prefix string) int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	n := 0
+	for _, msg := range l.msgs {
+		if strings.HasPrefix(msg, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// infoLogger is a testLogger telling that it discards the debug messages,
+// which it still records.
+type infoLogger struct {
+	*testLogger
+}
+
+func (infoLogger) Enabled(level LogLevel) bool {
+	return level >= LogLevelInfo
+}
+
+func TestWithStatsInterval(t *testing.T) {
+	for backend, msg := range map[BackendType]string{
+		GoLevelDBBackend: "DB stats",
+		PebbleDBBackend:  "pebble DB stats",
+	} {
+		backend, msg := backend, msg
+		t.Run(string(backend), func(t *testing.T) {
+			dir, err := os.MkdirTemp("", "options_test")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			logger := &testLogger{}
+			db, err := NewDB("db", backend, dir, WithLogger(logger), WithStatsInterval(time.Millisecond))
+			require.NoError(t, err)
+			require.NoError(t, db.Set(bz("a"), bz("1")))
+			require.Eventually(t, func() bool {
+				return logger.count(msg+" name db written 1 ") > 0
+			}, time.Second, time.Millisecond)
+
+			// Close stops the stats.
+			require.NoError(t, db.Close())
+			n := logger.count(msg)
+			time.Sleep(20 * time.Millisecond)
+			assert.Equal(t, n, logger.count(msg))
+
+			logger = &testLogger{}
+			db, err = NewDB("db", backend, dir, WithLogger(logger), WithStatsInterval(-1))
+			require.NoError(t, err)
+			time.Sleep(20 * time.Millisecond)
+			require.NoError(t, db.Close())
+			assert.Zero(t, logger.count(msg))
+
+			// Nor are they computed if the logger discards them.
+			logger = &testLogger{}
+			db, err = NewDB("db", backend, dir, WithLogger(infoLogger{logger}), WithStatsInterval(time.Millisecond))
+			require.NoError(t, err)
+			time.Sleep(20 * time.Millisecond)
+			require.NoError(t, db.Close())
+			assert.Zero(t, logger.count(msg))
+		})
+	}
+}
+
+func TestWithOnCompaction(t *testing.T) {
+	var (
+		mtx    sync.Mutex
+		events []CompactionInfo
+	)
+	db, err := NewDB("db", PebbleDBBackend, t.TempDir(), WithOnCompaction(func(info CompactionInfo) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		events = append(events, info)
+	}))
+	require.NoError(t, err)
+	defer db.Close()
+
+	for i := int64(0); i < 1000; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+	}
+	require.NoError(t, Flush(db))
+	require.NoError(t, Compact(db, nil, nil))
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	kinds := make(map[CompactionKind]CompactionInfo)
+	for _, info := range events {
+		require.NoError(t, info.Err)
+		kinds[info.Kind] = info
+	}
+	require.Contains(t, kinds, CompactionKindFlush)
+	require.Contains(t, kinds, CompactionKindCompaction)
+	assert.Positive(t, kinds[CompactionKindFlush].OutputBytes)
+	assert.Positive(t, kinds[CompactionKindCompaction].InputBytes)
+	assert.NotEmpty(t, kinds[CompactionKindCompaction].InputLevels)
+}
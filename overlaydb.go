@@ -0,0 +1,255 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Markers prefixed to the values of the overlay, to tell writes and deletions
+// apart.
+const (
+	overlayDeleted byte = iota
+	overlaySet
+)
+
+// OverlayDB layers writes in memory over a base database, which is not written
+// to until Commit. Reads see the pending writes on top of the base. Discard
+// throws the pending writes away, e.g. after a failed speculative execution.
+//
+// Pending deletions are kept as tombstones in the overlay, hiding the base key.
+// Like MemDB, writes block while an iterator is open, so iterators must be
+// closed before writing, committing or discarding.
+type OverlayDB struct {
+	mtx     sync.RWMutex // guards the overlay pointer, held exclusively by Commit and Discard
+	base    DB
+	overlay *MemDB
+}
+
+var _ DB = (*OverlayDB)(nil)
+
+// NewOverlayDB creates an OverlayDB over base. Closing the OverlayDB closes base,
+// without committing.
+func NewOverlayDB(base DB) *OverlayDB {
+	return &OverlayDB{
+		base:    base,
+		overlay: NewMemDB(),
+	}
+}
+
+// Base returns the base database.
+func (odb *OverlayDB) Base() DB {
+	return odb.base
+}
+
+// Commit writes all pending writes to the base database in a single batch, and
+// empties the overlay. If the batch fails, the pending writes are kept.
+func (odb *OverlayDB) Commit() error {
+	return odb.commit(false)
+}
+
+// CommitSync is like Commit, but flushes the base database to storage.
+func (odb *OverlayDB) CommitSync() error {
+	return odb.commit(true)
+}
+
+func (odb *OverlayDB) commit(sync bool) error {
+	odb.mtx.Lock()
+	defer odb.mtx.Unlock()
+
+	batch := odb.base.NewBatch()
+	defer batch.Close()
+	err := addOverlayToBatch(odb.overlay, batch)
+	if err != nil {
+		return err
+	}
+	if sync {
+		err = batch.WriteSync()
+	} else {
+		err = batch.Write()
+	}
+	if err != nil {
+		return err
+	}
+	odb.overlay = NewMemDB()
+	return nil
+}
+
+// addOverlayToBatch adds the pending writes of overlay to batch.
+func addOverlayToBatch(overlay *MemDB, batch Batch) error {
+	itr, err := overlay.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	for ; itr.Valid(); itr.Next() {
+		key, value := itr.Key(), itr.Value()
+		if value[0] == overlayDeleted {
+			err = batch.Delete(key)
+		} else {
+			err = batch.Set(key, value[1:])
+		}
+		if err != nil {
+			itr.Close()
+			return err
+		}
+	}
+	return itr.Close()
+}
+
+// Discard drops all pending writes.
+func (odb *OverlayDB) Discard() {
+	odb.mtx.Lock()
+	defer odb.mtx.Unlock()
+	odb.overlay = NewMemDB()
+}
+
+// Get implements DB.
+func (odb *OverlayDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	odb.mtx.RLock()
+	defer odb.mtx.RUnlock()
+	value, err := odb.overlay.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return odb.base.Get(key)
+	}
+	if value[0] == overlayDeleted {
+		return nil, nil
+	}
+	return value[1:], nil
+}
+
+// Has implements DB.
+func (odb *OverlayDB) Has(key []byte) (bool, error) {
+	value, err := odb.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return value != nil, nil
+}
+
+// Set implements DB.
+func (odb *OverlayDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	odb.mtx.RLock()
+	defer odb.mtx.RUnlock()
+	return odb.overlay.Set(key, append([]byte{overlaySet}, value...))
+}
+
+// SetSync implements DB. Pending writes are only persisted by CommitSync, so it
+// is the same as Set.
+func (odb *OverlayDB) SetSync(key []byte, value []byte) error {
+	return odb.Set(key, value)
+}
+
+// Delete implements DB.
+func (odb *OverlayDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	odb.mtx.RLock()
+	defer odb.mtx.RUnlock()
+	return odb.overlay.Set(key, []byte{overlayDeleted})
+}
+
+// DeleteSync implements DB. Pending writes are only persisted by CommitSync, so
+// it is the same as Delete.
+func (odb *OverlayDB) DeleteSync(key []byte) error {
+	return odb.Delete(key)
+}
+
+// Iterator implements DB.
+func (odb *OverlayDB) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	odb.mtx.RLock()
+	defer odb.mtx.RUnlock()
+	base, err := odb.base.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := odb.overlay.Iterator(start, end)
+	if err != nil {
+		base.Close()
+		return nil, err
+	}
+	return newOverlayDBIterator(overlay, base, start, end, false), nil
+}
+
+// ReverseIterator implements DB.
+func (odb *OverlayDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	odb.mtx.RLock()
+	defer odb.mtx.RUnlock()
+	base, err := odb.base.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := odb.overlay.ReverseIterator(start, end)
+	if err != nil {
+		base.Close()
+		return nil, err
+	}
+	return newOverlayDBIterator(overlay, base, start, end, true), nil
+}
+
+// Close implements DB. Pending writes are discarded.
+func (odb *OverlayDB) Close() error {
+	odb.Discard()
+	return odb.base.Close()
+}
+
+// NewBatch implements DB.
+func (odb *OverlayDB) NewBatch() Batch {
+	return newOverlayDBBatch(odb)
+}
+
+// Print implements DB.
+func (odb *OverlayDB) Print() error {
+	itr, err := odb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		key := itr.Key()
+		value := itr.Value()
+		fmt.Printf("[%X]:\t[%X]\n", key, value)
+	}
+	return itr.Error()
+}
+
+// Stats implements DB. It returns the stats of the base database, along with
+// the number of pending writes.
+func (odb *OverlayDB) Stats() map[string]string {
+	stats := odb.base.Stats()
+	if stats == nil {
+		stats = make(map[string]string)
+	}
+	odb.mtx.RLock()
+	stats["overlay.pending"] = odb.overlay.Stats()["database.size"]
+	odb.mtx.RUnlock()
+	return stats
+}
+
+// Compact implements Compacter, compacting the base database.
+func (odb *OverlayDB) Compact(start, end []byte) error {
+	return Compact(odb.base, start, end)
+}
+
+// Capabilities implements CapabilityReporter. Only compaction, of the base
+// database, is passed through.
+func (odb *OverlayDB) Capabilities() Capability {
+	return Capabilities(odb.base) & CapCompact
+}
@@ -0,0 +1,99 @@
+package db
+
+import "fmt"
+
+// overlayDBBatch buffers operations, and applies them atomically to the overlay
+// on write through a batch of the overlay MemDB.
+type overlayDBBatch struct {
+	db  *OverlayDB
+	ops []operation
+}
+
+var _ Batch = (*overlayDBBatch)(nil)
+
+func newOverlayDBBatch(db *OverlayDB) *overlayDBBatch {
+	return &overlayDBBatch{
+		db:  db,
+		ops: []operation{},
+	}
+}
+
+// Set implements Batch.
+func (b *overlayDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *overlayDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	return nil
+}
+
+// Count implements Batch.
+func (b *overlayDBBatch) Count() int {
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *overlayDBBatch) SizeBytes() int {
+	return opsSize(b.ops)
+}
+
+// Write implements Batch.
+func (b *overlayDBBatch) Write() error {
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.db.mtx.RLock()
+	defer b.db.mtx.RUnlock()
+
+	batch := b.db.overlay.NewBatch()
+	defer batch.Close()
+	for _, op := range b.ops {
+		var err error
+		switch op.opType {
+		case opTypeSet:
+			err = batch.Set(op.key, append([]byte{overlaySet}, op.value...))
+		case opTypeDelete:
+			err = batch.Set(op.key, []byte{overlayDeleted})
+		default:
+			err = fmt.Errorf("unknown operation type %v (%v)", op.opType, op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// WriteSync implements Batch. Pending writes are only persisted by
+// OverlayDB.CommitSync, so it is the same as Write.
+func (b *overlayDBBatch) WriteSync() error {
+	return b.Write()
+}
+
+// Close implements Batch.
+func (b *overlayDBBatch) Close() error {
+	b.ops = nil
+	return nil
+}
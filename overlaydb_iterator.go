@@ -0,0 +1,138 @@
+package db
+
+import "bytes"
+
+// overlayDBIterator merges the iterators of the overlay and of the base
+// database. Overlay items shadow the base items with the same key, and
+// tombstones are skipped along with the base item they hide.
+type overlayDBIterator struct {
+	overlay Iterator
+	base    Iterator
+	start   []byte
+	end     []byte
+
+	isReverse bool
+	isInvalid bool
+}
+
+var _ Iterator = (*overlayDBIterator)(nil)
+
+func newOverlayDBIterator(overlay, base Iterator, start, end []byte, isReverse bool) *overlayDBIterator {
+	itr := &overlayDBIterator{
+		overlay:   overlay,
+		base:      base,
+		start:     start,
+		end:       end,
+		isReverse: isReverse,
+	}
+	itr.skip()
+	return itr
+}
+
+// compare orders the current keys in iteration order: negative if the overlay
+// key comes first, positive if the base one does and zero if they are equal.
+// An exhausted iterator comes last.
+func (itr *overlayDBIterator) compare() int {
+	switch {
+	case !itr.overlay.Valid():
+		return 1
+	case !itr.base.Valid():
+		return -1
+	}
+	cmp := bytes.Compare(itr.overlay.Key(), itr.base.Key())
+	if itr.isReverse {
+		return -cmp
+	}
+	return cmp
+}
+
+// skip moves past shadowed base items and tombstones, until the current item
+// (the first of the two iterators) is a live one.
+func (itr *overlayDBIterator) skip() {
+	for itr.overlay.Valid() {
+		cmp := itr.compare()
+		if cmp > 0 {
+			// The base item comes first and is not shadowed.
+			return
+		}
+		if cmp == 0 {
+			itr.base.Next()
+		}
+		if itr.overlay.Value()[0] != overlayDeleted {
+			return
+		}
+		itr.overlay.Next()
+	}
+}
+
+// current returns the iterator positioned at the current item.
+func (itr *overlayDBIterator) current() Iterator {
+	if itr.compare() <= 0 {
+		return itr.overlay
+	}
+	return itr.base
+}
+
+// Domain implements Iterator.
+func (itr *overlayDBIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+// Valid implements Iterator.
+func (itr *overlayDBIterator) Valid() bool {
+	if itr.isInvalid {
+		return false
+	}
+	if itr.Error() != nil || (!itr.overlay.Valid() && !itr.base.Valid()) {
+		itr.isInvalid = true
+		return false
+	}
+	return true
+}
+
+// Next implements Iterator.
+func (itr *overlayDBIterator) Next() {
+	itr.assertIsValid()
+	itr.current().Next()
+	itr.skip()
+}
+
+// Key implements Iterator.
+func (itr *overlayDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.current().Key()
+}
+
+// Value implements Iterator.
+func (itr *overlayDBIterator) Value() []byte {
+	itr.assertIsValid()
+	current := itr.current()
+	if current == itr.overlay {
+		return current.Value()[1:]
+	}
+	return current.Value()
+}
+
+// Error implements Iterator.
+func (itr *overlayDBIterator) Error() error {
+	if err := itr.overlay.Error(); err != nil {
+		return err
+	}
+	return itr.base.Error()
+}
+
+// Close implements Iterator.
+func (itr *overlayDBIterator) Close() error {
+	err := itr.overlay.Close()
+	if berr := itr.base.Close(); err == nil {
+		err = berr
+	}
+	itr.isInvalid = true
+	return err
+}
+
+func (itr *overlayDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
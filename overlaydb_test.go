@@ -0,0 +1,96 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mockOverlayDB(t *testing.T) (*OverlayDB, DB) {
+	base := NewMemDB()
+	for _, key := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, base.Set(bz(key), bz("base")))
+	}
+	odb := NewOverlayDB(base)
+	require.NoError(t, odb.Set(bz("b"), bz("overlay")))
+	require.NoError(t, odb.Delete(bz("c")))
+	require.NoError(t, odb.Set(bz("e"), bz("overlay")))
+	return odb, base
+}
+
+func TestOverlayDBReads(t *testing.T) {
+	odb, base := mockOverlayDB(t)
+
+	checkValue(t, odb, bz("a"), bz("base"))
+	checkValue(t, odb, bz("b"), bz("overlay"))
+	checkValue(t, odb, bz("c"), nil)
+	checkValue(t, odb, bz("e"), bz("overlay"))
+	ok, err := odb.Has(bz("c"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// The base is untouched.
+	checkValue(t, base, bz("b"), bz("base"))
+	checkValue(t, base, bz("c"), bz("base"))
+	assert.Equal(t, "3", odb.Stats()["overlay.pending"])
+}
+
+func TestOverlayDBIterator(t *testing.T) {
+	odb, _ := mockOverlayDB(t)
+
+	itr, err := odb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("a"), bz("base"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("b"), bz("overlay"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("d"), bz("base"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("e"), bz("overlay"))
+	checkNext(t, itr, false)
+	checkInvalid(t, itr)
+	require.NoError(t, itr.Close())
+
+	itr, err = odb.ReverseIterator(bz("b"), bz("e"))
+	require.NoError(t, err)
+	checkItem(t, itr, bz("d"), bz("base"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("b"), bz("overlay"))
+	checkNext(t, itr, false)
+	checkInvalid(t, itr)
+	require.NoError(t, itr.Close())
+}
+
+func TestOverlayDBCommit(t *testing.T) {
+	odb, base := mockOverlayDB(t)
+
+	batch := odb.NewBatch()
+	require.NoError(t, batch.Delete(bz("a")))
+	require.NoError(t, batch.Set(bz("f"), bz("overlay")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	checkValue(t, base, bz("a"), bz("base"))
+
+	require.NoError(t, odb.Commit())
+	assert.Equal(t, "0", odb.Stats()["overlay.pending"])
+	checkValue(t, base, bz("a"), nil)
+	checkValue(t, base, bz("b"), bz("overlay"))
+	checkValue(t, base, bz("c"), nil)
+	checkValue(t, base, bz("d"), bz("base"))
+	checkValue(t, base, bz("e"), bz("overlay"))
+	checkValue(t, base, bz("f"), bz("overlay"))
+}
+
+func TestOverlayDBDiscard(t *testing.T) {
+	odb, base := mockOverlayDB(t)
+
+	odb.Discard()
+	checkValue(t, odb, bz("b"), bz("base"))
+	checkValue(t, odb, bz("c"), bz("base"))
+	checkValue(t, odb, bz("e"), nil)
+
+	// Nothing left to commit.
+	require.NoError(t, odb.CommitSync())
+	checkValue(t, base, bz("e"), nil)
+}
@@ -2,65 +2,260 @@ package db
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/bloom"
+	"github.com/cockroachdb/pebble/vfs"
 )
 
 func init() {
-	dbCreator := func(name string, dir string) (DB, error) {
-		return NewPebbleDB(name, dir)
+	registerDBCreator(PebbleDBBackend, newPebbleDBFromOptions, false)
+	registerBackendParams(PebbleDBBackend)
+}
+
+// newPebbleDBFromOptions accepts *pebble.Options as backend options, and the
+// backend params of applyPebbleParams.
+func newPebbleDBFromOptions(name, dir string, opts *Options) (DB, error) {
+	if opts.Recover {
+		return nil, errOption(PebbleDBBackend, "Recover")
+	}
+	o := &pebble.Options{}
+	if opts.BackendOptions != nil {
+		bo, ok := opts.BackendOptions.(*pebble.Options)
+		if !ok {
+			return nil, errBackendOptions(PebbleDBBackend, opts.BackendOptions)
+		}
+		o = bo.Clone()
+	}
+	cacheSize, err := applyPebbleParams(o, newBackendParams(opts.BackendParams))
+	if err != nil {
+		return nil, err
+	}
+	if opts.CacheSize > 0 {
+		cacheSize = opts.CacheSize
+	}
+	switch {
+	case opts.SharedCache != nil:
+		cache, err := opts.SharedCache.pebbleCache()
+		if err != nil {
+			return nil, err
+		}
+		o.Cache = cache
+	case cacheSize > 0:
+		cache := pebble.NewCache(cacheSize)
+		// pebble.Open takes its own reference.
+		defer cache.Unref()
+		o.Cache = cache
+	}
+	if opts.WriteBufferSize > 0 {
+		o.MemTableSize = int(opts.WriteBufferSize)
+	}
+	switch {
+	case opts.InMemory:
+		o.FS = vfs.NewMem()
+	case opts.FS != nil:
+		o.FS = opts.FS
+	}
+	if opts.BloomFilterBits != 0 {
+		o.Levels = pebbleFilterLevels(o.Levels, opts.BloomFilterBits)
+	}
+	if opts.ReadOnly {
+		o.ReadOnly = true
+	}
+	if opts.MergeOperator != nil {
+		o.Merger = pebbleMerger(opts.MergeOperator)
+	}
+	return newPebbleDB(name, dir, o, opts)
+}
+
+// pebbleLevels returns a copy of levels with set applied to each level. The
+// levels without options take those of the last one, so a single level applies
+// to all of them.
+func pebbleLevels(levels []pebble.LevelOptions, set func(*pebble.LevelOptions)) []pebble.LevelOptions {
+	levels = append([]pebble.LevelOptions(nil), levels...)
+	if len(levels) == 0 {
+		levels = make([]pebble.LevelOptions, 1)
+	}
+	for i := range levels {
+		set(&levels[i])
+	}
+	return levels
+}
+
+// pebbleFilterLevels returns a copy of levels with bloom filters of bitsPerKey
+// bits per key, or without filters if negative.
+func pebbleFilterLevels(levels []pebble.LevelOptions, bitsPerKey int) []pebble.LevelOptions {
+	return pebbleLevels(levels, func(l *pebble.LevelOptions) {
+		if bitsPerKey > 0 {
+			l.FilterPolicy = bloom.FilterPolicy(bitsPerKey)
+			l.FilterType = pebble.TableFilter
+		} else {
+			l.FilterPolicy = nil
+		}
+	})
+}
+
+// applyPebbleParams applies the backend params of PebbleDB to o: the memtable
+// and target_file_size (of the tables of all the levels) sizes, the
+// max_open_files and l0_compaction_threshold (level 0 tables triggering a
+// compaction) counts, compression (none, snappy or zstd) and the disable_wal
+// and readonly options. It returns the size of the cache param, if set, whose
+// cache the caller creates.
+func applyPebbleParams(o *pebble.Options, params *backendParams) (cacheSize int64, err error) {
+	cacheSize, _ = params.size("cache")
+	if v, ok := params.size("memtable"); ok {
+		o.MemTableSize = int(v)
+	}
+	if v, ok := params.int("max_open_files"); ok {
+		o.MaxOpenFiles = v
+	}
+	if v, ok := params.int("l0_compaction_threshold"); ok {
+		o.L0CompactionThreshold = v
+	}
+	if v, ok := params.size("target_file_size"); ok {
+		o.Levels = pebbleLevels(o.Levels, func(l *pebble.LevelOptions) { l.TargetFileSize = v })
+	}
+	if v, ok := params.string("compression"); ok {
+		compression, known := map[string]pebble.Compression{
+			"none":   pebble.NoCompression,
+			"snappy": pebble.SnappyCompression,
+			"zstd":   pebble.ZstdCompression,
+		}[v]
+		if known {
+			o.Levels = pebbleLevels(o.Levels, func(l *pebble.LevelOptions) { l.Compression = compression })
+		} else {
+			params.fail("compression", v, errors.New("expected none, snappy or zstd"))
+		}
+	}
+	if v, ok := params.bool("disable_wal"); ok {
+		o.DisableWAL = v
 	}
-	registerDBCreator(PebbleDBBackend, dbCreator, false)
+	if v, ok := params.bool("readonly"); ok {
+		o.ReadOnly = v
+	}
+	return cacheSize, params.err()
 }
 
 // PebbleDB is a PebbleDB backend.
 type PebbleDB struct {
 	db      *pebble.DB
+	opts    *pebble.Options // the options the database was opened with
 	name    string
-	written uint64
-	f       timerFunc
+	path    string
+	written uint64               // bytes written, accessed atomically
+	wo      *pebble.WriteOptions // pebble.NoSync unless writes are synced
+	logger  Logger
+	health  *pebbleHealth
+	iters   *iteratorTracker
+	lock    *dbLock
+	ops     *opTracker // pebble panics when used after Close
+
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
 }
 
 var _ DB = (*PebbleDB)(nil)
 
-func NewPebbleDB(name string, dir string) (DB, error) {
+// NewPebbleDB creates a PebbleDB with default options.
+func NewPebbleDB(name string, dir string) (*PebbleDB, error) {
 	opts := &pebble.Options{}
 	opts.EnsureDefaults()
 	return NewPebbleDBWithOpts(name, dir, opts)
 }
 
+// NewPebbleDBWithOpts creates a PebbleDB with the given *pebble.Options. This is
+// the place to tune e.g. the block cache (opts.Cache) or the WAL (opts.DisableWAL,
+// opts.WALDir, opts.WALBytesPerSync). Unset fields are filled with pebble's defaults.
 func NewPebbleDBWithOpts(name string, dir string, opts *pebble.Options) (*PebbleDB, error) {
-	log.Printf("New pebble db: %s", name)
+	return newPebbleDB(name, dir, opts, newOptions(nil))
+}
+
+func newPebbleDB(name string, dir string, o *pebble.Options, opts *Options) (*PebbleDB, error) {
+	opts.Logger.Info("New pebble db", "name", name)
 	dbPath := filepath.Join(dir, name+".db")
-	opts.EnsureDefaults()
-	p, err := pebble.Open(dbPath, opts)
+	if o.FS != nil {
+		dbPath = o.FS.PathJoin(dir, name+".db")
+	}
+	o.EnsureDefaults()
+	// The listener is added to a copy, so that o can be used to open other
+	// databases.
+	o = o.Clone()
+	health := &pebbleHealth{onStall: opts.OnWriteStall}
+	o.AddEventListener(health.listener())
+	if opts.OnCompaction != nil {
+		o.AddEventListener(pebbleCompactionListener(opts.OnCompaction))
+	}
+	p, err := pebble.Open(dbPath, o)
 	if err != nil {
-		return nil, err
+		return nil, pebbleError(err)
 	}
 	database := &PebbleDB{
-		db:   p,
-		name: name,
-	}
-	ticker := time.NewTicker(1 * time.Minute)
-	f := func() {
-		for {
-			select {
-			case <-ticker.C:
-				log.Printf("pebble DB %s stats", database.name)
-				log.Printf("%d bytes written", database.written)
-				for k, v := range database.Stats() {
-					log.Printf("%s %s", k, v)
-				}
-			}
+		db:     p,
+		opts:   o,
+		name:   name,
+		wo:     pebble.NoSync,
+		logger: opts.Logger,
+		health: health,
+		iters:  newIteratorTracker(name, opts),
+		lock:   opts.lock,
+		ops:    newOpTracker(),
+		quit:   make(chan struct{}),
+	}
+	if !opts.InMemory && opts.FS == nil {
+		database.path = dbPath
+	}
+	if opts.SyncWrites {
+		database.wo = pebble.Sync
+	}
+	if opts.logsStats() {
+		database.wg.Add(1)
+		go database.runStats(opts.StatsInterval)
+	}
+	return database, nil
+}
+
+// runStats logs the metrics of the database every interval until it is closed.
+func (db *PebbleDB) runStats(interval time.Duration) {
+	defer db.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-db.quit:
+			return
+		case <-ticker.C:
+			m, _ := db.Metrics()
+			logMetrics(db.logger, "pebble DB stats", db.name, atomic.LoadUint64(&db.written), m)
 		}
 	}
-	database.f = f
-	go database.f()
-	return database, err
+}
+
+// errPebbleClosed is returned by the operations on a closed PebbleDB, which
+// pebble would panic on.
+var errPebbleClosed = wrapError(ErrClosed, pebble.ErrClosed)
+
+// pebbleError wraps the pebble errors with the shared error kinds.
+func pebbleError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, pebble.ErrClosed):
+		return wrapError(ErrClosed, err)
+	case errors.Is(err, pebble.ErrReadOnly):
+		return wrapError(ErrReadOnly, err)
+	case errors.Is(err, pebble.ErrCorruption):
+		return wrapError(ErrCorrupted, err)
+	}
+	return err
 }
 
 // Get implements DB.
@@ -69,18 +264,46 @@ func (db *PebbleDB) Get(key []byte) ([]byte, error) {
 		return nil, errKeyEmpty
 	}
 
+	if !db.ops.begin() {
+		return nil, errPebbleClosed
+	}
+	defer db.ops.end()
 	res, closer, err := db.db.Get(key)
 	if err != nil {
 		if err == pebble.ErrNotFound {
 			return nil, nil
 		}
-		return nil, err
+		return nil, pebbleError(err)
 	}
 	defer closer.Close()
 
 	return cp(res), nil
 }
 
+// GetUnsafe implements UnsafeGetter, passing the value pinned by pebble, e.g.
+// in the block cache.
+func (db *PebbleDB) GetUnsafe(key []byte, fn func(value []byte) error) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if !db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer db.ops.end()
+	res, closer, err := db.db.Get(key)
+	if err == pebble.ErrNotFound {
+		return fn(nil)
+	}
+	if err != nil {
+		return pebbleError(err)
+	}
+	defer closer.Close()
+	if res == nil {
+		res = []byte{}
+	}
+	return fn(res)
+}
+
 // Has implements DB.
 func (db *PebbleDB) Has(key []byte) (bool, error) {
 	if len(key) == 0 {
@@ -95,32 +318,40 @@ func (db *PebbleDB) Has(key []byte) (bool, error) {
 
 // Set implements DB.
 func (db *PebbleDB) Set(key []byte, value []byte) error {
-	db.written += uint64(len(value))
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
 	if value == nil {
 		return errValueNil
 	}
-	err := db.db.Set(key, value, pebble.NoSync)
+	if !db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer db.ops.end()
+	atomic.AddUint64(&db.written, uint64(len(value)))
+	err := db.db.Set(key, value, db.wo)
 	if err != nil {
-		return err
+		return pebbleError(err)
 	}
 	return nil
 }
 
 // SetSync implements DB.
 func (db *PebbleDB) SetSync(key []byte, value []byte) error {
-	db.written += uint64(len(value))
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
 	if value == nil {
 		return errValueNil
 	}
+	if !db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer db.ops.end()
+	atomic.AddUint64(&db.written, uint64(len(value)))
 	err := db.db.Set(key, value, pebble.Sync)
 	if err != nil {
-		return err
+		return pebbleError(err)
 	}
 	return nil
 }
@@ -130,21 +361,29 @@ func (db *PebbleDB) Delete(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
-	err := db.db.Delete(key, pebble.NoSync)
+	if !db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer db.ops.end()
+	err := db.db.Delete(key, db.wo)
 	if err != nil {
-		return err
+		return pebbleError(err)
 	}
 	return nil
 }
 
 // DeleteSync implements DB.
-func (db PebbleDB) DeleteSync(key []byte) error {
+func (db *PebbleDB) DeleteSync(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
+	if !db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer db.ops.end()
 	err := db.db.Delete(key, pebble.Sync)
 	if err != nil {
-		return nil
+		return pebbleError(err)
 	}
 	return nil
 }
@@ -153,10 +392,20 @@ func (db *PebbleDB) DB() *pebble.DB {
 	return db.db
 }
 
-// Close implements DB.
-func (db PebbleDB) Close() error {
-	db.db.Close()
-	return nil
+// Close implements DB. It waits for the background goroutines and the
+// operations in flight, such as batch writes, after which the operations fail
+// with ErrClosed.
+func (db *PebbleDB) Close() error {
+	defer db.lock.release()
+	db.closeOnce.Do(func() {
+		close(db.quit)
+	})
+	db.wg.Wait()
+	if !db.ops.close() {
+		return errPebbleClosed
+	}
+	db.iters.close()
+	return pebbleError(db.db.Close())
 }
 
 // Print implements DB.
@@ -176,9 +425,375 @@ func (db *PebbleDB) Print() error {
 
 // Stats implements DB.
 func (db *PebbleDB) Stats() map[string]string {
+	if !db.ops.begin() {
+		return map[string]string{}
+	}
+	defer db.ops.end()
+	m := db.db.Metrics()
+	stats := make(map[string]string)
+	stats["pebble.stats"] = m.String()
+	stats["pebble.disk-space-usage"] = fmt.Sprintf("%d", m.DiskSpaceUsage())
+	stats["pebble.read-amp"] = fmt.Sprintf("%d", m.ReadAmp())
+	return stats
+}
+
+// Metrics implements MetricsReporter. The compaction bytes do not include
+// memtable flushes, which pebble does not count as compactions.
+func (db *PebbleDB) Metrics() (Metrics, error) {
+	if !db.ops.begin() {
+		return Metrics{}, errPebbleClosed
+	}
+	defer db.ops.end()
+	pm := db.db.Metrics()
+	m := Metrics{
+		Levels:       make([]LevelMetrics, len(pm.Levels)),
+		DiskSize:     pm.DiskSpaceUsage(),
+		MemTableSize: pm.MemTable.Size,
+		BlockCache: CacheMetrics{
+			Size:   uint64(pm.BlockCache.Size),
+			Hits:   uint64(pm.BlockCache.Hits),
+			Misses: uint64(pm.BlockCache.Misses),
+		},
+		Compaction: CompactionMetrics{
+			Count:        uint64(pm.Compact.Count),
+			Running:      pm.Compact.NumInProgress,
+			PendingBytes: pm.Compact.EstimatedDebt,
+		},
+		Snapshots:    int64(pm.Snapshots.Count),
+		WriteStalled: db.health.stalled.Load(),
+		WriteStalls:  db.health.stalls.Load(),
+	}
+	for i, level := range pm.Levels {
+		m.Levels[i] = LevelMetrics{Files: level.NumFiles, Size: uint64(level.Size)}
+		m.Compaction.ReadBytes += level.BytesRead
+		m.Compaction.WriteBytes += level.BytesCompacted
+	}
+	return m, nil
+}
+
+// DiskUsage implements DiskUsageReporter, with the live sstables of pebble.
+// The WAL is counted wherever it is, as long as it is under the directory of
+// the database. It returns ErrNotSupported for databases kept in memory or in
+// Options.FS.
+func (db *PebbleDB) DiskUsage() (DiskUsageReport, error) {
+	if db.path == "" {
+		return DiskUsageReport{}, ErrNotSupported
+	}
+	if !db.ops.begin() {
+		return DiskUsageReport{}, errPebbleClosed
+	}
+	defer db.ops.end()
+	levels, err := db.db.SSTables()
+	if err != nil {
+		return DiskUsageReport{}, pebbleError(err)
+	}
+	live := make(map[uint64]struct{})
+	for _, tables := range levels {
+		for _, t := range tables {
+			live[uint64(t.FileNum)] = struct{}{}
+		}
+	}
+	return diskUsage(db.path, live)
+}
+
+// Flush implements Flusher, flushing the memtable to an sstable.
+func (db *PebbleDB) Flush() error {
+	if !db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer db.ops.end()
+	return pebbleError(db.db.Flush())
+}
+
+// Sync implements Syncer, syncing the WAL with an empty log record.
+func (db *PebbleDB) Sync() error {
+	if !db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer db.ops.end()
+	return pebbleError(db.db.LogData(nil, pebble.Sync))
+}
+
+// Backup implements Backupper with a checkpoint of the database in targetDir.
+// Since the sstables are never modified, hard links are as safe as copies. The
+// context is only checked before starting.
+func (db *PebbleDB) Backup(ctx context.Context, targetDir string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return db.Checkpoint(filepath.Join(targetDir, db.name+".db"))
+}
+
+// Checkpoint implements Checkpointer. The sstables are hard linked when path is
+// on the same filesystem and copied otherwise, while the WAL is synced and
+// copied.
+func (db *PebbleDB) Checkpoint(path string) error {
+	if !db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer db.ops.end()
+	return pebbleError(db.db.Checkpoint(path, pebble.WithFlushedWAL()))
+}
+
+// Merge implements Merger, with the merger of the pebble options: the
+// MergeOperator given to NewDB, or by default pebble's concatenation of the
+// values.
+func (db *PebbleDB) Merge(key, operand []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if operand == nil {
+		return errValueNil
+	}
+	if !db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer db.ops.end()
+	atomic.AddUint64(&db.written, uint64(len(operand)))
+	return pebbleError(db.db.Merge(key, operand, db.wo))
+}
+
+// pebbleMerger returns the pebble merger of op.
+func pebbleMerger(op MergeOperator) *pebble.Merger {
+	return &pebble.Merger{
+		Name: op.Name(),
+		Merge: func(key, value []byte) (pebble.ValueMerger, error) {
+			return &pebbleValueMerger{op: op, key: cp(key), value: cp(value)}, nil
+		},
+	}
+}
+
+// pebbleValueMerger combines the operands of a key with a MergeOperator, as
+// they are passed by pebble.
+type pebbleValueMerger struct {
+	op    MergeOperator
+	key   []byte
+	value []byte
+}
+
+func (m *pebbleValueMerger) MergeNewer(value []byte) (err error) {
+	m.value, err = m.op.Merge(m.key, m.value, value)
+	return err
+}
+
+func (m *pebbleValueMerger) MergeOlder(value []byte) (err error) {
+	m.value, err = m.op.Merge(m.key, value, m.value)
+	return err
+}
+
+func (m *pebbleValueMerger) Finish(bool) ([]byte, io.Closer, error) {
+	return m.value, nil, nil
+}
+
+// HealthCheck implements HealthChecker, with the write stalls and background
+// corruption reported by the events of pebble.
+func (db *PebbleDB) HealthCheck(context.Context) error {
+	return db.health.check()
+}
+
+// pebbleHealth tracks the health of a PebbleDB through its event listener.
+type pebbleHealth struct {
+	stalled atomic.Bool
+	stalls  atomic.Uint64
+	onStall func(WriteStallInfo) // Options.OnWriteStall, if set
+
+	mtx sync.Mutex
+	err error // the first corruption found by a background operation
+}
+
+func (h *pebbleHealth) listener() pebble.EventListener {
+	return pebble.EventListener{
+		WriteStallBegin: func(info pebble.WriteStallBeginInfo) {
+			h.stalled.Store(true)
+			h.stalls.Add(1)
+			if h.onStall != nil {
+				h.onStall(WriteStallInfo{Stalled: true, Reason: info.Reason})
+			}
+		},
+		WriteStallEnd: func() {
+			h.stalled.Store(false)
+			if h.onStall != nil {
+				h.onStall(WriteStallInfo{})
+			}
+		},
+		BackgroundError: func(err error) {
+			if !errors.Is(err, pebble.ErrCorruption) {
+				return
+			}
+			h.mtx.Lock()
+			defer h.mtx.Unlock()
+			if h.err == nil {
+				h.err = wrapError(ErrCorrupted, err)
+			}
+		},
+	}
+}
+
+// pebbleCompactionListener reports the completed compactions and flushes of
+// pebble to fn.
+func pebbleCompactionListener(fn func(CompactionInfo)) pebble.EventListener {
+	tablesSize := func(tables []pebble.TableInfo) uint64 {
+		var size uint64
+		for _, t := range tables {
+			size += t.Size
+		}
+		return size
+	}
+	return pebble.EventListener{
+		CompactionEnd: func(i pebble.CompactionInfo) {
+			info := CompactionInfo{
+				Kind:        CompactionKindCompaction,
+				Reason:      i.Reason,
+				OutputLevel: i.Output.Level,
+				OutputBytes: tablesSize(i.Output.Tables),
+				Duration:    i.TotalDuration,
+				Err:         i.Err,
+			}
+			for _, level := range i.Input {
+				info.InputLevels = append(info.InputLevels, level.Level)
+				info.InputBytes += tablesSize(level.Tables)
+			}
+			fn(info)
+		},
+		FlushEnd: func(i pebble.FlushInfo) {
+			fn(CompactionInfo{
+				Kind:        CompactionKindFlush,
+				Reason:      i.Reason,
+				OutputBytes: tablesSize(i.Output),
+				Duration:    i.TotalDuration,
+				Err:         i.Err,
+			})
+		},
+	}
+}
+
+func (h *pebbleHealth) check() error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	if h.err != nil {
+		return h.err
+	}
+	if h.stalled.Load() {
+		return ErrWriteStalled
+	}
 	return nil
 }
 
+// Compact implements Compacter.
+func (db *PebbleDB) Compact(start, end []byte) error {
+	if !db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer db.ops.end()
+	if end == nil {
+		// pebble needs an upper bound: use the key right after the last one.
+		last, err := db.lastKey()
+		if err != nil {
+			return err
+		}
+		if last == nil {
+			// Empty database
+			return nil
+		}
+		end = rangeUpperBound(last)
+	}
+	if bytes.Compare(start, end) >= 0 {
+		return nil
+	}
+	return pebbleError(db.db.Compact(start, end, true))
+}
+
+// EstimateSize implements SizeEstimator, using the disk usage of the sstables
+// overlapping the range. Data still in the memtable is not accounted for.
+func (db *PebbleDB) EstimateSize(start, end []byte) (uint64, error) {
+	if !db.ops.begin() {
+		return 0, errPebbleClosed
+	}
+	defer db.ops.end()
+	if end == nil {
+		// pebble needs an upper bound: use the key right after the last one.
+		last, err := db.lastKey()
+		if err != nil {
+			return 0, err
+		}
+		if last == nil {
+			// Empty database
+			return 0, nil
+		}
+		end = rangeUpperBound(last)
+	}
+	if bytes.Compare(start, end) >= 0 {
+		return 0, nil
+	}
+	size, err := db.db.EstimateDiskUsage(start, end)
+	return size, pebbleError(err)
+}
+
+// EstimateCount implements CountEstimator, summing the entries of the sstables
+// minus their deletion tombstones. Data still in the memtable is not accounted
+// for.
+func (db *PebbleDB) EstimateCount() (uint64, error) {
+	deletions, entries, err := db.EstimateTombstones()
+	if err != nil {
+		return 0, err
+	}
+	if deletions > entries {
+		return 0, nil
+	}
+	return entries - deletions, nil
+}
+
+// EstimateTombstones implements TombstoneEstimator, with the properties of the
+// sstables. Data still in the memtable is not accounted for.
+func (db *PebbleDB) EstimateTombstones() (tombstones, entries uint64, err error) {
+	if !db.ops.begin() {
+		return 0, 0, errPebbleClosed
+	}
+	defer db.ops.end()
+	levels, err := db.db.SSTables(pebble.WithProperties())
+	if err != nil {
+		return 0, 0, pebbleError(err)
+	}
+	for _, tables := range levels {
+		for _, table := range tables {
+			entries += table.Properties.NumEntries
+			tombstones += table.Properties.NumDeletions
+		}
+	}
+	return tombstones, entries, nil
+}
+
+// DeleteRange implements RangeDeleter.
+func (db *PebbleDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	b := newPebbleDBBatch(db)
+	defer b.Close()
+	if err := b.DeleteRange(start, end); err != nil {
+		return err
+	}
+	return b.Write()
+}
+
+// lastKey returns a copy of the last key in the database, or nil if it is
+// empty.
+func (db *PebbleDB) lastKey() ([]byte, error) {
+	if !db.ops.begin() {
+		return nil, errPebbleClosed
+	}
+	defer db.ops.end()
+	var last []byte
+	itr := db.db.NewIter(nil)
+	if itr.Last() {
+		last = cp(itr.Key())
+	}
+	if err := itr.Close(); err != nil {
+		return nil, pebbleError(err)
+	}
+	return last, nil
+}
+
 // NewBatch implements DB.
 func (db *PebbleDB) NewBatch() Batch {
 	return newPebbleDBBatch(db)
@@ -189,14 +804,16 @@ func (db *PebbleDB) Iterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
+	if !db.ops.begin() {
+		return nil, errPebbleClosed
+	}
+	defer db.ops.end()
 	o := pebble.IterOptions{
 		LowerBound: start,
 		UpperBound: end,
 	}
 	itr := db.db.NewIter(&o)
-	itr.First()
-
-	return newPebbleDBIterator(itr, start, end, false), nil
+	return db.iters.track(newPebbleDBIterator(itr, start, end, false), nil)
 }
 
 // ReverseIterator implements DB.
@@ -204,23 +821,31 @@ func (db *PebbleDB) ReverseIterator(start, end []byte) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
+	if !db.ops.begin() {
+		return nil, errPebbleClosed
+	}
+	defer db.ops.end()
 	o := pebble.IterOptions{
 		LowerBound: start,
 		UpperBound: end,
 	}
 	itr := db.db.NewIter(&o)
-	itr.Last()
-	return newPebbleDBIterator(itr, start, end, true), nil
+	return db.iters.track(newPebbleDBIterator(itr, start, end, true), nil)
 }
 
-var _ Batch = (*pebbleDBBatch)(nil)
-
 type pebbleDBBatch struct {
 	db    *PebbleDB
 	batch *pebble.Batch
+	size  int
+	// maxKey is the largest key set so far, used to bound DeleteRange calls
+	// with a nil end.
+	maxKey []byte
 }
 
-var _ Batch = (*pebbleDBBatch)(nil)
+var (
+	_ Batch         = (*pebbleDBBatch)(nil)
+	_ BatchResetter = (*pebbleDBBatch)(nil)
+)
 
 func newPebbleDBBatch(db *PebbleDB) *pebbleDBBatch {
 	return &pebbleDBBatch{
@@ -239,9 +864,13 @@ func (b *pebbleDBBatch) Set(key, value []byte) error {
 		return errValueNil
 	}
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.batch.Set(key, value, nil)
+	b.size += len(key) + len(value)
+	if bytes.Compare(key, b.maxKey) > 0 {
+		b.maxKey = cp(key)
+	}
 	return nil
 }
 
@@ -252,23 +881,75 @@ func (b *pebbleDBBatch) Delete(key []byte) error {
 		return errKeyEmpty
 	}
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.batch.Delete(key, nil)
+	b.size += len(key)
 	return nil
 }
 
+// DeleteRange implements RangeDeleter.
+func (b *pebbleDBBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if b.batch == nil {
+		return ErrBatchWritten
+	}
+	if end == nil {
+		// pebble needs an upper bound: use the key right after the last one,
+		// either in the database or set earlier in this batch.
+		last, err := b.db.lastKey()
+		if err != nil {
+			return err
+		}
+		end = rangeUpperBound(maxKey(last, b.maxKey))
+		if end == nil {
+			// Nothing to delete
+			return nil
+		}
+	}
+	if bytes.Compare(start, end) >= 0 {
+		return nil
+	}
+	if err := b.batch.DeleteRange(start, end, nil); err != nil {
+		return pebbleError(err)
+	}
+	b.size += len(start) + len(end)
+	return nil
+}
+
+// Count implements Batch.
+func (b *pebbleDBBatch) Count() int {
+	if b.batch == nil {
+		return 0
+	}
+	return int(b.batch.Count())
+}
+
+// SizeBytes implements Batch.
+func (b *pebbleDBBatch) SizeBytes() int {
+	if b.batch == nil {
+		return 0
+	}
+	return b.size
+}
+
 // Write implements Batch.
 func (b *pebbleDBBatch) Write() error {
 	// fmt.Println("pebbleDBBatch.Write")
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
-	b.db.written += uint64(b.batch.Len())
+	if !b.db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer b.db.ops.end()
+	atomic.AddUint64(&b.db.written, uint64(b.batch.Len()))
 
-	err := b.batch.Commit(pebble.NoSync)
+	err := b.batch.Commit(b.db.wo)
 	if err != nil {
-		return err
+		return pebbleError(err)
 	}
 	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
 
@@ -279,17 +960,34 @@ func (b *pebbleDBBatch) Write() error {
 func (b *pebbleDBBatch) WriteSync() error {
 	// fmt.Println("pebbleDBBatch.WriteSync")
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
-	b.db.written += uint64(b.batch.Len())
+	if !b.db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer b.db.ops.end()
+	atomic.AddUint64(&b.db.written, uint64(b.batch.Len()))
 	err := b.batch.Commit(pebble.Sync)
 	if err != nil {
-		return err
+		return pebbleError(err)
 	}
 	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
 	return b.Close()
 }
 
+// Reset implements BatchResetter. Closed batches take a new pebble batch,
+// which pebble pools.
+func (b *pebbleDBBatch) Reset() error {
+	if b.batch == nil {
+		b.batch = b.db.db.NewBatch()
+	} else {
+		b.batch.Reset()
+	}
+	b.size = 0
+	b.maxKey = nil
+	return nil
+}
+
 // Close implements Batch.
 func (b *pebbleDBBatch) Close() error {
 	// fmt.Println("pebbleDBBatch.Close")
@@ -311,17 +1009,15 @@ type pebbleDBIterator struct {
 	isInvalid  bool
 }
 
-var _ Iterator = (*pebbleDBIterator)(nil)
+var _ UnsafeIterator = (*pebbleDBIterator)(nil)
 
 func newPebbleDBIterator(source *pebble.Iterator, start, end []byte, isReverse bool) *pebbleDBIterator {
+	// The iterator bounds are set via IterOptions, so positioning at the first
+	// (or last) key is all that is needed.
 	if isReverse {
-		if end == nil {
-			source.Last()
-		}
+		source.Last()
 	} else {
-		if start == nil {
-			source.First()
-		}
+		source.First()
 	}
 	return &pebbleDBIterator{
 		source:    source,
@@ -396,8 +1092,20 @@ func (itr *pebbleDBIterator) Value() []byte {
 	return cp(itr.source.Value())
 }
 
+// KeyUnsafe implements UnsafeIterator.
+func (itr *pebbleDBIterator) KeyUnsafe() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()
+}
+
+// ValueUnsafe implements UnsafeIterator.
+func (itr *pebbleDBIterator) ValueUnsafe() []byte {
+	itr.assertIsValid()
+	return itr.source.Value()
+}
+
 // Next implements Iterator.
-func (itr pebbleDBIterator) Next() {
+func (itr *pebbleDBIterator) Next() {
 	// fmt.Println("pebbleDBIterator.Next")
 	itr.assertIsValid()
 	if itr.isReverse {
@@ -409,7 +1117,7 @@ func (itr pebbleDBIterator) Next() {
 
 // Error implements Iterator.
 func (itr *pebbleDBIterator) Error() error {
-	return itr.source.Error()
+	return pebbleError(itr.source.Error())
 }
 
 // Close implements Iterator.
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"github.com/cockroachdb/pebble/objstorage"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+var _ Ingester = (*PebbleDB)(nil)
+
+// NewSSTWriter implements Ingester, with the table format and options of the
+// database.
+func (db *PebbleDB) NewSSTWriter(path string) (SSTWriter, error) {
+	if !db.ops.begin() {
+		return nil, errPebbleClosed
+	}
+	defer db.ops.end()
+	f, err := vfs.Default.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	writable := objstorage.NewFileWritable(f)
+	opts := db.opts.MakeWriterOptions(0, db.db.FormatMajorVersion().MaxTableFormat())
+	return &pebbleSSTWriter{w: sstable.NewWriter(writable, opts), writable: writable}, nil
+}
+
+// IngestExternalFiles implements Ingester.
+func (db *PebbleDB) IngestExternalFiles(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	if !db.ops.begin() {
+		return errPebbleClosed
+	}
+	defer db.ops.end()
+	return pebbleError(db.db.Ingest(paths))
+}
+
+type pebbleSSTWriter struct {
+	w        *sstable.Writer
+	writable objstorage.Writable
+	done     bool
+}
+
+var _ SSTWriter = (*pebbleSSTWriter)(nil)
+
+// Set implements SSTWriter.
+func (w *pebbleSSTWriter) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if w.done {
+		return errSSTWriterDone
+	}
+	return pebbleError(w.w.Set(key, value))
+}
+
+// Delete implements SSTWriter.
+func (w *pebbleSSTWriter) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if w.done {
+		return errSSTWriterDone
+	}
+	return pebbleError(w.w.Delete(key))
+}
+
+// Finish implements SSTWriter. Closing the sstable writer finishes and syncs
+// the file.
+func (w *pebbleSSTWriter) Finish() error {
+	if w.done {
+		return errSSTWriterDone
+	}
+	w.done = true
+	return pebbleError(w.w.Close())
+}
+
+// Close implements SSTWriter.
+func (w *pebbleSSTWriter) Close() error {
+	if !w.done {
+		w.done = true
+		w.writable.Abort()
+	}
+	return nil
+}
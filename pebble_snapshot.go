@@ -0,0 +1,81 @@
+package db
+
+import "github.com/cockroachdb/pebble"
+
+type pebbleDBSnapshot struct {
+	snap  *pebble.Snapshot
+	iters *iteratorTracker
+	ops   *opTracker // of the database, whose snapshots fail once closed
+}
+
+var _ DBReader = (*pebbleDBSnapshot)(nil)
+
+// Snapshot implements Snapshotter.
+func (db *PebbleDB) Snapshot() (DBReader, error) {
+	if !db.ops.begin() {
+		return nil, errPebbleClosed
+	}
+	defer db.ops.end()
+	return &pebbleDBSnapshot{snap: db.db.NewSnapshot(), iters: db.iters, ops: db.ops}, nil
+}
+
+// Get implements DBReader.
+func (s *pebbleDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	if !s.ops.begin() {
+		return nil, errPebbleClosed
+	}
+	defer s.ops.end()
+	res, closer, err := s.snap.Get(key)
+	if err != nil {
+		if err == pebble.ErrNotFound {
+			return nil, nil
+		}
+		return nil, pebbleError(err)
+	}
+	defer closer.Close()
+
+	return cp(res), nil
+}
+
+// Has implements DBReader.
+func (s *pebbleDBSnapshot) Has(key []byte) (bool, error) {
+	bytes, err := s.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return bytes != nil, nil
+}
+
+// Iterator implements DBReader.
+func (s *pebbleDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if !s.ops.begin() {
+		return nil, errPebbleClosed
+	}
+	defer s.ops.end()
+	itr := s.snap.NewIter(&pebble.IterOptions{LowerBound: start, UpperBound: end})
+	return s.iters.track(newPebbleDBIterator(itr, start, end, false), nil)
+}
+
+// ReverseIterator implements DBReader.
+func (s *pebbleDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if !s.ops.begin() {
+		return nil, errPebbleClosed
+	}
+	defer s.ops.end()
+	itr := s.snap.NewIter(&pebble.IterOptions{LowerBound: start, UpperBound: end})
+	return s.iters.track(newPebbleDBIterator(itr, start, end, true), nil)
+}
+
+// Close implements DBReader.
+func (s *pebbleDBSnapshot) Close() error {
+	return s.snap.Close()
+}
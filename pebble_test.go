@@ -3,7 +3,9 @@ package db
 import (
 	"fmt"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -20,15 +22,83 @@ func TestPebbleDBBackend(t *testing.T) {
 	assert.True(t, ok)
 }
 
-// func TestPebbleDBStats(t *testing.T) {
-// 	name := fmt.Sprintf("test_%x", randStr(12))
-// 	dir := os.TempDir()
-// 	db, err := NewDB(name, PebbleDBBackend, dir)
-// 	require.NoError(t, err)
-// 	defer cleanupDBDir(dir, name)
+func TestPebbleDBStats(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, PebbleDBBackend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+
+	assert.NotEmpty(t, db.Stats())
+}
+
+func TestPebbleDBClosed(t *testing.T) {
+	db, err := NewDB("db", PebbleDBBackend, t.TempDir(), WithLogger(&testLogger{}), WithStatsInterval(time.Millisecond))
+	require.NoError(t, err)
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	batch := db.NewBatch()
+	require.NoError(t, batch.Set(bz("b"), bz("2")))
+	require.NoError(t, db.Close())
 
-// 	assert.NotEmpty(t, db.Stats())
-// }
+	// The operations fail rather than panic in pebble.
+	_, err = db.Get(bz("a"))
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.ErrorIs(t, db.Set(bz("a"), bz("2")), ErrClosed)
+	assert.ErrorIs(t, db.DeleteSync(bz("a")), ErrClosed)
+	assert.ErrorIs(t, batch.Write(), ErrClosed)
+	require.NoError(t, batch.Close())
+	_, err = db.Iterator(nil, nil)
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = db.(Snapshotter).Snapshot()
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.ErrorIs(t, Compact(db, nil, nil), ErrClosed)
+	assert.Empty(t, db.Stats())
+	assert.ErrorIs(t, db.Close(), ErrClosed)
+}
+
+func TestPebbleDBCloseWaitsForWrites(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB("db", PebbleDBBackend, dir)
+	require.NoError(t, err)
+
+	const writers, batches = 8, 100
+	var (
+		wg      sync.WaitGroup
+		mtx     sync.Mutex
+		written [][]byte
+	)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < batches; i++ {
+				key := bz(fmt.Sprintf("%d/%d", w, i))
+				batch := db.NewBatch()
+				assert.NoError(t, batch.Set(key, key))
+				err := batch.Write()
+				batch.Close()
+				if err != nil {
+					assert.ErrorIs(t, err, ErrClosed)
+					return
+				}
+				mtx.Lock()
+				written = append(written, key)
+				mtx.Unlock()
+			}
+		}(w)
+	}
+	time.Sleep(time.Millisecond)
+	require.NoError(t, db.Close())
+	wg.Wait()
+
+	// The writes which succeeded were completed before the database closed.
+	db, err = NewDB("db", PebbleDBBackend, dir)
+	require.NoError(t, err)
+	defer db.Close()
+	for _, key := range written {
+		checkValue(t, db, key, key)
+	}
+}
 
 func BenchmarkPebbleDBRandomReadsWrites(b *testing.B) {
 	name := fmt.Sprintf("test_%x", randStr(12))
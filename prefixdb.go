@@ -1,8 +1,10 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"sync"
+	"time"
 )
 
 // PrefixDB wraps a namespace of another database as a logical database.
@@ -14,10 +16,18 @@ type PrefixDB struct {
 
 var _ DB = (*PrefixDB)(nil)
 
-// NewPrefixDB lets you namespace multiple DBs within a single DB.
+// NewPrefixDB lets you namespace multiple DBs within a single DB. The prefix
+// is copied. Prefixing a PrefixDB nests the namespaces: the result uses the
+// concatenated prefixes on the same underlying database.
 func NewPrefixDB(db DB, prefix []byte) *PrefixDB {
+	if inner, ok := db.(*PrefixDB); ok {
+		return &PrefixDB{
+			prefix: append(cp(inner.prefix), prefix...),
+			db:     inner.db,
+		}
+	}
 	return &PrefixDB{
-		prefix: prefix,
+		prefix: cp(prefix),
 		db:     db,
 	}
 }
@@ -38,6 +48,43 @@ func (pdb *PrefixDB) Get(key []byte) ([]byte, error) {
 	return value, nil
 }
 
+// GetWithOptions implements ReadOptionsDB, passing the options on to the
+// underlying database.
+func (pdb *PrefixDB) GetWithOptions(key []byte, opts ReadOptions) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	pdb.mtx.Lock()
+	defer pdb.mtx.Unlock()
+	return GetWithOptions(pdb.db, pdb.prefixed(key), opts)
+}
+
+// GetUnsafe implements UnsafeGetter, with the zero-copy read of the underlying
+// database if it supports it.
+func (pdb *PrefixDB) GetUnsafe(key []byte, fn func(value []byte) error) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	pdb.mtx.Lock()
+	defer pdb.mtx.Unlock()
+	return GetUnsafe(pdb.db, pdb.prefixed(key), fn)
+}
+
+// MultiGet implements MultiGetter.
+func (pdb *PrefixDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	pkeys := make([][]byte, len(keys))
+	for i, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+		pkeys[i] = pdb.prefixed(key)
+	}
+	pdb.mtx.Lock()
+	defer pdb.mtx.Unlock()
+
+	return MultiGet(pdb.db, pkeys)
+}
+
 // Has implements DB.
 func (pdb *PrefixDB) Has(key []byte) (bool, error) {
 	if len(key) == 0 {
@@ -110,48 +157,30 @@ func (pdb *PrefixDB) DeleteSync(key []byte) error {
 
 // Iterator implements DB.
 func (pdb *PrefixDB) Iterator(start, end []byte) (Iterator, error) {
-	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
-		return nil, errKeyEmpty
-	}
-	pdb.mtx.Lock()
-	defer pdb.mtx.Unlock()
-
-	var pstart, pend []byte
-	pstart = append(cp(pdb.prefix), start...)
-	if end == nil {
-		pend = cpIncr(pdb.prefix)
-	} else {
-		pend = append(cp(pdb.prefix), end...)
-	}
-	itr, err := pdb.db.Iterator(pstart, pend)
-	if err != nil {
-		return nil, err
-	}
-
-	return newPrefixIterator(pdb.prefix, start, end, itr)
+	return pdb.IteratorWithOptions(start, end, IteratorOptions{})
 }
 
 // ReverseIterator implements DB.
 func (pdb *PrefixDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return pdb.IteratorWithOptions(start, end, IteratorOptions{Reverse: true})
+}
+
+// IteratorWithOptions implements IteratorOptionsDB, passing the options on to
+// the underlying database.
+func (pdb *PrefixDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
 	pdb.mtx.Lock()
 	defer pdb.mtx.Unlock()
 
-	var pstart, pend []byte
-	pstart = append(cp(pdb.prefix), start...)
-	if end == nil {
-		pend = cpIncr(pdb.prefix)
-	} else {
-		pend = append(cp(pdb.prefix), end...)
-	}
-	ritr, err := pdb.db.ReverseIterator(pstart, pend)
+	pstart, pend := prefixRange(pdb.prefix, start, end)
+	itr, err := IteratorWithOptions(pdb.db, pstart, pend, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	return newPrefixIterator(pdb.prefix, start, end, ritr)
+	return newPrefixIterator(pdb.prefix, start, end, itr)
 }
 
 // NewBatch implements DB.
@@ -159,7 +188,7 @@ func (pdb *PrefixDB) NewBatch() Batch {
 	pdb.mtx.Lock()
 	defer pdb.mtx.Unlock()
 
-	return newPrefixBatch(pdb.prefix, pdb.db.NewBatch())
+	return newPrefixBatch(pdb.db, pdb.prefix, pdb.db.NewBatch())
 }
 
 // Close implements DB.
@@ -199,6 +228,117 @@ func (pdb *PrefixDB) Stats() map[string]string {
 	return stats
 }
 
+// Metrics implements MetricsReporter, with the metrics of the whole underlying
+// database.
+func (pdb *PrefixDB) Metrics() (Metrics, error) {
+	return GetMetrics(pdb.db)
+}
+
+// Compact implements Compacter, compacting the range within the prefix.
+func (pdb *PrefixDB) Compact(start, end []byte) error {
+	pstart, pend := prefixRange(pdb.prefix, start, end)
+	return Compact(pdb.db, pstart, pend)
+}
+
+// Flush implements Flusher, flushing the whole underlying database.
+func (pdb *PrefixDB) Flush() error {
+	return Flush(pdb.db)
+}
+
+// Sync implements Syncer, syncing the whole underlying database.
+func (pdb *PrefixDB) Sync() error {
+	return Sync(pdb.db)
+}
+
+// Backup implements Backupper, backing up the whole underlying database, from
+// which the PrefixDB can be recreated.
+func (pdb *PrefixDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, pdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, checkpointing the whole underlying
+// database.
+func (pdb *PrefixDB) Checkpoint(path string) error {
+	return Checkpoint(pdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (pdb *PrefixDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, pdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which PrefixDB passes through.
+func (pdb *PrefixDB) Capabilities() Capability {
+	return Capabilities(pdb.db) & (CapSnapshot | CapDeleteRange | CapTTL | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint | CapGetUnsafe)
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of the
+// underlying database.
+func (pdb *PrefixDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	pdb.mtx.Lock()
+	defer pdb.mtx.Unlock()
+	return CompareAndSwap(pdb.db, pdb.prefixed(key), old, new)
+}
+
+// SetWithTTL implements TTLSetter, if the underlying database supports it.
+func (pdb *PrefixDB) SetWithTTL(key, value []byte, ttl time.Duration) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	pdb.mtx.Lock()
+	defer pdb.mtx.Unlock()
+	return SetWithTTL(pdb.db, pdb.prefixed(key), value, ttl)
+}
+
+// Merge implements Merger, if the underlying database supports it.
+func (pdb *PrefixDB) Merge(key, operand []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	pdb.mtx.Lock()
+	defer pdb.mtx.Unlock()
+	return Merge(pdb.db, pdb.prefixed(key), operand)
+}
+
+// EstimateSize implements SizeEstimator, estimating the range within the
+// prefix. An empty range estimates the whole prefix.
+func (pdb *PrefixDB) EstimateSize(start, end []byte) (uint64, error) {
+	pstart, pend := prefixRange(pdb.prefix, start, end)
+	return EstimateSize(pdb.db, pstart, pend)
+}
+
+// DeleteRange implements RangeDeleter, deleting the range within the prefix.
+func (pdb *PrefixDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	pdb.mtx.Lock()
+	defer pdb.mtx.Unlock()
+
+	pstart, pend := prefixRange(pdb.prefix, start, end)
+	return DeleteRange(pdb.db, pstart, pend)
+}
+
+// prefixRange translates the range [start, end) within prefix to the range of
+// the underlying database. A nil start or end maps to the bounds of prefix.
+func prefixRange(prefix, start, end []byte) (pstart, pend []byte) {
+	pstart = append(cp(prefix), start...)
+	if len(pstart) == 0 {
+		pstart = nil
+	}
+	switch {
+	case end == nil && len(prefix) > 0:
+		pend = prefixEnd(prefix)
+	case end != nil:
+		pend = append(cp(prefix), end...)
+	}
+	return pstart, pend
+}
+
 func (pdb *PrefixDB) prefixed(key []byte) []byte {
 	return append(cp(pdb.prefix), key...)
 }
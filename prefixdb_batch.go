@@ -1,14 +1,19 @@
 package db
 
 type prefixDBBatch struct {
+	db     DB
 	prefix []byte
 	source Batch
 }
 
-var _ Batch = (*prefixDBBatch)(nil)
+var (
+	_ Batch         = (*prefixDBBatch)(nil)
+	_ BatchResetter = (*prefixDBBatch)(nil)
+)
 
-func newPrefixBatch(prefix []byte, source Batch) prefixDBBatch {
+func newPrefixBatch(db DB, prefix []byte, source Batch) prefixDBBatch {
 	return prefixDBBatch{
+		db:     db,
 		prefix: prefix,
 		source: source,
 	}
@@ -35,6 +40,25 @@ func (pb prefixDBBatch) Delete(key []byte) error {
 	return pb.source.Delete(pkey)
 }
 
+// DeleteRange implements RangeDeleter.
+func (pb prefixDBBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	pstart, pend := prefixRange(pb.prefix, start, end)
+	return BatchDeleteRange(pb.db, pb.source, pstart, pend)
+}
+
+// Count implements Batch.
+func (pb prefixDBBatch) Count() int {
+	return pb.source.Count()
+}
+
+// SizeBytes implements Batch.
+func (pb prefixDBBatch) SizeBytes() int {
+	return pb.source.SizeBytes()
+}
+
 // Write implements Batch.
 func (pb prefixDBBatch) Write() error {
 	return pb.source.Write()
@@ -45,6 +69,11 @@ func (pb prefixDBBatch) WriteSync() error {
 	return pb.source.WriteSync()
 }
 
+// Reset implements BatchResetter, if the underlying batch supports it.
+func (pb prefixDBBatch) Reset() error {
+	return ResetBatch(pb.source)
+}
+
 // Close implements Batch.
 func (pb prefixDBBatch) Close() error {
 	return pb.source.Close()
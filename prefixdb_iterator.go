@@ -5,22 +5,34 @@ import (
 	"fmt"
 )
 
+// PrefixIterator returns an iterator over the keys of db starting with prefix,
+// in ascending order. Keys are returned in full, including the prefix. An empty
+// prefix iterates over the whole database.
+func PrefixIterator(db DB, prefix []byte) (Iterator, error) {
+	start, end := prefixDomain(prefix)
+	return db.Iterator(start, end)
+}
+
+// ReversePrefixIterator is like PrefixIterator, in descending order.
+func ReversePrefixIterator(db DB, prefix []byte) (Iterator, error) {
+	start, end := prefixDomain(prefix)
+	return db.ReverseIterator(start, end)
+}
+
 // IteratePrefix is a convenience function for iterating over a key domain
 // restricted by prefix.
+//
+// Deprecated: use PrefixIterator.
 func IteratePrefix(db DB, prefix []byte) (Iterator, error) {
-	var start, end []byte
+	return PrefixIterator(db, prefix)
+}
+
+// prefixDomain returns the iteration domain of the keys starting with prefix.
+func prefixDomain(prefix []byte) (start, end []byte) {
 	if len(prefix) == 0 {
-		start = nil
-		end = nil
-	} else {
-		start = cp(prefix)
-		end = cpIncr(prefix)
-	}
-	itr, err := db.Iterator(start, end)
-	if err != nil {
-		return nil, err
+		return nil, nil
 	}
-	return itr, nil
+	return cp(prefix), prefixEnd(prefix)
 }
 
 // Strips prefix while iterating from Iterator.
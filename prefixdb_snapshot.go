@@ -0,0 +1,69 @@
+package db
+
+// prefixDBSnapshot is a view of a snapshot of the underlying database limited
+// to the prefix.
+type prefixDBSnapshot struct {
+	prefix []byte
+	source DBReader
+}
+
+var _ DBReader = (*prefixDBSnapshot)(nil)
+
+// Snapshot implements Snapshotter, if the underlying database does.
+func (pdb *PrefixDB) Snapshot() (DBReader, error) {
+	pdb.mtx.Lock()
+	defer pdb.mtx.Unlock()
+
+	source, err := Snapshot(pdb.db)
+	if err != nil {
+		return nil, err
+	}
+	return &prefixDBSnapshot{prefix: pdb.prefix, source: source}, nil
+}
+
+// Get implements DBReader.
+func (s *prefixDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	return s.source.Get(append(cp(s.prefix), key...))
+}
+
+// Has implements DBReader.
+func (s *prefixDBSnapshot) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	return s.source.Has(append(cp(s.prefix), key...))
+}
+
+// Iterator implements DBReader.
+func (s *prefixDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	pstart, pend := prefixRange(s.prefix, start, end)
+	itr, err := s.source.Iterator(pstart, pend)
+	if err != nil {
+		return nil, err
+	}
+	return newPrefixIterator(s.prefix, start, end, itr)
+}
+
+// ReverseIterator implements DBReader.
+func (s *prefixDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	pstart, pend := prefixRange(s.prefix, start, end)
+	itr, err := s.source.ReverseIterator(pstart, pend)
+	if err != nil {
+		return nil, err
+	}
+	return newPrefixIterator(s.prefix, start, end, itr)
+}
+
+// Close implements DBReader.
+func (s *prefixDBSnapshot) Close() error {
+	return s.source.Close()
+}
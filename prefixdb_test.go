@@ -115,3 +115,78 @@ func TestPrefixDBReverseIterator7(t *testing.T) {
 	checkInvalid(t, itr)
 	itr.Close()
 }
+
+func TestPrefixDBNested(t *testing.T) {
+	db := mockDBWithStuff(t)
+	prefix := bz("ke")
+	pdb := NewPrefixDB(NewPrefixDB(db, prefix), bz("y"))
+	prefix[0] = 'x' // the prefix is copied
+
+	checkValue(t, pdb, bz("1"), bz("value1"))
+	require.NoError(t, pdb.Set(bz("4"), bz("value4")))
+	checkValue(t, db, bz("key4"), bz("value4"))
+
+	itr, err := pdb.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	for _, key := range []string{"4", "3", "2", "1"} {
+		checkItem(t, itr, bz(key), bz("value"+key))
+		checkNext(t, itr, key != "1")
+	}
+	itr.Close()
+}
+
+func TestPrefixDBEmptyPrefix(t *testing.T) {
+	db := mockDBWithStuff(t)
+	pdb := NewPrefixDB(db, nil)
+
+	checkValue(t, pdb, bz("key1"), bz("value1"))
+	itr, err := pdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("k"), bz("val"))
+	itr.Close()
+
+	itr, err = pdb.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("something"), bz("else"))
+	itr.Close()
+}
+
+func TestPrefixDBReverseIteratorEdges(t *testing.T) {
+	db := NewMemDB()
+	for _, key := range [][]byte{{0x01}, {0x01, 0x00}, {0x01, 0xff}, {0x02}, {0xff}, {0xff, 0xff}, {0xff, 0xff, 0x01}} {
+		require.NoError(t, db.Set(key, key))
+	}
+
+	// The key equal to the prefix and the neighbouring keys are not visible.
+	pdb := NewPrefixDB(db, []byte{0x01})
+	itr, err := pdb.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte{0xff}, []byte{0x01, 0xff})
+	checkNext(t, itr, true)
+	checkItem(t, itr, []byte{0x00}, []byte{0x01, 0x00})
+	checkNext(t, itr, false)
+	itr.Close()
+
+	itr, err = pdb.ReverseIterator(nil, []byte{0xff})
+	require.NoError(t, err)
+	checkItem(t, itr, []byte{0x00}, []byte{0x01, 0x00})
+	checkNext(t, itr, false)
+	itr.Close()
+
+	// A prefix of 0xFF bytes has no upper bound.
+	pdb = NewPrefixDB(db, []byte{0xff})
+	itr, err = pdb.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte{0xff, 0x01}, []byte{0xff, 0xff, 0x01})
+	checkNext(t, itr, true)
+	checkItem(t, itr, []byte{0xff}, []byte{0xff, 0xff})
+	checkNext(t, itr, false)
+	itr.Close()
+
+	pdb = NewPrefixDB(db, []byte{0xff, 0xff})
+	itr, err = pdb.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte{0x01}, []byte{0xff, 0xff, 0x01})
+	checkNext(t, itr, false)
+	itr.Close()
+}
@@ -0,0 +1,240 @@
+package db
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// ProfileLabelDB runs the operations on a database with the pprof labels
+// db=<name> and op=<operation>, the operations being named as in MetricsDB, so
+// that the CPU, goroutine and block profiles of a node attribute the time
+// spent in the database, e.g. in writes slowed down by compactions or in long
+// scans, to a specific store. The iterators apply the labels while moving to
+// the next item.
+//
+// The labels of a goroutine are set from a context: the ContextDB methods, and
+// the context helpers such as GetContext, add the labels to those of their
+// context and restore the latter afterwards. The other operations, including
+// batch writes, run with the labels of the database only, and leave the
+// goroutine without labels.
+type ProfileLabelDB struct {
+	db   DB
+	name string
+}
+
+var (
+	_ DB        = (*ProfileLabelDB)(nil)
+	_ ContextDB = (*ProfileLabelDB)(nil)
+)
+
+// NewProfileLabelDB wraps db, labeling its operations with name. Closing the
+// ProfileLabelDB closes db.
+func NewProfileLabelDB(db DB, name string) *ProfileLabelDB {
+	return &ProfileLabelDB{db: db, name: name}
+}
+
+// do runs fn with the labels of op added to those of ctx.
+func (pdb *ProfileLabelDB) do(ctx context.Context, op string, fn func(context.Context)) {
+	pprof.Do(ctx, pprof.Labels("db", pdb.name, "op", op), fn)
+}
+
+// Get implements DB.
+func (pdb *ProfileLabelDB) Get(key []byte) ([]byte, error) {
+	return pdb.GetContext(context.Background(), key)
+}
+
+// GetContext implements ContextDB.
+func (pdb *ProfileLabelDB) GetContext(ctx context.Context, key []byte) (value []byte, err error) {
+	pdb.do(ctx, metricsOpGet, func(ctx context.Context) {
+		value, err = GetContext(ctx, pdb.db, key)
+	})
+	return value, err
+}
+
+// Has implements DB.
+func (pdb *ProfileLabelDB) Has(key []byte) (bool, error) {
+	return pdb.HasContext(context.Background(), key)
+}
+
+// HasContext implements ContextDB.
+func (pdb *ProfileLabelDB) HasContext(ctx context.Context, key []byte) (has bool, err error) {
+	pdb.do(ctx, metricsOpHas, func(ctx context.Context) {
+		has, err = HasContext(ctx, pdb.db, key)
+	})
+	return has, err
+}
+
+// Set implements DB.
+func (pdb *ProfileLabelDB) Set(key []byte, value []byte) error {
+	return pdb.SetContext(context.Background(), key, value)
+}
+
+// SetContext implements ContextDB.
+func (pdb *ProfileLabelDB) SetContext(ctx context.Context, key, value []byte) (err error) {
+	pdb.do(ctx, metricsOpSet, func(ctx context.Context) {
+		err = SetContext(ctx, pdb.db, key, value)
+	})
+	return err
+}
+
+// SetSync implements DB.
+func (pdb *ProfileLabelDB) SetSync(key []byte, value []byte) error {
+	return pdb.SetSyncContext(context.Background(), key, value)
+}
+
+// SetSyncContext implements ContextDB.
+func (pdb *ProfileLabelDB) SetSyncContext(ctx context.Context, key, value []byte) (err error) {
+	pdb.do(ctx, metricsOpSetSync, func(ctx context.Context) {
+		err = SetSyncContext(ctx, pdb.db, key, value)
+	})
+	return err
+}
+
+// Delete implements DB.
+func (pdb *ProfileLabelDB) Delete(key []byte) error {
+	return pdb.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext implements ContextDB.
+func (pdb *ProfileLabelDB) DeleteContext(ctx context.Context, key []byte) (err error) {
+	pdb.do(ctx, metricsOpDelete, func(ctx context.Context) {
+		err = DeleteContext(ctx, pdb.db, key)
+	})
+	return err
+}
+
+// DeleteSync implements DB.
+func (pdb *ProfileLabelDB) DeleteSync(key []byte) error {
+	return pdb.DeleteSyncContext(context.Background(), key)
+}
+
+// DeleteSyncContext implements ContextDB.
+func (pdb *ProfileLabelDB) DeleteSyncContext(ctx context.Context, key []byte) (err error) {
+	pdb.do(ctx, metricsOpDeleteSync, func(ctx context.Context) {
+		err = DeleteSyncContext(ctx, pdb.db, key)
+	})
+	return err
+}
+
+// Iterator implements DB.
+func (pdb *ProfileLabelDB) Iterator(start, end []byte) (Iterator, error) {
+	return pdb.IteratorContext(context.Background(), start, end)
+}
+
+// IteratorContext implements ContextDB. The labels are added to those of ctx
+// whenever the iterator moves.
+func (pdb *ProfileLabelDB) IteratorContext(ctx context.Context, start, end []byte) (itr Iterator, err error) {
+	pdb.do(ctx, metricsOpIterator, func(ctx context.Context) {
+		itr, err = IteratorContext(ctx, pdb.db, start, end)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newProfileLabelDBIterator(ctx, pdb, metricsOpIterator, itr), nil
+}
+
+// ReverseIterator implements DB.
+func (pdb *ProfileLabelDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return pdb.ReverseIteratorContext(context.Background(), start, end)
+}
+
+// ReverseIteratorContext implements ContextDB. The labels are added to those
+// of ctx whenever the iterator moves.
+func (pdb *ProfileLabelDB) ReverseIteratorContext(ctx context.Context, start, end []byte) (itr Iterator, err error) {
+	pdb.do(ctx, metricsOpReverseIterator, func(ctx context.Context) {
+		itr, err = ReverseIteratorContext(ctx, pdb.db, start, end)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newProfileLabelDBIterator(ctx, pdb, metricsOpReverseIterator, itr), nil
+}
+
+// Close implements DB.
+func (pdb *ProfileLabelDB) Close() error {
+	return pdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (pdb *ProfileLabelDB) NewBatch() Batch {
+	return newProfileLabelDBBatch(pdb, pdb.db.NewBatch())
+}
+
+// Print implements DB.
+func (pdb *ProfileLabelDB) Print() error {
+	return pdb.db.Print()
+}
+
+// Stats implements DB.
+func (pdb *ProfileLabelDB) Stats() map[string]string {
+	return pdb.db.Stats()
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database.
+func (pdb *ProfileLabelDB) CompareAndSwap(key, old, new []byte) (swapped bool, err error) {
+	pdb.do(context.Background(), metricsOpCompareAndSwap, func(context.Context) {
+		swapped, err = CompareAndSwap(pdb.db, key, old, new)
+	})
+	return swapped, err
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+// Reads of the snapshot are not labeled.
+func (pdb *ProfileLabelDB) Snapshot() (DBReader, error) {
+	return Snapshot(pdb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (pdb *ProfileLabelDB) Metrics() (Metrics, error) {
+	return GetMetrics(pdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (pdb *ProfileLabelDB) EstimateCount() (uint64, error) {
+	return EstimateCount(pdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (pdb *ProfileLabelDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(pdb.db, start, end)
+}
+
+// Compact implements Compacter, labeled with op=compact.
+func (pdb *ProfileLabelDB) Compact(start, end []byte) (err error) {
+	pdb.do(context.Background(), "compact", func(context.Context) {
+		err = Compact(pdb.db, start, end)
+	})
+	return err
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (pdb *ProfileLabelDB) Flush() error {
+	return Flush(pdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (pdb *ProfileLabelDB) Sync() error {
+	return Sync(pdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+func (pdb *ProfileLabelDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, pdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (pdb *ProfileLabelDB) Checkpoint(path string) error {
+	return Checkpoint(pdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (pdb *ProfileLabelDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, pdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which ProfileLabelDB passes through.
+func (pdb *ProfileLabelDB) Capabilities() Capability {
+	return Capabilities(pdb.db) & (CapSnapshot | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
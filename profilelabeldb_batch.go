@@ -0,0 +1,61 @@
+package db
+
+import "context"
+
+// profileLabelDBBatch wraps a batch of the underlying database, writing it
+// with the labels of its ProfileLabelDB.
+type profileLabelDBBatch struct {
+	pdb    *ProfileLabelDB
+	source Batch
+}
+
+var _ Batch = (*profileLabelDBBatch)(nil)
+
+func newProfileLabelDBBatch(pdb *ProfileLabelDB, source Batch) *profileLabelDBBatch {
+	return &profileLabelDBBatch{
+		pdb:    pdb,
+		source: source,
+	}
+}
+
+// Set implements Batch.
+func (b *profileLabelDBBatch) Set(key, value []byte) error {
+	return b.source.Set(key, value)
+}
+
+// Delete implements Batch.
+func (b *profileLabelDBBatch) Delete(key []byte) error {
+	return b.source.Delete(key)
+}
+
+// Count implements Batch.
+func (b *profileLabelDBBatch) Count() int {
+	return b.source.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *profileLabelDBBatch) SizeBytes() int {
+	return b.source.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *profileLabelDBBatch) Write() error {
+	return b.write(metricsOpBatchWrite, b.source.Write)
+}
+
+// WriteSync implements Batch.
+func (b *profileLabelDBBatch) WriteSync() error {
+	return b.write(metricsOpBatchWriteSync, b.source.WriteSync)
+}
+
+func (b *profileLabelDBBatch) write(op string, write func() error) (err error) {
+	b.pdb.do(context.Background(), op, func(context.Context) {
+		err = write()
+	})
+	return err
+}
+
+// Close implements Batch.
+func (b *profileLabelDBBatch) Close() error {
+	return b.source.Close()
+}
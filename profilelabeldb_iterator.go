@@ -0,0 +1,60 @@
+package db
+
+import "context"
+
+// profileLabelDBIterator wraps an iterator of the underlying database, moving
+// it with the labels of its ProfileLabelDB.
+type profileLabelDBIterator struct {
+	pdb    *ProfileLabelDB
+	ctx    context.Context
+	op     string
+	source Iterator
+}
+
+var _ Iterator = (*profileLabelDBIterator)(nil)
+
+func newProfileLabelDBIterator(ctx context.Context, pdb *ProfileLabelDB, op string, source Iterator) *profileLabelDBIterator {
+	return &profileLabelDBIterator{
+		pdb:    pdb,
+		ctx:    ctx,
+		op:     op,
+		source: source,
+	}
+}
+
+// Domain implements Iterator.
+func (itr *profileLabelDBIterator) Domain() ([]byte, []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *profileLabelDBIterator) Valid() bool {
+	return itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *profileLabelDBIterator) Next() {
+	itr.pdb.do(itr.ctx, itr.op, func(context.Context) {
+		itr.source.Next()
+	})
+}
+
+// Key implements Iterator.
+func (itr *profileLabelDBIterator) Key() []byte {
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *profileLabelDBIterator) Value() []byte {
+	return itr.source.Value()
+}
+
+// Error implements Iterator.
+func (itr *profileLabelDBIterator) Error() error {
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *profileLabelDBIterator) Close() error {
+	return itr.source.Close()
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// labelRecordingDB records the pprof labels of the contexts of its operations.
+type labelRecordingDB struct {
+	*MemDB
+	labels []string
+}
+
+func (db *labelRecordingDB) record(ctx context.Context) {
+	dbName, _ := pprof.Label(ctx, "db")
+	op, _ := pprof.Label(ctx, "op")
+	caller, _ := pprof.Label(ctx, "caller")
+	db.labels = append(db.labels, dbName+" "+op+" "+caller)
+}
+
+func (db *labelRecordingDB) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	db.record(ctx)
+	return db.Get(key)
+}
+
+func (db *labelRecordingDB) HasContext(ctx context.Context, key []byte) (bool, error) {
+	db.record(ctx)
+	return db.Has(key)
+}
+
+func (db *labelRecordingDB) SetContext(ctx context.Context, key, value []byte) error {
+	db.record(ctx)
+	return db.Set(key, value)
+}
+
+func (db *labelRecordingDB) SetSyncContext(ctx context.Context, key, value []byte) error {
+	db.record(ctx)
+	return db.SetSync(key, value)
+}
+
+func (db *labelRecordingDB) DeleteContext(ctx context.Context, key []byte) error {
+	db.record(ctx)
+	return db.Delete(key)
+}
+
+func (db *labelRecordingDB) DeleteSyncContext(ctx context.Context, key []byte) error {
+	db.record(ctx)
+	return db.DeleteSync(key)
+}
+
+func (db *labelRecordingDB) IteratorContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	db.record(ctx)
+	return db.Iterator(start, end)
+}
+
+func (db *labelRecordingDB) ReverseIteratorContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	db.record(ctx)
+	return db.ReverseIterator(start, end)
+}
+
+func TestProfileLabelDB(t *testing.T) {
+	rdb := &labelRecordingDB{MemDB: NewMemDB()}
+	pdb := NewProfileLabelDB(rdb, "state")
+	defer pdb.Close()
+
+	require.NoError(t, pdb.Set(bz("a"), bz("1")))
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("caller", "test"))
+	value, err := GetContext(ctx, pdb, bz("a"))
+	require.NoError(t, err)
+	assert.Equal(t, bz("1"), value)
+	itr, err := pdb.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("a"), bz("1"))
+	checkNext(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	assert.Equal(t, []string{
+		"state set ",
+		"state get test",
+		"state reverse_iterator ",
+	}, rdb.labels)
+
+	batch := pdb.NewBatch()
+	require.NoError(t, batch.Delete(bz("a")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	checkValue(t, pdb, bz("a"), nil)
+}
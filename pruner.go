@@ -0,0 +1,349 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultPruneBatchSize is the number of keys deleted at a time by a Pruner,
+// if not given.
+const defaultPruneBatchSize = 1000
+
+// RetentionPolicy is the retention of the keys under a prefix, enforced by a
+// Pruner. A key is pruned once either of KeepLast and TTL allows it.
+type RetentionPolicy struct {
+	// Prefix is the prefix of the keys, empty for the whole database.
+	Prefix []byte
+
+	// KeepLast, if positive, keeps the last KeepLast keys under the prefix,
+	// in key order, e.g. the blocks of the last heights with keys ordered by
+	// height.
+	KeepLast uint64
+
+	// TTL, if positive, prunes the keys under the prefix older than TTL, as
+	// told by Timestamp, which is then required.
+	TTL time.Duration
+	// Timestamp returns the time of a key under the prefix, e.g. decoded from
+	// its value. Keys for which it returns false are kept.
+	Timestamp func(key, value []byte) (time.Time, bool)
+}
+
+// PrunerOptions configure a Pruner.
+type PrunerOptions struct {
+	// BatchSize is the number of keys deleted at a time, 1000 if zero, so that
+	// a large prune does not block the other writers for long.
+	BatchSize int
+
+	// Interval, if non-zero, runs Pruner.Prune in the background at this
+	// interval.
+	Interval time.Duration
+
+	// Compact compacts the pruned ranges after each prune, so that their disk
+	// space is reclaimed without waiting for the backend. It is ignored for
+	// databases which do not implement Compacter.
+	Compact bool
+
+	// Logger logs the prunes run in the background, if set.
+	Logger Logger
+}
+
+// PruneProgress is the progress of a Pruner on the keys of a RetentionPolicy.
+type PruneProgress struct {
+	// Prefix is the prefix of the policy.
+	Prefix []byte
+	// Running is set while the keys are being pruned.
+	Running bool
+	// Pruned is the number of keys pruned so far by the current prune, or by
+	// the last one if none is running.
+	Pruned uint64
+	// TotalPruned is the number of keys pruned by all prunes.
+	TotalPruned uint64
+	// Batches is the number of batches of deletions written by all prunes.
+	Batches uint64
+	// Prunes is the number of prunes completed, successfully or not.
+	Prunes uint64
+	// LastPrune is when the last prune completed.
+	LastPrune time.Time
+	// LastDuration is how long the last prune took.
+	LastDuration time.Duration
+	// LastErr is the error of the last prune, if it failed.
+	LastErr error
+}
+
+// Pruner deletes the keys of a database which are no longer retained by its
+// policies, e.g. the block data below a retain height, incrementally in
+// batches of range or point deletions, and optionally compacts the pruned
+// ranges. It can run periodically in the background.
+type Pruner struct {
+	db       DB
+	policies []RetentionPolicy
+	opts     PrunerOptions
+	now      func() time.Time
+
+	pruneMtx sync.Mutex // serializes the prunes
+
+	mtx      sync.Mutex
+	progress []PruneProgress
+
+	ctx       context.Context // canceled on Close
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewPruner creates a Pruner of the keys of db according to policies. The
+// database is not closed with the Pruner.
+func NewPruner(db DB, policies []RetentionPolicy, opts PrunerOptions) (*Pruner, error) {
+	for _, policy := range policies {
+		if policy.TTL > 0 && policy.Timestamp == nil {
+			return nil, fmt.Errorf("retention policy of prefix %X: TTL without Timestamp", policy.Prefix)
+		}
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultPruneBatchSize
+	}
+	p := &Pruner{
+		db:       db,
+		policies: policies,
+		opts:     opts,
+		now:      time.Now,
+		progress: make([]PruneProgress, len(policies)),
+	}
+	for i, policy := range policies {
+		p.progress[i].Prefix = cp(policy.Prefix)
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	if opts.Interval > 0 {
+		p.wg.Add(1)
+		go p.run()
+	}
+	return p, nil
+}
+
+func (p *Pruner) run() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			pruned, err := p.Prune(p.ctx)
+			switch {
+			case p.opts.Logger == nil || errors.Is(err, context.Canceled):
+			case err != nil:
+				p.opts.Logger.Error("Failed to prune", "pruned", pruned, "err", err)
+			default:
+				p.opts.Logger.Info("Pruned", "pruned", pruned, "duration", time.Since(start))
+			}
+		}
+	}
+}
+
+// Prune prunes the keys no longer retained by the policies, and returns the
+// number of keys pruned. It stops between batches once ctx is done.
+func (p *Pruner) Prune(ctx context.Context) (uint64, error) {
+	p.pruneMtx.Lock()
+	defer p.pruneMtx.Unlock()
+	var total uint64
+	for i := range p.policies {
+		pruned, err := p.prunePolicy(ctx, i)
+		total += pruned
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// prunePolicy prunes the keys of policy i, and compacts them.
+func (p *Pruner) prunePolicy(ctx context.Context, i int) (uint64, error) {
+	policy := p.policies[i]
+	start := time.Now()
+	p.mtx.Lock()
+	p.progress[i].Running = true
+	p.progress[i].Pruned = 0
+	p.mtx.Unlock()
+
+	var (
+		pruned uint64
+		last   []byte // last key pruned
+		err    error
+	)
+	if policy.KeepLast > 0 {
+		pruned, last, err = p.pruneKeepLast(ctx, i)
+	}
+	if err == nil && policy.TTL > 0 {
+		var n uint64
+		var ttlLast []byte
+		n, ttlLast, err = p.pruneTTL(ctx, i)
+		pruned += n
+		last = maxKey(last, ttlLast)
+	}
+	if err == nil && p.opts.Compact && last != nil {
+		if cerr := Compact(p.db, prefixStart(policy.Prefix), rangeUpperBound(last)); !errors.Is(cerr, ErrNotSupported) {
+			err = cerr
+		}
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	progress := &p.progress[i]
+	progress.Running = false
+	progress.Prunes++
+	progress.LastPrune = time.Now()
+	progress.LastDuration = progress.LastPrune.Sub(start)
+	progress.LastErr = err
+	return pruned, err
+}
+
+// pruneKeepLast deletes the keys of policy i before its last KeepLast keys,
+// and returns their number and the last one.
+func (p *Pruner) pruneKeepLast(ctx context.Context, i int) (uint64, []byte, error) {
+	policy := p.policies[i]
+	start, end := prefixStart(policy.Prefix), prefixEnd(policy.Prefix)
+	// The keys are pruned up to the last key not retained.
+	itr, err := IteratorWithOptions(p.db, start, end, IteratorOptions{Reverse: true, KeysOnly: true})
+	if err != nil {
+		return 0, nil, err
+	}
+	var kept uint64
+	for ; itr.Valid() && kept < policy.KeepLast; itr.Next() {
+		kept++
+	}
+	var cutoff []byte
+	if itr.Valid() {
+		cutoff = rangeUpperBound(itr.Key())
+	}
+	err = itr.Error()
+	if cerr := itr.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil || cutoff == nil {
+		return 0, nil, err
+	}
+
+	var (
+		pruned uint64
+		last   []byte
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return pruned, last, err
+		}
+		keys, err := collectKeys(p.db, start, cutoff, p.opts.BatchSize)
+		if err != nil || len(keys) == 0 {
+			return pruned, last, err
+		}
+		last = keys[len(keys)-1]
+		// A single range tombstone replaces the tombstones of the keys where
+		// supported.
+		if rd, ok := p.db.(RangeDeleter); ok {
+			err = rd.DeleteRange(start, rangeUpperBound(last))
+		} else {
+			err = deleteKeys(p.db, keys)
+		}
+		if err != nil {
+			return pruned, last, err
+		}
+		pruned += uint64(len(keys))
+		p.pruned(i, len(keys))
+		start = rangeUpperBound(last)
+	}
+}
+
+// pruneTTL deletes the keys of policy i older than its TTL, and returns their
+// number and the last one.
+func (p *Pruner) pruneTTL(ctx context.Context, i int) (uint64, []byte, error) {
+	policy := p.policies[i]
+	start, end := prefixStart(policy.Prefix), prefixEnd(policy.Prefix)
+	var (
+		pruned uint64
+		last   []byte
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return pruned, last, err
+		}
+		keys, next, err := p.collectExpired(policy, start, end, p.now().Add(-policy.TTL))
+		if err != nil {
+			return pruned, last, err
+		}
+		if len(keys) > 0 {
+			if err := deleteKeys(p.db, keys); err != nil {
+				return pruned, last, err
+			}
+			last = keys[len(keys)-1]
+			pruned += uint64(len(keys))
+			p.pruned(i, len(keys))
+		}
+		if next == nil {
+			return pruned, last, nil
+		}
+		start = next
+	}
+}
+
+// collectExpired returns copies of the keys of the range [start, end) whose
+// timestamp is before deadline, up to the batch size, and the key to continue
+// from, nil at the end of the range.
+func (p *Pruner) collectExpired(policy RetentionPolicy, start, end []byte, deadline time.Time) (keys [][]byte, next []byte, err error) {
+	itr, err := p.db.Iterator(start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if cerr := itr.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+	for ; itr.Valid(); itr.Next() {
+		if len(keys) >= p.opts.BatchSize {
+			return keys, cp(itr.Key()), nil
+		}
+		if t, ok := policy.Timestamp(itr.Key(), itr.Value()); ok && t.Before(deadline) {
+			keys = append(keys, cp(itr.Key()))
+		}
+	}
+	return keys, nil, itr.Error()
+}
+
+// pruned records a batch of n keys pruned for policy i.
+func (p *Pruner) pruned(i, n int) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.progress[i].Pruned += uint64(n)
+	p.progress[i].TotalPruned += uint64(n)
+	p.progress[i].Batches++
+}
+
+// Progress returns the progress of the pruning of each policy, in the order of
+// the policies.
+func (p *Pruner) Progress() []PruneProgress {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	progress := make([]PruneProgress, len(p.progress))
+	copy(progress, p.progress)
+	return progress
+}
+
+// Close stops the Pruner, interrupting the background prune in progress
+// between batches.
+func (p *Pruner) Close() error {
+	p.closeOnce.Do(p.cancel)
+	p.wg.Wait()
+	return nil
+}
+
+// prefixStart returns the first key with prefix, nil for an empty prefix.
+func prefixStart(prefix []byte) []byte {
+	if len(prefix) == 0 {
+		return nil
+	}
+	return cp(prefix)
+}
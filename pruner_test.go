@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrunerKeepLast(t *testing.T) {
+	for _, backend := range []BackendType{MemDBBackend, PebbleDBBackend} {
+		t.Run(string(backend), func(t *testing.T) {
+			db, err := NewDB("db", backend, t.TempDir())
+			require.NoError(t, err)
+			defer db.Close()
+			for h := int64(1); h <= 100; h++ {
+				require.NoError(t, db.Set(append(bz("H:"), int642Bytes(h)...), bz("block")))
+			}
+			require.NoError(t, db.Set(bz("I:1"), bz("index")))
+
+			p, err := NewPruner(db, []RetentionPolicy{{Prefix: bz("H:"), KeepLast: 10}},
+				PrunerOptions{BatchSize: 25, Compact: true})
+			require.NoError(t, err)
+			defer p.Close()
+			pruned, err := p.Prune(context.Background())
+			require.NoError(t, err)
+			assert.EqualValues(t, 90, pruned)
+
+			keys, err := collectKeys(db, nil, nil, 0)
+			require.NoError(t, err)
+			require.Len(t, keys, 11)
+			assert.Equal(t, append(bz("H:"), int642Bytes(91)...), keys[0])
+			assert.Equal(t, bz("I:1"), keys[10])
+
+			progress := p.Progress()
+			require.Len(t, progress, 1)
+			assert.Equal(t, bz("H:"), progress[0].Prefix)
+			assert.False(t, progress[0].Running)
+			assert.EqualValues(t, 90, progress[0].Pruned)
+			assert.EqualValues(t, 4, progress[0].Batches)
+			assert.EqualValues(t, 1, progress[0].Prunes)
+			assert.NoError(t, progress[0].LastErr)
+
+			// Nothing is left to prune until new keys are written.
+			pruned, err = p.Prune(context.Background())
+			require.NoError(t, err)
+			assert.Zero(t, pruned)
+			require.NoError(t, db.Set(append(bz("H:"), int642Bytes(101)...), bz("block")))
+			pruned, err = p.Prune(context.Background())
+			require.NoError(t, err)
+			assert.EqualValues(t, 1, pruned)
+			progress = p.Progress()
+			assert.EqualValues(t, 91, progress[0].TotalPruned)
+			assert.EqualValues(t, 3, progress[0].Prunes)
+		})
+	}
+}
+
+func TestPrunerTTL(t *testing.T) {
+	db := NewMemDB()
+	now := time.Unix(1_000_000, 0)
+	timestamp := func(key, value []byte) (time.Time, bool) {
+		if len(value) != 8 {
+			return time.Time{}, false
+		}
+		return time.Unix(int64(binary.BigEndian.Uint64(value)), 0), true
+	}
+	for i := 0; i < 50; i++ {
+		// Every other key is an hour old, and the others a minute old.
+		age := time.Minute
+		if i%2 == 0 {
+			age = time.Hour
+		}
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, uint64(now.Add(-age).Unix()))
+		require.NoError(t, db.Set(bz(fmt.Sprintf("tx/%02d", i)), value))
+	}
+	require.NoError(t, db.Set(bz("tx/meta"), bz("no timestamp")))
+
+	p, err := NewPruner(db, []RetentionPolicy{{Prefix: bz("tx/"), TTL: 30 * time.Minute, Timestamp: timestamp}},
+		PrunerOptions{BatchSize: 10})
+	require.NoError(t, err)
+	defer p.Close()
+	p.now = func() time.Time { return now }
+	pruned, err := p.Prune(context.Background())
+	require.NoError(t, err)
+	assert.EqualValues(t, 25, pruned)
+	for i := 0; i < 50; i++ {
+		ok, err := db.Has(bz(fmt.Sprintf("tx/%02d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, i%2 != 0, ok, i)
+	}
+	ok, err := db.Has(bz("tx/meta"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.EqualValues(t, 3, p.Progress()[0].Batches)
+
+	_, err = NewPruner(db, []RetentionPolicy{{TTL: time.Hour}}, PrunerOptions{})
+	assert.Error(t, err)
+}
+
+func TestPrunerCanceled(t *testing.T) {
+	db := NewMemDB()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, db.Set(int642Bytes(int64(i)), bz("v")))
+	}
+	p, err := NewPruner(db, []RetentionPolicy{{KeepLast: 1}}, PrunerOptions{})
+	require.NoError(t, err)
+	defer p.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	pruned, err := p.Prune(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, pruned)
+	assert.ErrorIs(t, p.Progress()[0].LastErr, context.Canceled)
+}
+
+func TestPrunerBackground(t *testing.T) {
+	db := NewMemDB()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, db.Set(int642Bytes(int64(i)), bz("v")))
+	}
+	logger := &testLogger{}
+	p, err := NewPruner(db, []RetentionPolicy{{KeepLast: 5}}, PrunerOptions{Interval: time.Millisecond, Logger: logger})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return p.Progress()[0].TotalPruned == 95
+	}, 5*time.Second, time.Millisecond)
+	require.NoError(t, p.Close())
+	assert.Positive(t, logger.count("Pruned"))
+	keys, err := collectKeys(db, nil, nil, 0)
+	require.NoError(t, err)
+	assert.Len(t, keys, 5)
+}
@@ -0,0 +1,320 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned by a QuotaDB for writes which would take the
+// database beyond its quota.
+var ErrQuotaExceeded = errors.New("database quota exceeded")
+
+// quotaRefreshInterval is the interval at which a QuotaDB measures the usage of
+// its database again, counting the bytes written in the meantime.
+const quotaRefreshInterval = time.Second
+
+// QuotaDB caps the total size of a database, e.g. for shared hosting. Writes
+// which would take the usage beyond the limit fail with ErrQuotaExceeded, while
+// deletes are always allowed.
+//
+// The usage is measured at most every second, and the bytes of the keys and
+// values written in the meantime are added to it. It is therefore approximate:
+// the measure may lag behind the writes still buffered by the database, and
+// does not show the space reclaimed by deletes until compactions run.
+type QuotaDB struct {
+	db         DB
+	limit      uint64
+	measure    func() (uint64, error)
+	mtx        sync.Mutex
+	measured   uint64
+	written    uint64 // since measuredAt
+	measuredAt time.Time
+}
+
+var _ DB = (*QuotaDB)(nil)
+
+// NewQuotaDB creates a QuotaDB over db limited to limit bytes, measuring the
+// usage with EstimateSize. Closing the QuotaDB closes db.
+func NewQuotaDB(db DB, limit uint64) *QuotaDB {
+	return NewQuotaDBWithUsage(db, limit, func() (uint64, error) {
+		return EstimateSize(db, nil, nil)
+	})
+}
+
+// NewQuotaDBWithUsage is like NewQuotaDB, measuring the usage with usage, e.g.
+// DirUsage of the directory of the database to account for all its files.
+func NewQuotaDBWithUsage(db DB, limit uint64, usage func() (uint64, error)) *QuotaDB {
+	return &QuotaDB{db: db, limit: limit, measure: usage}
+}
+
+// DirUsage returns a usage function for NewQuotaDBWithUsage, summing the sizes
+// of the files under dir.
+func DirUsage(dir string) func() (uint64, error) {
+	return func() (uint64, error) {
+		var size uint64
+		err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					// Removed during the walk, e.g. by a compaction.
+					return nil
+				}
+				return err
+			}
+			if d.Type().IsRegular() {
+				info, err := d.Info()
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				size += uint64(info.Size())
+			}
+			return nil
+		})
+		return size, err
+	}
+}
+
+// usage returns the current usage, measuring it again if it is stale. The
+// caller must hold mtx.
+func (qdb *QuotaDB) usage() (uint64, error) {
+	if time.Since(qdb.measuredAt) >= quotaRefreshInterval {
+		measured, err := qdb.measure()
+		if err != nil {
+			return 0, fmt.Errorf("failed to measure database usage: %w", err)
+		}
+		qdb.measured, qdb.written, qdb.measuredAt = measured, 0, time.Now()
+	}
+	return qdb.measured + qdb.written, nil
+}
+
+// reserve accounts for a write of size bytes, or fails with ErrQuotaExceeded
+// if it does not fit.
+func (qdb *QuotaDB) reserve(size int) error {
+	qdb.mtx.Lock()
+	defer qdb.mtx.Unlock()
+	usage, err := qdb.usage()
+	if err != nil {
+		return err
+	}
+	if usage+uint64(size) > qdb.limit {
+		return fmt.Errorf("%w: writing %d bytes with %d of %d bytes used", ErrQuotaExceeded, size, usage, qdb.limit)
+	}
+	qdb.written += uint64(size)
+	return nil
+}
+
+// Usage returns the approximate number of bytes used by the database.
+func (qdb *QuotaDB) Usage() (uint64, error) {
+	qdb.mtx.Lock()
+	defer qdb.mtx.Unlock()
+	return qdb.usage()
+}
+
+// Limit returns the quota of the database in bytes.
+func (qdb *QuotaDB) Limit() uint64 {
+	return qdb.limit
+}
+
+// Get implements DB.
+func (qdb *QuotaDB) Get(key []byte) ([]byte, error) {
+	return qdb.db.Get(key)
+}
+
+// Has implements DB.
+func (qdb *QuotaDB) Has(key []byte) (bool, error) {
+	return qdb.db.Has(key)
+}
+
+// Set implements DB.
+func (qdb *QuotaDB) Set(key []byte, value []byte) error {
+	if err := qdb.reserve(len(key) + len(value)); err != nil {
+		return err
+	}
+	return qdb.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (qdb *QuotaDB) SetSync(key []byte, value []byte) error {
+	if err := qdb.reserve(len(key) + len(value)); err != nil {
+		return err
+	}
+	return qdb.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (qdb *QuotaDB) Delete(key []byte) error {
+	return qdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (qdb *QuotaDB) DeleteSync(key []byte) error {
+	return qdb.db.DeleteSync(key)
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database.
+func (qdb *QuotaDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	if new != nil {
+		if err := qdb.reserve(len(key) + len(new)); err != nil {
+			return false, err
+		}
+	}
+	return CompareAndSwap(qdb.db, key, old, new)
+}
+
+// Iterator implements DB.
+func (qdb *QuotaDB) Iterator(start, end []byte) (Iterator, error) {
+	return qdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (qdb *QuotaDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return qdb.db.ReverseIterator(start, end)
+}
+
+// Close implements DB.
+func (qdb *QuotaDB) Close() error {
+	return qdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (qdb *QuotaDB) NewBatch() Batch {
+	return &quotaDBBatch{db: qdb, source: qdb.db.NewBatch()}
+}
+
+// Print implements DB.
+func (qdb *QuotaDB) Print() error {
+	return qdb.db.Print()
+}
+
+// Stats implements DB.
+func (qdb *QuotaDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range qdb.db.Stats() {
+		stats["quota.db."+k] = v
+	}
+	stats["quota.limit"] = strconv.FormatUint(qdb.limit, 10)
+	if usage, err := qdb.Usage(); err == nil {
+		stats["quota.usage"] = strconv.FormatUint(usage, 10)
+	}
+	return stats
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+func (qdb *QuotaDB) Snapshot() (DBReader, error) {
+	return Snapshot(qdb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (qdb *QuotaDB) Metrics() (Metrics, error) {
+	return GetMetrics(qdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (qdb *QuotaDB) EstimateCount() (uint64, error) {
+	return EstimateCount(qdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (qdb *QuotaDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(qdb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (qdb *QuotaDB) Compact(start, end []byte) error {
+	return Compact(qdb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (qdb *QuotaDB) Flush() error {
+	return Flush(qdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (qdb *QuotaDB) Sync() error {
+	return Sync(qdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+func (qdb *QuotaDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, qdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (qdb *QuotaDB) Checkpoint(path string) error {
+	return Checkpoint(qdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (qdb *QuotaDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, qdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which QuotaDB passes through.
+func (qdb *QuotaDB) Capabilities() Capability {
+	return Capabilities(qdb.db) & (CapSnapshot | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
+
+// quotaDBBatch wraps a batch of the underlying database, checking the quota
+// when it is written.
+type quotaDBBatch struct {
+	db     *QuotaDB
+	source Batch
+	size   int
+}
+
+var _ Batch = (*quotaDBBatch)(nil)
+
+// Set implements Batch.
+func (b *quotaDBBatch) Set(key, value []byte) error {
+	if err := b.source.Set(key, value); err != nil {
+		return err
+	}
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete implements Batch.
+func (b *quotaDBBatch) Delete(key []byte) error {
+	return b.source.Delete(key)
+}
+
+// Count implements Batch.
+func (b *quotaDBBatch) Count() int {
+	return b.source.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *quotaDBBatch) SizeBytes() int {
+	return b.source.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *quotaDBBatch) Write() error {
+	if err := b.db.reserve(b.size); err != nil {
+		return err
+	}
+	return b.source.Write()
+}
+
+// WriteSync implements Batch.
+func (b *quotaDBBatch) WriteSync() error {
+	if err := b.db.reserve(b.size); err != nil {
+		return err
+	}
+	return b.source.WriteSync()
+}
+
+// Close implements Batch.
+func (b *quotaDBBatch) Close() error {
+	return b.source.Close()
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaDB(t *testing.T) {
+	var measured uint64 = 100
+	qdb := NewQuotaDBWithUsage(NewMemDB(), 120, func() (uint64, error) { return measured, nil })
+	defer qdb.Close()
+
+	require.NoError(t, qdb.Set(bz("a"), make([]byte, 9)))
+	usage, err := qdb.Usage()
+	require.NoError(t, err)
+	assert.EqualValues(t, 110, usage)
+
+	err = qdb.Set(bz("b"), make([]byte, 10))
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	checkValue(t, qdb, bz("b"), nil)
+
+	batch := qdb.NewBatch()
+	require.NoError(t, batch.Set(bz("b"), make([]byte, 9)))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	batch = qdb.NewBatch()
+	require.NoError(t, batch.Set(bz("c"), []byte{}))
+	assert.ErrorIs(t, batch.Write(), ErrQuotaExceeded)
+	require.NoError(t, batch.Close())
+
+	// Deletes are always allowed.
+	require.NoError(t, qdb.Delete(bz("a")))
+	assert.Equal(t, "120", qdb.Stats()["quota.usage"])
+	assert.Equal(t, "120", qdb.Stats()["quota.limit"])
+
+	// The writes are forgotten once the usage is measured again.
+	measured = 0
+	qdb.measuredAt = qdb.measuredAt.Add(-quotaRefreshInterval)
+	require.NoError(t, qdb.Set(bz("b"), make([]byte, 100)))
+}
+
+func TestQuotaDBUsage(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	qdb := NewQuotaDBWithUsage(db, 1<<30, DirUsage(dir))
+	defer qdb.Close()
+
+	usage, err := qdb.Usage()
+	require.NoError(t, err)
+	assert.NotZero(t, usage, "goleveldb creates its files up front")
+
+	source := NewMemDB()
+	require.NoError(t, source.Set(bz("a"), make([]byte, 100)))
+	qdb = NewQuotaDB(source, 100)
+	assert.ErrorIs(t, qdb.Set(bz("b"), bz("1")), ErrQuotaExceeded)
+	qdb = NewQuotaDBWithUsage(NewMemDB(), 100, func() (uint64, error) { return 0, errors.New("boom") })
+	assert.ErrorContains(t, qdb.Set(bz("a"), bz("1")), "boom")
+}
@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimitedDB throttles the bytes written to a database and read by its
+// iterators, e.g. so that background jobs such as pruning or reindexing, given
+// a RateLimitedDB, cannot starve the consensus-critical reads of other users of
+// the same disk. Get and Has are not throttled.
+//
+// The limits are enforced by token buckets holding up to a second of bytes, so
+// bursts up to the limit go through immediately. Operations beyond it wait
+// until their bytes are available, in turn.
+type RateLimitedDB struct {
+	db    DB
+	write *tokenBucket
+	read  *tokenBucket
+}
+
+var _ DB = (*RateLimitedDB)(nil)
+
+// NewRateLimitedDB creates a RateLimitedDB over db, limiting the writes to
+// writeBytesPerSec and the iterator reads to readBytesPerSec. A zero limit
+// disables throttling. Closing the RateLimitedDB closes db.
+func NewRateLimitedDB(db DB, writeBytesPerSec, readBytesPerSec int) *RateLimitedDB {
+	return &RateLimitedDB{
+		db:    db,
+		write: newTokenBucket(writeBytesPerSec),
+		read:  newTokenBucket(readBytesPerSec),
+	}
+}
+
+// tokenBucket is a token bucket refilled at rate tokens per second, up to a
+// second of tokens. A nil bucket never waits.
+type tokenBucket struct {
+	rate float64
+
+	mtx    sync.Mutex
+	tokens float64 // negative when in debt
+	last   time.Time
+	waited time.Duration
+}
+
+func newTokenBucket(rate int) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &tokenBucket{rate: float64(rate), tokens: float64(rate), last: time.Now()}
+}
+
+// wait takes n tokens from the bucket, sleeping until they are available.
+func (b *tokenBucket) wait(n int) {
+	if b == nil || n == 0 {
+		return
+	}
+	b.mtx.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.rate {
+		b.tokens = b.rate
+	}
+	b.last = now
+	// Take the tokens even if they are not all there yet, so that concurrent
+	// callers wait for their turn in order.
+	b.tokens -= float64(n)
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / b.rate * float64(time.Second))
+		b.waited += delay
+	}
+	b.mtx.Unlock()
+	time.Sleep(delay)
+}
+
+// waitedFor returns the total time waited for tokens.
+func (b *tokenBucket) waitedFor() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.waited
+}
+
+// Get implements DB.
+func (rdb *RateLimitedDB) Get(key []byte) ([]byte, error) {
+	return rdb.db.Get(key)
+}
+
+// Has implements DB.
+func (rdb *RateLimitedDB) Has(key []byte) (bool, error) {
+	return rdb.db.Has(key)
+}
+
+// Set implements DB.
+func (rdb *RateLimitedDB) Set(key []byte, value []byte) error {
+	rdb.write.wait(len(key) + len(value))
+	return rdb.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (rdb *RateLimitedDB) SetSync(key []byte, value []byte) error {
+	rdb.write.wait(len(key) + len(value))
+	return rdb.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (rdb *RateLimitedDB) Delete(key []byte) error {
+	rdb.write.wait(len(key))
+	return rdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (rdb *RateLimitedDB) DeleteSync(key []byte) error {
+	rdb.write.wait(len(key))
+	return rdb.db.DeleteSync(key)
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database.
+func (rdb *RateLimitedDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	rdb.write.wait(len(key) + len(new))
+	return CompareAndSwap(rdb.db, key, old, new)
+}
+
+// Iterator implements DB.
+func (rdb *RateLimitedDB) Iterator(start, end []byte) (Iterator, error) {
+	itr, err := rdb.db.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newRateLimitedDBIterator(rdb.read, itr), nil
+}
+
+// ReverseIterator implements DB.
+func (rdb *RateLimitedDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	itr, err := rdb.db.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newRateLimitedDBIterator(rdb.read, itr), nil
+}
+
+// Close implements DB.
+func (rdb *RateLimitedDB) Close() error {
+	return rdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (rdb *RateLimitedDB) NewBatch() Batch {
+	return newRateLimitedDBBatch(rdb.write, rdb.db.NewBatch())
+}
+
+// Print implements DB.
+func (rdb *RateLimitedDB) Print() error {
+	return rdb.db.Print()
+}
+
+// Stats implements DB.
+func (rdb *RateLimitedDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range rdb.db.Stats() {
+		stats["ratelimit.db."+k] = v
+	}
+	stats["ratelimit.write_waited"] = rdb.write.waitedFor().String()
+	stats["ratelimit.read_waited"] = rdb.read.waitedFor().String()
+	if rdb.write != nil {
+		stats["ratelimit.write_bytes_per_sec"] = strconv.FormatFloat(rdb.write.rate, 'f', -1, 64)
+	}
+	if rdb.read != nil {
+		stats["ratelimit.read_bytes_per_sec"] = strconv.FormatFloat(rdb.read.rate, 'f', -1, 64)
+	}
+	return stats
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (rdb *RateLimitedDB) Metrics() (Metrics, error) {
+	return GetMetrics(rdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (rdb *RateLimitedDB) EstimateCount() (uint64, error) {
+	return EstimateCount(rdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (rdb *RateLimitedDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(rdb.db, start, end)
+}
+
+// Compact implements Compacter. Compactions are not throttled.
+func (rdb *RateLimitedDB) Compact(start, end []byte) error {
+	return Compact(rdb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (rdb *RateLimitedDB) Flush() error {
+	return Flush(rdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (rdb *RateLimitedDB) Sync() error {
+	return Sync(rdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+// Backups are not throttled.
+func (rdb *RateLimitedDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, rdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (rdb *RateLimitedDB) Checkpoint(path string) error {
+	return Checkpoint(rdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (rdb *RateLimitedDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, rdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which RateLimitedDB passes through.
+func (rdb *RateLimitedDB) Capabilities() Capability {
+	return Capabilities(rdb.db) & (CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
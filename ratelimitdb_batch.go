@@ -0,0 +1,63 @@
+package db
+
+// rateLimitedDBBatch wraps a batch of the underlying database, throttling its
+// bytes when it is written.
+type rateLimitedDBBatch struct {
+	bucket *tokenBucket
+	source Batch
+	size   int
+}
+
+var _ Batch = (*rateLimitedDBBatch)(nil)
+
+func newRateLimitedDBBatch(bucket *tokenBucket, source Batch) *rateLimitedDBBatch {
+	return &rateLimitedDBBatch{
+		bucket: bucket,
+		source: source,
+	}
+}
+
+// Set implements Batch.
+func (b *rateLimitedDBBatch) Set(key, value []byte) error {
+	if err := b.source.Set(key, value); err != nil {
+		return err
+	}
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete implements Batch.
+func (b *rateLimitedDBBatch) Delete(key []byte) error {
+	if err := b.source.Delete(key); err != nil {
+		return err
+	}
+	b.size += len(key)
+	return nil
+}
+
+// Count implements Batch.
+func (b *rateLimitedDBBatch) Count() int {
+	return b.source.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *rateLimitedDBBatch) SizeBytes() int {
+	return b.source.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *rateLimitedDBBatch) Write() error {
+	b.bucket.wait(b.size)
+	return b.source.Write()
+}
+
+// WriteSync implements Batch.
+func (b *rateLimitedDBBatch) WriteSync() error {
+	b.bucket.wait(b.size)
+	return b.source.WriteSync()
+}
+
+// Close implements Batch.
+func (b *rateLimitedDBBatch) Close() error {
+	return b.source.Close()
+}
@@ -0,0 +1,62 @@
+package db
+
+// rateLimitedDBIterator wraps an iterator of the underlying database,
+// throttling the bytes of the items it lands on.
+type rateLimitedDBIterator struct {
+	bucket *tokenBucket
+	source Iterator
+}
+
+var _ Iterator = (*rateLimitedDBIterator)(nil)
+
+func newRateLimitedDBIterator(bucket *tokenBucket, source Iterator) *rateLimitedDBIterator {
+	itr := &rateLimitedDBIterator{
+		bucket: bucket,
+		source: source,
+	}
+	itr.waitItem()
+	return itr
+}
+
+// waitItem throttles the item the iterator landed on.
+func (itr *rateLimitedDBIterator) waitItem() {
+	if itr.source.Valid() {
+		itr.bucket.wait(len(itr.source.Key()) + len(itr.source.Value()))
+	}
+}
+
+// Domain implements Iterator.
+func (itr *rateLimitedDBIterator) Domain() ([]byte, []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *rateLimitedDBIterator) Valid() bool {
+	return itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *rateLimitedDBIterator) Next() {
+	itr.source.Next()
+	itr.waitItem()
+}
+
+// Key implements Iterator.
+func (itr *rateLimitedDBIterator) Key() []byte {
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *rateLimitedDBIterator) Value() []byte {
+	return itr.source.Value()
+}
+
+// Error implements Iterator.
+func (itr *rateLimitedDBIterator) Error() error {
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *rateLimitedDBIterator) Close() error {
+	return itr.source.Close()
+}
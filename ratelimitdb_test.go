@@ -0,0 +1,67 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitedDBWrites(t *testing.T) {
+	rdb := NewRateLimitedDB(NewMemDB(), 1000, 0)
+	defer rdb.Close()
+
+	// A second of bytes goes through immediately.
+	start := time.Now()
+	require.NoError(t, rdb.Set(bz("key"), make([]byte, 997)))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	// Beyond it, the writes wait for their bytes.
+	require.NoError(t, rdb.Set(bz("key"), make([]byte, 97)))
+	batch := rdb.NewBatch()
+	require.NoError(t, batch.Set(bz("key"), make([]byte, 97)))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	stats := rdb.Stats()
+	waited, err := time.ParseDuration(stats["ratelimit.write_waited"])
+	require.NoError(t, err)
+	assert.Greater(t, waited, 150*time.Millisecond)
+	assert.Equal(t, "1000", stats["ratelimit.write_bytes_per_sec"])
+	assert.Equal(t, "0s", stats["ratelimit.read_waited"])
+
+	// Reads are not throttled.
+	start = time.Now()
+	checkValue(t, rdb, bz("key"), make([]byte, 97))
+	itr, err := rdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("key"), make([]byte, 97))
+	require.NoError(t, itr.Close())
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestRateLimitedDBReads(t *testing.T) {
+	source := NewMemDB()
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, source.Set(bz(key), make([]byte, 99)))
+	}
+	rdb := NewRateLimitedDB(source, 0, 1000)
+	defer rdb.Close()
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		itr, err := rdb.ReverseIterator(nil, nil)
+		require.NoError(t, err)
+		for ; itr.Valid(); itr.Next() {
+		}
+		require.NoError(t, itr.Close())
+	}
+	// 1200 bytes were read, 200 beyond the burst.
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	start = time.Now()
+	require.NoError(t, rdb.Set(bz("d"), make([]byte, 10000)))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
@@ -0,0 +1,39 @@
+package db
+
+// ReadOptions are optional settings for reads made with GetWithOptions, and for
+// iterators through IteratorOptions. The zero value is the default behavior of
+// the database.
+//
+// They are hints for the backends supporting them (e.g. goleveldb, RocksDB and
+// CLevelDB) and are ignored by the others.
+type ReadOptions struct {
+	// DontFillCache does not add the blocks read to the block cache, so that
+	// large one-off scans (e.g. archival exports) do not evict the hot working
+	// set.
+	DontFillCache bool
+
+	// VerifyChecksums verifies the checksums of all the data read, failing with
+	// an ErrCorrupted error on mismatch.
+	VerifyChecksums bool
+}
+
+// ReadOptionsDB is implemented by databases which can apply ReadOptions to
+// single key reads.
+type ReadOptionsDB interface {
+	// GetWithOptions fetches the value of the given key, or nil if it does not
+	// exist, like DB.Get, configured by opts.
+	// CONTRACT: key, value readonly []byte
+	GetWithOptions(key []byte, opts ReadOptions) ([]byte, error)
+}
+
+// GetWithOptions fetches the value of key in db configured by opts. If db does
+// not implement ReadOptionsDB, the options are ignored.
+func GetWithOptions(db DB, key []byte, opts ReadOptions) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	if rdb, ok := db.(ReadOptionsDB); ok {
+		return rdb.GetWithOptions(key, opts)
+	}
+	return db.Get(key)
+}
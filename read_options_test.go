@@ -0,0 +1,82 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOptions(t *testing.T) {
+	opts := ReadOptions{DontFillCache: true, VerifyChecksums: true}
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			for i := int64(0); i < 10; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+
+			value, err := GetWithOptions(db, int642Bytes(3), opts)
+			require.NoError(t, err)
+			assert.Equal(t, int642Bytes(3), value)
+			value, err = GetWithOptions(db, int642Bytes(10), opts)
+			require.NoError(t, err)
+			assert.Nil(t, value)
+			_, err = GetWithOptions(db, []byte{}, opts)
+			assert.Equal(t, errKeyEmpty, err)
+
+			itr, err := IteratorWithOptions(db, nil, nil, IteratorOptions{Reverse: true, ReadOptions: opts})
+			require.NoError(t, err)
+			for i := int64(9); i >= 0; i-- {
+				checkValid(t, itr, true)
+				checkItem(t, itr, int642Bytes(i), int642Bytes(i))
+				itr.Next()
+			}
+			checkValid(t, itr, false)
+			require.NoError(t, itr.Error())
+			require.NoError(t, itr.Close())
+		})
+	}
+}
+
+func TestGoLevelDBDontFillCache(t *testing.T) {
+	dir, err := os.MkdirTemp("", "read_options_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db, err := NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	for i := int64(0); i < 1000; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+	}
+	require.NoError(t, db.Compact(nil, nil))
+	require.NoError(t, db.Close())
+
+	// Reopen with an empty block cache.
+	db, err = NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	defer db.Close()
+	cached := func() string {
+		size, err := db.DB().GetProperty("leveldb.cachedblock")
+		require.NoError(t, err)
+		return size
+	}
+
+	itr, err := IteratorWithOptions(db, nil, nil, IteratorOptions{ReadOptions: ReadOptions{DontFillCache: true}})
+	require.NoError(t, err)
+	for ; itr.Valid(); itr.Next() {
+	}
+	require.NoError(t, itr.Close())
+	assert.Equal(t, "0", cached())
+
+	_, err = db.Get(int642Bytes(1))
+	require.NoError(t, err)
+	assert.NotEqual(t, "0", cached())
+}
@@ -0,0 +1,197 @@
+package db
+
+import "context"
+
+// ReadOnlyDB rejects the writes to a database with ErrReadOnly, enforcing
+// read-only access at the interface level, e.g. for inspection tools which are
+// handed a DB. Compactions and flushes are rejected too, with ErrNotSupported.
+type ReadOnlyDB struct {
+	db DB
+}
+
+var _ DB = (*ReadOnlyDB)(nil)
+
+// NewReadOnlyDB wraps db, rejecting writes. Closing the ReadOnlyDB closes db.
+func NewReadOnlyDB(db DB) *ReadOnlyDB {
+	return &ReadOnlyDB{db: db}
+}
+
+// OpenReadOnly opens an existing database with WithReadOnly, so that the
+// backend does not take its write lock where it can avoid it, and wraps it in
+// a ReadOnlyDB. It is meant for inspection tools and analytics jobs.
+//
+// Whether a database in use by another process can be opened depends on the
+// backend: RocksDB opens it without locking, GoLevelDB, BoltDB and BadgerDB
+// share their lock with other readers only and fail while a writer holds it,
+// and PebbleDB always takes an exclusive lock.
+func OpenReadOnly(name string, backend BackendType, dir string, opts ...Option) (*ReadOnlyDB, error) {
+	db, err := NewDB(name, backend, dir, append(opts, WithReadOnly())...)
+	if err != nil {
+		return nil, err
+	}
+	return NewReadOnlyDB(db), nil
+}
+
+// Get implements DB.
+func (rdb *ReadOnlyDB) Get(key []byte) ([]byte, error) {
+	return rdb.db.Get(key)
+}
+
+// GetWithOptions implements ReadOptionsDB, passing the options on to the
+// underlying database.
+func (rdb *ReadOnlyDB) GetWithOptions(key []byte, opts ReadOptions) ([]byte, error) {
+	return GetWithOptions(rdb.db, key, opts)
+}
+
+// GetUnsafe implements UnsafeGetter, with the zero-copy read of the underlying
+// database if it supports it.
+func (rdb *ReadOnlyDB) GetUnsafe(key []byte, fn func(value []byte) error) error {
+	return GetUnsafe(rdb.db, key, fn)
+}
+
+// MultiGet implements MultiGetter.
+func (rdb *ReadOnlyDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	return MultiGet(rdb.db, keys)
+}
+
+// Has implements DB.
+func (rdb *ReadOnlyDB) Has(key []byte) (bool, error) {
+	return rdb.db.Has(key)
+}
+
+// Set implements DB. It returns ErrReadOnly.
+func (*ReadOnlyDB) Set([]byte, []byte) error {
+	return ErrReadOnly
+}
+
+// SetSync implements DB. It returns ErrReadOnly.
+func (*ReadOnlyDB) SetSync([]byte, []byte) error {
+	return ErrReadOnly
+}
+
+// Delete implements DB. It returns ErrReadOnly.
+func (*ReadOnlyDB) Delete([]byte) error {
+	return ErrReadOnly
+}
+
+// DeleteSync implements DB. It returns ErrReadOnly.
+func (*ReadOnlyDB) DeleteSync([]byte) error {
+	return ErrReadOnly
+}
+
+// Iterator implements DB.
+func (rdb *ReadOnlyDB) Iterator(start, end []byte) (Iterator, error) {
+	return rdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (rdb *ReadOnlyDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return rdb.db.ReverseIterator(start, end)
+}
+
+// IteratorWithOptions implements IteratorOptionsDB, passing the options on to
+// the underlying database.
+func (rdb *ReadOnlyDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	return IteratorWithOptions(rdb.db, start, end, opts)
+}
+
+// Close implements DB.
+func (rdb *ReadOnlyDB) Close() error {
+	return rdb.db.Close()
+}
+
+// NewBatch implements DB. The batch rejects all writes with ErrReadOnly.
+func (*ReadOnlyDB) NewBatch() Batch {
+	return readOnlyBatch{}
+}
+
+// Print implements DB.
+func (rdb *ReadOnlyDB) Print() error {
+	return rdb.db.Print()
+}
+
+// Stats implements DB.
+func (rdb *ReadOnlyDB) Stats() map[string]string {
+	return rdb.db.Stats()
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+func (rdb *ReadOnlyDB) Snapshot() (DBReader, error) {
+	return Snapshot(rdb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (rdb *ReadOnlyDB) Metrics() (Metrics, error) {
+	return GetMetrics(rdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (rdb *ReadOnlyDB) EstimateCount() (uint64, error) {
+	return EstimateCount(rdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (rdb *ReadOnlyDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(rdb.db, start, end)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+func (rdb *ReadOnlyDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, rdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (rdb *ReadOnlyDB) Checkpoint(path string) error {
+	return Checkpoint(rdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (rdb *ReadOnlyDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, rdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which ReadOnlyDB passes through.
+func (rdb *ReadOnlyDB) Capabilities() Capability {
+	return Capabilities(rdb.db) & (CapSnapshot | CapBackup | CapCheckpoint | CapGetUnsafe)
+}
+
+// readOnlyBatch is the batch of a ReadOnlyDB, rejecting all writes.
+type readOnlyBatch struct{}
+
+var _ Batch = readOnlyBatch{}
+
+// Set implements Batch. It returns ErrReadOnly.
+func (readOnlyBatch) Set([]byte, []byte) error {
+	return ErrReadOnly
+}
+
+// Delete implements Batch. It returns ErrReadOnly.
+func (readOnlyBatch) Delete([]byte) error {
+	return ErrReadOnly
+}
+
+// Count implements Batch.
+func (readOnlyBatch) Count() int {
+	return 0
+}
+
+// SizeBytes implements Batch.
+func (readOnlyBatch) SizeBytes() int {
+	return 0
+}
+
+// Write implements Batch. It returns ErrReadOnly.
+func (readOnlyBatch) Write() error {
+	return ErrReadOnly
+}
+
+// WriteSync implements Batch. It returns ErrReadOnly.
+func (readOnlyBatch) WriteSync() error {
+	return ErrReadOnly
+}
+
+// Close implements Batch.
+func (readOnlyBatch) Close() error {
+	return nil
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyDB(t *testing.T) {
+	source := NewMemDB()
+	require.NoError(t, source.Set(bz("a"), bz("1")))
+	rdb := NewReadOnlyDB(source)
+	defer rdb.Close()
+
+	checkValue(t, rdb, bz("a"), bz("1"))
+	itr, err := rdb.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("a"), bz("1"))
+	require.NoError(t, itr.Close())
+
+	assert.ErrorIs(t, rdb.Set(bz("b"), bz("2")), ErrReadOnly)
+	assert.ErrorIs(t, rdb.SetSync(bz("b"), bz("2")), ErrReadOnly)
+	assert.ErrorIs(t, rdb.Delete(bz("a")), ErrReadOnly)
+	assert.ErrorIs(t, rdb.DeleteSync(bz("a")), ErrReadOnly)
+	assert.ErrorIs(t, DeleteRange(rdb, nil, nil), ErrReadOnly)
+	_, err = CompareAndSwap(rdb, bz("a"), bz("1"), bz("2"))
+	assert.ErrorIs(t, err, ErrReadOnly)
+	assert.ErrorIs(t, Compact(rdb, nil, nil), ErrNotSupported)
+
+	batch := rdb.NewBatch()
+	assert.ErrorIs(t, batch.Set(bz("b"), bz("2")), ErrReadOnly)
+	assert.ErrorIs(t, batch.Write(), ErrReadOnly)
+	require.NoError(t, batch.Close())
+
+	checkValue(t, source, bz("a"), bz("1"))
+	checkValue(t, source, bz("b"), nil)
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	for _, backend := range []BackendType{GoLevelDBBackend, PebbleDBBackend} {
+		t.Run(string(backend), func(t *testing.T) {
+			dir := t.TempDir()
+			_, err := OpenReadOnly("db", backend, dir)
+			require.Error(t, err, "the database must exist")
+
+			db, err := NewDB("db", backend, dir)
+			require.NoError(t, err)
+			require.NoError(t, db.Set(bz("a"), bz("1")))
+			require.NoError(t, db.Close())
+
+			rdb, err := OpenReadOnly("db", backend, dir)
+			require.NoError(t, err)
+			defer rdb.Close()
+			checkValue(t, rdb, bz("a"), bz("1"))
+			assert.ErrorIs(t, rdb.Set(bz("a"), bz("2")), ErrReadOnly)
+			assert.NoError(t, HealthCheck(context.Background(), rdb))
+		})
+	}
+
+	_, err := OpenReadOnly("db", MemDBBackend, "")
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
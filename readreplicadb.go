@@ -0,0 +1,244 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// ReadReplicaDB sends all writes to a primary database and balances reads and
+// iterators over a set of read replicas, round-robin. Keeping the replicas up
+// to date with the primary (e.g. several RemoteDBs served from followers) is
+// outside of its scope, so reads may observe stale data.
+//
+// A read failing on a replica is retried on the next replicas, and finally on
+// the primary. Without replicas, all reads are served by the primary.
+type ReadReplicaDB struct {
+	primary  DB
+	replicas []DB
+	next     uint64   // atomic, index of the next replica to read from
+	reads    []uint64 // atomic, reads served per replica
+}
+
+var _ DB = (*ReadReplicaDB)(nil)
+
+// NewReadReplicaDB creates a ReadReplicaDB writing to primary and reading from
+// replicas. Closing the ReadReplicaDB closes all of them.
+func NewReadReplicaDB(primary DB, replicas ...DB) *ReadReplicaDB {
+	return &ReadReplicaDB{
+		primary:  primary,
+		replicas: append([]DB{}, replicas...),
+		reads:    make([]uint64, len(replicas)),
+	}
+}
+
+// read calls fn on the next replica, failing over to the others and then to the
+// primary on error. The last error is returned if they all fail.
+func (rdb *ReadReplicaDB) read(fn func(DB) error) error {
+	n := len(rdb.replicas)
+	if n == 0 {
+		return fn(rdb.primary)
+	}
+	first := int((atomic.AddUint64(&rdb.next, 1) - 1) % uint64(n))
+	for i := 0; i < n; i++ {
+		idx := (first + i) % n
+		if err := fn(rdb.replicas[idx]); err == nil {
+			atomic.AddUint64(&rdb.reads[idx], 1)
+			return nil
+		}
+	}
+	return fn(rdb.primary)
+}
+
+// Get implements DB.
+func (rdb *ReadReplicaDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	var value []byte
+	err := rdb.read(func(db DB) (err error) {
+		value, err = db.Get(key)
+		return err
+	})
+	return value, err
+}
+
+// MultiGet implements MultiGetter, reading all keys from the same replica.
+func (rdb *ReadReplicaDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+	}
+	var values [][]byte
+	err := rdb.read(func(db DB) (err error) {
+		values, err = MultiGet(db, keys)
+		return err
+	})
+	return values, err
+}
+
+// Has implements DB.
+func (rdb *ReadReplicaDB) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	var ok bool
+	err := rdb.read(func(db DB) (err error) {
+		ok, err = db.Has(key)
+		return err
+	})
+	return ok, err
+}
+
+// Set implements DB.
+func (rdb *ReadReplicaDB) Set(key []byte, value []byte) error {
+	return rdb.primary.Set(key, value)
+}
+
+// SetSync implements DB.
+func (rdb *ReadReplicaDB) SetSync(key []byte, value []byte) error {
+	return rdb.primary.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (rdb *ReadReplicaDB) Delete(key []byte) error {
+	return rdb.primary.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (rdb *ReadReplicaDB) DeleteSync(key []byte) error {
+	return rdb.primary.DeleteSync(key)
+}
+
+// Iterator implements DB.
+func (rdb *ReadReplicaDB) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	var itr Iterator
+	err := rdb.read(func(db DB) (err error) {
+		itr, err = db.Iterator(start, end)
+		return err
+	})
+	return itr, err
+}
+
+// ReverseIterator implements DB.
+func (rdb *ReadReplicaDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	var itr Iterator
+	err := rdb.read(func(db DB) (err error) {
+		itr, err = db.ReverseIterator(start, end)
+		return err
+	})
+	return itr, err
+}
+
+// Close implements DB. The primary and all replicas are closed, the first error
+// is returned.
+func (rdb *ReadReplicaDB) Close() error {
+	err := rdb.primary.Close()
+	for _, replica := range rdb.replicas {
+		if rerr := replica.Close(); err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// NewBatch implements DB. Batches are written to the primary.
+func (rdb *ReadReplicaDB) NewBatch() Batch {
+	return rdb.primary.NewBatch()
+}
+
+// Print implements DB. It prints the primary.
+func (rdb *ReadReplicaDB) Print() error {
+	return rdb.primary.Print()
+}
+
+// Stats implements DB. It returns the stats of the primary, along with the
+// number of reads served by each replica.
+func (rdb *ReadReplicaDB) Stats() map[string]string {
+	stats := rdb.primary.Stats()
+	if stats == nil {
+		stats = make(map[string]string)
+	}
+	stats["replica.count"] = strconv.Itoa(len(rdb.replicas))
+	for i := range rdb.replicas {
+		stats[fmt.Sprintf("replica.%d.reads", i)] = strconv.FormatUint(atomic.LoadUint64(&rdb.reads[i]), 10)
+	}
+	return stats
+}
+
+// Metrics implements MetricsReporter, with the metrics of the primary.
+func (rdb *ReadReplicaDB) Metrics() (Metrics, error) {
+	return GetMetrics(rdb.primary)
+}
+
+// CompareAndSwap implements ConditionalWriter on the primary, since the
+// replicas may lag behind it.
+func (rdb *ReadReplicaDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	return CompareAndSwap(rdb.primary, key, old, new)
+}
+
+// Count implements Counter, counting the keys of the primary.
+func (rdb *ReadReplicaDB) Count() (uint64, error) {
+	return Count(rdb.primary)
+}
+
+// EstimateCount implements CountEstimator, estimating the keys of the primary.
+func (rdb *ReadReplicaDB) EstimateCount() (uint64, error) {
+	return EstimateCount(rdb.primary)
+}
+
+// EstimateSize implements SizeEstimator, estimating the range on the primary.
+func (rdb *ReadReplicaDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(rdb.primary, start, end)
+}
+
+// DeleteRange implements RangeDeleter, deleting the range on the primary.
+func (rdb *ReadReplicaDB) DeleteRange(start, end []byte) error {
+	return DeleteRange(rdb.primary, start, end)
+}
+
+// Compact implements Compacter, compacting the primary. Replicas are expected
+// to be compacted where they are served.
+func (rdb *ReadReplicaDB) Compact(start, end []byte) error {
+	return Compact(rdb.primary, start, end)
+}
+
+// Flush implements Flusher, flushing the primary.
+func (rdb *ReadReplicaDB) Flush() error {
+	return Flush(rdb.primary)
+}
+
+// Sync implements Syncer, syncing the primary.
+func (rdb *ReadReplicaDB) Sync() error {
+	return Sync(rdb.primary)
+}
+
+// Backup implements Backupper, backing up the primary.
+func (rdb *ReadReplicaDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, rdb.primary, targetDir)
+}
+
+// Checkpoint implements Checkpointer, checkpointing the primary.
+func (rdb *ReadReplicaDB) Checkpoint(path string) error {
+	return Checkpoint(rdb.primary, path)
+}
+
+// HealthCheck implements HealthChecker, checking the primary and every
+// replica.
+func (rdb *ReadReplicaDB) HealthCheck(ctx context.Context) error {
+	return healthCheckAll(ctx, append([]DB{rdb.primary}, rdb.replicas...))
+}
+
+// Capabilities implements CapabilityReporter, with the features of the primary
+// which ReadReplicaDB passes through.
+func (rdb *ReadReplicaDB) Capabilities() Capability {
+	return Capabilities(rdb.primary) & (CapDeleteRange | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadReplicaDBRouting(t *testing.T) {
+	primary := NewMemDB()
+	replicas := []DB{NewMemDB(), NewMemDB()}
+	for i, replica := range replicas {
+		require.NoError(t, replica.Set(bz("replica"), []byte{byte(i)}))
+	}
+	rdb := NewReadReplicaDB(primary, replicas...)
+
+	// Writes go to the primary only.
+	require.NoError(t, rdb.Set(bz("a"), bz("1")))
+	checkValue(t, primary, bz("a"), bz("1"))
+	checkValue(t, replicas[0], bz("a"), nil)
+
+	// Reads alternate between the replicas.
+	checkValue(t, rdb, bz("replica"), []byte{0})
+	checkValue(t, rdb, bz("replica"), []byte{1})
+	checkValue(t, rdb, bz("replica"), []byte{0})
+
+	itr, err := rdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("replica"), []byte{1})
+	require.NoError(t, itr.Close())
+
+	stats := rdb.Stats()
+	assert.Equal(t, "2", stats["replica.count"])
+	assert.Equal(t, "2", stats["replica.0.reads"])
+	assert.Equal(t, "2", stats["replica.1.reads"])
+	require.NoError(t, rdb.Close())
+}
+
+// unavailableDB fails every read.
+type unavailableDB struct {
+	*MemDB
+}
+
+func (db unavailableDB) Get([]byte) ([]byte, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestReadReplicaDBFailover(t *testing.T) {
+	primary := NewMemDB()
+	require.NoError(t, primary.Set(bz("a"), bz("primary")))
+	healthy := NewMemDB()
+	require.NoError(t, healthy.Set(bz("a"), bz("replica")))
+
+	rdb := NewReadReplicaDB(primary, unavailableDB{NewMemDB()}, healthy)
+	for i := 0; i < 4; i++ {
+		checkValue(t, rdb, bz("a"), bz("replica"))
+	}
+
+	// With all replicas down, the primary serves the reads.
+	rdb = NewReadReplicaDB(primary, unavailableDB{NewMemDB()})
+	checkValue(t, rdb, bz("a"), bz("primary"))
+
+	// And without replicas too.
+	rdb = NewReadReplicaDB(primary)
+	checkValue(t, rdb, bz("a"), bz("primary"))
+}
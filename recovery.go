@@ -0,0 +1,31 @@
+package db
+
+// RecoveryInfo describes the recovery of a database found corrupted when
+// opened with Options.Recover, as reported to Options.OnRecovery. The counts
+// are only known for goleveldb, and are zero for the other backends.
+type RecoveryInfo struct {
+	// Err is the corruption which prevented opening the database.
+	Err error
+	// Tables is the number of tables scanned.
+	Tables int
+	// DroppedTables is the number of tables dropped, none of whose entries
+	// could be read.
+	DroppedTables int
+	// CorruptedBlocks is the number of blocks of the tables which could not be
+	// read, and were dropped along with their entries.
+	CorruptedBlocks int
+	// RecoveredEntries is the number of entries recovered from the tables,
+	// including the overwritten values and the deletions.
+	RecoveredEntries int
+}
+
+// reportRecovery logs the recovery of the database name, and reports it to
+// Options.OnRecovery.
+func reportRecovery(name string, opts *Options, info RecoveryInfo) {
+	opts.Logger.Error("Recovered corrupted db", "name", name, "err", info.Err,
+		"tables", info.Tables, "dropped_tables", info.DroppedTables,
+		"corrupted_blocks", info.CorruptedBlocks, "recovered_entries", info.RecoveredEntries)
+	if opts.OnRecovery != nil {
+		opts.OnRecovery(info)
+	}
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// removeManifests removes the manifests of the goleveldb database in dir, which
+// then fails to open as corrupted.
+func removeManifests(t *testing.T, dir string) {
+	manifests, err := filepath.Glob(filepath.Join(dir, "db.db", "MANIFEST-*"))
+	require.NoError(t, err)
+	for _, manifest := range manifests {
+		require.NoError(t, os.Remove(manifest))
+	}
+}
+
+func TestNewDBRecovery(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewDB("db", GoLevelDBBackend, dir)
+	require.NoError(t, err)
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.Set(bz("b"), bz("2")))
+	require.NoError(t, Compact(db, nil, nil))
+	require.NoError(t, db.Close())
+	removeManifests(t, dir)
+
+	_, err = NewDB("db", GoLevelDBBackend, dir)
+	assert.ErrorIs(t, err, ErrCorrupted)
+	_, err = NewDB("db", GoLevelDBBackend, dir, WithRecovery(nil), WithReadOnly())
+	assert.ErrorIs(t, err, ErrCorrupted)
+
+	var infos []RecoveryInfo
+	logger := &testLogger{}
+	db, err = NewDB("db", GoLevelDBBackend, dir, WithLogger(logger), WithRecovery(func(info RecoveryInfo) {
+		infos = append(infos, info)
+	}))
+	require.NoError(t, err)
+	checkValue(t, db, bz("a"), bz("1"))
+	checkValue(t, db, bz("b"), bz("2"))
+	require.NoError(t, db.Set(bz("c"), bz("3")))
+	require.NoError(t, db.Close())
+
+	require.Len(t, infos, 1)
+	assert.ErrorIs(t, infos[0].Err, ErrCorrupted)
+	assert.Equal(t, 1, infos[0].Tables)
+	assert.Equal(t, 2, infos[0].RecoveredEntries)
+	assert.Zero(t, infos[0].DroppedTables)
+	assert.Zero(t, infos[0].CorruptedBlocks)
+	assert.Equal(t, 1, logger.count("Recovered corrupted db"))
+
+	// The recovered database opens as usual.
+	db, err = NewDB("db", GoLevelDBBackend, dir)
+	require.NoError(t, err)
+	defer db.Close()
+	checkValue(t, db, bz("c"), bz("3"))
+}
+
+func TestNewDBRecoveryCorruptedTable(t *testing.T) {
+	dir := t.TempDir()
+	const n = 2000
+	newCorruptedGoLevelDB(t, dir, n)
+	removeManifests(t, dir)
+
+	var info RecoveryInfo
+	db, err := NewDB("db", GoLevelDBBackend, dir, WithRecovery(func(i RecoveryInfo) { info = i }))
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Equal(t, 1, info.Tables)
+	assert.Positive(t, info.CorruptedBlocks)
+	assert.Less(t, info.RecoveredEntries, n)
+	assert.Greater(t, info.RecoveredEntries, n/2)
+}
+
+func TestNewDBRecoveryNotSupported(t *testing.T) {
+	_, err := NewDB("db", PebbleDBBackend, t.TempDir(), WithRecovery(nil))
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
@@ -0,0 +1,60 @@
+package remotedb
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	db "github.com/cometbft/cometbft-db"
+	"github.com/cometbft/cometbft-db/remotedb/grpcdb"
+)
+
+// defaultRemoteBackend is the backend the server is asked to open when the
+// type parameter is omitted.
+const defaultRemoteBackend = db.GoLevelDBBackend
+
+func init() {
+	db.RegisterBackend(db.RemoteDBBackend, func(name, dir string) (db.DB, error) {
+		return newRemoteDBFromDir(name, dir)
+	})
+}
+
+// newRemoteDBFromDir connects to a remote database for db.NewDB. Since the
+// backend only receives a name and a directory, the directory holds the server
+// address followed by optional query parameters:
+//
+//	host:port?cert=server.crt&type=goleveldb&dir=data
+//
+// cert is the server certificate; without it the connection is plaintext. type
+// and dir are the backend and directory the server opens the database with,
+// type defaulting to goleveldb.
+func newRemoteDBFromDir(name, dir string) (*RemoteDB, error) {
+	addr, rawQuery, _ := strings.Cut(dir, "?")
+	if addr == "" {
+		return nil, fmt.Errorf("remotedb: missing server address in %q", dir)
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("remotedb: invalid parameters in %q: %w", dir, err)
+	}
+
+	var rd *RemoteDB
+	if cert := query.Get("cert"); cert != "" {
+		rd, err = NewRemoteDB(addr, cert)
+	} else {
+		rd, err = newRemoteDB(grpcdb.NewInsecureClient(addr))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backend := query.Get("type")
+	if backend == "" {
+		backend = string(defaultRemoteBackend)
+	}
+	if err := rd.InitRemote(&Init{Name: name, Type: backend, Dir: query.Get("dir")}); err != nil {
+		rd.Close()
+		return nil, err
+	}
+	return rd, nil
+}
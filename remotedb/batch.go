@@ -1,15 +1,12 @@
 package remotedb
 
 import (
-	"errors"
 	"fmt"
 
 	db "github.com/cometbft/cometbft-db"
 	protodb "github.com/cometbft/cometbft-db/remotedb/proto"
 )
 
-var errBatchClosed = errors.New("batch has been written or closed")
-
 type batch struct {
 	db  *RemoteDB
 	ops []*protodb.Operation
@@ -27,7 +24,7 @@ func newBatch(rdb *RemoteDB) *batch {
 // Set implements Batch.
 func (b *batch) Set(key, value []byte) error {
 	if b.ops == nil {
-		return errBatchClosed
+		return db.ErrBatchWritten
 	}
 	op := &protodb.Operation{
 		Entity: &protodb.Entity{Key: key, Value: value},
@@ -40,7 +37,7 @@ func (b *batch) Set(key, value []byte) error {
 // Delete implements Batch.
 func (b *batch) Delete(key []byte) error {
 	if b.ops == nil {
-		return errBatchClosed
+		return db.ErrBatchWritten
 	}
 	op := &protodb.Operation{
 		Entity: &protodb.Entity{Key: key},
@@ -50,14 +47,28 @@ func (b *batch) Delete(key []byte) error {
 	return nil
 }
 
+// Count implements Batch.
+func (b *batch) Count() int {
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *batch) SizeBytes() int {
+	size := 0
+	for _, op := range b.ops {
+		size += len(op.Entity.Key) + len(op.Entity.Value)
+	}
+	return size
+}
+
 // Write implements Batch.
 func (b *batch) Write() error {
 	if b.ops == nil {
-		return errBatchClosed
+		return db.ErrBatchWritten
 	}
 	_, err := b.db.dc.BatchWrite(b.db.ctx, &protodb.Batch{Ops: b.ops})
 	if err != nil {
-		return fmt.Errorf("remoteDB.BatchWrite: %w", err)
+		return fmt.Errorf("remoteDB.BatchWrite: %w", remoteError(b.db.ctx, err))
 	}
 	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
 	b.Close()
@@ -67,11 +78,11 @@ func (b *batch) Write() error {
 // WriteSync implements Batch.
 func (b *batch) WriteSync() error {
 	if b.ops == nil {
-		return errBatchClosed
+		return db.ErrBatchWritten
 	}
 	_, err := b.db.dc.BatchWriteSync(b.db.ctx, &protodb.Batch{Ops: b.ops})
 	if err != nil {
-		return fmt.Errorf("RemoteDB.BatchWriteSync: %w", err)
+		return fmt.Errorf("RemoteDB.BatchWriteSync: %w", remoteError(b.db.ctx, err))
 	}
 	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
 	return b.Close()
@@ -33,5 +33,13 @@ remote database with a client setup such as:
 	if !client.Has(dk1) {
 	      client.SetSync(dk1, dv1)
 	}
+
+Importing remotedb also registers the "remotedb" backend, so that a remote
+database can be opened through db.NewDB with the server address (and optional
+parameters) passed as the directory:
+
+	import _ "github.com/cometbft/cometbft-db/remotedb"
+
+	store, err := db.NewDB("state", db.RemoteDBBackend, "127.0.0.1:8998?cert=server.crt&type=goleveldb")
 */
 package remotedb
@@ -3,20 +3,33 @@ package grpcdb
 import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 
 	protodb "github.com/cometbft/cometbft-db/remotedb/proto"
 )
 
 // NewClient creates a gRPC client connected to the bound gRPC server at serverAddr.
-// Use kind to set the level of security to either Secure or Insecure.
-func NewClient(serverAddr, serverCert string) (protodb.DBClient, error) {
+// Use kind to set the level of security to either Secure or Insecure. The
+// returned connection must be closed once the client is no longer used.
+func NewClient(serverAddr, serverCert string) (protodb.DBClient, *grpc.ClientConn, error) {
 	creds, err := credentials.NewClientTLSFromFile(serverCert, "")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	cc, err := grpc.Dial(serverAddr, grpc.WithTransportCredentials(creds))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return protodb.NewDBClient(cc), nil
+	return protodb.NewDBClient(cc), cc, nil
+}
+
+// NewInsecureClient creates a gRPC client connected to the bound gRPC server at
+// serverAddr over a plaintext connection. Only use it on trusted networks. The
+// returned connection must be closed once the client is no longer used.
+func NewInsecureClient(serverAddr string) (protodb.DBClient, *grpc.ClientConn, error) {
+	cc, err := grpc.Dial(serverAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return protodb.NewDBClient(cc), cc, nil
 }
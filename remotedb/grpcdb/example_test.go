@@ -19,10 +19,11 @@ func Example() {
 		}
 	}()
 
-	client, err := grpcdb.NewClient(addr, cert)
+	client, conn, err := grpcdb.NewClient(addr, cert)
 	if err != nil {
 		log.Fatalf("Failed to create grpcDB client: %v", err)
 	}
+	defer conn.Close()
 
 	ctx := context.Background()
 	// 1. Initialize the DB
@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 
 	db "github.com/cometbft/cometbft-db"
 	protodb "github.com/cometbft/cometbft-db/remotedb/proto"
@@ -215,6 +217,9 @@ func (s *server) batchWrite(c context.Context, b *protodb.Batch, sync bool) (*pr
 	bat := s.db.NewBatch()
 	defer bat.Close()
 	for _, op := range b.Ops {
+		if op.Entity == nil {
+			return nil, status.Error(codes.InvalidArgument, "batch operation without entity")
+		}
 		switch op.Type {
 		case protodb.Operation_SET:
 			err := bat.Set(op.Entity.Key, op.Entity.Value)
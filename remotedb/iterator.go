@@ -1,23 +1,26 @@
 package remotedb
 
 import (
+	"context"
+
 	db "github.com/cometbft/cometbft-db"
 	protodb "github.com/cometbft/cometbft-db/remotedb/proto"
 )
 
-func makeIterator(dic protodb.DB_IteratorClient) db.Iterator {
-	itr := &iterator{dic: dic}
+func makeIterator(ctx context.Context, dic protodb.DB_IteratorClient) db.Iterator {
+	itr := &iterator{ctx: ctx, dic: dic}
 	itr.Next() // We need to call Next to prime the iterator
 	return itr
 }
 
-func makeReverseIterator(dric protodb.DB_ReverseIteratorClient) db.Iterator {
-	rItr := &reverseIterator{dric: dric}
+func makeReverseIterator(ctx context.Context, dric protodb.DB_ReverseIteratorClient) db.Iterator {
+	rItr := &reverseIterator{ctx: ctx, dric: dric}
 	rItr.Next() // We need to call Next to prime the iterator
 	return rItr
 }
 
 type reverseIterator struct {
+	ctx  context.Context
 	dric protodb.DB_ReverseIteratorClient
 	cur  *protodb.Iterator
 	err  error
@@ -43,7 +46,7 @@ func (rItr *reverseIterator) Next() {
 	var err error
 	rItr.cur, err = rItr.dric.Recv()
 	if err != nil {
-		rItr.err = err
+		rItr.err = remoteError(rItr.ctx, err)
 	}
 }
 
@@ -80,6 +83,7 @@ func (rItr *reverseIterator) assertIsValid() {
 // needed. It is NOT safe for concurrent usage,
 // matching the behavior of other iterators.
 type iterator struct {
+	ctx context.Context
 	dic protodb.DB_IteratorClient
 	cur *protodb.Iterator
 	err error
@@ -105,7 +109,7 @@ func (itr *iterator) Next() {
 	var err error
 	itr.cur, err = itr.dic.Recv()
 	if err != nil {
-		itr.err = err
+		itr.err = remoteError(itr.ctx, err)
 	}
 }
 
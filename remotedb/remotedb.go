@@ -5,6 +5,10 @@ import (
 	"errors"
 	"fmt"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	db "github.com/cometbft/cometbft-db"
 	"github.com/cometbft/cometbft-db/remotedb/grpcdb"
 	protodb "github.com/cometbft/cometbft-db/remotedb/proto"
@@ -13,17 +17,18 @@ import (
 type RemoteDB struct {
 	ctx context.Context
 	dc  protodb.DBClient
+	cc  *grpc.ClientConn
 }
 
 func NewRemoteDB(serverAddr string, serverKey string) (*RemoteDB, error) {
 	return newRemoteDB(grpcdb.NewClient(serverAddr, serverKey))
 }
 
-func newRemoteDB(gdc protodb.DBClient, err error) (*RemoteDB, error) {
+func newRemoteDB(gdc protodb.DBClient, cc *grpc.ClientConn, err error) (*RemoteDB, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &RemoteDB{dc: gdc, ctx: context.Background()}, nil
+	return &RemoteDB{dc: gdc, cc: cc, ctx: context.Background()}, nil
 }
 
 type Init struct {
@@ -37,63 +42,97 @@ func (rd *RemoteDB) InitRemote(in *Init) error {
 	return err
 }
 
-var _ db.DB = (*RemoteDB)(nil)
+var (
+	_ db.DB        = (*RemoteDB)(nil)
+	_ db.ContextDB = (*RemoteDB)(nil)
+)
 
-// Close is a noop currently
+// Close closes the connection to the server. The remote database itself is
+// left open on the server.
 func (rd *RemoteDB) Close() error {
-	return nil
+	return rd.cc.Close()
 }
 
 func (rd *RemoteDB) Delete(key []byte) error {
-	if _, err := rd.dc.Delete(rd.ctx, &protodb.Entity{Key: key}); err != nil {
-		return fmt.Errorf("remoteDB.Delete: %w", err)
+	return rd.DeleteContext(rd.ctx, key)
+}
+
+func (rd *RemoteDB) DeleteContext(ctx context.Context, key []byte) error {
+	if _, err := rd.dc.Delete(ctx, &protodb.Entity{Key: key}); err != nil {
+		return fmt.Errorf("remoteDB.Delete: %w", remoteError(ctx, err))
 	}
 	return nil
 }
 
 func (rd *RemoteDB) DeleteSync(key []byte) error {
-	if _, err := rd.dc.DeleteSync(rd.ctx, &protodb.Entity{Key: key}); err != nil {
-		return fmt.Errorf("remoteDB.DeleteSync: %w", err)
+	return rd.DeleteSyncContext(rd.ctx, key)
+}
+
+func (rd *RemoteDB) DeleteSyncContext(ctx context.Context, key []byte) error {
+	if _, err := rd.dc.DeleteSync(ctx, &protodb.Entity{Key: key}); err != nil {
+		return fmt.Errorf("remoteDB.DeleteSync: %w", remoteError(ctx, err))
 	}
 	return nil
 }
 
 func (rd *RemoteDB) Set(key, value []byte) error {
-	if _, err := rd.dc.Set(rd.ctx, &protodb.Entity{Key: key, Value: value}); err != nil {
-		return fmt.Errorf("remoteDB.Set: %w", err)
+	return rd.SetContext(rd.ctx, key, value)
+}
+
+func (rd *RemoteDB) SetContext(ctx context.Context, key, value []byte) error {
+	if _, err := rd.dc.Set(ctx, &protodb.Entity{Key: key, Value: value}); err != nil {
+		return fmt.Errorf("remoteDB.Set: %w", remoteError(ctx, err))
 	}
 	return nil
 }
 
 func (rd *RemoteDB) SetSync(key, value []byte) error {
-	if _, err := rd.dc.SetSync(rd.ctx, &protodb.Entity{Key: key, Value: value}); err != nil {
-		return fmt.Errorf("remoteDB.SetSync: %w", err)
+	return rd.SetSyncContext(rd.ctx, key, value)
+}
+
+func (rd *RemoteDB) SetSyncContext(ctx context.Context, key, value []byte) error {
+	if _, err := rd.dc.SetSync(ctx, &protodb.Entity{Key: key, Value: value}); err != nil {
+		return fmt.Errorf("remoteDB.SetSync: %w", remoteError(ctx, err))
 	}
 	return nil
 }
 
 func (rd *RemoteDB) Get(key []byte) ([]byte, error) {
-	res, err := rd.dc.Get(rd.ctx, &protodb.Entity{Key: key})
+	return rd.GetContext(rd.ctx, key)
+}
+
+func (rd *RemoteDB) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	res, err := rd.dc.Get(ctx, &protodb.Entity{Key: key})
 	if err != nil {
-		return nil, fmt.Errorf("remoteDB.Get error: %w", err)
+		return nil, fmt.Errorf("remoteDB.Get error: %w", remoteError(ctx, err))
 	}
 	return res.Value, nil
 }
 
 func (rd *RemoteDB) Has(key []byte) (bool, error) {
-	res, err := rd.dc.Has(rd.ctx, &protodb.Entity{Key: key})
+	return rd.HasContext(rd.ctx, key)
+}
+
+func (rd *RemoteDB) HasContext(ctx context.Context, key []byte) (bool, error) {
+	res, err := rd.dc.Has(ctx, &protodb.Entity{Key: key})
 	if err != nil {
-		return false, err
+		return false, remoteError(ctx, err)
 	}
 	return res.Exists, nil
 }
 
 func (rd *RemoteDB) ReverseIterator(start, end []byte) (db.Iterator, error) {
-	dic, err := rd.dc.ReverseIterator(rd.ctx, &protodb.Entity{Start: start, End: end})
+	return rd.ReverseIteratorContext(rd.ctx, start, end)
+}
+
+// ReverseIteratorContext implements db.ContextDB. The iterator streams from
+// the server within ctx.
+func (rd *RemoteDB) ReverseIteratorContext(ctx context.Context, start, end []byte) (db.Iterator, error) {
+	dic, err := rd.dc.ReverseIterator(ctx, &protodb.Entity{Start: start, End: end})
 	if err != nil {
-		return nil, fmt.Errorf("RemoteDB.Iterator error: %w", err)
+		return nil, fmt.Errorf("RemoteDB.Iterator error: %w", remoteError(ctx, err))
 	}
-	return makeReverseIterator(dic), nil
+	return makeReverseIterator(ctx, dic), nil
 }
 
 func (rd *RemoteDB) NewBatch() db.Batch {
@@ -115,9 +154,37 @@ func (rd *RemoteDB) Stats() map[string]string {
 }
 
 func (rd *RemoteDB) Iterator(start, end []byte) (db.Iterator, error) {
-	dic, err := rd.dc.Iterator(rd.ctx, &protodb.Entity{Start: start, End: end})
+	return rd.IteratorContext(rd.ctx, start, end)
+}
+
+// IteratorContext implements db.ContextDB. The iterator streams from the server
+// within ctx.
+func (rd *RemoteDB) IteratorContext(ctx context.Context, start, end []byte) (db.Iterator, error) {
+	dic, err := rd.dc.Iterator(ctx, &protodb.Entity{Start: start, End: end})
 	if err != nil {
-		return nil, fmt.Errorf("RemoteDB.Iterator error: %w", err)
+		return nil, fmt.Errorf("RemoteDB.Iterator error: %w", remoteError(ctx, err))
 	}
-	return makeIterator(dic), nil
+	return makeIterator(ctx, dic), nil
+}
+
+// errorKinds are the shared error kinds sent by the server as gRPC codes.
+var errorKinds = map[codes.Code]error{
+	codes.NotFound:           db.ErrKeyNotFound,
+	codes.FailedPrecondition: db.ErrClosed,
+	codes.DataLoss:           db.ErrCorrupted,
+	codes.PermissionDenied:   db.ErrReadOnly,
+	codes.Unavailable:        db.ErrUnavailable,
+}
+
+// remoteError returns the error of ctx if it is done, rather than the gRPC
+// status it caused, and otherwise wraps the status with the shared error kind
+// of its code, so that callers can match either with errors.Is.
+func remoteError(ctx context.Context, err error) error {
+	if cerr := ctx.Err(); cerr != nil {
+		return cerr
+	}
+	if kind, ok := errorKinds[status.Code(err)]; ok {
+		return fmt.Errorf("%w: %w", kind, err)
+	}
+	return err
 }
@@ -1,6 +1,7 @@
 package remotedb_test
 
 import (
+	"context"
 	"net"
 	"os"
 	"testing"
@@ -8,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	db "github.com/cometbft/cometbft-db"
 	"github.com/cometbft/cometbft-db/remotedb"
 	"github.com/cometbft/cometbft-db/remotedb/grpcdb"
 )
@@ -28,9 +30,11 @@ func TestRemoteDB(t *testing.T) {
 
 	client, err := remotedb.NewRemoteDB(ln.Addr().String(), cert)
 	require.Nil(t, err, "expecting a successful client creation")
+	defer client.Close()
 	dbName := "test-remote-db"
 	require.Nil(t, client.InitRemote(&remotedb.Init{Name: dbName, Type: "goleveldb"}))
 	defer os.RemoveAll(dbName + ".db")
+	defer os.RemoveAll(dbName + ".lock")
 
 	k1 := []byte("key-1")
 	v1, err := client.Get(k1)
@@ -165,3 +169,77 @@ func TestRemoteDB(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, rv5, v5, "expecting k5 to have been stored")
 }
+
+func TestRemoteDBBackend(t *testing.T) {
+	cert := "test.crt"
+	key := "test.key"
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.Nil(t, err, "expecting a port to have been assigned on which we can listen")
+	srv, err := grpcdb.NewServer(cert, key)
+	require.Nil(t, err)
+	defer srv.Stop()
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			panic(err)
+		}
+	}()
+
+	client, err := db.NewDB("test-remote-backend", db.RemoteDBBackend, ln.Addr().String()+"?cert="+cert+"&type=memdb")
+	require.NoError(t, err)
+	_, ok := client.(*remotedb.RemoteDB)
+	require.True(t, ok)
+
+	require.NoError(t, client.Set([]byte("key"), []byte("value")))
+	value, err := client.Get([]byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+
+	// Closing releases the connection to the server.
+	require.NoError(t, client.Close())
+	_, err = client.Get([]byte("key"))
+	require.Error(t, err)
+
+	_, err = db.NewDB("test-remote-backend", db.RemoteDBBackend, ln.Addr().String()+"?type=memdb;")
+	require.Error(t, err, "expecting invalid parameters to be rejected")
+}
+
+func TestRemoteDBContext(t *testing.T) {
+	cert := "test.crt"
+	key := "test.key"
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.Nil(t, err, "expecting a port to have been assigned on which we can listen")
+	srv, err := grpcdb.NewServer(cert, key)
+	require.Nil(t, err)
+	defer srv.Stop()
+	go func() {
+		if err := srv.Serve(ln); err != nil {
+			panic(err)
+		}
+	}()
+
+	client, err := db.NewDB("test-remote-context", db.RemoteDBBackend, ln.Addr().String()+"?cert="+cert+"&type=memdb")
+	require.NoError(t, err)
+	defer client.Close()
+	require.NoError(t, db.SetContext(context.Background(), client, []byte("key"), []byte("value")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	value, err := db.GetContext(ctx, client, []byte("key"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), value)
+	itr, err := db.IteratorContext(ctx, client, nil, nil)
+	require.NoError(t, err)
+	require.True(t, itr.Valid())
+
+	cancel()
+	_, err = db.GetContext(ctx, client, []byte("key"))
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, db.DeleteContext(ctx, client, []byte("key")), context.Canceled)
+	_, err = db.ReverseIteratorContext(ctx, client, nil, nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// The stream of the open iterator is cancelled too.
+	itr.Next()
+	require.False(t, itr.Valid())
+	require.ErrorIs(t, itr.Error(), context.Canceled)
+	require.NoError(t, itr.Close())
+}
@@ -0,0 +1,26 @@
+/*
+server exposes an already opened db.DB over gRPC, using the same service
+definition as grpcdb so that remotedb.RemoteDB can be used as the client.
+
+Unlike grpcdb, where the client chooses which database the server opens
+through Init, the database here is owned by the host process. This makes it
+possible to run the storage layer in a separate process or on another host:
+
+	ldb, err := db.NewGoLevelDB("state", dir)
+	srv, err := server.New(ldb,
+	    server.WithTLS("server.crt", "server.key"),
+	    server.WithRequestTimeout(5*time.Second),
+	)
+	go func() {
+	    if err := srv.ListenAndServe(":8998"); err != nil {
+	        log.Fatalf("serve: %v", err)
+	    }
+	}()
+
+	// On exit, finish in-flight RPCs before closing the database.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	srv.Shutdown(ctx)
+	ldb.Close()
+*/
+package server
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	db "github.com/cometbft/cometbft-db"
+	protodb "github.com/cometbft/cometbft-db/remotedb/proto"
+)
+
+// Server serves a single db.DB over gRPC.
+type Server struct {
+	srv *grpc.Server
+}
+
+type config struct {
+	creds          credentials.TransportCredentials
+	requestTimeout time.Duration
+	streamTimeout  time.Duration
+	grpcOpts       []grpc.ServerOption
+}
+
+// Option configures a Server.
+type Option func(*config) error
+
+// WithTLS serves over TLS using the given certificate and key files.
+// Without it, the server accepts plaintext connections.
+func WithTLS(certFile, keyFile string) Option {
+	return func(c *config) error {
+		creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+		c.creds = creds
+		return nil
+	}
+}
+
+// WithRequestTimeout bounds the duration of every unary RPC (Get, Set, Delete,
+// BatchWrite, ...). A shorter deadline set by the client still applies. Zero,
+// the default, means no server-side deadline.
+func WithRequestTimeout(d time.Duration) Option {
+	return func(c *config) error {
+		c.requestTimeout = d
+		return nil
+	}
+}
+
+// WithStreamTimeout bounds the duration of every streaming RPC, and therefore
+// how long a remote iterator may be kept open. Zero, the default, means no
+// server-side deadline.
+func WithStreamTimeout(d time.Duration) Option {
+	return func(c *config) error {
+		c.streamTimeout = d
+		return nil
+	}
+}
+
+// WithServerOptions passes additional options to the underlying grpc.Server.
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(c *config) error {
+		c.grpcOpts = append(c.grpcOpts, opts...)
+		return nil
+	}
+}
+
+// New creates a Server exposing database. The caller keeps ownership of
+// database and must close it after the server has been shut down.
+func New(database db.DB, opts ...Option) (*Server, error) {
+	if database == nil {
+		return nil, errors.New("server: database is nil")
+	}
+	cfg := &config{}
+	for _, opt := range opts {
+		if err := opt(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	grpcOpts := append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryErrorInterceptor, unaryTimeoutInterceptor(cfg.requestTimeout)),
+		grpc.ChainStreamInterceptor(streamErrorInterceptor, streamTimeoutInterceptor(cfg.streamTimeout)),
+	}, cfg.grpcOpts...)
+	if cfg.creds != nil {
+		grpcOpts = append(grpcOpts, grpc.Creds(cfg.creds))
+	}
+	srv := grpc.NewServer(grpcOpts...)
+	protodb.RegisterDBServer(srv, &service{db: database})
+	return &Server{srv: srv}, nil
+}
+
+// Serve accepts connections on ln and blocks until the server stops. It returns
+// nil once the server has been shut down.
+func (s *Server) Serve(ln net.Listener) error {
+	err := s.srv.Serve(ln)
+	if errors.Is(err, grpc.ErrServerStopped) {
+		return nil
+	}
+	return err
+}
+
+// ListenAndServe listens on the TCP address addr and calls Serve.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Shutdown stops accepting new connections and RPCs, and waits for the
+// in-flight ones to complete. If ctx is done first, the remaining RPCs are
+// cancelled and ctx.Err() is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.srv.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.srv.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Stop closes all connections and cancels in-flight RPCs immediately.
+func (s *Server) Stop() {
+	s.srv.Stop()
+}
+
+// errorCodes are the gRPC codes of the shared error kinds, with which clients
+// can restore them.
+var errorCodes = []struct {
+	kind error
+	code codes.Code
+}{
+	{db.ErrKeyNotFound, codes.NotFound},
+	{db.ErrClosed, codes.FailedPrecondition},
+	{db.ErrCorrupted, codes.DataLoss},
+	{db.ErrReadOnly, codes.PermissionDenied},
+	{db.ErrUnavailable, codes.Unavailable},
+}
+
+// statusError converts the database errors of the shared kinds into gRPC status
+// errors with the matching codes.
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.kind) {
+			return status.Error(ec.code, err.Error())
+		}
+	}
+	return err
+}
+
+func unaryErrorInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	return resp, statusError(err)
+}
+
+func streamErrorInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return statusError(handler(srv, ss))
+}
+
+func unaryTimeoutInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamTimeoutInterceptor(timeout time.Duration) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(ss.Context(), timeout)
+			defer cancel()
+			ss = &timeoutStream{ServerStream: ss, ctx: ctx}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// timeoutStream overrides the context of a grpc.ServerStream.
+type timeoutStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *timeoutStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,192 @@
+package server_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	db "github.com/cometbft/cometbft-db"
+	"github.com/cometbft/cometbft-db/remotedb"
+	"github.com/cometbft/cometbft-db/remotedb/grpcdb"
+	protodb "github.com/cometbft/cometbft-db/remotedb/proto"
+	"github.com/cometbft/cometbft-db/remotedb/server"
+)
+
+const (
+	cert = "../test.crt"
+	key  = "../test.key"
+)
+
+// startServer serves database on a random local port and returns a connected
+// client.
+func startServer(t *testing.T, database db.DB, opts ...server.Option) (*server.Server, *remotedb.RemoteDB) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	srv, err := server.New(database, append([]server.Option{server.WithTLS(cert, key)}, opts...)...)
+	require.NoError(t, err)
+
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+	t.Cleanup(func() {
+		srv.Stop()
+		require.NoError(t, <-served)
+	})
+
+	client, err := remotedb.NewRemoteDB(ln.Addr().String(), cert)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	require.NoError(t, client.InitRemote(&remotedb.Init{}))
+	return srv, client
+}
+
+func TestServer(t *testing.T) {
+	memdb := db.NewMemDB()
+	_, client := startServer(t, memdb)
+
+	require.NoError(t, client.Set([]byte("a"), []byte{1}))
+	require.NoError(t, client.SetSync([]byte("b"), []byte{2}))
+
+	// Writes go straight to the served database.
+	value, err := memdb.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1}, value)
+
+	bat := client.NewBatch()
+	require.NoError(t, bat.Set([]byte("c"), []byte{3}))
+	require.NoError(t, bat.Delete([]byte("b")))
+	require.NoError(t, bat.Write())
+
+	itr, err := client.Iterator(nil, nil)
+	require.NoError(t, err)
+	var keys []string
+	for ; itr.Valid(); itr.Next() {
+		keys = append(keys, string(itr.Key()))
+	}
+	require.NoError(t, itr.Close())
+	assert.Equal(t, []string{"a", "c"}, keys)
+
+	itr, err = client.ReverseIterator(nil, []byte("c"))
+	require.NoError(t, err)
+	require.True(t, itr.Valid())
+	assert.Equal(t, []byte("a"), itr.Key())
+	itr.Next()
+	assert.False(t, itr.Valid())
+	require.NoError(t, itr.Close())
+
+	require.NoError(t, client.Delete([]byte("a")))
+	has, err := client.Has([]byte("a"))
+	require.NoError(t, err)
+	assert.False(t, has)
+}
+
+func TestServerNilDB(t *testing.T) {
+	_, err := server.New(nil)
+	require.Error(t, err)
+}
+
+func TestServerErrorKinds(t *testing.T) {
+	fdb := db.NewFaultDB(db.NewMemDB())
+	fdb.Inject(db.Fault{Ops: db.FaultGet | db.FaultBatchWrite, Err: db.ErrFaultCorruption})
+	_, client := startServer(t, fdb)
+
+	_, err := client.Get([]byte("a"))
+	assert.ErrorIs(t, err, db.ErrCorrupted)
+	assert.Equal(t, codes.DataLoss, status.Code(err))
+
+	bat := client.NewBatch()
+	require.NoError(t, bat.Set([]byte("a"), []byte{1}))
+	assert.ErrorIs(t, bat.Write(), db.ErrCorrupted)
+	require.NoError(t, bat.Close())
+}
+
+func TestServerBatchWithoutEntity(t *testing.T) {
+	memdb := db.NewMemDB()
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	srv, err := server.New(memdb, server.WithTLS(cert, key))
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+	defer func() {
+		srv.Stop()
+		require.NoError(t, <-served)
+	}()
+
+	client, conn, err := grpcdb.NewClient(ln.Addr().String(), cert)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	for _, typ := range []protodb.Operation_Type{protodb.Operation_SET, protodb.Operation_DELETE} {
+		_, err = client.BatchWrite(context.Background(), &protodb.Batch{Ops: []*protodb.Operation{
+			{Entity: &protodb.Entity{Key: []byte("a"), Value: []byte{1}}, Type: protodb.Operation_SET},
+			{Type: typ},
+		}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	has, err := memdb.Has([]byte("a"))
+	require.NoError(t, err)
+	assert.False(t, has, "expecting the rejected batch not to be written")
+}
+
+// slowDB delays every iterator step.
+type slowDB struct {
+	db.DB
+	delay time.Duration
+}
+
+func (s *slowDB) Iterator(start, end []byte) (db.Iterator, error) {
+	itr, err := s.DB.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return &slowIterator{Iterator: itr, delay: s.delay}, nil
+}
+
+type slowIterator struct {
+	db.Iterator
+	delay time.Duration
+}
+
+func (s *slowIterator) Next() {
+	time.Sleep(s.delay)
+	s.Iterator.Next()
+}
+
+func TestServerStreamTimeout(t *testing.T) {
+	memdb := db.NewMemDB()
+	for i := byte(0); i < 100; i++ {
+		require.NoError(t, memdb.Set([]byte{i}, []byte{i}))
+	}
+	_, client := startServer(t, &slowDB{DB: memdb, delay: 10 * time.Millisecond},
+		server.WithStreamTimeout(100*time.Millisecond))
+
+	itr, err := client.Iterator(nil, nil)
+	require.NoError(t, err)
+	n := 0
+	for ; itr.Valid(); itr.Next() {
+		n++
+	}
+	assert.Less(t, n, 100)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(itr.Error()))
+	require.NoError(t, itr.Close())
+}
+
+func TestServerShutdown(t *testing.T) {
+	srv, client := startServer(t, db.NewMemDB())
+	require.NoError(t, client.Set([]byte("a"), []byte{1}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, srv.Shutdown(ctx))
+
+	// The server no longer accepts RPCs.
+	_, err := client.Get([]byte("a"))
+	require.Error(t, err)
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	db "github.com/cometbft/cometbft-db"
+	protodb "github.com/cometbft/cometbft-db/remotedb/proto"
+)
+
+// service implements protodb.DBServer on top of a fixed database.
+//
+// The database operations themselves are not cancellable, so deadlines are
+// checked before every operation and between iterator items.
+type service struct {
+	db db.DB
+}
+
+var _ protodb.DBServer = (*service)(nil)
+
+var nothing = new(protodb.Nothing)
+
+// checkContext returns the gRPC status error for a done context, if any.
+func checkContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	return nil
+}
+
+// Init implements protodb.DBServer. The database is chosen by the host process,
+// so the requested name, type and directory are ignored.
+func (s *service) Init(ctx context.Context, _ *protodb.Init) (*protodb.Entity, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	return &protodb.Entity{CreatedAt: time.Now().Unix()}, nil
+}
+
+func (s *service) Get(ctx context.Context, in *protodb.Entity) (*protodb.Entity, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	value, err := s.db.Get(in.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &protodb.Entity{Key: in.Key, Value: value}, nil
+}
+
+func (s *service) GetStream(ds protodb.DB_GetStreamServer) error {
+	ctx := ds.Context()
+	for {
+		in, err := ds.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		out, err := s.Get(ctx, in)
+		if err != nil {
+			out = &protodb.Entity{Key: in.Key, Err: err.Error()}
+		}
+		if err := ds.Send(out); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *service) Has(ctx context.Context, in *protodb.Entity) (*protodb.Entity, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	exists, err := s.db.Has(in.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &protodb.Entity{Exists: exists}, nil
+}
+
+func (s *service) Set(ctx context.Context, in *protodb.Entity) (*protodb.Nothing, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.db.Set(in.Key, in.Value); err != nil {
+		return nil, err
+	}
+	return nothing, nil
+}
+
+func (s *service) SetSync(ctx context.Context, in *protodb.Entity) (*protodb.Nothing, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.db.SetSync(in.Key, in.Value); err != nil {
+		return nil, err
+	}
+	return nothing, nil
+}
+
+func (s *service) Delete(ctx context.Context, in *protodb.Entity) (*protodb.Nothing, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.db.Delete(in.Key); err != nil {
+		return nil, err
+	}
+	return nothing, nil
+}
+
+func (s *service) DeleteSync(ctx context.Context, in *protodb.Entity) (*protodb.Nothing, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.db.DeleteSync(in.Key); err != nil {
+		return nil, err
+	}
+	return nothing, nil
+}
+
+func (s *service) Iterator(query *protodb.Entity, dis protodb.DB_IteratorServer) error {
+	it, err := s.db.Iterator(query.Start, query.End)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	return sendIterator(dis.Context(), it, dis.Send)
+}
+
+func (s *service) ReverseIterator(query *protodb.Entity, dis protodb.DB_ReverseIteratorServer) error {
+	it, err := s.db.ReverseIterator(query.Start, query.End)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	return sendIterator(dis.Context(), it, dis.Send)
+}
+
+func sendIterator(ctx context.Context, it db.Iterator, send func(*protodb.Iterator) error) error {
+	start, end := it.Domain()
+	domain := &protodb.Domain{Start: start, End: end}
+	for ; it.Valid(); it.Next() {
+		if err := checkContext(ctx); err != nil {
+			return err
+		}
+		out := &protodb.Iterator{
+			Domain: domain,
+			Valid:  true,
+			Key:    it.Key(),
+			Value:  it.Value(),
+		}
+		if err := send(out); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+func (s *service) Stats(ctx context.Context, _ *protodb.Nothing) (*protodb.Stats, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	return &protodb.Stats{Data: s.db.Stats(), TimeAt: time.Now().Unix()}, nil
+}
+
+func (s *service) BatchWrite(ctx context.Context, b *protodb.Batch) (*protodb.Nothing, error) {
+	return s.batchWrite(ctx, b, false)
+}
+
+func (s *service) BatchWriteSync(ctx context.Context, b *protodb.Batch) (*protodb.Nothing, error) {
+	return s.batchWrite(ctx, b, true)
+}
+
+func (s *service) batchWrite(ctx context.Context, b *protodb.Batch, sync bool) (*protodb.Nothing, error) {
+	bat := s.db.NewBatch()
+	defer bat.Close()
+	for _, op := range b.Ops {
+		if op.Entity == nil {
+			return nil, status.Error(codes.InvalidArgument, "batch operation without entity")
+		}
+		var err error
+		switch op.Type {
+		case protodb.Operation_SET:
+			err = bat.Set(op.Entity.Key, op.Entity.Value)
+		case protodb.Operation_DELETE:
+			err = bat.Delete(op.Entity.Key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Last chance to honour the deadline, the write itself cannot be aborted.
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	var err error
+	if sync {
+		err = bat.WriteSync()
+	} else {
+		err = bat.Write()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nothing, nil
+}
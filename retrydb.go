@@ -0,0 +1,384 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy defines how a RetryDB retries the errors of a class.
+type RetryPolicy struct {
+	// Match reports whether err belongs to the class of the policy.
+	Match func(err error) bool
+
+	// MaxAttempts is the maximum number of attempts of an operation, including
+	// the first one.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. It doubles for every
+	// following retry, up to MaxBackoff.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum delay between two retries. The delay is not
+	// capped if zero.
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction of every delay, between 0 and 1, which is
+	// randomized, so that concurrent callers do not retry in lockstep.
+	Jitter float64
+}
+
+// DefaultRetryPolicy retries ErrUnavailable errors, such as lock contention on
+// SQLite and BadgerDB or lost connections to a RemoteDB, up to 5 times.
+var DefaultRetryPolicy = RetryPolicy{
+	Match:          func(err error) bool { return errors.Is(err, ErrUnavailable) },
+	MaxAttempts:    5,
+	InitialBackoff: 10 * time.Millisecond,
+	MaxBackoff:     time.Second,
+	Jitter:         0.5,
+}
+
+// backoff returns the delay before the given retry, starting from 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.InitialBackoff
+	for i := 1; i < retry && delay <= math.MaxInt64/2; i++ {
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	if p.Jitter > 0 && delay > 0 {
+		jitter := time.Duration(p.Jitter * float64(delay))
+		delay = delay - jitter + time.Duration(rand.Int63n(int64(jitter)+1)) //nolint:gosec
+	}
+	return delay
+}
+
+// RetryDB retries the operations on a database which fail with transient
+// errors, with exponential backoff and jitter, e.g. for remote databases. The
+// errors are classified by retry policies, checked in order; errors matched by
+// no policy are returned at once.
+//
+// Reads, writes, batch writes and the creation of iterators are retried, since
+// repeating them is harmless. Conditional writes and the operations of
+// iterators are not. The ContextDB methods stop retrying once their context is
+// done, returning the last error.
+type RetryDB struct {
+	db       DB
+	policies []RetryPolicy
+
+	retries uint64 // atomic
+}
+
+var (
+	_ DB        = (*RetryDB)(nil)
+	_ ContextDB = (*RetryDB)(nil)
+)
+
+// NewRetryDB creates a RetryDB over db with the given policies, or with
+// DefaultRetryPolicy if there are none. Closing the RetryDB closes db.
+func NewRetryDB(db DB, policies ...RetryPolicy) *RetryDB {
+	if len(policies) == 0 {
+		policies = []RetryPolicy{DefaultRetryPolicy}
+	}
+	return &RetryDB{db: db, policies: policies}
+}
+
+// policy returns the policy matching err, if any.
+func (rdb *RetryDB) policy(err error) (RetryPolicy, bool) {
+	for _, p := range rdb.policies {
+		if p.Match(err) {
+			return p, true
+		}
+	}
+	return RetryPolicy{}, false
+}
+
+// retry runs op until it succeeds, fails with an error no policy retries, or
+// exhausts the attempts of the policy of its error.
+func (rdb *RetryDB) retry(ctx context.Context, op func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		if perr, ok := err.(permanentError); ok {
+			return perr.err
+		}
+		p, ok := rdb.policy(err)
+		if !ok || attempt >= p.MaxAttempts {
+			return err
+		}
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		atomic.AddUint64(&rdb.retries, 1)
+	}
+}
+
+// permanentError is returned by the operations given to retry to stop retrying
+// with err.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// Get implements DB.
+func (rdb *RetryDB) Get(key []byte) ([]byte, error) {
+	return rdb.GetContext(context.Background(), key)
+}
+
+// GetContext implements ContextDB.
+func (rdb *RetryDB) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	var value []byte
+	err := rdb.retry(ctx, func() (err error) {
+		value, err = GetContext(ctx, rdb.db, key)
+		return err
+	})
+	return value, err
+}
+
+// Has implements DB.
+func (rdb *RetryDB) Has(key []byte) (bool, error) {
+	return rdb.HasContext(context.Background(), key)
+}
+
+// HasContext implements ContextDB.
+func (rdb *RetryDB) HasContext(ctx context.Context, key []byte) (bool, error) {
+	var has bool
+	err := rdb.retry(ctx, func() (err error) {
+		has, err = HasContext(ctx, rdb.db, key)
+		return err
+	})
+	return has, err
+}
+
+// Set implements DB.
+func (rdb *RetryDB) Set(key []byte, value []byte) error {
+	return rdb.SetContext(context.Background(), key, value)
+}
+
+// SetContext implements ContextDB.
+func (rdb *RetryDB) SetContext(ctx context.Context, key, value []byte) error {
+	return rdb.retry(ctx, func() error { return SetContext(ctx, rdb.db, key, value) })
+}
+
+// SetSync implements DB.
+func (rdb *RetryDB) SetSync(key []byte, value []byte) error {
+	return rdb.SetSyncContext(context.Background(), key, value)
+}
+
+// SetSyncContext implements ContextDB.
+func (rdb *RetryDB) SetSyncContext(ctx context.Context, key, value []byte) error {
+	return rdb.retry(ctx, func() error { return SetSyncContext(ctx, rdb.db, key, value) })
+}
+
+// Delete implements DB.
+func (rdb *RetryDB) Delete(key []byte) error {
+	return rdb.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext implements ContextDB.
+func (rdb *RetryDB) DeleteContext(ctx context.Context, key []byte) error {
+	return rdb.retry(ctx, func() error { return DeleteContext(ctx, rdb.db, key) })
+}
+
+// DeleteSync implements DB.
+func (rdb *RetryDB) DeleteSync(key []byte) error {
+	return rdb.DeleteSyncContext(context.Background(), key)
+}
+
+// DeleteSyncContext implements ContextDB.
+func (rdb *RetryDB) DeleteSyncContext(ctx context.Context, key []byte) error {
+	return rdb.retry(ctx, func() error { return DeleteSyncContext(ctx, rdb.db, key) })
+}
+
+// Iterator implements DB.
+func (rdb *RetryDB) Iterator(start, end []byte) (Iterator, error) {
+	return rdb.IteratorContext(context.Background(), start, end)
+}
+
+// IteratorContext implements ContextDB.
+func (rdb *RetryDB) IteratorContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	var itr Iterator
+	err := rdb.retry(ctx, func() (err error) {
+		itr, err = IteratorContext(ctx, rdb.db, start, end)
+		return err
+	})
+	return itr, err
+}
+
+// ReverseIterator implements DB.
+func (rdb *RetryDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return rdb.ReverseIteratorContext(context.Background(), start, end)
+}
+
+// ReverseIteratorContext implements ContextDB.
+func (rdb *RetryDB) ReverseIteratorContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	var itr Iterator
+	err := rdb.retry(ctx, func() (err error) {
+		itr, err = ReverseIteratorContext(ctx, rdb.db, start, end)
+		return err
+	})
+	return itr, err
+}
+
+// Close implements DB.
+func (rdb *RetryDB) Close() error {
+	return rdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (rdb *RetryDB) NewBatch() Batch {
+	return &retryDBBatch{db: rdb, source: rdb.db.NewBatch()}
+}
+
+// Print implements DB.
+func (rdb *RetryDB) Print() error {
+	return rdb.db.Print()
+}
+
+// Stats implements DB.
+func (rdb *RetryDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range rdb.db.Stats() {
+		stats["retry.db."+k] = v
+	}
+	stats["retry.retries"] = strconv.FormatUint(atomic.LoadUint64(&rdb.retries), 10)
+	return stats
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database. It is not retried, since a failed attempt may have
+// swapped the value.
+func (rdb *RetryDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	return CompareAndSwap(rdb.db, key, old, new)
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+func (rdb *RetryDB) Snapshot() (DBReader, error) {
+	return Snapshot(rdb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (rdb *RetryDB) Metrics() (Metrics, error) {
+	return GetMetrics(rdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (rdb *RetryDB) EstimateCount() (uint64, error) {
+	return EstimateCount(rdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (rdb *RetryDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(rdb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (rdb *RetryDB) Compact(start, end []byte) error {
+	return Compact(rdb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (rdb *RetryDB) Flush() error {
+	return Flush(rdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (rdb *RetryDB) Sync() error {
+	return Sync(rdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+func (rdb *RetryDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, rdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (rdb *RetryDB) Checkpoint(path string) error {
+	return Checkpoint(rdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database. It
+// is not retried, so that transient failures are reported.
+func (rdb *RetryDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, rdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which RetryDB passes through.
+func (rdb *RetryDB) Capabilities() Capability {
+	return Capabilities(rdb.db) & (CapSnapshot | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
+
+// retryDBBatch retries the writes of a batch of the underlying database.
+type retryDBBatch struct {
+	db     *RetryDB
+	source Batch
+}
+
+var _ Batch = (*retryDBBatch)(nil)
+
+// Set implements Batch.
+func (b *retryDBBatch) Set(key, value []byte) error {
+	return b.source.Set(key, value)
+}
+
+// Delete implements Batch.
+func (b *retryDBBatch) Delete(key []byte) error {
+	return b.source.Delete(key)
+}
+
+// Count implements Batch.
+func (b *retryDBBatch) Count() int {
+	return b.source.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *retryDBBatch) SizeBytes() int {
+	return b.source.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *retryDBBatch) Write() error {
+	return b.write(b.source.Write)
+}
+
+// WriteSync implements Batch.
+func (b *retryDBBatch) WriteSync() error {
+	return b.write(b.source.WriteSync)
+}
+
+// write retries write. Backends which do not allow writing a batch again after
+// a failure return ErrBatchWritten, in which case the previous error is
+// returned.
+func (b *retryDBBatch) write(write func() error) error {
+	var last error
+	return b.db.retry(context.Background(), func() error {
+		err := write()
+		if last != nil && errors.Is(err, ErrBatchWritten) {
+			return permanentError{last}
+		}
+		last = err
+		return err
+	})
+}
+
+// Close implements Batch.
+func (b *retryDBBatch) Close() error {
+	return b.source.Close()
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryDB(t *testing.T) {
+	fdb := NewFaultDB(NewMemDB())
+	policy := DefaultRetryPolicy
+	policy.InitialBackoff = time.Millisecond
+	rdb := NewRetryDB(fdb, policy)
+	defer rdb.Close()
+
+	// Transient errors are retried.
+	fdb.Inject(Fault{Ops: FaultAll, Times: 2, Err: ErrFaultTimeout})
+	require.NoError(t, rdb.Set(bz("a"), bz("1")))
+	checkValue(t, rdb, bz("a"), bz("1"))
+	assert.Equal(t, "2", rdb.Stats()["retry.retries"])
+
+	fdb.Clear()
+	fdb.Inject(Fault{Ops: FaultBatchWrite | FaultIterator, Times: 2, Err: ErrFaultTimeout})
+	batch := rdb.NewBatch()
+	require.NoError(t, batch.Set(bz("b"), bz("2")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	itr, err := rdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("a"), bz("1"))
+	require.NoError(t, itr.Close())
+
+	// Other errors, and the errors of the last attempt, are returned.
+	fdb.Clear()
+	fdb.Inject(Fault{Ops: FaultGet, Times: 1, Err: ErrFaultDiskFull})
+	_, err = rdb.Get(bz("a"))
+	assert.ErrorIs(t, err, ErrFaultDiskFull)
+	fdb.Clear()
+	fdb.Inject(Fault{Ops: FaultGet, Err: ErrFaultTimeout})
+	_, err = rdb.Get(bz("a"))
+	assert.ErrorIs(t, err, ErrUnavailable)
+	assert.Equal(t, "8", rdb.Stats()["retry.retries"])
+}
+
+func TestRetryDBPolicies(t *testing.T) {
+	fdb := NewFaultDB(NewMemDB())
+	rdb := NewRetryDB(fdb, RetryPolicy{
+		Match:       func(err error) bool { return err == ErrFaultDiskFull },
+		MaxAttempts: 2,
+	}, RetryPolicy{
+		Match:          func(err error) bool { return err == ErrFaultTimeout },
+		MaxAttempts:    10,
+		InitialBackoff: time.Hour,
+	})
+	defer rdb.Close()
+
+	fdb.Inject(Fault{Ops: FaultSet, Times: 2, Err: ErrFaultDiskFull})
+	assert.ErrorIs(t, rdb.Set(bz("a"), bz("1")), ErrFaultDiskFull)
+	require.NoError(t, rdb.Set(bz("a"), bz("1")))
+
+	// The retries stop when the context is done.
+	fdb.Inject(Fault{Ops: FaultDelete, Err: ErrFaultTimeout})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, DeleteContext(ctx, rdb, bz("a")), ErrFaultTimeout)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	assert.Equal(t, 10*time.Millisecond, p.backoff(1))
+	assert.Equal(t, 20*time.Millisecond, p.backoff(2))
+	assert.Equal(t, 40*time.Millisecond, p.backoff(3))
+	assert.Equal(t, 50*time.Millisecond, p.backoff(4))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		delay := p.backoff(2)
+		assert.GreaterOrEqual(t, delay, 10*time.Millisecond)
+		assert.LessOrEqual(t, delay, 20*time.Millisecond)
+	}
+	// Without MaxBackoff, the delay keeps doubling.
+	p = RetryPolicy{InitialBackoff: 10 * time.Millisecond}
+	assert.Equal(t, 10*time.Millisecond, p.backoff(1))
+	assert.Equal(t, 80*time.Millisecond, p.backoff(4))
+	assert.Equal(t, 10240*time.Millisecond, p.backoff(11))
+	assert.Positive(t, p.backoff(100))
+}
@@ -4,9 +4,13 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/linxGnu/grocksdb"
@@ -15,10 +19,110 @@ import (
 const BlockCacheSize = 1 << 30
 
 func init() {
-	dbCreator := func(name string, dir string) (DB, error) {
-		return NewRocksDB(name, dir)
+	registerDBCreator(RocksDBBackend, newRocksDBFromOptions, false)
+	registerBackendParams(RocksDBBackend)
+}
+
+// newRocksDBFromOptions accepts *grocksdb.Options as backend options, which are
+// used as is: the cache size and the backend params of applyRocksDBParams only
+// apply to the default configuration.
+func newRocksDBFromOptions(name, dir string, opts *Options) (DB, error) {
+	if opts.InMemory {
+		return nil, errOption(RocksDBBackend, "InMemory")
+	}
+	if opts.FS != nil {
+		return nil, errOption(RocksDBBackend, "FS")
+	}
+	if opts.SharedCache != nil {
+		return nil, errOption(RocksDBBackend, "SharedCache")
+	}
+	ropts, ok := opts.BackendOptions.(*grocksdb.Options)
+	if opts.BackendOptions != nil && !ok {
+		return nil, errBackendOptions(RocksDBBackend, opts.BackendOptions)
+	}
+	// The params only apply to the default configuration, and are unknown with
+	// backend options.
+	params := newBackendParams(opts.BackendParams)
+	if ropts == nil {
+		cfg := DefaultRocksDBConfig()
+		applyRocksDBParams(&cfg, params)
+		if err := params.err(); err != nil {
+			return nil, err
+		}
+		if opts.CacheSize > 0 {
+			cfg.BlockCacheSize = uint64(opts.CacheSize)
+		}
+		switch {
+		case opts.BloomFilterBits > 0:
+			cfg.FilterBitsPerKey = float64(opts.BloomFilterBits)
+		case opts.BloomFilterBits < 0:
+			cfg.FilterBitsPerKey = 0
+		}
+		latest, err := loadLatestOptions(filepath.Join(dir, name+".db"), cfg.BlockCacheSize)
+		if err != nil {
+			return nil, err
+		}
+		ropts = NewRocksdbOptionsWithConfig(latest, cfg)
+	} else if err := params.err(); err != nil {
+		return nil, err
+	}
+	if opts.WriteBufferSize > 0 {
+		ropts.SetWriteBufferSize(uint64(opts.WriteBufferSize))
+	}
+	if opts.MergeOperator != nil {
+		ropts.SetMergeOperator(rocksDBMergeOperator{op: opts.MergeOperator})
+	}
+	db, err := newRocksDB(name, dir, ropts, opts.ReadOnly, opts.SyncWrites)
+	if errors.Is(err, ErrCorrupted) && opts.Recover && !opts.ReadOnly {
+		cause := err
+		if err = grocksdb.RepairDb(filepath.Join(dir, name+".db"), ropts); err != nil {
+			return nil, fmt.Errorf("failed to recover from %v: %w", cause, rocksDBError(err))
+		}
+		if db, err = newRocksDB(name, dir, ropts, false, opts.SyncWrites); err == nil {
+			reportRecovery(name, opts, RecoveryInfo{Err: cause})
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	db.iters = newIteratorTracker(name, opts)
+	db.lock = opts.lock
+	if opts.OnWriteStall != nil {
+		db.stalls = watchWriteStalls(db.writeStalled, opts.OnWriteStall)
+	}
+	return db, nil
+}
+
+// applyRocksDBParams applies the backend params of RocksDB to cfg: the block
+// cache size (cache) and the filter bits per key (filter_bits).
+func applyRocksDBParams(cfg *RocksDBConfig, params *backendParams) {
+	if v, ok := params.size("cache"); ok {
+		cfg.BlockCacheSize = uint64(v)
 	}
-	registerDBCreator(RocksDBBackend, dbCreator, false)
+	if v, ok := params.string("filter_bits"); ok {
+		bits, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			params.fail("filter_bits", v, err)
+		} else {
+			cfg.FilterBitsPerKey = bits
+		}
+	}
+}
+
+// rocksDBError wraps the RocksDB errors with the shared error kinds. RocksDB
+// only returns status messages, so they are matched by their prefix.
+func rocksDBError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case strings.HasPrefix(err.Error(), "Corruption:"):
+		return wrapError(ErrCorrupted, err)
+	case strings.Contains(err.Error(), "read only"):
+		return wrapError(ErrReadOnly, err)
+	case strings.HasPrefix(err.Error(), "Not implemented:"):
+		return wrapError(ErrNotSupported, err)
+	}
+	return err
 }
 
 // RocksDB is a RocksDB backend.
@@ -27,28 +131,77 @@ type RocksDB struct {
 	ro     *grocksdb.ReadOptions
 	wo     *grocksdb.WriteOptions
 	woSync *grocksdb.WriteOptions
+	iters  *iteratorTracker
+	lock   *dbLock
+	stalls *writeStallWatcher
+	ops    *opTracker
 }
 
 var _ DB = (*RocksDB)(nil)
 
+// errRocksDBClosed is returned by the operations on a closed RocksDB, which
+// would otherwise use the memory freed by Close.
+var errRocksDBClosed = fmt.Errorf("rocksdb: %w", ErrClosed)
+
+// RocksDBConfig holds the tunables applied on top of the RocksDB options by
+// NewRocksDBWithConfig. Only the default column family is used, so there is no
+// column family configuration.
+type RocksDBConfig struct {
+	// BlockCacheSize is the capacity of the shared LRU block cache, in bytes.
+	BlockCacheSize uint64
+	// FilterBitsPerKey is the bloom-equivalent bits per key of the filter
+	// policy. Zero disables filters.
+	FilterBitsPerKey float64
+}
+
+// DefaultRocksDBConfig returns the configuration used by NewRocksDB.
+func DefaultRocksDBConfig() RocksDBConfig {
+	return RocksDBConfig{
+		BlockCacheSize:   BlockCacheSize,
+		FilterBitsPerKey: 9.9,
+	}
+}
+
+// NewRocksDB creates a RocksDB with the default configuration.
 func NewRocksDB(name string, dir string) (*RocksDB, error) {
-	opts, err := loadLatestOptions(dir)
+	return NewRocksDBWithConfig(name, dir, DefaultRocksDBConfig())
+}
+
+// NewRocksDBWithConfig creates a RocksDB, reusing the options persisted by an
+// existing database (if any) and applying cfg on top of them.
+func NewRocksDBWithConfig(name string, dir string, cfg RocksDBConfig) (*RocksDB, error) {
+	opts, err := loadLatestOptions(filepath.Join(dir, name+".db"), cfg.BlockCacheSize)
 	if err != nil {
 		return nil, err
 	}
 	// customize rocksdb options
-	opts = NewRocksdbOptions(opts)
+	opts = NewRocksdbOptionsWithConfig(opts, cfg)
 	return NewRocksDBWithOptions(name, dir, opts)
 }
 
 func NewRocksDBWithOptions(name string, dir string, opts *grocksdb.Options) (*RocksDB, error) {
+	return newRocksDB(name, dir, opts, false, false)
+}
+
+// newRocksDB opens the database, read-only if readOnly, syncing all writes if
+// syncWrites.
+func newRocksDB(name string, dir string, opts *grocksdb.Options, readOnly, syncWrites bool) (*RocksDB, error) {
 	dbPath := filepath.Join(dir, name+".db")
-	db, err := grocksdb.OpenDb(opts, dbPath)
+	var (
+		db  *grocksdb.DB
+		err error
+	)
+	if readOnly {
+		db, err = grocksdb.OpenDbForReadOnly(opts, dbPath, false)
+	} else {
+		db, err = grocksdb.OpenDb(opts, dbPath)
+	}
 	if err != nil {
-		return nil, err
+		return nil, rocksDBError(err)
 	}
 	ro := grocksdb.NewDefaultReadOptions()
 	wo := grocksdb.NewDefaultWriteOptions()
+	wo.SetSync(syncWrites)
 	woSync := grocksdb.NewDefaultWriteOptions()
 	woSync.SetSync(true)
 	return NewRocksDBWithRawDB(db, ro, wo, woSync), nil
@@ -60,21 +213,93 @@ func NewRocksDBWithRawDB(db *grocksdb.DB, ro *grocksdb.ReadOptions, wo *grocksdb
 		ro:     ro,
 		wo:     wo,
 		woSync: woSync,
+		ops:    newOpTracker(),
 	}
 }
 
+// readOptions returns the RocksDB read options for opts, and the function
+// releasing them. Non-default options are created from the RocksDB defaults,
+// rather than from the read options given to NewRocksDBWithRawDB. Checksums
+// are verified by default.
+func (db *RocksDB) readOptions(opts ReadOptions) (*grocksdb.ReadOptions, func()) {
+	if opts == (ReadOptions{}) {
+		return db.ro, func() {}
+	}
+	ro := grocksdb.NewDefaultReadOptions()
+	ro.SetFillCache(!opts.DontFillCache)
+	return ro, ro.Destroy
+}
+
 // Get implements DB.
 func (db *RocksDB) Get(key []byte) ([]byte, error) {
+	return db.GetWithOptions(key, ReadOptions{})
+}
+
+// GetWithOptions implements ReadOptionsDB.
+func (db *RocksDB) GetWithOptions(key []byte, opts ReadOptions) ([]byte, error) {
 	if len(key) == 0 {
 		return nil, errKeyEmpty
 	}
-	res, err := db.db.Get(db.ro, key)
+	if !db.ops.begin() {
+		return nil, errRocksDBClosed
+	}
+	defer db.ops.end()
+	ro, release := db.readOptions(opts)
+	defer release()
+	res, err := db.db.Get(ro, key)
 	if err != nil {
-		return nil, err
+		return nil, rocksDBError(err)
 	}
 	return moveSliceToBytes(res), nil
 }
 
+// GetUnsafe implements UnsafeGetter, passing the value in memory allocated by
+// RocksDB, which is freed once fn returns.
+func (db *RocksDB) GetUnsafe(key []byte, fn func(value []byte) error) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
+	res, err := db.db.Get(db.ro, key)
+	if err != nil {
+		return rocksDBError(err)
+	}
+	defer res.Free()
+	if !res.Exists() {
+		return fn(nil)
+	}
+	value := res.Data()
+	if value == nil {
+		value = []byte{}
+	}
+	return fn(value)
+}
+
+// MultiGet implements MultiGetter, using the native RocksDB MultiGet.
+func (db *RocksDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+	}
+	if !db.ops.begin() {
+		return nil, errRocksDBClosed
+	}
+	defer db.ops.end()
+	slices, err := db.db.MultiGet(db.ro, keys...)
+	if err != nil {
+		return nil, rocksDBError(err)
+	}
+	values := make([][]byte, len(slices))
+	for i, s := range slices {
+		values[i] = moveSliceToBytes(s)
+	}
+	return values, nil
+}
+
 // Has implements DB.
 func (db *RocksDB) Has(key []byte) (bool, error) {
 	bytes, err := db.Get(key)
@@ -92,9 +317,13 @@ func (db *RocksDB) Set(key []byte, value []byte) error {
 	if value == nil {
 		return errValueNil
 	}
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
 	err := db.db.Put(db.wo, key, value)
 	if err != nil {
-		return err
+		return rocksDBError(err)
 	}
 	return nil
 }
@@ -107,9 +336,13 @@ func (db *RocksDB) SetSync(key []byte, value []byte) error {
 	if value == nil {
 		return errValueNil
 	}
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
 	err := db.db.Put(db.woSync, key, value)
 	if err != nil {
-		return err
+		return rocksDBError(err)
 	}
 	return nil
 }
@@ -119,9 +352,13 @@ func (db *RocksDB) Delete(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
 	err := db.db.Delete(db.wo, key)
 	if err != nil {
-		return err
+		return rocksDBError(err)
 	}
 	return nil
 }
@@ -131,9 +368,13 @@ func (db *RocksDB) DeleteSync(key []byte) error {
 	if len(key) == 0 {
 		return errKeyEmpty
 	}
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
 	err := db.db.Delete(db.woSync, key)
 	if err != nil {
-		return nil
+		return rocksDBError(err)
 	}
 	return nil
 }
@@ -142,8 +383,15 @@ func (db *RocksDB) DB() *grocksdb.DB {
 	return db.db
 }
 
-// Close implements DB.
+// Close implements DB. It waits for the operations in flight, such as batch
+// writes, after which the operations fail with ErrClosed.
 func (db *RocksDB) Close() error {
+	defer db.lock.release()
+	db.stalls.stop()
+	if !db.ops.close() {
+		return errRocksDBClosed
+	}
+	db.iters.close()
 	db.ro.Destroy()
 	db.wo.Destroy()
 	db.woSync.Destroy()
@@ -168,6 +416,10 @@ func (db *RocksDB) Print() error {
 
 // Stats implements DB.
 func (db *RocksDB) Stats() map[string]string {
+	if !db.ops.begin() {
+		return map[string]string{}
+	}
+	defer db.ops.end()
 	keys := []string{"rocksdb.stats"}
 	stats := make(map[string]string, len(keys))
 	for _, key := range keys {
@@ -176,6 +428,263 @@ func (db *RocksDB) Stats() map[string]string {
 	return stats
 }
 
+// DeleteRange implements RangeDeleter.
+func (db *RocksDB) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	b := newRocksDBBatch(db)
+	defer b.Close()
+	if err := b.DeleteRange(start, end); err != nil {
+		return err
+	}
+	return b.Write()
+}
+
+// lastKey returns a copy of the last key in the database, or nil if it is
+// empty.
+func (db *RocksDB) lastKey() []byte {
+	itr := db.db.NewIterator(db.ro)
+	defer itr.Close()
+	itr.SeekToLast()
+	if !itr.Valid() {
+		return nil
+	}
+	return moveSliceToBytes(itr.Key())
+}
+
+// EstimateSize implements SizeEstimator, using the RocksDB approximate sizes.
+// Data still in the memtables is not accounted for.
+func (db *RocksDB) EstimateSize(start, end []byte) (uint64, error) {
+	if !db.ops.begin() {
+		return 0, errRocksDBClosed
+	}
+	defer db.ops.end()
+	if end == nil {
+		// RocksDB needs an upper bound: use the key right after the last one.
+		end = rangeUpperBound(db.lastKey())
+		if end == nil {
+			// Empty database
+			return 0, nil
+		}
+	}
+	sizes, err := db.db.GetApproximateSizes([]grocksdb.Range{{Start: start, Limit: end}})
+	if err != nil {
+		return 0, err
+	}
+	return sizes[0], nil
+}
+
+// EstimateCount implements CountEstimator, using the estimate-num-keys property.
+func (db *RocksDB) EstimateCount() (uint64, error) {
+	if !db.ops.begin() {
+		return 0, errRocksDBClosed
+	}
+	defer db.ops.end()
+	return strconv.ParseUint(db.db.GetProperty("rocksdb.estimate-num-keys"), 10, 64)
+}
+
+// Flush implements Flusher, waiting for the memtable to be flushed.
+func (db *RocksDB) Flush() error {
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
+	opts := grocksdb.NewDefaultFlushOptions()
+	defer opts.Destroy()
+	opts.SetWait(true)
+	return rocksDBError(db.db.Flush(opts))
+}
+
+// Sync implements Syncer, writing out and syncing the WAL.
+func (db *RocksDB) Sync() error {
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
+	return rocksDBError(db.db.FlushWAL(true))
+}
+
+// Backup implements Backupper with the RocksDB backup engine. The backup is
+// taken in a temporary directory within targetDir after flushing the
+// memtables, and restored from there into the copy, so that the copy can be
+// opened directly. The context is only checked between the two steps.
+func (db *RocksDB) Backup(ctx context.Context, targetDir string) error {
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
+	dbPath := filepath.Join(targetDir, filepath.Base(db.db.Name()))
+	if _, err := os.Stat(dbPath); !errors.Is(err, os.ErrNotExist) {
+		if err == nil {
+			err = fmt.Errorf("backup %s already exists", dbPath)
+		}
+		return err
+	}
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		return err
+	}
+	backupDir, err := os.MkdirTemp(targetDir, "rocksdb-backup-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(backupDir)
+
+	be, err := grocksdb.CreateBackupEngineWithPath(db.db, backupDir)
+	if err != nil {
+		return rocksDBError(err)
+	}
+	defer be.Close()
+	if err := be.CreateNewBackupFlush(true); err != nil {
+		return rocksDBError(err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	ro := grocksdb.NewRestoreOptions()
+	defer ro.Destroy()
+	return rocksDBError(be.RestoreDBFromLatestBackup(dbPath, dbPath, ro))
+}
+
+// Metrics implements MetricsReporter, from the live files and the properties of
+// the database. The disk size excludes the WAL. The block cache hits and misses and the compaction metrics
+// require RocksDB statistics, which are not enabled, and are left zero.
+func (db *RocksDB) Metrics() (Metrics, error) {
+	if !db.ops.begin() {
+		return Metrics{}, errRocksDBClosed
+	}
+	defer db.ops.end()
+	var m Metrics
+	for _, file := range db.db.GetLiveFilesMetaData() {
+		for len(m.Levels) <= file.Level {
+			m.Levels = append(m.Levels, LevelMetrics{})
+		}
+		m.Levels[file.Level].Files++
+		m.Levels[file.Level].Size += uint64(file.Size)
+		m.DiskSize += uint64(file.Size)
+	}
+	m.MemTableSize, _ = db.db.GetIntProperty("rocksdb.cur-size-all-mem-tables")
+	m.BlockCache.Size, _ = db.db.GetIntProperty("rocksdb.block-cache-usage")
+	m.Compaction.PendingBytes, _ = db.db.GetIntProperty("rocksdb.estimate-pending-compaction-bytes")
+	running, _ := db.db.GetIntProperty("rocksdb.num-running-compactions")
+	m.Compaction.Running = int64(running)
+	snapshots, _ := db.db.GetIntProperty("rocksdb.num-snapshots")
+	m.Snapshots = int64(snapshots)
+	m.WriteStalled, _ = db.writeStalled()
+	return m, nil
+}
+
+// DiskUsage implements DiskUsageReporter, with the live sstables of RocksDB.
+func (db *RocksDB) DiskUsage() (DiskUsageReport, error) {
+	if !db.ops.begin() {
+		return DiskUsageReport{}, errRocksDBClosed
+	}
+	defer db.ops.end()
+	live := make(map[uint64]struct{})
+	for _, file := range db.db.GetLiveFilesMetaData() {
+		name := strings.TrimPrefix(file.Name, "/")
+		if num, err := strconv.ParseUint(strings.TrimSuffix(name, ".sst"), 10, 64); err == nil {
+			live[num] = struct{}{}
+		}
+	}
+	return diskUsage(db.db.Name(), live)
+}
+
+// Checkpoint implements Checkpointer with a RocksDB checkpoint. The memtables
+// are flushed first, so that the checkpoint only consists of sstables, which
+// are hard linked when path is on the same filesystem, and of copies of the
+// small metadata files.
+func (db *RocksDB) Checkpoint(path string) error {
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
+	cp, err := db.db.NewCheckpoint()
+	if err != nil {
+		return rocksDBError(err)
+	}
+	defer cp.Destroy()
+	return rocksDBError(cp.CreateCheckpoint(path, 0))
+}
+
+// Merge implements Merger, with the merge operator of the RocksDB options, e.g.
+// the MergeOperator given to NewDB. It returns an error wrapping
+// ErrNotSupported if there is none.
+func (db *RocksDB) Merge(key, operand []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if operand == nil {
+		return errValueNil
+	}
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
+	return rocksDBError(db.db.Merge(db.wo, key, operand))
+}
+
+// rocksDBMergeOperator is the RocksDB merge operator of a MergeOperator.
+type rocksDBMergeOperator struct {
+	op MergeOperator
+}
+
+func (m rocksDBMergeOperator) Name() string {
+	return m.op.Name()
+}
+
+// FullMerge implements grocksdb.MergeOperator.
+func (m rocksDBMergeOperator) FullMerge(key, existingValue []byte, operands [][]byte) ([]byte, bool) {
+	value := existingValue
+	for _, operand := range operands {
+		if value == nil {
+			value = operand
+			continue
+		}
+		var err error
+		if value, err = m.op.Merge(key, value, operand); err != nil {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// PartialMerge implements grocksdb.PartialMerger.
+func (m rocksDBMergeOperator) PartialMerge(key, leftOperand, rightOperand []byte) ([]byte, bool) {
+	value, err := m.op.Merge(key, leftOperand, rightOperand)
+	return value, err == nil
+}
+
+// HealthCheck implements HealthChecker. RocksDB stops writes while there are too
+// many memtables or level 0 files, and after a background error, in which case
+// writes fail and HealthCheck detects it.
+func (db *RocksDB) HealthCheck(context.Context) error {
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
+	if stalled, _ := db.writeStalled(); stalled {
+		return ErrWriteStalled
+	}
+	return nil
+}
+
+// writeStalled reports whether RocksDB stops writes.
+func (db *RocksDB) writeStalled() (bool, string) {
+	stopped, _ := db.db.GetIntProperty("rocksdb.is-write-stopped")
+	return stopped != 0, "writes stopped"
+}
+
+// Compact implements Compacter.
+func (db *RocksDB) Compact(start, end []byte) error {
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
+	db.db.CompactRange(grocksdb.Range{Start: start, Limit: end})
+	return nil
+}
+
 // NewBatch implements DB.
 func (db *RocksDB) NewBatch() Batch {
 	return newRocksDBBatch(db)
@@ -183,25 +692,38 @@ func (db *RocksDB) NewBatch() Batch {
 
 // Iterator implements DB.
 func (db *RocksDB) Iterator(start, end []byte) (Iterator, error) {
-	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
-		return nil, errKeyEmpty
-	}
-	itr := db.db.NewIterator(db.ro)
-	return newRocksDBIterator(itr, start, end, false), nil
+	return db.IteratorWithOptions(start, end, IteratorOptions{})
 }
 
 // ReverseIterator implements DB.
 func (db *RocksDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return db.IteratorWithOptions(start, end, IteratorOptions{Reverse: true})
+}
+
+// IteratorWithOptions implements IteratorOptionsDB, applying the ReadOptions.
+// KeysOnly iterators still read the values.
+func (db *RocksDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
 	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
 		return nil, errKeyEmpty
 	}
-	itr := db.db.NewIterator(db.ro)
-	return newRocksDBIterator(itr, start, end, true), nil
+	if !db.ops.begin() {
+		return nil, errRocksDBClosed
+	}
+	defer db.ops.end()
+	// The read options are copied by the iterator.
+	ro, release := db.readOptions(opts.ReadOptions)
+	defer release()
+	source := db.db.NewIterator(ro)
+	itr := Iterator(newRocksDBIterator(source, start, end, opts.Reverse))
+	if opts.KeysOnly {
+		itr = newKeysOnlyIterator(itr)
+	}
+	return db.iters.track(itr, nil)
 }
 
 // loadLatestOptions try to load options from existing db, returns nil if not exists.
-func loadLatestOptions(dir string) (*grocksdb.Options, error) {
-	opts, err := grocksdb.LoadLatestOptions(dir, grocksdb.NewDefaultEnv(), true, grocksdb.NewLRUCache(BlockCacheSize))
+func loadLatestOptions(dbPath string, blockCacheSize uint64) (*grocksdb.Options, error) {
+	opts, err := grocksdb.LoadLatestOptions(dbPath, grocksdb.NewDefaultEnv(), true, grocksdb.NewLRUCache(blockCacheSize))
 	if err != nil {
 		// not found is not an error
 		if strings.HasPrefix(err.Error(), "NotFound: ") {
@@ -225,6 +747,12 @@ func loadLatestOptions(dir string) (*grocksdb.Options, error) {
 // NewRocksdbOptions build options for `application.db`,
 // it overrides existing options if provided, otherwise create new one assuming it's a new database.
 func NewRocksdbOptions(opts *grocksdb.Options) *grocksdb.Options {
+	return NewRocksdbOptionsWithConfig(opts, DefaultRocksDBConfig())
+}
+
+// NewRocksdbOptionsWithConfig is like NewRocksdbOptions, but sizes the block cache
+// and the filter policy according to cfg.
+func NewRocksdbOptionsWithConfig(opts *grocksdb.Options, cfg RocksDBConfig) *grocksdb.Options {
 	if opts == nil {
 		opts = grocksdb.NewDefaultOptions()
 		// only enable dynamic-level-bytes on new db, don't override for existing db
@@ -238,11 +766,13 @@ func NewRocksdbOptions(opts *grocksdb.Options) *grocksdb.Options {
 	// block based table options
 	bbto := grocksdb.NewDefaultBlockBasedTableOptions()
 
-	// 1G block cache
-	bbto.SetBlockCache(grocksdb.NewLRUCache(BlockCacheSize))
+	// 1G block cache by default
+	bbto.SetBlockCache(grocksdb.NewLRUCache(cfg.BlockCacheSize))
 
 	// http://rocksdb.org/blog/2021/12/29/ribbon-filter.html
-	bbto.SetFilterPolicy(grocksdb.NewRibbonHybridFilterPolicy(9.9, 1))
+	if cfg.FilterBitsPerKey > 0 {
+		bbto.SetFilterPolicy(grocksdb.NewRibbonHybridFilterPolicy(cfg.FilterBitsPerKey, 1))
+	}
 
 	// partition index
 	// http://rocksdb.org/blog/2017/05/12/partitioned-index-filter.html
@@ -3,14 +3,25 @@
 
 package db
 
-import "github.com/linxGnu/grocksdb"
+import (
+	"bytes"
+
+	"github.com/linxGnu/grocksdb"
+)
 
 type rocksDBBatch struct {
 	db    *RocksDB
 	batch *grocksdb.WriteBatch
+	size  int
+	// maxKey is the largest key set so far, used to bound DeleteRange calls
+	// with a nil end.
+	maxKey []byte
 }
 
-var _ Batch = (*rocksDBBatch)(nil)
+var (
+	_ Batch         = (*rocksDBBatch)(nil)
+	_ BatchResetter = (*rocksDBBatch)(nil)
+)
 
 func newRocksDBBatch(db *RocksDB) *rocksDBBatch {
 	return &rocksDBBatch{
@@ -28,9 +39,13 @@ func (b *rocksDBBatch) Set(key, value []byte) error {
 		return errValueNil
 	}
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.batch.Put(key, value)
+	b.size += len(key) + len(value)
+	if bytes.Compare(key, b.maxKey) > 0 {
+		b.maxKey = cp(key)
+	}
 	return nil
 }
 
@@ -40,44 +55,106 @@ func (b *rocksDBBatch) Delete(key []byte) error {
 		return errKeyEmpty
 	}
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
 	}
 	b.batch.Delete(key)
+	b.size += len(key)
+	return nil
+}
+
+// DeleteRange implements RangeDeleter.
+func (b *rocksDBBatch) DeleteRange(start, end []byte) error {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return errKeyEmpty
+	}
+	if b.batch == nil {
+		return ErrBatchWritten
+	}
+	if end == nil {
+		// RocksDB needs an upper bound: use the key right after the last one,
+		// either in the database or set earlier in this batch.
+		end = rangeUpperBound(maxKey(b.db.lastKey(), b.maxKey))
+		if end == nil {
+			// Nothing to delete
+			return nil
+		}
+	}
+	if bytes.Compare(start, end) >= 0 {
+		return nil
+	}
+	b.batch.DeleteRange(start, end)
+	b.size += len(start) + len(end)
 	return nil
 }
 
+// Count implements Batch.
+func (b *rocksDBBatch) Count() int {
+	if b.batch == nil {
+		return 0
+	}
+	return b.batch.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *rocksDBBatch) SizeBytes() int {
+	if b.batch == nil {
+		return 0
+	}
+	return b.size
+}
+
 // Write implements Batch.
 func (b *rocksDBBatch) Write() error {
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
+	}
+	if !b.db.ops.begin() {
+		return errRocksDBClosed
 	}
+	defer b.db.ops.end()
 	err := b.db.db.Write(b.db.wo, b.batch)
 	if err != nil {
-		return err
+		return rocksDBError(err)
 	}
 	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
-	b.Close()
-	return nil
+	return b.Close()
 }
 
 // WriteSync implements Batch.
 func (b *rocksDBBatch) WriteSync() error {
 	if b.batch == nil {
-		return errBatchClosed
+		return ErrBatchWritten
+	}
+	if !b.db.ops.begin() {
+		return errRocksDBClosed
 	}
+	defer b.db.ops.end()
 	err := b.db.db.Write(b.db.woSync, b.batch)
 	if err != nil {
-		return err
+		return rocksDBError(err)
 	}
 	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
 	return b.Close()
 }
 
+// Reset implements BatchResetter.
+func (b *rocksDBBatch) Reset() error {
+	if b.batch == nil {
+		b.batch = grocksdb.NewWriteBatch()
+	} else {
+		b.batch.Clear()
+	}
+	b.size = 0
+	b.maxKey = nil
+	return nil
+}
+
 // Close implements Batch.
 func (b *rocksDBBatch) Close() error {
 	if b.batch != nil {
 		b.batch.Destroy()
 		b.batch = nil
+		b.size = 0
 	}
 	return nil
 }
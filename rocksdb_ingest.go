@@ -0,0 +1,94 @@
+//go:build rocksdb
+// +build rocksdb
+
+package db
+
+import "github.com/linxGnu/grocksdb"
+
+var _ Ingester = (*RocksDB)(nil)
+
+// NewSSTWriter implements Ingester. The file is written with the default
+// RocksDB options, rather than the table options of the database, which
+// RocksDB does not need to ingest it.
+func (db *RocksDB) NewSSTWriter(path string) (SSTWriter, error) {
+	envOpts := grocksdb.NewDefaultEnvOptions()
+	defer envOpts.Destroy()
+	opts := grocksdb.NewDefaultOptions()
+	defer opts.Destroy()
+	w := grocksdb.NewSSTFileWriter(envOpts, opts)
+	if err := w.Open(path); err != nil {
+		w.Destroy()
+		return nil, rocksDBError(err)
+	}
+	return &rocksDBSSTWriter{w: w}, nil
+}
+
+// IngestExternalFiles implements Ingester. The files are moved into the
+// database, like pebble does.
+func (db *RocksDB) IngestExternalFiles(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	if !db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer db.ops.end()
+	opts := grocksdb.NewDefaultIngestExternalFileOptions()
+	defer opts.Destroy()
+	opts.SetMoveFiles(true)
+	return rocksDBError(db.db.IngestExternalFile(paths, opts))
+}
+
+type rocksDBSSTWriter struct {
+	w    *grocksdb.SSTFileWriter
+	done bool
+}
+
+var _ SSTWriter = (*rocksDBSSTWriter)(nil)
+
+// Set implements SSTWriter.
+func (w *rocksDBSSTWriter) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if w.done {
+		return errSSTWriterDone
+	}
+	return rocksDBError(w.w.Put(key, value))
+}
+
+// Delete implements SSTWriter.
+func (w *rocksDBSSTWriter) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if w.done {
+		return errSSTWriterDone
+	}
+	return rocksDBError(w.w.Delete(key))
+}
+
+// Finish implements SSTWriter.
+func (w *rocksDBSSTWriter) Finish() error {
+	if w.done {
+		return errSSTWriterDone
+	}
+	w.done = true
+	err := w.w.Finish()
+	w.w.Destroy()
+	w.w = nil
+	return rocksDBError(err)
+}
+
+// Close implements SSTWriter.
+func (w *rocksDBSSTWriter) Close() error {
+	w.done = true
+	if w.w != nil {
+		w.w.Destroy()
+		w.w = nil
+	}
+	return nil
+}
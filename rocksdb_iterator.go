@@ -107,7 +107,7 @@ func (itr *rocksDBIterator) Value() []byte {
 }
 
 // Next implements Iterator.
-func (itr rocksDBIterator) Next() {
+func (itr *rocksDBIterator) Next() {
 	itr.assertIsValid()
 	if itr.isReverse {
 		itr.source.Prev()
@@ -118,7 +118,7 @@ func (itr rocksDBIterator) Next() {
 
 // Error implements Iterator.
 func (itr *rocksDBIterator) Error() error {
-	return itr.source.Err()
+	return rocksDBError(itr.source.Err())
 }
 
 // Close implements Iterator.
@@ -0,0 +1,89 @@
+//go:build rocksdb
+// +build rocksdb
+
+package db
+
+import "github.com/linxGnu/grocksdb"
+
+type rocksDBSnapshot struct {
+	db   *RocksDB
+	snap *grocksdb.Snapshot
+	ro   *grocksdb.ReadOptions
+}
+
+var _ DBReader = (*rocksDBSnapshot)(nil)
+
+// Snapshot implements Snapshotter.
+func (db *RocksDB) Snapshot() (DBReader, error) {
+	if !db.ops.begin() {
+		return nil, errRocksDBClosed
+	}
+	defer db.ops.end()
+	snap := db.db.NewSnapshot()
+	ro := grocksdb.NewDefaultReadOptions()
+	ro.SetSnapshot(snap)
+	return &rocksDBSnapshot{db: db, snap: snap, ro: ro}, nil
+}
+
+// Get implements DBReader.
+func (s *rocksDBSnapshot) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	if !s.db.ops.begin() {
+		return nil, errRocksDBClosed
+	}
+	defer s.db.ops.end()
+	res, err := s.db.db.Get(s.ro, key)
+	if err != nil {
+		return nil, rocksDBError(err)
+	}
+	return moveSliceToBytes(res), nil
+}
+
+// Has implements DBReader.
+func (s *rocksDBSnapshot) Has(key []byte) (bool, error) {
+	bytes, err := s.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return bytes != nil, nil
+}
+
+// Iterator implements DBReader.
+func (s *rocksDBSnapshot) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if !s.db.ops.begin() {
+		return nil, errRocksDBClosed
+	}
+	defer s.db.ops.end()
+	itr := s.db.db.NewIterator(s.ro)
+	return s.db.iters.track(newRocksDBIterator(itr, start, end, false), nil)
+}
+
+// ReverseIterator implements DBReader.
+func (s *rocksDBSnapshot) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if !s.db.ops.begin() {
+		return nil, errRocksDBClosed
+	}
+	defer s.db.ops.end()
+	itr := s.db.db.NewIterator(s.ro)
+	return s.db.iters.track(newRocksDBIterator(itr, start, end, true), nil)
+}
+
+// Close implements DBReader.
+func (s *rocksDBSnapshot) Close() error {
+	s.ro.Destroy()
+	// The snapshots are released along with a closed database.
+	if !s.db.ops.begin() {
+		return errRocksDBClosed
+	}
+	defer s.db.ops.end()
+	s.db.db.ReleaseSnapshot(s.snap)
+	return nil
+}
@@ -33,4 +33,19 @@ func TestRocksDBStats(t *testing.T) {
 	assert.NotEmpty(t, db.Stats())
 }
 
-// TODO: Add tests for rocksdb
+func TestRocksDBWithConfig(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	cfg := DefaultRocksDBConfig()
+	cfg.BlockCacheSize = 8 << 20
+	cfg.FilterBitsPerKey = 0
+	db, err := NewRocksDBWithConfig(name, dir, cfg)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+	defer db.Close()
+
+	require.NoError(t, db.Set([]byte("a"), []byte{1}))
+	value, err := db.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1}, value)
+}
@@ -0,0 +1,355 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ShardFunc maps a key to the index of the shard holding it, in [0, n).
+type ShardFunc func(key []byte, n int) int
+
+// HashSharding spreads keys evenly over the shards using the FNV-1a hash of the
+// key. Iteration has to merge every shard.
+func HashSharding(key []byte, n int) int {
+	h := fnv.New32a()
+	h.Write(key) //nolint:errcheck // never fails
+	return int(h.Sum32() % uint32(n))
+}
+
+// RangeSharding partitions the key space at the given sorted split keys: shard 0
+// holds the keys below splits[0], shard i the keys in [splits[i-1], splits[i])
+// and the last shard the keys from the last split onwards. It should be used
+// with len(splits)+1 shards; keys past the last shard go to the last shard.
+func RangeSharding(splits ...[]byte) ShardFunc {
+	splits = append([][]byte{}, splits...)
+	if !sort.SliceIsSorted(splits, func(i, j int) bool { return string(splits[i]) < string(splits[j]) }) {
+		panic("split keys must be sorted")
+	}
+	return func(key []byte, n int) int {
+		i := sort.Search(len(splits), func(i int) bool { return string(key) < string(splits[i]) })
+		if i >= n {
+			i = n - 1
+		}
+		return i
+	}
+}
+
+// ShardedDB partitions keys across several databases, e.g. living on different
+// disks, while exposing them as a single ordered database. Iterators merge the
+// iterators of all shards.
+//
+// Every key belongs to exactly one shard, so the sharding function and the
+// order of the shards must not change once data has been written. Batches are
+// only atomic within a shard: a failed Write may have been applied on some
+// shards but not others.
+type ShardedDB struct {
+	shards  []DB
+	shardFn ShardFunc
+}
+
+var _ DB = (*ShardedDB)(nil)
+
+// NewShardedDB creates a ShardedDB over shards, routing keys with shardFn (e.g.
+// HashSharding or RangeSharding). Closing the ShardedDB closes the shards.
+func NewShardedDB(shards []DB, shardFn ShardFunc) (*ShardedDB, error) {
+	if len(shards) == 0 {
+		return nil, errors.New("sharded db needs at least one shard")
+	}
+	if shardFn == nil {
+		return nil, errors.New("sharded db needs a shard function")
+	}
+	return &ShardedDB{
+		shards:  append([]DB{}, shards...),
+		shardFn: shardFn,
+	}, nil
+}
+
+// shard returns the shard holding key.
+func (sdb *ShardedDB) shard(key []byte) DB {
+	return sdb.shards[sdb.shardIndex(key)]
+}
+
+func (sdb *ShardedDB) shardIndex(key []byte) int {
+	i := sdb.shardFn(key, len(sdb.shards))
+	if i < 0 || i >= len(sdb.shards) {
+		panic(fmt.Sprintf("shard function returned shard %d out of %d", i, len(sdb.shards)))
+	}
+	return i
+}
+
+// Shards returns the underlying databases.
+func (sdb *ShardedDB) Shards() []DB {
+	return append([]DB{}, sdb.shards...)
+}
+
+// Get implements DB.
+func (sdb *ShardedDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	return sdb.shard(key).Get(key)
+}
+
+// MultiGet implements MultiGetter, issuing one MultiGet per shard concurrently.
+func (sdb *ShardedDB) MultiGet(keys [][]byte) ([][]byte, error) {
+	type shardKeys struct {
+		keys [][]byte
+		idx  []int // positions of keys in the request
+	}
+	groups := make([]shardKeys, len(sdb.shards))
+	for i, key := range keys {
+		if len(key) == 0 {
+			return nil, errKeyEmpty
+		}
+		s := sdb.shardIndex(key)
+		groups[s].keys = append(groups[s].keys, key)
+		groups[s].idx = append(groups[s].idx, i)
+	}
+
+	values := make([][]byte, len(keys))
+	errs := make([]error, len(sdb.shards))
+	var wg sync.WaitGroup
+	for s, g := range groups {
+		if len(g.keys) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(s int, g shardKeys) {
+			defer wg.Done()
+			vs, err := MultiGet(sdb.shards[s], g.keys)
+			if err != nil {
+				errs[s] = err
+				return
+			}
+			for j, v := range vs {
+				values[g.idx[j]] = v
+			}
+		}(s, g)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// Has implements DB.
+func (sdb *ShardedDB) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	return sdb.shard(key).Has(key)
+}
+
+// Set implements DB.
+func (sdb *ShardedDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return sdb.shard(key).Set(key, value)
+}
+
+// SetSync implements DB.
+func (sdb *ShardedDB) SetSync(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return sdb.shard(key).SetSync(key, value)
+}
+
+// Delete implements DB.
+func (sdb *ShardedDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return sdb.shard(key).Delete(key)
+}
+
+// DeleteSync implements DB.
+func (sdb *ShardedDB) DeleteSync(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return sdb.shard(key).DeleteSync(key)
+}
+
+// Iterator implements DB.
+func (sdb *ShardedDB) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return sdb.newIterator(start, end, false)
+}
+
+// ReverseIterator implements DB.
+func (sdb *ShardedDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return sdb.newIterator(start, end, true)
+}
+
+func (sdb *ShardedDB) newIterator(start, end []byte, isReverse bool) (Iterator, error) {
+	sources := make([]Iterator, 0, len(sdb.shards))
+	for _, shard := range sdb.shards {
+		var (
+			itr Iterator
+			err error
+		)
+		if isReverse {
+			itr, err = shard.ReverseIterator(start, end)
+		} else {
+			itr, err = shard.Iterator(start, end)
+		}
+		if err != nil {
+			for _, source := range sources {
+				source.Close()
+			}
+			return nil, err
+		}
+		sources = append(sources, itr)
+	}
+	return newShardedDBIterator(sources, start, end, isReverse), nil
+}
+
+// Close implements DB. All shards are closed, the first error is returned.
+func (sdb *ShardedDB) Close() error {
+	var firstErr error
+	for _, shard := range sdb.shards {
+		if err := shard.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// NewBatch implements DB.
+func (sdb *ShardedDB) NewBatch() Batch {
+	return newShardedDBBatch(sdb)
+}
+
+// Print implements DB.
+func (sdb *ShardedDB) Print() error {
+	itr, err := sdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		key := itr.Key()
+		value := itr.Value()
+		fmt.Printf("[%X]:\t[%X]\n", key, value)
+	}
+	return itr.Error()
+}
+
+// Stats implements DB. The stats of each shard are prefixed with "shard.<i>.".
+func (sdb *ShardedDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	stats["shard.count"] = strconv.Itoa(len(sdb.shards))
+	for i, shard := range sdb.shards {
+		for k, v := range shard.Stats() {
+			stats[fmt.Sprintf("shard.%d.%s", i, k)] = v
+		}
+	}
+	return stats
+}
+
+// Metrics implements MetricsReporter, summing the metrics of the shards which
+// support it level by level.
+func (sdb *ShardedDB) Metrics() (Metrics, error) {
+	return metricsAll(sdb.shards)
+}
+
+// DeleteRange implements RangeDeleter, deleting the range on every shard. The
+// deletion is not atomic across shards.
+func (sdb *ShardedDB) DeleteRange(start, end []byte) error {
+	for _, shard := range sdb.shards {
+		if err := DeleteRange(shard, start, end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of the
+// shard of key.
+func (sdb *ShardedDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	return CompareAndSwap(sdb.shards[sdb.shardIndex(key)], key, old, new)
+}
+
+// Count implements Counter, summing the counts of the shards.
+func (sdb *ShardedDB) Count() (uint64, error) {
+	return countAll(sdb.shards)
+}
+
+// EstimateCount implements CountEstimator, summing the estimates of the shards.
+// It returns ErrNotSupported unless all shards support it.
+func (sdb *ShardedDB) EstimateCount() (uint64, error) {
+	return estimateCountAll(sdb.shards)
+}
+
+// EstimateSize implements SizeEstimator, summing the estimates of the shards
+// which support it.
+func (sdb *ShardedDB) EstimateSize(start, end []byte) (uint64, error) {
+	return estimateSizeAll(sdb.shards, start, end)
+}
+
+// Compact implements Compacter, compacting the range on every shard which
+// supports it.
+func (sdb *ShardedDB) Compact(start, end []byte) error {
+	supported := false
+	for _, shard := range sdb.shards {
+		err := Compact(shard, start, end)
+		if errors.Is(err, ErrNotSupported) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		supported = true
+	}
+	if !supported {
+		return ErrNotSupported
+	}
+	return nil
+}
+
+// Flush implements Flusher, flushing every shard. All shards must support it.
+func (sdb *ShardedDB) Flush() error {
+	return flushAll(sdb.shards)
+}
+
+// Sync implements Syncer, syncing every shard. All shards must support it.
+func (sdb *ShardedDB) Sync() error {
+	return syncAll(sdb.shards)
+}
+
+// HealthCheck implements HealthChecker, checking every shard.
+func (sdb *ShardedDB) HealthCheck(ctx context.Context) error {
+	return healthCheckAll(ctx, sdb.shards)
+}
+
+// Capabilities implements CapabilityReporter. Ranges are deleted natively,
+// and the shards flushed and synced, only if all shards support it, while
+// compaction only requires one shard to support it.
+func (sdb *ShardedDB) Capabilities() Capability {
+	return capabilitiesAll(sdb.shards)&(CapDeleteRange|CapFlush|CapSync) |
+		capabilitiesAny(sdb.shards)&CapCompact
+}
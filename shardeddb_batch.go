@@ -0,0 +1,123 @@
+package db
+
+// shardedDBBatch splits a batch into one batch per shard, created on first use.
+type shardedDBBatch struct {
+	db      *ShardedDB
+	batches []Batch
+	closed  bool
+}
+
+var _ Batch = (*shardedDBBatch)(nil)
+
+func newShardedDBBatch(db *ShardedDB) *shardedDBBatch {
+	return &shardedDBBatch{
+		db:      db,
+		batches: make([]Batch, len(db.shards)),
+	}
+}
+
+func (b *shardedDBBatch) batch(key []byte) Batch {
+	i := b.db.shardIndex(key)
+	if b.batches[i] == nil {
+		b.batches[i] = b.db.shards[i].NewBatch()
+	}
+	return b.batches[i]
+}
+
+// Set implements Batch.
+func (b *shardedDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.closed {
+		return ErrBatchWritten
+	}
+	return b.batch(key).Set(key, value)
+}
+
+// Delete implements Batch.
+func (b *shardedDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.closed {
+		return ErrBatchWritten
+	}
+	return b.batch(key).Delete(key)
+}
+
+// Count implements Batch.
+func (b *shardedDBBatch) Count() int {
+	count := 0
+	for _, batch := range b.batches {
+		if batch != nil {
+			count += batch.Count()
+		}
+	}
+	return count
+}
+
+// SizeBytes implements Batch.
+func (b *shardedDBBatch) SizeBytes() int {
+	size := 0
+	for _, batch := range b.batches {
+		if batch != nil {
+			size += batch.SizeBytes()
+		}
+	}
+	return size
+}
+
+// Write implements Batch.
+func (b *shardedDBBatch) Write() error {
+	return b.write(false)
+}
+
+// WriteSync implements Batch.
+func (b *shardedDBBatch) WriteSync() error {
+	return b.write(true)
+}
+
+// write writes the shard batches in shard order, stopping at the first error.
+func (b *shardedDBBatch) write(sync bool) error {
+	if b.closed {
+		return ErrBatchWritten
+	}
+	for _, batch := range b.batches {
+		if batch == nil {
+			continue
+		}
+		var err error
+		if sync {
+			err = batch.WriteSync()
+		} else {
+			err = batch.Write()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// Close implements Batch.
+func (b *shardedDBBatch) Close() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	var firstErr error
+	for _, batch := range b.batches {
+		if batch == nil {
+			continue
+		}
+		if err := batch.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
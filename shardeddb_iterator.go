@@ -0,0 +1,134 @@
+package db
+
+import (
+	"bytes"
+	"container/heap"
+)
+
+// shardedDBIterator merges the iterators of all shards. Keys are unique across
+// shards, so the merge is a plain k-way merge over a heap ordered by the current
+// key of each source iterator.
+type shardedDBIterator struct {
+	sources []Iterator // all sources, for Error and Close
+	heap    iteratorHeap
+	start   []byte
+	end     []byte
+
+	isInvalid bool
+}
+
+var _ Iterator = (*shardedDBIterator)(nil)
+
+func newShardedDBIterator(sources []Iterator, start, end []byte, isReverse bool) *shardedDBIterator {
+	itr := &shardedDBIterator{
+		sources: sources,
+		heap:    iteratorHeap{isReverse: isReverse},
+		start:   start,
+		end:     end,
+	}
+	for _, source := range sources {
+		if source.Valid() {
+			itr.heap.iters = append(itr.heap.iters, source)
+		}
+	}
+	heap.Init(&itr.heap)
+	return itr
+}
+
+// Domain implements Iterator.
+func (itr *shardedDBIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+// Valid implements Iterator.
+func (itr *shardedDBIterator) Valid() bool {
+	if itr.isInvalid {
+		return false
+	}
+	if itr.heap.Len() == 0 || itr.Error() != nil {
+		itr.isInvalid = true
+		return false
+	}
+	return true
+}
+
+// Next implements Iterator.
+func (itr *shardedDBIterator) Next() {
+	itr.assertIsValid()
+	top := itr.heap.iters[0]
+	top.Next()
+	if top.Valid() {
+		heap.Fix(&itr.heap, 0)
+	} else {
+		heap.Pop(&itr.heap)
+	}
+}
+
+// Key implements Iterator.
+func (itr *shardedDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.heap.iters[0].Key()
+}
+
+// Value implements Iterator.
+func (itr *shardedDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.heap.iters[0].Value()
+}
+
+// Error implements Iterator.
+func (itr *shardedDBIterator) Error() error {
+	for _, source := range itr.sources {
+		if err := source.Error(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements Iterator.
+func (itr *shardedDBIterator) Close() error {
+	var firstErr error
+	for _, source := range itr.sources {
+		if err := source.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	itr.heap.iters = nil
+	itr.isInvalid = true
+	return firstErr
+}
+
+func (itr *shardedDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
+
+// iteratorHeap implements heap.Interface over valid iterators, with the smallest
+// current key on top (the largest one if isReverse).
+type iteratorHeap struct {
+	iters     []Iterator
+	isReverse bool
+}
+
+func (h *iteratorHeap) Len() int { return len(h.iters) }
+
+func (h *iteratorHeap) Less(i, j int) bool {
+	cmp := bytes.Compare(h.iters[i].Key(), h.iters[j].Key())
+	if h.isReverse {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+func (h *iteratorHeap) Swap(i, j int) { h.iters[i], h.iters[j] = h.iters[j], h.iters[i] }
+
+func (h *iteratorHeap) Push(x interface{}) { h.iters = append(h.iters, x.(Iterator)) }
+
+func (h *iteratorHeap) Pop() interface{} {
+	n := len(h.iters)
+	x := h.iters[n-1]
+	h.iters = h.iters[:n-1]
+	return x
+}
@@ -0,0 +1,85 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRangeSharding(t *testing.T) {
+	shardFn := RangeSharding(bz("b"), bz("m"))
+	assert.Equal(t, 0, shardFn(bz("a"), 3))
+	assert.Equal(t, 1, shardFn(bz("b"), 3))
+	assert.Equal(t, 1, shardFn(bz("lzz"), 3))
+	assert.Equal(t, 2, shardFn(bz("m"), 3))
+	assert.Equal(t, 2, shardFn(bz("zzz"), 3))
+	// Fewer shards than ranges
+	assert.Equal(t, 1, shardFn(bz("zzz"), 2))
+
+	assert.Panics(t, func() { RangeSharding(bz("m"), bz("b")) })
+}
+
+func TestNewShardedDB(t *testing.T) {
+	_, err := NewShardedDB(nil, HashSharding)
+	require.Error(t, err)
+	_, err = NewShardedDB([]DB{NewMemDB()}, nil)
+	require.Error(t, err)
+}
+
+func TestShardedDBRouting(t *testing.T) {
+	shards := []DB{NewMemDB(), NewMemDB(), NewMemDB()}
+	sdb, err := NewShardedDB(shards, RangeSharding(bz("b"), bz("m")))
+	require.NoError(t, err)
+
+	require.NoError(t, sdb.Set(bz("a"), bz("1")))
+	batch := sdb.NewBatch()
+	require.NoError(t, batch.Set(bz("c"), bz("2")))
+	require.NoError(t, batch.Set(bz("x"), bz("3")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	checkValue(t, shards[0], bz("a"), bz("1"))
+	checkValue(t, shards[1], bz("c"), bz("2"))
+	checkValue(t, shards[2], bz("x"), bz("3"))
+	checkValue(t, shards[0], bz("x"), nil)
+
+	stats := sdb.Stats()
+	assert.Equal(t, "3", stats["shard.count"])
+}
+
+func TestShardedDBIterator(t *testing.T) {
+	sdb, err := NewShardedDB([]DB{NewMemDB(), NewMemDB(), NewMemDB(), NewMemDB()}, HashSharding)
+	require.NoError(t, err)
+	for i := int64(0); i < 100; i++ {
+		require.NoError(t, sdb.Set(int642Bytes(i), int642Bytes(i)))
+	}
+	// Keys are spread over all shards.
+	for _, shard := range sdb.Shards() {
+		itr, err := shard.Iterator(nil, nil)
+		require.NoError(t, err)
+		assert.True(t, itr.Valid())
+		itr.Close()
+	}
+
+	itr, err := sdb.Iterator(int642Bytes(10), int642Bytes(90))
+	require.NoError(t, err)
+	checkDomain(t, itr, int642Bytes(10), int642Bytes(90))
+	for i := int64(10); i < 90; i++ {
+		checkValid(t, itr, true)
+		checkItem(t, itr, int642Bytes(i), int642Bytes(i))
+		itr.Next()
+	}
+	checkInvalid(t, itr)
+	require.NoError(t, itr.Close())
+
+	itr, err = sdb.ReverseIterator(nil, int642Bytes(50))
+	require.NoError(t, err)
+	for i := int64(49); i >= 0; i-- {
+		checkValid(t, itr, true)
+		checkItem(t, itr, int642Bytes(i), int642Bytes(i))
+		itr.Next()
+	}
+	checkInvalid(t, itr)
+	require.NoError(t, itr.Close())
+}
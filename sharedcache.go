@@ -0,0 +1,346 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/syndtr/goleveldb/leveldb/cache"
+)
+
+// SharedCache is a memory budget shared by the databases opened with it, see
+// Options.SharedCache. A node opens several stores, e.g. the block store, the
+// state and the transaction index, which otherwise each size their caches and
+// write buffers independently; with a SharedCache their combined block caches
+// and write buffers stay within a single budget, which the busiest stores get
+// most of.
+//
+// The PebbleDB databases share a single block cache, which PebbleDB also
+// charges with their memtables. The goleveldb databases share a single LRU
+// block cache, and reserve twice their write buffer size in it while open,
+// for the memtable being written and the one being flushed; opening a
+// goleveldb database fails if the write buffers would take the whole budget.
+// A SharedCache is used by the databases of a single backend, the first one
+// opened with it.
+type SharedCache struct {
+	size int64
+
+	mtx     sync.Mutex
+	backend BackendType   // backend of the databases, "" until the first is opened
+	pebble  *pebble.Cache // nil unless used by PebbleDB
+	lru     *sharedLRU    // nil unless used by goleveldb
+	closed  bool
+}
+
+// NewSharedCache creates a SharedCache of size bytes. It must be closed once
+// the databases opened with it no longer need it, which may be before they are
+// closed.
+func NewSharedCache(size int64) *SharedCache {
+	return &SharedCache{size: size}
+}
+
+// Size returns the budget of the cache, in bytes.
+func (c *SharedCache) Size() int64 {
+	return c.size
+}
+
+// Usage returns the number of bytes of the budget in use, by the cached
+// blocks and the write buffers of the databases.
+func (c *SharedCache) Usage() int64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	switch {
+	case c.pebble != nil:
+		return c.pebble.Size()
+	case c.lru != nil:
+		return c.lru.usage()
+	default:
+		return 0
+	}
+}
+
+// Close releases the reference of c to the block cache of PebbleDB, which is
+// freed once the databases using it are closed too. No database can be opened
+// with c afterwards.
+func (c *SharedCache) Close() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.pebble != nil {
+		c.pebble.Unref()
+	}
+	return nil
+}
+
+// use binds c to the databases of backend.
+func (c *SharedCache) use(backend BackendType) error {
+	if c.closed {
+		return fmt.Errorf("shared cache: %w", ErrClosed)
+	}
+	if c.backend != "" && c.backend != backend {
+		return fmt.Errorf("shared cache is used by %s, it cannot be used by %s", c.backend, backend)
+	}
+	c.backend = backend
+	return nil
+}
+
+// pebbleCache returns the block cache of the PebbleDB databases. pebble.Open
+// takes its own reference.
+func (c *SharedCache) pebbleCache() (*pebble.Cache, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err := c.use(PebbleDBBackend); err != nil {
+		return nil, err
+	}
+	if c.pebble == nil {
+		c.pebble = pebble.NewCache(c.size)
+	}
+	return c.pebble, nil
+}
+
+// goLevelDBCacher returns the block cache of a goleveldb database, reserving
+// writeBuffers bytes in the budget until it is closed.
+func (c *SharedCache) goLevelDBCacher(writeBuffers int) (*sharedLRUCacher, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err := c.use(GoLevelDBBackend); err != nil {
+		return nil, err
+	}
+	if c.lru == nil {
+		c.lru = &sharedLRU{size: int(c.size)}
+		c.lru.recent.next = &c.lru.recent
+		c.lru.recent.prev = &c.lru.recent
+	}
+	if err := c.lru.reserve(writeBuffers); err != nil {
+		return nil, err
+	}
+	return &sharedLRUCacher{lru: c.lru, reserved: writeBuffers}, nil
+}
+
+// sharedLRU is an LRU block cache shared by goleveldb databases, each using it
+// through a sharedLRUCacher. It works like the LRU cache of goleveldb, whose
+// entries are released through their own handles, but evicts the least
+// recently used blocks of all the databases.
+type sharedLRU struct {
+	mtx      sync.Mutex
+	size     int
+	reserved int // bytes reserved for write buffers
+	used     int // bytes of the cached blocks
+	recent   sharedLRUNode
+}
+
+// sharedLRUNode is a cached block.
+type sharedLRUNode struct {
+	n      *cache.Node
+	h      *cache.Handle
+	cacher *sharedLRUCacher
+	ban    bool
+
+	next, prev *sharedLRUNode
+}
+
+func (n *sharedLRUNode) insert(at *sharedLRUNode) {
+	x := at.next
+	at.next = n
+	n.prev = at
+	n.next = x
+	x.prev = n
+}
+
+func (n *sharedLRUNode) remove() {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev = nil
+	n.next = nil
+}
+
+func (r *sharedLRU) usage() int64 {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return int64(r.used + r.reserved)
+}
+
+// reserve reserves n bytes of the budget for write buffers, evicting blocks to
+// make room for them.
+func (r *sharedLRU) reserve(n int) error {
+	r.mtx.Lock()
+	if r.reserved+n >= r.size {
+		r.mtx.Unlock()
+		return fmt.Errorf("shared cache of %d bytes is too small for write buffers of %d bytes, %d bytes being reserved",
+			r.size, n, r.reserved)
+	}
+	r.reserved += n
+	evicted := r.evictLocked()
+	r.mtx.Unlock()
+	releaseSharedLRUNodes(evicted)
+	return nil
+}
+
+// unreserve releases n bytes reserved for write buffers.
+func (r *sharedLRU) unreserve(n int) {
+	r.mtx.Lock()
+	r.reserved -= n
+	r.mtx.Unlock()
+}
+
+// evictLocked removes the least recently used blocks until the budget is no
+// longer exceeded, and returns them to be released once the lock is released.
+func (r *sharedLRU) evictLocked() []*sharedLRUNode {
+	var evicted []*sharedLRUNode
+	for r.used+r.reserved > r.size && r.recent.prev != &r.recent {
+		rn := r.recent.prev
+		rn.remove()
+		rn.n.CacheData = nil
+		r.used -= rn.n.Size()
+		evicted = append(evicted, rn)
+	}
+	return evicted
+}
+
+// evictCacherLocked removes the blocks of cacher for which evict returns true,
+// and returns them to be released once the lock is released.
+func (r *sharedLRU) evictCacherLocked(cacher *sharedLRUCacher, evict func(*cache.Node) bool) []*sharedLRUNode {
+	var evicted []*sharedLRUNode
+	for rn := r.recent.prev; rn != &r.recent; {
+		prev := rn.prev
+		if rn.cacher == cacher && evict(rn.n) {
+			rn.remove()
+			rn.n.CacheData = nil
+			r.used -= rn.n.Size()
+			evicted = append(evicted, rn)
+		}
+		rn = prev
+	}
+	return evicted
+}
+
+// releaseSharedLRUNodes releases the handles of the evicted blocks.
+func releaseSharedLRUNodes(evicted []*sharedLRUNode) {
+	for _, rn := range evicted {
+		rn.h.Release()
+	}
+}
+
+// sharedLRUCacher is the block cache of a goleveldb database in a sharedLRU.
+type sharedLRUCacher struct {
+	lru      *sharedLRU
+	reserved int
+
+	mtx    sync.Mutex
+	closed bool
+}
+
+var _ cache.Cacher = (*sharedLRUCacher)(nil)
+
+// New implements opt.Cacher, returning the cacher itself whatever the
+// capacity, since the budget is that of the SharedCache.
+func (c *sharedLRUCacher) New(int) cache.Cacher {
+	return c
+}
+
+// Capacity implements cache.Cacher.
+func (c *sharedLRUCacher) Capacity() int {
+	c.lru.mtx.Lock()
+	defer c.lru.mtx.Unlock()
+	return c.lru.size - c.lru.reserved
+}
+
+// SetCapacity implements cache.Cacher. It does nothing, the budget being that
+// of the SharedCache.
+func (c *sharedLRUCacher) SetCapacity(int) {}
+
+// Promote implements cache.Cacher.
+func (c *sharedLRUCacher) Promote(n *cache.Node) {
+	r := c.lru
+	var evicted []*sharedLRUNode
+	r.mtx.Lock()
+	if n.CacheData == nil {
+		if n.Size() <= r.size-r.reserved {
+			rn := &sharedLRUNode{n: n, h: n.GetHandle(), cacher: c}
+			rn.insert(&r.recent)
+			n.CacheData = unsafe.Pointer(rn)
+			r.used += n.Size()
+			evicted = r.evictLocked()
+		}
+	} else {
+		rn := (*sharedLRUNode)(n.CacheData)
+		if !rn.ban {
+			rn.remove()
+			rn.insert(&r.recent)
+		}
+	}
+	r.mtx.Unlock()
+	releaseSharedLRUNodes(evicted)
+}
+
+// Ban implements cache.Cacher.
+func (c *sharedLRUCacher) Ban(n *cache.Node) {
+	r := c.lru
+	r.mtx.Lock()
+	if n.CacheData == nil {
+		n.CacheData = unsafe.Pointer(&sharedLRUNode{n: n, cacher: c, ban: true})
+		r.mtx.Unlock()
+		return
+	}
+	rn := (*sharedLRUNode)(n.CacheData)
+	if rn.ban {
+		r.mtx.Unlock()
+		return
+	}
+	rn.remove()
+	rn.ban = true
+	r.used -= n.Size()
+	r.mtx.Unlock()
+	rn.h.Release()
+	rn.h = nil
+}
+
+// Evict implements cache.Cacher.
+func (c *sharedLRUCacher) Evict(n *cache.Node) {
+	r := c.lru
+	r.mtx.Lock()
+	rn := (*sharedLRUNode)(n.CacheData)
+	if rn == nil || rn.ban {
+		r.mtx.Unlock()
+		return
+	}
+	rn.remove()
+	n.CacheData = nil
+	r.used -= n.Size()
+	r.mtx.Unlock()
+	rn.h.Release()
+}
+
+// EvictNS implements cache.Cacher, evicting the blocks of the table ns of this
+// database only.
+func (c *sharedLRUCacher) EvictNS(ns uint64) {
+	c.lru.mtx.Lock()
+	evicted := c.lru.evictCacherLocked(c, func(n *cache.Node) bool { return n.NS() == ns })
+	c.lru.mtx.Unlock()
+	releaseSharedLRUNodes(evicted)
+}
+
+// EvictAll implements cache.Cacher, evicting the blocks of this database only.
+func (c *sharedLRUCacher) EvictAll() {
+	c.lru.mtx.Lock()
+	evicted := c.lru.evictCacherLocked(c, func(*cache.Node) bool { return true })
+	c.lru.mtx.Unlock()
+	releaseSharedLRUNodes(evicted)
+}
+
+// Close implements cache.Cacher. The blocks of the database, evicted by
+// goleveldb beforehand, and its write buffers are given back to the budget.
+func (c *sharedLRUCacher) Close() error {
+	c.EvictAll()
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if !c.closed {
+		c.closed = true
+		c.lru.unreserve(c.reserved)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharedCache(t *testing.T) {
+	for _, dbType := range []BackendType{GoLevelDBBackend, PebbleDBBackend} {
+		t.Run(string(dbType), func(t *testing.T) {
+			const size = 1 << 20
+			cache := NewSharedCache(size)
+			defer cache.Close()
+
+			dir := t.TempDir()
+			var dbs []DB
+			for i := 0; i < 3; i++ {
+				db, err := NewDB(fmt.Sprintf("db%d", i), dbType, dir,
+					WithSharedCache(cache), WithWriteBufferSize(64<<10))
+				require.NoError(t, err)
+				defer db.Close()
+				dbs = append(dbs, db)
+			}
+
+			value := make([]byte, 1000)
+			for _, db := range dbs {
+				for i := int64(0); i < 1000; i++ {
+					require.NoError(t, db.Set(int642Bytes(i), value))
+				}
+				require.NoError(t, Compact(db, nil, nil))
+			}
+			for _, db := range dbs {
+				for i := int64(0); i < 1000; i++ {
+					checkValue(t, db, int642Bytes(i), value)
+				}
+			}
+			// Blocks are cached beyond the write buffers.
+			assert.Greater(t, cache.Usage(), int64(3*2*64<<10))
+			assert.LessOrEqual(t, cache.Usage(), cache.Size())
+		})
+	}
+}
+
+func TestSharedCacheGoLevelDBWriteBuffers(t *testing.T) {
+	cache := NewSharedCache(1 << 20)
+	defer cache.Close()
+	dir := t.TempDir()
+
+	// Each database reserves twice its write buffer.
+	db, err := NewDB("db0", GoLevelDBBackend, dir, WithSharedCache(cache), WithWriteBufferSize(256<<10))
+	require.NoError(t, err)
+	assert.EqualValues(t, 512<<10, cache.Usage())
+
+	_, err = NewDB("db1", GoLevelDBBackend, dir, WithSharedCache(cache), WithWriteBufferSize(256<<10))
+	assert.Error(t, err)
+	assert.EqualValues(t, 512<<10, cache.Usage())
+
+	// Closing the database gives its write buffers back.
+	require.NoError(t, db.Close())
+	assert.Zero(t, cache.Usage())
+	db, err = NewDB("db1", GoLevelDBBackend, dir, WithSharedCache(cache), WithWriteBufferSize(256<<10))
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
+
+func TestSharedCacheBackends(t *testing.T) {
+	cache := NewSharedCache(1 << 20)
+	dir := t.TempDir()
+
+	db, err := NewDB("db0", PebbleDBBackend, dir, WithSharedCache(cache))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = NewDB("db1", GoLevelDBBackend, dir, WithSharedCache(cache), WithWriteBufferSize(64<<10))
+	assert.Error(t, err)
+
+	// The databases outlive the cache.
+	require.NoError(t, cache.Close())
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	checkValue(t, db, bz("a"), bz("1"))
+
+	_, err = NewDB("db2", PebbleDBBackend, dir, WithSharedCache(cache))
+	assert.ErrorIs(t, err, ErrClosed)
+}
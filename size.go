@@ -0,0 +1,51 @@
+package db
+
+import "errors"
+
+// SizeEstimator is implemented by databases which can estimate the storage
+// used by a range of keys without reading it.
+type SizeEstimator interface {
+	// EstimateSize returns the approximate number of bytes used by the keys in
+	// the range [start, end), including their values. A nil start is the first
+	// key, a nil end is after the last key. The estimate may only account for
+	// data already flushed to disk.
+	EstimateSize(start, end []byte) (uint64, error)
+}
+
+// EstimateSize estimates the bytes used by the range [start, end) of db, see
+// SizeEstimator. It returns ErrNotSupported if db does not implement
+// SizeEstimator.
+func EstimateSize(db DB, start, end []byte) (uint64, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return 0, errKeyEmpty
+	}
+	se, ok := db.(SizeEstimator)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return se.EstimateSize(start, end)
+}
+
+// estimateSizeAll sums the estimates of the range over dbs, skipping those
+// which do not support it. It returns ErrNotSupported if none do.
+func estimateSizeAll(dbs []DB, start, end []byte) (uint64, error) {
+	var (
+		total     uint64
+		supported bool
+	)
+	for _, db := range dbs {
+		size, err := EstimateSize(db, start, end)
+		if errors.Is(err, ErrNotSupported) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		total += size
+		supported = true
+	}
+	if !supported {
+		return 0, ErrNotSupported
+	}
+	return total, nil
+}
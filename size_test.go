@@ -0,0 +1,74 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateSize(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			size, err := EstimateSize(db, nil, nil)
+			if errors.Is(err, ErrNotSupported) {
+				return
+			}
+			require.NoError(t, err)
+			assert.Zero(t, size)
+
+			// Incompressible values, flushed to disk by compaction.
+			for i := int64(0); i < 1000; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), []byte(randStr(1024))))
+			}
+			err = Compact(db, nil, nil)
+			if !errors.Is(err, ErrNotSupported) {
+				require.NoError(t, err)
+			}
+
+			total, err := EstimateSize(db, nil, nil)
+			require.NoError(t, err)
+			assert.Greater(t, total, uint64(500*1024))
+			assert.Less(t, total, uint64(2000*1024))
+
+			half, err := EstimateSize(db, int642Bytes(500), nil)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, half, total)
+
+			none, err := EstimateSize(db, int642Bytes(2000), int642Bytes(3000))
+			require.NoError(t, err)
+			assert.Less(t, none, total)
+
+			_, err = EstimateSize(db, []byte{}, nil)
+			assert.Equal(t, errKeyEmpty, err)
+		})
+	}
+}
+
+func TestEstimateSizeMemDB(t *testing.T) {
+	db := NewMemDB()
+	require.NoError(t, db.Set(bz("a"), bz("123")))
+	require.NoError(t, db.Set(bz("b"), bz("12345")))
+	require.NoError(t, db.Set(bz("c"), bz("1")))
+
+	size, err := EstimateSize(db, nil, nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 12, size)
+	size, err = EstimateSize(db, bz("b"), bz("c"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, size)
+
+	// Wrappers without a supporting database underneath.
+	_, err = EstimateSize(NewPrefixDB(NewDiscardDB(), bz("p")), nil, nil)
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
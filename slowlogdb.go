@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// slowLogKeyPrefixLen is the number of bytes of the keys logged by a SlowLogDB,
+// enough to tell the tables of CometBFT apart.
+const slowLogKeyPrefixLen = 16
+
+// SlowLogDB logs the operations on a database which take at least a threshold,
+// e.g. to diagnose write stalls in production. Gets, Has, writes, batch writes,
+// iterator creation and every iterator step are timed. The operations are
+// logged at Info level with their duration and the prefix and size of their
+// key.
+type SlowLogDB struct {
+	db        DB
+	logger    Logger
+	threshold time.Duration
+}
+
+var _ DB = (*SlowLogDB)(nil)
+
+// NewSlowLogDB wraps db, logging to logger the operations taking at least
+// threshold. Closing the SlowLogDB closes db.
+func NewSlowLogDB(db DB, logger Logger, threshold time.Duration) *SlowLogDB {
+	return &SlowLogDB{db: db, logger: logger, threshold: threshold}
+}
+
+// observe logs the operation op which started at start, if it was slow.
+func (sdb *SlowLogDB) observe(op string, start time.Time, key []byte, keyvals ...interface{}) {
+	elapsed := time.Since(start)
+	if elapsed < sdb.threshold || !logEnabled(sdb.logger, LogLevelInfo) {
+		return
+	}
+	prefix := key
+	if len(prefix) > slowLogKeyPrefixLen {
+		prefix = prefix[:slowLogKeyPrefixLen]
+	}
+	keyvals = append([]interface{}{"op", op, "duration", elapsed,
+		"key_prefix", hexKey(prefix), "key_size", len(key)}, keyvals...)
+	sdb.logger.Info("Slow database operation", keyvals...)
+}
+
+// Get implements DB.
+func (sdb *SlowLogDB) Get(key []byte) ([]byte, error) {
+	start := time.Now()
+	value, err := sdb.db.Get(key)
+	sdb.observe("get", start, key, "value_size", len(value))
+	return value, err
+}
+
+// Has implements DB.
+func (sdb *SlowLogDB) Has(key []byte) (bool, error) {
+	start := time.Now()
+	has, err := sdb.db.Has(key)
+	sdb.observe("has", start, key)
+	return has, err
+}
+
+// Set implements DB.
+func (sdb *SlowLogDB) Set(key []byte, value []byte) error {
+	start := time.Now()
+	err := sdb.db.Set(key, value)
+	sdb.observe("set", start, key, "value_size", len(value))
+	return err
+}
+
+// SetSync implements DB.
+func (sdb *SlowLogDB) SetSync(key []byte, value []byte) error {
+	start := time.Now()
+	err := sdb.db.SetSync(key, value)
+	sdb.observe("set_sync", start, key, "value_size", len(value))
+	return err
+}
+
+// Delete implements DB.
+func (sdb *SlowLogDB) Delete(key []byte) error {
+	start := time.Now()
+	err := sdb.db.Delete(key)
+	sdb.observe("delete", start, key)
+	return err
+}
+
+// DeleteSync implements DB.
+func (sdb *SlowLogDB) DeleteSync(key []byte) error {
+	start := time.Now()
+	err := sdb.db.DeleteSync(key)
+	sdb.observe("delete_sync", start, key)
+	return err
+}
+
+// Iterator implements DB.
+func (sdb *SlowLogDB) Iterator(start, end []byte) (Iterator, error) {
+	now := time.Now()
+	itr, err := sdb.db.Iterator(start, end)
+	sdb.observe("iterator", now, start)
+	if err != nil {
+		return nil, err
+	}
+	return newSlowLogDBIterator(sdb, itr), nil
+}
+
+// ReverseIterator implements DB.
+func (sdb *SlowLogDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	now := time.Now()
+	itr, err := sdb.db.ReverseIterator(start, end)
+	sdb.observe("reverse_iterator", now, end)
+	if err != nil {
+		return nil, err
+	}
+	return newSlowLogDBIterator(sdb, itr), nil
+}
+
+// Close implements DB.
+func (sdb *SlowLogDB) Close() error {
+	return sdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (sdb *SlowLogDB) NewBatch() Batch {
+	return &slowLogDBBatch{db: sdb, source: sdb.db.NewBatch()}
+}
+
+// Print implements DB.
+func (sdb *SlowLogDB) Print() error {
+	return sdb.db.Print()
+}
+
+// Stats implements DB.
+func (sdb *SlowLogDB) Stats() map[string]string {
+	return sdb.db.Stats()
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database.
+func (sdb *SlowLogDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	start := time.Now()
+	swapped, err := CompareAndSwap(sdb.db, key, old, new)
+	sdb.observe("compare_and_swap", start, key, "value_size", len(new))
+	return swapped, err
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+// Reads of the snapshot are not timed.
+func (sdb *SlowLogDB) Snapshot() (DBReader, error) {
+	return Snapshot(sdb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (sdb *SlowLogDB) Metrics() (Metrics, error) {
+	return GetMetrics(sdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (sdb *SlowLogDB) EstimateCount() (uint64, error) {
+	return EstimateCount(sdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (sdb *SlowLogDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(sdb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (sdb *SlowLogDB) Compact(start, end []byte) error {
+	return Compact(sdb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (sdb *SlowLogDB) Flush() error {
+	return Flush(sdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (sdb *SlowLogDB) Sync() error {
+	return Sync(sdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+func (sdb *SlowLogDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, sdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (sdb *SlowLogDB) Checkpoint(path string) error {
+	return Checkpoint(sdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (sdb *SlowLogDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, sdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which SlowLogDB passes through.
+func (sdb *SlowLogDB) Capabilities() Capability {
+	return Capabilities(sdb.db) & (CapSnapshot | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
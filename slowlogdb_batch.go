@@ -0,0 +1,67 @@
+package db
+
+import "time"
+
+// slowLogDBBatch wraps a batch of the underlying database, timing its writes.
+type slowLogDBBatch struct {
+	db     *SlowLogDB
+	source Batch
+	first  []byte // the first key written, logged as the key of the batch
+}
+
+var _ Batch = (*slowLogDBBatch)(nil)
+
+// Set implements Batch.
+func (b *slowLogDBBatch) Set(key, value []byte) error {
+	if err := b.source.Set(key, value); err != nil {
+		return err
+	}
+	if b.first == nil {
+		b.first = cp(key)
+	}
+	return nil
+}
+
+// Delete implements Batch.
+func (b *slowLogDBBatch) Delete(key []byte) error {
+	if err := b.source.Delete(key); err != nil {
+		return err
+	}
+	if b.first == nil {
+		b.first = cp(key)
+	}
+	return nil
+}
+
+// Count implements Batch.
+func (b *slowLogDBBatch) Count() int {
+	return b.source.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *slowLogDBBatch) SizeBytes() int {
+	return b.source.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *slowLogDBBatch) Write() error {
+	return b.write("batch_write", b.source.Write)
+}
+
+// WriteSync implements Batch.
+func (b *slowLogDBBatch) WriteSync() error {
+	return b.write("batch_write_sync", b.source.WriteSync)
+}
+
+func (b *slowLogDBBatch) write(op string, write func() error) error {
+	count, size := b.source.Count(), b.source.SizeBytes()
+	start := time.Now()
+	err := write()
+	b.db.observe(op, start, b.first, "count", count, "size_bytes", size)
+	return err
+}
+
+// Close implements Batch.
+func (b *slowLogDBBatch) Close() error {
+	return b.source.Close()
+}
@@ -0,0 +1,57 @@
+package db
+
+import "time"
+
+// slowLogDBIterator wraps an iterator of the underlying database, timing its
+// steps.
+type slowLogDBIterator struct {
+	db     *SlowLogDB
+	source Iterator
+}
+
+var _ Iterator = (*slowLogDBIterator)(nil)
+
+func newSlowLogDBIterator(db *SlowLogDB, source Iterator) *slowLogDBIterator {
+	return &slowLogDBIterator{db: db, source: source}
+}
+
+// Domain implements Iterator.
+func (itr *slowLogDBIterator) Domain() ([]byte, []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *slowLogDBIterator) Valid() bool {
+	return itr.source.Valid()
+}
+
+// Next implements Iterator. The step is logged with the key it lands on.
+func (itr *slowLogDBIterator) Next() {
+	start := time.Now()
+	itr.source.Next()
+	var key []byte
+	if itr.source.Valid() {
+		key = itr.source.Key()
+	}
+	itr.db.observe("iterator_next", start, key)
+}
+
+// Key implements Iterator.
+func (itr *slowLogDBIterator) Key() []byte {
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *slowLogDBIterator) Value() []byte {
+	return itr.source.Value()
+}
+
+// Error implements Iterator.
+func (itr *slowLogDBIterator) Error() error {
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *slowLogDBIterator) Close() error {
+	return itr.source.Close()
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowLogDB(t *testing.T) {
+	logger := &testLogger{}
+	sdb := NewSlowLogDB(NewMemDB(), logger, 0)
+	defer sdb.Close()
+
+	require.NoError(t, sdb.Set(bz("a"), bz("1")))
+	checkValue(t, sdb, bz("0123456789abcdefghij"), nil)
+	batch := sdb.NewBatch()
+	require.NoError(t, batch.Set(bz("b"), bz("22")))
+	require.NoError(t, batch.Delete(bz("c")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	itr, err := sdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	itr.Next()
+	require.NoError(t, itr.Close())
+
+	assert.Equal(t, 1, logger.count("Slow database operation op set"))
+	assert.Equal(t, 1, logger.count("Slow database operation op get"))
+	assert.Equal(t, 1, logger.count("Slow database operation op batch_write"))
+	assert.Equal(t, 1, logger.count("Slow database operation op iterator "))
+	assert.Equal(t, 1, logger.count("Slow database operation op iterator_next"))
+	logger.mtx.Lock()
+	defer logger.mtx.Unlock()
+	// Long keys are truncated.
+	assert.Contains(t, logger.msgs[1], "key_prefix 30313233343536373839616263646566 key_size 20 value_size 0")
+	assert.Contains(t, logger.msgs[2], "key_prefix 62 key_size 1 count 2 size_bytes")
+	assert.Contains(t, logger.msgs[4], "key_prefix 62 key_size 1")
+}
+
+func TestSlowLogDBThreshold(t *testing.T) {
+	logger := &testLogger{}
+	bdb := &blockingDB{DB: NewMemDB(), unblock: make(chan struct{})}
+	sdb := NewSlowLogDB(bdb, logger, 10*time.Millisecond)
+	defer sdb.Close()
+
+	require.NoError(t, sdb.Set(bz("a"), bz("1")))
+	time.AfterFunc(20*time.Millisecond, func() { close(bdb.unblock) })
+	checkValue(t, sdb, bz("a"), bz("1"))
+	checkValue(t, sdb, bz("a"), bz("1"))
+
+	assert.Equal(t, 1, logger.count("Slow database operation"))
+	assert.Equal(t, 1, logger.count("Slow database operation op get"))
+}
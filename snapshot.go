@@ -0,0 +1,44 @@
+package db
+
+// DBReader is a read-only view of a database. Like DB, it is safe for
+// concurrent use, and keys and values must be considered read-only.
+type DBReader interface {
+	// Get fetches the value of the given key, or nil if it does not exist.
+	// CONTRACT: key, value readonly []byte
+	Get([]byte) ([]byte, error)
+
+	// Has checks if a key exists.
+	// CONTRACT: key, value readonly []byte
+	Has(key []byte) (bool, error)
+
+	// Iterator returns an iterator over a domain of keys, in ascending order,
+	// with the same semantics as DB.Iterator.
+	Iterator(start, end []byte) (Iterator, error)
+
+	// ReverseIterator returns an iterator over a domain of keys, in descending
+	// order, with the same semantics as DB.ReverseIterator.
+	ReverseIterator(start, end []byte) (Iterator, error)
+
+	// Close releases the view. Iterators created from it must be closed first,
+	// and the view must not be used afterwards.
+	Close() error
+}
+
+// Snapshotter is implemented by databases which can take consistent
+// point-in-time views of their contents.
+type Snapshotter interface {
+	// Snapshot returns a read-only view of the database as of the call, which
+	// is not affected by later writes. Callers must Close it when done, since
+	// holding a snapshot may keep the backend from reclaiming space.
+	Snapshot() (DBReader, error)
+}
+
+// Snapshot returns a point-in-time read-only view of db, see Snapshotter. It
+// returns ErrNotSupported if db does not implement Snapshotter.
+func Snapshot(db DB) (DBReader, error) {
+	s, ok := db.(Snapshotter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return s.Snapshot()
+}
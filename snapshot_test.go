@@ -0,0 +1,106 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			for i := int64(0); i < 10; i++ {
+				require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+			}
+
+			snap, err := Snapshot(db)
+			if errors.Is(err, ErrNotSupported) {
+				_, ok := db.(Snapshotter)
+				// Only wrappers over databases without snapshots may implement it.
+				if ok {
+					assert.IsType(t, &PrefixDB{}, db)
+				}
+				return
+			}
+			require.NoError(t, err)
+
+			// Writes after the snapshot are not visible in it.
+			require.NoError(t, db.Set(int642Bytes(1), bz("new")))
+			require.NoError(t, db.Set(int642Bytes(20), int642Bytes(20)))
+			require.NoError(t, db.Delete(int642Bytes(2)))
+
+			checkValue(t, snap, int642Bytes(1), int642Bytes(1))
+			checkValue(t, snap, int642Bytes(2), int642Bytes(2))
+			checkValue(t, snap, int642Bytes(20), nil)
+			ok, err := snap.Has(int642Bytes(2))
+			require.NoError(t, err)
+			assert.True(t, ok)
+			ok, err = snap.Has(int642Bytes(20))
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			checkValue(t, db, int642Bytes(1), bz("new"))
+			checkValue(t, db, int642Bytes(2), nil)
+
+			itr, err := snap.Iterator(int642Bytes(1), nil)
+			require.NoError(t, err)
+			for i := int64(1); i < 10; i++ {
+				checkValid(t, itr, true)
+				checkItem(t, itr, int642Bytes(i), int642Bytes(i))
+				itr.Next()
+			}
+			checkValid(t, itr, false)
+			require.NoError(t, itr.Close())
+
+			itr, err = snap.ReverseIterator(nil, int642Bytes(5))
+			require.NoError(t, err)
+			for i := int64(4); i >= 0; i-- {
+				checkValid(t, itr, true)
+				checkItem(t, itr, int642Bytes(i), int642Bytes(i))
+				itr.Next()
+			}
+			checkValid(t, itr, false)
+			require.NoError(t, itr.Close())
+
+			_, err = snap.Get([]byte{})
+			assert.Equal(t, errKeyEmpty, err)
+			_, err = snap.Iterator([]byte{}, nil)
+			assert.Equal(t, errKeyEmpty, err)
+
+			require.NoError(t, snap.Close())
+		})
+	}
+}
+
+func TestSnapshotNotSupported(t *testing.T) {
+	_, err := Snapshot(NewDiscardDB())
+	assert.ErrorIs(t, err, ErrNotSupported)
+	_, err = Snapshot(NewPrefixDB(NewDiscardDB(), bz("p")))
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestMemDBSnapshotWhileIterating(t *testing.T) {
+	// An open iterator holds the MemDB read lock, which must not block taking
+	// a snapshot.
+	db := NewMemDB()
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer itr.Close()
+
+	snap, err := db.Snapshot()
+	require.NoError(t, err)
+	defer snap.Close()
+	checkValue(t, snap, bz("a"), bz("1"))
+}
@@ -0,0 +1,304 @@
+//go:build sqlitedb
+// +build sqlitedb
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/mattn/go-sqlite3" // also registers the sqlite3 driver
+)
+
+const (
+	// sqliteFileName is the name of the SQLite database file within the database
+	// directory.
+	sqliteFileName = "data.sqlite"
+
+	// BLOB keys are compared with memcmp(), yielding the same order as bytes.Compare.
+	sqliteCreateTable = `CREATE TABLE IF NOT EXISTS kv (
+	key BLOB NOT NULL PRIMARY KEY,
+	value BLOB NOT NULL
+) WITHOUT ROWID`
+	sqliteGet    = `SELECT value FROM kv WHERE key = ?`
+	sqliteHas    = `SELECT 1 FROM kv WHERE key = ?`
+	sqliteSet    = `INSERT INTO kv (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value`
+	sqliteDelete = `DELETE FROM kv WHERE key = ?`
+)
+
+func init() {
+	registerDBCreator(SQLiteDBBackend, newSQLiteDBFromOptions, false)
+}
+
+// newSQLiteDBFromOptions sets the page cache size of the connections. Writes
+// are always synced.
+func newSQLiteDBFromOptions(name, dir string, opts *Options) (DB, error) {
+	if opts.InMemory {
+		return nil, errOption(SQLiteDBBackend, "InMemory")
+	}
+	if opts.FS != nil {
+		return nil, errOption(SQLiteDBBackend, "FS")
+	}
+	if opts.Recover {
+		return nil, errOption(SQLiteDBBackend, "Recover")
+	}
+	if opts.BackendOptions != nil {
+		return nil, errBackendOptions(SQLiteDBBackend, opts.BackendOptions)
+	}
+	db, err := newSQLiteDB(name, dir, opts.ReadOnly, opts.CacheSize)
+	if err != nil {
+		return nil, err
+	}
+	db.iters = newIteratorTracker(name, opts)
+	db.lock = opts.lock
+	return db, nil
+}
+
+// SQLiteDB stores key/value pairs in a single SQLite table, which makes the data
+// inspectable with the standard sqlite3 tooling.
+//
+// NOTE: The database runs in WAL mode with synchronous=FULL, so all operations
+// (including Set, Delete) are synchronous.
+type SQLiteDB struct {
+	db     *sql.DB
+	dir    string
+	iters  *iteratorTracker
+	lock   *dbLock
+	closed atomic.Bool
+}
+
+var _ DB = (*SQLiteDB)(nil)
+
+// NewSQLiteDB opens (creating it if needed) the SQLite database stored under
+// dir/name.db.
+func NewSQLiteDB(name, dir string) (*SQLiteDB, error) {
+	return newSQLiteDB(name, dir, false, 0)
+}
+
+// newSQLiteDB opens the database, with a page cache of cacheSize bytes per
+// connection if positive. A read-only database must already exist.
+func newSQLiteDB(name, dir string, readOnly bool, cacheSize int64) (*SQLiteDB, error) {
+	dbPath := filepath.Join(dir, name+".db")
+	dsn := fmt.Sprintf("file:%s?_journal_mode=WAL&_synchronous=FULL&_busy_timeout=5000",
+		filepath.Join(dbPath, sqliteFileName))
+	if readOnly {
+		dsn += "&mode=ro"
+	} else if err := os.MkdirAll(dbPath, 0o755); err != nil {
+		return nil, err
+	}
+	if cacheSize > 0 {
+		// Negative sizes are in KiB rather than pages.
+		dsn += fmt.Sprintf("&_cache_size=-%d", (cacheSize+1023)/1024)
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if readOnly {
+		err = db.QueryRow(sqliteHas, []byte{0}).Scan(new(int))
+		if errors.Is(err, sql.ErrNoRows) {
+			err = nil
+		}
+	} else {
+		_, err = db.Exec(sqliteCreateTable)
+	}
+	if err != nil {
+		db.Close()
+		return nil, sqliteDBError(err)
+	}
+	return &SQLiteDB{db: db, dir: dbPath}, nil
+}
+
+// sqliteDBError wraps the SQLite errors with the shared error kinds. The error
+// of database/sql for a closed database is not exported, so it is matched by
+// its message.
+func sqliteDBError(err error) error {
+	var serr sqlite3.Error
+	switch {
+	case err == nil:
+		return nil
+	case err.Error() == "sql: database is closed":
+		return wrapError(ErrClosed, err)
+	case errors.As(err, &serr) && serr.Code == sqlite3.ErrReadonly:
+		return wrapError(ErrReadOnly, err)
+	case errors.As(err, &serr) && (serr.Code == sqlite3.ErrCorrupt || serr.Code == sqlite3.ErrNotADB):
+		return wrapError(ErrCorrupted, err)
+	case errors.As(err, &serr) && (serr.Code == sqlite3.ErrBusy || serr.Code == sqlite3.ErrLocked):
+		return wrapError(ErrUnavailable, err)
+	}
+	return err
+}
+
+// Get implements DB.
+func (sdb *SQLiteDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	var value []byte
+	err := sdb.db.QueryRow(sqliteGet, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, sqliteDBError(err)
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+// Has implements DB.
+func (sdb *SQLiteDB) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	var found int
+	err := sdb.db.QueryRow(sqliteHas, key).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, sqliteDBError(err)
+	}
+	return true, nil
+}
+
+// Set implements DB.
+func (sdb *SQLiteDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	_, err := sdb.db.Exec(sqliteSet, key, value)
+	return sqliteDBError(err)
+}
+
+// SetSync implements DB.
+func (sdb *SQLiteDB) SetSync(key []byte, value []byte) error {
+	return sdb.Set(key, value)
+}
+
+// Delete implements DB.
+func (sdb *SQLiteDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	_, err := sdb.db.Exec(sqliteDelete, key)
+	return sqliteDBError(err)
+}
+
+// DeleteSync implements DB.
+func (sdb *SQLiteDB) DeleteSync(key []byte) error {
+	return sdb.Delete(key)
+}
+
+// DB returns the underlying *sql.DB.
+func (sdb *SQLiteDB) DB() *sql.DB {
+	return sdb.db
+}
+
+// Close implements DB.
+func (sdb *SQLiteDB) Close() error {
+	defer sdb.lock.release()
+	// Closing a sql.DB twice succeeds.
+	if sdb.closed.Swap(true) {
+		return wrapError(ErrClosed, errors.New("sql: database is closed"))
+	}
+	sdb.iters.close()
+	return sqliteDBError(sdb.db.Close())
+}
+
+// Print implements DB.
+func (sdb *SQLiteDB) Print() error {
+	itr, err := sdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", itr.Key(), itr.Value())
+	}
+	return itr.Error()
+}
+
+// Stats implements DB.
+func (sdb *SQLiteDB) Stats() map[string]string {
+	pragmas := []string{"page_count", "page_size", "freelist_count", "journal_mode"}
+	stats := make(map[string]string, len(pragmas)+2)
+	for _, pragma := range pragmas {
+		var value string
+		if err := sdb.db.QueryRow("PRAGMA " + pragma).Scan(&value); err == nil {
+			stats["sqlite."+pragma] = value
+		}
+	}
+	dbStats := sdb.db.Stats()
+	stats["sqlite.open-connections"] = fmt.Sprintf("%d", dbStats.OpenConnections)
+	stats["sqlite.in-use"] = fmt.Sprintf("%d", dbStats.InUse)
+	return stats
+}
+
+// Compact implements Compacter. SQLite cannot compact a key range, so the whole
+// database is rebuilt with VACUUM instead, returning free pages to the system.
+func (sdb *SQLiteDB) Compact(_, _ []byte) error {
+	_, err := sdb.db.Exec("VACUUM")
+	return sqliteDBError(err)
+}
+
+// Flush implements Flusher, checkpointing the write-ahead log into the database
+// file. Commits are always synced, so SQLiteDB does not implement Syncer.
+func (sdb *SQLiteDB) Flush() error {
+	_, err := sdb.db.Exec("PRAGMA wal_checkpoint(FULL)")
+	return sqliteDBError(err)
+}
+
+// Backup implements Backupper with VACUUM INTO, which writes a compacted copy
+// of the database as of a read transaction.
+func (sdb *SQLiteDB) Backup(ctx context.Context, targetDir string) error {
+	if err := os.MkdirAll(targetDir, 0o755); err != nil {
+		return err
+	}
+	dir := filepath.Join(targetDir, filepath.Base(sdb.dir))
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		return err
+	}
+	_, err := sdb.db.ExecContext(ctx, "VACUUM INTO ?", filepath.Join(dir, sqliteFileName))
+	return sqliteDBError(err)
+}
+
+// NewBatch implements DB.
+func (sdb *SQLiteDB) NewBatch() Batch {
+	return newSQLiteDBBatch(sdb)
+}
+
+// Iterator implements DB.
+func (sdb *SQLiteDB) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return sdb.iters.track(newSQLiteDBIterator(sdb.db, start, end, IteratorOptions{}))
+}
+
+// ReverseIterator implements DB.
+func (sdb *SQLiteDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return sdb.iters.track(newSQLiteDBIterator(sdb.db, start, end, IteratorOptions{Reverse: true}))
+}
+
+// IteratorWithOptions implements IteratorOptionsDB. KeysOnly iterators do not
+// select the values.
+func (sdb *SQLiteDB) IteratorWithOptions(start, end []byte, opts IteratorOptions) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	return sdb.iters.track(newSQLiteDBIterator(sdb.db, start, end, opts))
+}
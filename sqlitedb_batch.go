@@ -0,0 +1,112 @@
+//go:build sqlitedb
+// +build sqlitedb
+
+package db
+
+import "fmt"
+
+// sqliteDBBatch stores operations internally and applies them to SQLite within
+// a single transaction on Write().
+type sqliteDBBatch struct {
+	db  *SQLiteDB
+	ops []operation
+}
+
+var _ Batch = (*sqliteDBBatch)(nil)
+
+func newSQLiteDBBatch(db *SQLiteDB) *sqliteDBBatch {
+	return &sqliteDBBatch{
+		db:  db,
+		ops: []operation{},
+	}
+}
+
+// Set implements Batch.
+func (b *sqliteDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *sqliteDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	return nil
+}
+
+// Count implements Batch.
+func (b *sqliteDBBatch) Count() int {
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *sqliteDBBatch) SizeBytes() int {
+	return opsSize(b.ops)
+}
+
+// Write implements Batch.
+func (b *sqliteDBBatch) Write() error {
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	tx, err := b.db.db.Begin()
+	if err != nil {
+		return sqliteDBError(err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op once committed
+
+	set, err := tx.Prepare(sqliteSet)
+	if err != nil {
+		return err
+	}
+	defer set.Close()
+	del, err := tx.Prepare(sqliteDelete)
+	if err != nil {
+		return err
+	}
+	defer del.Close()
+
+	for _, op := range b.ops {
+		switch op.opType {
+		case opTypeSet:
+			_, err = set.Exec(op.key, op.value)
+		case opTypeDelete:
+			_, err = del.Exec(op.key)
+		default:
+			err = fmt.Errorf("unknown operation type %v (%v)", op.opType, op)
+		}
+		if err != nil {
+			return sqliteDBError(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return sqliteDBError(err)
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// WriteSync implements Batch.
+func (b *sqliteDBBatch) WriteSync() error {
+	return b.Write()
+}
+
+// Close implements Batch.
+func (b *sqliteDBBatch) Close() error {
+	b.ops = nil
+	return nil
+}
@@ -0,0 +1,136 @@
+//go:build sqlitedb
+// +build sqlitedb
+
+package db
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// sqliteDBIterator iterates over the result of a range query. The query holds a
+// read transaction (and a connection) open until the iterator is closed.
+type sqliteDBIterator struct {
+	rows  *sql.Rows
+	start []byte
+	end   []byte
+
+	key      []byte
+	value    []byte
+	keysOnly bool
+	valid    bool
+	err      error
+}
+
+var _ Iterator = (*sqliteDBIterator)(nil)
+
+func newSQLiteDBIterator(db *sql.DB, start, end []byte, opts IteratorOptions) (*sqliteDBIterator, error) {
+	var (
+		query strings.Builder
+		conds []string
+		args  []interface{}
+	)
+	if opts.KeysOnly {
+		query.WriteString("SELECT key FROM kv")
+	} else {
+		query.WriteString("SELECT key, value FROM kv")
+	}
+	if start != nil {
+		conds = append(conds, "key >= ?")
+		args = append(args, start)
+	}
+	if end != nil {
+		conds = append(conds, "key < ?")
+		args = append(args, end)
+	}
+	if len(conds) > 0 {
+		query.WriteString(" WHERE ")
+		query.WriteString(strings.Join(conds, " AND "))
+	}
+	if opts.Reverse {
+		query.WriteString(" ORDER BY key DESC")
+	} else {
+		query.WriteString(" ORDER BY key ASC")
+	}
+
+	rows, err := db.Query(query.String(), args...)
+	if err != nil {
+		return nil, sqliteDBError(err)
+	}
+	itr := &sqliteDBIterator{
+		rows:     rows,
+		start:    start,
+		end:      end,
+		keysOnly: opts.KeysOnly,
+	}
+	itr.advance()
+	return itr, nil
+}
+
+// advance loads the next row, if any.
+func (itr *sqliteDBIterator) advance() {
+	if !itr.rows.Next() {
+		itr.valid = false
+		itr.err = sqliteDBError(itr.rows.Err())
+		return
+	}
+	var key, value []byte
+	dest := []interface{}{&key, &value}
+	if itr.keysOnly {
+		dest = dest[:1]
+	}
+	if err := itr.rows.Scan(dest...); err != nil {
+		itr.valid = false
+		itr.err = sqliteDBError(err)
+		return
+	}
+	if value == nil && !itr.keysOnly {
+		value = []byte{}
+	}
+	itr.key, itr.value, itr.valid = key, value, true
+}
+
+// Domain implements Iterator.
+func (itr *sqliteDBIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+// Valid implements Iterator.
+func (itr *sqliteDBIterator) Valid() bool {
+	return itr.valid
+}
+
+// Next implements Iterator.
+func (itr *sqliteDBIterator) Next() {
+	itr.assertIsValid()
+	itr.advance()
+}
+
+// Key implements Iterator.
+func (itr *sqliteDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.key
+}
+
+// Value implements Iterator.
+func (itr *sqliteDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.value
+}
+
+// Error implements Iterator.
+func (itr *sqliteDBIterator) Error() error {
+	return itr.err
+}
+
+// Close implements Iterator.
+func (itr *sqliteDBIterator) Close() error {
+	itr.valid = false
+	return itr.rows.Close()
+}
+
+func (itr *sqliteDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -0,0 +1,66 @@
+//go:build sqlitedb
+// +build sqlitedb
+
+package db
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLiteDBBackend(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewDB(name, SQLiteDBBackend, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+	defer db.Close()
+
+	_, ok := db.(*SQLiteDB)
+	assert.True(t, ok)
+	assert.NotEmpty(t, db.Stats())
+}
+
+func TestSQLiteDBKeyOrdering(t *testing.T) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewSQLiteDB(name, dir)
+	require.NoError(t, err)
+	defer cleanupDBDir(dir, name)
+	defer db.Close()
+
+	// BLOB comparison must match bytes.Compare, including prefixes and high bytes.
+	keys := [][]byte{{0x00}, {0x01}, {0x01, 0x00}, {0x7f}, {0x80}, {0xff}, {0xff, 0xff}}
+	for i := len(keys) - 1; i >= 0; i-- {
+		require.NoError(t, db.Set(keys[i], []byte{byte(i)}))
+	}
+
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer itr.Close()
+	var got [][]byte
+	for ; itr.Valid(); itr.Next() {
+		got = append(got, itr.Key())
+	}
+	require.NoError(t, itr.Error())
+	assert.Equal(t, keys, got)
+}
+
+func BenchmarkSQLiteDBRandomReadsWrites(b *testing.B) {
+	name := fmt.Sprintf("test_%x", randStr(12))
+	dir := os.TempDir()
+	db, err := NewSQLiteDB(name, dir)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		db.Close()
+		cleanupDBDir(dir, name)
+	}()
+
+	benchmarkRandomReadsWrites(b, db)
+}
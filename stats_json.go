@@ -0,0 +1,114 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// statsDocument is the JSON document returned by StatsJSON.
+type statsDocument struct {
+	Metrics *statsMetrics     `json:"metrics,omitempty"`
+	Stats   map[string]string `json:"stats"`
+}
+
+// statsMetrics are the Metrics of a statsDocument, with the same fields for
+// all backends.
+type statsMetrics struct {
+	Levels       []statsLevel    `json:"levels"`
+	DiskSize     uint64          `json:"disk_size"`
+	MemTableSize uint64          `json:"memtable_size"`
+	BlockCache   statsCache      `json:"block_cache"`
+	Compaction   statsCompaction `json:"compaction"`
+	Snapshots    int64           `json:"snapshots"`
+	Iterators    int64           `json:"iterators"`
+	WriteStalled bool            `json:"write_stalled"`
+	WriteStalls  uint64          `json:"write_stalls"`
+}
+
+type statsLevel struct {
+	Level int    `json:"level"`
+	Files int64  `json:"files"`
+	Size  uint64 `json:"size"`
+}
+
+type statsCache struct {
+	Size    uint64  `json:"size"`
+	Hits    uint64  `json:"hits"`
+	Misses  uint64  `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+}
+
+type statsCompaction struct {
+	Count        uint64 `json:"count"`
+	ReadBytes    uint64 `json:"read_bytes"`
+	WriteBytes   uint64 `json:"write_bytes"`
+	Running      int64  `json:"running"`
+	PendingBytes uint64 `json:"pending_bytes"`
+}
+
+// StatsJSON returns the statistics of db as a JSON document, for any backend.
+// Its "metrics" object holds the typed Metrics of db, with the same fields for
+// all backends: "levels" (each with "level", "files" and "size"),
+// "disk_size", "memtable_size", "block_cache" ("size", "hits", "misses",
+// "hit_rate"), "compaction" ("count", "read_bytes", "write_bytes", "running",
+// "pending_bytes"), "snapshots", "iterators", "write_stalled" and
+// "write_stalls". It is omitted if db does not implement MetricsReporter. Its
+// "stats" object holds the backend-specific Stats of db.
+func StatsJSON(db DB) ([]byte, error) {
+	doc := statsDocument{Stats: db.Stats()}
+	m, err := GetMetrics(db)
+	switch {
+	case err == nil:
+		doc.Metrics = newStatsMetrics(m)
+	case !errors.Is(err, ErrNotSupported):
+		return nil, err
+	}
+	if doc.Stats == nil {
+		doc.Stats = map[string]string{}
+	}
+	return json.Marshal(doc)
+}
+
+func newStatsMetrics(m Metrics) *statsMetrics {
+	sm := &statsMetrics{
+		Levels:       make([]statsLevel, len(m.Levels)),
+		DiskSize:     m.DiskSize,
+		MemTableSize: m.MemTableSize,
+		BlockCache: statsCache{
+			Size:    m.BlockCache.Size,
+			Hits:    m.BlockCache.Hits,
+			Misses:  m.BlockCache.Misses,
+			HitRate: m.BlockCache.HitRate(),
+		},
+		Compaction:   statsCompaction(m.Compaction),
+		Snapshots:    m.Snapshots,
+		Iterators:    m.Iterators,
+		WriteStalled: m.WriteStalled,
+		WriteStalls:  m.WriteStalls,
+	}
+	for i, level := range m.Levels {
+		sm.Levels[i] = statsLevel{Level: i, Files: level.Files, Size: level.Size}
+	}
+	return sm
+}
+
+// NewStatsHandler returns an HTTP handler serving the StatsJSON document of db,
+// e.g. to be mounted on a debug server. The handler is not meant to be exposed
+// publicly, since the document may reveal details of the node.
+func NewStatsHandler(db DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		doc, err := StatsJSON(db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(doc)
+	})
+}
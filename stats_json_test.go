@@ -0,0 +1,62 @@
+package db
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsJSON(t *testing.T) {
+	db, err := NewGoLevelDB("db", t.TempDir())
+	require.NoError(t, err)
+	defer db.Close()
+	for i := int64(0); i < 1000; i++ {
+		require.NoError(t, db.Set(int642Bytes(i), int642Bytes(i)))
+	}
+	require.NoError(t, db.Compact(nil, nil))
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer itr.Close()
+
+	doc, err := StatsJSON(db)
+	require.NoError(t, err)
+	var stats struct {
+		Metrics struct {
+			Levels []struct {
+				Files int64 `json:"files"`
+			} `json:"levels"`
+			DiskSize   uint64 `json:"disk_size"`
+			Compaction struct {
+				Count uint64 `json:"count"`
+			} `json:"compaction"`
+			Iterators int64 `json:"iterators"`
+		} `json:"metrics"`
+		Stats map[string]string `json:"stats"`
+	}
+	require.NoError(t, json.Unmarshal(doc, &stats))
+	assert.NotEmpty(t, stats.Metrics.Levels)
+	assert.Positive(t, stats.Metrics.DiskSize)
+	assert.Positive(t, stats.Metrics.Compaction.Count)
+	assert.EqualValues(t, 1, stats.Metrics.Iterators)
+	assert.Contains(t, stats.Stats, "leveldb.stats")
+}
+
+func TestStatsHandler(t *testing.T) {
+	handler := NewStatsHandler(NewMemDB())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var doc map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Contains(t, doc, "stats")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,410 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tieredDemoteBatchSize is the number of hot keys examined at a time by Demote.
+const tieredDemoteBatchSize = 1000
+
+// TierPolicy configures how keys move between the tiers of a TieredDB.
+type TierPolicy struct {
+	// PromoteOnRead copies keys read from the cold tier into the hot tier, so
+	// that subsequent reads are served hot.
+	PromoteOnRead bool
+
+	// Demote reports whether a key of the hot tier should be moved to the cold
+	// tier by TieredDB.Demote, e.g. because it is part of old history. If nil,
+	// keys are never demoted.
+	Demote func(key, value []byte) bool
+
+	// DemoteInterval, if non-zero, runs TieredDB.Demote in the background at
+	// this interval.
+	DemoteInterval time.Duration
+}
+
+// TieredDB keeps recent data in a fast hot database (e.g. in memory or on SSD)
+// and older data in a larger cold database. Writes go to the hot tier, reads
+// fall back to the cold tier on a miss, and the TierPolicy decides which keys
+// are promoted and demoted.
+//
+// A key may be present in both tiers, in which case the hot value wins. Deletes
+// apply to both tiers, cold first, so that a failure cannot resurrect an older
+// cold value. Writes are serialized with promotions and demotions, but are
+// not atomic across tiers.
+type TieredDB struct {
+	mtx    sync.Mutex // serializes writes, promotions and demotions
+	hot    DB
+	cold   DB
+	policy TierPolicy
+
+	promotions uint64 // atomic
+	demotions  uint64 // atomic
+
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+var _ DB = (*TieredDB)(nil)
+
+// NewTieredDB creates a TieredDB over the hot and cold databases. Closing the
+// TieredDB closes both.
+func NewTieredDB(hot, cold DB, policy TierPolicy) *TieredDB {
+	tdb := &TieredDB{
+		hot:    hot,
+		cold:   cold,
+		policy: policy,
+		quit:   make(chan struct{}),
+	}
+	if policy.Demote != nil && policy.DemoteInterval > 0 {
+		tdb.wg.Add(1)
+		go tdb.runDemote(policy.DemoteInterval)
+	}
+	return tdb
+}
+
+func (tdb *TieredDB) runDemote(interval time.Duration) {
+	defer tdb.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-tdb.quit:
+			return
+		case <-ticker.C:
+			_, _ = tdb.Demote()
+		}
+	}
+}
+
+// Hot returns the hot database.
+func (tdb *TieredDB) Hot() DB {
+	return tdb.hot
+}
+
+// Cold returns the cold database.
+func (tdb *TieredDB) Cold() DB {
+	return tdb.cold
+}
+
+// Get implements DB.
+func (tdb *TieredDB) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	value, err := tdb.hot.Get(key)
+	if err != nil || value != nil {
+		return value, err
+	}
+	value, err = tdb.cold.Get(key)
+	if err != nil || value == nil {
+		return value, err
+	}
+	if tdb.policy.PromoteOnRead {
+		if err := tdb.promote(key, value); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// promote copies a cold key to the hot tier, unless it was written (or deleted)
+// since it was read.
+func (tdb *TieredDB) promote(key, value []byte) error {
+	tdb.mtx.Lock()
+	defer tdb.mtx.Unlock()
+	ok, err := tdb.hot.Has(key)
+	if err != nil || ok {
+		return err
+	}
+	current, err := tdb.cold.Get(key)
+	if err != nil || current == nil {
+		return err
+	}
+	if err := tdb.hot.Set(key, current); err != nil {
+		return err
+	}
+	atomic.AddUint64(&tdb.promotions, 1)
+	return nil
+}
+
+// Demote moves the hot keys selected by the policy to the cold tier, and
+// returns how many were moved. It is a no-op without a Demote policy.
+func (tdb *TieredDB) Demote() (int, error) {
+	if tdb.policy.Demote == nil {
+		return 0, nil
+	}
+	var start []byte
+	moved := 0
+	for {
+		keys, next, err := tdb.demoteCandidates(start)
+		if err != nil {
+			return moved, err
+		}
+		for _, key := range keys {
+			ok, err := tdb.demote(key)
+			if err != nil {
+				return moved, err
+			}
+			if ok {
+				moved++
+			}
+		}
+		if next == nil {
+			return moved, nil
+		}
+		start = next
+	}
+}
+
+// demoteCandidates returns up to tieredDemoteBatchSize hot keys from start
+// selected by the policy, and where to continue from (nil when done). The
+// iterator is closed before the keys are moved, since not all backends allow
+// writing while iterating.
+func (tdb *TieredDB) demoteCandidates(start []byte) (keys [][]byte, next []byte, err error) {
+	itr, err := tdb.hot.Iterator(start, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer itr.Close()
+	n := 0
+	for ; itr.Valid(); itr.Next() {
+		if n == tieredDemoteBatchSize {
+			return keys, cp(itr.Key()), itr.Error()
+		}
+		n++
+		if tdb.policy.Demote(itr.Key(), itr.Value()) {
+			keys = append(keys, cp(itr.Key()))
+		}
+	}
+	return keys, nil, itr.Error()
+}
+
+// demote moves a single key to the cold tier, if it still qualifies.
+func (tdb *TieredDB) demote(key []byte) (bool, error) {
+	tdb.mtx.Lock()
+	defer tdb.mtx.Unlock()
+	value, err := tdb.hot.Get(key)
+	if err != nil || value == nil || !tdb.policy.Demote(key, value) {
+		return false, err
+	}
+	if err := tdb.cold.Set(key, value); err != nil {
+		return false, err
+	}
+	if err := tdb.hot.Delete(key); err != nil {
+		return false, err
+	}
+	atomic.AddUint64(&tdb.demotions, 1)
+	return true, nil
+}
+
+// Has implements DB.
+func (tdb *TieredDB) Has(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, errKeyEmpty
+	}
+	ok, err := tdb.hot.Has(key)
+	if err != nil || ok {
+		return ok, err
+	}
+	return tdb.cold.Has(key)
+}
+
+// Set implements DB.
+func (tdb *TieredDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	tdb.mtx.Lock()
+	defer tdb.mtx.Unlock()
+	return tdb.hot.Set(key, value)
+}
+
+// SetSync implements DB.
+func (tdb *TieredDB) SetSync(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	tdb.mtx.Lock()
+	defer tdb.mtx.Unlock()
+	return tdb.hot.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (tdb *TieredDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	tdb.mtx.Lock()
+	defer tdb.mtx.Unlock()
+	if err := tdb.cold.Delete(key); err != nil {
+		return err
+	}
+	return tdb.hot.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (tdb *TieredDB) DeleteSync(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	tdb.mtx.Lock()
+	defer tdb.mtx.Unlock()
+	if err := tdb.cold.DeleteSync(key); err != nil {
+		return err
+	}
+	return tdb.hot.DeleteSync(key)
+}
+
+// Iterator implements DB.
+func (tdb *TieredDB) Iterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	hot, err := tdb.hot.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	cold, err := tdb.cold.Iterator(start, end)
+	if err != nil {
+		hot.Close()
+		return nil, err
+	}
+	return newTieredDBIterator(hot, cold, start, end, false), nil
+}
+
+// ReverseIterator implements DB.
+func (tdb *TieredDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	hot, err := tdb.hot.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	cold, err := tdb.cold.ReverseIterator(start, end)
+	if err != nil {
+		hot.Close()
+		return nil, err
+	}
+	return newTieredDBIterator(hot, cold, start, end, true), nil
+}
+
+// Close implements DB. It stops background demotion and closes both tiers.
+func (tdb *TieredDB) Close() error {
+	tdb.closeOnce.Do(func() {
+		close(tdb.quit)
+	})
+	tdb.wg.Wait()
+	err := tdb.hot.Close()
+	if cerr := tdb.cold.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// NewBatch implements DB.
+func (tdb *TieredDB) NewBatch() Batch {
+	return newTieredDBBatch(tdb)
+}
+
+// Print implements DB.
+func (tdb *TieredDB) Print() error {
+	itr, err := tdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		key := itr.Key()
+		value := itr.Value()
+		fmt.Printf("[%X]:\t[%X]\n", key, value)
+	}
+	return itr.Error()
+}
+
+// Stats implements DB. The stats of the tiers are prefixed with "tier.hot." and
+// "tier.cold.".
+func (tdb *TieredDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range tdb.hot.Stats() {
+		stats["tier.hot."+k] = v
+	}
+	for k, v := range tdb.cold.Stats() {
+		stats["tier.cold."+k] = v
+	}
+	stats["tier.promotions"] = strconv.FormatUint(atomic.LoadUint64(&tdb.promotions), 10)
+	stats["tier.demotions"] = strconv.FormatUint(atomic.LoadUint64(&tdb.demotions), 10)
+	return stats
+}
+
+// Metrics implements MetricsReporter, summing the metrics of the tiers which
+// support it level by level.
+func (tdb *TieredDB) Metrics() (Metrics, error) {
+	return metricsAll([]DB{tdb.hot, tdb.cold})
+}
+
+// EstimateCount implements CountEstimator, summing the estimates of the tiers.
+// Keys present in both tiers are counted twice. It returns ErrNotSupported
+// unless both tiers support it.
+func (tdb *TieredDB) EstimateCount() (uint64, error) {
+	return estimateCountAll([]DB{tdb.hot, tdb.cold})
+}
+
+// EstimateSize implements SizeEstimator, summing the estimates of the tiers
+// which support it.
+func (tdb *TieredDB) EstimateSize(start, end []byte) (uint64, error) {
+	return estimateSizeAll([]DB{tdb.hot, tdb.cold}, start, end)
+}
+
+// Compact implements Compacter, compacting the range on both tiers if they
+// support it.
+func (tdb *TieredDB) Compact(start, end []byte) error {
+	herr := Compact(tdb.hot, start, end)
+	if herr != nil && !errors.Is(herr, ErrNotSupported) {
+		return herr
+	}
+	cerr := Compact(tdb.cold, start, end)
+	if cerr != nil && !errors.Is(cerr, ErrNotSupported) {
+		return cerr
+	}
+	if herr != nil && cerr != nil {
+		return ErrNotSupported
+	}
+	return nil
+}
+
+// Flush implements Flusher, flushing both tiers, which must both support it.
+func (tdb *TieredDB) Flush() error {
+	return flushAll([]DB{tdb.hot, tdb.cold})
+}
+
+// Sync implements Syncer, syncing both tiers, which must both support it.
+func (tdb *TieredDB) Sync() error {
+	return syncAll([]DB{tdb.hot, tdb.cold})
+}
+
+// HealthCheck implements HealthChecker, checking both tiers.
+func (tdb *TieredDB) HealthCheck(ctx context.Context) error {
+	return healthCheckAll(ctx, []DB{tdb.hot, tdb.cold})
+}
+
+// Capabilities implements CapabilityReporter. Flushing and syncing require
+// both tiers to support it, compaction only one of them.
+func (tdb *TieredDB) Capabilities() Capability {
+	tiers := []DB{tdb.hot, tdb.cold}
+	return capabilitiesAll(tiers)&(CapFlush|CapSync) | capabilitiesAny(tiers)&CapCompact
+}
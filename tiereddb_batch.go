@@ -0,0 +1,120 @@
+package db
+
+import "fmt"
+
+// tieredDBBatch buffers operations. On write, deletes are applied to the cold
+// tier first, then all operations to the hot tier.
+type tieredDBBatch struct {
+	db  *TieredDB
+	ops []operation
+}
+
+var _ Batch = (*tieredDBBatch)(nil)
+
+func newTieredDBBatch(db *TieredDB) *tieredDBBatch {
+	return &tieredDBBatch{
+		db:  db,
+		ops: []operation{},
+	}
+}
+
+// Set implements Batch.
+func (b *tieredDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *tieredDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	return nil
+}
+
+// Count implements Batch.
+func (b *tieredDBBatch) Count() int {
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *tieredDBBatch) SizeBytes() int {
+	return opsSize(b.ops)
+}
+
+// Write implements Batch.
+func (b *tieredDBBatch) Write() error {
+	return b.write(false)
+}
+
+// WriteSync implements Batch.
+func (b *tieredDBBatch) WriteSync() error {
+	return b.write(true)
+}
+
+func (b *tieredDBBatch) write(sync bool) error {
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.db.mtx.Lock()
+	defer b.db.mtx.Unlock()
+
+	cold := b.db.cold.NewBatch()
+	defer cold.Close()
+	hot := b.db.hot.NewBatch()
+	defer hot.Close()
+	hasDeletes := false
+	for _, op := range b.ops {
+		var err error
+		switch op.opType {
+		case opTypeSet:
+			err = hot.Set(op.key, op.value)
+		case opTypeDelete:
+			hasDeletes = true
+			if err = cold.Delete(op.key); err == nil {
+				err = hot.Delete(op.key)
+			}
+		default:
+			err = fmt.Errorf("unknown operation type %v (%v)", op.opType, op)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if hasDeletes {
+		if err := writeBatch(cold, sync); err != nil {
+			return err
+		}
+	}
+	if err := writeBatch(hot, sync); err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// Close implements Batch.
+func (b *tieredDBBatch) Close() error {
+	b.ops = nil
+	return nil
+}
+
+func writeBatch(batch Batch, sync bool) error {
+	if sync {
+		return batch.WriteSync()
+	}
+	return batch.Write()
+}
@@ -0,0 +1,121 @@
+package db
+
+import "bytes"
+
+// tieredDBIterator merges the iterators of the hot and cold tiers. When a key
+// is present in both, the hot item is returned and the cold one skipped.
+type tieredDBIterator struct {
+	hot   Iterator
+	cold  Iterator
+	start []byte
+	end   []byte
+
+	isReverse bool
+	isInvalid bool
+}
+
+var _ Iterator = (*tieredDBIterator)(nil)
+
+func newTieredDBIterator(hot, cold Iterator, start, end []byte, isReverse bool) *tieredDBIterator {
+	itr := &tieredDBIterator{
+		hot:       hot,
+		cold:      cold,
+		start:     start,
+		end:       end,
+		isReverse: isReverse,
+	}
+	itr.skipShadowed()
+	return itr
+}
+
+// compare orders the current keys of the tiers in iteration order: negative if
+// the hot key comes first, positive if the cold one does and zero if they are
+// equal. An exhausted iterator comes last.
+func (itr *tieredDBIterator) compare() int {
+	switch {
+	case !itr.hot.Valid():
+		return 1
+	case !itr.cold.Valid():
+		return -1
+	}
+	cmp := bytes.Compare(itr.hot.Key(), itr.cold.Key())
+	if itr.isReverse {
+		return -cmp
+	}
+	return cmp
+}
+
+// skipShadowed advances the cold iterator past a key also present hot.
+func (itr *tieredDBIterator) skipShadowed() {
+	if itr.hot.Valid() && itr.cold.Valid() && itr.compare() == 0 {
+		itr.cold.Next()
+	}
+}
+
+// current returns the iterator positioned at the current item.
+func (itr *tieredDBIterator) current() Iterator {
+	if itr.compare() <= 0 {
+		return itr.hot
+	}
+	return itr.cold
+}
+
+// Domain implements Iterator.
+func (itr *tieredDBIterator) Domain() ([]byte, []byte) {
+	return itr.start, itr.end
+}
+
+// Valid implements Iterator.
+func (itr *tieredDBIterator) Valid() bool {
+	if itr.isInvalid {
+		return false
+	}
+	if itr.Error() != nil || (!itr.hot.Valid() && !itr.cold.Valid()) {
+		itr.isInvalid = true
+		return false
+	}
+	return true
+}
+
+// Next implements Iterator.
+func (itr *tieredDBIterator) Next() {
+	itr.assertIsValid()
+	itr.current().Next()
+	itr.skipShadowed()
+}
+
+// Key implements Iterator.
+func (itr *tieredDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.current().Key()
+}
+
+// Value implements Iterator.
+func (itr *tieredDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.current().Value()
+}
+
+// Error implements Iterator.
+func (itr *tieredDBIterator) Error() error {
+	if err := itr.hot.Error(); err != nil {
+		return err
+	}
+	return itr.cold.Error()
+}
+
+// Close implements Iterator.
+func (itr *tieredDBIterator) Close() error {
+	err := itr.hot.Close()
+	if cerr := itr.cold.Close(); err == nil {
+		err = cerr
+	}
+	itr.isInvalid = true
+	return err
+}
+
+func (itr *tieredDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTieredDBReadFallback(t *testing.T) {
+	hot, cold := NewMemDB(), NewMemDB()
+	require.NoError(t, cold.Set(bz("a"), bz("cold")))
+	require.NoError(t, cold.Set(bz("b"), bz("cold")))
+	tdb := NewTieredDB(hot, cold, TierPolicy{})
+	defer tdb.Close()
+
+	checkValue(t, tdb, bz("a"), bz("cold"))
+	checkValue(t, hot, bz("a"), nil)
+
+	// Writes go hot and shadow the cold value.
+	require.NoError(t, tdb.Set(bz("b"), bz("hot")))
+	checkValue(t, tdb, bz("b"), bz("hot"))
+	checkValue(t, cold, bz("b"), bz("cold"))
+
+	// Deletes apply to both tiers.
+	require.NoError(t, tdb.Delete(bz("b")))
+	checkValue(t, tdb, bz("b"), nil)
+	checkValue(t, cold, bz("b"), nil)
+
+	batch := tdb.NewBatch()
+	require.NoError(t, batch.Delete(bz("a")))
+	require.NoError(t, batch.Set(bz("c"), bz("hot")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	checkValue(t, tdb, bz("a"), nil)
+	checkValue(t, cold, bz("a"), nil)
+	checkValue(t, hot, bz("c"), bz("hot"))
+}
+
+func TestTieredDBPromoteOnRead(t *testing.T) {
+	hot, cold := NewMemDB(), NewMemDB()
+	require.NoError(t, cold.Set(bz("a"), bz("1")))
+	tdb := NewTieredDB(hot, cold, TierPolicy{PromoteOnRead: true})
+	defer tdb.Close()
+
+	checkValue(t, tdb, bz("a"), bz("1"))
+	checkValue(t, hot, bz("a"), bz("1"))
+	assert.Equal(t, "1", tdb.Stats()["tier.promotions"])
+}
+
+func isOld(key, _ []byte) bool {
+	return bytes.HasPrefix(key, bz("old/"))
+}
+
+func TestTieredDBDemote(t *testing.T) {
+	hot, cold := NewMemDB(), NewMemDB()
+	tdb := NewTieredDB(hot, cold, TierPolicy{Demote: isOld})
+	defer tdb.Close()
+
+	for i := int64(0); i < 2500; i++ {
+		require.NoError(t, tdb.Set(append(bz("old/"), int642Bytes(i)...), int642Bytes(i)))
+	}
+	require.NoError(t, tdb.Set(bz("new"), bz("value")))
+
+	moved, err := tdb.Demote()
+	require.NoError(t, err)
+	assert.Equal(t, 2500, moved)
+	assert.Equal(t, 1, countKeys(t, hot))
+	assert.Equal(t, 2500, countKeys(t, cold))
+	checkValue(t, tdb, append(bz("old/"), int642Bytes(42)...), int642Bytes(42))
+	checkValue(t, tdb, bz("new"), bz("value"))
+}
+
+func TestTieredDBBackgroundDemote(t *testing.T) {
+	hot, cold := NewMemDB(), NewMemDB()
+	tdb := NewTieredDB(hot, cold, TierPolicy{Demote: isOld, DemoteInterval: 10 * time.Millisecond})
+	require.NoError(t, tdb.Set(bz("old/1"), bz("value")))
+
+	assert.Eventually(t, func() bool { return countKeys(t, cold) == 1 }, time.Second, 10*time.Millisecond)
+	require.NoError(t, tdb.Close())
+}
+
+func TestTieredDBIterator(t *testing.T) {
+	hot, cold := NewMemDB(), NewMemDB()
+	require.NoError(t, cold.Set(bz("a"), bz("cold")))
+	require.NoError(t, cold.Set(bz("b"), bz("cold")))
+	require.NoError(t, cold.Set(bz("d"), bz("cold")))
+	require.NoError(t, hot.Set(bz("b"), bz("hot")))
+	require.NoError(t, hot.Set(bz("c"), bz("hot")))
+	require.NoError(t, hot.Set(bz("d"), bz("hot")))
+	tdb := NewTieredDB(hot, cold, TierPolicy{})
+	defer tdb.Close()
+
+	itr, err := tdb.Iterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("a"), bz("cold"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("b"), bz("hot"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("c"), bz("hot"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("d"), bz("hot"))
+	checkNext(t, itr, false)
+	checkInvalid(t, itr)
+	require.NoError(t, itr.Close())
+
+	itr, err = tdb.ReverseIterator(bz("b"), nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("d"), bz("hot"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("c"), bz("hot"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("b"), bz("hot"))
+	checkNext(t, itr, false)
+	checkInvalid(t, itr)
+	require.NoError(t, itr.Close())
+}
+
+func countKeys(t *testing.T, db DB) int {
+	itr, err := db.Iterator(nil, nil)
+	require.NoError(t, err)
+	defer itr.Close()
+	n := 0
+	for ; itr.Valid(); itr.Next() {
+		n++
+	}
+	require.NoError(t, itr.Error())
+	return n
+}
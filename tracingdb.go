@@ -0,0 +1,259 @@
+package db
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingDB creates OpenTelemetry spans for the operations on a database, with
+// the sizes of the keys and values as attributes. The ContextDB methods, and
+// the context helpers such as GetContext, start the spans as children of the
+// span of their context, so that the storage time shows up in the traces of
+// the callers. The other operations, including batch writes, start root spans.
+type TracingDB struct {
+	db     DB
+	tracer trace.Tracer
+}
+
+var (
+	_ DB        = (*TracingDB)(nil)
+	_ ContextDB = (*TracingDB)(nil)
+)
+
+// The attributes of the spans of a TracingDB.
+const (
+	tracingKeySize    = attribute.Key("db.key_size")
+	tracingValueSize  = attribute.Key("db.value_size")
+	tracingFound      = attribute.Key("db.found")
+	tracingBatchCount = attribute.Key("db.batch.count")
+	tracingBatchBytes = attribute.Key("db.batch.bytes")
+	tracingItems      = attribute.Key("db.iterator.items")
+	tracingReadBytes  = attribute.Key("db.iterator.bytes")
+)
+
+// NewTracingDB wraps db, creating spans with tracer. Closing the TracingDB
+// closes db.
+func NewTracingDB(db DB, tracer trace.Tracer) *TracingDB {
+	return &TracingDB{db: db, tracer: tracer}
+}
+
+// start starts the span of an operation on key.
+func (tdb *TracingDB) start(ctx context.Context, name string, key []byte) (context.Context, trace.Span) {
+	return tdb.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(tracingKeySize.Int(len(key))))
+}
+
+// endSpan ends span, recording err.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Get implements DB.
+func (tdb *TracingDB) Get(key []byte) ([]byte, error) {
+	return tdb.GetContext(context.Background(), key)
+}
+
+// GetContext implements ContextDB.
+func (tdb *TracingDB) GetContext(ctx context.Context, key []byte) ([]byte, error) {
+	ctx, span := tdb.start(ctx, "db.Get", key)
+	value, err := GetContext(ctx, tdb.db, key)
+	span.SetAttributes(tracingFound.Bool(value != nil), tracingValueSize.Int(len(value)))
+	endSpan(span, err)
+	return value, err
+}
+
+// Has implements DB.
+func (tdb *TracingDB) Has(key []byte) (bool, error) {
+	return tdb.HasContext(context.Background(), key)
+}
+
+// HasContext implements ContextDB.
+func (tdb *TracingDB) HasContext(ctx context.Context, key []byte) (bool, error) {
+	ctx, span := tdb.start(ctx, "db.Has", key)
+	has, err := HasContext(ctx, tdb.db, key)
+	span.SetAttributes(tracingFound.Bool(has))
+	endSpan(span, err)
+	return has, err
+}
+
+// Set implements DB.
+func (tdb *TracingDB) Set(key []byte, value []byte) error {
+	return tdb.SetContext(context.Background(), key, value)
+}
+
+// SetContext implements ContextDB.
+func (tdb *TracingDB) SetContext(ctx context.Context, key, value []byte) error {
+	ctx, span := tdb.start(ctx, "db.Set", key)
+	span.SetAttributes(tracingValueSize.Int(len(value)))
+	err := SetContext(ctx, tdb.db, key, value)
+	endSpan(span, err)
+	return err
+}
+
+// SetSync implements DB.
+func (tdb *TracingDB) SetSync(key []byte, value []byte) error {
+	return tdb.SetSyncContext(context.Background(), key, value)
+}
+
+// SetSyncContext implements ContextDB.
+func (tdb *TracingDB) SetSyncContext(ctx context.Context, key, value []byte) error {
+	ctx, span := tdb.start(ctx, "db.SetSync", key)
+	span.SetAttributes(tracingValueSize.Int(len(value)))
+	err := SetSyncContext(ctx, tdb.db, key, value)
+	endSpan(span, err)
+	return err
+}
+
+// Delete implements DB.
+func (tdb *TracingDB) Delete(key []byte) error {
+	return tdb.DeleteContext(context.Background(), key)
+}
+
+// DeleteContext implements ContextDB.
+func (tdb *TracingDB) DeleteContext(ctx context.Context, key []byte) error {
+	ctx, span := tdb.start(ctx, "db.Delete", key)
+	err := DeleteContext(ctx, tdb.db, key)
+	endSpan(span, err)
+	return err
+}
+
+// DeleteSync implements DB.
+func (tdb *TracingDB) DeleteSync(key []byte) error {
+	return tdb.DeleteSyncContext(context.Background(), key)
+}
+
+// DeleteSyncContext implements ContextDB.
+func (tdb *TracingDB) DeleteSyncContext(ctx context.Context, key []byte) error {
+	ctx, span := tdb.start(ctx, "db.DeleteSync", key)
+	err := DeleteSyncContext(ctx, tdb.db, key)
+	endSpan(span, err)
+	return err
+}
+
+// Iterator implements DB.
+func (tdb *TracingDB) Iterator(start, end []byte) (Iterator, error) {
+	return tdb.IteratorContext(context.Background(), start, end)
+}
+
+// IteratorContext implements ContextDB. The span lasts until the iterator is
+// closed.
+func (tdb *TracingDB) IteratorContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	ctx, span := tdb.tracer.Start(ctx, "db.Iterator", trace.WithSpanKind(trace.SpanKindClient))
+	itr, err := IteratorContext(ctx, tdb.db, start, end)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return newTracingDBIterator(span, itr), nil
+}
+
+// ReverseIterator implements DB.
+func (tdb *TracingDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return tdb.ReverseIteratorContext(context.Background(), start, end)
+}
+
+// ReverseIteratorContext implements ContextDB. The span lasts until the
+// iterator is closed.
+func (tdb *TracingDB) ReverseIteratorContext(ctx context.Context, start, end []byte) (Iterator, error) {
+	ctx, span := tdb.tracer.Start(ctx, "db.ReverseIterator", trace.WithSpanKind(trace.SpanKindClient))
+	itr, err := ReverseIteratorContext(ctx, tdb.db, start, end)
+	if err != nil {
+		endSpan(span, err)
+		return nil, err
+	}
+	return newTracingDBIterator(span, itr), nil
+}
+
+// Close implements DB.
+func (tdb *TracingDB) Close() error {
+	return tdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (tdb *TracingDB) NewBatch() Batch {
+	return newTracingDBBatch(tdb.tracer, tdb.db.NewBatch())
+}
+
+// Print implements DB.
+func (tdb *TracingDB) Print() error {
+	return tdb.db.Print()
+}
+
+// Stats implements DB.
+func (tdb *TracingDB) Stats() map[string]string {
+	return tdb.db.Stats()
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database.
+func (tdb *TracingDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	_, span := tdb.start(context.Background(), "db.CompareAndSwap", key)
+	span.SetAttributes(tracingValueSize.Int(len(new)))
+	swapped, err := CompareAndSwap(tdb.db, key, old, new)
+	endSpan(span, err)
+	return swapped, err
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+// Reads of the snapshot are not traced.
+func (tdb *TracingDB) Snapshot() (DBReader, error) {
+	return Snapshot(tdb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (tdb *TracingDB) Metrics() (Metrics, error) {
+	return GetMetrics(tdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (tdb *TracingDB) EstimateCount() (uint64, error) {
+	return EstimateCount(tdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (tdb *TracingDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(tdb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (tdb *TracingDB) Compact(start, end []byte) error {
+	return Compact(tdb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (tdb *TracingDB) Flush() error {
+	return Flush(tdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (tdb *TracingDB) Sync() error {
+	return Sync(tdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+func (tdb *TracingDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, tdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (tdb *TracingDB) Checkpoint(path string) error {
+	return Checkpoint(tdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (tdb *TracingDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, tdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which TracingDB passes through.
+func (tdb *TracingDB) Capabilities() Capability {
+	return Capabilities(tdb.db) & (CapSnapshot | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
@@ -0,0 +1,75 @@
+package db
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingDBBatch wraps a batch of the underlying database, creating a span
+// when it is written.
+type tracingDBBatch struct {
+	tracer trace.Tracer
+	source Batch
+	size   int
+}
+
+var _ Batch = (*tracingDBBatch)(nil)
+
+func newTracingDBBatch(tracer trace.Tracer, source Batch) *tracingDBBatch {
+	return &tracingDBBatch{
+		tracer: tracer,
+		source: source,
+	}
+}
+
+// Set implements Batch.
+func (b *tracingDBBatch) Set(key, value []byte) error {
+	if err := b.source.Set(key, value); err != nil {
+		return err
+	}
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete implements Batch.
+func (b *tracingDBBatch) Delete(key []byte) error {
+	if err := b.source.Delete(key); err != nil {
+		return err
+	}
+	b.size += len(key)
+	return nil
+}
+
+// Count implements Batch.
+func (b *tracingDBBatch) Count() int {
+	return b.source.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *tracingDBBatch) SizeBytes() int {
+	return b.source.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *tracingDBBatch) Write() error {
+	return b.write("db.Batch.Write", b.source.Write)
+}
+
+// WriteSync implements Batch.
+func (b *tracingDBBatch) WriteSync() error {
+	return b.write("db.Batch.WriteSync", b.source.WriteSync)
+}
+
+func (b *tracingDBBatch) write(name string, write func() error) error {
+	_, span := b.tracer.Start(context.Background(), name, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(tracingBatchCount.Int(b.source.Count()), tracingBatchBytes.Int(b.size)))
+	err := write()
+	endSpan(span, err)
+	return err
+}
+
+// Close implements Batch.
+func (b *tracingDBBatch) Close() error {
+	return b.source.Close()
+}
@@ -0,0 +1,80 @@
+package db
+
+import "go.opentelemetry.io/otel/trace"
+
+// tracingDBIterator wraps an iterator of the underlying database, ending its
+// span when it is closed with the number and bytes of the items it landed on.
+type tracingDBIterator struct {
+	span   trace.Span
+	source Iterator
+	items  int
+	bytes  int
+	closed bool
+}
+
+var _ Iterator = (*tracingDBIterator)(nil)
+
+func newTracingDBIterator(span trace.Span, source Iterator) *tracingDBIterator {
+	itr := &tracingDBIterator{
+		span:   span,
+		source: source,
+	}
+	itr.countItem()
+	return itr
+}
+
+// countItem counts the item the iterator landed on.
+func (itr *tracingDBIterator) countItem() {
+	if itr.source.Valid() {
+		itr.items++
+		itr.bytes += len(itr.source.Key()) + len(itr.source.Value())
+	}
+}
+
+// Domain implements Iterator.
+func (itr *tracingDBIterator) Domain() ([]byte, []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *tracingDBIterator) Valid() bool {
+	return itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *tracingDBIterator) Next() {
+	itr.source.Next()
+	itr.countItem()
+}
+
+// Key implements Iterator.
+func (itr *tracingDBIterator) Key() []byte {
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *tracingDBIterator) Value() []byte {
+	return itr.source.Value()
+}
+
+// Error implements Iterator.
+func (itr *tracingDBIterator) Error() error {
+	return itr.source.Error()
+}
+
+// Close implements Iterator. The span is ended on the first call, recording
+// the iterator error if any.
+func (itr *tracingDBIterator) Close() error {
+	if itr.closed {
+		return itr.source.Close()
+	}
+	itr.closed = true
+	spanErr := itr.source.Error()
+	err := itr.source.Close()
+	if err != nil {
+		spanErr = err
+	}
+	itr.span.SetAttributes(tracingItems.Int(itr.items), tracingReadBytes.Int(itr.bytes))
+	endSpan(itr.span, spanErr)
+	return err
+}
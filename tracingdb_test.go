@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newTestTracingDB(t *testing.T) (*TracingDB, *tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tdb := NewTracingDB(NewMemDB(), provider.Tracer("test"))
+	t.Cleanup(func() { tdb.Close() })
+	return tdb, recorder, provider
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTracingDB(t *testing.T) {
+	tdb, recorder, _ := newTestTracingDB(t)
+
+	require.NoError(t, tdb.Set(bz("a"), bz("1")))
+	checkValue(t, tdb, bz("a"), bz("1"))
+	_, err := tdb.Get(nil)
+	require.Error(t, err)
+
+	batch := tdb.NewBatch()
+	require.NoError(t, batch.Set(bz("b"), bz("22")))
+	require.NoError(t, batch.Delete(bz("c")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	itr, err := tdb.ReverseIterator(nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("b"), bz("22"))
+	checkNext(t, itr, true)
+	checkNext(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 5)
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name()
+	}
+	assert.Equal(t, []string{"db.Set", "db.Get", "db.Get", "db.Batch.Write", "db.ReverseIterator"}, names)
+
+	assert.Equal(t, map[attribute.Key]attribute.Value{
+		tracingKeySize:   attribute.IntValue(1),
+		tracingValueSize: attribute.IntValue(1),
+		tracingFound:     attribute.BoolValue(true),
+	}, spanAttributes(spans[1]))
+	assert.Equal(t, codes.Error, spans[2].Status().Code)
+	assert.Equal(t, map[attribute.Key]attribute.Value{
+		tracingBatchCount: attribute.IntValue(2),
+		tracingBatchBytes: attribute.IntValue(4),
+	}, spanAttributes(spans[3]))
+	assert.Equal(t, map[attribute.Key]attribute.Value{
+		tracingItems:     attribute.IntValue(2),
+		tracingReadBytes: attribute.IntValue(5),
+	}, spanAttributes(spans[4]))
+}
+
+func TestTracingDBContext(t *testing.T) {
+	tdb, recorder, provider := newTestTracingDB(t)
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "block")
+	require.NoError(t, SetContext(ctx, tdb, bz("a"), bz("1")))
+	_, err := GetContext(ctx, tdb, bz("a"))
+	require.NoError(t, err)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	for _, span := range spans[:2] {
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Equal(t, parent.SpanContext().TraceID(), span.SpanContext().TraceID())
+	}
+}
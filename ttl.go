@@ -0,0 +1,40 @@
+package db
+
+import (
+	"errors"
+	"time"
+)
+
+// errTTLInvalid is returned when setting a key with a non-positive TTL.
+var errTTLInvalid = errors.New("ttl must be positive")
+
+// TTLSetter is implemented by databases which can expire keys automatically,
+// either natively (e.g. BadgerDB) or through TTLDB.
+type TTLSetter interface {
+	// SetWithTTL sets the value for the given key, which expires after ttl.
+	// Once expired, the key is no longer returned by reads and is eventually
+	// deleted. Setting the key again, with or without a TTL, replaces its
+	// expiry.
+	// CONTRACT: key, value readonly []byte
+	SetWithTTL(key, value []byte, ttl time.Duration) error
+}
+
+// SetWithTTL sets key to value in db, expiring after ttl, see TTLSetter. It
+// returns ErrNotSupported if db does not implement TTLSetter, in which case it
+// can be wrapped in a TTLDB.
+func SetWithTTL(db DB, key, value []byte, ttl time.Duration) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if ttl <= 0 {
+		return errTTLInvalid
+	}
+	ts, ok := db.(TTLSetter)
+	if !ok {
+		return ErrNotSupported
+	}
+	return ts.SetWithTTL(key, value, ttl)
+}
@@ -0,0 +1,352 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ttlSweepBatchSize is the number of expiry index entries processed at a time
+// by Sweep.
+const ttlSweepBatchSize = 1000
+
+// Key prefixes of the TTLDB data and expiry index in the underlying database.
+var (
+	ttlDataPrefix  = []byte{0x00}
+	ttlIndexPrefix = []byte{0x01}
+)
+
+// Markers prefixed to the values of the TTLDB data, followed for expiring keys
+// by their deadline.
+const (
+	ttlPersistent byte = iota
+	ttlExpiring
+)
+
+// TTLDB adds key expiry to any database. Keys set with SetWithTTL are hidden
+// from reads once expired, and deleted by Sweep, which can run periodically in
+// the background. Backends with native TTL support, such as BadgerDB, do not
+// need it.
+//
+// The data is stored under a prefix of the underlying database, with the values
+// prefixed by their expiry. The expiry index, used to find the keys to sweep, is
+// stored under another prefix and ordered by deadline. The underlying database
+// must therefore only be used through the TTLDB.
+type TTLDB struct {
+	mtx  sync.RWMutex // held shared by writes and exclusively by sweeps
+	db   DB
+	data *PrefixDB
+	now  func() time.Time
+
+	expired uint64 // atomic
+
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+var (
+	_ DB        = (*TTLDB)(nil)
+	_ TTLSetter = (*TTLDB)(nil)
+)
+
+// NewTTLDB creates a TTLDB over db. If sweepInterval is non-zero, Sweep runs in
+// the background at this interval. Closing the TTLDB closes db.
+func NewTTLDB(db DB, sweepInterval time.Duration) *TTLDB {
+	tdb := &TTLDB{
+		db:   db,
+		data: NewPrefixDB(db, ttlDataPrefix),
+		now:  time.Now,
+		quit: make(chan struct{}),
+	}
+	if sweepInterval > 0 {
+		tdb.wg.Add(1)
+		go tdb.runSweep(sweepInterval)
+	}
+	return tdb
+}
+
+func (tdb *TTLDB) runSweep(interval time.Duration) {
+	defer tdb.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-tdb.quit:
+			return
+		case <-ticker.C:
+			_, _ = tdb.Sweep()
+		}
+	}
+}
+
+// ttlDataKey returns the key of the data item of key.
+func ttlDataKey(key []byte) []byte {
+	return append(cp(ttlDataPrefix), key...)
+}
+
+// ttlIndexKey returns the key of the expiry index entry of key, ordered by
+// deadline.
+func ttlIndexKey(deadline uint64, key []byte) []byte {
+	ikey := make([]byte, len(ttlIndexPrefix)+8+len(key))
+	n := copy(ikey, ttlIndexPrefix)
+	binary.BigEndian.PutUint64(ikey[n:], deadline)
+	copy(ikey[n+8:], key)
+	return ikey
+}
+
+// ttlEncode returns the data item of value, with a deadline in Unix nanoseconds
+// or 0 if it does not expire.
+func ttlEncode(value []byte, deadline uint64) []byte {
+	if deadline == 0 {
+		return append([]byte{ttlPersistent}, value...)
+	}
+	item := make([]byte, 9+len(value))
+	item[0] = ttlExpiring
+	binary.BigEndian.PutUint64(item[1:], deadline)
+	copy(item[9:], value)
+	return item
+}
+
+// ttlDecode returns the value and deadline of a data item, with a deadline of 0
+// if it does not expire.
+func ttlDecode(item []byte) (value []byte, deadline uint64) {
+	if item[0] == ttlExpiring {
+		return item[9:], binary.BigEndian.Uint64(item[1:9])
+	}
+	return item[1:], 0
+}
+
+// ttlLive returns the value of a data item, or nil if it has expired at now.
+func ttlLive(item []byte, now uint64) []byte {
+	value, deadline := ttlDecode(item)
+	if deadline != 0 && deadline <= now {
+		return nil
+	}
+	return value
+}
+
+// unixNow returns the current time in Unix nanoseconds.
+func (tdb *TTLDB) unixNow() uint64 {
+	return uint64(tdb.now().UnixNano())
+}
+
+// Get implements DB.
+func (tdb *TTLDB) Get(key []byte) ([]byte, error) {
+	item, err := tdb.data.Get(key)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	return ttlLive(item, tdb.unixNow()), nil
+}
+
+// Has implements DB.
+func (tdb *TTLDB) Has(key []byte) (bool, error) {
+	value, err := tdb.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return value != nil, nil
+}
+
+// Set implements DB. The key does not expire, even if it was previously set
+// with a TTL.
+func (tdb *TTLDB) Set(key []byte, value []byte) error {
+	return tdb.set(key, value, 0, false)
+}
+
+// SetSync implements DB.
+func (tdb *TTLDB) SetSync(key []byte, value []byte) error {
+	return tdb.set(key, value, 0, true)
+}
+
+// SetWithTTL implements TTLSetter.
+func (tdb *TTLDB) SetWithTTL(key, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		return errTTLInvalid
+	}
+	return tdb.set(key, value, tdb.unixNow()+uint64(ttl), false)
+}
+
+// set writes the data item of key and, if it expires, its expiry index entry.
+// Index entries of previous expiries are left for Sweep to clean up.
+func (tdb *TTLDB) set(key, value []byte, deadline uint64, sync bool) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	tdb.mtx.RLock()
+	defer tdb.mtx.RUnlock()
+
+	batch := tdb.db.NewBatch()
+	defer batch.Close()
+	if err := batch.Set(ttlDataKey(key), ttlEncode(value, deadline)); err != nil {
+		return err
+	}
+	if deadline != 0 {
+		if err := batch.Set(ttlIndexKey(deadline, key), []byte{}); err != nil {
+			return err
+		}
+	}
+	if sync {
+		return batch.WriteSync()
+	}
+	return batch.Write()
+}
+
+// Delete implements DB.
+func (tdb *TTLDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	tdb.mtx.RLock()
+	defer tdb.mtx.RUnlock()
+	return tdb.db.Delete(ttlDataKey(key))
+}
+
+// DeleteSync implements DB.
+func (tdb *TTLDB) DeleteSync(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	tdb.mtx.RLock()
+	defer tdb.mtx.RUnlock()
+	return tdb.db.DeleteSync(ttlDataKey(key))
+}
+
+// Sweep deletes the expired keys, and returns how many were deleted. Writes
+// are blocked while each chunk of the expiry index is processed.
+func (tdb *TTLDB) Sweep() (int, error) {
+	swept := 0
+	for {
+		n, more, err := tdb.sweep()
+		swept += n
+		atomic.AddUint64(&tdb.expired, uint64(n))
+		if err != nil || !more {
+			return swept, err
+		}
+	}
+}
+
+// sweep processes up to ttlSweepBatchSize expired index entries. It returns the
+// number of keys deleted, and whether there may be more entries to process.
+func (tdb *TTLDB) sweep() (int, bool, error) {
+	tdb.mtx.Lock()
+	defer tdb.mtx.Unlock()
+
+	now := tdb.unixNow()
+	entries, err := tdb.expiredEntries(now)
+	if err != nil {
+		return 0, false, err
+	}
+	batch := tdb.db.NewBatch()
+	defer batch.Close()
+	swept := 0
+	for _, ikey := range entries {
+		if err := batch.Delete(ikey); err != nil {
+			return 0, false, err
+		}
+		deadline := binary.BigEndian.Uint64(ikey[len(ttlIndexPrefix):])
+		key := ikey[len(ttlIndexPrefix)+8:]
+		item, err := tdb.data.Get(key)
+		if err != nil {
+			return 0, false, err
+		}
+		// The key may have been deleted or set again since.
+		if item == nil {
+			continue
+		}
+		if _, d := ttlDecode(item); d != deadline {
+			continue
+		}
+		if err := batch.Delete(ttlDataKey(key)); err != nil {
+			return 0, false, err
+		}
+		swept++
+	}
+	if err := batch.Write(); err != nil {
+		return 0, false, err
+	}
+	return swept, len(entries) == ttlSweepBatchSize, nil
+}
+
+// expiredEntries returns up to ttlSweepBatchSize expiry index keys with a
+// deadline up to now.
+func (tdb *TTLDB) expiredEntries(now uint64) ([][]byte, error) {
+	itr, err := tdb.db.Iterator(ttlIndexPrefix, ttlIndexKey(now+1, nil))
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+	entries := make([][]byte, 0)
+	for ; itr.Valid() && len(entries) < ttlSweepBatchSize; itr.Next() {
+		entries = append(entries, cp(itr.Key()))
+	}
+	return entries, itr.Error()
+}
+
+// Iterator implements DB. Keys expiring while the iterator is open may still be
+// returned.
+func (tdb *TTLDB) Iterator(start, end []byte) (Iterator, error) {
+	source, err := tdb.data.Iterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newTTLDBIterator(source, tdb.unixNow()), nil
+}
+
+// ReverseIterator implements DB. Keys expiring while the iterator is open may
+// still be returned.
+func (tdb *TTLDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	source, err := tdb.data.ReverseIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	return newTTLDBIterator(source, tdb.unixNow()), nil
+}
+
+// Close implements DB. It stops background sweeping and closes the underlying
+// database.
+func (tdb *TTLDB) Close() error {
+	tdb.closeOnce.Do(func() {
+		close(tdb.quit)
+	})
+	tdb.wg.Wait()
+	return tdb.db.Close()
+}
+
+// NewBatch implements DB.
+func (tdb *TTLDB) NewBatch() Batch {
+	return newTTLDBBatch(tdb)
+}
+
+// Print implements DB.
+func (tdb *TTLDB) Print() error {
+	itr, err := tdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		key := itr.Key()
+		value := itr.Value()
+		fmt.Printf("[%X]:\t[%X]\n", key, value)
+	}
+	return itr.Error()
+}
+
+// Stats implements DB. The stats of the underlying database are prefixed with
+// "ttl.db.".
+func (tdb *TTLDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range tdb.db.Stats() {
+		stats["ttl.db."+k] = v
+	}
+	stats["ttl.expired"] = strconv.FormatUint(atomic.LoadUint64(&tdb.expired), 10)
+	return stats
+}
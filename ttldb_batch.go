@@ -0,0 +1,65 @@
+package db
+
+// ttlDBBatch writes the data items of a TTLDB to a batch of the underlying
+// database. Keys set by the batch do not expire.
+type ttlDBBatch struct {
+	db    *TTLDB
+	batch Batch
+}
+
+var _ Batch = (*ttlDBBatch)(nil)
+
+func newTTLDBBatch(db *TTLDB) *ttlDBBatch {
+	return &ttlDBBatch{
+		db:    db,
+		batch: db.db.NewBatch(),
+	}
+}
+
+// Set implements Batch.
+func (b *ttlDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return b.batch.Set(ttlDataKey(key), ttlEncode(value, 0))
+}
+
+// Delete implements Batch.
+func (b *ttlDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return b.batch.Delete(ttlDataKey(key))
+}
+
+// Count implements Batch.
+func (b *ttlDBBatch) Count() int {
+	return b.batch.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *ttlDBBatch) SizeBytes() int {
+	return b.batch.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *ttlDBBatch) Write() error {
+	b.db.mtx.RLock()
+	defer b.db.mtx.RUnlock()
+	return b.batch.Write()
+}
+
+// WriteSync implements Batch.
+func (b *ttlDBBatch) WriteSync() error {
+	b.db.mtx.RLock()
+	defer b.db.mtx.RUnlock()
+	return b.batch.WriteSync()
+}
+
+// Close implements Batch.
+func (b *ttlDBBatch) Close() error {
+	return b.batch.Close()
+}
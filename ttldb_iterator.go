@@ -0,0 +1,68 @@
+package db
+
+// ttlDBIterator decodes the data items of a TTLDB, skipping those expired when
+// it was created.
+type ttlDBIterator struct {
+	source Iterator
+	now    uint64
+}
+
+var _ Iterator = (*ttlDBIterator)(nil)
+
+func newTTLDBIterator(source Iterator, now uint64) *ttlDBIterator {
+	itr := &ttlDBIterator{source: source, now: now}
+	itr.skipExpired()
+	return itr
+}
+
+func (itr *ttlDBIterator) skipExpired() {
+	for itr.source.Valid() && ttlLive(itr.source.Value(), itr.now) == nil {
+		itr.source.Next()
+	}
+}
+
+// Domain implements Iterator.
+func (itr *ttlDBIterator) Domain() (start, end []byte) {
+	return itr.source.Domain()
+}
+
+// Valid implements Iterator.
+func (itr *ttlDBIterator) Valid() bool {
+	return itr.source.Valid()
+}
+
+// Next implements Iterator.
+func (itr *ttlDBIterator) Next() {
+	itr.assertIsValid()
+	itr.source.Next()
+	itr.skipExpired()
+}
+
+// Key implements Iterator.
+func (itr *ttlDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.source.Key()
+}
+
+// Value implements Iterator.
+func (itr *ttlDBIterator) Value() []byte {
+	itr.assertIsValid()
+	value, _ := ttlDecode(itr.source.Value())
+	return value
+}
+
+// Error implements Iterator.
+func (itr *ttlDBIterator) Error() error {
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *ttlDBIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *ttlDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTTLDB returns a TTLDB over db with a clock advanced by the returned
+// function.
+func newTestTTLDB(db DB) (*TTLDB, func(time.Duration)) {
+	now := time.Unix(1_000_000, 0)
+	tdb := NewTTLDB(db, 0)
+	tdb.now = func() time.Time { return now }
+	return tdb, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestTTLDB(t *testing.T) {
+	mdb := NewMemDB()
+	tdb, advance := newTestTTLDB(mdb)
+	defer tdb.Close()
+
+	require.NoError(t, SetWithTTL(tdb, bz("a"), bz("1"), time.Minute))
+	require.NoError(t, SetWithTTL(tdb, bz("b"), bz("2"), time.Hour))
+	require.NoError(t, tdb.Set(bz("c"), bz("3")))
+	checkValue(t, tdb, bz("a"), bz("1"))
+	assert.Equal(t, 3, countKeys(t, tdb))
+
+	advance(time.Minute)
+	checkValue(t, tdb, bz("a"), nil)
+	checkValue(t, tdb, bz("b"), bz("2"))
+	checkValue(t, tdb, bz("c"), bz("3"))
+	ok, err := tdb.Has(bz("a"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 2, countKeys(t, tdb))
+
+	// Expired keys stay in the underlying database until swept.
+	n, err := tdb.Sweep()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 3, countKeys(t, mdb)) // b, c and the index entry of b
+	assert.Equal(t, "1", tdb.Stats()["ttl.expired"])
+
+	advance(time.Hour)
+	n, err = tdb.Sweep()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, countKeys(t, mdb))
+	checkValue(t, tdb, bz("c"), bz("3"))
+}
+
+func TestTTLDBReplaceExpiry(t *testing.T) {
+	tdb, advance := newTestTTLDB(NewMemDB())
+	defer tdb.Close()
+
+	// Setting a key again replaces its expiry, and the stale index entry must
+	// not delete it.
+	require.NoError(t, tdb.SetWithTTL(bz("a"), bz("1"), time.Minute))
+	require.NoError(t, tdb.Set(bz("a"), bz("2")))
+	require.NoError(t, tdb.SetWithTTL(bz("b"), bz("1"), time.Minute))
+	require.NoError(t, tdb.SetWithTTL(bz("b"), bz("2"), time.Hour))
+	require.NoError(t, tdb.SetWithTTL(bz("c"), bz("1"), time.Minute))
+	require.NoError(t, tdb.Delete(bz("c")))
+
+	advance(2 * time.Minute)
+	n, err := tdb.Sweep()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	checkValue(t, tdb, bz("a"), bz("2"))
+	checkValue(t, tdb, bz("b"), bz("2"))
+	checkValue(t, tdb, bz("c"), nil)
+
+	// Keys set by a batch do not expire.
+	require.NoError(t, tdb.SetWithTTL(bz("d"), bz("1"), time.Minute))
+	batch := tdb.NewBatch()
+	require.NoError(t, batch.Set(bz("d"), bz("2")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	advance(time.Hour)
+	checkValue(t, tdb, bz("b"), nil)
+	checkValue(t, tdb, bz("d"), bz("2"))
+}
+
+func TestTTLDBSweepChunks(t *testing.T) {
+	tdb, advance := newTestTTLDB(NewMemDB())
+	defer tdb.Close()
+
+	n := 2*ttlSweepBatchSize + 10
+	for i := 0; i < n; i++ {
+		require.NoError(t, tdb.SetWithTTL(int642Bytes(int64(i)), bz("v"), time.Minute))
+	}
+	advance(time.Minute)
+	swept, err := tdb.Sweep()
+	require.NoError(t, err)
+	assert.Equal(t, n, swept)
+}
+
+func TestTTLDBBackgroundSweep(t *testing.T) {
+	mdb := NewMemDB()
+	tdb := NewTTLDB(mdb, 10*time.Millisecond)
+	defer tdb.Close()
+
+	require.NoError(t, tdb.SetWithTTL(bz("a"), bz("1"), time.Millisecond))
+	assert.Eventually(t, func() bool { return countKeys(t, mdb) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestSetWithTTLErrors(t *testing.T) {
+	tdb := NewTTLDB(NewMemDB(), 0)
+	defer tdb.Close()
+
+	assert.Equal(t, errKeyEmpty, SetWithTTL(tdb, []byte{}, bz("v"), time.Minute))
+	assert.Equal(t, errValueNil, SetWithTTL(tdb, bz("a"), nil, time.Minute))
+	assert.Equal(t, errTTLInvalid, SetWithTTL(tdb, bz("a"), bz("v"), 0))
+	assert.ErrorIs(t, SetWithTTL(NewMemDB(), bz("a"), bz("v"), time.Minute), ErrNotSupported)
+
+	// PrefixDB passes TTLs through to its underlying database.
+	pdb := NewPrefixDB(tdb, bz("p"))
+	require.NoError(t, SetWithTTL(pdb, bz("a"), bz("v"), time.Minute))
+	checkValue(t, tdb, bz("pa"), bz("v"))
+}
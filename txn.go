@@ -0,0 +1,221 @@
+package db
+
+import "errors"
+
+var (
+	// ErrTxnConflict is returned by Txn.Commit when the transaction conflicts
+	// with another one committed in the meantime. The transaction can be
+	// retried from scratch.
+	ErrTxnConflict = errors.New("transaction conflict")
+
+	// errTxnDone is returned when a committed or discarded transaction is used.
+	errTxnDone = errors.New("transaction has been committed or discarded")
+)
+
+// Txn is a read-write transaction. Reads see the writes made in the
+// transaction, which are applied atomically by Commit or dropped by Discard.
+// One of them must always be called. A Txn is not safe for concurrent use, and
+// iterators must be closed before writing to it or ending it.
+type Txn interface {
+	// Get fetches the value of the given key, or nil if it does not exist.
+	// CONTRACT: key, value readonly []byte
+	Get(key []byte) ([]byte, error)
+
+	// Has checks if a key exists.
+	// CONTRACT: key, value readonly []byte
+	Has(key []byte) (bool, error)
+
+	// Iterator returns an iterator over a domain of keys, in ascending order,
+	// with the same semantics as DB.Iterator.
+	Iterator(start, end []byte) (Iterator, error)
+
+	// ReverseIterator returns an iterator over a domain of keys, in descending
+	// order, with the same semantics as DB.ReverseIterator.
+	ReverseIterator(start, end []byte) (Iterator, error)
+
+	// Set sets the value for the given key, replacing it if it already exists.
+	// CONTRACT: key, value readonly []byte
+	Set(key, value []byte) error
+
+	// Delete deletes the key, or does nothing if the key does not exist.
+	// CONTRACT: key readonly []byte
+	Delete(key []byte) error
+
+	// Commit applies the writes of the transaction to the database. It may
+	// return ErrTxnConflict. The transaction cannot be used afterwards, even if
+	// Commit fails.
+	Commit() error
+
+	// Discard drops the writes of the transaction. It is a no-op on a committed
+	// transaction, so it can be deferred.
+	Discard() error
+}
+
+// Transactor is implemented by databases with native transactions.
+type Transactor interface {
+	// NewTxn starts a read-write transaction.
+	NewTxn() (Txn, error)
+}
+
+// NewTxn starts a read-write transaction on db, using its native transactions
+// if it implements Transactor.
+//
+// Otherwise the transaction is emulated: writes are buffered in memory and
+// written in a single batch on Commit, while reads go to a Snapshot of db taken
+// when the transaction starts, if db supports them, or to db itself otherwise.
+// Emulated transactions do not detect conflicts: writes committed in the
+// meantime are silently overwritten.
+func NewTxn(db DB) (Txn, error) {
+	if t, ok := db.(Transactor); ok {
+		return t.NewTxn()
+	}
+	snap, err := Snapshot(db)
+	switch {
+	case errors.Is(err, ErrNotSupported):
+		return newBufferedTxn(db, nil), nil
+	case err != nil:
+		return nil, err
+	}
+	return newBufferedTxn(db, snap), nil
+}
+
+// bufferedTxn emulates a transaction with an overlay of pending writes over a
+// read view of the database, using the tombstone markers of OverlayDB.
+type bufferedTxn struct {
+	db      DB
+	reader  DBReader
+	snap    DBReader // nil if reading from db directly
+	overlay *MemDB   // nil once committed or discarded
+}
+
+var _ Txn = (*bufferedTxn)(nil)
+
+func newBufferedTxn(db DB, snap DBReader) *bufferedTxn {
+	txn := &bufferedTxn{
+		db:      db,
+		reader:  db,
+		snap:    snap,
+		overlay: NewMemDB(),
+	}
+	if snap != nil {
+		txn.reader = snap
+	}
+	return txn
+}
+
+// Get implements Txn.
+func (txn *bufferedTxn) Get(key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	if txn.overlay == nil {
+		return nil, errTxnDone
+	}
+	value, err := txn.overlay.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return txn.reader.Get(key)
+	}
+	if value[0] == overlayDeleted {
+		return nil, nil
+	}
+	return value[1:], nil
+}
+
+// Has implements Txn.
+func (txn *bufferedTxn) Has(key []byte) (bool, error) {
+	value, err := txn.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return value != nil, nil
+}
+
+// Set implements Txn.
+func (txn *bufferedTxn) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if txn.overlay == nil {
+		return errTxnDone
+	}
+	return txn.overlay.Set(cp(key), append([]byte{overlaySet}, value...))
+}
+
+// Delete implements Txn.
+func (txn *bufferedTxn) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if txn.overlay == nil {
+		return errTxnDone
+	}
+	return txn.overlay.Set(cp(key), []byte{overlayDeleted})
+}
+
+// Iterator implements Txn.
+func (txn *bufferedTxn) Iterator(start, end []byte) (Iterator, error) {
+	return txn.iterator(start, end, false)
+}
+
+// ReverseIterator implements Txn.
+func (txn *bufferedTxn) ReverseIterator(start, end []byte) (Iterator, error) {
+	return txn.iterator(start, end, true)
+}
+
+func (txn *bufferedTxn) iterator(start, end []byte, isReverse bool) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	if txn.overlay == nil {
+		return nil, errTxnDone
+	}
+	newIterator := txn.reader.Iterator
+	newOverlayIterator := txn.overlay.Iterator
+	if isReverse {
+		newIterator = txn.reader.ReverseIterator
+		newOverlayIterator = txn.overlay.ReverseIterator
+	}
+	base, err := newIterator(start, end)
+	if err != nil {
+		return nil, err
+	}
+	overlay, err := newOverlayIterator(start, end)
+	if err != nil {
+		base.Close()
+		return nil, err
+	}
+	return newOverlayDBIterator(overlay, base, start, end, isReverse), nil
+}
+
+// Commit implements Txn.
+func (txn *bufferedTxn) Commit() error {
+	if txn.overlay == nil {
+		return errTxnDone
+	}
+	defer txn.Discard() //nolint:errcheck // releases the snapshot
+
+	batch := txn.db.NewBatch()
+	defer batch.Close()
+	if err := addOverlayToBatch(txn.overlay, batch); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// Discard implements Txn.
+func (txn *bufferedTxn) Discard() error {
+	if txn.overlay == nil {
+		return nil
+	}
+	txn.overlay = nil
+	if txn.snap != nil {
+		return txn.snap.Close()
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxn(t *testing.T) {
+	for dbType := range backends {
+		t.Run(string(dbType), func(t *testing.T) {
+			name := fmt.Sprintf("test_%x", randStr(12))
+			dir := os.TempDir()
+			db, err := NewDB(name, dbType, dir)
+			require.NoError(t, err)
+			defer cleanupDBDir(dir, name)
+			defer db.Close()
+
+			require.NoError(t, db.Set(bz("a"), bz("1")))
+			require.NoError(t, db.Set(bz("b"), bz("2")))
+
+			// Discarded writes are dropped.
+			txn, err := NewTxn(db)
+			require.NoError(t, err)
+			require.NoError(t, txn.Set(bz("c"), bz("3")))
+			require.NoError(t, txn.Discard())
+			_, err = txn.Get(bz("a"))
+			assert.Equal(t, errTxnDone, err)
+			assert.Equal(t, errTxnDone, txn.Commit())
+			checkValue(t, db, bz("c"), nil)
+
+			// Reads see the writes of the transaction.
+			txn, err = NewTxn(db)
+			require.NoError(t, err)
+			defer txn.Discard()
+			require.NoError(t, txn.Set(bz("a"), bz("new")))
+			require.NoError(t, txn.Set(bz("c"), bz("3")))
+			require.NoError(t, txn.Delete(bz("b")))
+			assert.Equal(t, errKeyEmpty, txn.Set([]byte{}, bz("v")))
+			assert.Equal(t, errValueNil, txn.Set(bz("x"), nil))
+
+			value, err := txn.Get(bz("a"))
+			require.NoError(t, err)
+			assert.Equal(t, bz("new"), value)
+			ok, err := txn.Has(bz("b"))
+			require.NoError(t, err)
+			assert.False(t, ok)
+			ok, err = txn.Has(bz("c"))
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			itr, err := txn.Iterator(nil, nil)
+			require.NoError(t, err)
+			checkItem(t, itr, bz("a"), bz("new"))
+			checkNext(t, itr, true)
+			checkItem(t, itr, bz("c"), bz("3"))
+			checkNext(t, itr, false)
+			require.NoError(t, itr.Close())
+
+			itr, err = txn.ReverseIterator(nil, bz("c"))
+			require.NoError(t, err)
+			checkItem(t, itr, bz("a"), bz("new"))
+			checkNext(t, itr, false)
+			require.NoError(t, itr.Close())
+
+			require.NoError(t, txn.Commit())
+			require.NoError(t, txn.Discard())
+			assert.Equal(t, errTxnDone, txn.Set(bz("d"), bz("4")))
+
+			checkValue(t, db, bz("a"), bz("new"))
+			checkValue(t, db, bz("b"), nil)
+			checkValue(t, db, bz("c"), bz("3"))
+		})
+	}
+}
+
+func TestTxnEmulatedIsolation(t *testing.T) {
+	// With snapshots, reads ignore writes committed after the transaction
+	// started, and there is no conflict detection.
+	db := NewMemDB()
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+
+	txn, err := NewTxn(db)
+	require.NoError(t, err)
+	require.NoError(t, db.Set(bz("a"), bz("2")))
+	require.NoError(t, db.Set(bz("b"), bz("2")))
+
+	value, err := txn.Get(bz("a"))
+	require.NoError(t, err)
+	assert.Equal(t, bz("1"), value)
+	ok, err := txn.Has(bz("b"))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, txn.Set(bz("a"), bz("3")))
+	require.NoError(t, txn.Commit())
+	checkValue(t, db, bz("a"), bz("3"))
+	checkValue(t, db, bz("b"), bz("2"))
+
+	// Without snapshots, reads see the live database.
+	fdb := NewFaultDB(NewMemDB())
+	txn, err = NewTxn(fdb)
+	require.NoError(t, err)
+	defer txn.Discard()
+	require.NoError(t, fdb.Set(bz("a"), bz("1")))
+	value, err = txn.Get(bz("a"))
+	require.NoError(t, err)
+	assert.Equal(t, bz("1"), value)
+}
@@ -1,18 +1,62 @@
 package db
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	// errBatchClosed is returned when a closed or written batch is used.
-	errBatchClosed = errors.New("batch has been written or closed")
-
 	// errKeyEmpty is returned when attempting to use an empty or nil key.
 	errKeyEmpty = errors.New("key cannot be empty")
 
 	// errValueNil is returned when attempting to set a nil value.
 	errValueNil = errors.New("value cannot be nil")
+
+	// ErrNotSupported is returned by the helpers for optional features (e.g.
+	// Compact) when the database does not implement them.
+	ErrNotSupported = errors.New("operation not supported by this database")
 )
 
+// Errors shared by all backends. The backends wrap their own errors with these
+// where they apply, keeping the original error in the chain, so that callers
+// can check for them with errors.Is regardless of the backend.
+var (
+	// ErrBatchWritten is returned when a batch is used after it has been written
+	// or closed.
+	ErrBatchWritten = errors.New("batch has been written or closed")
+
+	// ErrKeyNotFound is returned when a key which must exist does not. DB.Get
+	// returns a nil value for missing keys instead.
+	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrClosed is returned when a database is used after it has been closed.
+	ErrClosed = errors.New("database is closed")
+
+	// ErrCorrupted is returned when a database detects corrupted data.
+	ErrCorrupted = errors.New("database is corrupted")
+
+	// ErrReadOnly is returned when writing to a database opened read-only.
+	ErrReadOnly = errors.New("database is read-only")
+
+	// ErrWriteStalled is returned by HealthCheck while the database holds back
+	// writes, e.g. until a compaction backlog has been cleared.
+	ErrWriteStalled = errors.New("database writes are stalled")
+
+	// ErrDBLocked is returned by NewDB when the database is locked by another
+	// process, or by another database of this process, see LockError.
+	ErrDBLocked = errors.New("database is locked")
+
+	// ErrUnavailable is returned when an operation failed for a transient
+	// reason, e.g. lock contention or a lost connection to a remote database,
+	// and may succeed if retried, see NewRetryDB.
+	ErrUnavailable = errors.New("database is temporarily unavailable")
+)
+
+// wrapError wraps the backend error err with the shared error kind.
+func wrapError(kind, err error) error {
+	return fmt.Errorf("%w: %w", kind, err)
+}
+
 // DB is the main interface for all database backends. DBs are concurrency-safe. Callers must call
 // Close on the database when done.
 //
@@ -67,6 +111,7 @@ type DB interface {
 	Print() error
 
 	// Stats returns a map of property values for all keys and the size of the cache.
+	// Their format depends on the backend: see GetMetrics for typed statistics.
 	Stats() map[string]string
 }
 
@@ -92,6 +137,16 @@ type Batch interface {
 	// methods will error.
 	WriteSync() error
 
+	// Count returns the number of operations in the batch. It is 0 once the batch has been written
+	// or closed.
+	Count() int
+
+	// SizeBytes returns the approximate size of the batch in bytes, i.e. the total length of its
+	// keys and values, including any encoding added by wrapping databases such as key prefixes. It
+	// is 0 once the batch has been written or closed. Callers can use it to write a batch before it
+	// grows too large.
+	SizeBytes() int
+
 	// Close closes the batch. It is idempotent, but calls to other methods afterwards will error.
 	Close() error
 }
@@ -0,0 +1,222 @@
+package db
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewDBFromURI creates a database from a single string of the form
+//
+//	backend://path/to/name?option=value&...
+//
+// where backend is a BackendType, the last path element is the database name and
+// the rest of the path its directory. For example, "goleveldb:///var/data/state?cache=512MB"
+// opens /var/data/state.db with goleveldb and a 512 MiB block cache. Relative
+// directories are written "goleveldb://./data/state" or "goleveldb:data/state".
+//
+// The options are the Options.BackendParams of the database, so the accepted
+// ones depend on the backend; unknown options are rejected.
+// Sizes accept the B, KB, MB, GB and TB suffixes (powers of 1024, KiB etc. are
+// also understood) and durations use the time.ParseDuration syntax.
+func NewDBFromURI(uri string) (DB, error) {
+	backend, name, dir, params, err := parseDBURI(uri)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) == 0 {
+		return NewDB(name, backend, dir)
+	}
+	backendParams := make(map[string]string, len(params))
+	for key := range params {
+		backendParams[key] = params.Get(key)
+	}
+	return NewDB(name, backend, dir, WithBackendParams(backendParams))
+}
+
+func parseDBURI(uri string) (backend BackendType, name, dir string, params url.Values, err error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", "", "", nil, fmt.Errorf("invalid database URI %q: %w", uri, err)
+	}
+	if u.Scheme == "" {
+		return "", "", "", nil, fmt.Errorf("invalid database URI %q: missing backend", uri)
+	}
+	params, err = url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return "", "", "", nil, fmt.Errorf("invalid database URI %q: %w", uri, err)
+	}
+
+	path := u.Opaque
+	if path == "" {
+		path = u.Host + u.Path
+	}
+	if path != "" {
+		path = filepath.FromSlash(path)
+		name, dir = filepath.Base(path), filepath.Dir(path)
+	}
+	return BackendType(u.Scheme), name, dir, params, nil
+}
+
+// paramBackends are the backends accepting Options.BackendParams.
+var paramBackends = map[BackendType]bool{}
+
+// registerBackendParams registers a backend whose creator applies
+// Options.BackendParams, which are also the options of its database URIs. NewDB
+// fails with BackendParams for the other backends.
+func registerBackendParams(backend BackendType) {
+	paramBackends[backend] = true
+}
+
+// backendParams gives typed access to Options.BackendParams. It remembers the
+// first malformed value and which options were read, so that err can report
+// both invalid and unknown options once the backend is done.
+type backendParams struct {
+	values map[string]string
+	used   map[string]bool
+	first  error
+}
+
+func newBackendParams(values map[string]string) *backendParams {
+	return &backendParams{values: values, used: make(map[string]bool)}
+}
+
+func (p *backendParams) get(key string) (string, bool) {
+	p.used[key] = true
+	v, ok := p.values[key]
+	return v, ok
+}
+
+func (p *backendParams) fail(key, value string, err error) {
+	if p.first == nil {
+		p.first = fmt.Errorf("invalid value %q for option %s: %w", value, key, err)
+	}
+}
+
+// size returns the size in bytes of option key.
+func (p *backendParams) size(key string) (int64, bool) {
+	s, ok := p.get(key)
+	if !ok {
+		return 0, false
+	}
+	v, err := parseSize(s)
+	if err != nil {
+		p.fail(key, s, err)
+		return 0, false
+	}
+	return v, true
+}
+
+// int returns option key as an integer.
+func (p *backendParams) int(key string) (int, bool) {
+	s, ok := p.get(key)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		p.fail(key, s, err)
+		return 0, false
+	}
+	return v, true
+}
+
+// bool returns option key as a boolean. A bare "?key" means true.
+func (p *backendParams) bool(key string) (bool, bool) {
+	s, ok := p.get(key)
+	if !ok {
+		return false, false
+	}
+	if s == "" {
+		return true, true
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		p.fail(key, s, err)
+		return false, false
+	}
+	return v, true
+}
+
+// duration returns option key as a time.Duration.
+func (p *backendParams) duration(key string) (time.Duration, bool) {
+	s, ok := p.get(key)
+	if !ok {
+		return 0, false
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		p.fail(key, s, err)
+		return 0, false
+	}
+	return v, true
+}
+
+// string returns option key as is.
+func (p *backendParams) string(key string) (string, bool) {
+	return p.get(key)
+}
+
+// err returns the first malformed option, or an error listing the options that
+// were never read.
+func (p *backendParams) err() error {
+	if p.first != nil {
+		return p.first
+	}
+	var unknown []string
+	for _, key := range sortedKeys(p.values) {
+		if !p.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown options %v", unknown)
+	}
+	return nil
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var sizeUnits = []struct {
+	suffix string
+	scale  int64
+}{
+	// Longest suffixes first, so that "MB" is not read as "M" + "B".
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// parseSize parses a human readable size such as "512MB" or "4KiB" into bytes.
+// Units are powers of 1024 and a bare number is a number of bytes.
+func parseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	scale := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(str, unit.suffix) {
+			str = strings.TrimSpace(strings.TrimSuffix(str, unit.suffix))
+			scale = unit.scale
+			break
+		}
+	}
+	n, err := strconv.ParseUint(str, 10, 63)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	if int64(n) > (1<<63-1)/scale {
+		return 0, fmt.Errorf("size %q overflows", s)
+	}
+	return int64(n) * scale, nil
+}
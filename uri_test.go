@@ -0,0 +1,96 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSize(t *testing.T) {
+	testCases := map[string]int64{
+		"0":      0,
+		"100":    100,
+		"100B":   100,
+		"4KB":    4 << 10,
+		"4kb":    4 << 10,
+		"4KiB":   4 << 10,
+		"512MB":  512 << 20,
+		"512 MB": 512 << 20,
+		"2G":     2 << 30,
+		"1TiB":   1 << 40,
+	}
+	for s, expected := range testCases {
+		size, err := parseSize(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, size, s)
+	}
+
+	for _, s := range []string{"", "MB", "-1MB", "1.5GB", "12XB", "9999999999TB"} {
+		_, err := parseSize(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseDBURI(t *testing.T) {
+	testCases := []struct {
+		uri     string
+		backend BackendType
+		name    string
+		dir     string
+	}{
+		{"goleveldb:///var/data/state?cache=512MB", GoLevelDBBackend, "state", "/var/data"},
+		{"goleveldb://./data/state", GoLevelDBBackend, "state", "data"},
+		{"pebbledb:data/state", PebbleDBBackend, "state", "data"},
+		{"memdb://", MemDBBackend, "", ""},
+	}
+	for _, tc := range testCases {
+		backend, name, dir, _, err := parseDBURI(tc.uri)
+		require.NoError(t, err, tc.uri)
+		assert.Equal(t, tc.backend, backend, tc.uri)
+		assert.Equal(t, tc.name, name, tc.uri)
+		assert.Equal(t, filepath.FromSlash(tc.dir), dir, tc.uri)
+	}
+
+	for _, uri := range []string{"/var/data/state", "goleveldb:///data?cache=%zz"} {
+		_, _, _, _, err := parseDBURI(uri)
+		assert.Error(t, err, uri)
+	}
+}
+
+func TestNewDBFromURI(t *testing.T) {
+	dir, err := os.MkdirTemp("", "db_uri_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.ToSlash(filepath.Join(dir, "state"))
+
+	db, err := NewDBFromURI("goleveldb://" + path + "?cache=16MB&write_buffer=4MB&compression=none")
+	require.NoError(t, err)
+	_, ok := db.(*GoLevelDB)
+	assert.True(t, ok)
+	require.NoError(t, db.Set([]byte("key"), []byte("value")))
+	require.NoError(t, db.Close())
+	assert.DirExists(t, filepath.Join(dir, "state.db"))
+
+	db, err = NewDBFromURI("goleveldb://" + path + "?readonly")
+	require.NoError(t, err)
+	checkValue(t, db, []byte("key"), []byte("value"))
+	assert.Error(t, db.Set([]byte("key"), []byte("other")))
+	require.NoError(t, db.Close())
+
+	db, err = NewDBFromURI("memdb://")
+	require.NoError(t, err)
+	_, ok = db.(*MemDB)
+	assert.True(t, ok)
+
+	_, err = NewDBFromURI("goleveldb://" + path + "?cahce=16MB")
+	assert.ErrorContains(t, err, "unknown options [cahce]")
+	_, err = NewDBFromURI("goleveldb://" + path + "?cache=lots")
+	assert.ErrorContains(t, err, "option cache")
+	_, err = NewDBFromURI("memdb://?cache=16MB")
+	assert.Error(t, err)
+	_, err = NewDBFromURI("nosuchdb:///data")
+	assert.Error(t, err)
+}
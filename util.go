@@ -11,24 +11,16 @@ func cp(bz []byte) (ret []byte) {
 	return ret
 }
 
-// Returns a slice of the same length (big endian)
-// except incremented by one.
-// Returns nil on overflow (e.g. if bz bytes are all 0xFF)
-// CONTRACT: len(bz) > 0
-func cpIncr(bz []byte) (ret []byte) {
-	if len(bz) == 0 {
-		panic("cpIncr expects non-zero bz length")
-	}
-	ret = cp(bz)
-	for i := len(bz) - 1; i >= 0; i-- {
-		if ret[i] < byte(0xFF) {
-			ret[i]++
-			return
-		}
-		ret[i] = byte(0x00)
-		if i == 0 {
-			// Overflow
-			return nil
+// prefixEnd returns the smallest key after all keys starting with prefix, for
+// use as the exclusive end of an iteration domain. Trailing 0xFF bytes cannot
+// be incremented and are dropped, e.g. the end of 0x01FF is 0x02. It returns
+// nil, i.e. no upper bound, if prefix is empty or only made of 0xFF bytes.
+func prefixEnd(prefix []byte) []byte {
+	end := cp(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xFF {
+			end[i]++
+			return end[:i+1]
 		}
 	}
 	return nil
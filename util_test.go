@@ -14,7 +14,7 @@ func TestPrefixIteratorNoMatchNil(t *testing.T) {
 		t.Run(fmt.Sprintf("Prefix w/ backend %s", backend), func(t *testing.T) {
 			db, dir := newTempDB(t, backend)
 			defer os.RemoveAll(dir)
-			itr, err := IteratePrefix(db, []byte("2"))
+			itr, err := PrefixIterator(db, []byte("2"))
 			require.NoError(t, err)
 
 			checkInvalid(t, itr)
@@ -33,7 +33,7 @@ func TestPrefixIteratorNoMatch1(t *testing.T) {
 		t.Run(fmt.Sprintf("Prefix w/ backend %s", backend), func(t *testing.T) {
 			db, dir := newTempDB(t, backend)
 			defer os.RemoveAll(dir)
-			itr, err := IteratePrefix(db, []byte("2"))
+			itr, err := PrefixIterator(db, []byte("2"))
 			require.NoError(t, err)
 			err = db.SetSync(bz("1"), bz("value_1"))
 			require.NoError(t, err)
@@ -51,7 +51,7 @@ func TestPrefixIteratorNoMatch2(t *testing.T) {
 			defer os.RemoveAll(dir)
 			err := db.SetSync(bz("3"), bz("value_3"))
 			require.NoError(t, err)
-			itr, err := IteratePrefix(db, []byte("4"))
+			itr, err := PrefixIterator(db, []byte("4"))
 			require.NoError(t, err)
 
 			checkInvalid(t, itr)
@@ -67,7 +67,7 @@ func TestPrefixIteratorMatch1(t *testing.T) {
 			defer os.RemoveAll(dir)
 			err := db.SetSync(bz("2"), bz("value_2"))
 			require.NoError(t, err)
-			itr, err := IteratePrefix(db, bz("2"))
+			itr, err := PrefixIterator(db, bz("2"))
 			require.NoError(t, err)
 
 			checkValid(t, itr, true)
@@ -102,7 +102,7 @@ func TestPrefixIteratorMatches1N(t *testing.T) {
 			require.NoError(t, err)
 			err = db.SetSync(bz("abcdefg"), bz("value_3"))
 			require.NoError(t, err)
-			itr, err := IteratePrefix(db, bz("a/"))
+			itr, err := PrefixIterator(db, bz("a/"))
 			require.NoError(t, err)
 
 			checkValid(t, itr, true)
@@ -118,3 +118,67 @@ func TestPrefixIteratorMatches1N(t *testing.T) {
 		})
 	}
 }
+
+// Prefixes ending with 0xFF bytes must not match the keys after the prefix.
+func TestPrefixIteratorOverflow(t *testing.T) {
+	for backend := range backends {
+		t.Run(fmt.Sprintf("Prefix w/ backend %s", backend), func(t *testing.T) {
+			db, dir := newTempDB(t, backend)
+			defer os.RemoveAll(dir)
+
+			for _, key := range [][]byte{{0x01, 0xFE}, {0x01, 0xFF}, {0x01, 0xFF, 0x00}, {0x01, 0xFF, 0xFF}, {0x02}, {0xFF, 0xFF}} {
+				require.NoError(t, db.SetSync(key, key))
+			}
+
+			itr, err := PrefixIterator(db, []byte{0x01, 0xFF})
+			require.NoError(t, err)
+			checkItem(t, itr, []byte{0x01, 0xFF}, []byte{0x01, 0xFF})
+			checkNext(t, itr, true)
+			checkItem(t, itr, []byte{0x01, 0xFF, 0x00}, []byte{0x01, 0xFF, 0x00})
+			checkNext(t, itr, true)
+			checkItem(t, itr, []byte{0x01, 0xFF, 0xFF}, []byte{0x01, 0xFF, 0xFF})
+			checkNext(t, itr, false)
+			require.NoError(t, itr.Close())
+
+			itr, err = ReversePrefixIterator(db, []byte{0x01})
+			require.NoError(t, err)
+			checkItem(t, itr, []byte{0x01, 0xFF, 0xFF}, []byte{0x01, 0xFF, 0xFF})
+			checkNext(t, itr, true)
+			checkItem(t, itr, []byte{0x01, 0xFF, 0x00}, []byte{0x01, 0xFF, 0x00})
+			checkNext(t, itr, true)
+			checkItem(t, itr, []byte{0x01, 0xFF}, []byte{0x01, 0xFF})
+			checkNext(t, itr, true)
+			checkItem(t, itr, []byte{0x01, 0xFE}, []byte{0x01, 0xFE})
+			checkNext(t, itr, false)
+			require.NoError(t, itr.Close())
+
+			// An all-0xFF prefix has no upper bound.
+			itr, err = ReversePrefixIterator(db, []byte{0xFF})
+			require.NoError(t, err)
+			checkItem(t, itr, []byte{0xFF, 0xFF}, []byte{0xFF, 0xFF})
+			checkNext(t, itr, false)
+			require.NoError(t, itr.Close())
+		})
+	}
+}
+
+func TestPrefixEnd(t *testing.T) {
+	testCases := []struct {
+		prefix []byte
+		end    []byte
+	}{
+		{nil, nil},
+		{[]byte{}, nil},
+		{[]byte{0x00}, []byte{0x01}},
+		{[]byte{0x01, 0x02}, []byte{0x01, 0x03}},
+		{[]byte{0x01, 0xFF}, []byte{0x02}},
+		{[]byte{0x01, 0xFF, 0xFF}, []byte{0x02}},
+		{[]byte{0xFF}, nil},
+		{[]byte{0xFF, 0xFF}, nil},
+	}
+	for _, tc := range testCases {
+		prefix := cp(tc.prefix)
+		require.Equal(t, tc.end, prefixEnd(tc.prefix), "prefix %X", tc.prefix)
+		require.Equal(t, prefix, cp(tc.prefix), "prefix must not be modified")
+	}
+}
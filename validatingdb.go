@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Errors returned by a ValidatingDB for writes breaking its rules. The
+// returned errors wrap them with the details, e.g. the size and limit.
+var (
+	ErrKeyTooLarge   = errors.New("key too large")
+	ErrValueTooLarge = errors.New("value too large")
+	ErrInvalidKey    = errors.New("invalid key")
+)
+
+// ValidationRules are the rules a ValidatingDB enforces on written keys and
+// values. The zero value allows everything.
+type ValidationRules struct {
+	// MaxKeySize and MaxValueSize, if positive, are the maximum sizes of keys
+	// and values in bytes.
+	MaxKeySize   int
+	MaxValueSize int
+
+	// KeyByte, if set, reports whether a byte is allowed in keys, e.g. to
+	// require printable keys.
+	KeyByte func(c byte) bool
+}
+
+// PrintableASCII reports whether c is a printable ASCII character, for use as
+// ValidationRules.KeyByte.
+func PrintableASCII(c byte) bool {
+	return c >= 0x20 && c < 0x7f
+}
+
+// check returns the error for key and value if they break the rules. A nil
+// value is not checked, for deletes.
+func (r ValidationRules) check(key, value []byte) error {
+	if r.MaxKeySize > 0 && len(key) > r.MaxKeySize {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrKeyTooLarge, len(key), r.MaxKeySize)
+	}
+	if r.MaxValueSize > 0 && len(value) > r.MaxValueSize {
+		return fmt.Errorf("%w: %d bytes, limit %d, for key %X", ErrValueTooLarge, len(value), r.MaxValueSize, key)
+	}
+	if r.KeyByte != nil {
+		for i, c := range key {
+			if !r.KeyByte(c) {
+				return fmt.Errorf("%w: byte %#x at offset %d of key %X", ErrInvalidKey, c, i, key)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidatingDB enforces ValidationRules on the keys and values written to a
+// database, e.g. so that a single oversized value cannot slow down the
+// compactions of goleveldb unnoticed. Writes breaking the rules fail with
+// ErrKeyTooLarge, ErrValueTooLarge or ErrInvalidKey and are not applied.
+// Reads and deletes are not checked.
+type ValidatingDB struct {
+	db    DB
+	rules ValidationRules
+}
+
+var _ DB = (*ValidatingDB)(nil)
+
+// NewValidatingDB wraps db, enforcing rules. Closing the ValidatingDB closes db.
+func NewValidatingDB(db DB, rules ValidationRules) *ValidatingDB {
+	return &ValidatingDB{db: db, rules: rules}
+}
+
+// Get implements DB.
+func (vdb *ValidatingDB) Get(key []byte) ([]byte, error) {
+	return vdb.db.Get(key)
+}
+
+// Has implements DB.
+func (vdb *ValidatingDB) Has(key []byte) (bool, error) {
+	return vdb.db.Has(key)
+}
+
+// Set implements DB.
+func (vdb *ValidatingDB) Set(key []byte, value []byte) error {
+	if err := vdb.rules.check(key, value); err != nil {
+		return err
+	}
+	return vdb.db.Set(key, value)
+}
+
+// SetSync implements DB.
+func (vdb *ValidatingDB) SetSync(key []byte, value []byte) error {
+	if err := vdb.rules.check(key, value); err != nil {
+		return err
+	}
+	return vdb.db.SetSync(key, value)
+}
+
+// Delete implements DB.
+func (vdb *ValidatingDB) Delete(key []byte) error {
+	return vdb.db.Delete(key)
+}
+
+// DeleteSync implements DB.
+func (vdb *ValidatingDB) DeleteSync(key []byte) error {
+	return vdb.db.DeleteSync(key)
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database.
+func (vdb *ValidatingDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	if err := vdb.rules.check(key, new); err != nil {
+		return false, err
+	}
+	return CompareAndSwap(vdb.db, key, old, new)
+}
+
+// Iterator implements DB.
+func (vdb *ValidatingDB) Iterator(start, end []byte) (Iterator, error) {
+	return vdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (vdb *ValidatingDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return vdb.db.ReverseIterator(start, end)
+}
+
+// Close implements DB.
+func (vdb *ValidatingDB) Close() error {
+	return vdb.db.Close()
+}
+
+// NewBatch implements DB. The batch checks the keys and values when they are
+// set.
+func (vdb *ValidatingDB) NewBatch() Batch {
+	return &validatingDBBatch{Batch: vdb.db.NewBatch(), rules: vdb.rules}
+}
+
+// Print implements DB.
+func (vdb *ValidatingDB) Print() error {
+	return vdb.db.Print()
+}
+
+// Stats implements DB.
+func (vdb *ValidatingDB) Stats() map[string]string {
+	return vdb.db.Stats()
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+func (vdb *ValidatingDB) Snapshot() (DBReader, error) {
+	return Snapshot(vdb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (vdb *ValidatingDB) Metrics() (Metrics, error) {
+	return GetMetrics(vdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (vdb *ValidatingDB) EstimateCount() (uint64, error) {
+	return EstimateCount(vdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (vdb *ValidatingDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(vdb.db, start, end)
+}
+
+// DeleteRange implements RangeDeleter, with the range deletion of the
+// underlying database.
+func (vdb *ValidatingDB) DeleteRange(start, end []byte) error {
+	return DeleteRange(vdb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (vdb *ValidatingDB) Compact(start, end []byte) error {
+	return Compact(vdb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (vdb *ValidatingDB) Flush() error {
+	return Flush(vdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (vdb *ValidatingDB) Sync() error {
+	return Sync(vdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+func (vdb *ValidatingDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, vdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (vdb *ValidatingDB) Checkpoint(path string) error {
+	return Checkpoint(vdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (vdb *ValidatingDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, vdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which ValidatingDB passes through.
+func (vdb *ValidatingDB) Capabilities() Capability {
+	return Capabilities(vdb.db) & (CapSnapshot | CapDeleteRange | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
+
+// validatingDBBatch checks the keys and values set in a batch of the
+// underlying database.
+type validatingDBBatch struct {
+	Batch
+	rules ValidationRules
+}
+
+// Set implements Batch.
+func (b *validatingDBBatch) Set(key, value []byte) error {
+	if err := b.rules.check(key, value); err != nil {
+		return err
+	}
+	return b.Batch.Set(key, value)
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatingDB(t *testing.T) {
+	vdb := NewValidatingDB(NewMemDB(), ValidationRules{
+		MaxKeySize:   4,
+		MaxValueSize: 8,
+		KeyByte:      PrintableASCII,
+	})
+	defer vdb.Close()
+
+	require.NoError(t, vdb.Set(bz("abcd"), bz("12345678")))
+	assert.ErrorIs(t, vdb.Set(bz("abcde"), bz("1")), ErrKeyTooLarge)
+	assert.ErrorIs(t, vdb.SetSync(bz("a"), bz("123456789")), ErrValueTooLarge)
+	err := vdb.Set([]byte{'a', 0}, bz("1"))
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	assert.EqualError(t, err, "invalid key: byte 0x0 at offset 1 of key 6100")
+	_, err = CompareAndSwap(vdb, bz("abcd"), bz("12345678"), bz("123456789"))
+	assert.ErrorIs(t, err, ErrValueTooLarge)
+	checkValue(t, vdb, bz("abcd"), bz("12345678"))
+
+	batch := vdb.NewBatch()
+	assert.ErrorIs(t, batch.Set(bz("b"), bz("123456789")), ErrValueTooLarge)
+	require.NoError(t, batch.Set(bz("b"), bz("1")))
+	require.NoError(t, batch.Delete(bz("abcd")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	checkValue(t, vdb, bz("b"), bz("1"))
+	checkValue(t, vdb, bz("abcd"), nil)
+
+	// The zero rules allow everything.
+	vdb = NewValidatingDB(NewMemDB(), ValidationRules{})
+	require.NoError(t, vdb.Set([]byte{0xff, 0}, make([]byte, 1<<20)))
+}
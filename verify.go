@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"errors"
+)
+
+// CorruptedRange is a range of keys which VerifyIntegrity could not read, from
+// Start inclusive, or the first key if nil, to End exclusive, or after the last
+// key if nil. Readable keys may be hidden in the range, since the iteration
+// skips ahead of the corruption without knowing the keys it skips.
+type CorruptedRange struct {
+	Start []byte
+	End   []byte
+	// Err is the error reading the first key of the range.
+	Err error
+}
+
+// IntegrityReport is the result of VerifyIntegrity.
+type IntegrityReport struct {
+	// Keys is the number of keys read successfully.
+	Keys uint64
+	// Bytes is the size of the keys and values read successfully.
+	Bytes uint64
+	// Corrupted are the ranges of keys which could not be read, in order.
+	Corrupted []CorruptedRange
+}
+
+// verifyReadOptions are the read options of VerifyIntegrity: the checksums are
+// verified by the backends which support it, and the values read are not
+// cached, since they are read once.
+var verifyReadOptions = IteratorOptions{
+	ReadOptions: ReadOptions{VerifyChecksums: true, DontFillCache: true},
+}
+
+// VerifyIntegrity reads every key and value of db, with the checksums verified
+// by the backends which support ReadOptions.VerifyChecksums, e.g. to check a
+// database after a crash or a disk failure. Reads failing with ErrCorrupted are
+// reported as corrupted ranges, and the verification resumes after them, at the
+// first key which can be read again; other errors end it. db must not be
+// written to during the verification.
+func VerifyIntegrity(ctx context.Context, db DB) (IntegrityReport, error) {
+	var (
+		report IntegrityReport
+		start  []byte
+		last   []byte
+	)
+	for {
+		err := verifyRange(ctx, db, start, &report, &last)
+		if err == nil || !errors.Is(err, ErrCorrupted) {
+			return report, err
+		}
+		corrupted := CorruptedRange{Err: err}
+		if last != nil {
+			corrupted.Start = append(cp(last), 0)
+		}
+		if corrupted.End, err = skipCorrupted(db, last); err != nil {
+			return report, err
+		}
+		report.Corrupted = append(report.Corrupted, corrupted)
+		if corrupted.End == nil {
+			return report, nil
+		}
+		start = corrupted.End
+	}
+}
+
+// verifyRange reads the keys from start, counting them in report and recording
+// the last one read in last, until the end of db or an error.
+func verifyRange(ctx context.Context, db DB, start []byte, report *IntegrityReport, last *[]byte) error {
+	itr, err := IteratorWithOptions(db, start, nil, verifyReadOptions)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		key, value := itr.Key(), itr.Value()
+		report.Keys++
+		report.Bytes += uint64(len(key) + len(value))
+		*last = append((*last)[:0], key...)
+	}
+	return itr.Error()
+}
+
+// skipCorrupted returns the first key which can be read after a corruption
+// following last, or nil if no key can be read after it. Since the keys of the
+// corrupted range are unknown, it seeks further and further ahead of last, by
+// adding increasing powers of two to its bytes from the last one, e.g. to "abd",
+// "abe", "abg" and so on, and then to "ad", "ae" and so on after the last key
+// "abc", so that few readable keys are skipped.
+func skipCorrupted(db DB, last []byte) ([]byte, error) {
+	for i := len(last) - 1; i >= 0; i-- {
+		for d := 1; int(last[i])+d <= 0xFF; d *= 2 {
+			seek := append(cp(last[:i]), last[i]+byte(d))
+			key, err := readableKey(db, seek)
+			switch {
+			case errors.Is(err, ErrCorrupted):
+				continue
+			case err != nil:
+				return nil, err
+			}
+			return key, nil
+		}
+	}
+	return nil, nil
+}
+
+// readableKey returns the first key from start, or nil if there is none.
+func readableKey(db DB, start []byte) ([]byte, error) {
+	itr, err := IteratorWithOptions(db, start, nil, verifyReadOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+	if !itr.Valid() {
+		return nil, itr.Error()
+	}
+	return cp(itr.Key()), nil
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCorruptedGoLevelDB writes n keys to a goleveldb database in dir, compacted
+// into a table of many blocks, and then flips a byte in the middle of the table.
+func newCorruptedGoLevelDB(t *testing.T, dir string, n int) {
+	db, err := NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	for i := 0; i < n; i++ {
+		require.NoError(t, db.Set([]byte(fmt.Sprintf("key%05d", i)), []byte(randStr(100))))
+	}
+	require.NoError(t, db.Compact(nil, nil))
+	require.NoError(t, db.Close())
+
+	tables, err := filepath.Glob(filepath.Join(dir, "db.db", "*.ldb"))
+	require.NoError(t, err)
+	require.Len(t, tables, 1)
+	data, err := os.ReadFile(tables[0])
+	require.NoError(t, err)
+	data[len(data)/3] ^= 0xFF
+	require.NoError(t, os.WriteFile(tables[0], data, 0o644))
+}
+
+func TestVerifyIntegrity(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemDB()
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.Set(bz("bc"), bz("23")))
+	report, err := VerifyIntegrity(ctx, db)
+	require.NoError(t, err)
+	assert.Equal(t, IntegrityReport{Keys: 2, Bytes: 6}, report)
+
+	// Other errors end the verification.
+	fdb := NewFaultDB(db)
+	fdb.Inject(Fault{Ops: FaultIterate, KeyPrefix: bz("b"), Err: ErrFaultDiskFull})
+	_, err = VerifyIntegrity(ctx, fdb)
+	assert.ErrorIs(t, err, ErrFaultDiskFull)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = VerifyIntegrity(cctx, db)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestVerifyIntegrityCorrupted(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	const n = 2000
+	newCorruptedGoLevelDB(t, dir, n)
+
+	db, err := NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	report, err := VerifyIntegrity(ctx, db)
+	require.NoError(t, err)
+	require.Len(t, report.Corrupted, 1)
+	corrupted := report.Corrupted[0]
+	assert.ErrorIs(t, corrupted.Err, ErrCorrupted)
+	require.NotNil(t, corrupted.Start)
+	require.NotNil(t, corrupted.End)
+	assert.Less(t, string(corrupted.Start), string(corrupted.End))
+	assert.Less(t, report.Keys, uint64(n))
+	assert.Greater(t, report.Keys, uint64(n/2))
+
+	// The keys around the range can be read.
+	for _, key := range [][]byte{corrupted.Start[:len(corrupted.Start)-1], corrupted.End} {
+		value, err := db.Get(key)
+		require.NoError(t, err)
+		assert.NotNil(t, value)
+	}
+	require.NoError(t, db.Close())
+
+	// Recovery drops the corrupted blocks.
+	require.NoError(t, RecoverGoLevelDB("db", dir))
+	db, err = NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	defer db.Close()
+	recovered, err := VerifyIntegrity(ctx, db)
+	require.NoError(t, err)
+	assert.Empty(t, recovered.Corrupted)
+	assert.GreaterOrEqual(t, recovered.Keys, report.Keys)
+	assert.Less(t, recovered.Keys, uint64(n))
+}
+
+func TestRecoverGoLevelDB(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	require.NoError(t, db.Set(bz("a"), bz("1")))
+	require.NoError(t, db.Compact(nil, nil))
+	require.NoError(t, db.Close())
+
+	// The database cannot be opened without its manifest.
+	manifests, err := filepath.Glob(filepath.Join(dir, "db.db", "MANIFEST-*"))
+	require.NoError(t, err)
+	for _, manifest := range manifests {
+		require.NoError(t, os.Remove(manifest))
+	}
+	_, err = NewGoLevelDB("db", dir)
+	require.Error(t, err)
+
+	require.NoError(t, RecoverGoLevelDB("db", dir))
+	db, err = NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	defer db.Close()
+	checkValue(t, db, bz("a"), bz("1"))
+}
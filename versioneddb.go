@@ -0,0 +1,470 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+)
+
+// versionedPruneBatchSize is the number of entries deleted at a time by Prune.
+const versionedPruneBatchSize = 1000
+
+// Key prefixes of the VersionedDB metadata and entries in the underlying
+// database.
+var (
+	versionedMetaPrefix = []byte{0x00}
+	versionedDataPrefix = []byte{0x01}
+
+	versionedLatestKey   = append(cp(versionedMetaPrefix), "latest"...)
+	versionedEarliestKey = append(cp(versionedMetaPrefix), "earliest"...)
+)
+
+// Markers prefixed to the values of the VersionedDB entries.
+const (
+	versionedDeleted byte = iota
+	versionedSet
+)
+
+// Errors returned by a VersionedDB for versions which cannot be read.
+var (
+	ErrVersionPruned   = errors.New("version pruned")
+	ErrVersionNotSaved = errors.New("version not saved")
+)
+
+// VersionedDB records every write to a database under its key and version, so
+// that the database can be read as of any saved version, e.g. for archival
+// queries at historical heights. Writes go to the working version, following
+// the last saved one, which SaveVersion saves. Get, Has and the iterators read
+// the working version, while GetAt and IteratorAt read saved versions. Prune
+// deletes the entries which are only needed by old versions.
+//
+// The entries are stored in the order of their keys and versions, with the keys
+// escaped so that the versions of a key are contiguous, and the values prefixed
+// by whether the key was set or deleted. The underlying database must therefore
+// only be used through the VersionedDB.
+type VersionedDB struct {
+	mtx      sync.RWMutex // held shared by writes and exclusively by SaveVersion and Prune
+	db       DB
+	latest   uint64
+	earliest uint64
+}
+
+var _ DB = (*VersionedDB)(nil)
+
+// NewVersionedDB creates a VersionedDB over db, loading its saved versions.
+// Closing the VersionedDB closes db.
+func NewVersionedDB(db DB) (*VersionedDB, error) {
+	vdb := &VersionedDB{db: db}
+	var err error
+	if vdb.latest, err = vdb.loadVersion(versionedLatestKey); err != nil {
+		return nil, err
+	}
+	if vdb.earliest, err = vdb.loadVersion(versionedEarliestKey); err != nil {
+		return nil, err
+	}
+	return vdb, nil
+}
+
+func (vdb *VersionedDB) loadVersion(key []byte) (uint64, error) {
+	value, err := vdb.db.Get(key)
+	if err != nil || value == nil {
+		return 0, err
+	}
+	if len(value) != 8 {
+		return 0, fmt.Errorf("%w: invalid version %X", ErrCorrupted, value)
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// versionedKey returns the prefix of the entries of key, ordered as key. Zero
+// bytes are escaped as 0x00 0xFF and the key is terminated by 0x00 0x00, so that
+// no key prefix is a prefix of another.
+func versionedKey(key []byte) []byte {
+	ekey := make([]byte, 0, len(versionedDataPrefix)+len(key)+2)
+	ekey = append(ekey, versionedDataPrefix...)
+	ekey = versionedEscape(ekey, key)
+	return append(ekey, 0x00, 0x00)
+}
+
+// versionedEscape appends the escaped key to b, with no terminator, which
+// bounds the ranges of entries in the order of the keys.
+func versionedEscape(b, key []byte) []byte {
+	for _, c := range key {
+		if c == 0x00 {
+			b = append(b, 0x00, 0xFF)
+		} else {
+			b = append(b, c)
+		}
+	}
+	return b
+}
+
+// versionedEntryKey returns the key of the entry of key at version.
+func versionedEntryKey(key []byte, version uint64) []byte {
+	return binary.BigEndian.AppendUint64(versionedKey(key), version)
+}
+
+// versionedBound returns the bound of a key range in the entries, or nil for
+// an unbounded end.
+func versionedBound(key []byte) []byte {
+	return versionedEscape(cp(versionedDataPrefix), key)
+}
+
+// versionedDecodeKey returns the key and version of the key of an entry.
+func versionedDecodeKey(ekey []byte) ([]byte, uint64, error) {
+	escaped := ekey[len(versionedDataPrefix):]
+	key := make([]byte, 0, len(escaped))
+	for i := 0; i+1 < len(escaped); i++ {
+		switch {
+		case escaped[i] != 0x00:
+			key = append(key, escaped[i])
+		case escaped[i+1] == 0xFF:
+			key = append(key, 0x00)
+			i++
+		case escaped[i+1] == 0x00 && len(escaped) == i+2+8:
+			return key, binary.BigEndian.Uint64(escaped[i+2:]), nil
+		default:
+			return nil, 0, fmt.Errorf("%w: invalid versioned key %X", ErrCorrupted, ekey)
+		}
+	}
+	return nil, 0, fmt.Errorf("%w: invalid versioned key %X", ErrCorrupted, ekey)
+}
+
+// versionedEncode returns the value of an entry setting value, or deleting the
+// key if value is nil.
+func versionedEncode(value []byte) []byte {
+	if value == nil {
+		return []byte{versionedDeleted}
+	}
+	return append([]byte{versionedSet}, value...)
+}
+
+// Version returns the last saved version, or 0 if none was saved.
+func (vdb *VersionedDB) Version() uint64 {
+	vdb.mtx.RLock()
+	defer vdb.mtx.RUnlock()
+	return vdb.latest
+}
+
+// SaveVersion saves the working version, and returns it. Later writes go to the
+// version after it.
+func (vdb *VersionedDB) SaveVersion() (uint64, error) {
+	vdb.mtx.Lock()
+	defer vdb.mtx.Unlock()
+
+	version := vdb.latest + 1
+	if err := vdb.db.SetSync(versionedLatestKey, binary.BigEndian.AppendUint64(nil, version)); err != nil {
+		return 0, err
+	}
+	vdb.latest = version
+	return version, nil
+}
+
+// checkVersion returns an error if version cannot be read.
+func (vdb *VersionedDB) checkVersion(version uint64) error {
+	vdb.mtx.RLock()
+	defer vdb.mtx.RUnlock()
+	if version > vdb.latest {
+		return fmt.Errorf("%w: %d, latest is %d", ErrVersionNotSaved, version, vdb.latest)
+	}
+	if version < vdb.earliest {
+		return fmt.Errorf("%w: %d, earliest is %d", ErrVersionPruned, version, vdb.earliest)
+	}
+	return nil
+}
+
+// GetAt returns the value of key as of a saved version, or nil if it did not
+// exist. Version 0 is the empty database before the first saved version.
+func (vdb *VersionedDB) GetAt(version uint64, key []byte) ([]byte, error) {
+	if err := vdb.checkVersion(version); err != nil {
+		return nil, err
+	}
+	return vdb.get(version, key)
+}
+
+// get returns the value of key as of version.
+func (vdb *VersionedDB) get(version uint64, key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errKeyEmpty
+	}
+	end := versionedKey(key)
+	if version < math.MaxUint64 {
+		end = binary.BigEndian.AppendUint64(end, version+1)
+	} else {
+		end = prefixEnd(end)
+	}
+	itr, err := vdb.db.ReverseIterator(versionedKey(key), end)
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+	if !itr.Valid() {
+		return nil, itr.Error()
+	}
+	entry := itr.Value()
+	if len(entry) == 0 {
+		return nil, fmt.Errorf("%w: empty versioned entry of key %X", ErrCorrupted, key)
+	}
+	if entry[0] == versionedDeleted {
+		return nil, nil
+	}
+	return cp(entry[1:]), nil
+}
+
+// HasAt returns whether key existed as of a saved version.
+func (vdb *VersionedDB) HasAt(version uint64, key []byte) (bool, error) {
+	value, err := vdb.GetAt(version, key)
+	return value != nil, err
+}
+
+// IteratorAt returns an iterator over the domain [start, end) as of a saved
+// version.
+func (vdb *VersionedDB) IteratorAt(version uint64, start, end []byte) (Iterator, error) {
+	if err := vdb.checkVersion(version); err != nil {
+		return nil, err
+	}
+	return vdb.iterator(version, start, end, false)
+}
+
+// ReverseIteratorAt returns a reverse iterator over the domain [start, end) as
+// of a saved version.
+func (vdb *VersionedDB) ReverseIteratorAt(version uint64, start, end []byte) (Iterator, error) {
+	if err := vdb.checkVersion(version); err != nil {
+		return nil, err
+	}
+	return vdb.iterator(version, start, end, true)
+}
+
+func (vdb *VersionedDB) iterator(version uint64, start, end []byte, reverse bool) (Iterator, error) {
+	if (start != nil && len(start) == 0) || (end != nil && len(end) == 0) {
+		return nil, errKeyEmpty
+	}
+	dstart, dend := versionedBound(start), prefixEnd(versionedDataPrefix)
+	if end != nil {
+		dend = versionedBound(end)
+	}
+	var (
+		source Iterator
+		err    error
+	)
+	if reverse {
+		source, err = vdb.db.ReverseIterator(dstart, dend)
+	} else {
+		source, err = vdb.db.Iterator(dstart, dend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newVersionedDBIterator(source, version, start, end, reverse), nil
+}
+
+// Prune deletes the entries which are only needed to read the versions before
+// version, which then fail with ErrVersionPruned. Writes are blocked while each
+// chunk of entries is processed.
+func (vdb *VersionedDB) Prune(version uint64) error {
+	vdb.mtx.Lock()
+	if version > vdb.latest {
+		vdb.mtx.Unlock()
+		return fmt.Errorf("%w: %d, latest is %d", ErrVersionNotSaved, version, vdb.latest)
+	}
+	if version <= vdb.earliest {
+		vdb.mtx.Unlock()
+		return nil
+	}
+	// The pruned versions are recorded first, so that they are not read while
+	// their entries are deleted.
+	err := vdb.db.SetSync(versionedEarliestKey, binary.BigEndian.AppendUint64(nil, version))
+	if err == nil {
+		vdb.earliest = version
+	}
+	vdb.mtx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	start := cp(versionedDataPrefix)
+	for start != nil {
+		if start, err = vdb.prune(version, start); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prune deletes the entries up to version which are shadowed by a later one up
+// to version, or are the deletion of their key, for about
+// versionedPruneBatchSize entries from start. It returns where to continue, or
+// nil when done.
+func (vdb *VersionedDB) prune(version uint64, start []byte) ([]byte, error) {
+	vdb.mtx.Lock()
+	defer vdb.mtx.Unlock()
+
+	itr, err := vdb.db.Iterator(start, prefixEnd(versionedDataPrefix))
+	if err != nil {
+		return nil, err
+	}
+	var (
+		deletes [][]byte
+		next    []byte
+		key     []byte
+		last    []byte // the entry of key kept so far
+	)
+	for ; itr.Valid(); itr.Next() {
+		k, v, err := versionedDecodeKey(itr.Key())
+		if err != nil {
+			itr.Close()
+			return nil, err
+		}
+		if !bytes.Equal(k, key) {
+			if len(deletes) >= versionedPruneBatchSize {
+				next = cp(itr.Key())
+				break
+			}
+			key, last = k, nil
+		}
+		if v > version {
+			continue
+		}
+		if last != nil {
+			deletes = append(deletes, last)
+		}
+		last = cp(itr.Key())
+		if len(itr.Value()) == 0 || itr.Value()[0] == versionedDeleted {
+			deletes = append(deletes, last)
+			last = nil
+		}
+	}
+	err = itr.Error()
+	if cerr := itr.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(deletes) == 0 {
+		return next, nil
+	}
+	return next, deleteKeys(vdb.db, deletes)
+}
+
+// write writes the entries of ops to the working version.
+func (vdb *VersionedDB) write(ops []operation, sync bool) error {
+	vdb.mtx.RLock()
+	defer vdb.mtx.RUnlock()
+
+	batch := vdb.db.NewBatch()
+	defer batch.Close()
+	for _, op := range ops {
+		if err := batch.Set(versionedEntryKey(op.key, vdb.latest+1), versionedEncode(op.value)); err != nil {
+			return err
+		}
+	}
+	if sync {
+		return batch.WriteSync()
+	}
+	return batch.Write()
+}
+
+// Get implements DB, reading the working version.
+func (vdb *VersionedDB) Get(key []byte) ([]byte, error) {
+	return vdb.get(math.MaxUint64, key)
+}
+
+// Has implements DB, reading the working version.
+func (vdb *VersionedDB) Has(key []byte) (bool, error) {
+	value, err := vdb.Get(key)
+	return value != nil, err
+}
+
+// Set implements DB.
+func (vdb *VersionedDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return vdb.write([]operation{{opTypeSet, key, value}}, false)
+}
+
+// SetSync implements DB.
+func (vdb *VersionedDB) SetSync(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return vdb.write([]operation{{opTypeSet, key, value}}, true)
+}
+
+// Delete implements DB. The key remains in the versions before the working
+// one.
+func (vdb *VersionedDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return vdb.write([]operation{{opTypeDelete, key, nil}}, false)
+}
+
+// DeleteSync implements DB.
+func (vdb *VersionedDB) DeleteSync(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return vdb.write([]operation{{opTypeDelete, key, nil}}, true)
+}
+
+// Iterator implements DB, reading the working version.
+func (vdb *VersionedDB) Iterator(start, end []byte) (Iterator, error) {
+	return vdb.iterator(math.MaxUint64, start, end, false)
+}
+
+// ReverseIterator implements DB, reading the working version.
+func (vdb *VersionedDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return vdb.iterator(math.MaxUint64, start, end, true)
+}
+
+// Close implements DB.
+func (vdb *VersionedDB) Close() error {
+	return vdb.db.Close()
+}
+
+// NewBatch implements DB. The batch writes to the working version when it is
+// written.
+func (vdb *VersionedDB) NewBatch() Batch {
+	return newVersionedDBBatch(vdb)
+}
+
+// Print implements DB.
+func (vdb *VersionedDB) Print() error {
+	itr, err := vdb.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	for ; itr.Valid(); itr.Next() {
+		key := itr.Key()
+		value := itr.Value()
+		fmt.Printf("[%X]:\t[%X]\n", key, value)
+	}
+	return itr.Error()
+}
+
+// Stats implements DB. The stats of the underlying database are prefixed with
+// "versioned.db.".
+func (vdb *VersionedDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range vdb.db.Stats() {
+		stats["versioned.db."+k] = v
+	}
+	vdb.mtx.RLock()
+	defer vdb.mtx.RUnlock()
+	stats["versioned.latest"] = strconv.FormatUint(vdb.latest, 10)
+	stats["versioned.earliest"] = strconv.FormatUint(vdb.earliest, 10)
+	return stats
+}
@@ -0,0 +1,81 @@
+package db
+
+// versionedDBBatch buffers the writes of a batch of a VersionedDB, which are
+// written to the version which is the working one when the batch is written.
+type versionedDBBatch struct {
+	db  *VersionedDB
+	ops []operation
+}
+
+var _ Batch = (*versionedDBBatch)(nil)
+
+func newVersionedDBBatch(db *VersionedDB) *versionedDBBatch {
+	return &versionedDBBatch{
+		db:  db,
+		ops: []operation{},
+	}
+}
+
+// Set implements Batch.
+func (b *versionedDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *versionedDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	return nil
+}
+
+// Count implements Batch.
+func (b *versionedDBBatch) Count() int {
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *versionedDBBatch) SizeBytes() int {
+	return opsSize(b.ops)
+}
+
+// Write implements Batch.
+func (b *versionedDBBatch) Write() error {
+	return b.write(false)
+}
+
+// WriteSync implements Batch.
+func (b *versionedDBBatch) WriteSync() error {
+	return b.write(true)
+}
+
+func (b *versionedDBBatch) write(sync bool) error {
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	if err := b.db.write(b.ops, sync); err != nil {
+		return err
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// Close implements Batch.
+func (b *versionedDBBatch) Close() error {
+	b.ops = nil
+	return nil
+}
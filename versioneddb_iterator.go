@@ -0,0 +1,122 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// versionedDBIterator iterates over the keys of a VersionedDB as of a version,
+// by reading all the entries of each key and keeping the last one up to the
+// version.
+type versionedDBIterator struct {
+	source     Iterator
+	version    uint64
+	start, end []byte
+	reverse    bool
+
+	key   []byte
+	value []byte
+	err   error
+}
+
+var _ Iterator = (*versionedDBIterator)(nil)
+
+func newVersionedDBIterator(source Iterator, version uint64, start, end []byte, reverse bool) *versionedDBIterator {
+	itr := &versionedDBIterator{
+		source:  source,
+		version: version,
+		start:   start,
+		end:     end,
+		reverse: reverse,
+	}
+	itr.next()
+	return itr
+}
+
+// next moves to the next key live as of the version, or invalidates the
+// iterator.
+func (itr *versionedDBIterator) next() {
+	itr.key, itr.value = nil, nil
+	for itr.source.Valid() {
+		key, _, err := versionedDecodeKey(itr.source.Key())
+		if err != nil {
+			itr.err = err
+			return
+		}
+		// Forward, the entries of key come by increasing version and the last
+		// one up to the version wins; in reverse, the first one does.
+		var entry []byte
+		for itr.source.Valid() {
+			k, v, err := versionedDecodeKey(itr.source.Key())
+			if err != nil {
+				itr.err = err
+				return
+			}
+			if !bytes.Equal(k, key) {
+				break
+			}
+			if v <= itr.version && (entry == nil || !itr.reverse) {
+				entry = cp(itr.source.Value())
+			}
+			itr.source.Next()
+		}
+		if entry == nil {
+			continue
+		}
+		if len(entry) == 0 {
+			itr.err = fmt.Errorf("%w: empty versioned entry of key %X", ErrCorrupted, key)
+			return
+		}
+		if entry[0] == versionedSet {
+			itr.key, itr.value = key, entry[1:]
+			return
+		}
+	}
+}
+
+// Domain implements Iterator.
+func (itr *versionedDBIterator) Domain() (start, end []byte) {
+	return itr.start, itr.end
+}
+
+// Valid implements Iterator.
+func (itr *versionedDBIterator) Valid() bool {
+	return itr.key != nil
+}
+
+// Next implements Iterator.
+func (itr *versionedDBIterator) Next() {
+	itr.assertIsValid()
+	itr.next()
+}
+
+// Key implements Iterator.
+func (itr *versionedDBIterator) Key() []byte {
+	itr.assertIsValid()
+	return itr.key
+}
+
+// Value implements Iterator.
+func (itr *versionedDBIterator) Value() []byte {
+	itr.assertIsValid()
+	return itr.value
+}
+
+// Error implements Iterator.
+func (itr *versionedDBIterator) Error() error {
+	if itr.err != nil {
+		return itr.err
+	}
+	return itr.source.Error()
+}
+
+// Close implements Iterator.
+func (itr *versionedDBIterator) Close() error {
+	return itr.source.Close()
+}
+
+func (itr *versionedDBIterator) assertIsValid() {
+	if !itr.Valid() {
+		panic("iterator is invalid")
+	}
+}
@@ -0,0 +1,127 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionedDB(t *testing.T) {
+	vdb, err := NewVersionedDB(NewMemDB())
+	require.NoError(t, err)
+	defer vdb.Close()
+
+	// Version 1.
+	require.NoError(t, vdb.Set(bz("a"), bz("1")))
+	require.NoError(t, vdb.Set([]byte{'b', 0}, bz("1")))
+	require.NoError(t, vdb.Set(bz("c"), bz("1")))
+	version, err := vdb.SaveVersion()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, version)
+
+	// Version 2, and unsaved writes in the working version 3.
+	require.NoError(t, vdb.Set(bz("a"), bz("2")))
+	require.NoError(t, vdb.Delete(bz("c")))
+	require.NoError(t, vdb.Set(bz("b"), bz("2")))
+	_, err = vdb.SaveVersion()
+	require.NoError(t, err)
+	batch := vdb.NewBatch()
+	require.NoError(t, batch.Set(bz("a"), bz("3")))
+	require.NoError(t, batch.Delete(bz("b")))
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+	assert.EqualValues(t, 2, vdb.Version())
+
+	value, err := vdb.GetAt(1, bz("a"))
+	require.NoError(t, err)
+	assert.Equal(t, bz("1"), value)
+	value, err = vdb.GetAt(2, bz("c"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+	value, err = vdb.GetAt(0, bz("a"))
+	require.NoError(t, err)
+	assert.Nil(t, value)
+	checkValue(t, vdb, bz("a"), bz("3"))
+	checkValue(t, vdb, bz("b"), nil)
+	_, err = vdb.GetAt(3, bz("a"))
+	assert.ErrorIs(t, err, ErrVersionNotSaved)
+
+	itr, err := vdb.IteratorAt(1, nil, nil)
+	require.NoError(t, err)
+	checkItem(t, itr, bz("a"), bz("1"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, []byte{'b', 0}, bz("1"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("c"), bz("1"))
+	checkNext(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	itr, err = vdb.ReverseIteratorAt(2, bz("a"), bz("c"))
+	require.NoError(t, err)
+	checkItem(t, itr, []byte{'b', 0}, bz("1"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("b"), bz("2"))
+	checkNext(t, itr, true)
+	checkItem(t, itr, bz("a"), bz("2"))
+	checkNext(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	itr, err = vdb.Iterator(bz("b"), nil)
+	require.NoError(t, err)
+	checkItem(t, itr, []byte{'b', 0}, bz("1"))
+	checkNext(t, itr, false)
+	require.NoError(t, itr.Close())
+
+	// Pruning keeps the entries needed by the later versions.
+	require.NoError(t, vdb.Prune(2))
+	_, err = vdb.GetAt(1, bz("a"))
+	assert.ErrorIs(t, err, ErrVersionPruned)
+	_, err = vdb.IteratorAt(1, nil, nil)
+	assert.ErrorIs(t, err, ErrVersionPruned)
+	value, err = vdb.GetAt(2, bz("a"))
+	require.NoError(t, err)
+	assert.Equal(t, bz("2"), value)
+	value, err = vdb.GetAt(2, []byte{'b', 0})
+	require.NoError(t, err)
+	assert.Equal(t, bz("1"), value)
+	assert.ErrorIs(t, vdb.Prune(3), ErrVersionNotSaved)
+
+	// The entries of a, b at versions 2 and 3, and b\x00 are left.
+	count := 0
+	itr, err = vdb.db.Iterator(versionedDataPrefix, prefixEnd(versionedDataPrefix))
+	require.NoError(t, err)
+	for ; itr.Valid(); itr.Next() {
+		count++
+	}
+	require.NoError(t, itr.Close())
+	assert.Equal(t, 5, count)
+}
+
+func TestVersionedDBReopen(t *testing.T) {
+	dir := t.TempDir()
+	db, err := NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	vdb, err := NewVersionedDB(db)
+	require.NoError(t, err)
+	require.NoError(t, vdb.Set(bz("a"), bz("1")))
+	_, err = vdb.SaveVersion()
+	require.NoError(t, err)
+	require.NoError(t, vdb.Set(bz("a"), bz("2")))
+	_, err = vdb.SaveVersion()
+	require.NoError(t, err)
+	require.NoError(t, vdb.Prune(2))
+	require.NoError(t, vdb.Close())
+
+	db, err = NewGoLevelDB("db", dir)
+	require.NoError(t, err)
+	vdb, err = NewVersionedDB(db)
+	require.NoError(t, err)
+	defer vdb.Close()
+	assert.EqualValues(t, 2, vdb.Version())
+	_, err = vdb.GetAt(1, bz("a"))
+	assert.ErrorIs(t, err, ErrVersionPruned)
+	value, err := vdb.GetAt(2, bz("a"))
+	require.NoError(t, err)
+	assert.Equal(t, bz("2"), value)
+}
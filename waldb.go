@@ -0,0 +1,467 @@
+package db
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// defaultWALSegmentSize is the size beyond which a WALDB starts a new log
+	// segment, if not given.
+	defaultWALSegmentSize = 64 << 20
+
+	// walSegmentExt is the extension of the log segment files, named by their
+	// sequence number.
+	walSegmentExt = ".wal"
+
+	// walRecordHeaderSize is the size of the header of a log record: the
+	// length and the CRC-32C of the payload.
+	walRecordHeaderSize = 8
+
+	// walCompactBatchSize is the size of the records written by CompactLog.
+	walCompactBatchSize = 1 << 20
+)
+
+var errWALRecordInvalid = fmt.Errorf("invalid write-ahead log record: %w", ErrCorrupted)
+
+// WALDB gives crash durability to a non-durable database, such as MemDB, by
+// appending every write to a log before applying it. The log is replayed into
+// the database when the WALDB is created, so the database should be empty then.
+// Writes reach the operating system before they are applied, so they survive
+// crashes of the process; SetSync, DeleteSync and Batch.WriteSync also sync the
+// log to disk. Reads are served by the database.
+//
+// The log is a sequence of segment files in a directory, each a sequence of
+// records with one write each: a single operation or a batch, so batches are
+// replayed atomically. A new segment is started when the current one grows
+// beyond the segment size. CompactLog replaces the segments by the current
+// contents of the database, so that the log does not grow without bounds.
+type WALDB struct {
+	mtx         sync.Mutex // serializes writes, so that they are applied in log order
+	db          DB
+	dir         string
+	segmentSize int64
+
+	segments []uint64 // sequence numbers of the segments, the last one being written
+	file     *os.File
+	size     int64 // size of the current segment
+}
+
+var _ DB = (*WALDB)(nil)
+
+// NewWALDB creates a WALDB over db logging to dir, which is created if needed,
+// and replays the existing log into db. A log record torn by a crash at the end
+// of the log is discarded. A segmentSize of 0 uses a default of 64 MiB. Closing
+// the WALDB closes db.
+func NewWALDB(db DB, dir string, segmentSize int64) (*WALDB, error) {
+	if segmentSize <= 0 {
+		segmentSize = defaultWALSegmentSize
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	wdb := &WALDB{db: db, dir: dir, segmentSize: segmentSize}
+	segments, err := walSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i, seq := range segments {
+		if err := wdb.replay(seq, i == len(segments)-1); err != nil {
+			return nil, err
+		}
+	}
+	wdb.segments = segments
+	if err := wdb.rotate(); err != nil {
+		return nil, err
+	}
+	return wdb, nil
+}
+
+// walSegments returns the sequence numbers of the segments in dir, in order.
+func walSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, walSegmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, walSegmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, seq)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i] < segments[j] })
+	return segments, nil
+}
+
+func (wdb *WALDB) segmentPath(seq uint64) string {
+	return filepath.Join(wdb.dir, fmt.Sprintf("%020d%s", seq, walSegmentExt))
+}
+
+// replay applies the records of a segment to the database. If last is set, an
+// invalid record ends the log, and the segment is truncated before it.
+func (wdb *WALDB) replay(seq uint64, last bool) error {
+	path := wdb.segmentPath(seq)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	offset := 0
+	for offset < len(data) {
+		ops, n, err := walDecodeRecord(data[offset:])
+		if err != nil {
+			if last {
+				return os.Truncate(path, int64(offset))
+			}
+			return fmt.Errorf("%w in %s at offset %d", err, path, offset)
+		}
+		if err := walApply(wdb.db, ops, false); err != nil {
+			return err
+		}
+		offset += n
+	}
+	return nil
+}
+
+// rotate starts a new segment, after syncing the current one.
+func (wdb *WALDB) rotate() error {
+	if wdb.file != nil {
+		if err := wdb.file.Sync(); err != nil {
+			return err
+		}
+		if err := wdb.file.Close(); err != nil {
+			return err
+		}
+		wdb.file = nil
+	}
+	var seq uint64 = 1
+	if len(wdb.segments) > 0 {
+		seq = wdb.segments[len(wdb.segments)-1] + 1
+	}
+	file, err := os.OpenFile(wdb.segmentPath(seq), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	wdb.segments = append(wdb.segments, seq)
+	wdb.file = file
+	wdb.size = 0
+	return nil
+}
+
+// walEncodeRecord returns the log record of ops: the header, followed by each
+// operation as its type, the uvarint length of the key, the key and, for sets,
+// the uvarint length of the value and the value.
+func walEncodeRecord(ops []operation) []byte {
+	record := make([]byte, walRecordHeaderSize, walRecordHeaderSize+opsSize(ops)+len(ops)*(1+2*binary.MaxVarintLen32))
+	for _, op := range ops {
+		record = append(record, byte(op.opType))
+		record = binary.AppendUvarint(record, uint64(len(op.key)))
+		record = append(record, op.key...)
+		if op.opType == opTypeSet {
+			record = binary.AppendUvarint(record, uint64(len(op.value)))
+			record = append(record, op.value...)
+		}
+	}
+	payload := record[walRecordHeaderSize:]
+	binary.BigEndian.PutUint32(record, uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:], crc32.Checksum(payload, backupStreamCRCTable))
+	return record
+}
+
+// walDecodeRecord decodes the log record at the start of data, and returns its
+// operations and size.
+func walDecodeRecord(data []byte) ([]operation, int, error) {
+	if len(data) < walRecordHeaderSize {
+		return nil, 0, errWALRecordInvalid
+	}
+	size := int(binary.BigEndian.Uint32(data))
+	if len(data)-walRecordHeaderSize < size {
+		return nil, 0, errWALRecordInvalid
+	}
+	payload := data[walRecordHeaderSize : walRecordHeaderSize+size]
+	if crc32.Checksum(payload, backupStreamCRCTable) != binary.BigEndian.Uint32(data[4:]) {
+		return nil, 0, errWALRecordInvalid
+	}
+	var ops []operation
+	for len(payload) > 0 {
+		op := operation{opType: opType(payload[0])}
+		payload = payload[1:]
+		var ok bool
+		if op.key, payload, ok = walDecodeBytes(payload); !ok {
+			return nil, 0, errWALRecordInvalid
+		}
+		switch op.opType {
+		case opTypeSet:
+			if op.value, payload, ok = walDecodeBytes(payload); !ok {
+				return nil, 0, errWALRecordInvalid
+			}
+		case opTypeDelete:
+		default:
+			return nil, 0, errWALRecordInvalid
+		}
+		ops = append(ops, op)
+	}
+	return ops, walRecordHeaderSize + size, nil
+}
+
+// walDecodeBytes decodes a uvarint length and the bytes following it.
+func walDecodeBytes(data []byte) (b, rest []byte, ok bool) {
+	n, size := binary.Uvarint(data)
+	if size <= 0 || uint64(len(data)-size) < n {
+		return nil, nil, false
+	}
+	return data[size : size+int(n)], data[size+int(n):], true
+}
+
+// walApply applies ops to db in a batch.
+func walApply(db DB, ops []operation, sync bool) error {
+	batch := db.NewBatch()
+	defer batch.Close()
+	for _, op := range ops {
+		var err error
+		if op.opType == opTypeSet {
+			err = batch.Set(op.key, op.value)
+		} else {
+			err = batch.Delete(op.key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if sync {
+		return batch.WriteSync()
+	}
+	return batch.Write()
+}
+
+// write logs ops and applies them to the database.
+func (wdb *WALDB) write(ops []operation, sync bool) error {
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+
+	if wdb.file == nil {
+		return ErrClosed
+	}
+	if wdb.size >= wdb.segmentSize {
+		if err := wdb.rotate(); err != nil {
+			return err
+		}
+	}
+	record := walEncodeRecord(ops)
+	n, err := wdb.file.Write(record)
+	wdb.size += int64(n)
+	if err != nil {
+		return err
+	}
+	if sync {
+		if err := wdb.file.Sync(); err != nil {
+			return err
+		}
+	}
+	return walApply(wdb.db, ops, false)
+}
+
+// CompactLog replaces the log by a new segment with the current contents of
+// the database, and deletes the previous segments. Writes are blocked
+// meanwhile. If it fails, the previous segments are kept and the log remains
+// valid.
+func (wdb *WALDB) CompactLog() error {
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+
+	if wdb.file == nil {
+		return ErrClosed
+	}
+	previous := wdb.segments
+	if err := wdb.rotate(); err != nil {
+		return err
+	}
+	// Replaying the previous segments and then the new one gives the same
+	// contents as the new one alone, so a crash at any point is harmless.
+	if err := wdb.writeContents(); err != nil {
+		return err
+	}
+	if err := wdb.file.Sync(); err != nil {
+		return err
+	}
+	for _, seq := range previous {
+		if err := os.Remove(wdb.segmentPath(seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	wdb.segments = wdb.segments[len(previous):]
+	return nil
+}
+
+// writeContents writes the contents of the database to the current segment, in
+// records of about walCompactBatchSize bytes.
+func (wdb *WALDB) writeContents() error {
+	itr, err := wdb.db.Iterator(nil, nil)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+	var (
+		ops     []operation
+		pending int
+	)
+	flush := func() error {
+		n, err := wdb.file.Write(walEncodeRecord(ops))
+		wdb.size += int64(n)
+		ops, pending = ops[:0], 0
+		return err
+	}
+	for ; itr.Valid(); itr.Next() {
+		key, value := cp(itr.Key()), cp(itr.Value())
+		ops = append(ops, operation{opTypeSet, key, value})
+		pending += len(key) + len(value)
+		if pending >= walCompactBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := itr.Error(); err != nil {
+		return err
+	}
+	if len(ops) > 0 {
+		return flush()
+	}
+	return nil
+}
+
+// Get implements DB.
+func (wdb *WALDB) Get(key []byte) ([]byte, error) {
+	return wdb.db.Get(key)
+}
+
+// Has implements DB.
+func (wdb *WALDB) Has(key []byte) (bool, error) {
+	return wdb.db.Has(key)
+}
+
+// Set implements DB.
+func (wdb *WALDB) Set(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return wdb.write([]operation{{opTypeSet, key, value}}, false)
+}
+
+// SetSync implements DB.
+func (wdb *WALDB) SetSync(key []byte, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	return wdb.write([]operation{{opTypeSet, key, value}}, true)
+}
+
+// Delete implements DB.
+func (wdb *WALDB) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return wdb.write([]operation{{opTypeDelete, key, nil}}, false)
+}
+
+// DeleteSync implements DB.
+func (wdb *WALDB) DeleteSync(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	return wdb.write([]operation{{opTypeDelete, key, nil}}, true)
+}
+
+// Iterator implements DB.
+func (wdb *WALDB) Iterator(start, end []byte) (Iterator, error) {
+	return wdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (wdb *WALDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return wdb.db.ReverseIterator(start, end)
+}
+
+// Close implements DB. It syncs and closes the log, and closes the database.
+func (wdb *WALDB) Close() error {
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+
+	if wdb.file != nil {
+		err := wdb.file.Sync()
+		if cerr := wdb.file.Close(); err == nil {
+			err = cerr
+		}
+		wdb.file = nil
+		if err != nil {
+			wdb.db.Close()
+			return err
+		}
+	}
+	return wdb.db.Close()
+}
+
+// NewBatch implements DB. The batch is logged as a single record when it is
+// written.
+func (wdb *WALDB) NewBatch() Batch {
+	return newWALDBBatch(wdb)
+}
+
+// Print implements DB.
+func (wdb *WALDB) Print() error {
+	return wdb.db.Print()
+}
+
+// Stats implements DB. The stats of the underlying database are prefixed with
+// "wal.db.".
+func (wdb *WALDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range wdb.db.Stats() {
+		stats["wal.db."+k] = v
+	}
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	stats["wal.segments"] = strconv.Itoa(len(wdb.segments))
+	stats["wal.segment_size"] = strconv.FormatInt(wdb.size, 10)
+	return stats
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+func (wdb *WALDB) Snapshot() (DBReader, error) {
+	return Snapshot(wdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (wdb *WALDB) EstimateCount() (uint64, error) {
+	return EstimateCount(wdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (wdb *WALDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(wdb.db, start, end)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which WALDB passes through.
+func (wdb *WALDB) Capabilities() Capability {
+	return Capabilities(wdb.db) & CapSnapshot
+}
@@ -0,0 +1,83 @@
+package db
+
+// walDBBatch buffers the writes of a batch of a WALDB, which are logged as a
+// single record when the batch is written.
+type walDBBatch struct {
+	db  *WALDB
+	ops []operation
+}
+
+var _ Batch = (*walDBBatch)(nil)
+
+func newWALDBBatch(db *WALDB) *walDBBatch {
+	return &walDBBatch{
+		db:  db,
+		ops: []operation{},
+	}
+}
+
+// Set implements Batch.
+func (b *walDBBatch) Set(key, value []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if value == nil {
+		return errValueNil
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *walDBBatch) Delete(key []byte) error {
+	if len(key) == 0 {
+		return errKeyEmpty
+	}
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	return nil
+}
+
+// Count implements Batch.
+func (b *walDBBatch) Count() int {
+	return len(b.ops)
+}
+
+// SizeBytes implements Batch.
+func (b *walDBBatch) SizeBytes() int {
+	return opsSize(b.ops)
+}
+
+// Write implements Batch.
+func (b *walDBBatch) Write() error {
+	return b.write(false)
+}
+
+// WriteSync implements Batch.
+func (b *walDBBatch) WriteSync() error {
+	return b.write(true)
+}
+
+func (b *walDBBatch) write(sync bool) error {
+	if b.ops == nil {
+		return ErrBatchWritten
+	}
+	if len(b.ops) > 0 {
+		if err := b.db.write(b.ops, sync); err != nil {
+			return err
+		}
+	}
+	// Make sure batch cannot be used afterwards. Callers should still call Close(), for errors.
+	return b.Close()
+}
+
+// Close implements Batch.
+func (b *walDBBatch) Close() error {
+	b.ops = nil
+	return nil
+}
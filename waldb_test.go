@@ -0,0 +1,101 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWALDB(t *testing.T) {
+	dir := t.TempDir()
+	wdb, err := NewWALDB(NewMemDB(), dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, wdb.Set(bz("a"), bz("1")))
+	require.NoError(t, wdb.SetSync(bz("b"), bz("2")))
+	require.NoError(t, wdb.Set(bz("c"), []byte{}))
+	require.NoError(t, wdb.Delete(bz("a")))
+	batch := wdb.NewBatch()
+	require.NoError(t, batch.Set(bz("d"), bz("4")))
+	require.NoError(t, batch.Delete(bz("b")))
+	require.NoError(t, batch.WriteSync())
+	require.NoError(t, batch.Close())
+	require.NoError(t, wdb.Close())
+	assert.ErrorIs(t, wdb.Set(bz("a"), bz("1")), ErrClosed)
+
+	// The log is replayed into a new database.
+	wdb, err = NewWALDB(NewMemDB(), dir, 0)
+	require.NoError(t, err)
+	defer wdb.Close()
+	checkValue(t, wdb, bz("a"), nil)
+	checkValue(t, wdb, bz("b"), nil)
+	checkValue(t, wdb, bz("c"), []byte{})
+	checkValue(t, wdb, bz("d"), bz("4"))
+	assert.Equal(t, "2", wdb.Stats()["wal.segments"])
+}
+
+func TestWALDBTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	wdb, err := NewWALDB(NewMemDB(), dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, wdb.Set(bz("a"), bz("1")))
+	require.NoError(t, wdb.Set(bz("b"), bz("2")))
+	path := wdb.segmentPath(wdb.segments[0])
+	require.NoError(t, wdb.Close())
+
+	// A record torn at the end of the log is discarded, and truncated so that
+	// later replays succeed.
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-1))
+	for i := 0; i < 2; i++ {
+		wdb, err = NewWALDB(NewMemDB(), dir, 0)
+		require.NoError(t, err)
+		checkValue(t, wdb, bz("a"), bz("1"))
+		checkValue(t, wdb, bz("b"), nil)
+		require.NoError(t, wdb.Close())
+	}
+
+	// Corruption before the end of the log fails the replay.
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xFF
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	_, err = NewWALDB(NewMemDB(), dir, 0)
+	assert.ErrorIs(t, err, ErrCorrupted)
+}
+
+func TestWALDBSegments(t *testing.T) {
+	dir := t.TempDir()
+	wdb, err := NewWALDB(NewMemDB(), dir, 64)
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, wdb.Set([]byte{byte(i)}, bz("value")))
+	}
+	for i := 0; i < 50; i++ {
+		require.NoError(t, wdb.Delete([]byte{byte(i)}))
+	}
+	segments, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	require.NoError(t, err)
+	assert.Greater(t, len(segments), 10)
+
+	// Compaction replaces the segments by the contents of the database.
+	require.NoError(t, wdb.CompactLog())
+	segments, err = filepath.Glob(filepath.Join(dir, "*.wal"))
+	require.NoError(t, err)
+	assert.Len(t, segments, 1)
+	require.NoError(t, wdb.Set(bz("x"), bz("y")))
+	require.NoError(t, wdb.Close())
+
+	wdb, err = NewWALDB(NewMemDB(), dir, 64)
+	require.NoError(t, err)
+	defer wdb.Close()
+	count, err := EstimateCount(wdb)
+	require.NoError(t, err)
+	assert.EqualValues(t, 51, count)
+	checkValue(t, wdb, []byte{49}, nil)
+	checkValue(t, wdb, []byte{50}, bz("value"))
+	checkValue(t, wdb, bz("x"), bz("y"))
+}
@@ -0,0 +1,296 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"sync"
+)
+
+// watchBufferSize is the number of events buffered for each watcher of a
+// WatchDB. A watcher falling further behind is dropped.
+const watchBufferSize = 1024
+
+// EventType is the type of a change of a key.
+type EventType byte
+
+const (
+	// EventSet is the setting of a key to a value.
+	EventSet EventType = iota + 1
+	// EventDelete is the deletion of a key.
+	EventDelete
+)
+
+// String implements fmt.Stringer.
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change of a key of a WatchDB.
+type Event struct {
+	Type  EventType
+	Key   []byte
+	Value []byte // nil for EventDelete
+}
+
+// WatchDB notifies watchers of the changes committed to a database, e.g. so that
+// indexers do not need to poll it with iterators. Every successful Set, Delete,
+// CompareAndSwap and batch write is sent as events to the watchers of the
+// prefixes of the changed keys, in commit order: writes are serialized to that
+// end. The writes of a batch are sent together, in the order they were added.
+//
+// A watcher whose buffer of 1024 events is full when an event is sent
+// is dropped, by closing its channel, rather than blocking the writes: it
+// should then watch again and catch up by reading the database.
+type WatchDB struct {
+	db DB
+
+	mtx      sync.Mutex // serializes writes, so that events are sent in order
+	watchers map[<-chan Event]*watcher
+	closed   bool
+	dropped  uint64
+}
+
+type watcher struct {
+	prefix []byte
+	ch     chan Event
+}
+
+var _ DB = (*WatchDB)(nil)
+
+// NewWatchDB wraps db, notifying watchers of its changes. Closing the WatchDB
+// closes db and the channels of the watchers.
+func NewWatchDB(db DB) *WatchDB {
+	return &WatchDB{db: db, watchers: make(map[<-chan Event]*watcher)}
+}
+
+// Watch returns a channel of the events of the keys starting with prefix,
+// committed from now on. An empty prefix watches all keys. The channel is
+// closed by Unwatch, by Close, or if the watcher falls behind.
+func (wdb *WatchDB) Watch(prefix []byte) <-chan Event {
+	w := &watcher{prefix: cp(prefix), ch: make(chan Event, watchBufferSize)}
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	if wdb.closed {
+		close(w.ch)
+	} else {
+		wdb.watchers[w.ch] = w
+	}
+	return w.ch
+}
+
+// Unwatch stops sending events to a channel returned by Watch, and closes it.
+// Events buffered before may still be received.
+func (wdb *WatchDB) Unwatch(ch <-chan Event) {
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	if w, ok := wdb.watchers[ch]; ok {
+		delete(wdb.watchers, ch)
+		close(w.ch)
+	}
+}
+
+// publish sends the events of ops to the watchers, dropping those falling
+// behind. The caller must hold mtx.
+func (wdb *WatchDB) publish(ops []operation) {
+	for ch, w := range wdb.watchers {
+		if !w.send(ops) {
+			delete(wdb.watchers, ch)
+			close(w.ch)
+			wdb.dropped++
+		}
+	}
+}
+
+// send sends the events of the ops on keys with the prefix of the watcher, and
+// reports whether its buffer had room for them.
+func (w *watcher) send(ops []operation) bool {
+	for _, op := range ops {
+		if !bytes.HasPrefix(op.key, w.prefix) {
+			continue
+		}
+		event := Event{Type: EventDelete, Key: cp(op.key)}
+		if op.opType == opTypeSet {
+			event.Type, event.Value = EventSet, cp(op.value)
+		}
+		select {
+		case w.ch <- event:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// write applies a single operation with fn, and publishes it if it succeeds.
+func (wdb *WatchDB) write(op operation, fn func() error) error {
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	if err := fn(); err != nil {
+		return err
+	}
+	wdb.publish([]operation{op})
+	return nil
+}
+
+// Get implements DB.
+func (wdb *WatchDB) Get(key []byte) ([]byte, error) {
+	return wdb.db.Get(key)
+}
+
+// Has implements DB.
+func (wdb *WatchDB) Has(key []byte) (bool, error) {
+	return wdb.db.Has(key)
+}
+
+// Set implements DB.
+func (wdb *WatchDB) Set(key []byte, value []byte) error {
+	return wdb.write(operation{opTypeSet, key, value}, func() error {
+		return wdb.db.Set(key, value)
+	})
+}
+
+// SetSync implements DB.
+func (wdb *WatchDB) SetSync(key []byte, value []byte) error {
+	return wdb.write(operation{opTypeSet, key, value}, func() error {
+		return wdb.db.SetSync(key, value)
+	})
+}
+
+// Delete implements DB.
+func (wdb *WatchDB) Delete(key []byte) error {
+	return wdb.write(operation{opTypeDelete, key, nil}, func() error {
+		return wdb.db.Delete(key)
+	})
+}
+
+// DeleteSync implements DB.
+func (wdb *WatchDB) DeleteSync(key []byte) error {
+	return wdb.write(operation{opTypeDelete, key, nil}, func() error {
+		return wdb.db.DeleteSync(key)
+	})
+}
+
+// CompareAndSwap implements ConditionalWriter, with the conditional write of
+// the underlying database. Only successful swaps are sent.
+func (wdb *WatchDB) CompareAndSwap(key, old, new []byte) (bool, error) {
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	swapped, err := CompareAndSwap(wdb.db, key, old, new)
+	if swapped {
+		wdb.publish([]operation{{opTypeSet, key, new}})
+	}
+	return swapped, err
+}
+
+// Iterator implements DB.
+func (wdb *WatchDB) Iterator(start, end []byte) (Iterator, error) {
+	return wdb.db.Iterator(start, end)
+}
+
+// ReverseIterator implements DB.
+func (wdb *WatchDB) ReverseIterator(start, end []byte) (Iterator, error) {
+	return wdb.db.ReverseIterator(start, end)
+}
+
+// Close implements DB. It closes the channels of the watchers.
+func (wdb *WatchDB) Close() error {
+	wdb.mtx.Lock()
+	if !wdb.closed {
+		wdb.closed = true
+		for ch, w := range wdb.watchers {
+			delete(wdb.watchers, ch)
+			close(w.ch)
+		}
+	}
+	wdb.mtx.Unlock()
+	return wdb.db.Close()
+}
+
+// NewBatch implements DB. The events of the batch are sent when it is written.
+func (wdb *WatchDB) NewBatch() Batch {
+	return &watchDBBatch{db: wdb, batch: wdb.db.NewBatch()}
+}
+
+// Print implements DB.
+func (wdb *WatchDB) Print() error {
+	return wdb.db.Print()
+}
+
+// Stats implements DB. The stats of the underlying database are prefixed with
+// "watch.db.".
+func (wdb *WatchDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for k, v := range wdb.db.Stats() {
+		stats["watch.db."+k] = v
+	}
+	wdb.mtx.Lock()
+	defer wdb.mtx.Unlock()
+	stats["watch.watchers"] = strconv.Itoa(len(wdb.watchers))
+	stats["watch.dropped"] = strconv.FormatUint(wdb.dropped, 10)
+	return stats
+}
+
+// Snapshot implements Snapshotter, if the underlying database supports it.
+func (wdb *WatchDB) Snapshot() (DBReader, error) {
+	return Snapshot(wdb.db)
+}
+
+// Metrics implements MetricsReporter, if the underlying database supports it.
+func (wdb *WatchDB) Metrics() (Metrics, error) {
+	return GetMetrics(wdb.db)
+}
+
+// EstimateCount implements CountEstimator.
+func (wdb *WatchDB) EstimateCount() (uint64, error) {
+	return EstimateCount(wdb.db)
+}
+
+// EstimateSize implements SizeEstimator.
+func (wdb *WatchDB) EstimateSize(start, end []byte) (uint64, error) {
+	return EstimateSize(wdb.db, start, end)
+}
+
+// Compact implements Compacter.
+func (wdb *WatchDB) Compact(start, end []byte) error {
+	return Compact(wdb.db, start, end)
+}
+
+// Flush implements Flusher, if the underlying database supports it.
+func (wdb *WatchDB) Flush() error {
+	return Flush(wdb.db)
+}
+
+// Sync implements Syncer, if the underlying database supports it.
+func (wdb *WatchDB) Sync() error {
+	return Sync(wdb.db)
+}
+
+// Backup implements Backupper, if the underlying database supports it.
+func (wdb *WatchDB) Backup(ctx context.Context, targetDir string) error {
+	return Backup(ctx, wdb.db, targetDir)
+}
+
+// Checkpoint implements Checkpointer, if the underlying database supports it.
+func (wdb *WatchDB) Checkpoint(path string) error {
+	return Checkpoint(wdb.db, path)
+}
+
+// HealthCheck implements HealthChecker, checking the underlying database.
+func (wdb *WatchDB) HealthCheck(ctx context.Context) error {
+	return HealthCheck(ctx, wdb.db)
+}
+
+// Capabilities implements CapabilityReporter, with the features of the
+// underlying database which WatchDB passes through. Range deletions are not
+// passed through, since their keys are unknown.
+func (wdb *WatchDB) Capabilities() Capability {
+	return Capabilities(wdb.db) & (CapSnapshot | CapCompact | CapFlush | CapSync | CapBackup | CapCheckpoint)
+}
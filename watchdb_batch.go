@@ -0,0 +1,66 @@
+package db
+
+// watchDBBatch records the writes of a batch of the underlying database, to
+// send their events when it is written.
+type watchDBBatch struct {
+	db    *WatchDB
+	batch Batch
+	ops   []operation
+}
+
+var _ Batch = (*watchDBBatch)(nil)
+
+// Set implements Batch.
+func (b *watchDBBatch) Set(key, value []byte) error {
+	if err := b.batch.Set(key, value); err != nil {
+		return err
+	}
+	b.ops = append(b.ops, operation{opTypeSet, key, value})
+	return nil
+}
+
+// Delete implements Batch.
+func (b *watchDBBatch) Delete(key []byte) error {
+	if err := b.batch.Delete(key); err != nil {
+		return err
+	}
+	b.ops = append(b.ops, operation{opTypeDelete, key, nil})
+	return nil
+}
+
+// Count implements Batch.
+func (b *watchDBBatch) Count() int {
+	return b.batch.Count()
+}
+
+// SizeBytes implements Batch.
+func (b *watchDBBatch) SizeBytes() int {
+	return b.batch.SizeBytes()
+}
+
+// Write implements Batch.
+func (b *watchDBBatch) Write() error {
+	return b.write(b.batch.Write)
+}
+
+// WriteSync implements Batch.
+func (b *watchDBBatch) WriteSync() error {
+	return b.write(b.batch.WriteSync)
+}
+
+func (b *watchDBBatch) write(write func() error) error {
+	b.db.mtx.Lock()
+	defer b.db.mtx.Unlock()
+	if err := write(); err != nil {
+		return err
+	}
+	b.db.publish(b.ops)
+	b.ops = nil
+	return nil
+}
+
+// Close implements Batch.
+func (b *watchDBBatch) Close() error {
+	b.ops = nil
+	return b.batch.Close()
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchDB(t *testing.T) {
+	wdb := NewWatchDB(NewMemDB())
+	all := wdb.Watch(nil)
+	a := wdb.Watch(bz("a"))
+
+	require.NoError(t, wdb.Set(bz("a1"), bz("1")))
+	require.NoError(t, wdb.SetSync(bz("b1"), bz("2")))
+	require.NoError(t, wdb.Delete(bz("a1")))
+	swapped, err := wdb.CompareAndSwap(bz("a2"), nil, bz("3"))
+	require.NoError(t, err)
+	require.True(t, swapped)
+	swapped, err = wdb.CompareAndSwap(bz("a2"), nil, bz("4"))
+	require.NoError(t, err)
+	require.False(t, swapped)
+
+	// The events of a batch are only sent when it is written.
+	batch := wdb.NewBatch()
+	require.NoError(t, batch.Set(bz("a3"), bz("5")))
+	require.NoError(t, batch.Delete(bz("b1")))
+	assert.Len(t, all, 4)
+	require.NoError(t, batch.Write())
+	require.NoError(t, batch.Close())
+
+	// Failed writes are not sent.
+	assert.Error(t, wdb.Set(nil, bz("6")))
+
+	assert.Equal(t, []Event{
+		{Type: EventSet, Key: bz("a1"), Value: bz("1")},
+		{Type: EventDelete, Key: bz("a1")},
+		{Type: EventSet, Key: bz("a2"), Value: bz("3")},
+		{Type: EventSet, Key: bz("a3"), Value: bz("5")},
+	}, drainEvents(a))
+	assert.Equal(t, []Event{
+		{Type: EventSet, Key: bz("a1"), Value: bz("1")},
+		{Type: EventSet, Key: bz("b1"), Value: bz("2")},
+		{Type: EventDelete, Key: bz("a1")},
+		{Type: EventSet, Key: bz("a2"), Value: bz("3")},
+		{Type: EventSet, Key: bz("a3"), Value: bz("5")},
+		{Type: EventDelete, Key: bz("b1")},
+	}, drainEvents(all))
+
+	wdb.Unwatch(a)
+	_, ok := <-a
+	assert.False(t, ok)
+	assert.Equal(t, "1", wdb.Stats()["watch.watchers"])
+
+	require.NoError(t, wdb.Close())
+	_, ok = <-all
+	assert.False(t, ok)
+	_, ok = <-wdb.Watch(nil)
+	assert.False(t, ok)
+}
+
+func TestWatchDBSlowWatcher(t *testing.T) {
+	wdb := NewWatchDB(NewMemDB())
+	defer wdb.Close()
+	ch := wdb.Watch(nil)
+
+	// A watcher falling behind is dropped, after the events it had room for.
+	for i := 0; i <= watchBufferSize; i++ {
+		require.NoError(t, wdb.Set(bz("k"), bz("v")))
+	}
+	assert.Len(t, drainEvents(ch), watchBufferSize)
+	_, ok := <-ch
+	assert.False(t, ok)
+	assert.Equal(t, "1", wdb.Stats()["watch.dropped"])
+}
+
+// drainEvents returns the events buffered in ch, stopping if it is closed.
+func drainEvents(ch <-chan Event) []Event {
+	var events []Event
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
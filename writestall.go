@@ -0,0 +1,68 @@
+package db
+
+import "time"
+
+// writeStallPollInterval is how often the backends which do not report write
+// stall events check whether writes are stalled, when Options.OnWriteStall is
+// set.
+const writeStallPollInterval = 100 * time.Millisecond
+
+// WriteStallInfo describes the beginning or the end of a write stall, as
+// reported to Options.OnWriteStall.
+type WriteStallInfo struct {
+	// Stalled is set when writes start being stalled, and cleared when they
+	// resume.
+	Stalled bool
+	// Reason is the backend-specific reason of the stall, e.g. "L0 file count
+	// limit exceeded" for pebble. It is empty when writes resume.
+	Reason string
+}
+
+// writeStallWatcher polls whether the writes of a database are stalled,
+// reporting the beginning and the end of the stalls.
+type writeStallWatcher struct {
+	quit chan struct{}
+	done chan struct{}
+}
+
+// watchWriteStalls calls onStall whenever the result of stalled changes,
+// polling it every writeStallPollInterval until the watcher is stopped.
+func watchWriteStalls(stalled func() (bool, string), onStall func(WriteStallInfo)) *writeStallWatcher {
+	w := &writeStallWatcher{
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(writeStallPollInterval)
+		defer ticker.Stop()
+		var wasStalled bool
+		for {
+			select {
+			case <-w.quit:
+				return
+			case <-ticker.C:
+				isStalled, reason := stalled()
+				if isStalled == wasStalled {
+					continue
+				}
+				wasStalled = isStalled
+				if !isStalled {
+					reason = ""
+				}
+				onStall(WriteStallInfo{Stalled: isStalled, Reason: reason})
+			}
+		}
+	}()
+	return w
+}
+
+// stop stops the watcher, once its last call to onStall has returned. It does
+// nothing on a nil watcher.
+func (w *writeStallWatcher) stop() {
+	if w == nil {
+		return
+	}
+	close(w.quit)
+	<-w.done
+}
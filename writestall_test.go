@@ -0,0 +1,64 @@
+package db
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchWriteStalls(t *testing.T) {
+	var stalled atomic.Bool
+	events := make(chan WriteStallInfo, 10)
+	w := watchWriteStalls(func() (bool, string) {
+		return stalled.Load(), "test"
+	}, func(info WriteStallInfo) {
+		events <- info
+	})
+	defer w.stop()
+
+	stalled.Store(true)
+	select {
+	case info := <-events:
+		assert.Equal(t, WriteStallInfo{Stalled: true, Reason: "test"}, info)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stall not reported")
+	}
+	stalled.Store(false)
+	select {
+	case info := <-events:
+		assert.Equal(t, WriteStallInfo{}, info)
+	case <-time.After(5 * time.Second):
+		t.Fatal("end of stall not reported")
+	}
+	assert.Empty(t, events)
+
+	var nilWatcher *writeStallWatcher
+	nilWatcher.stop()
+}
+
+func TestWithOnWriteStallPebble(t *testing.T) {
+	var events []WriteStallInfo
+	db, err := NewDB("db", PebbleDBBackend, t.TempDir(), WithOnWriteStall(func(info WriteStallInfo) {
+		events = append(events, info)
+	}))
+	require.NoError(t, err)
+	defer db.Close()
+	pdb := db.(*PebbleDB)
+
+	listener := pdb.health.listener()
+	listener.WriteStallBegin(pebble.WriteStallBeginInfo{Reason: "test"})
+	m, err := GetMetrics(db)
+	require.NoError(t, err)
+	assert.True(t, m.WriteStalled)
+	assert.EqualValues(t, 1, m.WriteStalls)
+
+	listener.WriteStallEnd()
+	m, err = GetMetrics(db)
+	require.NoError(t, err)
+	assert.False(t, m.WriteStalled)
+	assert.Equal(t, []WriteStallInfo{{Stalled: true, Reason: "test"}, {}}, events)
+}